@@ -1,15 +1,16 @@
 "use client"
 
 import { useEffect, useState, Suspense } from "react"
-import { useRouter, useSearchParams } from "next/navigation"
+import { useParams, useRouter, useSearchParams } from "next/navigation"
 import { authApi } from "@/lib/api/auth"
 import type { User } from "@/types"
 import { Loader2, AlertCircle, CheckCircle } from "lucide-react"
 import { useTranslations } from "@/lib/i18n"
 
-function GoogleCallbackContent() {
+function ProviderCallbackContent() {
   const router = useRouter()
   const searchParams = useSearchParams()
+  const { provider } = useParams<{ provider: string }>()
   const t = useTranslations('auth')
   const tCommon = useTranslations('common')
   const [status, setStatus] = useState<"loading" | "success" | "error">("loading")
@@ -133,10 +134,13 @@ function GoogleCallbackContent() {
           localStorage.removeItem("oauth_state")
 
           // Exchange code for tokens (mobile flow)
-          const response = await authApi.googleExchangeToken(code, state || undefined)
+          if (!state) {
+            throw new Error(t('invalid_state_parameter_csrf'))
+          }
+          const response = await authApi.oidcExchangeToken(provider, code, state)
 
           if (!response.success || !response.data) {
-            throw new Error(response.error?.message || "Failed to authenticate with Google")
+            throw new Error(response.error?.message || "Failed to authenticate")
           }
 
           // Store tokens
@@ -197,7 +201,7 @@ function GoogleCallbackContent() {
         setMessage(t('no_authentication_data_received'))
         setTimeout(() => router.push("/login"), 3000)
       } catch (error: any) {
-        console.error("Google callback error:", error)
+        console.error("Provider callback error:", error)
         setStatus("error")
         setMessage(error.message || tCommon('authentication_failed_please_try_again'))
         setTimeout(() => router.push("/login"), 3000)
@@ -205,7 +209,7 @@ function GoogleCallbackContent() {
     }
 
     handleCallback()
-  }, [searchParams, router])
+  }, [searchParams, router, provider])
 
   return (
     <div className="min-h-screen flex items-center justify-center relative z-10">
@@ -255,14 +259,14 @@ function GoogleCallbackContent() {
   )
 }
 
-export default function GoogleCallbackPage() {
+export default function ProviderCallbackPage() {
   return (
     <Suspense fallback={
       <div className="min-h-screen flex items-center justify-center">
         <Loader2 className="h-12 w-12 text-primary animate-spin" />
       </div>
     }>
-      <GoogleCallbackContent />
+      <ProviderCallbackContent />
     </Suspense>
   )
 }
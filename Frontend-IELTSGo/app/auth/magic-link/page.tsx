@@ -0,0 +1,78 @@
+"use client"
+
+import { useEffect, useState, Suspense } from "react"
+import { useRouter, useSearchParams } from "next/navigation"
+import { authApi } from "@/lib/api/auth"
+import { Loader2, AlertCircle } from "lucide-react"
+import { useTranslations } from "@/lib/i18n"
+
+// The emailed link opens this page instead of an API URL, so mail scanners
+// that prefetch links cannot use up the single-use token
+function MagicLinkContent() {
+  const router = useRouter()
+  const searchParams = useSearchParams()
+  const tCommon = useTranslations('common')
+  const [error, setError] = useState<string | null>(null)
+
+  useEffect(() => {
+    const token = searchParams.get("token")
+    if (!token) {
+      setError(tCommon('authentication_failed_please_try_again'))
+      setTimeout(() => router.push("/login"), 3000)
+      return
+    }
+
+    authApi.loginWithEmailCode({ token })
+      .then((response) => {
+        if (!response.success || !response.data) {
+          throw new Error(response.error?.message)
+        }
+        // The provider callback page stores the tokens and loads the profile
+        const params = new URLSearchParams({
+          success: "true",
+          access_token: response.data.access_token,
+          refresh_token: response.data.refresh_token,
+          user_id: response.data.user_id,
+          email: response.data.email,
+          role: response.data.role,
+        })
+        router.replace(`/auth/email/callback?${params.toString()}`)
+      })
+      .catch((err: any) => {
+        setError(err?.response?.data?.error?.message || err?.message || tCommon('authentication_failed_please_try_again'))
+        setTimeout(() => router.push("/login"), 3000)
+      })
+  }, [searchParams, router])
+
+  return (
+    <div className="min-h-screen flex items-center justify-center relative z-10">
+      <div className="max-w-md w-full mx-4">
+        <div className="bg-card border border-border rounded-lg p-8 shadow-lg">
+          <div className="flex flex-col items-center space-y-4">
+            {error ? (
+              <>
+                <AlertCircle className="h-12 w-12 text-destructive" />
+                <h2 className="text-2xl font-bold text-center">{tCommon('authentication_failed')}</h2>
+                <p className="text-center text-muted-foreground">{error}</p>
+              </>
+            ) : (
+              <Loader2 className="h-12 w-12 text-primary animate-spin" />
+            )}
+          </div>
+        </div>
+      </div>
+    </div>
+  )
+}
+
+export default function MagicLinkPage() {
+  return (
+    <Suspense fallback={
+      <div className="min-h-screen flex items-center justify-center">
+        <Loader2 className="h-12 w-12 text-primary animate-spin" />
+      </div>
+    }>
+      <MagicLinkContent />
+    </Suspense>
+  )
+}
@@ -50,8 +50,10 @@ apiClient.interceptors.response.use(
             refresh_token: refreshToken,
           })
 
-          const { token } = response.data
+          // Refresh tokens are single-use: store the replacement with the new access token
+          const { access_token: token, refresh_token: nextRefreshToken } = response.data.data
           setToken(token)
+          setRefreshToken(nextRefreshToken)
 
           // Retry original request with new token
           if (originalRequest.headers) {
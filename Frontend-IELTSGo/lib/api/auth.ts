@@ -4,6 +4,8 @@ import type {
   RegisterData, 
   AuthResponse,
   GoogleAuthResponse,
+  OIDCProvider,
+  LinkedIdentity,
   ErrorData
 } from "@/types"
 
@@ -102,6 +104,65 @@ export const authApi = {
     return response.data
   },
 
+  // ============= Passwordless sign-in =============
+
+  // Email a 6-digit sign-in code and magic link
+  requestEmailLoginCode: async (email: string): Promise<{ success: boolean; message?: string }> => {
+    const response = await apiClient.post("/auth/login/email-code", { email })
+    return response.data
+  },
+
+  // Sign in with the emailed code, or with the token from the magic link
+  loginWithEmailCode: async (data: { email: string; code: string } | { token: string }): Promise<AuthResponse> => {
+    const response = await apiClient.post<AuthResponse>("/auth/login/email-code/verify", data)
+    return response.data
+  },
+
+  // ============= OpenID Connect providers =============
+
+  // Sign-in providers configured on the backend (Google, Microsoft, ...)
+  getOIDCProviders: async (): Promise<OIDCProvider[]> => {
+    const response = await apiClient.get<{ success: boolean; data: OIDCProvider[] }>("/auth/oidc/providers")
+    return response.data.data || []
+  },
+
+  // Get a provider's sign-in URL
+  getOIDCAuthUrl: async (provider: string): Promise<GoogleAuthResponse> => {
+    const response = await apiClient.get<GoogleAuthResponse>(`/auth/oidc/${provider}/url`)
+    return response.data
+  },
+
+  // Exchange a provider's authorization code for tokens (mobile flow)
+  oidcExchangeToken: async (provider: string, code: string, state: string): Promise<AuthResponse> => {
+    const response = await apiClient.post<AuthResponse>(`/auth/oidc/${provider}/token`, {
+      code,
+      state,
+    })
+    return response.data
+  },
+
+  // Providers linked to the current account
+  listIdentities: async (): Promise<LinkedIdentity[]> => {
+    const response = await apiClient.get<{ success: boolean; data: LinkedIdentity[] }>("/auth/identities")
+    return response.data.data || []
+  },
+
+  // Get the URL that links a provider to the current account; the backend
+  // redirects back to /profile?linked=<provider>. The response sets the state
+  // cookie that binds the link to this browser.
+  linkIdentity: async (provider: string): Promise<GoogleAuthResponse> => {
+    const response = await apiClient.post<GoogleAuthResponse>(`/auth/identities/${provider}`, undefined, {
+      withCredentials: true,
+    })
+    return response.data
+  },
+
+  // Unlink a provider from the current account
+  unlinkIdentity: async (provider: string): Promise<{ success: boolean; message?: string }> => {
+    const response = await apiClient.delete(`/auth/identities/${provider}`)
+    return response.data
+  },
+
   // ============= Password Reset =============
   
   // Request password reset (sends 6-digit code to email)
@@ -110,9 +171,10 @@ export const authApi = {
     return response.data
   },
 
-  // Reset password using 6-digit code
-  resetPasswordByCode: async (code: string, newPassword: string): Promise<{ success: boolean; message?: string }> => {
+  // Reset password using the 6-digit code sent to the email
+  resetPasswordByCode: async (code: string, newPassword: string, email: string): Promise<{ success: boolean; message?: string }> => {
     const response = await apiClient.post("/auth/reset-password-by-code", {
+      email,
       code,
       new_password: newPassword,
     })
@@ -121,9 +183,9 @@ export const authApi = {
 
   // ============= Email Verification =============
   
-  // Verify email using 6-digit code
-  verifyEmailByCode: async (code: string): Promise<{ success: boolean; message?: string }> => {
-    const response = await apiClient.post("/auth/verify-email-by-code", { code })
+  // Verify email using the 6-digit code sent to it
+  verifyEmailByCode: async (code: string, email: string): Promise<{ success: boolean; message?: string }> => {
+    const response = await apiClient.post("/auth/verify-email-by-code", { email, code })
     return response.data
   },
 
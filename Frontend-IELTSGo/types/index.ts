@@ -56,6 +56,19 @@ export interface GoogleAuthResponse {
   error?: ErrorData
 }
 
+export interface OIDCProvider {
+  name: string
+  display_name: string
+}
+
+export interface LinkedIdentity {
+  id: string
+  provider: string
+  email?: string
+  created_at: string
+  last_login_at?: string
+}
+
 // User Preferences - matches backend UserPreferences struct
 export interface UserPreferences {
   user_id: string
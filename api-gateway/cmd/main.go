@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/middleware"
+	"github.com/bisosad1501/ielts-platform/api-gateway/internal/proxy"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/routes"
 	"github.com/gin-gonic/gin"
 )
@@ -27,20 +35,68 @@ func main() {
 	log.Printf("📝 Exercise Service: %s", cfg.Services.ExerciseService)
 	log.Printf("📝 Notification Service: %s", cfg.Services.NotificationService)
 
+	// Initialize tracing (the gateway mints the trace for every client request)
+	shutdownTracing, err := tracing.Init(context.Background(), "api-gateway")
+	if err != nil {
+		log.Printf("⚠️ Tracing disabled: %v", err)
+	}
+
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.New()
 
+	// Only X-Forwarded-For set by configured proxies is believed; by default the
+	// client IP is the connection's address, so clients cannot pick their own
+	// rate limit buckets
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("❌ Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	// Global middleware
 	r.Use(gin.Recovery()) // Panic recovery
+	r.Use(tracing.Middleware("api-gateway"))
+	r.Use(tracing.RequestID())
+	r.Use(metrics.Middleware("api-gateway"))
 	r.Use(middleware.CORS())
 	r.Use(middleware.RequestLogger())
 
-	// Initialize auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret)
+	// Initialize rate limiting (per-IP budget applies to every request)
+	rateLimitStore, err := middleware.NewRateLimitStore(cfg.RateLimit)
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize rate limit store: %v", err)
+	}
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimit, rateLimitStore)
+	r.Use(rateLimiter.Limit(config.PolicyIP))
+	log.Printf("📝 Rate Limiting: enabled=%v store=%s", cfg.RateLimit.Enabled, cfg.RateLimit.Store)
+
+	// Register upstreams and start active health checks
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	proxy.Init(ctx, cfg)
+	log.Printf("📝 Load Balancing: %s (retries=%d, health check every %s)",
+		cfg.Proxy.LoadBalancing, cfg.Proxy.MaxRetries, cfg.Proxy.HealthCheckInterval)
+
+	// Initialize auth middleware; tokens are verified against auth-service's JWKS
+	// and, unless disabled, checked against the denylist of revoked sessions
+	var sessionDenylist *middleware.SessionDenylist
+	if cfg.Sessions.RevocationCheck {
+		if sessionDenylist, err = middleware.NewSessionDenylist(cfg.Sessions.RedisURL); err != nil {
+			log.Printf("⚠️ Session revocation check disabled: %v", err)
+		} else {
+			defer sessionDenylist.Close()
+		}
+	}
+	authMiddleware := middleware.NewAuthMiddleware(jwks.NewKeySet(cfg.JWKSURL), sessionDenylist)
+	log.Printf("📝 Session revocation check: enabled=%v", sessionDenylist != nil)
+
+	// Route-level permission policies; auth-service resolves permissions for
+	// older tokens and records denials in its audit log
+	authServiceURL, _, _ := strings.Cut(cfg.Services.AuthService, ",")
+	authClient := client.NewAuthServiceClient(strings.TrimSpace(authServiceURL), cfg.InternalAPIKey)
+	authorizer := middleware.NewAuthorizer(routes.AdminPolicies, authClient, cfg.Authz.PermissionCacheTTL)
 
 	// Setup all routes
-	routes.SetupRoutes(r, cfg, authMiddleware)
+	routes.SetupRoutes(r, cfg, authMiddleware, authorizer, rateLimiter)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -52,9 +108,27 @@ func main() {
 		}
 	}()
 
+	// Operational endpoints get their own listener so they are never exposed
+	// through the public port
+	internal := gin.New()
+	internal.Use(gin.Recovery())
+	routes.SetupInternalRoutes(internal)
+	go func() {
+		if err := internal.Run(":" + cfg.InternalPort); err != nil {
+			log.Fatalf("❌ Failed to start internal listener: %v", err)
+		}
+	}()
+
 	log.Printf("✅ API Gateway running on http://localhost:%s", cfg.ServerPort)
+	log.Printf("🔒 Internal endpoints on port %s", cfg.InternalPort)
 	log.Println("📚 Visit http://localhost:" + cfg.ServerPort + " for API documentation")
 
 	<-quit
 	log.Println("🛑 Shutting down API Gateway...")
+
+	flushCtx, flushCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer flushCancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("⚠️ Failed to flush traces: %v", err)
+	}
 }
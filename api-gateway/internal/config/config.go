@@ -3,15 +3,26 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	ServerPort string
-	JWTSecret  string
-	Services   ServiceURLs
-	RateLimit  RateLimitConfig
+	ServerPort     string
+	InternalPort   string // operational endpoints, reachable only inside the cluster
+	JWKSURL        string // auth-service's published token signing keys
+	InternalAPIKey string
+	TrustedProxies []string // proxies in front of the gateway whose X-Forwarded-For is believed
+	Services       ServiceURLs
+	RateLimit      RateLimitConfig
+	Proxy          ProxyConfig
+	Authz          AuthzConfig
+	Sessions       SessionConfig
 }
 
+// ServiceURLs holds the upstream address of each backend. A value may list
+// several instances separated by commas, e.g. "http://auth-1:8081,http://auth-2:8081".
 type ServiceURLs struct {
 	AuthService         string
 	UserService         string
@@ -22,15 +33,64 @@ type ServiceURLs struct {
 	StorageService      string
 }
 
+// ProxyConfig controls load balancing, health checks, retries and circuit breaking
+type ProxyConfig struct {
+	LoadBalancing       string        // "round_robin" or "least_connections"
+	MaxRetries          int           // extra attempts for idempotent requests
+	HealthCheckPath     string        // polled on every instance
+	HealthCheckInterval time.Duration // time between health checks
+	HealthCheckTimeout  time.Duration // timeout of a single health check
+	BreakerThreshold    int           // consecutive failures that open an instance's breaker
+	BreakerCooldown     time.Duration // how long an open breaker rejects requests
+}
+
+// AuthzConfig controls route-level permission enforcement
+type AuthzConfig struct {
+	PermissionCacheTTL time.Duration // how long role permissions fetched from auth-service are reused
+}
+
+// SessionConfig controls rejecting access tokens of sessions revoked in
+// auth-service before the tokens expire
+type SessionConfig struct {
+	RevocationCheck bool   // look up each token's session in the denylist
+	RedisURL        string // Redis shared with auth-service, which writes the denylist
+}
+
 type RateLimitConfig struct {
-	RequestsPerMinute int
-	Enabled           bool
+	RequestsPerMinute     int // Per-IP budget applied to every request
+	UserRequestsPerMinute int // Per-user budget applied to authenticated routes
+	Enabled               bool
+	Store                 string // "memory" (single replica) or "redis" (shared across replicas)
+	RedisURL              string
+	Policies              map[string]RateLimitPolicy
 }
 
+// RateLimitPolicy describes a token bucket: Requests tokens are refilled every
+// Period, and at most Burst tokens can be spent at once.
+type RateLimitPolicy struct {
+	Requests int
+	Period   time.Duration
+	Burst    int
+	KeyBy    string // "ip" or "user"
+}
+
+// Rate limit policy names referenced by the routes
+const (
+	PolicyIP            = "ip"
+	PolicyUser          = "user"
+	PolicyLogin         = "login"
+	PolicyPasswordReset = "password_reset"
+	PolicyEmailLogin    = "email_login"
+	PolicySubmission    = "submission"
+)
+
 func LoadConfig() (*Config, error) {
 	config := &Config{
-		ServerPort: getEnv("SERVER_PORT", "8080"),
-		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
+		ServerPort:     getEnv("SERVER_PORT", "8080"),
+		InternalPort:   getEnv("INTERNAL_PORT", "9080"),
+		JWKSURL:        getEnv("JWKS_URL", "http://auth-service:8081/.well-known/jwks.json"),
+		InternalAPIKey: getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
+		TrustedProxies: getEnvAsList("TRUSTED_PROXIES"),
 		Services: ServiceURLs{
 			AuthService:         getEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
 			UserService:         getEnv("USER_SERVICE_URL", "http://user-service:8082"),
@@ -41,14 +101,39 @@ func LoadConfig() (*Config, error) {
 			StorageService:      getEnv("STORAGE_SERVICE_URL", "http://storage-service:8087"),
 		},
 		RateLimit: RateLimitConfig{
-			RequestsPerMinute: getEnvAsInt("RATE_LIMIT_RPM", 100),
-			Enabled:           getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			RequestsPerMinute:     getEnvAsInt("RATE_LIMIT_RPM", 100),
+			UserRequestsPerMinute: getEnvAsInt("RATE_LIMIT_USER_RPM", 300),
+			Enabled:               getEnvAsBool("RATE_LIMIT_ENABLED", true),
+			Store:                 getEnv("RATE_LIMIT_STORE", "memory"),
+			RedisURL:              getEnv("REDIS_URL", "redis://:ielts_redis_password@redis:6379"),
+		},
+		Proxy: ProxyConfig{
+			LoadBalancing:       getEnv("PROXY_LOAD_BALANCING", "round_robin"),
+			MaxRetries:          getEnvAsInt("PROXY_MAX_RETRIES", 2),
+			HealthCheckPath:     getEnv("PROXY_HEALTH_CHECK_PATH", "/health"),
+			HealthCheckInterval: getEnvAsDuration("PROXY_HEALTH_CHECK_INTERVAL", 10*time.Second),
+			HealthCheckTimeout:  getEnvAsDuration("PROXY_HEALTH_CHECK_TIMEOUT", 2*time.Second),
+			BreakerThreshold:    getEnvAsInt("PROXY_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:     getEnvAsDuration("PROXY_BREAKER_COOLDOWN", 30*time.Second),
+		},
+		Authz: AuthzConfig{
+			PermissionCacheTTL: getEnvAsDuration("AUTHZ_PERMISSION_CACHE_TTL", 5*time.Minute),
 		},
+		Sessions: SessionConfig{
+			RevocationCheck: getEnvAsBool("SESSION_REVOCATION_CHECK", true),
+			RedisURL:        getEnv("REDIS_URL", "redis://:ielts_redis_password@redis:6379"),
+		},
+	}
+
+	if lb := config.Proxy.LoadBalancing; lb != "round_robin" && lb != "least_connections" {
+		return nil, fmt.Errorf("PROXY_LOAD_BALANCING must be round_robin or least_connections, got %q", lb)
 	}
 
-	if config.JWTSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is required")
+	policies, err := loadRateLimitPolicies(config.RateLimit, os.Getenv("RATE_LIMIT_POLICIES"))
+	if err != nil {
+		return nil, err
 	}
+	config.RateLimit.Policies = policies
 
 	return config, nil
 }
@@ -70,6 +155,29 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return value
 }
 
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsList splits a comma-separated variable, returning nil when it is unset
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	valueStr := os.Getenv(key)
 	if valueStr == "" {
@@ -77,3 +185,84 @@ func getEnvAsBool(key string, defaultValue bool) bool {
 	}
 	return valueStr == "true" || valueStr == "1"
 }
+
+// loadRateLimitPolicies builds the default policies and applies overrides from
+// RATE_LIMIT_POLICIES, a comma-separated list of name=requests/period[:burst][@key],
+// e.g. "login=5/1m:5@ip,submission=20/1h@user".
+func loadRateLimitPolicies(cfg RateLimitConfig, overrides string) (map[string]RateLimitPolicy, error) {
+	policies := map[string]RateLimitPolicy{
+		PolicyIP:            {Requests: cfg.RequestsPerMinute, Period: time.Minute, Burst: cfg.RequestsPerMinute, KeyBy: "ip"},
+		PolicyUser:          {Requests: cfg.UserRequestsPerMinute, Period: time.Minute, Burst: cfg.UserRequestsPerMinute, KeyBy: "user"},
+		PolicyLogin:         {Requests: 10, Period: time.Minute, Burst: 5, KeyBy: "ip"},
+		PolicyPasswordReset: {Requests: 5, Period: 15 * time.Minute, Burst: 5, KeyBy: "ip"},
+		PolicyEmailLogin:    {Requests: 5, Period: 15 * time.Minute, Burst: 5, KeyBy: "ip"},
+		PolicySubmission:    {Requests: 10, Period: time.Minute, Burst: 5, KeyBy: "user"},
+	}
+
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, policy, err := parseRateLimitPolicy(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_POLICIES entry %q: %w", entry, err)
+		}
+		if existing, ok := policies[name]; ok && policy.KeyBy == "" {
+			policy.KeyBy = existing.KeyBy
+		}
+		if policy.KeyBy == "" {
+			policy.KeyBy = "ip"
+		}
+		policies[name] = policy
+	}
+
+	return policies, nil
+}
+
+func parseRateLimitPolicy(entry string) (string, RateLimitPolicy, error) {
+	var policy RateLimitPolicy
+
+	name, spec, ok := strings.Cut(entry, "=")
+	if !ok || name == "" {
+		return "", policy, fmt.Errorf("expected name=requests/period")
+	}
+
+	if rest, key, found := strings.Cut(spec, "@"); found {
+		if key != "ip" && key != "user" {
+			return "", policy, fmt.Errorf("key must be ip or user")
+		}
+		policy.KeyBy = key
+		spec = rest
+	}
+
+	if rest, burst, found := strings.Cut(spec, ":"); found {
+		b, err := strconv.Atoi(burst)
+		if err != nil || b <= 0 {
+			return "", policy, fmt.Errorf("burst must be a positive integer")
+		}
+		policy.Burst = b
+		spec = rest
+	}
+
+	requests, period, ok := strings.Cut(spec, "/")
+	if !ok {
+		return "", policy, fmt.Errorf("expected requests/period")
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return "", policy, fmt.Errorf("requests must be a positive integer")
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return "", policy, fmt.Errorf("period must be a positive duration")
+	}
+
+	policy.Requests = n
+	policy.Period = d
+	if policy.Burst == 0 {
+		policy.Burst = n
+	}
+
+	return strings.TrimSpace(name), policy, nil
+}
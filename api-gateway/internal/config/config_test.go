@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseRateLimitPolicy tests the RATE_LIMIT_POLICIES entry format
+func TestParseRateLimitPolicy(t *testing.T) {
+	tests := []struct {
+		entry    string
+		name     string
+		expected RateLimitPolicy
+		wantErr  bool
+	}{
+		{"login=5/1m", "login", RateLimitPolicy{Requests: 5, Period: time.Minute, Burst: 5}, false},
+		{"login=5/1m:3", "login", RateLimitPolicy{Requests: 5, Period: time.Minute, Burst: 3}, false},
+		{"submission=30/1h@user", "submission", RateLimitPolicy{Requests: 30, Period: time.Hour, Burst: 30, KeyBy: "user"}, false},
+		{"reset=5/15m:2@ip", "reset", RateLimitPolicy{Requests: 5, Period: 15 * time.Minute, Burst: 2, KeyBy: "ip"}, false},
+		{"login", "", RateLimitPolicy{}, true},
+		{"=5/1m", "", RateLimitPolicy{}, true},
+		{"login=5", "", RateLimitPolicy{}, true},
+		{"login=0/1m", "", RateLimitPolicy{}, true},
+		{"login=x/1m", "", RateLimitPolicy{}, true},
+		{"login=5/soon", "", RateLimitPolicy{}, true},
+		{"login=5/-1m", "", RateLimitPolicy{}, true},
+		{"login=5/1m:0", "", RateLimitPolicy{}, true},
+		{"login=5/1m@session", "", RateLimitPolicy{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			name, policy, err := parseRateLimitPolicy(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateLimitPolicy(%q) error = %v, wantErr %v", tt.entry, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if name != tt.name || policy != tt.expected {
+				t.Errorf("parseRateLimitPolicy(%q) = %q, %+v; expected %q, %+v", tt.entry, name, policy, tt.name, tt.expected)
+			}
+		})
+	}
+}
+
+// TestLoadRateLimitPolicies tests the defaults and overriding them
+func TestLoadRateLimitPolicies(t *testing.T) {
+	cfg := RateLimitConfig{RequestsPerMinute: 100, UserRequestsPerMinute: 300}
+
+	policies, err := loadRateLimitPolicies(cfg, "")
+	if err != nil {
+		t.Fatalf("loadRateLimitPolicies() error = %v", err)
+	}
+	for _, name := range []string{PolicyIP, PolicyUser, PolicyLogin, PolicyPasswordReset, PolicyEmailLogin, PolicySubmission} {
+		if _, ok := policies[name]; !ok {
+			t.Errorf("default policy %q missing", name)
+		}
+	}
+	if got := policies[PolicyIP]; got.Requests != 100 || got.Burst != 100 || got.KeyBy != "ip" {
+		t.Errorf("ip policy = %+v, expected 100/1m keyed by ip", got)
+	}
+
+	policies, err = loadRateLimitPolicies(cfg, " submission=30/1h , custom=2/1s ")
+	if err != nil {
+		t.Fatalf("loadRateLimitPolicies() error = %v", err)
+	}
+	if got := policies[PolicySubmission]; got.Requests != 30 || got.Period != time.Hour || got.KeyBy != "user" {
+		t.Errorf("overridden submission policy = %+v, expected 30/1h keeping its user key", got)
+	}
+	if got := policies["custom"]; got.KeyBy != "ip" {
+		t.Errorf("new policy key = %q, expected ip by default", got.KeyBy)
+	}
+
+	if _, err := loadRateLimitPolicies(cfg, "login=5/1m,broken"); err == nil {
+		t.Error("expected an error for an invalid entry")
+	}
+}
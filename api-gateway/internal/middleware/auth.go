@@ -1,30 +1,43 @@
 package middleware
 
 import (
-	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 type AuthMiddleware struct {
-    jwtSecret string
+	keySet   *jwks.KeySet
+	denylist *SessionDenylist
 }
 
-func NewAuthMiddleware(jwtSecret string) *AuthMiddleware {
-	return &AuthMiddleware{jwtSecret: jwtSecret}
+// NewAuthMiddleware verifies tokens against auth-service's published signing
+// keys and, when denylist is not nil, rejects tokens of revoked sessions
+func NewAuthMiddleware(keySet *jwks.KeySet, denylist *SessionDenylist) *AuthMiddleware {
+	return &AuthMiddleware{keySet: keySet, denylist: denylist}
 }
 
 type Claims struct {
-	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
-	Role   string    `json:"role"`
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	Permissions []string  `json:"permissions,omitempty"` // absent in tokens issued before route policies
+	SessionID   string    `json:"sid,omitempty"`         // absent in tokens issued before session tracking
+	Actor       *Actor    `json:"act,omitempty"`         // set when an admin is impersonating the user
 	jwt.RegisteredClaims
 }
 
+// Actor is the admin behind an impersonation token
+type Actor struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
 // ValidateToken validates JWT and adds claims to headers for downstream services
 func (m *AuthMiddleware) ValidateToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -50,12 +63,7 @@ func (m *AuthMiddleware) ValidateToken() gin.HandlerFunc {
 
 		tokenString := parts[1]
 
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{
@@ -76,10 +84,23 @@ func (m *AuthMiddleware) ValidateToken() gin.HandlerFunc {
 			return
 		}
 
+		if m.sessionRevoked(c, claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "session_revoked",
+				"message": "This session has been signed out",
+			})
+			c.Abort()
+			return
+		}
+
 		// Add claims to request headers for downstream services
 		c.Request.Header.Set("X-User-ID", claims.UserID.String())
 		c.Request.Header.Set("X-User-Email", claims.Email)
 		c.Request.Header.Set("X-User-Role", claims.Role)
+		c.Set("user_id", claims.UserID.String())
+		c.Set("role", claims.Role)
+		c.Set("permissions", claims.Permissions)
+		setImpersonator(c, claims)
 
 		// Keep original Authorization header for services that need it
 		c.Next()
@@ -102,18 +123,15 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 		}
 
 		tokenString := parts[1]
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err == nil && token.Valid {
-			if claims, ok := token.Claims.(*Claims); ok {
+			if claims, ok := token.Claims.(*Claims); ok && !m.sessionRevoked(c, claims) {
 				c.Request.Header.Set("X-User-ID", claims.UserID.String())
 				c.Request.Header.Set("X-User-Email", claims.Email)
 				c.Request.Header.Set("X-User-Role", claims.Role)
+				c.Set("user_id", claims.UserID.String())
+				setImpersonator(c, claims)
 			}
 		}
 
@@ -132,12 +150,7 @@ func (m *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
             parts := strings.Split(authHeader, " ")
             if len(parts) == 2 && parts[0] == "Bearer" {
                 tokenString := parts[1]
-                if token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-                    if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-                        return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-                    }
-                    return []byte(m.jwtSecret), nil
-                }); err == nil && token.Valid {
+                if token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keySet.Keyfunc, jwks.ParserOptions()...); err == nil && token.Valid {
                     if claims, ok := token.Claims.(*Claims); ok {
                         role = claims.Role
                     }
@@ -160,3 +173,29 @@ func (m *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
         c.Abort()
     }
 }
+
+// setImpersonator passes on the admin behind an impersonation token, replacing
+// any X-Impersonator-ID sent by the client
+func setImpersonator(c *gin.Context, claims *Claims) {
+	c.Request.Header.Del("X-Impersonator-ID")
+	if claims.Actor == nil {
+		return
+	}
+	c.Request.Header.Set("X-Impersonator-ID", claims.Actor.Subject)
+	c.Set("impersonator_id", claims.Actor.Subject)
+}
+
+// sessionRevoked checks the token's session against the denylist. Lookups that
+// fail let the request through: the token is still signed and unexpired.
+func (m *AuthMiddleware) sessionRevoked(c *gin.Context, claims *Claims) bool {
+	if m.denylist == nil || claims.SessionID == "" {
+		return false
+	}
+
+	revoked, err := m.denylist.IsRevoked(c.Request.Context(), claims.SessionID)
+	if err != nil {
+		log.Printf("⚠️ Session denylist lookup failed: %v", err)
+		return false
+	}
+	return revoked
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/gin-gonic/gin"
+)
+
+// RoutePolicies maps "METHOD /route/template" (as reported by gin's FullPath)
+// to the permission a caller needs, e.g. "DELETE /api/v1/admin/courses/:id": "course:delete"
+type RoutePolicies map[string]string
+
+// Authorizer enforces route policies using the permissions embedded in the
+// JWT, falling back to auth-service for tokens that predate the claim
+type Authorizer struct {
+	policies   RoutePolicies
+	authClient *client.AuthServiceClient
+	cacheTTL   time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedPermissions // role -> permissions
+}
+
+type cachedPermissions struct {
+	permissions []string
+	expiresAt   time.Time
+}
+
+func NewAuthorizer(policies RoutePolicies, authClient *client.AuthServiceClient, cacheTTL time.Duration) *Authorizer {
+	return &Authorizer{
+		policies:   policies,
+		authClient: authClient,
+		cacheTTL:   cacheTTL,
+		cache:      make(map[string]cachedPermissions),
+	}
+}
+
+// Enforce rejects requests whose caller lacks the permission required by the
+// route's policy. Routes without a policy are denied, so a new admin endpoint
+// cannot be exposed by forgetting to declare one. Must run after ValidateToken.
+func (a *Authorizer) Enforce() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		required, ok := a.policies[route]
+		if !ok {
+			log.Printf("[Authz] No policy declared for %s, denying", route)
+			forbid(c, "", "This endpoint has no access policy")
+			return
+		}
+		c.Set("required_permission", required)
+
+		permissions, err := a.permissions(c)
+		if err != nil {
+			log.Printf("[Authz] Failed to resolve permissions for role %q: %v", c.GetString("role"), err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "authorization_unavailable",
+				"message": "Unable to verify permissions. Please try again later.",
+			})
+			c.Abort()
+			return
+		}
+
+		if !slices.Contains(permissions, required) {
+			forbid(c, required, "You do not have permission to access this resource")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// permissions returns the caller's permissions from the token, or resolves
+// them by role for tokens issued without the permissions claim
+func (a *Authorizer) permissions(c *gin.Context) ([]string, error) {
+	if claimed, ok := c.Get("permissions"); ok {
+		if permissions, ok := claimed.([]string); ok && permissions != nil {
+			return permissions, nil
+		}
+	}
+
+	role := c.GetString("role")
+	if role == "" {
+		return nil, nil
+	}
+
+	a.mu.RLock()
+	cached, ok := a.cache[role]
+	a.mu.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.permissions, nil
+	}
+
+	permissions, err := a.authClient.WithContext(c.Request.Context()).GetRolePermissions(role)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	a.cache[role] = cachedPermissions{permissions: permissions, expiresAt: time.Now().Add(a.cacheTTL)}
+	a.mu.Unlock()
+
+	return permissions, nil
+}
+
+// AuditForbidden reports every 403 on the route group to auth-service's audit
+// log, whether the gateway denied the request or the backend did
+func (a *Authorizer) AuditForbidden() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if c.Writer.Status() != http.StatusForbidden {
+			return
+		}
+
+		source := "upstream"
+		if c.IsAborted() {
+			source = "gateway"
+		}
+
+		event := client.AuditEventRequest{
+			UserID:      c.GetString("user_id"),
+			EventType:   "access_denied",
+			EventStatus: "failed",
+			IPAddress:   c.ClientIP(),
+			UserAgent:   c.Request.UserAgent(),
+			Metadata: map[string]interface{}{
+				"method":              c.Request.Method,
+				"path":                c.Request.URL.Path,
+				"route":               c.FullPath(),
+				"role":                c.GetString("role"),
+				"required_permission": c.GetString("required_permission"),
+				"source":              source,
+				"request_id":          c.GetHeader("X-Request-ID"),
+			},
+		}
+
+		// Auditing must not delay the response; keep the trace but not the cancellation
+		ctx := context.WithoutCancel(c.Request.Context())
+		go func() {
+			if err := a.authClient.WithContext(ctx).RecordAuditEvent(event); err != nil {
+				log.Printf("[Authz] Failed to audit denied %s %s: %v", event.Metadata["method"], event.Metadata["path"], err)
+			}
+		}()
+	}
+}
+
+func forbid(c *gin.Context, required, message string) {
+	body := gin.H{
+		"error":   "forbidden",
+		"message": message,
+	}
+	if required != "" {
+		body["required_permission"] = required
+	}
+	c.JSON(http.StatusForbidden, body)
+	c.Abort()
+}
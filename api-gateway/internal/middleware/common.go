@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -11,8 +12,9 @@ func CORS() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Request-ID, traceparent, tracestate")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, RateLimit-Policy, Retry-After, X-Request-ID, X-Trace-ID")
 		c.Writer.Header().Set("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -38,13 +40,21 @@ func RequestLogger() gin.HandlerFunc {
 		// Get status code
 		statusCode := c.Writer.Status()
 
-		// Log format: [Gateway] Method Path Status Latency
+		// Requests made with an impersonation token name the admin behind them
+		impersonation := ""
+		if impersonatorID := c.GetString("impersonator_id"); impersonatorID != "" {
+			impersonation = " ImpersonatedBy:" + impersonatorID + " User:" + c.GetString("user_id")
+		}
+
+		// Log format: [Gateway] Method Path Status Latency RequestID [ImpersonatedBy User]
 		gin.DefaultWriter.Write([]byte(
 			"[Gateway] " +
 				c.Request.Method + " " +
 				c.Request.URL.Path + " " +
-				"Status:" + string(rune(statusCode)) + " " +
-				"Latency:" + latency.String() + "\n",
+				"Status:" + strconv.Itoa(statusCode) + " " +
+				"Latency:" + latency.String() + " " +
+				"RequestID:" + c.GetString("request_id") +
+				impersonation + "\n",
 		))
 	}
 }
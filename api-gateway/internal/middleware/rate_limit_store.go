@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Bucket describes the token bucket a store should apply to a key
+type Bucket struct {
+	Rate     float64 // tokens refilled per second
+	Capacity float64 // maximum tokens (burst)
+}
+
+// BucketState is the outcome of taking one token from a bucket
+type BucketState struct {
+	Allowed bool
+	Tokens  float64 // tokens left after this request
+}
+
+// RateLimitStore keeps token buckets. Implementations must be safe for
+// concurrent use; a shared store lets several gateway replicas enforce one budget.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, bucket Bucket) (BucketState, error)
+}
+
+// ============================================
+// In-process store
+// ============================================
+
+type memoryBucket struct {
+	tokens   float64
+	updated  time.Time
+	lastSeen time.Time
+}
+
+// MemoryStore keeps buckets in process memory (single gateway replica)
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*memoryBucket
+	now     func() time.Time
+}
+
+// NewMemoryStore creates an in-process store and starts a janitor that evicts idle buckets
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		buckets: make(map[string]*memoryBucket),
+		now:     time.Now,
+	}
+	go s.cleanupLoop(5*time.Minute, 30*time.Minute)
+	return s
+}
+
+// Take refills the bucket for key and tries to take one token
+func (s *MemoryStore) Take(_ context.Context, key string, bucket Bucket) (BucketState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: bucket.Capacity, updated: now}
+		s.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(bucket.Capacity, b.tokens+elapsed*bucket.Rate)
+		b.updated = now
+	}
+	b.lastSeen = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return BucketState{Allowed: true, Tokens: b.tokens}, nil
+	}
+	return BucketState{Allowed: false, Tokens: b.tokens}, nil
+}
+
+func (s *MemoryStore) cleanupLoop(interval, idle time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := s.now().Add(-idle)
+		s.mu.Lock()
+		for key, b := range s.buckets {
+			if b.lastSeen.Before(cutoff) {
+				delete(s.buckets, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// ============================================
+// Redis store (shared across gateway replicas)
+// ============================================
+
+// tokenBucketScript refills and takes from a bucket atomically. It uses the
+// Redis clock so replicas with skewed clocks still agree on the refill.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local ttl = tonumber(ARGV[3])
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+  tokens = capacity
+  ts = now
+end
+
+local elapsed = math.max(0, now - ts) / 1000
+tokens = math.min(capacity, tokens + elapsed * rate)
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps buckets in Redis so every gateway replica shares them
+type RedisStore struct {
+	client *redis.Client
+}
+
+// NewRedisStore connects to Redis and returns a shared store
+func NewRedisStore(redisURL string) (*RedisStore, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &RedisStore{client: client}, nil
+}
+
+// Take refills the bucket for key and tries to take one token
+func (s *RedisStore) Take(ctx context.Context, key string, bucket Bucket) (BucketState, error) {
+	// Keep idle buckets until they would be full again, plus a minute of slack
+	ttl := time.Minute
+	if bucket.Rate > 0 {
+		ttl += time.Duration(bucket.Capacity / bucket.Rate * float64(time.Second))
+	}
+
+	res, err := tokenBucketScript.Run(ctx, s.client, []string{key},
+		bucket.Rate, bucket.Capacity, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return BucketState{}, fmt.Errorf("rate limit script: %w", err)
+	}
+	if len(res) != 2 {
+		return BucketState{}, fmt.Errorf("rate limit script: unexpected reply %v", res)
+	}
+
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return BucketState{}, fmt.Errorf("rate limit script: invalid tokens %q", tokensStr)
+	}
+
+	return BucketState{Allowed: allowed == 1, Tokens: tokens}, nil
+}
+
+// Close releases the Redis connection pool
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClock is a settable clock for MemoryStore
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestStore(clock *fakeClock) *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket), now: clock.now}
+}
+
+// TestMemoryStoreBurst tests that a full bucket allows exactly its capacity
+func TestMemoryStoreBurst(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	store := newTestStore(clock)
+	bucket := Bucket{Rate: 1, Capacity: 3}
+
+	for i := 0; i < 3; i++ {
+		state, _ := store.Take(context.Background(), "k", bucket)
+		if !state.Allowed {
+			t.Fatalf("request %d rejected within the burst", i+1)
+		}
+		if want := float64(2 - i); state.Tokens != want {
+			t.Errorf("request %d left %.2f tokens, expected %.2f", i+1, state.Tokens, want)
+		}
+	}
+	if state, _ := store.Take(context.Background(), "k", bucket); state.Allowed {
+		t.Error("request beyond the burst was allowed")
+	}
+	if state, _ := store.Take(context.Background(), "other", bucket); !state.Allowed {
+		t.Error("a different key should have its own bucket")
+	}
+}
+
+// TestMemoryStoreRefill tests refilling at the bucket's rate up to its capacity
+func TestMemoryStoreRefill(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	store := newTestStore(clock)
+	bucket := Bucket{Rate: 0.5, Capacity: 2} // one token every two seconds
+
+	store.Take(context.Background(), "k", bucket)
+	store.Take(context.Background(), "k", bucket)
+
+	tests := []struct {
+		name    string
+		advance time.Duration
+		allowed bool
+	}{
+		{"empty bucket", 0, false},
+		{"half a token refilled", time.Second, false},
+		{"one token refilled", time.Second, true},
+		{"refill capped at capacity", time.Hour, true},
+		{"one token left of the capacity", 0, true},
+		{"empty again", 0, false},
+	}
+	for _, tt := range tests {
+		clock.t = clock.t.Add(tt.advance)
+		state, err := store.Take(context.Background(), "k", bucket)
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", tt.name, err)
+		}
+		if state.Allowed != tt.allowed {
+			t.Errorf("%s: allowed = %v, expected %v", tt.name, state.Allowed, tt.allowed)
+		}
+	}
+}
+
+// TestLimitKeys tests which bucket a request is counted against
+func TestLimitKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	policies := map[string]config.RateLimitPolicy{
+		"per_ip":   {Requests: 1, Period: time.Hour, Burst: 1, KeyBy: "ip"},
+		"per_user": {Requests: 1, Period: time.Hour, Burst: 1, KeyBy: "user"},
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		first  func(*http.Request) (string, string) // remote address and user ID of the first request
+		second func(*http.Request) (string, string)
+		status int // of the second request
+	}{
+		{
+			name:   "same IP shares a bucket",
+			policy: "per_ip",
+			first:  func(*http.Request) (string, string) { return "203.0.113.7:1000", "" },
+			second: func(*http.Request) (string, string) { return "203.0.113.7:2000", "" },
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "X-Forwarded-For from an untrusted client is ignored",
+			policy: "per_ip",
+			first: func(r *http.Request) (string, string) {
+				r.Header.Set("X-Forwarded-For", "198.51.100.1")
+				return "203.0.113.7:1000", ""
+			},
+			second: func(r *http.Request) (string, string) {
+				r.Header.Set("X-Forwarded-For", "198.51.100.2")
+				return "203.0.113.7:1000", ""
+			},
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "different IPs have their own buckets",
+			policy: "per_ip",
+			first:  func(*http.Request) (string, string) { return "203.0.113.7:1000", "" },
+			second: func(*http.Request) (string, string) { return "203.0.113.8:1000", "" },
+			status: http.StatusOK,
+		},
+		{
+			name:   "per-user policy separates users on one IP",
+			policy: "per_user",
+			first:  func(*http.Request) (string, string) { return "203.0.113.7:1000", "user-a" },
+			second: func(*http.Request) (string, string) { return "203.0.113.7:1000", "user-b" },
+			status: http.StatusOK,
+		},
+		{
+			name:   "per-user policy follows the user across IPs",
+			policy: "per_user",
+			first:  func(*http.Request) (string, string) { return "203.0.113.7:1000", "user-a" },
+			second: func(*http.Request) (string, string) { return "203.0.113.8:1000", "user-a" },
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:   "per-user policy falls back to the IP without a token",
+			policy: "per_user",
+			first:  func(*http.Request) (string, string) { return "203.0.113.7:1000", "" },
+			second: func(*http.Request) (string, string) { return "203.0.113.7:2000", "" },
+			status: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := &fakeClock{t: time.Unix(1700000000, 0)}
+			limiter := NewRateLimiter(config.RateLimitConfig{Enabled: true, Policies: policies}, newTestStore(clock))
+
+			r := gin.New()
+			if err := r.SetTrustedProxies(nil); err != nil {
+				t.Fatal(err)
+			}
+			var userID string
+			r.Use(func(c *gin.Context) {
+				if userID != "" {
+					c.Set("user_id", userID)
+				}
+			})
+			r.GET("/", limiter.Limit(tt.policy), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			send := func(prepare func(*http.Request) (string, string)) int {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr, userID = prepare(req)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				return w.Code
+			}
+
+			if code := send(tt.first); code != http.StatusOK {
+				t.Fatalf("first request status = %d, expected 200", code)
+			}
+			if code := send(tt.second); code != tt.status {
+				t.Errorf("second request status = %d, expected %d", code, tt.status)
+			}
+		})
+	}
+}
+
+// TestLimitHeaders tests the rate limit headers of allowed and rejected requests
+func TestLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	limiter := NewRateLimiter(config.RateLimitConfig{
+		Enabled: true,
+		Policies: map[string]config.RateLimitPolicy{
+			"login": {Requests: 10, Period: time.Minute, Burst: 1, KeyBy: "ip"},
+		},
+	}, newTestStore(clock))
+
+	r := gin.New()
+	r.POST("/login", limiter.Limit("login"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = "203.0.113.7:1000"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send()
+	if w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("allowed request headers = limit %q, remaining %q; expected 1, 0",
+			w.Header().Get("RateLimit-Limit"), w.Header().Get("RateLimit-Remaining"))
+	}
+	if got := w.Header().Get("RateLimit-Policy"); got != "10;w=60;burst=1" {
+		t.Errorf("RateLimit-Policy = %q, expected 10;w=60;burst=1", got)
+	}
+
+	w = send()
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, expected 429", w.Code)
+	}
+	// 10 tokens a minute is one every 6 seconds
+	if got := w.Header().Get("Retry-After"); got != "6" {
+		t.Errorf("Retry-After = %q, expected 6", got)
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/revocation"
+	"github.com/go-redis/redis/v8"
+)
+
+// SessionDenylist reads the revoked sessions auth-service records in Redis
+type SessionDenylist struct {
+	client *redis.Client
+}
+
+// NewSessionDenylist connects to the Redis instance auth-service writes to
+func NewSessionDenylist(redisURL string) (*SessionDenylist, error) {
+	opt, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	client := redis.NewClient(opt)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	return &SessionDenylist{client: client}, nil
+}
+
+// IsRevoked reports whether the session was revoked while its tokens are still valid
+func (d *SessionDenylist) IsRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := d.client.Exists(ctx, revocation.SessionKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("session denylist: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Close releases the Redis connection pool
+func (d *SessionDenylist) Close() error {
+	return d.client.Close()
+}
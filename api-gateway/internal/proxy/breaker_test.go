@@ -0,0 +1,130 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCircuitBreakerTransitions tests the closed, open and half-open states
+func TestCircuitBreakerTransitions(t *testing.T) {
+	type step struct {
+		action  string // "allow", "success", "failure" or "ignore"
+		allowed bool   // expected result of "allow"
+		state   BreakerState
+	}
+
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		steps    []step
+	}{
+		{
+			name:     "opens after threshold consecutive failures",
+			cooldown: time.Hour,
+			steps: []step{
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "allow", allowed: true, state: BreakerClosed},
+				{action: "failure", state: BreakerOpen},
+				{action: "allow", allowed: false, state: BreakerOpen},
+			},
+		},
+		{
+			name:     "success resets the failure count",
+			cooldown: time.Hour,
+			steps: []step{
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "success", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerOpen},
+			},
+		},
+		{
+			name:     "half-open lets a single probe through",
+			cooldown: 0,
+			steps: []step{
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerHalfOpen},
+				{action: "allow", allowed: true, state: BreakerHalfOpen},
+				{action: "allow", allowed: false, state: BreakerHalfOpen},
+			},
+		},
+		{
+			name:     "successful probe closes the breaker",
+			cooldown: 0,
+			steps: []step{
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerHalfOpen},
+				{action: "allow", allowed: true, state: BreakerHalfOpen},
+				{action: "success", state: BreakerClosed},
+				{action: "allow", allowed: true, state: BreakerClosed},
+				{action: "allow", allowed: true, state: BreakerClosed},
+			},
+		},
+		{
+			name:     "ignored probe frees the slot for another",
+			cooldown: 0,
+			steps: []step{
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerClosed},
+				{action: "failure", state: BreakerHalfOpen},
+				{action: "allow", allowed: true, state: BreakerHalfOpen},
+				{action: "ignore", state: BreakerHalfOpen},
+				{action: "allow", allowed: true, state: BreakerHalfOpen},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewCircuitBreaker(3, tt.cooldown)
+			for i, s := range tt.steps {
+				switch s.action {
+				case "allow":
+					if got := b.Allow(); got != s.allowed {
+						t.Fatalf("step %d: Allow() = %v, expected %v", i, got, s.allowed)
+					}
+				case "success":
+					b.Success()
+				case "failure":
+					b.Failure()
+				case "ignore":
+					b.Ignore()
+				}
+				if got := b.State(); got != s.state {
+					t.Fatalf("step %d (%s): State() = %s, expected %s", i, s.action, got, s.state)
+				}
+			}
+		})
+	}
+}
+
+// TestCircuitBreakerFailedProbe tests that a failed probe reopens the
+// breaker for another cooldown
+func TestCircuitBreakerFailedProbe(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Hour)
+	for i := 0; i < 3; i++ {
+		b.Failure()
+	}
+
+	// Pretend the cooldown has elapsed
+	b.openedAt = time.Now().Add(-2 * time.Hour)
+	if !b.Allow() {
+		t.Fatal("Allow() = false after the cooldown, expected a probe")
+	}
+	if got := b.State(); got != BreakerHalfOpen {
+		t.Fatalf("State() = %s, expected half_open", got)
+	}
+
+	b.Failure()
+	if got := b.State(); got != BreakerOpen {
+		t.Errorf("State() after failed probe = %s, expected open", got)
+	}
+	if b.Allow() {
+		t.Error("Allow() = true right after a failed probe")
+	}
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// unhealthyThreshold is the number of consecutive failed checks that mark an instance unhealthy
+const unhealthyThreshold = 2
+
+// StartHealthChecks polls every instance's health endpoint until ctx is cancelled
+func (u *Upstream) StartHealthChecks(ctx context.Context) {
+	client := &http.Client{Timeout: u.cfg.HealthCheckTimeout}
+
+	go func() {
+		ticker := time.NewTicker(u.cfg.HealthCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			for _, inst := range u.instances {
+				u.checkInstance(ctx, client, inst)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (u *Upstream) checkInstance(ctx context.Context, client *http.Client, inst *Instance) {
+	healthy := false
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, inst.URL.String()+u.cfg.HealthCheckPath, nil)
+	if err == nil {
+		if resp, err := client.Do(req); err == nil {
+			resp.Body.Close()
+			healthy = resp.StatusCode >= 200 && resp.StatusCode < 300
+		}
+	}
+
+	if healthy {
+		inst.healthFails = 0
+		if !inst.healthy.Swap(true) {
+			log.Printf("[Health] %s instance %s is healthy again", u.Name, inst.URL.Host)
+		}
+		return
+	}
+
+	inst.healthFails++
+	if inst.healthFails >= unhealthyThreshold && inst.healthy.Swap(false) {
+		log.Printf("[Health] %s instance %s marked unhealthy", u.Name, inst.URL.Host)
+	}
+}
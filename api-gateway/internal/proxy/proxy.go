@@ -1,86 +1,186 @@
 package proxy
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"errors"
 	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
+	"sync"
 
+	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
-// ReverseProxy creates a reverse proxy handler for a target service
-func ReverseProxy(targetURL string) gin.HandlerFunc {
-	target, err := url.Parse(targetURL)
-	if err != nil {
-		log.Fatalf("Failed to parse target URL %s: %v", targetURL, err)
+// registry holds one upstream per configured target, shared by every route
+var registry = struct {
+	sync.RWMutex
+	cfg       config.ProxyConfig
+	ctx       context.Context
+	upstreams map[string]*Upstream
+	order     []*Upstream
+}{
+	cfg:       config.ProxyConfig{LoadBalancing: "round_robin", BreakerThreshold: 5},
+	ctx:       context.Background(),
+	upstreams: make(map[string]*Upstream),
+}
+
+// Init registers every backend service and starts active health checks.
+// It must be called before routes are set up.
+func Init(ctx context.Context, cfg *config.Config) {
+	registry.Lock()
+	registry.cfg = cfg.Proxy
+	registry.ctx = ctx
+	registry.Unlock()
+
+	services := []struct{ name, targets string }{
+		{"auth-service", cfg.Services.AuthService},
+		{"user-service", cfg.Services.UserService},
+		{"course-service", cfg.Services.CourseService},
+		{"exercise-service", cfg.Services.ExerciseService},
+		{"notification-service", cfg.Services.NotificationService},
+		{"ai-service", cfg.Services.AIService},
+		{"storage-service", cfg.Services.StorageService},
 	}
+	for _, svc := range services {
+		if svc.targets != "" {
+			register(svc.name, svc.targets)
+		}
+	}
+}
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
+// register creates (or returns) the upstream for a target list
+func register(name, targets string) *Upstream {
+	registry.Lock()
+	defer registry.Unlock()
 
-	// Customize the Director to preserve the path and query
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalDirector(req)
-		req.Host = target.Host
-		req.URL.Scheme = target.Scheme
-		req.URL.Host = target.Host
+	if u, ok := registry.upstreams[targets]; ok {
+		return u
+	}
 
-		// Log the proxied request
-		log.Printf("[Proxy] %s %s → %s%s", req.Method, req.URL.Path, target.String(), req.URL.Path)
+	u, err := NewUpstream(name, targets, registry.cfg)
+	if err != nil {
+		log.Fatalf("Failed to create upstream %s: %v", name, err)
+	}
+	if registry.cfg.HealthCheckInterval > 0 {
+		u.StartHealthChecks(registry.ctx)
 	}
 
-	// Custom error handler
-	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("[Proxy Error] Failed to proxy request to %s: %v", targetURL, err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		fmt.Fprintf(w, `{"error":"service_unavailable","message":"Failed to connect to backend service","details":"%v"}`, err.Error())
+	registry.upstreams[targets] = u
+	registry.order = append(registry.order, u)
+	return u
+}
+
+// lookup returns the upstream for a target list, registering it on first use
+func lookup(targets string) *Upstream {
+	registry.RLock()
+	u, ok := registry.upstreams[targets]
+	registry.RUnlock()
+	if ok {
+		return u
 	}
+	return register(targets, targets)
+}
 
-	return func(c *gin.Context) {
-		proxy.ServeHTTP(c.Writer, c.Request)
+// UpstreamStatuses reports the health of every registered upstream
+func UpstreamStatuses() map[string][]Status {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	statuses := make(map[string][]Status, len(registry.order))
+	for _, u := range registry.order {
+		statuses[u.Name] = u.Statuses()
 	}
+	return statuses
+}
+
+// ErrorResponse is the envelope returned when the gateway cannot reach a backend
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Upstream  string `json:"upstream"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// ReverseProxy creates a reverse proxy handler for a target service
+func ReverseProxy(targetURL string) gin.HandlerFunc {
+	return newHandler(lookup(targetURL), "")
 }
 
 // ProxyWithPathRewrite creates a reverse proxy that rewrites the path
 func ProxyWithPathRewrite(targetURL, stripPrefix string) gin.HandlerFunc {
-	target, err := url.Parse(targetURL)
-	if err != nil {
-		log.Fatalf("Failed to parse target URL %s: %v", targetURL, err)
-	}
+	return newHandler(lookup(targetURL), stripPrefix)
+}
 
-	proxy := httputil.NewSingleHostReverseProxy(target)
-
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		originalPath := req.URL.Path
-		originalDirector(req)
-		req.Host = target.Host
-		req.URL.Scheme = target.Scheme
-		req.URL.Host = target.Host
-
-		// Strip prefix from path
-		if stripPrefix != "" {
-			req.URL.Path = req.URL.Path[len(stripPrefix):]
-			if req.URL.Path == "" {
-				req.URL.Path = "/"
+func newHandler(upstream *Upstream, stripPrefix string) gin.HandlerFunc {
+	proxy := &httputil.ReverseProxy{
+		// The upstream picks the instance; the Director only rewrites the path
+		Director: func(req *http.Request) {
+			originalPath := req.URL.Path
+			if stripPrefix != "" {
+				req.URL.Path = req.URL.Path[len(stripPrefix):]
+				if req.URL.Path == "" {
+					req.URL.Path = "/"
+				}
+			}
+			if _, ok := req.Header["User-Agent"]; !ok {
+				// Explicitly disable the default Go User-Agent
+				req.Header.Set("User-Agent", "")
 			}
-		}
 
-		log.Printf("[Proxy] %s %s (stripped: %s) → %s%s",
-			req.Method, originalPath, stripPrefix, target.String(), req.URL.Path)
-	}
-
-	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-		log.Printf("[Proxy Error] Failed to proxy request to %s: %v", targetURL, err)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadGateway)
-		fmt.Fprintf(w, `{"error":"service_unavailable","message":"Failed to connect to backend service"}`)
+			log.Printf("[Proxy] %s %s → %s%s", req.Method, originalPath, upstream.Name, req.URL.Path)
+		},
+		Transport:    upstream,
+		ErrorHandler: errorHandler(upstream),
 	}
 
 	return func(c *gin.Context) {
+		// Replace the client's X-Forwarded-For with the address the gateway
+		// resolved, so backends trusting the gateway see the real client
+		c.Request.Header.Set("X-Forwarded-For", c.ClientIP())
+		c.Request.Header.Del("X-Real-IP")
 		proxy.ServeHTTP(c.Writer, c.Request)
 	}
 }
+
+// errorHandler writes a typed error envelope without leaking internal error details
+func errorHandler(upstream *Upstream) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		requestID := r.Header.Get("X-Request-ID")
+		log.Printf("[Proxy Error] %s %s → %s (request_id=%s): %v", r.Method, r.URL.Path, upstream.Name, requestID, err)
+
+		if errors.Is(err, context.Canceled) {
+			// Client went away; nobody is listening for a response
+			return
+		}
+
+		status := http.StatusBadGateway
+		resp := ErrorResponse{
+			Error:     "bad_gateway",
+			Message:   "The service returned an invalid response. Please try again later.",
+			Upstream:  upstream.Name,
+			RequestID: requestID,
+		}
+
+		var netErr net.Error
+		switch {
+		case errors.Is(err, errNoHealthyInstance):
+			status = http.StatusServiceUnavailable
+			resp.Error = "service_unavailable"
+			resp.Message = "The service is temporarily unavailable. Please try again later."
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			status = http.StatusGatewayTimeout
+			resp.Error = "upstream_timeout"
+			resp.Message = "The service took too long to respond. Please try again later."
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if status == http.StatusServiceUnavailable {
+			w.Header().Set("Retry-After", "5")
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
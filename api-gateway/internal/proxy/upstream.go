@@ -0,0 +1,254 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
+	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
+)
+
+// maxRetryBodySize is the largest request body buffered so it can be replayed on retry
+const maxRetryBodySize = 1 << 20
+
+// errNoHealthyInstance is returned when every instance is unhealthy or has an open breaker
+var errNoHealthyInstance = errors.New("no healthy upstream instance")
+
+// Instance is a single backend process behind an upstream
+type Instance struct {
+	URL     *url.URL
+	breaker *CircuitBreaker
+
+	healthy     atomic.Bool
+	active      atomic.Int64
+	healthFails int // consecutive failed health checks, owned by the health checker
+}
+
+// Upstream is a named backend service with one or more instances
+type Upstream struct {
+	Name      string
+	instances []*Instance
+	cfg       config.ProxyConfig
+	transport http.RoundTripper
+	next      atomic.Uint64
+}
+
+// NewUpstream parses a comma-separated list of instance URLs
+func NewUpstream(name, targets string, cfg config.ProxyConfig) (*Upstream, error) {
+	u := &Upstream{
+		Name: name,
+		cfg:  cfg,
+		// Each attempt gets its own client span carrying the traceparent
+		transport: tracing.NewTransport(&http.Transport{
+			Proxy:               http.ProxyFromEnvironment,
+			MaxIdleConns:        100,
+			MaxIdleConnsPerHost: 20,
+			IdleConnTimeout:     90 * time.Second,
+		}),
+	}
+
+	for _, raw := range strings.Split(targets, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		target, err := url.Parse(raw)
+		if err != nil || target.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q for upstream %s", raw, name)
+		}
+		inst := &Instance{
+			URL:     target,
+			breaker: NewCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		}
+		inst.healthy.Store(true) // optimistic until the first health check says otherwise
+		u.instances = append(u.instances, inst)
+	}
+
+	if len(u.instances) == 0 {
+		return nil, fmt.Errorf("upstream %s has no instances", name)
+	}
+
+	return u, nil
+}
+
+// pick chooses an instance that is healthy, has a closed (or probing) breaker
+// and has not been tried yet for this request
+func (u *Upstream) pick(tried map[*Instance]bool) *Instance {
+	var candidates []*Instance
+	for _, inst := range u.instances {
+		if !tried[inst] && inst.healthy.Load() && inst.breaker.State() != BreakerOpen {
+			candidates = append(candidates, inst)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var chosen *Instance
+	if u.cfg.LoadBalancing == "least_connections" {
+		for _, inst := range candidates {
+			if chosen == nil || inst.active.Load() < chosen.active.Load() {
+				chosen = inst
+			}
+		}
+	} else {
+		chosen = candidates[u.next.Add(1)%uint64(len(candidates))]
+	}
+
+	if !chosen.breaker.Allow() {
+		// Lost the half-open probe race; don't pick this instance again
+		tried[chosen] = true
+		return u.pick(tried)
+	}
+	return chosen
+}
+
+// RoundTrip sends the request to a chosen instance, retrying idempotent
+// requests on another instance when the connection fails or the upstream
+// answers 502/503/504.
+func (u *Upstream) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	var body []byte
+	if isRetryable(req) {
+		if replay, ok := bufferBody(req); ok {
+			body = replay
+			attempts += u.cfg.MaxRetries
+		}
+	}
+
+	tried := make(map[*Instance]bool)
+	var lastErr error
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		inst := u.pick(tried)
+		if inst == nil {
+			break
+		}
+		tried[inst] = true
+
+		outReq := req.Clone(req.Context())
+		outReq.URL.Scheme = inst.URL.Scheme
+		outReq.URL.Host = inst.URL.Host
+		outReq.Host = inst.URL.Host
+		if len(body) > 0 {
+			outReq.Body = io.NopCloser(bytes.NewReader(body))
+			outReq.ContentLength = int64(len(body))
+		}
+
+		inst.active.Add(1)
+		resp, err := u.transport.RoundTrip(outReq)
+
+		if err != nil {
+			inst.active.Add(-1)
+			if errors.Is(err, context.Canceled) {
+				inst.breaker.Ignore()
+				return nil, err
+			}
+			inst.breaker.Failure()
+			lastErr = err
+			log.Printf("[Proxy] %s attempt %d via %s failed: %v", u.Name, attempt+1, inst.URL.Host, err)
+			continue
+		}
+
+		if isUpstreamFailure(resp.StatusCode) {
+			inst.breaker.Failure()
+			// Keep the last instance's answer rather than replacing it with
+			// errNoHealthyInstance when there is nothing left to retry on
+			if attempt < attempts-1 && len(tried) < len(u.instances) {
+				resp.Body.Close()
+				inst.active.Add(-1)
+				lastErr = fmt.Errorf("upstream returned %d", resp.StatusCode)
+				log.Printf("[Proxy] %s attempt %d via %s returned %d, retrying", u.Name, attempt+1, inst.URL.Host, resp.StatusCode)
+				continue
+			}
+		} else {
+			inst.breaker.Success()
+		}
+
+		// Keep the connection counted until the body is fully consumed (e.g. SSE streams)
+		resp.Body = &countedBody{ReadCloser: resp.Body, inst: inst}
+		return resp, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errNoHealthyInstance
+	}
+	return nil, lastErr
+}
+
+// Status describes an instance for the gateway's upstream health endpoint
+type Status struct {
+	URL         string `json:"url"`
+	Healthy     bool   `json:"healthy"`
+	Breaker     string `json:"breaker"`
+	Connections int64  `json:"active_connections"`
+}
+
+// Statuses reports the health of every instance
+func (u *Upstream) Statuses() []Status {
+	statuses := make([]Status, 0, len(u.instances))
+	for _, inst := range u.instances {
+		statuses = append(statuses, Status{
+			URL:         inst.URL.String(),
+			Healthy:     inst.healthy.Load(),
+			Breaker:     inst.breaker.State().String(),
+			Connections: inst.active.Load(),
+		})
+	}
+	return statuses
+}
+
+// countedBody decrements the instance's active connection count once
+type countedBody struct {
+	io.ReadCloser
+	inst *Instance
+	once sync.Once
+}
+
+func (b *countedBody) Close() error {
+	b.once.Do(func() { b.inst.active.Add(-1) })
+	return b.ReadCloser.Close()
+}
+
+// isRetryable reports whether the method is idempotent and safe to replay
+func isRetryable(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isUpstreamFailure reports whether a status means the instance itself is failing
+func isUpstreamFailure(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// bufferBody reads a small request body into memory so it can be replayed.
+// It returns false when the body is too large or of unknown length.
+func bufferBody(req *http.Request) ([]byte, bool) {
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+		return []byte{}, true
+	}
+	if req.ContentLength < 0 || req.ContentLength > maxRetryBodySize {
+		return nil, false
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, maxRetryBodySize+1))
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil || len(data) > maxRetryBodySize {
+		return nil, false
+	}
+	return data, true
+}
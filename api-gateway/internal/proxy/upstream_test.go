@@ -0,0 +1,142 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
+)
+
+// fakeTransport answers each instance with a fixed status, or fails the
+// connection when the status is 0, and records the hosts it was sent to
+type fakeTransport struct {
+	statuses map[string]int
+	hosts    []string
+	bodies   []string
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.hosts = append(f.hosts, req.URL.Host)
+	body := ""
+	if req.Body != nil {
+		data, _ := io.ReadAll(req.Body)
+		body = string(data)
+	}
+	f.bodies = append(f.bodies, body)
+
+	status := f.statuses[req.URL.Host]
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+}
+
+// TestUpstreamRetries tests that only idempotent requests are retried on
+// another instance
+func TestUpstreamRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		statuses map[string]int
+		attempts int
+		status   int // 0 when the round trip should fail
+	}{
+		{"GET retried after a connection error", http.MethodGet, "", map[string]int{"a:1": 0, "b:1": 200}, 2, 200},
+		{"GET retried after a 503", http.MethodGet, "", map[string]int{"a:1": 503, "b:1": 200}, 2, 200},
+		{"PUT replays its body on retry", http.MethodPut, `{"x":1}`, map[string]int{"a:1": 502, "b:1": 200}, 2, 200},
+		{"DELETE retried", http.MethodDelete, "", map[string]int{"a:1": 0, "b:1": 204}, 2, 204},
+		{"POST not retried after a connection error", http.MethodPost, `{"x":1}`, map[string]int{"a:1": 0, "b:1": 200}, 1, 0},
+		{"POST not retried after a 503", http.MethodPost, `{"x":1}`, map[string]int{"a:1": 503, "b:1": 200}, 1, 503},
+		{"PATCH not retried", http.MethodPatch, `{"x":1}`, map[string]int{"a:1": 0, "b:1": 200}, 1, 0},
+		{"client errors are not retried", http.MethodGet, "", map[string]int{"a:1": 404, "b:1": 200}, 1, 404},
+		{"last failure returned when every instance fails", http.MethodGet, "", map[string]int{"a:1": 503, "b:1": 503}, 2, 503},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := NewUpstream("test", "http://a:1,http://b:1", config.ProxyConfig{
+				MaxRetries:       2,
+				BreakerThreshold: 5,
+				BreakerCooldown:  time.Minute,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			transport := &fakeTransport{statuses: tt.statuses}
+			u.transport = transport
+			u.next.Store(^uint64(0)) // the first pick is instance a
+
+			var body io.Reader
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			req := httptest.NewRequest(tt.method, "http://gateway/api/v1/x", body)
+
+			resp, err := u.RoundTrip(req)
+			if tt.status == 0 {
+				if err == nil {
+					t.Errorf("RoundTrip() succeeded with %d, expected an error", resp.StatusCode)
+				}
+			} else if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			} else {
+				resp.Body.Close()
+				if resp.StatusCode != tt.status {
+					t.Errorf("status = %d, expected %d", resp.StatusCode, tt.status)
+				}
+			}
+
+			if len(transport.hosts) != tt.attempts {
+				t.Fatalf("sent to %v, expected %d attempts", transport.hosts, tt.attempts)
+			}
+			if tt.attempts > 1 && transport.hosts[0] == transport.hosts[1] {
+				t.Errorf("retried on the same instance %s", transport.hosts[0])
+			}
+			for i, got := range transport.bodies {
+				if got != tt.body {
+					t.Errorf("attempt %d body = %q, expected %q", i+1, got, tt.body)
+				}
+			}
+		})
+	}
+}
+
+// TestUpstreamSkipsOpenBreaker tests that instances with an open breaker are
+// not picked and that none left gives errNoHealthyInstance
+func TestUpstreamSkipsOpenBreaker(t *testing.T) {
+	u, err := NewUpstream("test", "http://a:1,http://b:1", config.ProxyConfig{
+		MaxRetries:       2,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &fakeTransport{statuses: map[string]int{"a:1": 0, "b:1": 200}}
+	u.transport = transport
+	u.instances[0].breaker.Failure()
+
+	for i := 0; i < 3; i++ {
+		resp, err := u.RoundTrip(httptest.NewRequest(http.MethodPost, "http://gateway/x", nil))
+		if err != nil {
+			t.Fatalf("RoundTrip() error = %v", err)
+		}
+		resp.Body.Close()
+	}
+	for _, host := range transport.hosts {
+		if host != "b:1" {
+			t.Fatalf("sent to %v, expected only b:1", transport.hosts)
+		}
+	}
+
+	u.instances[1].breaker.Failure()
+	if _, err := u.RoundTrip(httptest.NewRequest(http.MethodGet, "http://gateway/x", nil)); !errors.Is(err, errNoHealthyInstance) {
+		t.Errorf("RoundTrip() error = %v, expected errNoHealthyInstance", err)
+	}
+}
@@ -0,0 +1,95 @@
+package routes
+
+import "github.com/bisosad1501/ielts-platform/api-gateway/internal/middleware"
+
+// AdminPolicies declares the permission each /admin route requires. Permissions
+// and their role grants live in auth_db (permissions, role_permissions); a
+// route missing from this table is denied.
+var AdminPolicies = middleware.RoutePolicies{
+	// Course management
+	"POST /api/v1/admin/courses":             "course:create",
+	"PUT /api/v1/admin/courses/:id":          "course:update",
+	"DELETE /api/v1/admin/courses/:id":       "course:delete",
+	"POST /api/v1/admin/courses/:id/publish": "course:publish",
+
+	// Modules, lessons and videos
+	"POST /api/v1/admin/modules":                   "course_content:manage",
+	"POST /api/v1/admin/lessons":                   "course_content:manage",
+	"POST /api/v1/admin/lessons/:lesson_id/videos": "course_content:manage",
+
+	// Exercise management
+	"POST /api/v1/admin/exercises":                    "exercise:create",
+	"PUT /api/v1/admin/exercises/:id":                 "exercise:update",
+	"DELETE /api/v1/admin/exercises/:id":              "exercise:delete",
+	"POST /api/v1/admin/exercises/:id/publish":        "exercise:publish",
+	"POST /api/v1/admin/exercises/:id/unpublish":      "exercise:publish",
+	"POST /api/v1/admin/exercises/:id/sections":       "exercise:create",
+	"GET /api/v1/admin/exercises/:id/analytics":       "exercise:analytics",
+	"POST /api/v1/admin/exercises/:id/tags":           "exercise:update",
+	"DELETE /api/v1/admin/exercises/:id/tags/:tag_id": "exercise:update",
+
+	// Mock tests
+	"GET /api/v1/admin/mock-tests":     "exercise:create",
+	"POST /api/v1/admin/mock-tests":    "exercise:create",
+	"PUT /api/v1/admin/mock-tests/:id": "exercise:update",
+
+	// Questions and question bank
+	"POST /api/v1/admin/questions":             "question:manage",
+	"POST /api/v1/admin/questions/:id/options": "question:manage",
+	"POST /api/v1/admin/questions/:id/answer":  "question:manage",
+	"GET /api/v1/admin/question-bank":          "question_bank:read",
+	"POST /api/v1/admin/question-bank":         "question_bank:manage",
+	"PUT /api/v1/admin/question-bank/:id":      "question_bank:manage",
+	"DELETE /api/v1/admin/question-bank/:id":   "question_bank:manage",
+
+	// Tags and notifications
+	"POST /api/v1/admin/tags":               "tag:manage",
+	"POST /api/v1/admin/notifications":      "notification:send",
+	"POST /api/v1/admin/notifications/bulk": "notification:send",
+
+	// Two-factor authentication policies
+	"GET /api/v1/admin/mfa/policies":       "mfa_policy:manage",
+	"PUT /api/v1/admin/mfa/policies/:role": "mfa_policy:manage",
+
+	// Account lockouts
+	"POST /api/v1/admin/users/:id/unlock": "user:unlock",
+
+	// User management and impersonation
+	"GET /api/v1/admin/users":                  "user:read",
+	"GET /api/v1/admin/users/:id":              "user:read",
+	"POST /api/v1/admin/users/:id/suspend":     "user:manage",
+	"POST /api/v1/admin/users/:id/reactivate":  "user:manage",
+	"POST /api/v1/admin/users/roles":           "user:manage",
+	"POST /api/v1/admin/users/:id/impersonate": "user:impersonate",
+
+	// Audit log
+	"GET /api/v1/admin/audit-logs":        "audit_log:read",
+	"GET /api/v1/admin/audit-logs/export": "audit_log:read",
+
+	// Personal data exports and account deletions
+	"GET /api/v1/admin/data-requests":            "data_request:manage",
+	"GET /api/v1/admin/data-requests/:id":        "data_request:manage",
+	"POST /api/v1/admin/data-requests/:id/retry": "data_request:manage",
+
+	// Examiner review
+	"GET /api/v1/admin/reviews":                             "submission:review",
+	"GET /api/v1/admin/reviews/:id":                         "submission:review",
+	"POST /api/v1/admin/reviews/:id/claim":                  "submission:review",
+	"POST /api/v1/admin/reviews/:id/release":                "submission:review",
+	"PUT /api/v1/admin/reviews/:id/scores":                  "submission:review",
+	"POST /api/v1/admin/reviews/:id/comments":               "submission:review",
+	"DELETE /api/v1/admin/reviews/:id/comments/:comment_id": "submission:review",
+	"POST /api/v1/admin/reviews/:id/publish":                "submission:review",
+
+	// Organizations
+	"GET /api/v1/admin/organizations":  "organization:manage",
+	"POST /api/v1/admin/organizations": "organization:manage",
+
+	// AI prompts
+	"POST /api/v1/admin/ai/writing/prompts":        "ai_prompt:manage",
+	"PUT /api/v1/admin/ai/writing/prompts/:id":     "ai_prompt:manage",
+	"DELETE /api/v1/admin/ai/writing/prompts/:id":  "ai_prompt:manage",
+	"POST /api/v1/admin/ai/speaking/prompts":       "ai_prompt:manage",
+	"PUT /api/v1/admin/ai/speaking/prompts/:id":    "ai_prompt:manage",
+	"DELETE /api/v1/admin/ai/speaking/prompts/:id": "ai_prompt:manage",
+}
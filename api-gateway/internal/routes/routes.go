@@ -3,13 +3,31 @@ package routes
 import (
 	"net/http"
 
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/config"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/middleware"
 	"github.com/bisosad1501/ielts-platform/api-gateway/internal/proxy"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.AuthMiddleware) {
+// SetupInternalRoutes registers operational endpoints on the internal listener,
+// which is not published outside the cluster
+func SetupInternalRoutes(r *gin.Engine) {
+	// Prometheus metrics for the gateway itself
+	r.GET("/metrics", metrics.Handler())
+
+	// Upstream instance health, circuit breaker state and active connections
+	r.GET("/health/upstreams", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"upstreams": proxy.UpstreamStatuses(),
+		})
+	})
+}
+
+func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.AuthMiddleware, authorizer *middleware.Authorizer, rateLimiter *middleware.RateLimiter) {
+	// Per-user budget, applied after token validation on every protected group
+	userLimit := rateLimiter.Limit(config.PolicyUser)
+
 	// Health check for gateway itself
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -47,6 +65,9 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 		})
 	})
 
+	// Token signing keys, so clients and other verifiers can validate access tokens
+	r.GET("/.well-known/jwks.json", proxy.ReverseProxy(cfg.Services.AuthService))
+
 	// API v1 routes
 	v1 := r.Group("/api/v1")
 
@@ -57,36 +78,121 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	{
 		// Public auth endpoints (no token required)
 		authGroup.POST("/register", proxy.ReverseProxy(cfg.Services.AuthService))
-		authGroup.POST("/login", proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/login", rateLimiter.Limit(config.PolicyLogin), proxy.ReverseProxy(cfg.Services.AuthService))
 		authGroup.POST("/refresh", proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// Passwordless sign-in
+		authGroup.POST("/login/email-code", rateLimiter.Limit(config.PolicyEmailLogin), proxy.ReverseProxy(cfg.Services.AuthService)) // Sends a 6-digit code and magic link
+		authGroup.POST("/login/email-code/verify", rateLimiter.Limit(config.PolicyLogin), proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/change-email/cancel", rateLimiter.Limit(config.PolicyLogin), proxy.ReverseProxy(cfg.Services.AuthService)) // Link sent to the old address
 		authGroup.POST("/logout", proxy.ReverseProxy(cfg.Services.AuthService))
 
 		// Email verification
 		authGroup.GET("/verify-email", proxy.ReverseProxy(cfg.Services.AuthService))          // Legacy token-based verification
-		authGroup.POST("/verify-email-by-code", proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code verification
-		authGroup.POST("/resend-verification", proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/verify-email-by-code", rateLimiter.Limit(config.PolicyPasswordReset), proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code verification
+		authGroup.POST("/resend-verification", rateLimiter.Limit(config.PolicyPasswordReset), proxy.ReverseProxy(cfg.Services.AuthService))
 
 		// Password reset
-		authGroup.POST("/forgot-password", proxy.ReverseProxy(cfg.Services.AuthService))        // Request reset (sends 6-digit code)
-		authGroup.POST("/reset-password", proxy.ReverseProxy(cfg.Services.AuthService))         // Legacy token-based reset
-		authGroup.POST("/reset-password-by-code", proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code reset
-
-		// Google OAuth
+		authGroup.POST("/forgot-password", rateLimiter.Limit(config.PolicyPasswordReset), proxy.ReverseProxy(cfg.Services.AuthService))        // Request reset (sends 6-digit code)
+		authGroup.POST("/reset-password", rateLimiter.Limit(config.PolicyPasswordReset), proxy.ReverseProxy(cfg.Services.AuthService))         // Legacy token-based reset
+		authGroup.POST("/reset-password-by-code", rateLimiter.Limit(config.PolicyPasswordReset), proxy.ReverseProxy(cfg.Services.AuthService)) // New 6-digit code reset
+
+		// OpenID Connect sign-in (any configured provider)
+		authGroup.GET("/oidc/providers", proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.GET("/oidc/:provider/url", proxy.ReverseProxy(cfg.Services.AuthService))      // Get OAuth URL (Mobile/Web)
+		authGroup.GET("/oidc/:provider", proxy.ReverseProxy(cfg.Services.AuthService))          // Web flow: Redirect to the provider
+		authGroup.GET("/oidc/:provider/callback", proxy.ReverseProxy(cfg.Services.AuthService)) // Web flow: Handle callback
+		authGroup.POST("/oidc/:provider/token", proxy.ReverseProxy(cfg.Services.AuthService))   // Mobile flow: Exchange code
+
+		// Google OAuth (aliases of the OpenID Connect routes)
 		authGroup.GET("/google/url", proxy.ReverseProxy(cfg.Services.AuthService))      // Get OAuth URL (Mobile/Web)
 		authGroup.GET("/google", proxy.ReverseProxy(cfg.Services.AuthService))          // Web flow: Redirect to Google
 		authGroup.GET("/google/callback", proxy.ReverseProxy(cfg.Services.AuthService)) // Web flow: Handle callback
 		authGroup.POST("/google/token", proxy.ReverseProxy(cfg.Services.AuthService))   // Mobile flow: Exchange code
 
+		// Two-factor login (second step after /login or Google)
+		authGroup.POST("/mfa/verify", rateLimiter.Limit(config.PolicyLogin), proxy.ReverseProxy(cfg.Services.AuthService))
+		authGroup.POST("/mfa/challenge/enrollment", rateLimiter.Limit(config.PolicyLogin), proxy.ReverseProxy(cfg.Services.AuthService))
+
 		// Protected auth endpoints (require token)
 		authProtected := authGroup.Group("")
-		authProtected.Use(authMiddleware.ValidateToken())
+		authProtected.Use(authMiddleware.ValidateToken(), userLimit)
 		{
 			authProtected.GET("/validate", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.DELETE("/impersonation", proxy.ReverseProxy(cfg.Services.AuthService)) // Ends the impersonation token used
 			authProtected.POST("/change-password", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/change-email", rateLimiter.Limit(config.PolicyPasswordReset), proxy.ReverseProxy(cfg.Services.AuthService)) // Sends a code to the new address
+			authProtected.POST("/change-email/confirm", rateLimiter.Limit(config.PolicyLogin), proxy.ReverseProxy(cfg.Services.AuthService))
 			authProtected.GET("/me", proxy.ReverseProxy(cfg.Services.AuthService))
+
+			// Sessions (signed-in devices)
+			authProtected.GET("/sessions", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.DELETE("/sessions", proxy.ReverseProxy(cfg.Services.AuthService)) // All except the current one
+			authProtected.DELETE("/sessions/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+
+			// Linked sign-in providers
+			authProtected.GET("/identities", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/identities/:provider", proxy.ReverseProxy(cfg.Services.AuthService)) // Returns the provider URL to open
+			authProtected.DELETE("/identities/:provider", proxy.ReverseProxy(cfg.Services.AuthService))
+
+			// Two-factor authentication management
+			authProtected.GET("/mfa", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/mfa/enroll", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/mfa/enroll/confirm", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/mfa/disable", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/mfa/recovery-codes", proxy.ReverseProxy(cfg.Services.AuthService))
+
+			// Personal data export and account deletion
+			authProtected.POST("/account/export", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.GET("/account/export/:id/download", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.GET("/account/data-requests", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.POST("/account/deletion", proxy.ReverseProxy(cfg.Services.AuthService))
+			authProtected.DELETE("/account/deletion", proxy.ReverseProxy(cfg.Services.AuthService)) // Only during the grace period
 		}
 	}
 
+	// Organizations and classrooms (from Auth Service; access depends on
+	// the caller's role in each organization)
+	organizationGroup := v1.Group("/organizations")
+	organizationGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		organizationGroup.GET("", proxy.ReverseProxy(cfg.Services.AuthService))
+		organizationGroup.GET("/:id/members", proxy.ReverseProxy(cfg.Services.AuthService))
+		organizationGroup.POST("/:id/members", proxy.ReverseProxy(cfg.Services.AuthService))
+		organizationGroup.DELETE("/:id/members/:user_id", proxy.ReverseProxy(cfg.Services.AuthService))
+		organizationGroup.GET("/:id/classrooms", proxy.ReverseProxy(cfg.Services.AuthService))
+		organizationGroup.POST("/:id/classrooms", proxy.ReverseProxy(cfg.Services.AuthService))
+	}
+
+	classroomGroup := v1.Group("/classrooms")
+	classroomGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		classroomGroup.GET("", proxy.ReverseProxy(cfg.Services.AuthService))
+		classroomGroup.POST("/join", proxy.ReverseProxy(cfg.Services.AuthService)) // By invite code
+		classroomGroup.GET("/:id/members", proxy.ReverseProxy(cfg.Services.AuthService))
+		classroomGroup.POST("/:id/members", proxy.ReverseProxy(cfg.Services.AuthService))
+		classroomGroup.DELETE("/:id/members/:user_id", proxy.ReverseProxy(cfg.Services.AuthService))
+		classroomGroup.POST("/:id/invites", proxy.ReverseProxy(cfg.Services.AuthService)) // CSV upload
+		classroomGroup.DELETE("/:id/invites/:invite_id", proxy.ReverseProxy(cfg.Services.AuthService))
+		classroomGroup.POST("/:id/invite-code", proxy.ReverseProxy(cfg.Services.AuthService))
+		classroomGroup.GET("/:id/progress", proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// Assignments and gradebook live in exercise-service
+		classroomGroup.GET("/:id/assignments", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		classroomGroup.POST("/:id/assignments", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		classroomGroup.GET("/:id/gradebook", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		classroomGroup.GET("/:id/gradebook/export", proxy.ReverseProxy(cfg.Services.ExerciseService)) // CSV
+	}
+
+	assignmentGroup := v1.Group("/assignments")
+	assignmentGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		assignmentGroup.GET("/my", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		assignmentGroup.GET("/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		assignmentGroup.PUT("/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		assignmentGroup.DELETE("/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+	}
+
 	// ============================================
 	// USER SERVICE - Most require auth
 	// ============================================
@@ -102,14 +208,14 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Protected social routes (auth required)
 	usersProtected := v1.Group("/users")
-	usersProtected.Use(authMiddleware.ValidateToken())
+	usersProtected.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		usersProtected.POST("/:id/follow", proxy.ReverseProxy(cfg.Services.UserService))
 		usersProtected.DELETE("/:id/follow", proxy.ReverseProxy(cfg.Services.UserService))
 	}
 
 	userGroup := v1.Group("/user")
-	userGroup.Use(authMiddleware.ValidateToken())
+	userGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		userGroup.GET("/profile", proxy.ReverseProxy(cfg.Services.UserService))
 		userGroup.PUT("/profile", proxy.ReverseProxy(cfg.Services.UserService))
@@ -162,7 +268,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 		// Protected review endpoints
 		courseProtected := courseGroup.Group("")
-		courseProtected.Use(authMiddleware.ValidateToken())
+		courseProtected.Use(authMiddleware.ValidateToken(), userLimit)
 		{
 			courseProtected.POST("/:id/enroll", proxy.ReverseProxy(cfg.Services.CourseService))
 			courseProtected.GET("/my-courses", proxy.ReverseProxy(cfg.Services.CourseService))
@@ -183,7 +289,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Video endpoints (protected)
 	videoGroup := v1.Group("/videos")
-	videoGroup.Use(authMiddleware.ValidateToken())
+	videoGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		videoGroup.POST("/track", proxy.ReverseProxy(cfg.Services.CourseService))        // Track video watch progress
 		videoGroup.GET("/history", proxy.ReverseProxy(cfg.Services.CourseService))       // Get watch history
@@ -192,14 +298,14 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Materials endpoints (protected)
 	materialGroup := v1.Group("/materials")
-	materialGroup.Use(authMiddleware.ValidateToken())
+	materialGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		materialGroup.POST("/:id/download", proxy.ReverseProxy(cfg.Services.CourseService)) // Record material download
 	}
 
 	// Enrollments endpoints (from Course Service)
 	enrollmentGroup := v1.Group("/enrollments")
-	enrollmentGroup.Use(authMiddleware.ValidateToken())
+	enrollmentGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		enrollmentGroup.POST("", proxy.ReverseProxy(cfg.Services.CourseService))
 		enrollmentGroup.GET("/my", proxy.ReverseProxy(cfg.Services.CourseService))
@@ -208,7 +314,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Progress endpoints (from Course Service)
 	progressGroup := v1.Group("/progress")
-	progressGroup.Use(authMiddleware.ValidateToken())
+	progressGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		progressGroup.GET("/lessons/:id", proxy.ReverseProxy(cfg.Services.CourseService)) // Get lesson progress (for resume watching)
 		progressGroup.PUT("/lessons/:id", proxy.ReverseProxy(cfg.Services.CourseService)) // Update lesson progress
@@ -226,7 +332,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 		// Protected (requires login)
 		exerciseProtected := exerciseGroup.Group("")
-		exerciseProtected.Use(authMiddleware.ValidateToken())
+		exerciseProtected.Use(authMiddleware.ValidateToken(), userLimit)
 		{
 			exerciseProtected.POST("/:id/start", proxy.ReverseProxy(cfg.Services.ExerciseService))
 		}
@@ -240,16 +346,44 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 
 	// Submissions (all protected)
 	submissionGroup := v1.Group("/submissions")
-	submissionGroup.Use(authMiddleware.ValidateToken())
+	submissionGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		submissionGroup.POST("", proxy.ReverseProxy(cfg.Services.ExerciseService))            // Start new submission
-		submissionGroup.POST("/:id/submit", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Unified submission (Phase 4)
+		submissionGroup.POST("/:id/submit", rateLimiter.Limit(config.PolicySubmission), proxy.ReverseProxy(cfg.Services.ExerciseService)) // Unified submission (Phase 4)
 		submissionGroup.PUT("/:id/answers", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Deprecated, use /submit
 		submissionGroup.GET("/:id/result", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		submissionGroup.GET("/:id/session", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Resume an attempt on any device
+		submissionGroup.PUT("/:id/draft", proxy.ReverseProxy(cfg.Services.ExerciseService))   // Autosave answers
 		submissionGroup.GET("/my", proxy.ReverseProxy(cfg.Services.ExerciseService))
 		submissionGroup.GET("", proxy.ReverseProxy(cfg.Services.ExerciseService)) // List my submissions (duplicate of /my)
 	}
 
+	// Mock tests: Listening, Reading, Writing and Speaking in one sitting
+	mockTestGroup := v1.Group("/mock-tests")
+	{
+		mockTestGroup.GET("", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		mockTestGroup.GET("/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		mockTestGroup.POST("/:id/sessions", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.ExerciseService)) // Start or resume a sitting
+	}
+
+	mockTestSessionGroup := v1.Group("/mock-test-sessions")
+	mockTestSessionGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		mockTestSessionGroup.GET("/my", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		mockTestSessionGroup.GET("/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		mockTestSessionGroup.POST("/:id/next", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Start the next stage
+	}
+
+	// Adaptive practice from the question bank
+	adaptiveGroup := v1.Group("/adaptive-practice")
+	adaptiveGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	{
+		adaptiveGroup.GET("/ability", proxy.ReverseProxy(cfg.Services.ExerciseService)) // Ability per question type
+		adaptiveGroup.POST("/sessions", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adaptiveGroup.GET("/sessions/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adaptiveGroup.POST("/sessions/:id/answers", proxy.ReverseProxy(cfg.Services.ExerciseService))
+	}
+
 	// ============================================
 		// STORAGE SERVICE
 		// ============================================
@@ -258,9 +392,9 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 			audio := storageGroup.Group("/audio")
 			{
 				// Protected routes (require auth)
-				audio.POST("/upload", authMiddleware.ValidateToken(), proxy.ReverseProxy(cfg.Services.ExerciseService))              // Upload audio (proxy to Storage Service)
-				audio.GET("/info/*object_name", authMiddleware.ValidateToken(), proxy.ReverseProxy(cfg.Services.ExerciseService))    // Get audio info
-				audio.GET("/presigned-url/*object_name", authMiddleware.ValidateToken(), proxy.ReverseProxy(cfg.Services.StorageService)) // Get presigned URL (direct to Storage Service)
+				audio.POST("/upload", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.ExerciseService))              // Upload audio (proxy to Storage Service)
+				audio.GET("/info/*object_name", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.ExerciseService))    // Get audio info
+				audio.GET("/presigned-url/*object_name", authMiddleware.ValidateToken(), userLimit, proxy.ReverseProxy(cfg.Services.StorageService)) // Get presigned URL (direct to Storage Service)
 				
 				// Public route (no auth required) - for HTML5 audio player
 				// NOTE: This is safe because audio files are already protected during upload
@@ -273,7 +407,7 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	// NOTIFICATION SERVICE - All protected
 	// ============================================
 	notificationGroup := v1.Group("/notifications")
-	notificationGroup.Use(authMiddleware.ValidateToken())
+	notificationGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		// SSE stream (must be before /:id to avoid route conflict)
 		notificationGroup.GET("/stream", proxy.ReverseProxy(cfg.Services.NotificationService))
@@ -309,11 +443,11 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	}
 
 	// ============================================
-	// ADMIN ROUTES - Require admin/instructor role
+	// ADMIN ROUTES - Require the permission declared in AdminPolicies
 	// ============================================
 	adminGroup := v1.Group("/admin")
-	adminGroup.Use(authMiddleware.ValidateToken())
-	adminGroup.Use(authMiddleware.RequireRole("instructor", "admin"))
+	adminGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	adminGroup.Use(authorizer.AuditForbidden(), authorizer.Enforce())
 	{
 		// Course management
 		adminGroup.POST("/courses", proxy.ReverseProxy(cfg.Services.CourseService))
@@ -353,16 +487,59 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 		// Tag management
 		adminGroup.POST("/tags", proxy.ReverseProxy(cfg.Services.ExerciseService))
 
+		// Mock test management
+		adminGroup.GET("/mock-tests", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/mock-tests", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.PUT("/mock-tests/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+
+		// Examiner review of writing and speaking submissions
+		adminGroup.GET("/reviews", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.GET("/reviews/:id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/reviews/:id/claim", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/reviews/:id/release", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.PUT("/reviews/:id/scores", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/reviews/:id/comments", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.DELETE("/reviews/:id/comments/:comment_id", proxy.ReverseProxy(cfg.Services.ExerciseService))
+		adminGroup.POST("/reviews/:id/publish", proxy.ReverseProxy(cfg.Services.ExerciseService))
+
 		// Notification management
 		adminGroup.POST("/notifications", proxy.ReverseProxy(cfg.Services.NotificationService))
 		adminGroup.POST("/notifications/bulk", proxy.ReverseProxy(cfg.Services.NotificationService))
+
+		// Two-factor authentication policies
+		adminGroup.GET("/mfa/policies", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.PUT("/mfa/policies/:role", proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// Account lockouts
+		adminGroup.POST("/users/:id/unlock", proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// User management and impersonation
+		adminGroup.GET("/users", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.GET("/users/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.POST("/users/:id/suspend", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.POST("/users/:id/reactivate", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.POST("/users/roles", proxy.ReverseProxy(cfg.Services.AuthService)) // Bulk assign or revoke
+		adminGroup.POST("/users/:id/impersonate", proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// Audit log
+		adminGroup.GET("/audit-logs", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.GET("/audit-logs/export", proxy.ReverseProxy(cfg.Services.AuthService)) // CSV or JSON download
+
+		// Personal data exports and account deletions
+		adminGroup.GET("/data-requests", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.GET("/data-requests/:id", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.POST("/data-requests/:id/retry", proxy.ReverseProxy(cfg.Services.AuthService))
+
+		// Organizations
+		adminGroup.GET("/organizations", proxy.ReverseProxy(cfg.Services.AuthService))
+		adminGroup.POST("/organizations", proxy.ReverseProxy(cfg.Services.AuthService))
 	}
 
 	// ============================================
 	// AI SERVICE - Protected (auth required)
 	// ============================================
 	aiGroup := v1.Group("/ai")
-	aiGroup.Use(authMiddleware.ValidateToken())
+	aiGroup.Use(authMiddleware.ValidateToken(), userLimit)
 	{
 		// Writing endpoints
 		aiGroup.POST("/writing/submit", proxy.ReverseProxy(cfg.Services.AIService))
@@ -380,11 +557,11 @@ func SetupRoutes(r *gin.Engine, cfg *config.Config, authMiddleware *middleware.A
 	}
 
 	// ============================================
-	// ADMIN AI ROUTES - Require admin role
+	// ADMIN AI ROUTES - Require ai_prompt:manage (admins only by default)
 	// ============================================
 	adminAIGroup := v1.Group("/admin/ai")
-	adminAIGroup.Use(authMiddleware.ValidateToken())
-	adminAIGroup.Use(authMiddleware.RequireRole("admin"))
+	adminAIGroup.Use(authMiddleware.ValidateToken(), userLimit)
+	adminAIGroup.Use(authorizer.AuditForbidden(), authorizer.Enforce())
 	{
 		// Writing prompts management
 		adminAIGroup.POST("/writing/prompts", proxy.ReverseProxy(cfg.Services.AIService))
@@ -45,13 +45,14 @@ CREATE TABLE users (
     email VARCHAR(255) NOT NULL,
     password_hash VARCHAR(255), -- NULL for OAuth users
     phone VARCHAR(20),
-    google_id VARCHAR(255), -- For Google OAuth
-    oauth_provider VARCHAR(50), -- 'google', 'facebook', etc.
+    google_id VARCHAR(255), -- Legacy Google link; copied into user_identities on startup
+    oauth_provider VARCHAR(50), -- Provider the account was created with: 'google', 'microsoft', etc.
     is_active BOOLEAN DEFAULT true,
     is_verified BOOLEAN DEFAULT false,
     email_verified_at TIMESTAMP,
     failed_login_attempts INTEGER DEFAULT 0,
     locked_until TIMESTAMP, -- Account lockout timestamp
+    lockout_count INTEGER DEFAULT 0, -- Consecutive lockouts; each one doubles the next lock
     last_login_at TIMESTAMP,
     last_login_ip VARCHAR(45), -- Supports both IPv4 and IPv6
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
@@ -76,6 +77,7 @@ CREATE TABLE roles (
     name VARCHAR(50) UNIQUE NOT NULL, -- e.g., 'student', 'instructor', 'admin'
     display_name VARCHAR(100) NOT NULL, -- Localized display name
     description TEXT,
+    mfa_required BOOLEAN DEFAULT false, -- Members must enrol in two-factor authentication
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -137,6 +139,9 @@ CREATE TABLE refresh_tokens (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the refresh token
+    family_id UUID NOT NULL, -- Session: every token rotated from the same sign-in
+    replaced_by UUID REFERENCES refresh_tokens(id) ON DELETE SET NULL, -- Token issued when this one was used
+    rotated_at TIMESTAMP, -- When this token was used and replaced; presenting it again revokes the family
     device_id VARCHAR(255), -- Unique device identifier
     device_name VARCHAR(100), -- User-friendly device name
     device_type VARCHAR(50), -- 'mobile', 'desktop', 'tablet'
@@ -153,6 +158,7 @@ CREATE TABLE refresh_tokens (
 CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
 CREATE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
 CREATE INDEX idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
 
 -- ----------------------------------------------------------------------------
 -- Password Reset Tokens Table
@@ -163,6 +169,7 @@ CREATE TABLE password_reset_tokens (
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the reset token
     code VARCHAR(6), -- Optional 6-digit verification code
+    failed_attempts INTEGER NOT NULL DEFAULT 0, -- Wrong codes entered; the code is invalidated after a few
     expires_at TIMESTAMP NOT NULL,
     used_at TIMESTAMP, -- When token was used
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -181,6 +188,7 @@ CREATE TABLE email_verification_tokens (
     user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the verification token
     code VARCHAR(6), -- Optional 6-digit verification code
+    failed_attempts INTEGER NOT NULL DEFAULT 0, -- Wrong codes entered; the code is invalidated after a few
     expires_at TIMESTAMP NOT NULL,
     verified_at TIMESTAMP, -- When email was verified
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -190,6 +198,113 @@ CREATE INDEX idx_email_verification_user_id ON email_verification_tokens(user_id
 CREATE INDEX idx_email_verification_token_hash ON email_verification_tokens(token_hash);
 CREATE INDEX idx_email_verification_code ON email_verification_tokens(code) WHERE verified_at IS NULL;
 
+-- ----------------------------------------------------------------------------
+-- Login Codes Table
+-- ----------------------------------------------------------------------------
+-- Single-use codes and magic links for passwordless sign-in
+CREATE TABLE login_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the magic link token
+    code VARCHAR(6) NOT NULL, -- 6-digit code sent with the link
+    failed_attempts INTEGER NOT NULL DEFAULT 0, -- Wrong codes entered; the code is invalidated after a few
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP, -- When the code signed the user in
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_login_codes_user_id ON login_codes(user_id);
+CREATE INDEX idx_login_codes_token_hash ON login_codes(token_hash);
+
+-- ----------------------------------------------------------------------------
+-- Email Change Requests Table
+-- ----------------------------------------------------------------------------
+-- A new address awaiting its verification code; the old address gets a link to cancel
+CREATE TABLE email_change_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email VARCHAR(255) NOT NULL,
+    code VARCHAR(6) NOT NULL, -- 6-digit code sent to the new address
+    cancel_token_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the token in the link sent to the old address
+    failed_attempts INTEGER NOT NULL DEFAULT 0, -- Wrong codes entered; the request is invalidated after a few
+    expires_at TIMESTAMP NOT NULL,
+    confirmed_at TIMESTAMP, -- When the email was swapped
+    cancelled_at TIMESTAMP, -- When the old address cancelled the change
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_email_change_requests_user_id ON email_change_requests(user_id);
+CREATE INDEX idx_email_change_requests_cancel_token_hash ON email_change_requests(cancel_token_hash);
+
+-- ----------------------------------------------------------------------------
+-- User Identities Table
+-- ----------------------------------------------------------------------------
+-- Accounts at OpenID Connect providers (Google, Microsoft, Keycloak, ...)
+-- linked to a user. subject is the provider's stable "sub" claim.
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255), -- Email at the provider when the identity was linked
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_user_identities_provider_subject ON user_identities(provider, subject);
+CREATE UNIQUE INDEX idx_user_identities_user_provider ON user_identities(user_id, provider);
+
+-- ----------------------------------------------------------------------------
+-- Signing Keys Table
+-- ----------------------------------------------------------------------------
+-- Asymmetric keys for access tokens, published on /.well-known/jwks.json.
+-- A key signs between not_before and signing_until and stays in the JWKS
+-- until verify_until, so tokens it signed remain valid after rotation.
+CREATE TABLE signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(10) NOT NULL, -- 'RS256' or 'EdDSA'
+    private_key TEXT NOT NULL, -- PKCS#8, AES-GCM encrypted, base64
+    public_key TEXT NOT NULL, -- PKIX PEM
+    not_before TIMESTAMP NOT NULL,
+    signing_until TIMESTAMP NOT NULL,
+    verify_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_signing_keys_verify_until ON signing_keys(verify_until);
+
+-- ============================================================================
+-- MULTI-FACTOR AUTHENTICATION
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- User MFA Table
+-- ----------------------------------------------------------------------------
+-- TOTP enrolment per user. A row with enabled_at NULL is an enrolment that
+-- has not been confirmed with a code yet and is not enforced at login.
+CREATE TABLE user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    totp_secret TEXT NOT NULL, -- Base32 secret, AES-GCM encrypted, base64
+    enabled_at TIMESTAMP, -- When enrolment was confirmed
+    last_used_step BIGINT, -- Last accepted TOTP time step, prevents code replay
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ----------------------------------------------------------------------------
+-- MFA Recovery Codes Table
+-- ----------------------------------------------------------------------------
+-- One-time codes for signing in without the authenticator device
+CREATE TABLE mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(255) NOT NULL, -- SHA-256 hash of the recovery code
+    used_at TIMESTAMP, -- When the code was used
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id) WHERE used_at IS NULL;
+
 -- ============================================================================
 -- AUDIT AND LOGGING
 -- ============================================================================
@@ -214,6 +329,158 @@ CREATE TABLE audit_logs (
 CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id);
 CREATE INDEX idx_audit_logs_event_type ON audit_logs(event_type);
 CREATE INDEX idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX idx_audit_logs_ip_address ON audit_logs(ip_address);
+
+-- ----------------------------------------------------------------------------
+-- Audit Logs Archive Table
+-- ----------------------------------------------------------------------------
+-- Entries older than AUDIT_LOG_RETENTION, moved here by the retention job so
+-- audit_logs stays small. Still searchable by admins with ?archived=true.
+CREATE TABLE audit_logs_archive (
+    id BIGINT PRIMARY KEY, -- id the entry had in audit_logs
+    user_id UUID, -- no foreign key: archived entries outlive their users
+    event_type VARCHAR(50) NOT NULL,
+    event_status VARCHAR(20) NOT NULL,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    device_info JSONB,
+    metadata JSONB,
+    error_message TEXT,
+    created_at TIMESTAMP,
+    archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_logs_archive_user_id ON audit_logs_archive(user_id);
+CREATE INDEX idx_audit_logs_archive_created_at ON audit_logs_archive(created_at);
+
+-- ============================================================================
+-- PERSONAL DATA REQUESTS
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- Account Data Requests Table
+-- ----------------------------------------------------------------------------
+-- Personal data exports and account deletions. A deletion waits until
+-- scheduled_for (the grace period) and can be cancelled until then. The
+-- background worker leases a due request with locked_until while it runs.
+CREATE TABLE account_data_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    request_type VARCHAR(20) NOT NULL, -- 'export', 'deletion'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'completed', 'failed', 'cancelled'
+    scheduled_for TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, -- When the worker runs it next
+    attempts INTEGER NOT NULL DEFAULT 0, -- Runs that left a step failed
+    locked_until TIMESTAMP,
+    archive BYTEA, -- Zip of an export, dropped when it expires
+    archive_expires_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX idx_account_data_requests_user_id ON account_data_requests(user_id);
+CREATE INDEX idx_account_data_requests_due ON account_data_requests(scheduled_for) WHERE status = 'pending';
+-- One open request of each type per user
+CREATE UNIQUE INDEX idx_account_data_requests_open ON account_data_requests(user_id, request_type) WHERE status = 'pending';
+
+-- ----------------------------------------------------------------------------
+-- Account Data Request Steps Table
+-- ----------------------------------------------------------------------------
+-- One step per service holding the user's data, so a failed service is
+-- retried without repeating the ones that succeeded
+CREATE TABLE account_data_request_steps (
+    request_id UUID NOT NULL REFERENCES account_data_requests(id) ON DELETE CASCADE,
+    service VARCHAR(50) NOT NULL, -- 'auth', 'user', 'course', 'exercise', 'notification', 'storage'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'completed', 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    output JSONB, -- Export: the data the service returned
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (request_id, service)
+);
+
+-- ============================================================================
+-- ORGANIZATIONS AND CLASSROOMS
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- Organizations Table
+-- ----------------------------------------------------------------------------
+-- Language centres and schools using the platform with their own learners
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(200) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ----------------------------------------------------------------------------
+-- Organization Members Table
+-- ----------------------------------------------------------------------------
+-- A member's role inside one organization, independent of their platform roles
+-- in user_roles: 'admin' manages members, 'instructor' runs classrooms
+CREATE TABLE organization_members (
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id),
+    added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+-- ----------------------------------------------------------------------------
+-- Classrooms Table
+-- ----------------------------------------------------------------------------
+-- A cohort inside an organization. Students join with the invite code.
+CREATE TABLE classrooms (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    description TEXT,
+    invite_code VARCHAR(16) UNIQUE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_classrooms_organization_id ON classrooms(organization_id);
+
+-- ----------------------------------------------------------------------------
+-- Classroom Members Table
+-- ----------------------------------------------------------------------------
+-- Teachers ('instructor' role) and students of a classroom
+CREATE TABLE classroom_members (
+    classroom_id UUID NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id),
+    added_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (classroom_id, user_id)
+);
+
+CREATE INDEX idx_classroom_members_user_id ON classroom_members(user_id);
+
+-- ----------------------------------------------------------------------------
+-- Classroom Invites Table
+-- ----------------------------------------------------------------------------
+-- Emails invited to a classroom before they had an account. The invite is
+-- accepted when that address joins the classroom.
+CREATE TABLE classroom_invites (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    classroom_id UUID NOT NULL REFERENCES classrooms(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    accepted_at TIMESTAMP,
+    UNIQUE (classroom_id, email)
+);
+
+CREATE INDEX idx_classroom_invites_email ON classroom_invites(email) WHERE accepted_at IS NULL;
 
 -- ============================================================================
 -- FUNCTIONS AND TRIGGERS
@@ -241,6 +508,26 @@ CREATE TRIGGER update_roles_updated_at
     FOR EACH ROW
     EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_user_mfa_updated_at
+    BEFORE UPDATE ON user_mfa
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_account_data_requests_updated_at
+    BEFORE UPDATE ON account_data_requests
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_organizations_updated_at
+    BEFORE UPDATE ON organizations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_classrooms_updated_at
+    BEFORE UPDATE ON classrooms
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
 -- ----------------------------------------------------------------------------
 -- Cleanup expired tokens
 -- ----------------------------------------------------------------------------
@@ -257,6 +544,18 @@ BEGIN
     -- Delete verified/expired email verification tokens
     DELETE FROM email_verification_tokens
     WHERE expires_at < CURRENT_TIMESTAMP OR verified_at IS NOT NULL;
+
+    -- Delete used/expired login codes
+    DELETE FROM login_codes
+    WHERE expires_at < CURRENT_TIMESTAMP OR used_at IS NOT NULL;
+
+    -- Delete finished/expired email changes
+    DELETE FROM email_change_requests
+    WHERE expires_at < CURRENT_TIMESTAMP OR confirmed_at IS NOT NULL OR cancelled_at IS NOT NULL;
+
+    -- Drop expired export archives
+    UPDATE account_data_requests SET archive = NULL
+    WHERE archive IS NOT NULL AND archive_expires_at < CURRENT_TIMESTAMP;
 END;
 $$ LANGUAGE plpgsql;
 
@@ -307,6 +606,51 @@ INSERT INTO role_permissions (role_id, permission_id) VALUES
 INSERT INTO role_permissions (role_id, permission_id) VALUES
     (3, 1), (3, 2), (3, 3), (3, 4), (3, 5), (3, 6), (3, 7), (3, 8), (3, 9), (3, 10);
 
+-- ----------------------------------------------------------------------------
+-- Route-Level Permissions (resource:action)
+-- ----------------------------------------------------------------------------
+-- Enforced by the API gateway's /admin route policies and embedded in JWTs
+INSERT INTO permissions (name, resource, action, description) VALUES
+    ('course:create', 'courses', 'create', 'Create courses'),
+    ('course:update', 'courses', 'update', 'Update courses'),
+    ('course:delete', 'courses', 'delete', 'Delete courses'),
+    ('course:publish', 'courses', 'publish', 'Publish courses'),
+    ('course_content:manage', 'course_content', 'manage', 'Create modules, lessons and videos'),
+    ('exercise:create', 'exercises', 'create', 'Create exercises and sections'),
+    ('exercise:update', 'exercises', 'update', 'Update exercises and their tags'),
+    ('exercise:delete', 'exercises', 'delete', 'Delete exercises'),
+    ('exercise:publish', 'exercises', 'publish', 'Publish and unpublish exercises'),
+    ('exercise:analytics', 'exercises', 'read', 'View exercise analytics'),
+    ('question:manage', 'questions', 'manage', 'Create questions, options and answers'),
+    ('question_bank:read', 'question_bank', 'read', 'Browse the question bank'),
+    ('question_bank:manage', 'question_bank', 'manage', 'Create, update and delete question bank entries'),
+    ('tag:manage', 'tags', 'manage', 'Create exercise tags'),
+    ('notification:send', 'notifications', 'create', 'Send notifications to users'),
+    ('ai_prompt:manage', 'ai_prompts', 'manage', 'Manage writing and speaking prompts'),
+    ('mfa_policy:manage', 'mfa_policies', 'manage', 'Require two-factor authentication for roles'),
+    ('user:unlock', 'users', 'unlock', 'Unlock accounts locked after failed sign-ins'),
+    ('data_request:manage', 'data_requests', 'manage', 'View and retry personal data exports and account deletions'),
+    ('user:read', 'users', 'read', 'Search user accounts and view their roles'),
+    ('user:manage', 'users', 'manage', 'Suspend and reactivate accounts and change their roles'),
+    ('user:impersonate', 'users', 'impersonate', 'Act as a user with a short-lived token to reproduce issues'),
+    ('audit_log:read', 'audit_logs', 'read', 'Search and export the audit log'),
+    ('organization:manage', 'organizations', 'manage', 'Create organizations and appoint their admins'),
+    ('submission:review', 'submissions', 'review', 'Review AI-evaluated writing and speaking and publish the final band');
+
+-- Instructors manage content; prompts and security settings stay admin-only
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'instructor' AND p.name LIKE '%:%'
+  AND p.name NOT IN ('ai_prompt:manage', 'mfa_policy:manage', 'user:unlock', 'data_request:manage',
+                     'user:read', 'user:manage', 'user:impersonate', 'audit_log:read',
+                     'organization:manage');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id
+FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name LIKE '%:%';
+
 -- ============================================================================
 -- SCHEMA MIGRATIONS TRACKING
 -- ============================================================================
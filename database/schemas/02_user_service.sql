@@ -159,6 +159,7 @@ CREATE TABLE practice_activities (
     -- Exercise reference
     exercise_id UUID,
     exercise_title VARCHAR(255),
+    source_id UUID, -- Originating record, e.g. the exercise attempt (for score corrections)
     
     -- Scoring
     score NUMERIC(5,2),
@@ -193,6 +194,38 @@ CREATE INDEX idx_practice_activities_user_skill ON practice_activities(user_id,
 CREATE INDEX idx_practice_activities_user_completed ON practice_activities(user_id, completed_at DESC);
 CREATE INDEX idx_practice_activities_completed_at ON practice_activities(completed_at DESC);
 CREATE INDEX idx_practice_activities_exercise_id ON practice_activities(exercise_id);
+CREATE INDEX idx_practice_activities_source_id ON practice_activities(source_id) WHERE source_id IS NOT NULL;
+
+-- ----------------------------------------------------------------------------
+-- Test Result Sets Table
+-- ----------------------------------------------------------------------------
+-- One four-skill sitting (e.g. an exercise-service mock test); its per-skill
+-- results are official_test_results rows with this test_set_id
+CREATE TABLE test_result_sets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES user_profiles(user_id) ON DELETE CASCADE,
+    test_type VARCHAR(20) NOT NULL CHECK (test_type IN ('full_test', 'mock_test')),
+    ielts_variant VARCHAR(20) NOT NULL CHECK (ielts_variant IN ('academic', 'general_training')),
+    test_date TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    listening_band NUMERIC(3,1) NOT NULL,
+    reading_band NUMERIC(3,1) NOT NULL,
+    writing_band NUMERIC(3,1) NOT NULL,
+    speaking_band NUMERIC(3,1) NOT NULL,
+    overall_band NUMERIC(3,1) NOT NULL CHECK (overall_band >= 0 AND overall_band <= 9),
+
+    -- Combined score report from the source service, shown as sent
+    score_report JSONB,
+
+    source_service VARCHAR(50) NOT NULL, -- 'exercise_service'
+    source_id UUID NOT NULL, -- e.g. mock_test_sessions.id
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (source_service, source_id)
+);
+
+CREATE INDEX idx_test_result_sets_user_date ON test_result_sets(user_id, test_date DESC);
 
 -- ----------------------------------------------------------------------------
 -- Official Test Results Table
@@ -224,6 +257,9 @@ CREATE TABLE official_test_results (
     
     -- IELTS variant (for reading only)
     ielts_variant VARCHAR(20) CHECK (ielts_variant IS NULL OR ielts_variant IN ('academic', 'general_training')),
+
+    -- Set of results from one four-skill sitting
+    test_set_id UUID REFERENCES test_result_sets(id) ON DELETE CASCADE,
     
     -- Ensure reading tests have IELTS variant specified
     CONSTRAINT official_test_results_reading_variant_rule CHECK (
@@ -238,6 +274,7 @@ CREATE INDEX idx_official_test_results_test_date ON official_test_results(test_d
 CREATE INDEX idx_official_test_results_user_skill_date ON official_test_results(user_id, skill_type, test_date DESC);
 CREATE INDEX idx_official_test_results_variant ON official_test_results(skill_type, ielts_variant) WHERE ielts_variant IS NOT NULL;
 CREATE INDEX idx_official_test_results_source ON official_test_results(source_service, source_id) WHERE source_id IS NOT NULL;
+CREATE INDEX idx_official_test_results_test_set ON official_test_results(test_set_id) WHERE test_set_id IS NOT NULL;
 CREATE UNIQUE INDEX idx_official_test_results_source_unique ON official_test_results(source_service, source_table, source_id) WHERE source_id IS NOT NULL;
 
 -- ============================================================================
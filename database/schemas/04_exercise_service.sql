@@ -176,7 +176,7 @@ CREATE TABLE questions (
     section_id UUID REFERENCES exercise_sections(id) ON DELETE CASCADE,
     question_number INTEGER NOT NULL,
     question_text TEXT NOT NULL,
-    question_type VARCHAR(50) NOT NULL, -- 'multiple_choice', 'fill_in_blank', 'matching', 'true_false', 'short_answer', 'essay'
+    question_type VARCHAR(50) NOT NULL, -- 'multiple_choice', 'multiple_select', 'true_false_not_given', 'matching_headings', 'map_labeling', 'sentence_completion', 'short_answer', 'essay', ... (graders in internal/grading)
     
     -- Media
     audio_url TEXT,
@@ -226,7 +226,7 @@ CREATE TABLE question_answers (
     question_id UUID NOT NULL REFERENCES questions(id) ON DELETE CASCADE,
     answer_text TEXT NOT NULL,
     answer_variations TEXT[], -- Alternative accepted answers
-    match_left TEXT, -- For matching questions: left side
+    match_left TEXT, -- Item, gap or map position this answers on questions with several
     match_right TEXT, -- For matching questions: right side
     is_primary_answer BOOLEAN DEFAULT true,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
@@ -234,6 +234,120 @@ CREATE TABLE question_answers (
 
 CREATE INDEX idx_question_answers_question_id ON question_answers(question_id);
 
+-- ============================================================================
+-- CLASSROOM ASSIGNMENTS
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- Assignments Table
+-- ----------------------------------------------------------------------------
+-- An exercise or lesson a teacher sets for a classroom (auth_db.classrooms)
+CREATE TABLE assignments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    classroom_id UUID NOT NULL, -- Reference to auth_db.classrooms.id
+    title VARCHAR(200) NOT NULL,
+    instructions TEXT,
+
+    -- What to do: an exercise of this service or a lesson of course_db
+    item_type VARCHAR(20) NOT NULL CHECK (item_type IN ('exercise', 'lesson')),
+    exercise_id UUID REFERENCES exercises(id) ON DELETE CASCADE,
+    lesson_id UUID, -- Reference to course_db.lessons.id
+    course_id UUID, -- Course of the lesson, for links
+    item_title VARCHAR(255) NOT NULL, -- Title of the exercise or lesson when assigned
+
+    -- Who: NULL assigns every student of the classroom, including later joiners
+    student_ids UUID[],
+
+    -- When and how often
+    open_at TIMESTAMP NOT NULL,
+    due_at TIMESTAMP NOT NULL,
+    max_attempts INTEGER CHECK (max_attempts IS NULL OR max_attempts > 0), -- NULL = unlimited (exercises only)
+    late_policy VARCHAR(20) NOT NULL DEFAULT 'allow', -- 'allow', 'penalty', 'block'
+    late_penalty_percent INTEGER NOT NULL DEFAULT 0 CHECK (late_penalty_percent BETWEEN 0 AND 100),
+
+    due_reminder_sent_at TIMESTAMP,
+    created_by UUID NOT NULL, -- Reference to auth_db.users.id
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (late_policy IN ('allow', 'penalty', 'block')),
+    CHECK ((item_type = 'exercise') = (exercise_id IS NOT NULL)),
+    CHECK ((item_type = 'lesson') = (lesson_id IS NOT NULL)),
+    CHECK (due_at > open_at)
+);
+
+CREATE INDEX idx_assignments_classroom_id ON assignments(classroom_id, due_at);
+CREATE INDEX idx_assignments_exercise_id ON assignments(exercise_id) WHERE exercise_id IS NOT NULL;
+CREATE INDEX idx_assignments_due_reminder ON assignments(due_at) WHERE due_reminder_sent_at IS NULL;
+
+-- ============================================================================
+-- MOCK TESTS
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- Mock Tests Table
+-- ----------------------------------------------------------------------------
+-- A full IELTS sitting: one exercise per skill, taken in order with official timings
+CREATE TABLE mock_tests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    title VARCHAR(255) NOT NULL,
+    description TEXT,
+    ielts_test_type VARCHAR(20) NOT NULL CHECK (ielts_test_type IN ('academic', 'general_training')),
+
+    listening_exercise_id UUID NOT NULL REFERENCES exercises(id),
+    reading_exercise_id UUID NOT NULL REFERENCES exercises(id),
+    writing_exercise_id UUID NOT NULL REFERENCES exercises(id),
+    speaking_exercise_id UUID NOT NULL REFERENCES exercises(id),
+
+    -- Break between Writing and Speaking; Listening, Reading and Writing run back to back
+    speaking_break_minutes INTEGER NOT NULL DEFAULT 10 CHECK (speaking_break_minutes >= 0),
+
+    is_published BOOLEAN DEFAULT false,
+    created_by UUID NOT NULL, -- Reference to auth_db.users.id
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_mock_tests_published ON mock_tests(created_at DESC) WHERE is_published = true;
+
+-- ----------------------------------------------------------------------------
+-- Mock Test Sessions Table
+-- ----------------------------------------------------------------------------
+-- A learner's sitting of a mock test; its stage attempts link back with
+-- user_exercise_attempts.mock_test_session_id
+CREATE TABLE mock_test_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    mock_test_id UUID NOT NULL REFERENCES mock_tests(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress'
+        CHECK (status IN ('in_progress', 'awaiting_results', 'completed', 'incomplete')),
+    current_stage INTEGER NOT NULL DEFAULT 0 CHECK (current_stage BETWEEN 0 AND 3), -- 0 listening .. 3 speaking
+
+    -- Results, once every stage has a band
+    listening_band NUMERIC(3,1),
+    reading_band NUMERIC(3,1),
+    writing_band NUMERIC(3,1),
+    speaking_band NUMERIC(3,1),
+    overall_band NUMERIC(3,1),
+    score_report JSONB, -- Combined score report
+
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+
+    -- Recording of the result set in user-service
+    user_service_synced_at TIMESTAMP,
+    user_service_sync_attempts INTEGER DEFAULT 0,
+    user_service_sync_error TEXT,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_mock_test_sessions_user ON mock_test_sessions(user_id, started_at DESC);
+CREATE INDEX idx_mock_test_sessions_to_settle ON mock_test_sessions(updated_at)
+    WHERE status IN ('in_progress', 'awaiting_results')
+       OR (status = 'completed' AND user_service_synced_at IS NULL);
+
 -- ============================================================================
 -- USER ATTEMPT TABLES
 -- ============================================================================
@@ -248,7 +362,7 @@ CREATE TABLE user_exercise_attempts (
     attempt_number INTEGER DEFAULT 1,
     status VARCHAR(20) DEFAULT 'in_progress', -- 'in_progress', 'completed', 'abandoned'
     
-    -- Scoring
+    -- Scoring (counts are answer-sheet question numbers, the raw score for band conversion)
     total_questions INTEGER NOT NULL,
     questions_answered INTEGER DEFAULT 0,
     correct_answers INTEGER DEFAULT 0,
@@ -260,6 +374,14 @@ CREATE TABLE user_exercise_attempts (
     time_spent_seconds INTEGER DEFAULT 0,
     started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     completed_at TIMESTAMP,
+    deadline_at TIMESTAMP, -- Server-owned deadline of timed attempts; answers are rejected after it plus a grace period
+    section_deadlines JSONB, -- {section_id: deadline} for sections with their own time limit
+    auto_submitted_at TIMESTAMP, -- Set when the expiry sweeper submitted the attempt
+    
+    -- Autosaved draft, not graded until the attempt is submitted
+    draft_answers JSONB, -- {question_id: answer} as sent to /submit
+    draft_writing JSONB, -- Writing essay draft
+    draft_saved_at TIMESTAMP,
     
     -- Metadata
     device_type VARCHAR(20),
@@ -297,7 +419,13 @@ CREATE TABLE user_exercise_attempts (
     user_service_sync_status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'synced', 'failed'
     user_service_sync_attempts INTEGER DEFAULT 0,
     user_service_last_sync_attempt TIMESTAMP,
-    user_service_sync_error TEXT
+    user_service_sync_error TEXT,
+
+    -- Classroom assignment the attempt was started for
+    assignment_id UUID REFERENCES assignments(id) ON DELETE SET NULL,
+
+    -- Mock test sitting the attempt is a stage of; recorded in user-service with the sitting
+    mock_test_session_id UUID REFERENCES mock_test_sessions(id) ON DELETE SET NULL
 );
 
 CREATE INDEX idx_user_exercise_attempts_user_id ON user_exercise_attempts(user_id);
@@ -309,8 +437,14 @@ CREATE INDEX idx_user_exercise_attempts_evaluation_status ON user_exercise_attem
     WHERE evaluation_status IN ('pending', 'processing');
 CREATE INDEX idx_user_exercise_attempts_is_official ON user_exercise_attempts(is_official_test) 
     WHERE is_official_test = true;
+CREATE INDEX idx_user_exercise_attempts_assignment_id ON user_exercise_attempts(assignment_id, user_id)
+    WHERE assignment_id IS NOT NULL;
+CREATE INDEX idx_user_exercise_attempts_mock_test_session ON user_exercise_attempts(mock_test_session_id)
+    WHERE mock_test_session_id IS NOT NULL;
 CREATE INDEX idx_user_exercise_attempts_official_test_result_id ON user_exercise_attempts(official_test_result_id) 
     WHERE official_test_result_id IS NOT NULL;
+CREATE INDEX idx_user_exercise_attempts_deadline ON user_exercise_attempts(deadline_at)
+    WHERE status = 'in_progress' AND deadline_at IS NOT NULL;
 
 -- ----------------------------------------------------------------------------
 -- User Answers Table
@@ -325,10 +459,15 @@ CREATE TABLE user_answers (
     answer_text TEXT, -- For text-based answers
     selected_option_id UUID, -- For single choice (references question_options.id)
     selected_options UUID[], -- For multiple choice
+    answer_data JSONB, -- {"matches": {item: answer}} or {"blanks": {gap: answer}} for multi-part questions
     
     -- Grading
     is_correct BOOLEAN,
     points_earned NUMERIC(5,2) DEFAULT 0,
+    -- Answer-sheet question numbers: one per gap, item or letter on multi-part questions
+    marks_earned INTEGER,
+    max_marks INTEGER,
+    part_results JSONB, -- [{"part": "5", "answer": "...", "is_correct": true}] for multi-part questions
     time_spent_seconds INTEGER,
     
     -- Metadata
@@ -346,6 +485,71 @@ CREATE INDEX idx_user_answers_attempt_id ON user_answers(attempt_id);
 CREATE INDEX idx_user_answers_question_id ON user_answers(question_id);
 CREATE INDEX idx_user_answers_user_id ON user_answers(user_id);
 
+-- ============================================================================
+-- EXAMINER REVIEW
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- Submission Reviews Table
+-- ----------------------------------------------------------------------------
+-- An instructor's review of an AI-evaluated writing or speaking attempt.
+-- The AI result stays on the attempt until the review is published.
+CREATE TABLE submission_reviews (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    attempt_id UUID NOT NULL UNIQUE REFERENCES user_exercise_attempts(id) ON DELETE CASCADE,
+    skill_type VARCHAR(20) NOT NULL CHECK (skill_type IN ('writing', 'speaking')),
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- 'queued', 'claimed', 'published'
+
+    reviewer_id UUID, -- Reference to auth_db.users.id
+    claimed_at TIMESTAMP,
+
+    -- AI result the review started from, and the reviewer's changes
+    ai_band_score NUMERIC(3,1),
+    ai_criteria_scores JSONB NOT NULL DEFAULT '{}',
+    criteria_overrides JSONB NOT NULL DEFAULT '{}', -- Only the criteria the reviewer changed
+    band_score NUMERIC(3,1) CHECK (band_score IS NULL OR (band_score >= 0 AND band_score <= 9)), -- Recalculated
+    reviewer_feedback TEXT,
+
+    published_at TIMESTAMP,
+    user_service_synced_at TIMESTAMP, -- Corrected band sent to user-service
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (status IN ('queued', 'claimed', 'published')),
+    CHECK (status = 'queued' OR reviewer_id IS NOT NULL)
+);
+
+CREATE INDEX idx_submission_reviews_queue ON submission_reviews(status, created_at) WHERE status <> 'published';
+CREATE INDEX idx_submission_reviews_reviewer ON submission_reviews(reviewer_id, status);
+CREATE INDEX idx_submission_reviews_unsynced ON submission_reviews(published_at)
+    WHERE status = 'published' AND user_service_synced_at IS NULL;
+
+-- ----------------------------------------------------------------------------
+-- Review Comments Table
+-- ----------------------------------------------------------------------------
+-- Inline comments on a span of the essay or a stretch of the recording
+CREATE TABLE review_comments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    review_id UUID NOT NULL REFERENCES submission_reviews(id) ON DELETE CASCADE,
+    author_id UUID NOT NULL, -- Reference to auth_db.users.id
+    anchor_type VARCHAR(20) NOT NULL, -- 'text_span', 'audio_timestamp', 'general'
+
+    start_offset INTEGER, -- Essay character offsets, end exclusive (text_span)
+    end_offset INTEGER,
+    quoted_text TEXT, -- The span when the comment was written
+    start_seconds NUMERIC(8,2), -- Recording position (audio_timestamp)
+    end_seconds NUMERIC(8,2),
+
+    comment TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CHECK (anchor_type IN ('text_span', 'audio_timestamp', 'general')),
+    CHECK (anchor_type <> 'text_span' OR (start_offset >= 0 AND end_offset > start_offset)),
+    CHECK (anchor_type <> 'audio_timestamp' OR (start_seconds >= 0 AND (end_seconds IS NULL OR end_seconds >= start_seconds)))
+);
+
+CREATE INDEX idx_review_comments_review_id ON review_comments(review_id, created_at);
+
 -- ============================================================================
 -- ANALYTICS AND METADATA
 -- ============================================================================
@@ -425,6 +629,13 @@ CREATE TABLE question_bank (
     is_verified BOOLEAN DEFAULT false,
     is_published BOOLEAN DEFAULT true,
     
+    -- Item response theory (2PL) parameters, calibrated from adaptive practice
+    -- responses; NULL until calibrated, when difficulty is taken from the label
+    irt_discrimination NUMERIC(5,3),
+    irt_difficulty NUMERIC(5,3),
+    irt_responses INTEGER NOT NULL DEFAULT 0, -- responses the parameters were fitted to
+    irt_calibrated_at TIMESTAMP,
+    
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
@@ -434,6 +645,66 @@ CREATE INDEX idx_question_bank_question_type ON question_bank(question_type);
 CREATE INDEX idx_question_bank_difficulty ON question_bank(difficulty);
 CREATE INDEX idx_question_bank_tags ON question_bank USING gin(tags);
 
+-- ============================================================================
+-- ADAPTIVE PRACTICE
+-- ============================================================================
+
+-- ----------------------------------------------------------------------------
+-- Adaptive Practice Sessions: bank questions picked one at a time to match
+-- the learner's estimated ability at a skill and question type
+-- ----------------------------------------------------------------------------
+CREATE TABLE adaptive_practice_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL,
+    skill_type VARCHAR(20) NOT NULL CHECK (skill_type IN ('listening', 'reading')),
+    question_type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed')),
+    stop_reason VARCHAR(20) CHECK (stop_reason IN ('converged', 'max_items', 'bank_exhausted')),
+    
+    -- Ability (theta) on the item logit scale: the prior estimated from
+    -- earlier answers, and the current estimate
+    prior_theta NUMERIC(6,3) NOT NULL,
+    prior_standard_error NUMERIC(6,3) NOT NULL,
+    prior_responses INTEGER NOT NULL DEFAULT 0,
+    theta NUMERIC(6,3) NOT NULL,
+    standard_error NUMERIC(6,3) NOT NULL,
+    items_answered INTEGER NOT NULL DEFAULT 0,
+    current_question_id UUID REFERENCES question_bank(id) ON DELETE SET NULL,
+    
+    -- Estimated band with a 95% confidence interval
+    estimated_band NUMERIC(2,1),
+    band_lower NUMERIC(2,1),
+    band_upper NUMERIC(2,1),
+    
+    started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_adaptive_sessions_user ON adaptive_practice_sessions(user_id, skill_type, question_type, started_at DESC);
+
+-- ----------------------------------------------------------------------------
+-- Adaptive Practice Responses: also the data bank questions are calibrated from
+-- ----------------------------------------------------------------------------
+CREATE TABLE adaptive_practice_responses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES adaptive_practice_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    bank_question_id UUID NOT NULL REFERENCES question_bank(id) ON DELETE CASCADE,
+    answer_data JSONB,
+    score NUMERIC(4,3) NOT NULL, -- share of the question's marks earned
+    is_correct BOOLEAN NOT NULL,
+    theta_before NUMERIC(6,3) NOT NULL,
+    theta_after NUMERIC(6,3) NOT NULL,
+    standard_error_after NUMERIC(6,3) NOT NULL,
+    answered_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (session_id, bank_question_id)
+);
+
+CREATE INDEX idx_adaptive_responses_question ON adaptive_practice_responses(bank_question_id);
+CREATE INDEX idx_adaptive_responses_user ON adaptive_practice_responses(user_id);
+
 -- ============================================================================
 -- MIGRATION TRACKING
 -- ============================================================================
@@ -517,6 +788,26 @@ CREATE TRIGGER update_user_answers_updated_at
     BEFORE UPDATE ON user_answers
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+CREATE TRIGGER update_assignments_updated_at
+    BEFORE UPDATE ON assignments
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_submission_reviews_updated_at
+    BEFORE UPDATE ON submission_reviews
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_mock_tests_updated_at
+    BEFORE UPDATE ON mock_tests
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_mock_test_sessions_updated_at
+    BEFORE UPDATE ON mock_test_sessions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_adaptive_practice_sessions_updated_at
+    BEFORE UPDATE ON adaptive_practice_sessions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 -- ----------------------------------------------------------------------------
 -- Auto-grade answer function
 -- ----------------------------------------------------------------------------
@@ -62,27 +62,34 @@ CREATE INDEX idx_notifications_scheduled_for ON notifications(scheduled_for) WHE
 CREATE TABLE email_notifications (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     notification_id UUID REFERENCES notifications(id) ON DELETE CASCADE,
-    user_id UUID NOT NULL,
+    user_id UUID, -- NULL for invitations to addresses without an account
     to_email VARCHAR(255) NOT NULL,
     subject VARCHAR(500) NOT NULL,
     body_html TEXT NOT NULL,
     body_text TEXT,
-    template_name VARCHAR(100), -- Reference to notification_templates
+    template_name VARCHAR(100), -- Email template the message was rendered from
+    template_version INTEGER, -- Version of that template
+    locale VARCHAR(10), -- 'vi', 'en'
     template_data JSONB, -- Variables for template
     status VARCHAR(20) DEFAULT 'pending', -- 'pending', 'sent', 'delivered', 'failed', 'bounced'
     sent_at TIMESTAMP,
     delivered_at TIMESTAMP,
     opened_at TIMESTAMP, -- Email opened tracking
     clicked_at TIMESTAMP, -- Link clicked tracking
-    external_id VARCHAR(255), -- ID from email service provider
+    bounced_at TIMESTAMP, -- Rejected permanently by the mail server
+    failed_at TIMESTAMP, -- Gave up after the last retry
+    external_id VARCHAR(255), -- Message-ID header of the sent message
     error_message TEXT,
     retry_count INTEGER DEFAULT 0,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- When the outbox worker picks it up next
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
 CREATE INDEX idx_email_notifications_user_id ON email_notifications(user_id);
 CREATE INDEX idx_email_notifications_to_email ON email_notifications(to_email);
 CREATE INDEX idx_email_notifications_status ON email_notifications(status);
+CREATE INDEX idx_email_notifications_due ON email_notifications(next_attempt_at) WHERE status = 'pending';
 
 -- ----------------------------------------------------------------------------
 -- Push Notifications Table
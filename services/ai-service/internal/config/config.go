@@ -18,7 +18,7 @@ type Config struct {
 
 	// Auth Service Integration
 	AuthServiceURL string
-	JWTSecret      string
+	JWKSURL        string
 
 	// Internal API Authentication
 	InternalAPIKey string
@@ -45,7 +45,7 @@ func LoadConfig() *Config {
 
 		// Auth Service
 		AuthServiceURL: getEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
-		JWTSecret:      getEnv("JWT_SECRET", "your_jwt_secret_key_minimum_32_characters_long"),
+		JWKSURL:        getEnv("JWKS_URL", "http://auth-service:8081/.well-known/jwks.json"),
 
 		// Internal API Authentication
 		InternalAPIKey: getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
@@ -36,7 +36,7 @@ func (h *AIHandler) EvaluateWriting(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.EvaluateWritingPure(req.EssayText, req.TaskType, req.PromptText)
+	result, err := h.service.EvaluateWritingPure(c.Request.Context(), req.EssayText, req.TaskType, req.PromptText)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -59,7 +59,7 @@ func (h *AIHandler) TranscribeSpeaking(c *gin.Context) {
 		return
 	}
 
-	transcript, err := h.service.TranscribeSpeakingPure(req.AudioURL)
+	transcript, err := h.service.TranscribeSpeakingPure(c.Request.Context(), req.AudioURL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -95,7 +95,7 @@ func (h *AIHandler) EvaluateSpeaking(c *gin.Context) {
 		wordCount = len(strings.Fields(req.TranscriptText))
 	}
 
-	result, err := h.service.EvaluateSpeakingPure(req.AudioURL, req.TranscriptText, req.PromptText, req.PartNumber, wordCount, req.Duration)
+	result, err := h.service.EvaluateSpeakingPure(c.Request.Context(), req.AudioURL, req.TranscriptText, req.PromptText, req.PartNumber, wordCount, req.Duration)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -6,18 +6,19 @@ import (
 	"strings"
 
 	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthMiddleware struct {
-	jwtSecret      string
+	keySet         *jwks.KeySet
 	internalAPIKey string
 }
 
 func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret:      cfg.JWTSecret,
+		keySet:         jwks.NewKeySet(cfg.JWKSURL),
 		internalAPIKey: cfg.InternalAPIKey,
 	}
 }
@@ -39,12 +40,7 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method")
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.Parse(tokenString, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
@@ -100,9 +96,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString != authHeader {
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				return []byte(m.jwtSecret), nil
-			})
+			token, err := jwt.Parse(tokenString, m.keySet.Keyfunc, jwks.ParserOptions()...)
 			if err == nil && token.Valid {
 				if claims, ok := token.Claims.(jwt.MapClaims); ok {
 					c.Set("user_id", claims["user_id"])
@@ -3,11 +3,15 @@ package routes
 import (
 	"github.com/bisosad1501/DATN/services/ai-service/internal/handlers"
 	"github.com/bisosad1501/DATN/services/ai-service/internal/middleware"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(handler *handlers.AIHandler, authMiddleware *middleware.AuthMiddleware, rateLimitMiddleware *middleware.RateLimitMiddleware) *gin.Engine {
 	router := gin.Default()
+	router.Use(tracing.Middleware("ai-service"), tracing.RequestID(), metrics.Middleware("ai-service"))
+	router.GET("/metrics", metrics.Handler())
 
 	// Health check
 	router.GET("/health", handler.HealthCheck)
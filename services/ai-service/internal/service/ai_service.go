@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"github.com/bisosad1501/DATN/services/ai-service/internal/config"
 	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
 	"github.com/bisosad1501/DATN/services/ai-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 )
 
 type AIService struct {
@@ -35,20 +37,24 @@ func NewAIService(repo *repository.AIRepository, cfg *config.Config) *AIService
 // All submission/prompt management moved to Exercise Service
 
 // EvaluateWritingPure evaluates writing without database operations (stateless with cache)
-func (s *AIService) EvaluateWritingPure(essayText, taskType, promptText string) (*models.OpenAIWritingEvaluation, error) {
+func (s *AIService) EvaluateWritingPure(ctx context.Context, essayText, taskType, promptText string) (result *models.OpenAIWritingEvaluation, err error) {
+	fromCache := false
+	defer func(start time.Time) { observeEvaluation("writing", start, fromCache, err) }(time.Now())
+
 	if essayText == "" {
 		return nil, fmt.Errorf("essay text is required")
 	}
 
 	// Check cache first
 	if cached, hit := s.cacheService.CheckWritingCache(essayText, taskType, promptText); hit {
+		fromCache = true
 		return cached, nil
 	}
 
 	wordCount := len(strings.Fields(essayText))
 
 	// Call OpenAI for evaluation (cache miss)
-	evalResult, err := s.openAIClient.EvaluateWriting(promptText, essayText, wordCount, 0)
+	evalResult, err := s.openAIClient.EvaluateWriting(ctx, promptText, essayText, wordCount, 0)
 	if err != nil {
 		return nil, fmt.Errorf("evaluation failed: %w", err)
 	}
@@ -60,7 +66,9 @@ func (s *AIService) EvaluateWritingPure(essayText, taskType, promptText string)
 }
 
 // TranscribeSpeakingPure transcribes audio without database operations (stateless)
-func (s *AIService) TranscribeSpeakingPure(audioURL string) (string, error) {
+func (s *AIService) TranscribeSpeakingPure(ctx context.Context, audioURL string) (_ string, err error) {
+	defer func(start time.Time) { observeEvaluation("transcription", start, false, err) }(time.Now())
+
 	if audioURL == "" {
 		return "", fmt.Errorf("audio URL is required")
 	}
@@ -68,7 +76,7 @@ func (s *AIService) TranscribeSpeakingPure(audioURL string) (string, error) {
 	log.Printf("🎤 [AI Service] Transcribing audio from URL: %s", audioURL)
 
 	// Download audio
-	audioData, err := downloadAudio(audioURL)
+	audioData, err := downloadAudio(ctx, audioURL)
 	if err != nil {
 		log.Printf("❌ [AI Service] Failed to download audio from URL %s: %v", audioURL, err)
 		return "", fmt.Errorf("failed to download audio: %w", err)
@@ -78,7 +86,7 @@ func (s *AIService) TranscribeSpeakingPure(audioURL string) (string, error) {
 
 	// Transcribe with OpenAI Whisper
 	log.Printf("🎤 [AI Service] Calling OpenAI Whisper API to transcribe audio...")
-	transcript, err := s.openAIClient.TranscribeAudio("audio.mp3", audioData)
+	transcript, err := s.openAIClient.TranscribeAudio(ctx, "audio.mp3", audioData)
 	if err != nil {
 		log.Printf("❌ [AI Service] Transcription failed: %v", err)
 		return "", fmt.Errorf("transcription failed: %w", err)
@@ -100,15 +108,17 @@ func (s *AIService) TranscribeSpeakingPure(audioURL string) (string, error) {
 }
 
 // EvaluateSpeakingPure evaluates speaking without database operations (stateless with cache)
-func (s *AIService) EvaluateSpeakingPure(audioURL, transcriptText, promptText string, partNumber int, wordCount int, duration float64) (*models.OpenAISpeakingEvaluation, error) {
+func (s *AIService) EvaluateSpeakingPure(ctx context.Context, audioURL, transcriptText, promptText string, partNumber int, wordCount int, duration float64) (result *models.OpenAISpeakingEvaluation, err error) {
+	fromCache := false
+	defer func(start time.Time) { observeEvaluation("speaking", start, fromCache, err) }(time.Now())
+
 	if audioURL == "" {
 		return nil, fmt.Errorf("audio URL is required")
 	}
 
 	// If transcript not provided, transcribe first
 	if transcriptText == "" {
-		var err error
-		transcriptText, err = s.TranscribeSpeakingPure(audioURL)
+		transcriptText, err = s.TranscribeSpeakingPure(ctx, audioURL)
 		if err != nil {
 			return nil, fmt.Errorf("transcription failed: %w", err)
 		}
@@ -124,12 +134,13 @@ func (s *AIService) EvaluateSpeakingPure(audioURL, transcriptText, promptText st
 
 	// Check cache first
 	if cached, hit := s.cacheService.CheckSpeakingCache(audioURL, transcriptText, partNumber); hit {
+		fromCache = true
 		return cached, nil
 	}
 
 	// Evaluate speaking with OpenAI (cache miss)
 	// Correct parameter order: part, promptText, transcriptText, wordCount, duration
-	evalResult, err := s.openAIClient.EvaluateSpeaking(partStr, promptText, transcriptText, wordCount, duration)
+	evalResult, err := s.openAIClient.EvaluateSpeaking(ctx, partStr, promptText, transcriptText, wordCount, duration)
 	if err != nil {
 		return nil, fmt.Errorf("evaluation failed: %w", err)
 	}
@@ -216,7 +227,7 @@ func (s *AIService) GetCacheStatistics() (map[string]interface{}, error) {
 }
 
 // Helper function to download audio with retry logic
-func downloadAudio(url string) ([]byte, error) {
+func downloadAudio(ctx context.Context, url string) ([]byte, error) {
 	// Support both external URLs and internal MinIO URLs
 	// Internal: http://minio:9000/ielts-audio/audio/user-id/file.mp3
 	// External: https://storage.example.com/audio.mp3
@@ -224,7 +235,8 @@ func downloadAudio(url string) ([]byte, error) {
 	log.Printf("📥 [AI Service] Downloading audio from URL: %s", url)
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: tracing.NewTransport(nil),
 	}
 
 	var lastErr error
@@ -232,7 +244,11 @@ func downloadAudio(url string) ([]byte, error) {
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		log.Printf("📥 [AI Service] Download attempt %d/%d", attempt, maxRetries)
-		resp, err := client.Get(url)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("attempt %d: %w", attempt, err)
 			log.Printf("⚠️ [AI Service] Download attempt %d failed: %v", attempt, err)
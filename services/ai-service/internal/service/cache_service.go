@@ -44,7 +44,9 @@ func generateContentHash(content string) string {
 }
 
 // CheckWritingCache checks if evaluation exists in cache
-func (cs *CacheService) CheckWritingCache(essayText, taskType, promptText string) (*models.OpenAIWritingEvaluation, bool) {
+func (cs *CacheService) CheckWritingCache(essayText, taskType, promptText string) (_ *models.OpenAIWritingEvaluation, hit bool) {
+	defer func() { observeCacheLookup("writing", hit) }()
+
 	// Generate cache key from content
 	cacheKey := fmt.Sprintf("writing:%s:%s:%s", taskType, promptText, essayText)
 	hash := generateContentHash(cacheKey)
@@ -108,7 +110,9 @@ func (cs *CacheService) SaveWritingCache(essayText, taskType, promptText string,
 }
 
 // CheckSpeakingCache checks if speaking evaluation exists in cache
-func (cs *CacheService) CheckSpeakingCache(audioURL, transcriptText string, partNumber int) (*models.OpenAISpeakingEvaluation, bool) {
+func (cs *CacheService) CheckSpeakingCache(audioURL, transcriptText string, partNumber int) (_ *models.OpenAISpeakingEvaluation, hit bool) {
+	defer func() { observeCacheLookup("speaking", hit) }()
+
 	// Generate cache key from transcript (audio URL may change but same audio = same transcript)
 	cacheKey := fmt.Sprintf("speaking:%d:%s", partNumber, transcriptText)
 	hash := generateContentHash(cacheKey)
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Cache hit ratio: sum(rate(ai_cache_lookups_total{result="hit"}[5m])) / sum(rate(ai_cache_lookups_total[5m]))
+var (
+	evaluationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_evaluations_total",
+		Help: "AI evaluations by kind (writing, speaking, transcription) and status (success, cached, error).",
+	}, []string{"kind", "status"})
+
+	evaluationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ai_evaluation_duration_seconds",
+		Help:    "Latency of AI evaluations, including cache lookups and OpenAI calls.",
+		Buckets: []float64{.1, .5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"kind", "status"})
+
+	cacheLookupsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_cache_lookups_total",
+		Help: "Evaluation cache lookups by kind and result (hit, miss).",
+	}, []string{"kind", "result"})
+)
+
+// observeEvaluation records the outcome of one evaluation started at start
+func observeEvaluation(kind string, start time.Time, cached bool, err error) {
+	status := "success"
+	switch {
+	case err != nil:
+		status = "error"
+	case cached:
+		status = "cached"
+	}
+	evaluationsTotal.WithLabelValues(kind, status).Inc()
+	evaluationDuration.WithLabelValues(kind, status).Observe(time.Since(start).Seconds())
+}
+
+// observeCacheLookup records a cache hit or miss
+func observeCacheLookup(kind string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookupsTotal.WithLabelValues(kind, result).Inc()
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/bisosad1501/DATN/services/ai-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 )
 
 type OpenAIClient struct {
@@ -26,13 +28,14 @@ func NewOpenAIClient(apiKey string) *OpenAIClient {
 		APIKey:  apiKey,
 		BaseURL: "https://api.openai.com/v1",
 		HTTPClient: &http.Client{
-			Timeout: 120 * time.Second, // Long timeout for AI processing
+			Timeout:   120 * time.Second, // Long timeout for AI processing
+			Transport: tracing.NewTransport(nil),
 		},
 	}
 }
 
 // TranscribeAudio transcribes audio using Whisper API
-func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*models.OpenAITranscription, error) {
+func (c *OpenAIClient) TranscribeAudio(ctx context.Context, audioURL string, audioData []byte) (*models.OpenAITranscription, error) {
 	if c == nil {
 		return nil, fmt.Errorf("OpenAI client not initialized (missing API key)")
 	}
@@ -59,7 +62,7 @@ func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*mode
 	writer.Close()
 
 	// Create request
-	req, err := http.NewRequest("POST", c.BaseURL+"/audio/transcriptions", body)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/audio/transcriptions", body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -112,7 +115,7 @@ func (c *OpenAIClient) TranscribeAudio(audioURL string, audioData []byte) (*mode
 }
 
 // EvaluateWriting evaluates writing using GPT-4
-func (c *OpenAIClient) EvaluateWriting(taskPromptText, essayText string, wordCount, timeSpent int) (*models.OpenAIWritingEvaluation, error) {
+func (c *OpenAIClient) EvaluateWriting(ctx context.Context, taskPromptText, essayText string, wordCount, timeSpent int) (*models.OpenAIWritingEvaluation, error) {
 	if c == nil {
 		return nil, fmt.Errorf("OpenAI client not initialized (missing API key)")
 	}
@@ -218,7 +221,7 @@ Guidelines:
 	}
 
 	eval := &models.OpenAIWritingEvaluation{}
-	_, err := c.callChatAPI(payload, eval)
+	_, err := c.callChatAPI(ctx, payload, eval)
 	if err != nil {
 		return nil, err
 	}
@@ -226,7 +229,7 @@ Guidelines:
 }
 
 // EvaluateSpeaking evaluates speaking using GPT-4
-func (c *OpenAIClient) EvaluateSpeaking(part string, promptText, transcriptText string, wordCount int, duration float64) (*models.OpenAISpeakingEvaluation, error) {
+func (c *OpenAIClient) EvaluateSpeaking(ctx context.Context, part string, promptText, transcriptText string, wordCount int, duration float64) (*models.OpenAISpeakingEvaluation, error) {
 	if c == nil {
 		return nil, fmt.Errorf("OpenAI client not initialized (missing API key)")
 	}
@@ -478,7 +481,7 @@ EVALUATION GUIDELINES:
 	}
 
 	eval := &models.OpenAISpeakingEvaluation{}
-	_, err := c.callChatAPI(payload, eval)
+	_, err := c.callChatAPI(ctx, payload, eval)
 	if err != nil {
 		return nil, err
 	}
@@ -486,13 +489,13 @@ EVALUATION GUIDELINES:
 }
 
 // callChatAPI is a helper to call OpenAI Chat API
-func (c *OpenAIClient) callChatAPI(payload interface{}, result interface{}) (interface{}, error) {
+func (c *OpenAIClient) callChatAPI(ctx context.Context, payload interface{}, result interface{}) (interface{}, error) {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", c.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/bisosad1501/DATN/services/auth-service/internal/routes"
 	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
 	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -24,6 +27,13 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "auth-service")
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database connection
 	db, err := database.NewPostgresConnection(cfg)
 	if err != nil {
@@ -45,35 +55,63 @@ func main() {
 	auditRepo := repository.NewAuditLogRepository(db)
 	passwordResetRepo := repository.NewPasswordResetRepository(db)
 	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	loginCodeRepo := repository.NewLoginCodeRepository(db)
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	signingKeyRepo := repository.NewSigningKeyRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	accountDataRepo := repository.NewAccountDataRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
 
-	// Initialize email service
-	emailService := service.NewEmailService(
-		cfg.SMTPHost,
-		cfg.SMTPPort,
-		cfg.SMTPUsername,
-		cfg.SMTPPassword,
-		cfg.SMTPFromEmail,
-		cfg.SMTPFromName,
-	)
+	// Google accounts linked before identities were tracked become identities
+	if backfilled, err := identityRepo.BackfillGoogleIdentities(); err != nil {
+		log.Printf("⚠️ Failed to backfill Google identities: %v", err)
+	} else if backfilled > 0 {
+		log.Printf("Backfilled %d Google identities", backfilled)
+	}
 
 	// Initialize service clients
 	userServiceClient := client.NewUserServiceClient(cfg.UserServiceURL, cfg.InternalAPIKey)
+	notificationClient := client.NewNotificationServiceClient(cfg.NotificationServiceURL, cfg.InternalAPIKey)
+
+	// Emails are queued in notification-service's outbox
+	emailService := service.NewEmailService(notificationClient)
+
+	// Initialize signing keys and schedule their rotation
+	keyManager, err := service.NewKeyManager(signingKeyRepo, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize signing keys: %v", err)
+	}
+	keyManager.Start(context.Background())
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, roleRepo, tokenRepo, auditRepo, passwordResetRepo, emailVerificationRepo, emailService, redisClient, cfg)
-	googleOAuthService := service.NewGoogleOAuthService(cfg, userRepo, roleRepo, tokenRepo, auditRepo, authService, userServiceClient)
+	authService := service.NewAuthService(userRepo, roleRepo, tokenRepo, auditRepo, mfaRepo, passwordResetRepo, emailVerificationRepo, loginCodeRepo, emailChangeRepo, emailService, redisClient, keyManager, cfg)
+	oidcService := service.NewOIDCService(cfg, userRepo, roleRepo, identityRepo, auditRepo, authService, redisClient, userServiceClient)
+	accountDataService := service.NewAccountDataService(cfg, accountDataRepo, auditRepo, authService, userServiceClient)
+	accountDataService.Start(context.Background())
+	auditService := service.NewAuditService(cfg, auditRepo)
+	auditService.Start(context.Background())
+	organizationService := service.NewOrganizationService(cfg, orgRepo, userRepo, roleRepo, auditRepo, userServiceClient, notificationClient)
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(authService, googleOAuthService)
+	authHandler := handlers.NewAuthHandler(authService, oidcService, accountDataService, auditService, organizationService)
+	internalHandler := handlers.NewInternalHandler(authService, organizationService)
 
 	// Setup Gin router
 	if cfg.AppEnv == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 	router := gin.Default()
+	// Per-IP failure counters key on c.ClientIP(), so only the gateway's
+	// X-Forwarded-For may set it
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+	router.Use(tracing.Middleware("auth-service"), tracing.RequestID(), metrics.Middleware("auth-service"))
+	router.GET("/metrics", metrics.Handler())
 
 	// Setup routes
-	routes.SetupRoutes(router, authHandler, authService)
+	routes.SetupRoutes(router, authHandler, internalHandler, authService, cfg.InternalAPIKey)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -10,6 +12,11 @@ type Config struct {
 	AppEnv string
 	Port   string
 
+	// TrustedProxies are the addresses (IPs or CIDRs) whose X-Forwarded-For is
+	// believed. The gateway replaces that header with the client address it
+	// resolved, so trusting the network it calls from gives the real client IP.
+	TrustedProxies []string
+
 	// Database
 	DBHost     string
 	DBPort     string
@@ -21,43 +28,91 @@ type Config struct {
 	RedisURL string
 
 	// JWT
-	JWTSecret          string
 	JWTExpiry          string
 	RefreshTokenExpiry string
 	BcryptRounds       int
 
+	// JWT signing keys
+	JWTSigningAlgorithm    string // RS256 or EdDSA
+	JWTKeyRotationInterval string // how long a key signs before the next one takes over
+	JWTKeyEncryptionSecret string // encrypts private keys at rest
+
 	// Security
-	MaxLoginAttempts    int
-	AccountLockDuration int // minutes
+	MaxLoginAttempts       int
+	AccountLockDuration    int // minutes, doubled for each further lockout
+	AccountLockMaxDuration int // minutes
+	IPFailureThreshold     int // failures from one IP before its attempts are slowed down
+	CodeMaxAttempts        int // wrong 6-digit codes before the code is invalidated
+
+	// Two-factor authentication
+	MFAIssuer           string // account issuer shown in authenticator apps
+	MFAEncryptionSecret string // encrypts TOTP secrets at rest
+	MFAChallengeTTL     string // how long a login has to complete the second step
+
+	// Impersonation
+	ImpersonationMaxDuration string // longest an admin may act as another user with one token
+
+	// Audit log
+	AuditLogRetention string // entries older than this are archived; 0 keeps everything in audit_logs
 
 	// Google OAuth
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURL  string
 
-	// SMTP Email
-	SMTPHost      string
-	SMTPPort      string
-	SMTPUsername  string
-	SMTPPassword  string
-	SMTPFromEmail string
-	SMTPFromName  string
+	// OpenID Connect providers; Google is added when GOOGLE_CLIENT_ID is set
+	OIDCProviders []OIDCProviderConfig
+
+	// Frontend, for links in emails
+	FrontendURL string
+
+	// Account deletion
+	AccountDeletionGracePeriod string // how long a deletion request can be cancelled before data is erased
 
 	// Service URLs
 	UserServiceURL         string
 	NotificationServiceURL string
+	CourseServiceURL       string
+	ExerciseServiceURL     string
+	StorageServiceURL      string
 	InternalAPIKey         string
 }
 
+// OIDCProviderConfig configures one OpenID Connect provider. Endpoints and
+// signing keys come from the issuer's discovery document.
+type OIDCProviderConfig struct {
+	Name        string // used in URLs, e.g. /auth/oidc/microsoft
+	DisplayName string
+	Issuer      string
+	// IssuerAliases are other iss values the provider puts in ID tokens
+	IssuerAliases []string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string
+	Scopes        []string
+	// TrustEmail treats the email claim as verified when the provider does not
+	// send email_verified (e.g. a school's own Microsoft tenant)
+	TrustEmail bool
+}
+
 func Load() *Config {
 	bcryptRounds, _ := strconv.Atoi(getEnv("BCRYPT_ROUNDS", "12"))
 	maxLoginAttempts, _ := strconv.Atoi(getEnv("MAX_LOGIN_ATTEMPTS", "5"))
 	lockDuration, _ := strconv.Atoi(getEnv("ACCOUNT_LOCK_DURATION", "30"))
+	maxLockDuration, _ := strconv.Atoi(getEnv("ACCOUNT_LOCK_MAX_DURATION", "1440"))
+	ipFailureThreshold, _ := strconv.Atoi(getEnv("IP_FAILURE_THRESHOLD", "20"))
+	codeMaxAttempts, _ := strconv.Atoi(getEnv("CODE_MAX_ATTEMPTS", "5"))
+
+	googleClientID := getEnv("GOOGLE_CLIENT_ID", "")
+	googleClientSecret := getEnv("GOOGLE_CLIENT_SECRET", "")
+	googleRedirectURL := getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/v1/auth/google/callback")
 
 	return &Config{
 		AppEnv: getEnv("APP_ENV", "development"),
 		Port:   getEnv("PORT", "8081"),
 
+		TrustedProxies: getEnvAsList("TRUSTED_PROXIES", "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,127.0.0.0/8,::1/128,fc00::/7"),
+
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
 		DBUser:     getEnv("DB_USER", "ielts_admin"),
@@ -66,34 +121,107 @@ func Load() *Config {
 
 		RedisURL: getEnv("REDIS_URL", "redis://:ielts_redis_password@localhost:6379"),
 
-		JWTSecret:          getEnv("JWT_SECRET", "your_jwt_secret_key_change_in_production"),
 		JWTExpiry:          getEnv("JWT_EXPIRY", "24h"),
 		RefreshTokenExpiry: getEnv("REFRESH_TOKEN_EXPIRY", "168h"),
 		BcryptRounds:       bcryptRounds,
 
-		MaxLoginAttempts:    maxLoginAttempts,
-		AccountLockDuration: lockDuration,
+		JWTSigningAlgorithm:    getEnv("JWT_SIGNING_ALGORITHM", "RS256"),
+		JWTKeyRotationInterval: getEnv("JWT_KEY_ROTATION_INTERVAL", "720h"),
+		JWTKeyEncryptionSecret: getEnv("JWT_KEY_ENCRYPTION_SECRET", "jwt_key_encryption_secret_change_in_production"),
+
+		MaxLoginAttempts:       maxLoginAttempts,
+		AccountLockDuration:    lockDuration,
+		AccountLockMaxDuration: maxLockDuration,
+		IPFailureThreshold:     ipFailureThreshold,
+		CodeMaxAttempts:        codeMaxAttempts,
+
+		MFAIssuer:           getEnv("MFA_ISSUER", "IELTS Platform"),
+		MFAEncryptionSecret: getEnv("MFA_ENCRYPTION_SECRET", "mfa_encryption_secret_change_in_production"),
+		MFAChallengeTTL:     getEnv("MFA_CHALLENGE_TTL", "5m"),
 
-		GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
-		GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
-		GoogleRedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/v1/auth/google/callback"),
+		ImpersonationMaxDuration: getEnv("IMPERSONATION_MAX_DURATION", "30m"),
 
-		SMTPHost:      getEnv("SMTP_HOST", "smtp.gmail.com"),
-		SMTPPort:      getEnv("SMTP_PORT", "587"),
-		SMTPUsername:  getEnv("SMTP_USERNAME", ""),
-		SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
-		SMTPFromEmail: getEnv("SMTP_FROM_EMAIL", "noreply@ieltsplatform.com"),
-		SMTPFromName:  getEnv("SMTP_FROM_NAME", "IELTS Learning Platform"),
+		AuditLogRetention: getEnv("AUDIT_LOG_RETENTION", "2160h"),
+
+		GoogleClientID:     googleClientID,
+		GoogleClientSecret: googleClientSecret,
+		GoogleRedirectURL:  googleRedirectURL,
+
+		OIDCProviders: loadOIDCProviders(googleClientID, googleClientSecret, googleRedirectURL),
+
+		FrontendURL: strings.TrimSuffix(getEnv("FRONTEND_URL", "http://localhost:3000"), "/"),
+
+		AccountDeletionGracePeriod: getEnv("ACCOUNT_DELETION_GRACE_PERIOD", "720h"),
 
 		UserServiceURL:         getEnv("USER_SERVICE_URL", "http://user-service:8082"),
 		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://notification-service:8085"),
+		CourseServiceURL:       getEnv("COURSE_SERVICE_URL", "http://course-service:8083"),
+		ExerciseServiceURL:     getEnv("EXERCISE_SERVICE_URL", "http://exercise-service:8084"),
+		StorageServiceURL:      getEnv("STORAGE_SERVICE_URL", "http://storage-service:8087"),
 		InternalAPIKey:         getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
 	}
 }
 
+// loadOIDCProviders reads the providers listed in OIDC_PROVIDERS, e.g.
+// "microsoft,keycloak", from OIDC_<NAME>_* variables
+func loadOIDCProviders(googleClientID, googleClientSecret, googleRedirectURL string) []OIDCProviderConfig {
+	var providers []OIDCProviderConfig
+	if googleClientID != "" {
+		providers = append(providers, OIDCProviderConfig{
+			Name:          "google",
+			DisplayName:   "Google",
+			Issuer:        "https://accounts.google.com",
+			IssuerAliases: []string{"accounts.google.com"},
+			ClientID:      googleClientID,
+			ClientSecret:  googleClientSecret,
+			RedirectURL:   googleRedirectURL,
+			Scopes:        []string{"openid", "email", "profile"},
+		})
+	}
+
+	redirectBase := strings.TrimSuffix(getEnv("OIDC_REDIRECT_BASE_URL", "http://localhost:8080/api/v1/auth/oidc"), "/")
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "google" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		issuer, clientID := getEnv(prefix+"ISSUER", ""), getEnv(prefix+"CLIENT_ID", "")
+		if issuer == "" || clientID == "" {
+			log.Printf("⚠️ OIDC provider %s skipped: %sISSUER and %sCLIENT_ID are required", name, prefix, prefix)
+			continue
+		}
+
+		trustEmail, _ := strconv.ParseBool(getEnv(prefix+"TRUST_EMAIL", "false"))
+		providers = append(providers, OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  getEnv(prefix+"DISPLAY_NAME", name),
+			Issuer:       issuer,
+			ClientID:     clientID,
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", redirectBase+"/"+name+"/callback"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+			TrustEmail:   trustEmail,
+		})
+	}
+	return providers
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
+
+// getEnvAsList splits a comma-separated variable; "none" gives an empty list
+func getEnvAsList(key, defaultValue string) []string {
+	var values []string
+	for _, v := range strings.Split(getEnv(key, defaultValue), ",") {
+		if v = strings.TrimSpace(v); v != "" && v != "none" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
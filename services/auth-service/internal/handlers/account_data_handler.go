@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestDataExport godoc
+// @Summary Export my personal data
+// @Description Queue an export of everything the platform holds about the current user. The archive can be downloaded for 7 days once the request completes.
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} models.SuccessResponse{data=models.AccountDataRequest}
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/account/export [post]
+func (h *AuthHandler) RequestDataExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	req, err := h.accountDataService.RequestExport(userID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondDataRequestError(c, "RequestDataExport", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Success: true,
+		Message: "Your data export has been queued",
+		Data:    req,
+	})
+}
+
+// ListDataRequests godoc
+// @Summary List my data requests
+// @Description The current user's exports and account deletions, newest first
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=[]models.AccountDataRequest}
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/account/data-requests [get]
+func (h *AuthHandler) ListDataRequests(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	requests, err := h.accountDataService.ListRequests(userID)
+	if err != nil {
+		respondDataRequestError(c, "ListDataRequests", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    requests,
+	})
+}
+
+// DownloadDataExport godoc
+// @Summary Download my data export
+// @Description Download the zip archive of a completed export
+// @Tags account
+// @Produce application/zip
+// @Security BearerAuth
+// @Param id path string true "Request ID"
+// @Success 200 {file} file
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 410 {object} models.ErrorResponse
+// @Router /auth/account/export/{id}/download [get]
+func (h *AuthHandler) DownloadDataExport(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	requestID, ok := dataRequestID(c)
+	if !ok {
+		return
+	}
+
+	archive, err := h.accountDataService.DownloadExport(userID, requestID)
+	if err != nil {
+		respondDataRequestError(c, "DownloadDataExport", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="ielts-data-export-%s.zip"`, requestID))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+// RequestAccountDeletion godoc
+// @Summary Delete my account
+// @Description Schedule the account for deletion after a grace period, during which it can be cancelled. Requires the password, and a two-factor code when enabled.
+// @Tags account
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.AccountDeletionRequest true "Identity confirmation"
+// @Success 202 {object} models.SuccessResponse{data=models.AccountDataRequest}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/account/deletion [post]
+func (h *AuthHandler) RequestAccountDeletion(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AccountDeletionRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	deletion, err := h.accountDataService.RequestDeletion(userID, &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondDataRequestError(c, "RequestAccountDeletion", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse{
+		Success: true,
+		Message: "Your account will be deleted on " + deletion.ScheduledFor.Format("2006-01-02") + ". You can cancel until then.",
+		Data:    deletion,
+	})
+}
+
+// CancelAccountDeletion godoc
+// @Summary Cancel my account deletion
+// @Description Cancel a scheduled deletion while it is still in its grace period
+// @Tags account
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/account/deletion [delete]
+func (h *AuthHandler) CancelAccountDeletion(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountDataService.CancelDeletion(userID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondDataRequestError(c, "CancelAccountDeletion", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Account deletion cancelled",
+	})
+}
+
+// ListAllDataRequests godoc
+// @Summary List data requests
+// @Description Every user's exports and account deletions, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, completed, failed or cancelled"
+// @Param page query int false "Page (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} models.SuccessResponse{data=models.AccountDataRequestList}
+// @Router /admin/data-requests [get]
+func (h *AuthHandler) ListAllDataRequests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	list, err := h.accountDataService.ListAllRequests(c.Query("status"), page, limit)
+	if err != nil {
+		respondDataRequestError(c, "ListAllDataRequests", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// GetDataRequest godoc
+// @Summary Get a data request
+// @Description A request with the state, attempts and last error of each service's step
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Request ID"
+// @Success 200 {object} models.SuccessResponse{data=models.AccountDataRequest}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/data-requests/{id} [get]
+func (h *AuthHandler) GetDataRequest(c *gin.Context) {
+	requestID, ok := dataRequestID(c)
+	if !ok {
+		return
+	}
+
+	req, err := h.accountDataService.GetRequest(requestID)
+	if err != nil {
+		respondDataRequestError(c, "GetDataRequest", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    req,
+	})
+}
+
+// RetryDataRequest godoc
+// @Summary Retry a failed data request
+// @Description Queue a failed request again; only its failed steps run
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Request ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/data-requests/{id}/retry [post]
+func (h *AuthHandler) RetryDataRequest(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	requestID, ok := dataRequestID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.accountDataService.RetryRequest(requestID, adminID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondDataRequestError(c, "RetryDataRequest", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Data request queued for retry",
+	})
+}
+
+func dataRequestID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Invalid request ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondDataRequestError maps the service's data request errors to status codes
+func respondDataRequestError(c *gin.Context, action string, err error) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		status, code = http.StatusBadRequest, "INVALID_PASSWORD"
+	case errors.Is(err, service.ErrInvalidMFACode):
+		status, code = http.StatusBadRequest, "INVALID_MFA_CODE"
+	case errors.Is(err, service.ErrDataRequestPending):
+		status, code = http.StatusConflict, "DATA_REQUEST_PENDING"
+	case errors.Is(err, service.ErrDataRequestNotFound):
+		status, code = http.StatusNotFound, "DATA_REQUEST_NOT_FOUND"
+	case errors.Is(err, service.ErrExportNotReady):
+		status, code = http.StatusConflict, "EXPORT_NOT_READY"
+	case errors.Is(err, service.ErrExportExpired):
+		status, code = http.StatusGone, "EXPORT_EXPIRED"
+	case errors.Is(err, service.ErrDeletionNotCancellable):
+		status, code = http.StatusConflict, "DELETION_NOT_CANCELLABLE"
+	case errors.Is(err, service.ErrDataRequestNotFailed):
+		status, code = http.StatusConflict, "DATA_REQUEST_NOT_FAILED"
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("[%s] ERROR: %v", action, err)
+		message = "Failed to process data request"
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
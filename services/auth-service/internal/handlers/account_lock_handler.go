@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UnlockAccount godoc
+// @Summary Unlock an account
+// @Description Lift a lockout caused by failed sign-ins and reset the account's failure counters
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/unlock [post]
+func (h *AuthHandler) UnlockAccount(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Invalid user ID",
+			},
+		})
+		return
+	}
+
+	if err := h.authService.UnlockAccount(userID, adminID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "USER_NOT_FOUND",
+					Message: "User not found",
+				},
+			})
+			return
+		}
+		log.Printf("[UnlockAccount] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to unlock account",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Account unlocked",
+	})
+}
+
+// respondAttemptError answers throttled attempts and invalidated codes,
+// reporting whether err was one of them
+func respondAttemptError(c *gin.Context, err error) bool {
+	var throttled *service.ThrottledError
+	switch {
+	case errors.As(err, &throttled):
+		retryAfter := throttled.RetryAfterSeconds()
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "TOO_MANY_ATTEMPTS",
+				Message: "Too many failed attempts. Please wait before trying again.",
+				Details: map[string]interface{}{"retry_after": retryAfter},
+			},
+		})
+	case errors.Is(err, service.ErrCodeAttemptsExceeded):
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "CODE_ATTEMPTS_EXCEEDED",
+				Message: "Too many incorrect codes. Please request a new code.",
+			},
+		})
+	default:
+		return false
+	}
+	return true
+}
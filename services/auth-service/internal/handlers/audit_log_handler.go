@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SearchAuditLogs godoc
+// @Summary Search the audit log
+// @Description Audit events, newest first, paged with a cursor
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query string false "User ID"
+// @Param event_type query string false "Event type; several may be comma-separated"
+// @Param event_status query string false "success or failed"
+// @Param ip query string false "IP address"
+// @Param from query string false "Created on or after (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Created before (YYYY-MM-DD or RFC 3339)"
+// @Param archived query bool false "Search entries archived by the retention job"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Param limit query int false "Page size (default 50, max 200)"
+// @Success 200 {object} models.SuccessResponse{data=models.AuditLogPage}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /admin/audit-logs [get]
+func (h *AuthHandler) SearchAuditLogs(c *gin.Context) {
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	page, err := h.auditService.Search(filter, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			respondInvalidQuery(c, "Invalid cursor")
+			return
+		}
+		log.Printf("[SearchAuditLogs] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to search audit logs",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    page,
+	})
+}
+
+// ExportAuditLogs godoc
+// @Summary Export the audit log
+// @Description Every matching audit event as a CSV or JSON download, newest first. Takes the same filters as the search.
+// @Tags admin
+// @Produce text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param format query string false "csv (default) or json"
+// @Param user_id query string false "User ID"
+// @Param event_type query string false "Event type; several may be comma-separated"
+// @Param event_status query string false "success or failed"
+// @Param ip query string false "IP address"
+// @Param from query string false "Created on or after (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Created before (YYYY-MM-DD or RFC 3339)"
+// @Param archived query bool false "Export entries archived by the retention job"
+// @Success 200 {file} file
+// @Failure 400 {object} models.ErrorResponse
+// @Router /admin/audit-logs/export [get]
+func (h *AuthHandler) ExportAuditLogs(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", service.AuditExportCSV)
+	contentType := "text/csv; charset=utf-8"
+	switch format {
+	case service.AuditExportCSV:
+	case service.AuditExportJSON:
+		contentType = "application/json"
+	default:
+		respondInvalidQuery(c, "format must be csv or json")
+		return
+	}
+
+	filter, ok := auditLogFilter(c)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only cut the download short
+	if err := h.auditService.Export(filter, format, c.Writer, adminID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		log.Printf("[ExportAuditLogs] ERROR: %v", err)
+	}
+}
+
+// auditLogFilter reads the audit log filters shared by search and export
+func auditLogFilter(c *gin.Context) (models.AuditLogFilter, bool) {
+	filter := models.AuditLogFilter{
+		EventStatus: c.Query("event_status"),
+		IPAddress:   c.Query("ip"),
+	}
+
+	if raw := c.Query("user_id"); raw != "" {
+		userID, err := uuid.Parse(raw)
+		if err != nil {
+			respondInvalidQuery(c, "Invalid user ID")
+			return filter, false
+		}
+		filter.UserID = &userID
+	}
+	for _, eventType := range strings.Split(c.Query("event_type"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			filter.EventTypes = append(filter.EventTypes, eventType)
+		}
+	}
+	if raw := c.Query("archived"); raw != "" {
+		archived, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondInvalidQuery(c, "archived must be true or false")
+			return filter, false
+		}
+		filter.Archived = archived
+	}
+
+	var ok bool
+	if filter.From, ok = parseDateQuery(c, "from"); !ok {
+		return filter, false
+	}
+	if filter.To, ok = parseDateQuery(c, "to"); !ok {
+		return filter, false
+	}
+
+	return filter, true
+}
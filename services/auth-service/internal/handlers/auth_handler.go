@@ -1,10 +1,9 @@
 package handlers
 
 import (
-	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"strconv"
 
 	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
 	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
@@ -13,14 +12,20 @@ import (
 )
 
 type AuthHandler struct {
-	authService        service.AuthService
-	googleOAuthService service.GoogleOAuthService
+	authService         service.AuthService
+	oidcService         service.OIDCService
+	accountDataService  service.AccountDataService
+	auditService        service.AuditService
+	organizationService service.OrganizationService
 }
 
-func NewAuthHandler(authService service.AuthService, googleOAuthService service.GoogleOAuthService) *AuthHandler {
+func NewAuthHandler(authService service.AuthService, oidcService service.OIDCService, accountDataService service.AccountDataService, auditService service.AuditService, organizationService service.OrganizationService) *AuthHandler {
 	return &AuthHandler{
-		authService:        authService,
-		googleOAuthService: googleOAuthService,
+		authService:         authService,
+		oidcService:         oidcService,
+		accountDataService:  accountDataService,
+		auditService:        auditService,
+		organizationService: organizationService,
 	}
 }
 
@@ -86,6 +91,8 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Param request body models.LoginRequest true "Login request"
 // @Success 200 {object} models.AuthResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 423 {object} models.AuthResponse "ACCOUNT_LOCKED, with details.retry_after"
+// @Failure 429 {object} models.AuthResponse "TOO_MANY_ATTEMPTS, with details.retry_after"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
@@ -132,9 +139,14 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		statusCode := http.StatusUnauthorized
 		if response.Error.Code == "ACCOUNT_LOCKED" {
 			statusCode = http.StatusLocked
+		} else if response.Error.Code == "TOO_MANY_ATTEMPTS" {
+			statusCode = http.StatusTooManyRequests
 		} else if response.Error.Code == "ACCOUNT_INACTIVE" {
 			statusCode = http.StatusForbidden
 		}
+		if retryAfter, ok := response.Error.Details["retry_after"].(int); ok {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
 		c.JSON(statusCode, response)
 		return
 	}
@@ -346,316 +358,17 @@ func (h *AuthHandler) HealthCheck(c *gin.Context) {
 	})
 }
 
-// GetGoogleAuthURL godoc
-// @Summary Get Google OAuth URL
-// @Description Get Google OAuth authorization URL for API clients
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, including the next key before it starts signing
 // @Tags auth
 // @Produce json
-// @Success 200 {object} map[string]interface{} "OAuth URL and state"
-// @Router /auth/google/url [get]
-func (h *AuthHandler) GetGoogleAuthURL(c *gin.Context) {
-	// Generate random state for CSRF protection
-	state := uuid.New().String()
-
-	// Get authorization URL
-	url := h.googleOAuthService.GetAuthURL(state)
-
-	// Return JSON response for API clients
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"url":   url,
-			"state": state,
-		},
-	})
-}
-
-// GetGoogleOAuthURL godoc
-// @Summary Get Google OAuth URL
-// @Description Get Google OAuth authorization URL (for API clients)
-// @Tags auth
-// @Produce json
-// @Success 200 {object} map[string]interface{}
-// @Router /auth/google/url [get]
-func (h *AuthHandler) GetGoogleOAuthURL(c *gin.Context) {
-	// Generate random state for CSRF protection
-	state := uuid.New().String()
-
-	// Store state in session or cookie (simplified here)
-	c.SetCookie("oauth_state", state, 600, "/", "", false, true)
-
-	// Get authorization URL
-	url := h.googleOAuthService.GetAuthURL(state)
-
-	// Return JSON response with URL
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"url":   url,
-			"state": state,
-		},
-	})
-}
-
-// GoogleLogin godoc
-// @Summary Initiate Google OAuth login
-// @Description Redirect user to Google OAuth consent screen (for web browsers)
-// @Tags auth
-// @Produce json
-// @Success 302 {string} string "Redirect to Google"
-// @Router /auth/google [get]
-func (h *AuthHandler) GoogleLogin(c *gin.Context) {
-	// Generate random state for CSRF protection
-	state := uuid.New().String()
-
-	// Store state in session or cookie (simplified here)
-	c.SetCookie("oauth_state", state, 600, "/", "", false, true)
-
-	// Get authorization URL
-	url := h.googleOAuthService.GetAuthURL(state)
-
-	// Redirect to Google
-	c.Redirect(http.StatusTemporaryRedirect, url)
-}
-
-// GoogleCallback godoc
-// @Summary Handle Google OAuth callback
-// @Description Process Google OAuth callback and authenticate user
-// @Tags auth
-// @Produce json
-// @Param code query string true "Authorization code"
-// @Param state query string true "State parameter"
-// @Success 200 {object} models.AuthResponse
-// @Failure 400 {object} models.ErrorResponse
-// @Router /auth/google/callback [get]
-func (h *AuthHandler) GoogleCallback(c *gin.Context) {
-    // If Google returned an error (e.g., user not in Test Users or access denied), handle gracefully
-    if errParam := c.Query("error"); errParam != "" {
-        frontendURL := os.Getenv("FRONTEND_URL")
-        if frontendURL == "" {
-            frontendURL = "http://localhost:3000"
-        }
-
-        // Prefer Google's description if present
-        errorDesc := c.Query("error_description")
-        message := errorDesc
-        if message == "" {
-            // Common case: access_denied for users not in Test Users when app is in Testing/Internal
-            switch errParam {
-            case "access_denied":
-                message = "Google denied access. If the app is in Testing/Internal, add this email to Test Users or publish the app."
-            default:
-                message = "Google OAuth error: " + errParam
-            }
-        }
-
-        redirectURL := fmt.Sprintf("%s/login?error=%s", frontendURL, message)
-        c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-        return
-    }
-    // Verify state for CSRF protection (skip in development if cookie not found)
-    state := c.Query("state")
-    storedState, err := c.Cookie("oauth_state")
-
-	// Only verify state if cookie exists (for proper OAuth flow from /auth/google)
-	// Skip verification if cookie doesn't exist (for direct URL access in testing)
-	if err == nil && state != storedState {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "INVALID_STATE",
-				Message: "Invalid state parameter",
-			},
-		})
-		return
-	}
-
-	// Clear state cookie if it exists
-	if err == nil {
-		c.SetCookie("oauth_state", "", -1, "/", "", false, true)
-	}
-
-	// Get authorization code
-	code := c.Query("code")
-	if code == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "MISSING_CODE",
-				Message: "Authorization code is required",
-			},
-		})
-		return
-	}
-
-	// Exchange code for token
-	log.Printf("[GoogleCallback] Starting token exchange for code: %s...", code[:10])
-	token, err := h.googleOAuthService.ExchangeCode(code)
-	if err != nil {
-		log.Printf("[GoogleCallback] ❌ Failed to exchange code: %v", err)
-		// Redirect to frontend with error
-		frontendURL := os.Getenv("FRONTEND_URL")
-		if frontendURL == "" {
-			frontendURL = "http://localhost:3000"
-		}
-		redirectURL := fmt.Sprintf("%s/login?error=%s", frontendURL, "Failed to exchange authorization code")
-		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-		return
-	}
-	log.Printf("[GoogleCallback] ✅ Token exchange successful")
-
-	// Get user info from Google
-	log.Printf("[GoogleCallback] Getting user info from Google...")
-	googleUser, err := h.googleOAuthService.GetUserInfo(token)
-	if err != nil {
-		log.Printf("[GoogleCallback] ❌ Failed to get user info: %v", err)
-		frontendURL := os.Getenv("FRONTEND_URL")
-		if frontendURL == "" {
-			frontendURL = "http://localhost:3000"
-		}
-		redirectURL := fmt.Sprintf("%s/login?error=%s", frontendURL, "Failed to get user information from Google")
-		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-		return
-	}
-	log.Printf("[GoogleCallback] ✅ Got user info: %s (%s)", googleUser.Email, googleUser.ID)
-
-	// Authenticate or create user
-	ip := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-
-	log.Printf("[GoogleCallback] Authenticating user...")
-	authResp, err := h.googleOAuthService.AuthenticateUser(googleUser, ip, userAgent)
-	if err != nil {
-		log.Printf("[GoogleCallback] ❌ Failed to authenticate user: %v", err)
-		frontendURL := os.Getenv("FRONTEND_URL")
-		if frontendURL == "" {
-			frontendURL = "http://localhost:3000"
-		}
-		redirectURL := fmt.Sprintf("%s/login?error=%s", frontendURL, "Failed to authenticate user")
-		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-		return
-	}
-	log.Printf("[GoogleCallback] ✅ User authenticated successfully")
-	log.Printf("[GoogleCallback] 🔍 authResp.Success = %v", authResp.Success)
-	log.Printf("[GoogleCallback] 🔍 authResp.Data != nil = %v", authResp.Data != nil)
-	if authResp.Data != nil {
-		log.Printf("[GoogleCallback] 🔍 authResp.Data.AccessToken = %s...", authResp.Data.AccessToken[:20])
-		log.Printf("[GoogleCallback] 🔍 authResp.Data.UserID = %s", authResp.Data.UserID)
-	}
-
-	// For web flow: Redirect to frontend with tokens
-	// Frontend URL from environment or default
-	frontendURL := os.Getenv("FRONTEND_URL")
-	if frontendURL == "" {
-		frontendURL = "http://localhost:3000"
-	}
-
-	if authResp.Success && authResp.Data != nil {
-		// Success: Redirect to frontend callback with tokens
-		redirectURL := fmt.Sprintf(
-			"%s/auth/google/callback?success=true&access_token=%s&refresh_token=%s&user_id=%s&email=%s&role=%s",
-			frontendURL,
-			authResp.Data.AccessToken,
-			authResp.Data.RefreshToken,
-			authResp.Data.UserID,
-			authResp.Data.Email,
-			authResp.Data.Role,
-		)
-		log.Printf("[GoogleCallback] 🎉 Redirecting to frontend: %s", frontendURL+"/auth/google/callback?success=true&...")
-		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-	} else {
-		// Error: Redirect to frontend login with error
-		errorMessage := "Authentication failed"
-		if authResp.Error != nil {
-			errorMessage = authResp.Error.Message
-		}
-		redirectURL := fmt.Sprintf("%s/login?error=%s", frontendURL, errorMessage)
-		log.Printf("[GoogleCallback] ⚠️ Redirecting to login with error: %s", errorMessage)
-		c.Redirect(http.StatusTemporaryRedirect, redirectURL)
-	}
-}
-
-// GoogleExchangeToken handles Google OAuth token exchange for mobile/API clients
-// Mobile app flow:
-// 1. Mobile gets URL from GET /auth/google/url
-// 2. Mobile opens URL in WebView/Browser
-// 3. User logs in with Google
-// 4. Mobile captures redirect URL with code parameter
-// 5. Mobile sends code to this endpoint
-// 6. Backend exchanges code for tokens and returns to mobile
-func (h *AuthHandler) GoogleExchangeToken(c *gin.Context) {
-	var req struct {
-		Code  string `json:"code" binding:"required"`
-		State string `json:"state"`
-	}
-
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "VALIDATION_ERROR",
-				Message: "Code is required",
-			},
-		})
-		return
-	}
-
-	// Exchange code for token
-	token, err := h.googleOAuthService.ExchangeCode(req.Code)
-	if err != nil {
-		log.Printf("[GoogleExchangeToken] Failed to exchange code: %v", err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "EXCHANGE_FAILED",
-				Message: "Failed to exchange authorization code. Code may be expired or invalid.",
-			},
-		})
-		return
-	}
-
-	// Get user info from Google
-	googleUser, err := h.googleOAuthService.GetUserInfo(token)
-	if err != nil {
-		log.Printf("[GoogleExchangeToken] Failed to get user info: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "USER_INFO_FAILED",
-				Message: "Failed to get user information from Google",
-			},
-		})
-		return
-	}
-
-	// Authenticate or create user
-	ip := c.ClientIP()
-	userAgent := c.GetHeader("User-Agent")
-
-	authResp, err := h.googleOAuthService.AuthenticateUser(googleUser, ip, userAgent)
-	if err != nil {
-		log.Printf("[GoogleExchangeToken] Failed to authenticate user: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "AUTH_FAILED",
-				Message: "Failed to authenticate user",
-			},
-		})
-		return
-	}
-
-	// Return response
-	if authResp.Success {
-		c.JSON(http.StatusOK, authResp)
-	} else {
-		// Handle specific error cases
-		statusCode := http.StatusBadRequest
-		if authResp.Error.Code == "ACCOUNT_INACTIVE" {
-			statusCode = http.StatusForbidden
-		}
-		c.JSON(statusCode, authResp)
-	}
+// @Success 200 {object} jwks.Set
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Verifiers refetch on unknown kids, so a short cache only delays cleanup of retired keys
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.authService.GetJWKS())
 }
 
 // ForgotPassword godoc
@@ -856,9 +569,10 @@ func (h *AuthHandler) ResetPasswordByCode(c *gin.Context) {
 		return
 	}
 
-	ip := c.ClientIP()
-
-	if err := h.authService.ResetPasswordByCode(req.Code, req.NewPassword, ip); err != nil {
+	if err := h.authService.ResetPasswordByCode(&req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if respondAttemptError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Success: false,
 			Error: &models.ErrorData{
@@ -899,7 +613,10 @@ func (h *AuthHandler) VerifyEmailByCode(c *gin.Context) {
 		return
 	}
 
-	if err := h.authService.VerifyEmailByCode(req.Code); err != nil {
+	if err := h.authService.VerifyEmailByCode(&req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if respondAttemptError(c, err) {
+			return
+		}
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{
 			Success: false,
 			Error: &models.ErrorData{
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestEmailChange godoc
+// @Summary Change email
+// @Description Confirm the password (and two-factor code when enabled), then send a 6-digit code to the new address and a cancel link to the current one
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ChangeEmailRequest true "New email and password"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/change-email [post]
+func (h *AuthHandler) RequestEmailChange(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ChangeEmailRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.authService.RequestEmailChange(userID, &req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondEmailChangeError(c, "RequestEmailChange", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "A verification code has been sent to the new email address",
+	})
+}
+
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Swap the email for the new address with the code sent to it. Every session is signed out.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ConfirmEmailChangeRequest true "Code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/change-email/confirm [post]
+func (h *AuthHandler) ConfirmEmailChange(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ConfirmEmailChangeRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.authService.ConfirmEmailChange(userID, req.Code, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondEmailChangeError(c, "ConfirmEmailChange", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Email changed. Please sign in again with your new email.",
+	})
+}
+
+// CancelEmailChange godoc
+// @Summary Cancel email change
+// @Description Cancel a pending email change with the token from the link sent to the current address
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.CancelEmailChangeRequest true "Token"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/change-email/cancel [post]
+func (h *AuthHandler) CancelEmailChange(c *gin.Context) {
+	var req models.CancelEmailChangeRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.authService.CancelEmailChange(req.Token, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondEmailChangeError(c, "CancelEmailChange", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Email change cancelled",
+	})
+}
+
+// respondEmailChangeError maps the service's email change errors to status codes
+func respondEmailChangeError(c *gin.Context, action string, err error) {
+	if respondAttemptError(c, err) {
+		return
+	}
+
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case errors.Is(err, service.ErrInvalidPassword):
+		status, code = http.StatusBadRequest, "INVALID_PASSWORD"
+	case errors.Is(err, service.ErrInvalidMFACode):
+		status, code = http.StatusBadRequest, "INVALID_MFA_CODE"
+	case errors.Is(err, service.ErrSameEmail):
+		status, code = http.StatusBadRequest, "SAME_EMAIL"
+	case errors.Is(err, service.ErrEmailTaken):
+		status, code = http.StatusConflict, "EMAIL_TAKEN"
+	case errors.Is(err, service.ErrInvalidEmailChangeCode):
+		status, code = http.StatusBadRequest, "INVALID_CODE"
+	case errors.Is(err, service.ErrInvalidEmailChangeToken):
+		status, code = http.StatusBadRequest, "INVALID_TOKEN"
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("[%s] ERROR: %v", action, err)
+		message = "Failed to change email"
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
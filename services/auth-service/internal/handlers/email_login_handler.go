@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestEmailLoginCode godoc
+// @Summary Request a sign-in code
+// @Description Email a single-use 6-digit code and magic link for signing in without a password
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.EmailLoginCodeRequest true "Email"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/login/email-code [post]
+func (h *AuthHandler) RequestEmailLoginCode(c *gin.Context) {
+	var req models.EmailLoginCodeRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Valid email is required",
+			},
+		})
+		return
+	}
+
+	if err := h.authService.RequestEmailLoginCode(&req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		log.Printf("[RequestEmailLoginCode] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to send sign-in code",
+			},
+		})
+		return
+	}
+
+	// Always return success for security (don't reveal if email exists)
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "If the email exists, a sign-in code has been sent",
+	})
+}
+
+// LoginWithEmailCode godoc
+// @Summary Sign in with an emailed code
+// @Description Exchange the 6-digit code (with email) or the magic link token for tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.VerifyEmailLoginCodeRequest true "Email and code, or token"
+// @Success 200 {object} models.AuthResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse
+// @Router /auth/login/email-code/verify [post]
+func (h *AuthHandler) LoginWithEmailCode(c *gin.Context) {
+	var req models.VerifyEmailLoginCodeRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err == nil && req.Token == "" && (req.Email == "" || req.Code == "") {
+		err = errors.New("email and code, or token, are required")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	response, err := h.authService.LoginWithEmailCode(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		if respondAttemptError(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidLoginCode) {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "INVALID_CODE",
+					Message: err.Error(),
+				},
+			})
+			return
+		}
+		log.Printf("[LoginWithEmailCode] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to login. Please try again later.",
+			},
+		})
+		return
+	}
+
+	if !response.Success {
+		statusCode := http.StatusUnauthorized
+		if response.Error.Code == "ACCOUNT_INACTIVE" {
+			statusCode = http.StatusForbidden
+		}
+		c.JSON(statusCode, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// InternalHandler serves service-to-service endpoints (API key protected)
+type InternalHandler struct {
+	authService         service.AuthService
+	organizationService service.OrganizationService
+}
+
+func NewInternalHandler(authService service.AuthService, organizationService service.OrganizationService) *InternalHandler {
+	return &InternalHandler{
+		authService:         authService,
+		organizationService: organizationService,
+	}
+}
+
+// GetRolePermissions returns the permissions granted to a role, used by the
+// gateway for tokens issued before permissions were embedded in the JWT
+func (h *InternalHandler) GetRolePermissions(c *gin.Context) {
+	role := c.Param("role")
+
+	permissions, err := h.authService.GetRolePermissions(role)
+	if err != nil {
+		log.Printf("[Internal] ERROR: failed to load permissions for role %s: %v", role, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to load role permissions",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"role":        role,
+			"permissions": permissions,
+		},
+	})
+}
+
+// RecordAuditEvent stores an audit log entry reported by another service
+func (h *InternalHandler) RecordAuditEvent(c *gin.Context) {
+	var req models.AuditEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+
+	if err := h.authService.RecordAuditEvent(&req); err != nil {
+		log.Printf("[Internal] ERROR: failed to record audit event %s: %v", req.EventType, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to record audit event",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Success: true,
+		Message: "Audit event recorded",
+	})
+}
+
+// GetClassroomAccess returns the role of the user_id query parameter in a
+// classroom and the classroom's students, used by exercise-service for
+// classroom assignments
+func (h *InternalHandler) GetClassroomAccess(c *gin.Context) {
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(c.Query("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Invalid user ID",
+			},
+		})
+		return
+	}
+
+	access, err := h.organizationService.GetClassroomAccess(classroomID, userID)
+	if err != nil {
+		respondOrganizationError(c, "Internal", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    access,
+	})
+}
+
+// ListUserClassrooms returns the classrooms a user teaches or studies in
+func (h *InternalHandler) ListUserClassrooms(c *gin.Context) {
+	userID, ok := pathUUID(c, "user_id", "user")
+	if !ok {
+		return
+	}
+
+	classrooms, err := h.organizationService.ListMyClassrooms(userID)
+	if err != nil {
+		respondOrganizationError(c, "Internal", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    classrooms,
+	})
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// MFAStatus godoc
+// @Summary Get two-factor authentication status
+// @Description Whether two-factor authentication is enabled and required for the current user
+// @Tags mfa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=models.MFAStatus}
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/mfa [get]
+func (h *AuthHandler) MFAStatus(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.authService.GetMFAStatus(userID)
+	if err != nil {
+		respondMFAError(c, "MFAStatus", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    status,
+	})
+}
+
+// EnrollMFA godoc
+// @Summary Start two-factor enrolment
+// @Description Generate a TOTP secret and provisioning URI to show as a QR code
+// @Tags mfa
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=models.MFAEnrollmentData}
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/mfa/enroll [post]
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.authService.EnrollMFA(userID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondMFAError(c, "EnrollMFA", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    enrollment,
+	})
+}
+
+// ConfirmMFA godoc
+// @Summary Confirm two-factor enrolment
+// @Description Enable two-factor authentication with a code from the authenticator app. The recovery codes are only returned once.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/mfa/enroll/confirm [post]
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	codes, err := h.authService.ConfirmMFA(userID, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondMFAError(c, "ConfirmMFA", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Two-factor authentication enabled. Store these recovery codes somewhere safe.",
+		Data:    gin.H{"recovery_codes": codes},
+	})
+}
+
+// DisableMFA godoc
+// @Summary Disable two-factor authentication
+// @Description Requires the password and a TOTP or recovery code. Not allowed when the user's role requires MFA.
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFADisableRequest true "Password and code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Router /auth/mfa/disable [post]
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFADisableRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.authService.DisableMFA(userID, &req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondMFAError(c, "DisableMFA", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace all recovery codes after checking a TOTP code
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.MFACodeRequest true "TOTP code"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/mfa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFACodeRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(userID, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondMFAError(c, "RegenerateRecoveryCodes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Recovery codes regenerated. Previous codes no longer work.",
+		Data:    gin.H{"recovery_codes": codes},
+	})
+}
+
+// VerifyMFA godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the mfa_token returned by login and a TOTP or recovery code for access and refresh tokens
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body models.MFAVerifyRequest true "MFA token and code"
+// @Success 200 {object} models.AuthResponse
+// @Failure 401 {object} models.AuthResponse
+// @Failure 429 {object} models.AuthResponse "TOO_MANY_ATTEMPTS, with details.retry_after"
+// @Router /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req models.MFAVerifyRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	response, err := h.authService.VerifyMFA(&req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondMFAError(c, "VerifyMFA", err)
+		return
+	}
+
+	if !response.Success {
+		statusCode := http.StatusUnauthorized
+		if response.Error.Code == "TOO_MANY_ATTEMPTS" {
+			statusCode = http.StatusTooManyRequests
+		} else if response.Error.Code == "ACCOUNT_INACTIVE" {
+			statusCode = http.StatusForbidden
+		}
+		if retryAfter, ok := response.Error.Details["retry_after"].(int); ok {
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+		}
+		c.JSON(statusCode, response)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ChallengeEnrollment godoc
+// @Summary Get the enrolment secret for a login challenge
+// @Description For logins that must set up two-factor authentication first but did not receive the secret (e.g. the Google redirect flow)
+// @Tags mfa
+// @Accept json
+// @Produce json
+// @Param request body object true "{\"mfa_token\": \"...\"}"
+// @Success 200 {object} models.SuccessResponse{data=models.MFAEnrollmentData}
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/mfa/challenge/enrollment [post]
+func (h *AuthHandler) ChallengeEnrollment(c *gin.Context) {
+	var req struct {
+		MFAToken string `json:"mfa_token" binding:"required"`
+	}
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	enrollment, err := h.authService.GetChallengeEnrollment(req.MFAToken)
+	if err != nil {
+		respondMFAError(c, "ChallengeEnrollment", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    enrollment,
+	})
+}
+
+// GetMFAPolicies godoc
+// @Summary List MFA role policies
+// @Description Whether each role must use two-factor authentication
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=[]models.MFAPolicy}
+// @Router /admin/mfa/policies [get]
+func (h *AuthHandler) GetMFAPolicies(c *gin.Context) {
+	policies, err := h.authService.GetMFAPolicies()
+	if err != nil {
+		respondMFAError(c, "GetMFAPolicies", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    policies,
+	})
+}
+
+// SetMFAPolicy godoc
+// @Summary Set MFA role policy
+// @Description Make two-factor authentication mandatory or optional for a role
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param role path string true "Role name"
+// @Param request body models.MFAPolicyRequest true "Policy"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/mfa/policies/{role} [put]
+func (h *AuthHandler) SetMFAPolicy(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MFAPolicyRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	role := c.Param("role")
+	if err := h.authService.SetMFAPolicy(role, *req.Required, adminID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondMFAError(c, "SetMFAPolicy", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "MFA policy updated",
+		Data:    models.MFAPolicy{Role: role, Required: *req.Required},
+	})
+}
+
+// currentUserID reads the user set by AuthMiddleware, responding 401 if absent
+func currentUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if exists {
+		if id, err := uuid.Parse(userID.(string)); err == nil {
+			return id, true
+		}
+	}
+
+	c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "UNAUTHORIZED",
+			Message: "User not authenticated",
+		},
+	})
+	return uuid.Nil, false
+}
+
+func bindMFARequest(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: err.Error(),
+			},
+		})
+		return false
+	}
+	return true
+}
+
+// respondMFAError maps the service's MFA errors to status codes
+func respondMFAError(c *gin.Context, action string, err error) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case errors.Is(err, service.ErrInvalidMFACode):
+		status, code = http.StatusBadRequest, "INVALID_MFA_CODE"
+	case errors.Is(err, service.ErrInvalidPassword):
+		status, code = http.StatusBadRequest, "INVALID_PASSWORD"
+	case errors.Is(err, service.ErrMFAAlreadyEnabled):
+		status, code = http.StatusConflict, "MFA_ALREADY_ENABLED"
+	case errors.Is(err, service.ErrMFANotEnabled):
+		status, code = http.StatusBadRequest, "MFA_NOT_ENABLED"
+	case errors.Is(err, service.ErrMFANotPending):
+		status, code = http.StatusBadRequest, "MFA_NOT_PENDING"
+	case errors.Is(err, service.ErrMFARequiredByRole):
+		status, code = http.StatusForbidden, "MFA_REQUIRED_BY_ROLE"
+	case errors.Is(err, service.ErrMFAChallenge):
+		status, code = http.StatusUnauthorized, "MFA_CHALLENGE_EXPIRED"
+	case errors.Is(err, service.ErrRoleNotFound):
+		status, code = http.StatusNotFound, "ROLE_NOT_FOUND"
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("[%s] ERROR: %v", action, err)
+		message = "Failed to process two-factor authentication request"
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
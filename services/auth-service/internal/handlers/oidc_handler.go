@@ -0,0 +1,401 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// oidcStateCookie binds a browser sign-in to the browser that started it
+const oidcStateCookie = "oauth_state"
+
+// ListOIDCProviders godoc
+// @Summary List sign-in providers
+// @Description OpenID Connect providers users can sign in with
+// @Tags auth
+// @Produce json
+// @Success 200 {object} models.SuccessResponse
+// @Router /auth/oidc/providers [get]
+func (h *AuthHandler) ListOIDCProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    h.oidcService.Providers(),
+	})
+}
+
+// GetOIDCAuthURL godoc
+// @Summary Get a provider's sign-in URL
+// @Description Authorization URL and state for API and mobile clients; /auth/google/url is the Google alias
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Success 200 {object} map[string]interface{} "OAuth URL and state"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/oidc/{provider}/url [get]
+func (h *AuthHandler) GetOIDCAuthURL(c *gin.Context) {
+	authURL, state, err := h.oidcService.AuthURL(oidcProviderParam(c), nil)
+	if err != nil {
+		respondOIDCError(c, "GetOIDCAuthURL", err)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, state, 600, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"url":   authURL,
+			"state": state,
+		},
+	})
+}
+
+// OIDCLogin godoc
+// @Summary Sign in with a provider
+// @Description Redirect the browser to the provider's consent screen; /auth/google is the Google alias
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Success 307 {string} string "Redirect to the provider"
+// @Router /auth/oidc/{provider} [get]
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	provider := oidcProviderParam(c)
+	authURL, state, err := h.oidcService.AuthURL(provider, nil)
+	if err != nil {
+		log.Printf("[OIDCLogin] %s: %v", provider, err)
+		redirectLoginError(c, oidcErrorMessage(err))
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, state, 600, "/", "", false, true)
+	c.Redirect(http.StatusTemporaryRedirect, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Handle a provider's callback
+// @Description Complete a sign-in or account link and redirect to the frontend; /auth/google/callback is the Google alias
+// @Tags auth
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State parameter"
+// @Success 307 {string} string "Redirect to the frontend"
+// @Router /auth/oidc/{provider}/callback [get]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	provider := oidcProviderParam(c)
+
+	// The user declined, or the provider refused the request
+	if errParam := c.Query("error"); errParam != "" {
+		message := c.Query("error_description")
+		if message == "" {
+			switch errParam {
+			case "access_denied":
+				message = "Access was denied. If the app is in Testing/Internal, add this email to Test Users or publish the app."
+			default:
+				message = "Sign-in error: " + errParam
+			}
+		}
+		redirectLoginError(c, message)
+		return
+	}
+
+	state := c.Query("state")
+	// Flows started in this browser carry the state cookie; a mismatch means
+	// someone else's callback URL was opened here. Links require it.
+	var requester service.OIDCRequester
+	if storedState, err := c.Cookie(oidcStateCookie); err == nil {
+		c.SetCookie(oidcStateCookie, "", -1, "/", "", false, true)
+		if storedState != state {
+			redirectLoginError(c, "Invalid state parameter")
+			return
+		}
+		requester.StateCookie = true
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		redirectLoginError(c, "Authorization code is required")
+		return
+	}
+
+	result, err := h.oidcService.Callback(provider, code, state, requester, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		log.Printf("[OIDCCallback] %s: %v", provider, err)
+		redirectLoginError(c, oidcErrorMessage(err))
+		return
+	}
+
+	if result.Linked != nil {
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/profile?linked=%s", frontendURL(), url.QueryEscape(provider)))
+		return
+	}
+
+	authResp := result.Auth
+	callbackURL := fmt.Sprintf("%s/auth/%s/callback", frontendURL(), url.PathEscape(provider))
+	switch {
+	case authResp.Success && authResp.Data != nil && authResp.Data.MFAToken != "":
+		// Second factor required: the frontend completes login at /auth/mfa/verify.
+		// The enrolment secret is fetched with the token rather than put in the URL.
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf(
+			"%s?mfa_required=true&mfa_token=%s&mfa_enrollment_required=%t&email=%s",
+			callbackURL,
+			url.QueryEscape(authResp.Data.MFAToken),
+			authResp.Data.MFAEnrollmentRequired,
+			url.QueryEscape(authResp.Data.Email),
+		))
+	case authResp.Success && authResp.Data != nil:
+		c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf(
+			"%s?success=true&access_token=%s&refresh_token=%s&user_id=%s&email=%s&role=%s",
+			callbackURL,
+			url.QueryEscape(authResp.Data.AccessToken),
+			url.QueryEscape(authResp.Data.RefreshToken),
+			url.QueryEscape(authResp.Data.UserID),
+			url.QueryEscape(authResp.Data.Email),
+			url.QueryEscape(authResp.Data.Role),
+		))
+	default:
+		message := "Authentication failed"
+		if authResp.Error != nil {
+			message = authResp.Error.Message
+		}
+		redirectLoginError(c, message)
+	}
+}
+
+// OIDCExchangeToken handles the code exchange for mobile/API clients:
+// 1. The client gets the URL and state from GET /auth/oidc/{provider}/url
+// 2. It opens the URL in a WebView/browser and the user signs in
+// 3. It captures the code from the redirect and posts it here with the state
+// Links started with POST /auth/identities/{provider} also need the access
+// token of the user being linked. POST /auth/google/token is the Google alias.
+func (h *AuthHandler) OIDCExchangeToken(c *gin.Context) {
+	var req struct {
+		Code  string `json:"code" binding:"required"`
+		State string `json:"state" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Code and state are required",
+			},
+		})
+		return
+	}
+
+	requester := service.OIDCRequester{UserID: h.bearerUserID(c)}
+	if storedState, err := c.Cookie(oidcStateCookie); err == nil && storedState == req.State {
+		c.SetCookie(oidcStateCookie, "", -1, "/", "", false, true)
+		requester.StateCookie = true
+	}
+
+	result, err := h.oidcService.Callback(oidcProviderParam(c), req.Code, req.State, requester, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondOIDCError(c, "OIDCExchangeToken", err)
+		return
+	}
+
+	if result.Linked != nil {
+		c.JSON(http.StatusOK, models.SuccessResponse{
+			Success: true,
+			Data:    result.Linked,
+			Message: "Account linked",
+		})
+		return
+	}
+
+	authResp := result.Auth
+	if !authResp.Success {
+		statusCode := http.StatusBadRequest
+		if authResp.Error.Code == "ACCOUNT_INACTIVE" {
+			statusCode = http.StatusForbidden
+		}
+		c.JSON(statusCode, authResp)
+		return
+	}
+	c.JSON(http.StatusOK, authResp)
+}
+
+// ListIdentities godoc
+// @Summary List linked sign-in providers
+// @Description Provider accounts the user can sign in with
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Router /auth/identities [get]
+func (h *AuthHandler) ListIdentities(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	identities, err := h.oidcService.ListIdentities(userID)
+	if err != nil {
+		respondOIDCError(c, "ListIdentities", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    identities,
+	})
+}
+
+// LinkIdentity godoc
+// @Summary Link a sign-in provider
+// @Description Authorization URL that links the provider account to the current user. Only this browser, which gets the state cookie, or a client with the user's access token can complete it.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name"
+// @Success 200 {object} map[string]interface{} "OAuth URL and state"
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/identities/{provider} [post]
+func (h *AuthHandler) LinkIdentity(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	authURL, state, err := h.oidcService.AuthURL(c.Param("provider"), &userID)
+	if err != nil {
+		respondOIDCError(c, "LinkIdentity", err)
+		return
+	}
+
+	c.SetCookie(oidcStateCookie, state, 600, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"url":   authURL,
+			"state": state,
+		},
+	})
+}
+
+// UnlinkIdentity godoc
+// @Summary Unlink a sign-in provider
+// @Description Remove a provider account; the last sign-in method of an account without a password cannot be removed
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param provider path string true "Provider name"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /auth/identities/{provider} [delete]
+func (h *AuthHandler) UnlinkIdentity(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.oidcService.UnlinkIdentity(userID, c.Param("provider"), c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondOIDCError(c, "UnlinkIdentity", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Account unlinked",
+	})
+}
+
+// bearerUserID is the user of the request's access token on routes without
+// the auth middleware, or nil. Impersonation tokens do not count.
+func (h *AuthHandler) bearerUserID(c *gin.Context) *uuid.UUID {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil
+	}
+	claims, err := h.authService.ValidateToken(token)
+	if err != nil || claims.Actor != nil {
+		return nil
+	}
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// oidcProviderParam is the provider in the path; the Google routes have none
+func oidcProviderParam(c *gin.Context) string {
+	if provider := c.Param("provider"); provider != "" {
+		return provider
+	}
+	return "google"
+}
+
+// respondOIDCError answers API clients with the status for a sign-in error
+func respondOIDCError(c *gin.Context, handler string, err error) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case errors.Is(err, service.ErrUnknownOIDCProvider), errors.Is(err, service.ErrIdentityNotFound):
+		status, code = http.StatusNotFound, "NOT_FOUND"
+	case errors.Is(err, service.ErrOIDCState):
+		status, code = http.StatusBadRequest, "INVALID_STATE"
+	case errors.Is(err, service.ErrOIDCExchange):
+		status, code = http.StatusBadRequest, "EXCHANGE_FAILED"
+	case errors.Is(err, service.ErrInvalidIDToken):
+		status, code = http.StatusUnauthorized, "INVALID_ID_TOKEN"
+	case errors.Is(err, service.ErrEmailNotVerified):
+		status, code = http.StatusForbidden, "EMAIL_NOT_VERIFIED"
+	case errors.Is(err, service.ErrIdentityLinked):
+		status, code = http.StatusConflict, "IDENTITY_ALREADY_LINKED"
+	case errors.Is(err, service.ErrLastLoginMethod):
+		status, code = http.StatusConflict, "LAST_LOGIN_METHOD"
+	case errors.Is(err, service.ErrOIDCProviderUnavailable):
+		status, code = http.StatusServiceUnavailable, "PROVIDER_UNAVAILABLE"
+	default:
+		log.Printf("[%s] ERROR: %v", handler, err)
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: oidcErrorMessage(err),
+		},
+	})
+}
+
+// oidcErrorMessage is the user-facing message for a sign-in error
+func oidcErrorMessage(err error) string {
+	for _, known := range []error{
+		service.ErrUnknownOIDCProvider,
+		service.ErrIdentityNotFound,
+		service.ErrOIDCState,
+		service.ErrOIDCExchange,
+		service.ErrInvalidIDToken,
+		service.ErrEmailNotVerified,
+		service.ErrIdentityLinked,
+		service.ErrLastLoginMethod,
+		service.ErrOIDCProviderUnavailable,
+	} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+	return "Failed to authenticate user"
+}
+
+func redirectLoginError(c *gin.Context, message string) {
+	c.Redirect(http.StatusTemporaryRedirect, fmt.Sprintf("%s/login?error=%s", frontendURL(), url.QueryEscape(message)))
+}
+
+func frontendURL() string {
+	if u := os.Getenv("FRONTEND_URL"); u != "" {
+		return u
+	}
+	return "http://localhost:3000"
+}
@@ -0,0 +1,684 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxInviteFileSize caps the CSV uploaded to invite students
+const maxInviteFileSize = 1 << 20
+
+// CreateOrganization godoc
+// @Summary Create an organization
+// @Description Create a language centre or school. The account with admin_email, if given, becomes its first admin.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateOrganizationRequest true "Organization"
+// @Success 201 {object} models.SuccessResponse{data=models.Organization}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/organizations [post]
+func (h *AuthHandler) CreateOrganization(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateOrganizationRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	org, err := h.organizationService.CreateOrganization(&req, adminID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondOrganizationError(c, "CreateOrganization", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Success: true,
+		Message: "Organization created",
+		Data:    org,
+	})
+}
+
+// SearchOrganizations godoc
+// @Summary Search organizations
+// @Description Organizations whose name or slug contains q, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Part of the name or slug"
+// @Param page query int false "Page (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} models.SuccessResponse{data=models.OrganizationList}
+// @Router /admin/organizations [get]
+func (h *AuthHandler) SearchOrganizations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	list, err := h.organizationService.SearchOrganizations(c.Query("q"), page, limit)
+	if err != nil {
+		respondOrganizationError(c, "SearchOrganizations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// ListMyOrganizations godoc
+// @Summary List my organizations
+// @Description Organizations the user belongs to, with their role in each
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=[]models.Organization}
+// @Router /organizations [get]
+func (h *AuthHandler) ListMyOrganizations(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	orgs, err := h.organizationService.ListMyOrganizations(userID)
+	if err != nil {
+		respondOrganizationError(c, "ListMyOrganizations", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    orgs,
+	})
+}
+
+// ListOrganizationMembers godoc
+// @Summary List organization members
+// @Description Members and their roles. Instructors and admins of the organization only.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {object} models.SuccessResponse{data=[]models.OrganizationMember}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id}/members [get]
+func (h *AuthHandler) ListOrganizationMembers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orgID, ok := pathUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	members, err := h.organizationService.ListMembers(orgID, userID)
+	if err != nil {
+		respondOrganizationError(c, "ListOrganizationMembers", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    members,
+	})
+}
+
+// AddOrganizationMember godoc
+// @Summary Add an organization member
+// @Description Add an existing account with a role, or change a member's role. Organization admins only.
+// @Tags organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body models.AddOrganizationMemberRequest true "Member"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id}/members [post]
+func (h *AuthHandler) AddOrganizationMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orgID, ok := pathUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	var req models.AddOrganizationMemberRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.organizationService.AddMember(orgID, userID, &req, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondOrganizationError(c, "AddOrganizationMember", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Member added",
+	})
+}
+
+// RemoveOrganizationMember godoc
+// @Summary Remove an organization member
+// @Description Remove a member from the organization and all its classrooms. Organization admins only.
+// @Tags organizations
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id}/members/{user_id} [delete]
+func (h *AuthHandler) RemoveOrganizationMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orgID, ok := pathUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(c, "user_id", "user")
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(orgID, memberID, userID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondOrganizationError(c, "RemoveOrganizationMember", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Member removed",
+	})
+}
+
+// CreateClassroom godoc
+// @Summary Create a classroom
+// @Description Create a classroom taught by the caller, with a fresh invite code. Instructors and admins of the organization only.
+// @Tags classrooms
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Param request body models.CreateClassroomRequest true "Classroom"
+// @Success 201 {object} models.SuccessResponse{data=models.Classroom}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id}/classrooms [post]
+func (h *AuthHandler) CreateClassroom(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orgID, ok := pathUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	var req models.CreateClassroomRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	classroom, err := h.organizationService.CreateClassroom(orgID, userID, &req)
+	if err != nil {
+		respondOrganizationError(c, "CreateClassroom", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse{
+		Success: true,
+		Message: "Classroom created",
+		Data:    classroom,
+	})
+}
+
+// ListClassrooms godoc
+// @Summary List an organization's classrooms
+// @Description Every classroom for organization admins; the caller's own classrooms for everyone else
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Organization ID"
+// @Success 200 {object} models.SuccessResponse{data=[]models.Classroom}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /organizations/{id}/classrooms [get]
+func (h *AuthHandler) ListClassrooms(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	orgID, ok := pathUUID(c, "id", "organization")
+	if !ok {
+		return
+	}
+
+	classrooms, err := h.organizationService.ListClassrooms(orgID, userID)
+	if err != nil {
+		respondOrganizationError(c, "ListClassrooms", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    classrooms,
+	})
+}
+
+// ListMyClassrooms godoc
+// @Summary List my classrooms
+// @Description Classrooms the user teaches or studies in, across organizations
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=[]models.Classroom}
+// @Router /classrooms [get]
+func (h *AuthHandler) ListMyClassrooms(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	classrooms, err := h.organizationService.ListMyClassrooms(userID)
+	if err != nil {
+		respondOrganizationError(c, "ListMyClassrooms", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    classrooms,
+	})
+}
+
+// JoinClassroom godoc
+// @Summary Join a classroom
+// @Description Join a classroom as a student with its invite code
+// @Tags classrooms
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.JoinClassroomRequest true "Invite code"
+// @Success 200 {object} models.SuccessResponse{data=models.Classroom}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /classrooms/join [post]
+func (h *AuthHandler) JoinClassroom(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.JoinClassroomRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	classroom, err := h.organizationService.JoinClassroom(userID, req.Code, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondOrganizationError(c, "JoinClassroom", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Joined classroom",
+		Data:    classroom,
+	})
+}
+
+// GetClassroomRoster godoc
+// @Summary Get a classroom roster
+// @Description Teachers, students and pending invites. Teachers and organization admins only.
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Success 200 {object} models.SuccessResponse{data=models.ClassroomRoster}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/members [get]
+func (h *AuthHandler) GetClassroomRoster(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	roster, err := h.organizationService.GetRoster(classroomID, userID)
+	if err != nil {
+		respondOrganizationError(c, "GetClassroomRoster", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    roster,
+	})
+}
+
+// AddClassroomMember godoc
+// @Summary Add a classroom member
+// @Description Make an existing account a teacher, or invite it as a student. Students join once they enter the classroom's code. Teachers must be instructors or admins of the organization.
+// @Tags classrooms
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Param request body models.AddClassroomMemberRequest true "Member"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/members [post]
+func (h *AuthHandler) AddClassroomMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	var req models.AddClassroomMemberRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.organizationService.AddClassroomMember(classroomID, userID, &req); err != nil {
+		respondOrganizationError(c, "AddClassroomMember", err)
+		return
+	}
+
+	message := "Member added"
+	if req.Role == models.RoleStudent {
+		message = "Student invited; they join once they enter the classroom code"
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: message,
+	})
+}
+
+// RemoveClassroomMember godoc
+// @Summary Remove a classroom member
+// @Description Remove a teacher or student from the classroom; they stay in the organization
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Param user_id path string true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/members/{user_id} [delete]
+func (h *AuthHandler) RemoveClassroomMember(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+	memberID, ok := pathUUID(c, "user_id", "user")
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.RemoveClassroomMember(classroomID, memberID, userID); err != nil {
+		respondOrganizationError(c, "RemoveClassroomMember", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Member removed",
+	})
+}
+
+// InviteClassroomStudents godoc
+// @Summary Invite students from a CSV
+// @Description Upload a CSV with one email per line or an "email" column. Existing accounts are notified in the app and other addresses emailed the invite code; students join once they enter it.
+// @Tags classrooms
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Param file formData file true "CSV of emails (max 500, 1 MB)"
+// @Success 200 {object} models.SuccessResponse{data=models.ClassroomInviteResult}
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/invites [post]
+func (h *AuthHandler) InviteClassroomStudents(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil || header.Size > maxInviteFileSize {
+		respondOrganizationError(c, "InviteClassroomStudents", service.ErrInvalidInviteFile)
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondOrganizationError(c, "InviteClassroomStudents", err)
+		return
+	}
+	defer file.Close()
+
+	result, err := h.organizationService.InviteStudents(classroomID, userID, file)
+	if err != nil {
+		respondOrganizationError(c, "InviteClassroomStudents", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// RevokeClassroomInvite godoc
+// @Summary Revoke a classroom invite
+// @Description Withdraw a pending invite. The emailed code keeps working until it is regenerated.
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Param invite_id path string true "Invite ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/invites/{invite_id} [delete]
+func (h *AuthHandler) RevokeClassroomInvite(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+	inviteID, ok := pathUUID(c, "invite_id", "invite")
+	if !ok {
+		return
+	}
+
+	if err := h.organizationService.RevokeInvite(classroomID, inviteID, userID); err != nil {
+		respondOrganizationError(c, "RevokeClassroomInvite", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Invite revoked",
+	})
+}
+
+// RegenerateClassroomInviteCode godoc
+// @Summary Regenerate a classroom invite code
+// @Description Replace the invite code so the old one stops working
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Success 200 {object} models.SuccessResponse{data=models.Classroom}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/invite-code [post]
+func (h *AuthHandler) RegenerateClassroomInviteCode(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	classroom, err := h.organizationService.RegenerateInviteCode(classroomID, userID)
+	if err != nil {
+		respondOrganizationError(c, "RegenerateClassroomInviteCode", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Invite code regenerated",
+		Data:    classroom,
+	})
+}
+
+// GetClassroomProgress godoc
+// @Summary Get classroom progress
+// @Description Each student's learning progress and skill statistics, with class averages. Teachers and organization admins only.
+// @Tags classrooms
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Classroom ID"
+// @Success 200 {object} models.SuccessResponse{data=models.ClassroomProgress}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /classrooms/{id}/progress [get]
+func (h *AuthHandler) GetClassroomProgress(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	classroomID, ok := pathUUID(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	progress, err := h.organizationService.GetClassroomProgress(classroomID, userID)
+	if err != nil {
+		respondOrganizationError(c, "GetClassroomProgress", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    progress,
+	})
+}
+
+// pathUUID reads a UUID path parameter; label names it in the error
+func pathUUID(c *gin.Context, name, label string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Invalid " + label + " ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondOrganizationError maps the service's organization and classroom errors to status codes
+func respondOrganizationError(c *gin.Context, action string, err error) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case errors.Is(err, service.ErrOrganizationNotFound):
+		status, code = http.StatusNotFound, "ORGANIZATION_NOT_FOUND"
+	case errors.Is(err, service.ErrClassroomNotFound):
+		status, code = http.StatusNotFound, "CLASSROOM_NOT_FOUND"
+	case errors.Is(err, service.ErrMemberNotFound):
+		status, code = http.StatusNotFound, "MEMBER_NOT_FOUND"
+	case errors.Is(err, service.ErrInviteNotFound):
+		status, code = http.StatusNotFound, "INVITE_NOT_FOUND"
+	case errors.Is(err, service.ErrUserNotFound):
+		status, code = http.StatusNotFound, "USER_NOT_FOUND"
+	case errors.Is(err, service.ErrOrganizationForbidden):
+		status, code = http.StatusForbidden, "FORBIDDEN"
+	case errors.Is(err, service.ErrOrganizationSlugTaken):
+		status, code = http.StatusConflict, "SLUG_TAKEN"
+	case errors.Is(err, service.ErrInvalidSlug):
+		status, code = http.StatusBadRequest, "INVALID_SLUG"
+	case errors.Is(err, service.ErrInvalidInviteCode):
+		status, code = http.StatusBadRequest, "INVALID_INVITE_CODE"
+	case errors.Is(err, service.ErrAlreadyInClassroom):
+		status, code = http.StatusConflict, "ALREADY_IN_CLASSROOM"
+	case errors.Is(err, service.ErrTeacherRoleRequired):
+		status, code = http.StatusBadRequest, "TEACHER_ROLE_REQUIRED"
+	case errors.Is(err, service.ErrInvalidInviteFile):
+		status, code = http.StatusBadRequest, "INVALID_INVITE_FILE"
+	case errors.Is(err, service.ErrTooManyInvites):
+		status, code = http.StatusBadRequest, "TOO_MANY_INVITES"
+	case errors.Is(err, service.ErrRoleNotFound):
+		status, code = http.StatusBadRequest, "ROLE_NOT_FOUND"
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("[%s] ERROR: %v", action, err)
+		message = "Failed to manage organization"
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
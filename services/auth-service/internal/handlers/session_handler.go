@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListSessions godoc
+// @Summary List active sessions
+// @Description Devices signed in to the account, with IP and created/last-used times
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse{data=[]models.Session}
+// @Failure 401 {object} models.ErrorResponse
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(userID, currentSessionID(c))
+	if err != nil {
+		log.Printf("[ListSessions] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to list sessions",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    sessions,
+	})
+}
+
+// RevokeSession godoc
+// @Summary Revoke a session
+// @Description Sign a device out; its access token stops working at the gateway immediately
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Session ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Invalid session ID",
+			},
+		})
+		return
+	}
+
+	if err := h.authService.RevokeSession(userID, sessionID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "SESSION_NOT_FOUND",
+					Message: "Session not found",
+				},
+			})
+			return
+		}
+		log.Printf("[RevokeSession] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to revoke session",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Session revoked",
+	})
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke all other sessions
+// @Description Sign out every device except the one making the request
+// @Tags sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Router /auth/sessions [delete]
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.authService.RevokeOtherSessions(userID, currentSessionID(c), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		log.Printf("[RevokeOtherSessions] ERROR: %v", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to revoke sessions",
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Other sessions revoked",
+		Data:    gin.H{"revoked": revoked},
+	})
+}
+
+// currentSessionID is the session of the request's access token, or uuid.Nil
+// for tokens issued before sessions were tracked
+func currentSessionID(c *gin.Context) uuid.UUID {
+	sessionID, _ := uuid.Parse(c.GetString("session_id"))
+	return sessionID
+}
@@ -0,0 +1,366 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SearchUsers godoc
+// @Summary Search users
+// @Description Users with their roles, newest first
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param q query string false "Part of the email, or an exact user ID"
+// @Param role query string false "Role name"
+// @Param status query string false "active, suspended or locked"
+// @Param verified query bool false "Email verified"
+// @Param created_from query string false "Created on or after (YYYY-MM-DD or RFC 3339)"
+// @Param created_to query string false "Created before (YYYY-MM-DD or RFC 3339)"
+// @Param page query int false "Page (default 1)"
+// @Param limit query int false "Page size (default 20, max 100)"
+// @Success 200 {object} models.SuccessResponse{data=models.AdminUserList}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /admin/users [get]
+func (h *AuthHandler) SearchUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := models.UserFilter{
+		Query:  c.Query("q"),
+		Role:   c.Query("role"),
+		Status: c.Query("status"),
+	}
+	switch filter.Status {
+	case "", models.UserStatusActive, models.UserStatusSuspended, models.UserStatusLocked:
+	default:
+		respondInvalidQuery(c, "status must be active, suspended or locked")
+		return
+	}
+	if raw := c.Query("verified"); raw != "" {
+		verified, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondInvalidQuery(c, "verified must be true or false")
+			return
+		}
+		filter.Verified = &verified
+	}
+	var ok bool
+	if filter.CreatedFrom, ok = parseDateQuery(c, "created_from"); !ok {
+		return
+	}
+	if filter.CreatedTo, ok = parseDateQuery(c, "created_to"); !ok {
+		return
+	}
+
+	list, err := h.authService.SearchUsers(filter, page, limit)
+	if err != nil {
+		respondUserAdminError(c, "SearchUsers", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    list,
+	})
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Description A user with their roles and lockout
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.SuccessResponse{data=models.AdminUser}
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id} [get]
+func (h *AuthHandler) GetUser(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.authService.GetUser(userID)
+	if err != nil {
+		respondUserAdminError(c, "GetUser", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    user,
+	})
+}
+
+// SuspendUser godoc
+// @Summary Suspend a user
+// @Description Deactivate an account and sign every session out
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.SuspendUserRequest true "Reason"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/users/{id}/suspend [post]
+func (h *AuthHandler) SuspendUser(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.SuspendUserRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	if err := h.authService.SuspendUser(userID, adminID, req.Reason, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondUserAdminError(c, "SuspendUser", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Account suspended",
+	})
+}
+
+// ReactivateUser godoc
+// @Summary Reactivate a user
+// @Description Let a suspended account sign in again
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Router /admin/users/{id}/reactivate [post]
+func (h *AuthHandler) ReactivateUser(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.ReactivateUser(userID, adminID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondUserAdminError(c, "ReactivateUser", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Account reactivated",
+	})
+}
+
+// ChangeUserRoles godoc
+// @Summary Change roles in bulk
+// @Description Assign or revoke one role for up to 100 users. Users losing a role are signed out; a user's only role is never revoked.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.BulkRoleChangeRequest true "Users, role and action"
+// @Success 200 {object} models.SuccessResponse{data=models.BulkRoleChangeResult}
+// @Failure 400 {object} models.ErrorResponse
+// @Router /admin/users/roles [post]
+func (h *AuthHandler) ChangeUserRoles(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.BulkRoleChangeRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	result, err := h.authService.ChangeUserRoles(&req, adminID, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondUserAdminError(c, "ChangeUserRoles", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Roles updated",
+		Data:    result,
+	})
+}
+
+// ImpersonateUser godoc
+// @Summary Impersonate a user
+// @Description Issue a short-lived access token for acting as the user, with the admin in its act claim. It cannot be refreshed and every request made with it is flagged in the gateway logs.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body models.ImpersonateRequest true "Reason and duration"
+// @Success 200 {object} models.SuccessResponse{data=models.ImpersonationData}
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /admin/users/{id}/impersonate [post]
+func (h *AuthHandler) ImpersonateUser(c *gin.Context) {
+	adminID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req models.ImpersonateRequest
+	if !bindMFARequest(c, &req) {
+		return
+	}
+
+	data, err := h.authService.Impersonate(userID, adminID, &req, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		respondUserAdminError(c, "ImpersonateUser", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// EndImpersonation godoc
+// @Summary End impersonation
+// @Description Revoke the impersonation token used for this request before it expires
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Router /auth/impersonation [delete]
+func (h *AuthHandler) EndImpersonation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	adminID, err := uuid.Parse(c.GetString("impersonator_id"))
+	sessionID, sidErr := uuid.Parse(c.GetString("session_id"))
+	if err != nil || sidErr != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "NOT_IMPERSONATING",
+				Message: "This token is not an impersonation token",
+			},
+		})
+		return
+	}
+
+	if err := h.authService.EndImpersonation(userID, adminID, sessionID, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		respondUserAdminError(c, "EndImpersonation", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Success: true,
+		Message: "Impersonation ended",
+	})
+}
+
+func userIDParam(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "VALIDATION_ERROR",
+				Message: "Invalid user ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// parseDateQuery reads an optional date or RFC 3339 timestamp from the query
+func parseDateQuery(c *gin.Context, name string) (*time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, true
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, true
+		}
+	}
+	respondInvalidQuery(c, name+" must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	return nil, false
+}
+
+func respondInvalidQuery(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "VALIDATION_ERROR",
+			Message: message,
+		},
+	})
+}
+
+// respondUserAdminError maps the service's user management errors to status codes
+func respondUserAdminError(c *gin.Context, action string, err error) {
+	status, code := http.StatusInternalServerError, "INTERNAL_ERROR"
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		status, code = http.StatusNotFound, "USER_NOT_FOUND"
+	case errors.Is(err, service.ErrRoleNotFound):
+		status, code = http.StatusBadRequest, "ROLE_NOT_FOUND"
+	case errors.Is(err, service.ErrCannotModifySelf):
+		status, code = http.StatusBadRequest, "CANNOT_MODIFY_SELF"
+	case errors.Is(err, service.ErrUserSuspended):
+		status, code = http.StatusConflict, "ALREADY_SUSPENDED"
+	case errors.Is(err, service.ErrUserNotSuspended):
+		status, code = http.StatusConflict, "NOT_SUSPENDED"
+	case errors.Is(err, service.ErrCannotImpersonate):
+		status, code = http.StatusForbidden, "CANNOT_IMPERSONATE"
+	}
+
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		log.Printf("[%s] ERROR: %v", action, err)
+		message = "Failed to manage user"
+	}
+
+	c.JSON(status, models.ErrorResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
@@ -57,6 +57,30 @@ func AuthMiddleware(authService service.AuthService) gin.HandlerFunc {
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 		c.Set("role", claims.Role)
+		c.Set("session_id", claims.SessionID)
+		if claims.Actor != nil {
+			c.Set("impersonator_id", claims.Actor.Subject)
+		}
+
+		c.Next()
+	}
+}
+
+// RejectImpersonation keeps impersonation tokens away from account
+// self-service such as passwords, sessions and data deletion
+func RejectImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, impersonating := c.Get("impersonator_id"); impersonating {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "IMPERSONATION_NOT_ALLOWED",
+					Message: "This action is not available while impersonating a user",
+				},
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -102,3 +126,35 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// InternalAuth validates the internal API key for service-to-service calls
+func InternalAuth(internalAPIKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-Internal-API-Key")
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "MISSING_API_KEY",
+					Message: "Internal API key required",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if apiKey != internalAPIKey {
+			c.JSON(http.StatusForbidden, models.ErrorResponse{
+				Success: false,
+				Error: &models.ErrorData{
+					Code:    "INVALID_API_KEY",
+					Message: "Invalid internal API key",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,5 +1,12 @@
 package models
 
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx/types"
+)
+
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
 	Email           string  `json:"email" binding:"required,email"`
@@ -23,8 +30,9 @@ type RefreshTokenRequest struct {
 
 // ChangePasswordRequest represents a change password request
 type ChangePasswordRequest struct {
-	OldPassword string `json:"old_password" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	OldPassword    string `json:"old_password" binding:"required"`
+	NewPassword    string `json:"new_password" binding:"required,min=8"`
+	RevokeSessions *bool  `json:"revoke_sessions,omitempty"` // sign out every session; defaults to true
 }
 
 // ForgotPasswordRequest represents a forgot password request
@@ -34,21 +42,135 @@ type ForgotPasswordRequest struct {
 
 // ResetPasswordRequest represents a reset password request
 type ResetPasswordRequest struct {
-	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	Token          string `json:"token" binding:"required"`
+	NewPassword    string `json:"new_password" binding:"required,min=8"`
+	RevokeSessions *bool  `json:"revoke_sessions,omitempty"` // sign out every session; defaults to true
 }
 
 // ResetPasswordByCodeRequest represents a reset password request with code
 type ResetPasswordByCodeRequest struct {
-	Code        string `json:"code" binding:"required,len=6"`
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	Email          string `json:"email" binding:"required,email"` // wrong codes count against this account's code
+	Code           string `json:"code" binding:"required,len=6"`
+	NewPassword    string `json:"new_password" binding:"required,min=8"`
+	RevokeSessions *bool  `json:"revoke_sessions,omitempty"` // sign out every session; defaults to true
 }
 
 // VerifyEmailByCodeRequest represents an email verification request with code
 type VerifyEmailByCodeRequest struct {
+	Email string `json:"email" binding:"required,email"` // wrong codes count against this account's code
+	Code  string `json:"code" binding:"required,len=6"`
+}
+
+// EmailLoginCodeRequest asks for a sign-in code and magic link by email
+type EmailLoginCodeRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// VerifyEmailLoginCodeRequest signs in with the emailed code, or with the
+// token from the magic link
+type VerifyEmailLoginCodeRequest struct {
+	Email string `json:"email,omitempty" binding:"omitempty,email"`
+	Code  string `json:"code,omitempty" binding:"omitempty,len=6"`
+	Token string `json:"token,omitempty"`
+}
+
+// ChangeEmailRequest starts an email change. Code is required when
+// two-factor authentication is enabled.
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code,omitempty"`
+}
+
+// ConfirmEmailChangeRequest carries the code sent to the new address
+type ConfirmEmailChangeRequest struct {
 	Code string `json:"code" binding:"required,len=6"`
 }
 
+// CancelEmailChangeRequest carries the token from the link sent to the old address
+type CancelEmailChangeRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// MFACodeRequest carries a code from the user's authenticator app
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFADisableRequest turns off two-factor authentication; Code may be a TOTP or recovery code.
+// Password is required unless the account only signs in with Google.
+type MFADisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFAVerifyRequest completes a login challenge with either a TOTP code or a recovery code
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// MFAPolicyRequest sets whether a role must use two-factor authentication
+type MFAPolicyRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+// AccountDeletionRequest confirms the user's identity before scheduling the
+// account for deletion. Password is required unless the account has none;
+// Code is required when two-factor authentication is enabled.
+type AccountDeletionRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// Account statuses admins can filter users by
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusLocked    = "locked" // locked out by failed sign-ins
+)
+
+// UserFilter narrows the admin user search; empty fields match every user
+type UserFilter struct {
+	Query       string // part of the email, or an exact user ID
+	Role        string
+	Status      string
+	Verified    *bool
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+}
+
+// SuspendUserRequest records why an admin suspended an account
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// BulkRoleChangeRequest assigns or revokes one role for several users
+type BulkRoleChangeRequest struct {
+	UserIDs []string `json:"user_ids" binding:"required,min=1,max=100,dive,uuid"`
+	Role    string   `json:"role" binding:"required"`
+	Action  string   `json:"action" binding:"required,oneof=assign revoke"`
+}
+
+// ImpersonateRequest starts acting as a user to reproduce an issue. Duration
+// defaults to, and is capped at, IMPERSONATION_MAX_DURATION.
+type ImpersonateRequest struct {
+	Reason          string `json:"reason" binding:"required,max=500"`
+	DurationMinutes int    `json:"duration_minutes" binding:"omitempty,min=1"`
+}
+
+// AuditEventRequest is an audit entry reported by another service, e.g. a 403 at the gateway
+type AuditEventRequest struct {
+	UserID       *uuid.UUID             `json:"user_id"`
+	EventType    string                 `json:"event_type" binding:"required,max=50"`
+	EventStatus  string                 `json:"event_status" binding:"required,max=20"`
+	IPAddress    string                 `json:"ip_address" binding:"omitempty,max=45"`
+	UserAgent    string                 `json:"user_agent"`
+	Metadata     map[string]interface{} `json:"metadata"`
+	ErrorMessage string                 `json:"error_message"`
+}
+
 // AuthResponse represents an authentication response
 type AuthResponse struct {
 	Success bool       `json:"success"`
@@ -56,7 +178,8 @@ type AuthResponse struct {
 	Error   *ErrorData `json:"error,omitempty"`
 }
 
-// AuthData represents authentication data in response
+// AuthData represents authentication data in response. When a second factor
+// is needed, tokens are empty and MFAToken must be exchanged at /auth/mfa/verify.
 type AuthData struct {
 	UserID       string `json:"user_id"`
 	Email        string `json:"email"`
@@ -64,6 +187,46 @@ type AuthData struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	ExpiresIn    int64  `json:"expires_in"` // seconds
+
+	MFARequired           bool               `json:"mfa_required,omitempty"`
+	MFAEnrollmentRequired bool               `json:"mfa_enrollment_required,omitempty"` // role policy requires MFA the user has not set up
+	MFAToken              string             `json:"mfa_token,omitempty"`
+	MFAEnrollment         *MFAEnrollmentData `json:"mfa_enrollment,omitempty"`
+	RecoveryCodes         []string           `json:"recovery_codes,omitempty"` // returned once, when enrolment completes at login
+}
+
+// Session is a signed-in device, backed by a refresh token
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	DeviceName string    `json:"device_name"`
+	DeviceType string    `json:"device_type"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // the session making the request
+}
+
+// MFAEnrollmentData is shown once while setting up an authenticator app
+type MFAEnrollmentData struct {
+	Secret          string `json:"secret"`           // base32, for manual entry
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// MFAStatus describes a user's two-factor authentication state
+type MFAStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RequiredByRole         bool       `json:"required_by_role"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// MFAPolicy reports whether a role must use two-factor authentication
+type MFAPolicy struct {
+	Role        string `json:"role"`
+	DisplayName string `json:"display_name"`
+	Required    bool   `json:"required"`
 }
 
 // ErrorData represents error data in response
@@ -85,3 +248,197 @@ type ErrorResponse struct {
 	Success bool       `json:"success"`
 	Error   *ErrorData `json:"error"`
 }
+
+// AccountDataRequestList is a page of data requests for admins
+type AccountDataRequestList struct {
+	Requests []AccountDataRequest `json:"requests"`
+	Total    int                  `json:"total"`
+	Page     int                  `json:"page"`
+	Limit    int                  `json:"limit"`
+}
+
+// AdminUser is an account as admins see it
+type AdminUser struct {
+	User
+	Roles       []string   `json:"roles"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+// AdminUserList is a page of users for admins
+type AdminUserList struct {
+	Users []AdminUser `json:"users"`
+	Total int         `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+}
+
+// BulkRoleChangeResult reports which users a bulk role change affected
+type BulkRoleChangeResult struct {
+	Changed []uuid.UUID `json:"changed"`
+	// Unchanged users already had (or lacked) the role, do not exist, or
+	// would have been left without any role
+	Unchanged []uuid.UUID `json:"unchanged"`
+}
+
+// ImpersonationData is an access token for acting as a user. It cannot be
+// refreshed and is rejected by the account's self-service endpoints.
+type ImpersonationData struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresIn   int64     `json:"expires_in"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	SessionID   uuid.UUID `json:"session_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+}
+
+// AuditLogFilter narrows an audit log search; empty fields match every entry
+type AuditLogFilter struct {
+	UserID      *uuid.UUID
+	EventTypes  []string
+	EventStatus string
+	IPAddress   string
+	From        *time.Time
+	To          *time.Time
+	BeforeID    int64 // cursor: only entries older than this one
+	Archived    bool  // search the entries moved out by the retention job
+}
+
+// AuditEvent is an audit log entry as admins see it
+type AuditEvent struct {
+	ID           int64              `db:"id" json:"id"`
+	UserID       *uuid.UUID         `db:"user_id" json:"user_id,omitempty"`
+	EventType    string             `db:"event_type" json:"event_type"`
+	EventStatus  string             `db:"event_status" json:"event_status"`
+	IPAddress    *string            `db:"ip_address" json:"ip_address,omitempty"`
+	UserAgent    *string            `db:"user_agent" json:"user_agent,omitempty"`
+	Metadata     types.NullJSONText `db:"metadata" json:"metadata"`
+	ErrorMessage *string            `db:"error_message" json:"error_message,omitempty"`
+	CreatedAt    time.Time          `db:"created_at" json:"created_at"`
+}
+
+// AuditLogPage is a page of audit events, newest first. Pass NextCursor as
+// ?cursor= to get the next page; it is empty on the last one.
+type AuditLogPage struct {
+	Events     []AuditEvent `json:"events"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// Organization and classroom roles. They are rows of the roles table, held
+// per organization or classroom rather than platform-wide.
+const (
+	RoleStudent    = "student"
+	RoleInstructor = "instructor" // a classroom's teacher
+	RoleAdmin      = "admin"
+)
+
+// CreateOrganizationRequest creates an organization, optionally with its first admin
+type CreateOrganizationRequest struct {
+	Name       string `json:"name" binding:"required,max=200"`
+	Slug       string `json:"slug" binding:"required,max=100"`
+	AdminEmail string `json:"admin_email" binding:"omitempty,email"`
+}
+
+// OrganizationList is a page of organizations, newest first
+type OrganizationList struct {
+	Organizations []Organization `json:"organizations"`
+	Total         int            `json:"total"`
+	Page          int            `json:"page"`
+	Limit         int            `json:"limit"`
+}
+
+// AddOrganizationMemberRequest adds an existing account to an organization,
+// or changes the role of a member
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=student instructor admin"`
+}
+
+// CreateClassroomRequest creates a classroom taught by the caller
+type CreateClassroomRequest struct {
+	Name        string  `json:"name" binding:"required,max=200"`
+	Description *string `json:"description"`
+}
+
+// JoinClassroomRequest joins a classroom with its invite code
+type JoinClassroomRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// AddClassroomMemberRequest makes an existing account a teacher or invites it
+// as a student
+type AddClassroomMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=student instructor"`
+}
+
+// ClassroomRoster is a classroom with its members and the emails still invited
+type ClassroomRoster struct {
+	Classroom      Classroom         `json:"classroom"`
+	Members        []ClassroomMember `json:"members"`
+	PendingInvites []ClassroomInvite `json:"pending_invites"`
+}
+
+// ClassroomAccess tells another service what a user may do in a classroom,
+// together with its students
+type ClassroomAccess struct {
+	Classroom Classroom         `json:"classroom"`
+	Role      string            `json:"role"` // instructor, admin (of the organization), student or empty
+	Students  []ClassroomMember `json:"students"`
+}
+
+// ClassroomInviteResult reports what happened to each email of an invite upload
+type ClassroomInviteResult struct {
+	Invited        []string            `json:"invited"`         // sent the invite code; they join by entering it
+	AlreadyMembers []string            `json:"already_members"` // already in the classroom or invited
+	Invalid        []InvalidInviteLine `json:"invalid"`
+}
+
+// InvalidInviteLine is a line of an invite upload that was skipped
+type InvalidInviteLine struct {
+	Line   int    `json:"line"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// ClassroomProgress is each student's progress with class-wide averages
+type ClassroomProgress struct {
+	ClassroomID uuid.UUID            `json:"classroom_id"`
+	Summary     ClassProgressSummary `json:"summary"`
+	Students    []StudentProgress    `json:"students"`
+}
+
+// ClassProgressSummary averages the students' progress. Band score averages
+// only count students who have a score.
+type ClassProgressSummary struct {
+	StudentCount        int                 `json:"student_count"`
+	ActiveLast7Days     int                 `json:"active_last_7_days"`
+	AverageStudyHours   float64             `json:"average_study_hours"`
+	AverageOverallScore *float64            `json:"average_overall_score,omitempty"`
+	AverageBandScores   map[string]*float64 `json:"average_band_scores"`
+}
+
+// StudentProgress is one student's progress from user-service
+type StudentProgress struct {
+	UserID                  uuid.UUID                `json:"user_id"`
+	Email                   string                   `json:"email"`
+	FullName                *string                  `json:"full_name,omitempty"`
+	TotalStudyHours         float64                  `json:"total_study_hours"`
+	TotalLessonsCompleted   int                      `json:"total_lessons_completed"`
+	TotalExercisesCompleted int                      `json:"total_exercises_completed"`
+	OverallScore            *float64                 `json:"overall_score,omitempty"`
+	CurrentStreakDays       int                      `json:"current_streak_days"`
+	LastStudyDate           *time.Time               `json:"last_study_date,omitempty"`
+	Skills                  map[string]SkillProgress `json:"skills"`
+}
+
+// SkillProgress is a student's progress and practice statistics in one skill
+type SkillProgress struct {
+	Progress         float64    `json:"progress"`
+	BandScore        *float64   `json:"band_score,omitempty"`
+	TotalPractices   int        `json:"total_practices"`
+	AverageScore     float64    `json:"average_score"`
+	BestScore        float64    `json:"best_score"`
+	TotalTimeMinutes int        `json:"total_time_minutes"`
+	LastPracticeDate *time.Time `json:"last_practice_date,omitempty"`
+}
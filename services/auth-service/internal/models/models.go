@@ -37,6 +37,7 @@ type Role struct {
 	Name        string    `db:"name" json:"name"`
 	DisplayName string    `db:"display_name" json:"display_name"`
 	Description string    `db:"description" json:"description"`
+	MFARequired bool      `db:"mfa_required" json:"mfa_required"`
 	CreatedAt   time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt   time.Time `db:"updated_at" json:"updated_at"`
 }
@@ -66,6 +67,11 @@ type RefreshToken struct {
 	UserID    uuid.UUID `db:"user_id" json:"user_id"`
 	TokenHash string    `db:"token_hash" json:"-"`
 
+	// Tokens rotated from the same sign-in share a family, which is the session
+	FamilyID   uuid.UUID  `db:"family_id" json:"family_id"`
+	ReplacedBy *uuid.UUID `db:"replaced_by" json:"-"`
+	RotatedAt  *time.Time `db:"rotated_at" json:"-"`
+
 	DeviceID   *string `db:"device_id" json:"device_id,omitempty"`
 	DeviceName *string `db:"device_name" json:"device_name,omitempty"`
 	DeviceType *string `db:"device_type" json:"device_type,omitempty"`
@@ -79,6 +85,9 @@ type RefreshToken struct {
 
 	CreatedAt  time.Time `db:"created_at" json:"created_at"`
 	LastUsedAt time.Time `db:"last_used_at" json:"last_used_at"`
+
+	// First token's created_at, loaded only when listing sessions
+	SessionStartedAt *time.Time `db:"session_started_at" json:"-"`
 }
 
 // AuditLog represents an audit log entry
@@ -95,26 +104,127 @@ type AuditLog struct {
 	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
 }
 
+// SigningKey is an asymmetric key used to sign access tokens
+type SigningKey struct {
+	Kid          string    `db:"kid" json:"kid"`
+	Algorithm    string    `db:"algorithm" json:"algorithm"`
+	PrivateKey   string    `db:"private_key" json:"-"` // encrypted PKCS#8, base64
+	PublicKey    string    `db:"public_key" json:"public_key"`
+	NotBefore    time.Time `db:"not_before" json:"not_before"`
+	SigningUntil time.Time `db:"signing_until" json:"signing_until"`
+	VerifyUntil  time.Time `db:"verify_until" json:"verify_until"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// UserMFA is a user's TOTP enrolment; EnabledAt is nil until it is confirmed
+type UserMFA struct {
+	UserID       uuid.UUID  `db:"user_id" json:"user_id"`
+	TOTPSecret   string     `db:"totp_secret" json:"-"` // encrypted, base64
+	EnabledAt    *time.Time `db:"enabled_at" json:"enabled_at,omitempty"`
+	LastUsedStep *int64     `db:"last_used_step" json:"-"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// UserIdentity links a user to an account at an OpenID Connect provider
+type UserIdentity struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	UserID      uuid.UUID  `db:"user_id" json:"-"`
+	Provider    string     `db:"provider" json:"provider"`
+	Subject     string     `db:"subject" json:"-"`
+	Email       *string    `db:"email" json:"email,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	LastLoginAt *time.Time `db:"last_login_at" json:"last_login_at,omitempty"`
+}
+
 // PasswordResetToken represents a password reset token
 type PasswordResetToken struct {
-	ID        uuid.UUID  `db:"id" json:"id"`
-	UserID    uuid.UUID  `db:"user_id" json:"user_id"`
-	TokenHash string     `db:"token_hash" json:"-"`
-	Code      *string    `db:"code" json:"code,omitempty"`
-	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
-	UsedAt    *time.Time `db:"used_at" json:"used_at,omitempty"`
-	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	ID             uuid.UUID  `db:"id" json:"id"`
+	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
+	TokenHash      string     `db:"token_hash" json:"-"`
+	Code           *string    `db:"code" json:"code,omitempty"`
+	FailedAttempts int        `db:"failed_attempts" json:"-"` // wrong codes entered
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt         *time.Time `db:"used_at" json:"used_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// LoginCode is a single-use code and magic link for passwordless sign-in
+type LoginCode struct {
+	ID             uuid.UUID  `db:"id" json:"id"`
+	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
+	TokenHash      string     `db:"token_hash" json:"-"`
+	Code           string     `db:"code" json:"-"`
+	FailedAttempts int        `db:"failed_attempts" json:"-"` // wrong codes entered
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	UsedAt         *time.Time `db:"used_at" json:"used_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// EmailChangeRequest is a new email address awaiting confirmation with the
+// code sent to it
+type EmailChangeRequest struct {
+	ID              uuid.UUID  `db:"id" json:"id"`
+	UserID          uuid.UUID  `db:"user_id" json:"user_id"`
+	NewEmail        string     `db:"new_email" json:"new_email"`
+	Code            string     `db:"code" json:"-"`
+	CancelTokenHash string     `db:"cancel_token_hash" json:"-"`
+	FailedAttempts  int        `db:"failed_attempts" json:"-"` // wrong codes entered
+	ExpiresAt       time.Time  `db:"expires_at" json:"expires_at"`
+	ConfirmedAt     *time.Time `db:"confirmed_at" json:"confirmed_at,omitempty"`
+	CancelledAt     *time.Time `db:"cancelled_at" json:"cancelled_at,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
 }
 
 // EmailVerificationToken represents an email verification token
 type EmailVerificationToken struct {
-	ID         uuid.UUID  `db:"id" json:"id"`
-	UserID     uuid.UUID  `db:"user_id" json:"user_id"`
-	TokenHash  string     `db:"token_hash" json:"-"`
-	Code       *string    `db:"code" json:"code,omitempty"`
-	ExpiresAt  time.Time  `db:"expires_at" json:"expires_at"`
-	VerifiedAt *time.Time `db:"verified_at" json:"verified_at,omitempty"`
-	CreatedAt  time.Time  `db:"created_at" json:"created_at"`
+	ID             uuid.UUID  `db:"id" json:"id"`
+	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
+	TokenHash      string     `db:"token_hash" json:"-"`
+	Code           *string    `db:"code" json:"code,omitempty"`
+	FailedAttempts int        `db:"failed_attempts" json:"-"` // wrong codes entered
+	ExpiresAt      time.Time  `db:"expires_at" json:"expires_at"`
+	VerifiedAt     *time.Time `db:"verified_at" json:"verified_at,omitempty"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+}
+
+// Personal data request types and statuses
+const (
+	DataRequestExport   = "export"
+	DataRequestDeletion = "deletion"
+
+	DataRequestPending   = "pending"
+	DataRequestCompleted = "completed"
+	DataRequestFailed    = "failed"
+	DataRequestCancelled = "cancelled"
+)
+
+// AccountDataRequest is a personal data export or an account deletion,
+// carried out by one step per service holding the user's data
+type AccountDataRequest struct {
+	ID               uuid.UUID                `db:"id" json:"id"`
+	UserID           uuid.UUID                `db:"user_id" json:"user_id"`
+	Type             string                   `db:"request_type" json:"type"`
+	Status           string                   `db:"status" json:"status"`
+	ScheduledFor     time.Time                `db:"scheduled_for" json:"scheduled_for"` // deletion: end of the grace period
+	Attempts         int                      `db:"attempts" json:"attempts"`
+	LockedUntil      *time.Time               `db:"locked_until" json:"-"`
+	ArchiveExpiresAt *time.Time               `db:"archive_expires_at" json:"archive_expires_at,omitempty"`
+	LastError        *string                  `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt        time.Time                `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time                `db:"updated_at" json:"updated_at"`
+	CompletedAt      *time.Time               `db:"completed_at" json:"completed_at,omitempty"`
+	Steps            []AccountDataRequestStep `db:"-" json:"steps,omitempty"`
+}
+
+// AccountDataRequestStep is one service's part of an AccountDataRequest
+type AccountDataRequestStep struct {
+	RequestID uuid.UUID `db:"request_id" json:"-"`
+	Service   string    `db:"service" json:"service"`
+	Status    string    `db:"status" json:"status"`
+	Attempts  int       `db:"attempts" json:"attempts"`
+	LastError *string   `db:"last_error" json:"last_error,omitempty"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
 
 // UserWithRoles represents a user with their roles
@@ -122,3 +232,58 @@ type UserWithRoles struct {
 	User
 	Roles []Role `json:"roles"`
 }
+
+// Organization is a language centre or school with its own members and classrooms
+type Organization struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	Slug      string     `db:"slug" json:"slug"`
+	CreatedBy *uuid.UUID `db:"created_by" json:"created_by,omitempty"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updated_at"`
+	Role      string     `db:"role" json:"role,omitempty"` // the caller's role, when listing their own organizations
+}
+
+// OrganizationMember is a user's role inside an organization
+type OrganizationMember struct {
+	OrganizationID uuid.UUID  `db:"organization_id" json:"-"`
+	UserID         uuid.UUID  `db:"user_id" json:"user_id"`
+	Email          string     `db:"email" json:"email"`
+	Role           string     `db:"role" json:"role"`
+	AddedBy        *uuid.UUID `db:"added_by" json:"added_by,omitempty"`
+	JoinedAt       time.Time  `db:"joined_at" json:"joined_at"`
+}
+
+// Classroom is a cohort of students and their teachers inside an organization
+type Classroom struct {
+	ID             uuid.UUID  `db:"id" json:"id"`
+	OrganizationID uuid.UUID  `db:"organization_id" json:"organization_id"`
+	Name           string     `db:"name" json:"name"`
+	Description    *string    `db:"description" json:"description,omitempty"`
+	InviteCode     string     `db:"invite_code" json:"invite_code,omitempty"` // only shown to teachers
+	CreatedBy      *uuid.UUID `db:"created_by" json:"created_by,omitempty"`
+	StudentCount   int        `db:"student_count" json:"student_count"`
+	CreatedAt      time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time  `db:"updated_at" json:"updated_at"`
+	Role           string     `db:"role" json:"role,omitempty"` // the caller's role in the classroom, if a member
+}
+
+// ClassroomMember is a teacher ('instructor' role) or student of a classroom
+type ClassroomMember struct {
+	ClassroomID uuid.UUID  `db:"classroom_id" json:"-"`
+	UserID      uuid.UUID  `db:"user_id" json:"user_id"`
+	Email       string     `db:"email" json:"email"`
+	Role        string     `db:"role" json:"role"`
+	AddedBy     *uuid.UUID `db:"added_by" json:"added_by,omitempty"`
+	JoinedAt    time.Time  `db:"joined_at" json:"joined_at"`
+}
+
+// ClassroomInvite is an email invited to a classroom before it had an account
+type ClassroomInvite struct {
+	ID          uuid.UUID  `db:"id" json:"id"`
+	ClassroomID uuid.UUID  `db:"classroom_id" json:"-"`
+	Email       string     `db:"email" json:"email"`
+	InvitedBy   *uuid.UUID `db:"invited_by" json:"invited_by,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+	AcceptedAt  *time.Time `db:"accepted_at" json:"accepted_at,omitempty"`
+}
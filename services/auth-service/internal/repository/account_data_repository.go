@@ -0,0 +1,508 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/personaldata"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type AccountDataRepository interface {
+	Create(req *models.AccountDataRequest, services []string) error
+	FindByID(id uuid.UUID) (*models.AccountDataRequest, error)
+	FindPending(userID uuid.UUID, requestType string) (*models.AccountDataRequest, error)
+	ListByUserID(userID uuid.UUID) ([]models.AccountDataRequest, error)
+	List(status string, limit, offset int) ([]models.AccountDataRequest, int, error)
+	ClaimDue(lease time.Duration) (*models.AccountDataRequest, error)
+	CompleteStep(requestID uuid.UUID, service string, output []byte) error
+	FailStep(requestID uuid.UUID, service, errMsg string) error
+	StepOutputs(requestID uuid.UUID) (map[string][]byte, error)
+	Complete(id uuid.UUID, archive []byte, archiveExpiresAt *time.Time) error
+	RecordFailure(id uuid.UUID, errMsg string, retryAt *time.Time) error
+	CancelDeletion(userID uuid.UUID) (bool, error)
+	Retry(id uuid.UUID) (bool, error)
+	FindArchive(id, userID uuid.UUID) ([]byte, error)
+
+	// The auth service's own step
+	ExportAuthData(userID uuid.UUID) (map[string]json.RawMessage, error)
+	DeactivateUser(userID uuid.UUID) error
+	EraseAuthData(userID uuid.UUID) error
+}
+
+type accountDataRepository struct {
+	db *sqlx.DB
+}
+
+func NewAccountDataRepository(db *sqlx.DB) AccountDataRepository {
+	return &accountDataRepository{db: db}
+}
+
+const accountDataRequestColumns = `
+	id, user_id, request_type, status, scheduled_for, attempts, locked_until,
+	archive_expires_at, last_error, created_at, updated_at, completed_at
+`
+
+// Create stores the request with a pending step for each service
+func (r *accountDataRepository) Create(req *models.AccountDataRequest, services []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	if req.Status == "" {
+		req.Status = models.DataRequestPending
+	}
+
+	err = tx.QueryRowx(`
+		INSERT INTO account_data_requests (id, user_id, request_type, status, scheduled_for)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at
+	`, req.ID, req.UserID, req.Type, req.Status, req.ScheduledFor).Scan(&req.CreatedAt, &req.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create data request: %w", err)
+	}
+
+	req.Steps = make([]models.AccountDataRequestStep, 0, len(services))
+	for _, service := range services {
+		step := models.AccountDataRequestStep{RequestID: req.ID, Service: service, Status: models.DataRequestPending}
+		err := tx.QueryRowx(`
+			INSERT INTO account_data_request_steps (request_id, service, status)
+			VALUES ($1, $2, $3)
+			RETURNING updated_at
+		`, step.RequestID, step.Service, step.Status).Scan(&step.UpdatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to create %s step: %w", service, err)
+		}
+		req.Steps = append(req.Steps, step)
+	}
+
+	return tx.Commit()
+}
+
+// FindByID returns the request with its steps
+func (r *accountDataRepository) FindByID(id uuid.UUID) (*models.AccountDataRequest, error) {
+	var req models.AccountDataRequest
+	err := r.db.Get(&req, `SELECT `+accountDataRequestColumns+` FROM account_data_requests WHERE id = $1`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("data request not found")
+		}
+		return nil, fmt.Errorf("failed to find data request: %w", err)
+	}
+
+	if err := r.loadSteps(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// FindPending returns the user's open request of the given type, or nil
+func (r *accountDataRepository) FindPending(userID uuid.UUID, requestType string) (*models.AccountDataRequest, error) {
+	var req models.AccountDataRequest
+	err := r.db.Get(&req, `
+		SELECT `+accountDataRequestColumns+`
+		FROM account_data_requests
+		WHERE user_id = $1 AND request_type = $2 AND status = $3
+	`, userID, requestType, models.DataRequestPending)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find data request: %w", err)
+	}
+
+	return &req, nil
+}
+
+// ListByUserID returns the user's requests, newest first
+func (r *accountDataRepository) ListByUserID(userID uuid.UUID) ([]models.AccountDataRequest, error) {
+	requests := []models.AccountDataRequest{}
+	err := r.db.Select(&requests, `
+		SELECT `+accountDataRequestColumns+`
+		FROM account_data_requests
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 50
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+// List returns requests in a status (any if empty) with their steps, newest first
+func (r *accountDataRepository) List(status string, limit, offset int) ([]models.AccountDataRequest, int, error) {
+	var total int
+	if err := r.db.Get(&total, `SELECT COUNT(*) FROM account_data_requests WHERE $1 = '' OR status = $1`, status); err != nil {
+		return nil, 0, fmt.Errorf("failed to count data requests: %w", err)
+	}
+
+	requests := []models.AccountDataRequest{}
+	err := r.db.Select(&requests, `
+		SELECT `+accountDataRequestColumns+`
+		FROM account_data_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list data requests: %w", err)
+	}
+
+	for i := range requests {
+		if err := r.loadSteps(&requests[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return requests, total, nil
+}
+
+// ClaimDue leases the next pending request whose time has come, so other
+// replicas skip it while it runs. It returns nil when nothing is due.
+func (r *accountDataRepository) ClaimDue(lease time.Duration) (*models.AccountDataRequest, error) {
+	var req models.AccountDataRequest
+	err := r.db.Get(&req, `
+		UPDATE account_data_requests
+		SET locked_until = NOW() + $2 * INTERVAL '1 second'
+		WHERE id = (
+			SELECT id FROM account_data_requests
+			WHERE status = $1 AND scheduled_for <= NOW()
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY scheduled_for
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+accountDataRequestColumns, models.DataRequestPending, lease.Seconds())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim data request: %w", err)
+	}
+
+	if err := r.loadSteps(&req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// CompleteStep marks a service's step done, keeping what an export returned
+func (r *accountDataRepository) CompleteStep(requestID uuid.UUID, service string, output []byte) error {
+	_, err := r.db.Exec(`
+		UPDATE account_data_request_steps
+		SET status = $3, attempts = attempts + 1, last_error = NULL, output = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE request_id = $1 AND service = $2
+	`, requestID, service, models.DataRequestCompleted, output)
+	if err != nil {
+		return fmt.Errorf("failed to complete %s step: %w", service, err)
+	}
+	return nil
+}
+
+// FailStep records a failed attempt at a service's step
+func (r *accountDataRepository) FailStep(requestID uuid.UUID, service, errMsg string) error {
+	_, err := r.db.Exec(`
+		UPDATE account_data_request_steps
+		SET status = $3, attempts = attempts + 1, last_error = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE request_id = $1 AND service = $2
+	`, requestID, service, models.DataRequestFailed, errMsg)
+	if err != nil {
+		return fmt.Errorf("failed to record failed %s step: %w", service, err)
+	}
+	return nil
+}
+
+// StepOutputs returns the data each completed export step collected, by service
+func (r *accountDataRepository) StepOutputs(requestID uuid.UUID) (map[string][]byte, error) {
+	rows, err := r.db.Query(`
+		SELECT service, output FROM account_data_request_steps
+		WHERE request_id = $1 AND output IS NOT NULL
+	`, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load step outputs: %w", err)
+	}
+	defer rows.Close()
+
+	outputs := make(map[string][]byte)
+	for rows.Next() {
+		var service string
+		var output []byte
+		if err := rows.Scan(&service, &output); err != nil {
+			return nil, fmt.Errorf("failed to scan step output: %w", err)
+		}
+		outputs[service] = output
+	}
+	return outputs, rows.Err()
+}
+
+// Complete finishes the request and releases its lease. The step outputs are
+// dropped; an export keeps only its archive.
+func (r *accountDataRepository) Complete(id uuid.UUID, archive []byte, archiveExpiresAt *time.Time) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE account_data_requests
+		SET status = $2, archive = $3, archive_expires_at = $4, last_error = NULL,
+		    locked_until = NULL, completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, id, models.DataRequestCompleted, archive, archiveExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete data request: %w", err)
+	}
+
+	if _, err := tx.Exec(`UPDATE account_data_request_steps SET output = NULL WHERE request_id = $1`, id); err != nil {
+		return fmt.Errorf("failed to clear step outputs: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// RecordFailure counts a run that left steps failed and releases the lease.
+// The request runs again at retryAt, or is marked failed when retryAt is nil.
+func (r *accountDataRepository) RecordFailure(id uuid.UUID, errMsg string, retryAt *time.Time) error {
+	status := models.DataRequestPending
+	if retryAt == nil {
+		status = models.DataRequestFailed
+	}
+
+	_, err := r.db.Exec(`
+		UPDATE account_data_requests
+		SET status = $2, attempts = attempts + 1, last_error = $3, locked_until = NULL,
+		    scheduled_for = COALESCE($4, scheduled_for)
+		WHERE id = $1
+	`, id, status, errMsg, retryAt)
+	if err != nil {
+		return fmt.Errorf("failed to record data request failure: %w", err)
+	}
+	return nil
+}
+
+// CancelDeletion cancels the user's deletion while it is still in its grace
+// period. The worker only claims requests after scheduled_for, so a cancelled
+// deletion has not touched any data.
+func (r *accountDataRepository) CancelDeletion(userID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE account_data_requests
+		SET status = $3
+		WHERE user_id = $1 AND request_type = $2 AND status = $4
+		  AND attempts = 0 AND scheduled_for > NOW()
+	`, userID, models.DataRequestDeletion, models.DataRequestCancelled, models.DataRequestPending)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel deletion: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// Retry puts a failed request back in the queue, with fresh attempts for its failed steps
+func (r *accountDataRepository) Retry(id uuid.UUID) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE account_data_requests
+		SET status = $2, attempts = 0, scheduled_for = NOW(), locked_until = NULL
+		WHERE id = $1 AND status = $3
+	`, id, models.DataRequestPending, models.DataRequestFailed)
+	if err != nil {
+		return false, fmt.Errorf("failed to retry data request: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE account_data_request_steps
+		SET status = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE request_id = $1 AND status = $3
+	`, id, models.DataRequestPending, models.DataRequestFailed)
+	if err != nil {
+		return false, fmt.Errorf("failed to reset failed steps: %w", err)
+	}
+
+	return true, tx.Commit()
+}
+
+// FindArchive returns the user's completed export archive while it has not expired
+func (r *accountDataRepository) FindArchive(id, userID uuid.UUID) ([]byte, error) {
+	var archive []byte
+	err := r.db.Get(&archive, `
+		SELECT archive FROM account_data_requests
+		WHERE id = $1 AND user_id = $2 AND request_type = $3 AND status = $4
+		  AND archive IS NOT NULL AND archive_expires_at > NOW()
+	`, id, userID, models.DataRequestExport, models.DataRequestCompleted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("archive not found")
+		}
+		return nil, fmt.Errorf("failed to find archive: %w", err)
+	}
+	return archive, nil
+}
+
+// authDataTables is what the auth service exports about a user; password
+// hashes, token hashes and MFA secrets are left out
+var authDataTables = []personaldata.Table{
+	{
+		Name:    "users",
+		Where:   "id = $1",
+		Columns: "id, email, phone, oauth_provider, is_active, is_verified, email_verified_at, last_login_at, last_login_ip, created_at, updated_at",
+	},
+	{
+		Name:    "user_roles",
+		Where:   "user_id = $1",
+		Columns: "(SELECT name FROM roles WHERE roles.id = role_id) AS role, assigned_at",
+	},
+	{
+		Name:    "user_identities",
+		Where:   "user_id = $1",
+		Columns: "provider, email, created_at, last_login_at",
+	},
+	{
+		Name:    "user_mfa",
+		Where:   "user_id = $1",
+		Columns: "enabled_at, created_at",
+	},
+	{
+		Name:    "refresh_tokens",
+		Where:   "user_id = $1",
+		Columns: "device_name, device_type, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at, revoked_reason",
+	},
+	{
+		Name:    "organization_members",
+		Where:   "user_id = $1",
+		Columns: "(SELECT name FROM organizations WHERE organizations.id = organization_id) AS organization, (SELECT name FROM roles WHERE roles.id = role_id) AS role, joined_at",
+	},
+	{
+		Name:    "classroom_members",
+		Where:   "user_id = $1",
+		Columns: "(SELECT name FROM classrooms WHERE classrooms.id = classroom_id) AS classroom, (SELECT name FROM roles WHERE roles.id = role_id) AS role, joined_at",
+	},
+	{
+		Name:    "audit_logs",
+		Where:   "user_id = $1",
+		Columns: "event_type, event_status, ip_address, user_agent, metadata, created_at",
+	},
+	{
+		Name:    "audit_logs_archive",
+		Where:   "user_id = $1",
+		Columns: "event_type, event_status, ip_address, user_agent, metadata, created_at",
+	},
+}
+
+// ExportAuthData returns the account, its sign-in methods, sessions and audit trail
+func (r *accountDataRepository) ExportAuthData(userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return personaldata.Export(context.Background(), r.db.DB, userID.String(), authDataTables)
+}
+
+// DeactivateUser stops the account from signing in while its data is erased
+func (r *accountDataRepository) DeactivateUser(userID uuid.UUID) error {
+	if _, err := r.db.Exec(`UPDATE users SET is_active = false WHERE id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+	return nil
+}
+
+// EraseAuthData anonymises the account. The users row stays, without any
+// personal data, so audit entries and requests keep a valid reference.
+func (r *accountDataRepository) EraseAuthData(userID uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{
+		"refresh_tokens", "password_reset_tokens", "email_verification_tokens",
+		"login_codes", "email_change_requests", "user_identities", "mfa_recovery_codes", "user_mfa",
+		"classroom_members", "organization_members",
+	} {
+		if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1`, table), userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE audit_logs
+		SET ip_address = NULL, user_agent = NULL, device_info = NULL, metadata = NULL
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise audit logs: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE audit_logs_archive
+		SET ip_address = NULL, user_agent = NULL, device_info = NULL, metadata = NULL
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise archived audit logs: %w", err)
+	}
+
+	// Earlier exports are copies of the data
+	_, err = tx.Exec(`UPDATE account_data_requests SET archive = NULL WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to drop export archives: %w", err)
+	}
+	_, err = tx.Exec(`
+		UPDATE account_data_request_steps SET output = NULL
+		WHERE request_id IN (SELECT id FROM account_data_requests WHERE user_id = $1)
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to drop export data: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM classroom_invites
+		WHERE email = (SELECT lower(email) FROM users WHERE id = $1)
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete classroom invites: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET email = 'deleted-' || id || '@deleted.invalid', password_hash = NULL, phone = NULL,
+		    google_id = NULL, oauth_provider = NULL, is_active = false, last_login_ip = NULL,
+		    deleted_at = COALESCE(deleted_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise user: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *accountDataRepository) loadSteps(req *models.AccountDataRequest) error {
+	req.Steps = []models.AccountDataRequestStep{}
+	err := r.db.Select(&req.Steps, `
+		SELECT request_id, service, status, attempts, last_error, updated_at
+		FROM account_data_request_steps
+		WHERE request_id = $1
+	`, req.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load data request steps: %w", err)
+	}
+	return nil
+}
@@ -2,13 +2,18 @@ package repository
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type AuditLogRepository interface {
 	Create(log *models.AuditLog) error
+	Search(filter models.AuditLogFilter, limit int) ([]models.AuditEvent, error)
+	ArchiveBefore(cutoff time.Time, batchSize int) (int, error)
 }
 
 type auditLogRepository struct {
@@ -46,3 +51,93 @@ func (r *auditLogRepository) Create(log *models.AuditLog) error {
 
 	return nil
 }
+
+// Search returns up to limit entries matching the filter, newest first.
+// Entries are paged by ID, which grows with created_at.
+func (r *auditLogRepository) Search(filter models.AuditLogFilter, limit int) ([]models.AuditEvent, error) {
+	table := "audit_logs"
+	if filter.Archived {
+		table = "audit_logs_archive"
+	}
+
+	conditions := []string{"TRUE"}
+	args := []interface{}{}
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.UserID != nil {
+		conditions = append(conditions, "user_id = "+addArg(*filter.UserID))
+	}
+	if len(filter.EventTypes) > 0 {
+		conditions = append(conditions, "event_type = ANY("+addArg(pq.Array(filter.EventTypes))+")")
+	}
+	if filter.EventStatus != "" {
+		conditions = append(conditions, "event_status = "+addArg(filter.EventStatus))
+	}
+	if filter.IPAddress != "" {
+		conditions = append(conditions, "ip_address = "+addArg(filter.IPAddress))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+addArg(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+addArg(*filter.To))
+	}
+	if filter.BeforeID > 0 {
+		conditions = append(conditions, "id < "+addArg(filter.BeforeID))
+	}
+
+	query := `
+		SELECT id, user_id, event_type, event_status, ip_address, user_agent,
+		       metadata, error_message, created_at
+		FROM ` + table + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY id DESC
+		LIMIT ` + addArg(limit)
+
+	events := []models.AuditEvent{}
+	if err := r.db.Select(&events, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to search audit logs: %w", err)
+	}
+
+	return events, nil
+}
+
+// ArchiveBefore moves up to batchSize entries created before cutoff to
+// audit_logs_archive and returns how many were moved
+func (r *auditLogRepository) ArchiveBefore(cutoff time.Time, batchSize int) (int, error) {
+	query := `
+		WITH moved AS (
+			DELETE FROM audit_logs
+			WHERE id IN (
+				SELECT id FROM audit_logs
+				WHERE created_at < $1
+				ORDER BY id
+				LIMIT $2
+			)
+			RETURNING id, user_id, event_type, event_status, ip_address, user_agent,
+			          device_info, metadata, error_message, created_at
+		)
+		INSERT INTO audit_logs_archive (
+			id, user_id, event_type, event_status, ip_address, user_agent,
+			device_info, metadata, error_message, created_at
+		)
+		SELECT id, user_id, event_type, event_status, ip_address, user_agent,
+		       device_info, metadata, error_message, created_at
+		FROM moved
+	`
+
+	result, err := r.db.Exec(query, cutoff, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive audit logs: %w", err)
+	}
+
+	archived, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return int(archived), nil
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type EmailChangeRepository interface {
+	Create(req *models.EmailChangeRequest) error
+	FindActiveByUserID(userID uuid.UUID) (*models.EmailChangeRequest, error)
+	FindByCancelTokenHash(tokenHash string) (*models.EmailChangeRequest, error)
+	RecordFailedAttempt(requestID uuid.UUID, maxAttempts int) (bool, error)
+	Confirm(requestID uuid.UUID) error
+	Cancel(requestID uuid.UUID) error
+	DeleteByUserID(userID uuid.UUID) error
+}
+
+type emailChangeRepository struct {
+	db *sqlx.DB
+}
+
+func NewEmailChangeRepository(db *sqlx.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+const emailChangeColumns = `
+	id, user_id, new_email, code, cancel_token_hash, failed_attempts,
+	expires_at, confirmed_at, cancelled_at, created_at
+`
+
+// Create stores a new email change request
+func (r *emailChangeRepository) Create(req *models.EmailChangeRequest) error {
+	query := `
+		INSERT INTO email_change_requests (id, user_id, new_email, code, cancel_token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	if req.ID == uuid.Nil {
+		req.ID = uuid.New()
+	}
+	if req.CreatedAt.IsZero() {
+		req.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.Exec(query, req.ID, req.UserID, req.NewEmail, req.Code, req.CancelTokenHash, req.ExpiresAt, req.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create email change request: %w", err)
+	}
+
+	return nil
+}
+
+// FindActiveByUserID finds the user's pending, unexpired email change
+func (r *emailChangeRepository) FindActiveByUserID(userID uuid.UUID) (*models.EmailChangeRequest, error) {
+	query := `
+		SELECT ` + emailChangeColumns + `
+		FROM email_change_requests
+		WHERE user_id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var req models.EmailChangeRequest
+	err := r.db.Get(&req, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("email change not found or expired")
+		}
+		return nil, fmt.Errorf("failed to find email change: %w", err)
+	}
+
+	return &req, nil
+}
+
+// FindByCancelTokenHash finds a pending email change by the token sent to the old address
+func (r *emailChangeRepository) FindByCancelTokenHash(tokenHash string) (*models.EmailChangeRequest, error) {
+	query := `
+		SELECT ` + emailChangeColumns + `
+		FROM email_change_requests
+		WHERE cancel_token_hash = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+	`
+
+	var req models.EmailChangeRequest
+	err := r.db.Get(&req, query, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("email change not found or expired")
+		}
+		return nil, fmt.Errorf("failed to find email change: %w", err)
+	}
+
+	return &req, nil
+}
+
+// RecordFailedAttempt counts a wrong code and expires the request once
+// maxAttempts is reached, reporting whether it was invalidated
+func (r *emailChangeRepository) RecordFailedAttempt(requestID uuid.UUID, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE email_change_requests
+		SET failed_attempts = failed_attempts + 1,
+		    expires_at = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() ELSE expires_at END
+		WHERE id = $1
+		RETURNING failed_attempts >= $2
+	`
+
+	var invalidated bool
+	if err := r.db.QueryRow(query, requestID, maxAttempts).Scan(&invalidated); err != nil {
+		return false, fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+
+	return invalidated, nil
+}
+
+// Confirm swaps the user's email for the new, now verified, address. It fails
+// if the request was cancelled or confirmed concurrently, or if another
+// account took the address in the meantime.
+func (r *emailChangeRepository) Confirm(requestID uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID uuid.UUID
+	var newEmail string
+	err = tx.QueryRow(`
+		UPDATE email_change_requests
+		SET confirmed_at = NOW()
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > NOW()
+		RETURNING user_id, new_email
+	`, requestID).Scan(&userID, &newEmail)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("email change not found or expired")
+		}
+		return fmt.Errorf("failed to confirm email change: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users
+		SET email = $2, is_verified = true, email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID, newEmail)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("email already in use")
+		}
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// Cancel stops a pending email change
+func (r *emailChangeRepository) Cancel(requestID uuid.UUID) error {
+	query := `
+		UPDATE email_change_requests
+		SET cancelled_at = NOW()
+		WHERE id = $1 AND confirmed_at IS NULL AND cancelled_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, requestID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel email change: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("email change not found or expired")
+	}
+
+	return nil
+}
+
+// DeleteByUserID deletes the user's pending email changes
+func (r *emailChangeRepository) DeleteByUserID(userID uuid.UUID) error {
+	query := `
+		DELETE FROM email_change_requests
+		WHERE user_id = $1 AND confirmed_at IS NULL
+	`
+
+	_, err := r.db.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete email changes: %w", err)
+	}
+
+	return nil
+}
@@ -13,8 +13,9 @@ import (
 type EmailVerificationRepository interface {
 	Create(token *models.EmailVerificationToken) error
 	FindByTokenHash(tokenHash string) (*models.EmailVerificationToken, error)
-	FindByCode(code string) (*models.EmailVerificationToken, error)
 	MarkAsVerified(tokenID uuid.UUID) error
+	FindActiveByUserID(userID uuid.UUID) (*models.EmailVerificationToken, error)
+	RecordFailedAttempt(tokenID uuid.UUID, maxAttempts int) (bool, error)
 	DeleteExpired() error
 	DeleteByUserID(userID uuid.UUID) error
 }
@@ -52,7 +53,7 @@ func (r *emailVerificationRepository) Create(token *models.EmailVerificationToke
 // FindByTokenHash finds an email verification token by its hash
 func (r *emailVerificationRepository) FindByTokenHash(tokenHash string) (*models.EmailVerificationToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, code, expires_at, verified_at, created_at
+		SELECT id, user_id, token_hash, code, failed_attempts, expires_at, verified_at, created_at
 		FROM email_verification_tokens
 		WHERE token_hash = $1 AND verified_at IS NULL AND expires_at > NOW()
 	`
@@ -69,26 +70,6 @@ func (r *emailVerificationRepository) FindByTokenHash(tokenHash string) (*models
 	return &token, nil
 }
 
-// FindByCode finds an email verification token by its 6-digit code
-func (r *emailVerificationRepository) FindByCode(code string) (*models.EmailVerificationToken, error) {
-	query := `
-		SELECT id, user_id, token_hash, code, expires_at, verified_at, created_at
-		FROM email_verification_tokens
-		WHERE code = $1 AND verified_at IS NULL AND expires_at > NOW()
-	`
-
-	var token models.EmailVerificationToken
-	err := r.db.Get(&token, query, code)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("code not found or expired")
-		}
-		return nil, fmt.Errorf("failed to find token by code: %w", err)
-	}
-
-	return &token, nil
-}
-
 // MarkAsVerified marks an email verification token as verified
 func (r *emailVerificationRepository) MarkAsVerified(tokenID uuid.UUID) error {
 	query := `
@@ -114,6 +95,47 @@ func (r *emailVerificationRepository) MarkAsVerified(tokenID uuid.UUID) error {
 	return nil
 }
 
+// FindActiveByUserID finds the user's unexpired verification code, if any
+func (r *emailVerificationRepository) FindActiveByUserID(userID uuid.UUID) (*models.EmailVerificationToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, code, failed_attempts, expires_at, verified_at, created_at
+		FROM email_verification_tokens
+		WHERE user_id = $1 AND verified_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var token models.EmailVerificationToken
+	err := r.db.Get(&token, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("code not found or expired")
+		}
+		return nil, fmt.Errorf("failed to find token by user: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RecordFailedAttempt counts a wrong code against the token and expires it
+// once maxAttempts is reached, reporting whether it was invalidated
+func (r *emailVerificationRepository) RecordFailedAttempt(tokenID uuid.UUID, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE email_verification_tokens
+		SET failed_attempts = failed_attempts + 1,
+		    expires_at = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() ELSE expires_at END
+		WHERE id = $1
+		RETURNING failed_attempts >= $2
+	`
+
+	var invalidated bool
+	if err := r.db.QueryRow(query, tokenID, maxAttempts).Scan(&invalidated); err != nil {
+		return false, fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+
+	return invalidated, nil
+}
+
 // DeleteExpired deletes all expired or verified tokens
 func (r *emailVerificationRepository) DeleteExpired() error {
 	query := `
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type IdentityRepository interface {
+	FindByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+	FindByUserID(userID uuid.UUID) ([]models.UserIdentity, error)
+	Create(identity *models.UserIdentity) error
+	CreateUserWithIdentity(email string, identity *models.UserIdentity) (*models.User, error)
+	TouchLogin(identityID uuid.UUID) error
+	Delete(userID uuid.UUID, provider string) (bool, error)
+	BackfillGoogleIdentities() (int64, error)
+}
+
+type identityRepository struct {
+	db *sqlx.DB
+}
+
+func NewIdentityRepository(db *sqlx.DB) IdentityRepository {
+	return &identityRepository{db: db}
+}
+
+// FindByProviderSubject returns the identity, or nil if it is not linked to anyone
+func (r *identityRepository) FindByProviderSubject(provider, subject string) (*models.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2
+	`
+
+	var identity models.UserIdentity
+	err := r.db.Get(&identity, query, provider, subject)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find identity: %w", err)
+	}
+
+	return &identity, nil
+}
+
+// FindByUserID lists the identities linked to a user
+func (r *identityRepository) FindByUserID(userID uuid.UUID) ([]models.UserIdentity, error) {
+	query := `
+		SELECT id, user_id, provider, subject, email, created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at
+	`
+
+	identities := []models.UserIdentity{}
+	if err := r.db.Select(&identities, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// Create links an identity to an existing user
+func (r *identityRepository) Create(identity *models.UserIdentity) error {
+	return insertIdentity(r.db, identity)
+}
+
+// CreateUserWithIdentity creates a verified account without a password for
+// someone signing in with a provider for the first time
+func (r *identityRepository) CreateUserWithIdentity(email string, identity *models.UserIdentity) (*models.User, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	err = tx.Get(&user, `
+		INSERT INTO users (email, oauth_provider, is_active, is_verified, email_verified_at)
+		VALUES ($1, $2, true, true, CURRENT_TIMESTAMP)
+		RETURNING id, email, password_hash, phone, google_id, oauth_provider,
+		          is_active, is_verified, email_verified_at,
+		          failed_login_attempts, locked_until, last_login_at, last_login_ip,
+		          created_at, updated_at, deleted_at
+	`, email, identity.Provider)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("email already registered")
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	identity.UserID = user.ID
+	if err := insertIdentity(tx, identity); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user: %w", err)
+	}
+	return &user, nil
+}
+
+// TouchLogin records a sign-in through the identity
+func (r *identityRepository) TouchLogin(identityID uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE user_identities SET last_login_at = CURRENT_TIMESTAMP WHERE id = $1`, identityID)
+	if err != nil {
+		return fmt.Errorf("failed to update identity login: %w", err)
+	}
+	return nil
+}
+
+// Delete unlinks the user's identity at a provider, reporting whether one existed.
+// The legacy Google link is cleared too so the startup backfill does not restore it.
+func (r *identityRepository) Delete(userID uuid.UUID, provider string) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM user_identities WHERE user_id = $1 AND provider = $2`, userID, provider)
+	if err != nil {
+		return false, fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return false, nil
+	}
+
+	if provider == "google" {
+		if _, err := tx.Exec(`UPDATE users SET google_id = NULL WHERE id = $1`, userID); err != nil {
+			return false, fmt.Errorf("failed to clear google id: %w", err)
+		}
+	}
+
+	return true, tx.Commit()
+}
+
+// BackfillGoogleIdentities copies Google links made before identities were
+// tracked into user_identities. Google's user ID is the OpenID Connect subject.
+func (r *identityRepository) BackfillGoogleIdentities() (int64, error) {
+	result, err := r.db.Exec(`
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		SELECT id, 'google', google_id, email
+		FROM users
+		WHERE google_id IS NOT NULL AND deleted_at IS NULL
+		ON CONFLICT DO NOTHING
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill google identities: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows, nil
+}
+
+func insertIdentity(q sqlx.Queryer, identity *models.UserIdentity) error {
+	if identity.ID == uuid.Nil {
+		identity.ID = uuid.New()
+	}
+
+	err := sqlx.Get(q, &identity.CreatedAt, `
+		INSERT INTO user_identities (id, user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("identity already linked")
+		}
+		return fmt.Errorf("failed to link identity: %w", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	return strings.Contains(err.Error(), "duplicate key value")
+}
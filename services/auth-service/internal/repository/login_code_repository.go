@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoginCodeRepository interface {
+	Create(code *models.LoginCode) error
+	FindByTokenHash(tokenHash string) (*models.LoginCode, error)
+	FindActiveByUserID(userID uuid.UUID) (*models.LoginCode, error)
+	MarkAsUsed(codeID uuid.UUID) error
+	RecordFailedAttempt(codeID uuid.UUID, maxAttempts int) (bool, error)
+	DeleteByUserID(userID uuid.UUID) error
+}
+
+type loginCodeRepository struct {
+	db *sqlx.DB
+}
+
+func NewLoginCodeRepository(db *sqlx.DB) LoginCodeRepository {
+	return &loginCodeRepository{db: db}
+}
+
+// Create stores a new login code
+func (r *loginCodeRepository) Create(code *models.LoginCode) error {
+	query := `
+		INSERT INTO login_codes (id, user_id, token_hash, code, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	if code.ID == uuid.Nil {
+		code.ID = uuid.New()
+	}
+	if code.CreatedAt.IsZero() {
+		code.CreatedAt = time.Now()
+	}
+
+	_, err := r.db.Exec(query, code.ID, code.UserID, code.TokenHash, code.Code, code.ExpiresAt, code.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create login code: %w", err)
+	}
+
+	return nil
+}
+
+// FindByTokenHash finds an unused login code by its magic link token
+func (r *loginCodeRepository) FindByTokenHash(tokenHash string) (*models.LoginCode, error) {
+	query := `
+		SELECT id, user_id, token_hash, code, failed_attempts, expires_at, used_at, created_at
+		FROM login_codes
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	`
+
+	var code models.LoginCode
+	err := r.db.Get(&code, query, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("code not found or expired")
+		}
+		return nil, fmt.Errorf("failed to find login code: %w", err)
+	}
+
+	return &code, nil
+}
+
+// FindActiveByUserID finds the user's unexpired login code, if any
+func (r *loginCodeRepository) FindActiveByUserID(userID uuid.UUID) (*models.LoginCode, error) {
+	query := `
+		SELECT id, user_id, token_hash, code, failed_attempts, expires_at, used_at, created_at
+		FROM login_codes
+		WHERE user_id = $1 AND used_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var code models.LoginCode
+	err := r.db.Get(&code, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("code not found or expired")
+		}
+		return nil, fmt.Errorf("failed to find login code by user: %w", err)
+	}
+
+	return &code, nil
+}
+
+// MarkAsUsed consumes the code. It fails if the code was already used, so a
+// code signs in at most once even when submitted concurrently.
+func (r *loginCodeRepository) MarkAsUsed(codeID uuid.UUID) error {
+	query := `
+		UPDATE login_codes
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, codeID)
+	if err != nil {
+		return fmt.Errorf("failed to mark login code as used: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("code not found or expired")
+	}
+
+	return nil
+}
+
+// RecordFailedAttempt counts a wrong code and expires the code once
+// maxAttempts is reached, reporting whether it was invalidated
+func (r *loginCodeRepository) RecordFailedAttempt(codeID uuid.UUID, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE login_codes
+		SET failed_attempts = failed_attempts + 1,
+		    expires_at = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() ELSE expires_at END
+		WHERE id = $1
+		RETURNING failed_attempts >= $2
+	`
+
+	var invalidated bool
+	if err := r.db.QueryRow(query, codeID, maxAttempts).Scan(&invalidated); err != nil {
+		return false, fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+
+	return invalidated, nil
+}
+
+// DeleteByUserID deletes all login codes of a user
+func (r *loginCodeRepository) DeleteByUserID(userID uuid.UUID) error {
+	query := `
+		DELETE FROM login_codes
+		WHERE user_id = $1
+	`
+
+	_, err := r.db.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user login codes: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type MFARepository interface {
+	FindByUserID(userID uuid.UUID) (*models.UserMFA, error)
+	SavePending(userID uuid.UUID, encryptedSecret string) error
+	Enable(userID uuid.UUID, step int64, recoveryCodeHashes []string) error
+	MarkStepUsed(userID uuid.UUID, step int64) (bool, error)
+	Delete(userID uuid.UUID) error
+	ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error
+	UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error)
+	CountRecoveryCodes(userID uuid.UUID) (int, error)
+}
+
+type mfaRepository struct {
+	db *sqlx.DB
+}
+
+func NewMFARepository(db *sqlx.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// FindByUserID returns the user's enrolment, or nil if they never started one
+func (r *mfaRepository) FindByUserID(userID uuid.UUID) (*models.UserMFA, error) {
+	query := `
+		SELECT user_id, totp_secret, enabled_at, last_used_step, created_at, updated_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	var mfa models.UserMFA
+	err := r.db.Get(&mfa, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find mfa enrolment: %w", err)
+	}
+
+	return &mfa, nil
+}
+
+// SavePending stores a new, unconfirmed secret. A confirmed enrolment is never
+// overwritten; it has to be disabled first.
+func (r *mfaRepository) SavePending(userID uuid.UUID, encryptedSecret string) error {
+	query := `
+		INSERT INTO user_mfa (user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret, last_used_step = NULL
+		WHERE user_mfa.enabled_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, encryptedSecret)
+	if err != nil {
+		return fmt.Errorf("failed to save mfa enrolment: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("mfa already enabled")
+	}
+
+	return nil
+}
+
+// Enable confirms the enrolment and stores its first recovery codes in one transaction
+func (r *mfaRepository) Enable(userID uuid.UUID, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE user_mfa
+		SET enabled_at = CURRENT_TIMESTAMP, last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to enable mfa: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("mfa enrolment not pending")
+	}
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MarkStepUsed records a TOTP step as consumed. It returns false if this or a
+// later step was already used, so each code is accepted at most once.
+func (r *mfaRepository) MarkStepUsed(userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)
+	`
+
+	result, err := r.db.Exec(query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to record mfa code use: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// Delete removes the enrolment and every recovery code
+func (r *mfaRepository) Delete(userID uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete mfa enrolment: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRecoveryCodes invalidates all existing recovery codes and stores new ones
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uuid.UUID, codeHashes []string) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sqlx.Tx, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, hash); err != nil {
+			return fmt.Errorf("failed to create recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode consumes an unused recovery code; it returns false if none matches
+func (r *mfaRepository) UseRecoveryCode(userID uuid.UUID, codeHash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+func (r *mfaRepository) CountRecoveryCodes(userID uuid.UUID) (int, error) {
+	var count int
+	err := r.db.Get(&count, `SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,486 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type OrganizationRepository interface {
+	// Organizations
+	Create(org *models.Organization, adminID *uuid.UUID, adminRoleID int) error
+	FindByID(id uuid.UUID) (*models.Organization, error)
+	Search(query string, limit, offset int) ([]models.Organization, int, error)
+	FindByUserID(userID uuid.UUID) ([]models.Organization, error)
+
+	// Organization members. A role of "" means the user is not a member.
+	FindMemberRole(orgID, userID uuid.UUID) (string, error)
+	ListMembers(orgID uuid.UUID) ([]models.OrganizationMember, error)
+	SetMember(orgID, userID uuid.UUID, roleID int, addedBy uuid.UUID) error
+	EnsureMember(orgID, userID uuid.UUID, roleID int, addedBy uuid.UUID) error
+	RemoveMember(orgID, userID uuid.UUID) error
+
+	// Classrooms
+	CreateClassroom(classroom *models.Classroom, teacherRoleID int) error
+	FindClassroomByID(id uuid.UUID) (*models.Classroom, error)
+	FindClassroomByInviteCode(code string) (*models.Classroom, error)
+	ListClassrooms(orgID uuid.UUID) ([]models.Classroom, error)
+	ListClassroomsByUser(userID uuid.UUID) ([]models.Classroom, error)
+	SetInviteCode(classroomID uuid.UUID, code string) error
+
+	// Classroom members and invites
+	FindClassroomRole(classroomID, userID uuid.UUID) (string, error)
+	ListClassroomMembers(classroomID uuid.UUID) ([]models.ClassroomMember, error)
+	AddClassroomMember(classroomID, userID uuid.UUID, roleID int, addedBy uuid.UUID) (bool, error)
+	SetClassroomMember(classroomID, userID uuid.UUID, roleID int, addedBy uuid.UUID) error
+	RemoveClassroomMember(classroomID, userID uuid.UUID) error
+	CreateInvites(classroomID uuid.UUID, emails []string, invitedBy uuid.UUID) ([]string, error)
+	ListPendingInvites(classroomID uuid.UUID) ([]models.ClassroomInvite, error)
+	AcceptInvite(classroomID uuid.UUID, email string) error
+	DeleteInvite(classroomID, inviteID uuid.UUID) error
+}
+
+type organizationRepository struct {
+	db *sqlx.DB
+}
+
+func NewOrganizationRepository(db *sqlx.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create stores an organization and, when adminID is set, its first admin
+func (r *organizationRepository) Create(org *models.Organization, adminID *uuid.UUID, adminRoleID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if org.ID == uuid.Nil {
+		org.ID = uuid.New()
+	}
+	err = tx.QueryRow(`
+		INSERT INTO organizations (id, name, slug, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, updated_at
+	`, org.ID, org.Name, org.Slug, org.CreatedBy).Scan(&org.CreatedAt, &org.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("organization slug already exists")
+		}
+		return fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	if adminID != nil {
+		_, err = tx.Exec(`
+			INSERT INTO organization_members (organization_id, user_id, role_id, added_by)
+			VALUES ($1, $2, $3, $4)
+		`, org.ID, *adminID, adminRoleID, org.CreatedBy)
+		if err != nil {
+			return fmt.Errorf("failed to add organization admin: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *organizationRepository) FindByID(id uuid.UUID) (*models.Organization, error) {
+	query := `SELECT id, name, slug, created_by, created_at, updated_at FROM organizations WHERE id = $1`
+
+	var org models.Organization
+	if err := r.db.Get(&org, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organization not found")
+		}
+		return nil, fmt.Errorf("failed to find organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+// Search returns a page of organizations whose name or slug contains query, newest first
+func (r *organizationRepository) Search(query string, limit, offset int) ([]models.Organization, int, error) {
+	where := ""
+	args := []interface{}{}
+	if query != "" {
+		where = "WHERE name ILIKE '%' || $1::text || '%' OR slug ILIKE '%' || $1::text || '%'"
+		args = append(args, query)
+	}
+
+	var total int
+	if err := r.db.Get(&total, "SELECT COUNT(*) FROM organizations "+where, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count organizations: %w", err)
+	}
+
+	args = append(args, limit, offset)
+	list := `
+		SELECT id, name, slug, created_by, created_at, updated_at
+		FROM organizations ` + where + fmt.Sprintf(`
+		ORDER BY created_at DESC
+		LIMIT $%d OFFSET $%d`, len(args)-1, len(args))
+
+	orgs := []models.Organization{}
+	if err := r.db.Select(&orgs, list, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to search organizations: %w", err)
+	}
+
+	return orgs, total, nil
+}
+
+// FindByUserID returns the organizations the user belongs to, with their role in each
+func (r *organizationRepository) FindByUserID(userID uuid.UUID) ([]models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.slug, o.created_by, o.created_at, o.updated_at, r.name AS role
+		FROM organizations o
+		INNER JOIN organization_members om ON om.organization_id = o.id
+		INNER JOIN roles r ON r.id = om.role_id
+		WHERE om.user_id = $1
+		ORDER BY o.name
+	`
+
+	orgs := []models.Organization{}
+	if err := r.db.Select(&orgs, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to find organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+func (r *organizationRepository) FindMemberRole(orgID, userID uuid.UUID) (string, error) {
+	query := `
+		SELECT r.name
+		FROM organization_members om
+		INNER JOIN roles r ON r.id = om.role_id
+		WHERE om.organization_id = $1 AND om.user_id = $2
+	`
+
+	var role string
+	if err := r.db.Get(&role, query, orgID, userID); err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to find organization member: %w", err)
+	}
+
+	return role, nil
+}
+
+func (r *organizationRepository) ListMembers(orgID uuid.UUID) ([]models.OrganizationMember, error) {
+	query := `
+		SELECT om.organization_id, om.user_id, u.email, r.name AS role, om.added_by, om.joined_at
+		FROM organization_members om
+		INNER JOIN users u ON u.id = om.user_id
+		INNER JOIN roles r ON r.id = om.role_id
+		WHERE om.organization_id = $1
+		ORDER BY r.id DESC, u.email
+	`
+
+	members := []models.OrganizationMember{}
+	if err := r.db.Select(&members, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	return members, nil
+}
+
+// SetMember adds a member or changes the role of an existing one
+func (r *organizationRepository) SetMember(orgID, userID uuid.UUID, roleID int, addedBy uuid.UUID) error {
+	query := `
+		INSERT INTO organization_members (organization_id, user_id, role_id, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, user_id) DO UPDATE SET role_id = EXCLUDED.role_id
+	`
+
+	if _, err := r.db.Exec(query, orgID, userID, roleID, addedBy); err != nil {
+		return fmt.Errorf("failed to set organization member: %w", err)
+	}
+	return nil
+}
+
+// EnsureMember adds the user with the given role unless they are already a
+// member, in which case their role is left alone
+func (r *organizationRepository) EnsureMember(orgID, userID uuid.UUID, roleID int, addedBy uuid.UUID) error {
+	query := `
+		INSERT INTO organization_members (organization_id, user_id, role_id, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (organization_id, user_id) DO NOTHING
+	`
+
+	if _, err := r.db.Exec(query, orgID, userID, roleID, addedBy); err != nil {
+		return fmt.Errorf("failed to add organization member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember removes the user from the organization and all its classrooms
+func (r *organizationRepository) RemoveMember(orgID, userID uuid.UUID) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		DELETE FROM classroom_members cm
+		USING classrooms c
+		WHERE c.id = cm.classroom_id AND c.organization_id = $1 AND cm.user_id = $2
+	`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove classroom memberships: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("organization member not found")
+	}
+
+	return tx.Commit()
+}
+
+const classroomColumns = `
+	c.id, c.organization_id, c.name, c.description, c.invite_code, c.created_by, c.created_at, c.updated_at,
+	(SELECT COUNT(*) FROM classroom_members s
+	 WHERE s.classroom_id = c.id AND s.role_id = (SELECT id FROM roles WHERE name = 'student')) AS student_count
+`
+
+// CreateClassroom stores a classroom with its creator as the first teacher
+func (r *organizationRepository) CreateClassroom(classroom *models.Classroom, teacherRoleID int) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if classroom.ID == uuid.Nil {
+		classroom.ID = uuid.New()
+	}
+	err = tx.QueryRow(`
+		INSERT INTO classrooms (id, organization_id, name, description, invite_code, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, updated_at
+	`, classroom.ID, classroom.OrganizationID, classroom.Name, classroom.Description,
+		classroom.InviteCode, classroom.CreatedBy).Scan(&classroom.CreatedAt, &classroom.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create classroom: %w", err)
+	}
+
+	if classroom.CreatedBy != nil {
+		_, err = tx.Exec(`
+			INSERT INTO classroom_members (classroom_id, user_id, role_id, added_by)
+			VALUES ($1, $2, $3, $2)
+		`, classroom.ID, *classroom.CreatedBy, teacherRoleID)
+		if err != nil {
+			return fmt.Errorf("failed to add classroom teacher: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *organizationRepository) FindClassroomByID(id uuid.UUID) (*models.Classroom, error) {
+	return r.findClassroom("c.id = $1", id)
+}
+
+// FindClassroomByInviteCode looks the code up regardless of case
+func (r *organizationRepository) FindClassroomByInviteCode(code string) (*models.Classroom, error) {
+	return r.findClassroom("c.invite_code = $1", strings.ToUpper(code))
+}
+
+func (r *organizationRepository) findClassroom(where string, arg interface{}) (*models.Classroom, error) {
+	query := `SELECT ` + classroomColumns + ` FROM classrooms c WHERE ` + where
+
+	var classroom models.Classroom
+	if err := r.db.Get(&classroom, query, arg); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("classroom not found")
+		}
+		return nil, fmt.Errorf("failed to find classroom: %w", err)
+	}
+
+	return &classroom, nil
+}
+
+func (r *organizationRepository) ListClassrooms(orgID uuid.UUID) ([]models.Classroom, error) {
+	query := `SELECT ` + classroomColumns + ` FROM classrooms c WHERE c.organization_id = $1 ORDER BY c.created_at DESC`
+
+	classrooms := []models.Classroom{}
+	if err := r.db.Select(&classrooms, query, orgID); err != nil {
+		return nil, fmt.Errorf("failed to list classrooms: %w", err)
+	}
+
+	return classrooms, nil
+}
+
+// ListClassroomsByUser returns the classrooms the user belongs to, with their role in each
+func (r *organizationRepository) ListClassroomsByUser(userID uuid.UUID) ([]models.Classroom, error) {
+	query := `
+		SELECT ` + classroomColumns + `, r.name AS role
+		FROM classrooms c
+		INNER JOIN classroom_members cm ON cm.classroom_id = c.id
+		INNER JOIN roles r ON r.id = cm.role_id
+		WHERE cm.user_id = $1
+		ORDER BY c.created_at DESC
+	`
+
+	classrooms := []models.Classroom{}
+	if err := r.db.Select(&classrooms, query, userID); err != nil {
+		return nil, fmt.Errorf("failed to list classrooms: %w", err)
+	}
+
+	return classrooms, nil
+}
+
+func (r *organizationRepository) SetInviteCode(classroomID uuid.UUID, code string) error {
+	result, err := r.db.Exec(`UPDATE classrooms SET invite_code = $2 WHERE id = $1`, classroomID, code)
+	if err != nil {
+		return fmt.Errorf("failed to update invite code: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("classroom not found")
+	}
+	return nil
+}
+
+func (r *organizationRepository) FindClassroomRole(classroomID, userID uuid.UUID) (string, error) {
+	query := `
+		SELECT r.name
+		FROM classroom_members cm
+		INNER JOIN roles r ON r.id = cm.role_id
+		WHERE cm.classroom_id = $1 AND cm.user_id = $2
+	`
+
+	var role string
+	if err := r.db.Get(&role, query, classroomID, userID); err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to find classroom member: %w", err)
+	}
+
+	return role, nil
+}
+
+// ListClassroomMembers returns the teachers first, then the students by email
+func (r *organizationRepository) ListClassroomMembers(classroomID uuid.UUID) ([]models.ClassroomMember, error) {
+	query := `
+		SELECT cm.classroom_id, cm.user_id, u.email, r.name AS role, cm.added_by, cm.joined_at
+		FROM classroom_members cm
+		INNER JOIN users u ON u.id = cm.user_id
+		INNER JOIN roles r ON r.id = cm.role_id
+		WHERE cm.classroom_id = $1
+		ORDER BY r.id DESC, u.email
+	`
+
+	members := []models.ClassroomMember{}
+	if err := r.db.Select(&members, query, classroomID); err != nil {
+		return nil, fmt.Errorf("failed to list classroom members: %w", err)
+	}
+
+	return members, nil
+}
+
+// AddClassroomMember adds the user unless they are already a member, and
+// reports whether they were added
+func (r *organizationRepository) AddClassroomMember(classroomID, userID uuid.UUID, roleID int, addedBy uuid.UUID) (bool, error) {
+	query := `
+		INSERT INTO classroom_members (classroom_id, user_id, role_id, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (classroom_id, user_id) DO NOTHING
+	`
+
+	result, err := r.db.Exec(query, classroomID, userID, roleID, addedBy)
+	if err != nil {
+		return false, fmt.Errorf("failed to add classroom member: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// SetClassroomMember adds a member or changes the role of an existing one
+func (r *organizationRepository) SetClassroomMember(classroomID, userID uuid.UUID, roleID int, addedBy uuid.UUID) error {
+	query := `
+		INSERT INTO classroom_members (classroom_id, user_id, role_id, added_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (classroom_id, user_id) DO UPDATE SET role_id = EXCLUDED.role_id
+	`
+
+	if _, err := r.db.Exec(query, classroomID, userID, roleID, addedBy); err != nil {
+		return fmt.Errorf("failed to set classroom member: %w", err)
+	}
+	return nil
+}
+
+func (r *organizationRepository) RemoveClassroomMember(classroomID, userID uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM classroom_members WHERE classroom_id = $1 AND user_id = $2`, classroomID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove classroom member: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("classroom member not found")
+	}
+	return nil
+}
+
+// CreateInvites invites every email not invited yet and returns those. Emails
+// are expected in lower case.
+func (r *organizationRepository) CreateInvites(classroomID uuid.UUID, emails []string, invitedBy uuid.UUID) ([]string, error) {
+	query := `
+		INSERT INTO classroom_invites (classroom_id, email, invited_by)
+		SELECT $1, e, $3 FROM unnest($2::text[]) AS e
+		ON CONFLICT (classroom_id, email) DO NOTHING
+		RETURNING email
+	`
+
+	created := []string{}
+	if err := r.db.Select(&created, query, classroomID, pq.Array(emails), invitedBy); err != nil {
+		return nil, fmt.Errorf("failed to create invites: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *organizationRepository) ListPendingInvites(classroomID uuid.UUID) ([]models.ClassroomInvite, error) {
+	query := `
+		SELECT id, classroom_id, email, invited_by, created_at, accepted_at
+		FROM classroom_invites
+		WHERE classroom_id = $1 AND accepted_at IS NULL
+		ORDER BY email
+	`
+
+	invites := []models.ClassroomInvite{}
+	if err := r.db.Select(&invites, query, classroomID); err != nil {
+		return nil, fmt.Errorf("failed to list invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+// AcceptInvite marks the classroom's invite for email as accepted, if there is one
+func (r *organizationRepository) AcceptInvite(classroomID uuid.UUID, email string) error {
+	query := `
+		UPDATE classroom_invites SET accepted_at = NOW()
+		WHERE classroom_id = $1 AND email = lower($2) AND accepted_at IS NULL
+	`
+
+	if _, err := r.db.Exec(query, classroomID, email); err != nil {
+		return fmt.Errorf("failed to accept invite: %w", err)
+	}
+	return nil
+}
+
+func (r *organizationRepository) DeleteInvite(classroomID, inviteID uuid.UUID) error {
+	query := `DELETE FROM classroom_invites WHERE id = $2 AND classroom_id = $1 AND accepted_at IS NULL`
+
+	result, err := r.db.Exec(query, classroomID, inviteID)
+	if err != nil {
+		return fmt.Errorf("failed to delete invite: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("invite not found")
+	}
+	return nil
+}
@@ -13,8 +13,9 @@ import (
 type PasswordResetRepository interface {
 	Create(token *models.PasswordResetToken) error
 	FindByTokenHash(tokenHash string) (*models.PasswordResetToken, error)
-	FindByCode(code string) (*models.PasswordResetToken, error)
 	MarkAsUsed(tokenID uuid.UUID) error
+	FindActiveByUserID(userID uuid.UUID) (*models.PasswordResetToken, error)
+	RecordFailedAttempt(tokenID uuid.UUID, maxAttempts int) (bool, error)
 	DeleteExpired() error
 	DeleteByUserID(userID uuid.UUID) error
 }
@@ -52,7 +53,7 @@ func (r *passwordResetRepository) Create(token *models.PasswordResetToken) error
 // FindByTokenHash finds a password reset token by its hash
 func (r *passwordResetRepository) FindByTokenHash(tokenHash string) (*models.PasswordResetToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, code, expires_at, used_at, created_at
+		SELECT id, user_id, token_hash, code, failed_attempts, expires_at, used_at, created_at
 		FROM password_reset_tokens
 		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
 	`
@@ -69,26 +70,6 @@ func (r *passwordResetRepository) FindByTokenHash(tokenHash string) (*models.Pas
 	return &token, nil
 }
 
-// FindByCode finds a password reset token by its 6-digit code
-func (r *passwordResetRepository) FindByCode(code string) (*models.PasswordResetToken, error) {
-	query := `
-		SELECT id, user_id, token_hash, code, expires_at, used_at, created_at
-		FROM password_reset_tokens
-		WHERE code = $1 AND used_at IS NULL AND expires_at > NOW()
-	`
-
-	var token models.PasswordResetToken
-	err := r.db.Get(&token, query, code)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("code not found or expired")
-		}
-		return nil, fmt.Errorf("failed to find token by code: %w", err)
-	}
-
-	return &token, nil
-}
-
 // MarkAsUsed marks a password reset token as used
 func (r *passwordResetRepository) MarkAsUsed(tokenID uuid.UUID) error {
 	query := `
@@ -114,6 +95,47 @@ func (r *passwordResetRepository) MarkAsUsed(tokenID uuid.UUID) error {
 	return nil
 }
 
+// FindActiveByUserID finds the user's unexpired password reset code, if any
+func (r *passwordResetRepository) FindActiveByUserID(userID uuid.UUID) (*models.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, code, failed_attempts, expires_at, used_at, created_at
+		FROM password_reset_tokens
+		WHERE user_id = $1 AND used_at IS NULL AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var token models.PasswordResetToken
+	err := r.db.Get(&token, query, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("code not found or expired")
+		}
+		return nil, fmt.Errorf("failed to find token by user: %w", err)
+	}
+
+	return &token, nil
+}
+
+// RecordFailedAttempt counts a wrong code against the token and expires it
+// once maxAttempts is reached, reporting whether it was invalidated
+func (r *passwordResetRepository) RecordFailedAttempt(tokenID uuid.UUID, maxAttempts int) (bool, error) {
+	query := `
+		UPDATE password_reset_tokens
+		SET failed_attempts = failed_attempts + 1,
+		    expires_at = CASE WHEN failed_attempts + 1 >= $2 THEN NOW() ELSE expires_at END
+		WHERE id = $1
+		RETURNING failed_attempts >= $2
+	`
+
+	var invalidated bool
+	if err := r.db.QueryRow(query, tokenID, maxAttempts).Scan(&invalidated); err != nil {
+		return false, fmt.Errorf("failed to record failed attempt: %w", err)
+	}
+
+	return invalidated, nil
+}
+
 // DeleteExpired deletes all expired or used tokens
 func (r *passwordResetRepository) DeleteExpired() error {
 	query := `
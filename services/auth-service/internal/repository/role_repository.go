@@ -7,13 +7,20 @@ import (
 	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type RoleRepository interface {
 	FindByName(name string) (*models.Role, error)
 	FindByUserID(userID uuid.UUID) ([]models.Role, error)
+	FindByUserIDs(userIDs []uuid.UUID) (map[uuid.UUID][]models.Role, error)
 	AssignRoleToUser(userID uuid.UUID, roleID int, assignedBy *uuid.UUID) error
 	RemoveRoleFromUser(userID uuid.UUID, roleID int) error
+	AssignRoleToUsers(userIDs []uuid.UUID, roleID int, assignedBy uuid.UUID) ([]uuid.UUID, error)
+	RemoveRoleFromUsers(userIDs []uuid.UUID, roleID int) ([]uuid.UUID, error)
+	FindPermissionNamesByRole(roleName string) ([]string, error)
+	FindAll() ([]models.Role, error)
+	SetMFARequired(roleName string, required bool) error
 }
 
 type roleRepository struct {
@@ -25,7 +32,7 @@ func NewRoleRepository(db *sqlx.DB) RoleRepository {
 }
 
 func (r *roleRepository) FindByName(name string) (*models.Role, error) {
-	query := `SELECT id, name, display_name, description, created_at, updated_at FROM roles WHERE name = $1`
+	query := `SELECT id, name, display_name, description, mfa_required, created_at, updated_at FROM roles WHERE name = $1`
 
 	var role models.Role
 	err := r.db.Get(&role, query, name)
@@ -39,12 +46,16 @@ func (r *roleRepository) FindByName(name string) (*models.Role, error) {
 	return &role, nil
 }
 
+// FindByUserID returns the user's roles, most privileged first. Roles are
+// seeded in order of privilege, so callers taking roles[0] as the primary
+// role get admin over instructor over student.
 func (r *roleRepository) FindByUserID(userID uuid.UUID) ([]models.Role, error) {
 	query := `
-		SELECT r.id, r.name, r.display_name, r.description, r.created_at, r.updated_at
+		SELECT r.id, r.name, r.display_name, r.description, r.mfa_required, r.created_at, r.updated_at
 		FROM roles r
 		INNER JOIN user_roles ur ON r.id = ur.role_id
 		WHERE ur.user_id = $1
+		ORDER BY r.id DESC
 	`
 
 	var roles []models.Role
@@ -81,3 +92,126 @@ func (r *roleRepository) RemoveRoleFromUser(userID uuid.UUID, roleID int) error
 
 	return nil
 }
+
+// FindByUserIDs loads the roles of several users at once, most privileged first
+func (r *roleRepository) FindByUserIDs(userIDs []uuid.UUID) (map[uuid.UUID][]models.Role, error) {
+	query := `
+		SELECT ur.user_id, r.id, r.name, r.display_name, r.description, r.mfa_required, r.created_at, r.updated_at
+		FROM roles r
+		INNER JOIN user_roles ur ON r.id = ur.role_id
+		WHERE ur.user_id = ANY($1::uuid[])
+		ORDER BY ur.user_id, r.id DESC
+	`
+
+	var rows []struct {
+		UserID uuid.UUID `db:"user_id"`
+		models.Role
+	}
+	if err := r.db.Select(&rows, query, pq.Array(uuidStrings(userIDs))); err != nil {
+		return nil, fmt.Errorf("failed to find roles: %w", err)
+	}
+
+	roles := make(map[uuid.UUID][]models.Role, len(userIDs))
+	for _, row := range rows {
+		roles[row.UserID] = append(roles[row.UserID], row.Role)
+	}
+
+	return roles, nil
+}
+
+// AssignRoleToUsers grants a role to every existing user in the list and
+// returns the ones that did not have it yet
+func (r *roleRepository) AssignRoleToUsers(userIDs []uuid.UUID, roleID int, assignedBy uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		INSERT INTO user_roles (user_id, role_id, assigned_by)
+		SELECT u.id, $2, $3
+		FROM users u
+		WHERE u.id = ANY($1::uuid[]) AND u.deleted_at IS NULL
+		ON CONFLICT (user_id, role_id) DO NOTHING
+		RETURNING user_id
+	`
+
+	changed := []uuid.UUID{}
+	if err := r.db.Select(&changed, query, pq.Array(uuidStrings(userIDs)), roleID, assignedBy); err != nil {
+		return nil, fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return changed, nil
+}
+
+// RemoveRoleFromUsers takes a role away from every user in the list and
+// returns the ones that lost it. A user's only role is kept so nobody is
+// left without one.
+func (r *roleRepository) RemoveRoleFromUsers(userIDs []uuid.UUID, roleID int) ([]uuid.UUID, error) {
+	query := `
+		DELETE FROM user_roles ur
+		WHERE ur.user_id = ANY($1::uuid[]) AND ur.role_id = $2
+		  AND EXISTS (
+			SELECT 1 FROM user_roles other
+			WHERE other.user_id = ur.user_id AND other.role_id <> ur.role_id
+		  )
+		RETURNING ur.user_id
+	`
+
+	changed := []uuid.UUID{}
+	if err := r.db.Select(&changed, query, pq.Array(uuidStrings(userIDs)), roleID); err != nil {
+		return nil, fmt.Errorf("failed to remove role: %w", err)
+	}
+
+	return changed, nil
+}
+
+func (r *roleRepository) FindPermissionNamesByRole(roleName string) ([]string, error) {
+	query := `
+		SELECT p.name
+		FROM permissions p
+		INNER JOIN role_permissions rp ON p.id = rp.permission_id
+		INNER JOIN roles r ON r.id = rp.role_id
+		WHERE r.name = $1
+		ORDER BY p.name
+	`
+
+	permissions := []string{}
+	err := r.db.Select(&permissions, query, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find permissions: %w", err)
+	}
+
+	return permissions, nil
+}
+
+func (r *roleRepository) FindAll() ([]models.Role, error) {
+	query := `SELECT id, name, display_name, description, mfa_required, created_at, updated_at FROM roles ORDER BY id`
+
+	var roles []models.Role
+	if err := r.db.Select(&roles, query); err != nil {
+		return nil, fmt.Errorf("failed to find roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// SetMFARequired changes whether members of a role must use two-factor authentication
+func (r *roleRepository) SetMFARequired(roleName string, required bool) error {
+	query := `UPDATE roles SET mfa_required = $2 WHERE name = $1`
+
+	result, err := r.db.Exec(query, roleName, required)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("role not found")
+	}
+
+	return nil
+}
+
+func uuidStrings(ids []uuid.UUID) []string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = id.String()
+	}
+	return strs
+}
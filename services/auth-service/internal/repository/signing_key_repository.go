@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/jmoiron/sqlx"
+)
+
+// rotationLockID is the Postgres advisory lock that serialises key rotation across replicas
+const rotationLockID = 0x6a776b73 // "jwks"
+
+type SigningKeyRepository interface {
+	FindValid(now time.Time) ([]models.SigningKey, error)
+	Create(key *models.SigningKey) error
+	DeleteExpired(now time.Time) error
+	WithRotationLock(fn func() error) error
+}
+
+type signingKeyRepository struct {
+	db *sqlx.DB
+}
+
+func NewSigningKeyRepository(db *sqlx.DB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+// FindValid returns every key that may still verify tokens, oldest first
+func (r *signingKeyRepository) FindValid(now time.Time) ([]models.SigningKey, error) {
+	query := `
+		SELECT kid, algorithm, private_key, public_key, not_before, signing_until, verify_until, created_at
+		FROM signing_keys
+		WHERE verify_until > $1
+		ORDER BY not_before ASC
+	`
+
+	var keys []models.SigningKey
+	if err := r.db.Select(&keys, query, now); err != nil {
+		return nil, fmt.Errorf("failed to find signing keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+func (r *signingKeyRepository) Create(key *models.SigningKey) error {
+	query := `
+		INSERT INTO signing_keys (kid, algorithm, private_key, public_key, not_before, signing_until, verify_until)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING created_at
+	`
+
+	err := r.db.QueryRowx(query,
+		key.Kid,
+		key.Algorithm,
+		key.PrivateKey,
+		key.PublicKey,
+		key.NotBefore,
+		key.SigningUntil,
+		key.VerifyUntil,
+	).Scan(&key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create signing key: %w", err)
+	}
+
+	return nil
+}
+
+func (r *signingKeyRepository) DeleteExpired(now time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM signing_keys WHERE verify_until <= $1`, now); err != nil {
+		return fmt.Errorf("failed to delete expired signing keys: %w", err)
+	}
+	return nil
+}
+
+// WithRotationLock runs fn while holding a session advisory lock, so only one
+// replica creates keys at a time
+func (r *signingKeyRepository) WithRotationLock(fn func() error) error {
+	ctx := context.Background()
+
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, rotationLockID); err != nil {
+		return fmt.Errorf("failed to acquire rotation lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, rotationLockID)
+
+	return fn()
+}
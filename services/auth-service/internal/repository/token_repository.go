@@ -13,9 +13,14 @@ import (
 type TokenRepository interface {
 	CreateRefreshToken(token *models.RefreshToken) error
 	FindRefreshToken(tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(oldID uuid.UUID, next *models.RefreshToken) (bool, error)
 	UpdateLastUsed(tokenID uuid.UUID) error
 	RevokeToken(tokenID uuid.UUID, revokedBy uuid.UUID, reason string) error
-	RevokeAllUserTokens(userID uuid.UUID) error
+	RevokeFamily(familyID uuid.UUID, reason string) error
+	RevokeUserSession(userID, familyID uuid.UUID, reason string) (bool, error)
+	RevokeAllUserTokens(userID uuid.UUID, reason string) ([]uuid.UUID, error)
+	RevokeOtherUserTokens(userID, keepFamilyID uuid.UUID, reason string) ([]uuid.UUID, error)
+	FindActiveByUserID(userID uuid.UUID) ([]models.RefreshToken, error)
 	CleanupExpiredTokens() error
 }
 
@@ -27,25 +32,38 @@ func NewTokenRepository(db *sqlx.DB) TokenRepository {
 	return &tokenRepository{db: db}
 }
 
+const insertRefreshTokenQuery = `
+	INSERT INTO refresh_tokens (
+		id, user_id, token_hash, family_id, device_id, device_name, device_type,
+		user_agent, ip_address, expires_at, created_at, last_used_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	RETURNING id, created_at
+`
+
+// CreateRefreshToken stores a token, starting a new family unless FamilyID is set
 func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
-	query := `
-		INSERT INTO refresh_tokens (
-			id, user_id, token_hash, device_id, device_name, device_type,
-			user_agent, ip_address, expires_at, created_at, last_used_at
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, created_at
-	`
+	if err := insertRefreshToken(r.db, token); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
 
+	return nil
+}
+
+func insertRefreshToken(q sqlx.Queryer, token *models.RefreshToken) error {
 	now := time.Now()
 	token.ID = uuid.New()
+	if token.FamilyID == uuid.Nil {
+		token.FamilyID = token.ID
+	}
 	token.CreatedAt = now
 	token.LastUsedAt = now
 
-	err := r.db.QueryRowx(query,
+	return q.QueryRowx(insertRefreshTokenQuery,
 		token.ID,
 		token.UserID,
 		token.TokenHash,
+		token.FamilyID,
 		token.DeviceID,
 		token.DeviceName,
 		token.DeviceType,
@@ -55,17 +73,12 @@ func (r *tokenRepository) CreateRefreshToken(token *models.RefreshToken) error {
 		token.CreatedAt,
 		token.LastUsedAt,
 	).Scan(&token.ID, &token.CreatedAt)
-
-	if err != nil {
-		return fmt.Errorf("failed to create refresh token: %w", err)
-	}
-
-	return nil
 }
 
 func (r *tokenRepository) FindRefreshToken(tokenHash string) (*models.RefreshToken, error) {
 	query := `
-		SELECT id, user_id, token_hash, device_id, device_name, device_type,
+		SELECT id, user_id, token_hash, family_id, replaced_by, rotated_at,
+		       device_id, device_name, device_type,
 		       user_agent, ip_address, expires_at, revoked_at, revoked_by,
 		       revoked_reason, created_at, last_used_at
 		FROM refresh_tokens
@@ -84,6 +97,38 @@ func (r *tokenRepository) FindRefreshToken(tokenHash string) (*models.RefreshTok
 	return &token, nil
 }
 
+// RotateRefreshToken replaces a token with next, in the same family. It returns
+// false without storing next if the old token was already rotated or revoked,
+// so concurrent uses of one token cannot both succeed.
+func (r *tokenRepository) RotateRefreshToken(oldID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertRefreshToken(tx, next); err != nil {
+		return false, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE refresh_tokens
+		SET rotated_at = $2, replaced_by = $3, last_used_at = $2
+		WHERE id = $1 AND rotated_at IS NULL AND revoked_at IS NULL
+	`, oldID, time.Now(), next.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	return true, nil
+}
+
 func (r *tokenRepository) UpdateLastUsed(tokenID uuid.UUID) error {
 	query := `UPDATE refresh_tokens SET last_used_at = $2 WHERE id = $1`
 
@@ -110,21 +155,91 @@ func (r *tokenRepository) RevokeToken(tokenID uuid.UUID, revokedBy uuid.UUID, re
 	return nil
 }
 
-func (r *tokenRepository) RevokeAllUserTokens(userID uuid.UUID) error {
+// RevokeFamily revokes every token of a session, including rotated ones
+func (r *tokenRepository) RevokeFamily(familyID uuid.UUID, reason string) error {
 	query := `
 		UPDATE refresh_tokens
 		SET revoked_at = $2, revoked_reason = $3
-		WHERE user_id = $1 AND revoked_at IS NULL
+		WHERE family_id = $1 AND revoked_at IS NULL
 	`
 
-	_, err := r.db.Exec(query, userID, time.Now(), "logout_all")
+	_, err := r.db.Exec(query, familyID, time.Now(), reason)
 	if err != nil {
-		return fmt.Errorf("failed to revoke all tokens: %w", err)
+		return fmt.Errorf("failed to revoke token family: %w", err)
 	}
 
 	return nil
 }
 
+// RevokeUserSession revokes one of the user's sessions (token families); it
+// returns false if the session does not belong to the user or is already revoked
+func (r *tokenRepository) RevokeUserSession(userID, familyID uuid.UUID, reason string) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $3, revoked_by = $2, revoked_reason = $4
+		WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, familyID, userID, time.Now(), reason)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
+// RevokeAllUserTokens revokes every active token of the user and returns the
+// sessions (family IDs) it revoked
+func (r *tokenRepository) RevokeAllUserTokens(userID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	return r.RevokeOtherUserTokens(userID, uuid.Nil, reason)
+}
+
+// RevokeOtherUserTokens revokes every active token of the user outside
+// keepFamilyID and returns the sessions (family IDs) it revoked
+func (r *tokenRepository) RevokeOtherUserTokens(userID, keepFamilyID uuid.UUID, reason string) ([]uuid.UUID, error) {
+	query := `
+		WITH revoked AS (
+			UPDATE refresh_tokens
+			SET revoked_at = $3, revoked_reason = $4
+			WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+			RETURNING family_id
+		)
+		SELECT DISTINCT family_id FROM revoked
+	`
+
+	var ids []uuid.UUID
+	err := r.db.Select(&ids, query, userID, keepFamilyID, time.Now(), reason)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	return ids, nil
+}
+
+// FindActiveByUserID lists the current token of each active session, most
+// recently used first
+func (r *tokenRepository) FindActiveByUserID(userID uuid.UUID) ([]models.RefreshToken, error) {
+	query := `
+		SELECT t.id, t.user_id, t.token_hash, t.family_id, t.replaced_by, t.rotated_at,
+		       t.device_id, t.device_name, t.device_type,
+		       t.user_agent, t.ip_address, t.expires_at, t.revoked_at, t.revoked_by,
+		       t.revoked_reason, t.created_at, t.last_used_at,
+		       (SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id) AS session_started_at
+		FROM refresh_tokens t
+		WHERE t.user_id = $1 AND t.revoked_at IS NULL AND t.rotated_at IS NULL AND t.expires_at > $2
+		ORDER BY t.last_used_at DESC
+	`
+
+	var tokens []models.RefreshToken
+	err := r.db.Select(&tokens, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
 func (r *tokenRepository) CleanupExpiredTokens() error {
 	query := `DELETE FROM refresh_tokens WHERE expires_at < $1 OR revoked_at IS NOT NULL`
 
@@ -3,13 +3,13 @@ package repository
 import (
     "database/sql"
     "fmt"
-    "log"
     "strings"
     "time"
 
     "github.com/bisosad1501/DATN/services/auth-service/internal/models"
     "github.com/google/uuid"
     "github.com/jmoiron/sqlx"
+    "github.com/lib/pq"
 )
 
 type UserRepository interface {
@@ -17,14 +17,15 @@ type UserRepository interface {
 	Delete(userID uuid.UUID) error
 	FindByID(id uuid.UUID) (*models.User, error)
 	FindByEmail(email string) (*models.User, error)
-	FindByGoogleID(googleID string) (*models.User, error)
-	FindOrCreateByGoogleID(googleID, email, name string) (*models.User, error)
 	Update(user *models.User) error
 	UpdateLoginInfo(userID uuid.UUID, ip string) error
-	IncrementFailedAttempts(userID uuid.UUID) error
+	RecordFailedLogin(userID uuid.UUID, maxAttempts int, baseLock, maxLock time.Duration) (*time.Time, error)
 	ResetFailedAttempts(userID uuid.UUID) error
 	LockAccount(userID uuid.UUID, duration time.Duration) error
 	IsAccountLocked(userID uuid.UUID) (bool, error)
+	Search(filter models.UserFilter, limit, offset int) ([]models.User, int, error)
+	SetActive(userID uuid.UUID, active bool) error
+	FindIDsByEmails(emails []string) (map[string]uuid.UUID, error)
 }
 
 type userRepository struct {
@@ -110,139 +111,6 @@ func (r *userRepository) FindByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (r *userRepository) FindByGoogleID(googleID string) (*models.User, error) {
-	query := `
-		SELECT id, email, password_hash, phone, google_id, oauth_provider,
-		       is_active, is_verified, email_verified_at,
-		       failed_login_attempts, locked_until, last_login_at, last_login_ip,
-		       created_at, updated_at, deleted_at
-		FROM users
-		WHERE google_id = $1 AND deleted_at IS NULL
-	`
-
-    var user models.User
-    err := r.db.Get(&user, query, googleID)
-    if err != nil {
-        if err == sql.ErrNoRows {
-            return nil, fmt.Errorf("user not found")
-        }
-        return nil, fmt.Errorf("failed to find user: %w", err)
-    }
-
-	return &user, nil
-}
-
-func (r *userRepository) FindOrCreateByGoogleID(googleID, email, name string) (*models.User, error) {
-	// Try to find existing user
-	log.Printf("[FindOrCreateByGoogleID] Searching for Google ID: %s", googleID)
-	user, err := r.FindByGoogleID(googleID)
-	if err == nil {
-		log.Printf("[FindOrCreateByGoogleID] Found existing user by Google ID: %s", user.ID)
-		return user, nil
-	}
-	log.Printf("[FindOrCreateByGoogleID] No user found by Google ID, checking email: %s", email)
-
-	// Check if user exists with this email (non-Google account)
-    existingUser, err := r.FindByEmail(email)
-    if err == nil {
-        log.Printf("[FindOrCreateByGoogleID] Found existing user by email: ID=%s, GoogleID=%v", existingUser.ID, existingUser.GoogleID)
-        if existingUser.GoogleID == nil {
-            log.Printf("[FindOrCreateByGoogleID] Linking Google ID to existing account...")
-            // Link Google ID to existing account
-            query := `
-                UPDATE users 
-                SET google_id = $1, oauth_provider = $2, is_verified = true, 
-                    email_verified_at = $3, updated_at = $4
-                WHERE id = $5
-                RETURNING id, email, password_hash, phone, google_id, oauth_provider,
-                          is_active, is_verified, email_verified_at,
-                          failed_login_attempts, locked_until, last_login_at, last_login_ip,
-                          created_at, updated_at, deleted_at
-            `
-            now := time.Now()
-            provider := "google"
-            err := r.db.Get(existingUser, query, googleID, provider, now, now, existingUser.ID)
-            if err != nil {
-                log.Printf("[FindOrCreateByGoogleID] ❌ Failed to link: %v", err)
-                return nil, fmt.Errorf("failed to link Google account: %w", err)
-            }
-            log.Printf("[FindOrCreateByGoogleID] ✅ Successfully linked Google ID to existing account")
-            return existingUser, nil
-        }
-        // Already linked to Google; return existing user instead of attempting to create duplicate
-        log.Printf("[FindOrCreateByGoogleID] ⚠️ User already has Google ID: %v", existingUser.GoogleID)
-        return existingUser, nil
-    }
-    // No user found by email; proceed to create a new Google user
-    log.Printf("[FindOrCreateByGoogleID] No user found by email, creating new user...")
-
-	// Create new user
-	log.Printf("[FindOrCreateByGoogleID] Creating new user with email: %s, Google ID: %s", email, googleID)
-	query := `
-		INSERT INTO users (id, email, google_id, oauth_provider, is_active, is_verified,
-		                   email_verified_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
-		RETURNING id, email, password_hash, phone, google_id, oauth_provider,
-		          is_active, is_verified, email_verified_at,
-		          failed_login_attempts, locked_until, last_login_at, last_login_ip,
-		          created_at, updated_at, deleted_at
-	`
-
-    now := time.Now()
-    newUserID := uuid.New()
-    newUser := &models.User{}
-    err = r.db.Get(newUser, query,
-        newUserID, // $1 - id
-        email,     // $2 - email
-        googleID,  // $3 - google_id
-        "google",  // $4 - oauth_provider
-        true,      // $5 - is_active
-        true,      // $6 - is_verified
-        now,       // $7 - email_verified_at
-        now,       // $8 - created_at AND updated_at (reused)
-    )
-
-    if err != nil {
-        log.Printf("[FindOrCreateByGoogleID] ❌ Failed to create user: %v", err)
-        // Handle duplicate email: link Google ID to existing account and return it
-        if strings.Contains(err.Error(), "idx_users_email_unique") || strings.Contains(strings.ToLower(err.Error()), "duplicate key value") {
-            log.Printf("[FindOrCreateByGoogleID] 🔄 Detected duplicate email, attempting to link to existing account")
-            existingUserByEmail, err2 := r.FindByEmail(email)
-            if err2 == nil && existingUserByEmail != nil {
-                if existingUserByEmail.GoogleID == nil {
-                    log.Printf("[FindOrCreateByGoogleID] Linking Google ID to existing account after duplicate...")
-                    linkQuery := `
-                        UPDATE users 
-                        SET google_id = $1, oauth_provider = $2, is_verified = true, 
-                            email_verified_at = $3, updated_at = $4
-                        WHERE id = $5
-                        RETURNING id, email, password_hash, phone, google_id, oauth_provider,
-                                  is_active, is_verified, email_verified_at,
-                                  failed_login_attempts, locked_until, last_login_at, last_login_ip,
-                                  created_at, updated_at, deleted_at
-                    `
-                    now2 := time.Now()
-                    provider := "google"
-                    if err3 := r.db.Get(existingUserByEmail, linkQuery, googleID, provider, now2, now2, existingUserByEmail.ID); err3 != nil {
-                        log.Printf("[FindOrCreateByGoogleID] ❌ Failed to link after duplicate: %v", err3)
-                        return nil, fmt.Errorf("failed to link Google account: %w", err3)
-                    }
-                    log.Printf("[FindOrCreateByGoogleID] ✅ Successfully linked Google ID to existing account after duplicate")
-                    return existingUserByEmail, nil
-                }
-                // Already linked; return existing user
-                log.Printf("[FindOrCreateByGoogleID] ⚠️ Existing account already linked to Google, returning existing user")
-                return existingUserByEmail, nil
-            }
-            log.Printf("[FindOrCreateByGoogleID] ⚠️ Could not fetch existing account by email after duplicate: %v", err2)
-        }
-        return nil, fmt.Errorf("failed to create user: %w", err)
-    }
-
-	log.Printf("[FindOrCreateByGoogleID] ✅ Successfully created new user: %s", newUser.ID)
-	return newUser, nil
-}
-
 func (r *userRepository) Update(user *models.User) error {
 	query := `
 		UPDATE users
@@ -291,25 +159,41 @@ func (r *userRepository) UpdateLoginInfo(userID uuid.UUID, ip string) error {
 	return nil
 }
 
-func (r *userRepository) IncrementFailedAttempts(userID uuid.UUID) error {
+// RecordFailedLogin counts a failed sign-in and, once maxAttempts is reached,
+// locks the account. Every lockout before the next successful sign-in doubles
+// the lock, capped at maxLock. It returns the lock end when this failure locked
+// the account and nil otherwise.
+func (r *userRepository) RecordFailedLogin(userID uuid.UUID, maxAttempts int, baseLock, maxLock time.Duration) (*time.Time, error) {
 	query := `
 		UPDATE users
-		SET failed_login_attempts = failed_login_attempts + 1, updated_at = $2
+		SET failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $2 THEN 0 ELSE failed_login_attempts + 1 END,
+		    lockout_count = CASE WHEN failed_login_attempts + 1 >= $2 THEN lockout_count + 1 ELSE lockout_count END,
+		    locked_until = CASE WHEN failed_login_attempts + 1 >= $2
+		        THEN NOW() + LEAST($3 * POWER(2, lockout_count), $4) * INTERVAL '1 second'
+		        ELSE locked_until END,
+		    updated_at = NOW()
 		WHERE id = $1
+		RETURNING failed_login_attempts, locked_until
 	`
 
-	_, err := r.db.Exec(query, userID, time.Now())
+	var attempts int
+	var lockedUntil sql.NullTime
+	err := r.db.QueryRow(query, userID, maxAttempts, baseLock.Seconds(), maxLock.Seconds()).Scan(&attempts, &lockedUntil)
 	if err != nil {
-		return fmt.Errorf("failed to increment failed attempts: %w", err)
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
 	}
 
-	return nil
+	// The counter only restarts from zero when this failure locked the account
+	if attempts == 0 && lockedUntil.Valid {
+		return &lockedUntil.Time, nil
+	}
+	return nil, nil
 }
 
 func (r *userRepository) ResetFailedAttempts(userID uuid.UUID) error {
 	query := `
 		UPDATE users
-		SET failed_login_attempts = 0, locked_until = NULL, updated_at = $2
+		SET failed_login_attempts = 0, lockout_count = 0, locked_until = NULL, updated_at = $2
 		WHERE id = $1
 	`
 
@@ -381,3 +265,113 @@ func (r *userRepository) Delete(userID uuid.UUID) error {
 
 	return nil
 }
+
+// Search lists users matching the admin filter, newest first, with the total
+// number of matches for pagination
+func (r *userRepository) Search(filter models.UserFilter, limit, offset int) ([]models.User, int, error) {
+	conditions := []string{"u.deleted_at IS NULL"}
+	args := []interface{}{}
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		arg := addArg(q)
+		conditions = append(conditions, fmt.Sprintf("(u.email ILIKE '%%' || %s::text || '%%' OR u.id::text = %s)", arg, arg))
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+			SELECT 1 FROM user_roles ur INNER JOIN roles r ON r.id = ur.role_id
+			WHERE ur.user_id = u.id AND r.name = %s)`, addArg(filter.Role)))
+	}
+	switch filter.Status {
+	case models.UserStatusActive:
+		conditions = append(conditions, "u.is_active = true AND (u.locked_until IS NULL OR u.locked_until <= NOW())")
+	case models.UserStatusSuspended:
+		conditions = append(conditions, "u.is_active = false")
+	case models.UserStatusLocked:
+		conditions = append(conditions, "u.is_active = true AND u.locked_until > NOW()")
+	}
+	if filter.Verified != nil {
+		conditions = append(conditions, "u.is_verified = "+addArg(*filter.Verified))
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "u.created_at >= "+addArg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "u.created_at < "+addArg(*filter.CreatedTo))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := r.db.Get(&total, "SELECT COUNT(*) FROM users u WHERE "+where, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := `
+		SELECT u.id, u.email, u.password_hash, u.phone, u.google_id, u.oauth_provider,
+		       u.is_active, u.is_verified, u.email_verified_at,
+		       u.failed_login_attempts, u.locked_until, u.last_login_at, u.last_login_ip,
+		       u.created_at, u.updated_at, u.deleted_at
+		FROM users u
+		WHERE ` + where + `
+		ORDER BY u.created_at DESC, u.id
+		LIMIT ` + addArg(limit) + ` OFFSET ` + addArg(offset)
+
+	users := []models.User{}
+	if err := r.db.Select(&users, query, args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// SetActive suspends or reactivates an account
+func (r *userRepository) SetActive(userID uuid.UUID, active bool) error {
+	query := `
+		UPDATE users
+		SET is_active = $2, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	result, err := r.db.Exec(query, userID, active)
+	if err != nil {
+		return fmt.Errorf("failed to update user status: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// FindIDsByEmails maps each given email that belongs to an account, in lower
+// case, to the account's ID
+func (r *userRepository) FindIDsByEmails(emails []string) (map[string]uuid.UUID, error) {
+	query := `
+		SELECT lower(email) AS email, id
+		FROM users
+		WHERE lower(email) = ANY($1::text[]) AND deleted_at IS NULL
+	`
+
+	var rows []struct {
+		Email string    `db:"email"`
+		ID    uuid.UUID `db:"id"`
+	}
+	if err := r.db.Select(&rows, query, pq.Array(emails)); err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+
+	ids := make(map[string]uuid.UUID, len(rows))
+	for _, row := range rows {
+		ids[row.Email] = row.ID
+	}
+	return ids, nil
+}
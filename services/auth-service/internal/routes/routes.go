@@ -7,10 +7,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, authService service.AuthService) {
+func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, internalHandler *handlers.InternalHandler, authService service.AuthService, internalAPIKey string) {
 	// Health check
 	router.GET("/health", authHandler.HealthCheck)
 
+	// Public keys for access token verification
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
+
 	// API v1 group
 	v1 := router.Group("/api/v1")
 	{
@@ -22,30 +25,148 @@ func SetupRoutes(router *gin.Engine, authHandler *handlers.AuthHandler, authServ
 			auth.POST("/login", authHandler.Login)
 			auth.POST("/refresh", authHandler.RefreshToken)
 
-			// Google OAuth endpoints
-			auth.GET("/google/url", authHandler.GetGoogleOAuthURL)      // Step 1: Get OAuth URL (Mobile/Web)
-			auth.GET("/google", authHandler.GoogleLogin)                // Web flow: Redirect to Google
-			auth.GET("/google/callback", authHandler.GoogleCallback)    // Web flow: Handle callback
-			auth.POST("/google/token", authHandler.GoogleExchangeToken) // Mobile flow: Exchange code for tokens
+			// Passwordless sign-in with an emailed code or magic link
+			auth.POST("/login/email-code", authHandler.RequestEmailLoginCode)
+			auth.POST("/login/email-code/verify", authHandler.LoginWithEmailCode)
+
+			// Cancel link sent to the old address when the email is changed
+			auth.POST("/change-email/cancel", authHandler.CancelEmailChange)
+
+			// OpenID Connect sign-in
+			auth.GET("/oidc/providers", authHandler.ListOIDCProviders)
+			auth.GET("/oidc/:provider/url", authHandler.GetOIDCAuthURL)       // Step 1: Get OAuth URL (Mobile/Web)
+			auth.GET("/oidc/:provider", authHandler.OIDCLogin)                // Web flow: Redirect to the provider
+			auth.GET("/oidc/:provider/callback", authHandler.OIDCCallback)    // Web flow: Handle callback
+			auth.POST("/oidc/:provider/token", authHandler.OIDCExchangeToken) // Mobile flow: Exchange code for tokens
+
+			// Google aliases of the OpenID Connect endpoints
+			auth.GET("/google/url", authHandler.GetOIDCAuthURL)
+			auth.GET("/google", authHandler.OIDCLogin)
+			auth.GET("/google/callback", authHandler.OIDCCallback)
+			auth.POST("/google/token", authHandler.OIDCExchangeToken)
 
 			// Password reset endpoints
 			auth.POST("/forgot-password", authHandler.ForgotPassword)             // Request password reset (sends 6-digit code)
 			auth.POST("/reset-password", authHandler.ResetPassword)               // Reset password with token (legacy)
 			auth.POST("/reset-password-by-code", authHandler.ResetPasswordByCode) // Reset password with 6-digit code
 
+			// Two-factor login: exchange the mfa_token returned by login for tokens
+			auth.POST("/mfa/verify", authHandler.VerifyMFA)
+			auth.POST("/mfa/challenge/enrollment", authHandler.ChallengeEnrollment) // Enrolment secret for the Google redirect flow
+
 			// Email verification endpoints
 			auth.GET("/verify-email", authHandler.VerifyEmail)                // Verify email with token (legacy)
 			auth.POST("/verify-email-by-code", authHandler.VerifyEmailByCode) // Verify email with 6-digit code
 			auth.POST("/resend-verification", authHandler.ResendVerification) // Resend verification email (sends 6-digit code)
 
-			// Protected endpoints (require authentication)
+			// Endpoints that also accept impersonation tokens
+			authenticated := auth.Group("")
+			authenticated.Use(middleware.AuthMiddleware(authService))
+			{
+				authenticated.GET("/validate", authHandler.ValidateToken)
+				authenticated.DELETE("/impersonation", authHandler.EndImpersonation) // Revokes the impersonation token used
+			}
+
+			// Protected endpoints (require authentication, not available while impersonating)
 			protected := auth.Group("")
-			protected.Use(middleware.AuthMiddleware(authService))
+			protected.Use(middleware.AuthMiddleware(authService), middleware.RejectImpersonation())
 			{
-				protected.GET("/validate", authHandler.ValidateToken)
 				protected.POST("/logout", authHandler.Logout)
 				protected.POST("/change-password", authHandler.ChangePassword)
+				protected.POST("/change-email", authHandler.RequestEmailChange)         // Sends a code to the new address
+				protected.POST("/change-email/confirm", authHandler.ConfirmEmailChange) // Swaps the email and signs every session out
+
+				// Sessions (signed-in devices)
+				protected.GET("/sessions", authHandler.ListSessions)
+				protected.DELETE("/sessions", authHandler.RevokeOtherSessions) // All except the current one
+				protected.DELETE("/sessions/:id", authHandler.RevokeSession)
+
+				// Linked sign-in providers
+				protected.GET("/identities", authHandler.ListIdentities)
+				protected.POST("/identities/:provider", authHandler.LinkIdentity) // Returns the provider URL to open
+				protected.DELETE("/identities/:provider", authHandler.UnlinkIdentity)
+
+				// Two-factor authentication management
+				protected.GET("/mfa", authHandler.MFAStatus)
+				protected.POST("/mfa/enroll", authHandler.EnrollMFA)
+				protected.POST("/mfa/enroll/confirm", authHandler.ConfirmMFA)
+				protected.POST("/mfa/disable", authHandler.DisableMFA)
+				protected.POST("/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+
+				// Personal data export and account deletion
+				protected.POST("/account/export", authHandler.RequestDataExport)
+				protected.GET("/account/export/:id/download", authHandler.DownloadDataExport)
+				protected.GET("/account/data-requests", authHandler.ListDataRequests)
+				protected.POST("/account/deletion", authHandler.RequestAccountDeletion)
+				protected.DELETE("/account/deletion", authHandler.CancelAccountDeletion) // Only during the grace period
+			}
+
+			// Internal endpoints (service-to-service, require API key)
+			internal := auth.Group("/internal")
+			internal.Use(middleware.InternalAuth(internalAPIKey))
+			{
+				internal.GET("/roles/:role/permissions", internalHandler.GetRolePermissions)
+				internal.POST("/audit-events", internalHandler.RecordAuditEvent)
+				internal.GET("/classrooms/:id/access", internalHandler.GetClassroomAccess) // ?user_id=
+				internal.GET("/users/:user_id/classrooms", internalHandler.ListUserClassrooms)
 			}
 		}
+
+		// Admin endpoints (reached through the gateway's admin routes)
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthMiddleware(authService), middleware.RoleMiddleware("admin"))
+		{
+			admin.GET("/mfa/policies", authHandler.GetMFAPolicies)
+			admin.PUT("/mfa/policies/:role", authHandler.SetMFAPolicy)
+			admin.POST("/users/:id/unlock", authHandler.UnlockAccount)
+
+			// User management
+			admin.GET("/users", authHandler.SearchUsers)
+			admin.GET("/users/:id", authHandler.GetUser)
+			admin.POST("/users/:id/suspend", authHandler.SuspendUser) // Also signs every session out
+			admin.POST("/users/:id/reactivate", authHandler.ReactivateUser)
+			admin.POST("/users/roles", authHandler.ChangeUserRoles)           // Bulk assign or revoke
+			admin.POST("/users/:id/impersonate", authHandler.ImpersonateUser) // Short-lived access token with an act claim
+
+			// Audit log
+			admin.GET("/audit-logs", authHandler.SearchAuditLogs)
+			admin.GET("/audit-logs/export", authHandler.ExportAuditLogs) // CSV or JSON download
+
+			// Personal data exports and account deletions
+			admin.GET("/data-requests", authHandler.ListAllDataRequests)
+			admin.GET("/data-requests/:id", authHandler.GetDataRequest)
+			admin.POST("/data-requests/:id/retry", authHandler.RetryDataRequest)
+
+			// Organizations
+			admin.GET("/organizations", authHandler.SearchOrganizations)
+			admin.POST("/organizations", authHandler.CreateOrganization)
+		}
+
+		// Organizations (access depends on the caller's role in each organization)
+		organizations := v1.Group("/organizations")
+		organizations.Use(middleware.AuthMiddleware(authService), middleware.RejectImpersonation())
+		{
+			organizations.GET("", authHandler.ListMyOrganizations)
+			organizations.GET("/:id/members", authHandler.ListOrganizationMembers)
+			organizations.POST("/:id/members", authHandler.AddOrganizationMember) // Also changes a member's role
+			organizations.DELETE("/:id/members/:user_id", authHandler.RemoveOrganizationMember)
+			organizations.GET("/:id/classrooms", authHandler.ListClassrooms)
+			organizations.POST("/:id/classrooms", authHandler.CreateClassroom)
+		}
+
+		// Classrooms
+		classrooms := v1.Group("/classrooms")
+		classrooms.Use(middleware.AuthMiddleware(authService), middleware.RejectImpersonation())
+		{
+			classrooms.GET("", authHandler.ListMyClassrooms)
+			classrooms.POST("/join", authHandler.JoinClassroom) // By invite code
+			classrooms.GET("/:id/members", authHandler.GetClassroomRoster)
+			classrooms.POST("/:id/members", authHandler.AddClassroomMember)
+			classrooms.DELETE("/:id/members/:user_id", authHandler.RemoveClassroomMember)
+			classrooms.POST("/:id/invites", authHandler.InviteClassroomStudents) // CSV upload
+			classrooms.DELETE("/:id/invites/:invite_id", authHandler.RevokeClassroomInvite)
+			classrooms.POST("/:id/invite-code", authHandler.RegenerateClassroomInviteCode)
+			classrooms.GET("/:id/progress", authHandler.GetClassroomProgress)
+		}
 	}
 }
@@ -0,0 +1,486 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/google/uuid"
+)
+
+const (
+	// dataRequestPollInterval is how often the worker looks for due requests
+	dataRequestPollInterval = time.Minute
+	// dataRequestLease keeps other replicas off a request while it runs; a
+	// crashed run is picked up again once it lapses
+	dataRequestLease = 10 * time.Minute
+	// dataRequestMaxAttempts is how many runs may fail before an admin has to retry
+	dataRequestMaxAttempts = 6
+	// dataExportRetention is how long an export archive can be downloaded
+	dataExportRetention = 7 * 24 * time.Hour
+
+	// authDataStep is the auth service's own step. A deletion runs it last,
+	// once every other service has erased its data, since it anonymises the
+	// account the others are keyed by.
+	authDataStep = "auth"
+)
+
+var (
+	ErrDataRequestPending     = errors.New("a request of this type is already in progress")
+	ErrDataRequestNotFound    = errors.New("data request not found")
+	ErrExportNotReady         = errors.New("the export is not ready yet")
+	ErrExportExpired          = errors.New("the export has expired; request a new one")
+	ErrDeletionNotCancellable = errors.New("no account deletion can be cancelled")
+	ErrDataRequestNotFailed   = errors.New("only failed requests can be retried")
+)
+
+// AccountDataService exports a user's personal data from every service and
+// deletes accounts after a grace period. Each service's part is a tracked
+// step, so a run that fails part-way only repeats the failed steps.
+type AccountDataService interface {
+	RequestExport(userID uuid.UUID, ip, userAgent string) (*models.AccountDataRequest, error)
+	RequestDeletion(userID uuid.UUID, req *models.AccountDeletionRequest, ip, userAgent string) (*models.AccountDataRequest, error)
+	CancelDeletion(userID uuid.UUID, ip, userAgent string) error
+	ListRequests(userID uuid.UUID) ([]models.AccountDataRequest, error)
+	DownloadExport(userID, requestID uuid.UUID) ([]byte, error)
+
+	// Admin
+	ListAllRequests(status string, page, limit int) (*models.AccountDataRequestList, error)
+	GetRequest(requestID uuid.UUID) (*models.AccountDataRequest, error)
+	RetryRequest(requestID, adminID uuid.UUID, ip, userAgent string) error
+
+	// Start processes due requests until ctx is cancelled
+	Start(ctx context.Context)
+}
+
+// dataService is a service holding personal data, reached through its internal API
+type dataService struct {
+	name   string
+	client client.PersonalDataClient
+}
+
+type accountDataService struct {
+	dataRepo      repository.AccountDataRepository
+	auditRepo     repository.AuditLogRepository
+	authService   AuthService
+	storageClient *client.StorageServiceClient
+	services      []dataService
+	gracePeriod   time.Duration
+}
+
+func NewAccountDataService(
+	cfg *config.Config,
+	dataRepo repository.AccountDataRepository,
+	auditRepo repository.AuditLogRepository,
+	authService AuthService,
+	userServiceClient *client.UserServiceClient,
+) AccountDataService {
+	gracePeriod, err := time.ParseDuration(cfg.AccountDeletionGracePeriod)
+	if err != nil || gracePeriod < 0 {
+		log.Printf("⚠️ Invalid ACCOUNT_DELETION_GRACE_PERIOD %q, using 720h", cfg.AccountDeletionGracePeriod)
+		gracePeriod = 720 * time.Hour
+	}
+
+	storageClient := client.NewStorageServiceClient(cfg.StorageServiceURL, cfg.InternalAPIKey)
+
+	return &accountDataService{
+		dataRepo:      dataRepo,
+		auditRepo:     auditRepo,
+		authService:   authService,
+		storageClient: storageClient,
+		services: []dataService{
+			{"user", userServiceClient},
+			{"course", client.NewCourseServiceClient(cfg.CourseServiceURL, cfg.InternalAPIKey)},
+			{"exercise", client.NewExerciseServiceClient(cfg.ExerciseServiceURL, cfg.InternalAPIKey)},
+			{"notification", client.NewNotificationServiceClient(cfg.NotificationServiceURL, cfg.InternalAPIKey)},
+			{"storage", storageClient},
+		},
+		gracePeriod: gracePeriod,
+	}
+}
+
+// stepNames lists every step in the order they run
+func (s *accountDataService) stepNames() []string {
+	names := make([]string, 0, len(s.services)+1)
+	for _, svc := range s.services {
+		names = append(names, svc.name)
+	}
+	return append(names, authDataStep)
+}
+
+// RequestExport queues an export of everything held about the user
+func (s *accountDataService) RequestExport(userID uuid.UUID, ip, userAgent string) (*models.AccountDataRequest, error) {
+	return s.createRequest(userID, models.DataRequestExport, time.Now(), ip, userAgent, "data_export_requested")
+}
+
+// RequestDeletion schedules the account for deletion once the grace period
+// ends, after the user confirms their password and second factor
+func (s *accountDataService) RequestDeletion(userID uuid.UUID, req *models.AccountDeletionRequest, ip, userAgent string) (*models.AccountDataRequest, error) {
+	if err := s.authService.ConfirmIdentity(userID, req.Password, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrInvalidMFACode) {
+			writeAuditLog(s.auditRepo, &userID, "account_deletion_requested", "failed", ip, userAgent, err.Error())
+		}
+		return nil, err
+	}
+
+	return s.createRequest(userID, models.DataRequestDeletion, time.Now().Add(s.gracePeriod), ip, userAgent, "account_deletion_requested")
+}
+
+func (s *accountDataService) createRequest(userID uuid.UUID, requestType string, scheduledFor time.Time, ip, userAgent, event string) (*models.AccountDataRequest, error) {
+	pending, err := s.dataRepo.FindPending(userID, requestType)
+	if err != nil {
+		return nil, err
+	}
+	if pending != nil {
+		return nil, ErrDataRequestPending
+	}
+
+	req := &models.AccountDataRequest{
+		UserID:       userID,
+		Type:         requestType,
+		ScheduledFor: scheduledFor,
+	}
+	if err := s.dataRepo.Create(req, s.stepNames()); err != nil {
+		return nil, err
+	}
+
+	writeAuditLog(s.auditRepo, &userID, event, "success", ip, userAgent, "", map[string]interface{}{
+		"request_id":    req.ID,
+		"scheduled_for": req.ScheduledFor,
+	})
+	return req, nil
+}
+
+// CancelDeletion stops a scheduled deletion while it is in its grace period
+func (s *accountDataService) CancelDeletion(userID uuid.UUID, ip, userAgent string) error {
+	cancelled, err := s.dataRepo.CancelDeletion(userID)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrDeletionNotCancellable
+	}
+
+	writeAuditLog(s.auditRepo, &userID, "account_deletion_cancelled", "success", ip, userAgent, "")
+	return nil
+}
+
+// ListRequests returns the user's exports and deletions, newest first
+func (s *accountDataService) ListRequests(userID uuid.UUID) ([]models.AccountDataRequest, error) {
+	return s.dataRepo.ListByUserID(userID)
+}
+
+// DownloadExport returns the zip archive of a completed export
+func (s *accountDataService) DownloadExport(userID, requestID uuid.UUID) ([]byte, error) {
+	req, err := s.dataRepo.FindByID(requestID)
+	if err != nil || req.UserID != userID || req.Type != models.DataRequestExport {
+		return nil, ErrDataRequestNotFound
+	}
+	if req.Status != models.DataRequestCompleted {
+		return nil, ErrExportNotReady
+	}
+	if req.ArchiveExpiresAt == nil || time.Now().After(*req.ArchiveExpiresAt) {
+		return nil, ErrExportExpired
+	}
+
+	archive, err := s.dataRepo.FindArchive(requestID, userID)
+	if err != nil {
+		return nil, ErrExportExpired
+	}
+	return archive, nil
+}
+
+// ListAllRequests pages through every user's requests, optionally by status
+func (s *accountDataService) ListAllRequests(status string, page, limit int) (*models.AccountDataRequestList, error) {
+	requests, total, err := s.dataRepo.List(status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AccountDataRequestList{
+		Requests: requests,
+		Total:    total,
+		Page:     page,
+		Limit:    limit,
+	}, nil
+}
+
+// GetRequest returns a request with the state of each service's step
+func (s *accountDataService) GetRequest(requestID uuid.UUID) (*models.AccountDataRequest, error) {
+	req, err := s.dataRepo.FindByID(requestID)
+	if err != nil {
+		return nil, ErrDataRequestNotFound
+	}
+	return req, nil
+}
+
+// RetryRequest queues a failed request again; completed steps are not repeated
+func (s *accountDataService) RetryRequest(requestID, adminID uuid.UUID, ip, userAgent string) error {
+	req, err := s.dataRepo.FindByID(requestID)
+	if err != nil {
+		return ErrDataRequestNotFound
+	}
+
+	retried, err := s.dataRepo.Retry(requestID)
+	if err != nil {
+		return err
+	}
+	if !retried {
+		return ErrDataRequestNotFailed
+	}
+
+	writeAuditLog(s.auditRepo, &req.UserID, "data_request_retried", "success", ip, userAgent, "", map[string]interface{}{
+		"request_id": requestID,
+		"admin_id":   adminID,
+	})
+	return nil
+}
+
+// Start processes due requests on a ticker until ctx is cancelled
+func (s *accountDataService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(dataRequestPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.processDue(ctx)
+			}
+		}
+	}()
+}
+
+// processDue runs every due request, one at a time
+func (s *accountDataService) processDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		req, err := s.dataRepo.ClaimDue(dataRequestLease)
+		if err != nil {
+			log.Printf("❌ Failed to claim data request: %v", err)
+			return
+		}
+		if req == nil {
+			return
+		}
+		s.process(req)
+	}
+}
+
+// process runs the request's unfinished steps and records the outcome
+func (s *accountDataService) process(req *models.AccountDataRequest) {
+	if req.Type == models.DataRequestDeletion {
+		// Nothing may sign in or hold a session while the data goes
+		if err := s.dataRepo.DeactivateUser(req.UserID); err != nil {
+			s.recordFailure(req, err.Error())
+			return
+		}
+		s.authService.RevokeAllSessions(req.UserID, "account_deleted")
+	}
+
+	done := make(map[string]bool, len(req.Steps))
+	for _, step := range req.Steps {
+		done[step.Service] = step.Status == models.DataRequestCompleted
+	}
+
+	var failures []string
+	for _, name := range s.stepNames() {
+		if done[name] {
+			continue
+		}
+		if name == authDataStep && req.Type == models.DataRequestDeletion && len(failures) > 0 {
+			continue
+		}
+
+		if err := s.runStep(req, name); err != nil {
+			log.Printf("⚠️ Data request %s: %s step failed: %v", req.ID, name, err)
+			if err := s.dataRepo.FailStep(req.ID, name, err.Error()); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	if len(failures) > 0 {
+		s.recordFailure(req, strings.Join(failures, "; "))
+		return
+	}
+
+	if req.Type == models.DataRequestExport {
+		s.completeExport(req)
+		return
+	}
+
+	if err := s.dataRepo.Complete(req.ID, nil, nil); err != nil {
+		log.Printf("❌ Failed to complete data request %s: %v", req.ID, err)
+		return
+	}
+	writeAuditLog(s.auditRepo, &req.UserID, "account_deleted", "success", "", "", "", map[string]interface{}{
+		"request_id": req.ID,
+	})
+}
+
+// runStep exports or erases one service's data and marks the step done
+func (s *accountDataService) runStep(req *models.AccountDataRequest, name string) error {
+	userID := req.UserID.String()
+
+	if name == authDataStep {
+		if req.Type == models.DataRequestDeletion {
+			if err := s.dataRepo.EraseAuthData(req.UserID); err != nil {
+				return err
+			}
+			return s.dataRepo.CompleteStep(req.ID, name, nil)
+		}
+
+		data, err := s.dataRepo.ExportAuthData(req.UserID)
+		if err != nil {
+			return err
+		}
+		output, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return s.dataRepo.CompleteStep(req.ID, name, output)
+	}
+
+	for _, svc := range s.services {
+		if svc.name != name {
+			continue
+		}
+
+		if req.Type == models.DataRequestDeletion {
+			if err := svc.client.EraseUserData(userID); err != nil {
+				return err
+			}
+			return s.dataRepo.CompleteStep(req.ID, name, nil)
+		}
+
+		output, err := svc.client.ExportUserData(userID)
+		if err != nil {
+			return err
+		}
+		return s.dataRepo.CompleteStep(req.ID, name, output)
+	}
+
+	return fmt.Errorf("unknown service %q", name)
+}
+
+// completeExport packs every step's data into the archive the user downloads
+func (s *accountDataService) completeExport(req *models.AccountDataRequest) {
+	outputs, err := s.dataRepo.StepOutputs(req.ID)
+	if err != nil {
+		s.recordFailure(req, err.Error())
+		return
+	}
+
+	archive, err := s.buildArchive(outputs)
+	if err != nil {
+		s.recordFailure(req, fmt.Sprintf("archive: %v", err))
+		return
+	}
+
+	expiresAt := time.Now().Add(dataExportRetention)
+	if err := s.dataRepo.Complete(req.ID, archive, &expiresAt); err != nil {
+		log.Printf("❌ Failed to complete data request %s: %v", req.ID, err)
+		return
+	}
+	writeAuditLog(s.auditRepo, &req.UserID, "data_export_completed", "success", "", "", "", map[string]interface{}{
+		"request_id": req.ID,
+		"size":       len(archive),
+	})
+}
+
+// buildArchive writes <service>.json for each service, and the user's
+// recordings under recordings/
+func (s *accountDataService) buildArchive(outputs map[string][]byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, name := range s.stepNames() {
+		output, ok := outputs[name]
+		if !ok {
+			continue
+		}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, output, "", "  "); err != nil {
+			return nil, fmt.Errorf("%s data: %w", name, err)
+		}
+		w, err := zw.Create(name + ".json")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(indented.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	if output, ok := outputs["storage"]; ok {
+		var storage struct {
+			Recordings []client.StoredObject `json:"recordings"`
+		}
+		if err := json.Unmarshal(output, &storage); err != nil {
+			return nil, fmt.Errorf("storage data: %w", err)
+		}
+		for _, object := range storage.Recordings {
+			if err := s.addRecording(zw, object.ObjectName); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *accountDataService) addRecording(zw *zip.Writer, objectName string) error {
+	body, err := s.storageClient.DownloadObject(objectName)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	w, err := zw.Create("recordings/" + path.Base(objectName))
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, body); err != nil {
+		return fmt.Errorf("download %s: %w", objectName, err)
+	}
+	return nil
+}
+
+// recordFailure schedules another run with exponential backoff, or fails the
+// request once it has used up its attempts
+func (s *accountDataService) recordFailure(req *models.AccountDataRequest, errMsg string) {
+	attempts := req.Attempts + 1
+	if attempts >= dataRequestMaxAttempts {
+		if err := s.dataRepo.RecordFailure(req.ID, errMsg, nil); err != nil {
+			log.Printf("❌ %v", err)
+		}
+		writeAuditLog(s.auditRepo, &req.UserID, "data_request_failed", "failed", "", "", errMsg, map[string]interface{}{
+			"request_id": req.ID,
+			"type":       req.Type,
+		})
+		return
+	}
+
+	retryAt := time.Now().Add(time.Minute << attempts)
+	if err := s.dataRepo.RecordFailure(req.ID, errMsg, &retryAt); err != nil {
+		log.Printf("❌ %v", err)
+	}
+}
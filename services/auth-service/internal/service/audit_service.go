@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/csvsafe"
+	"github.com/google/uuid"
+)
+
+const (
+	// auditExportBatchSize is how many entries an export reads at a time
+	auditExportBatchSize = 1000
+	// auditArchiveBatchSize bounds each archive statement so it does not hold
+	// locks on audit_logs for long
+	auditArchiveBatchSize = 5000
+	auditArchiveInterval  = time.Hour
+)
+
+// Audit log export formats
+const (
+	AuditExportCSV  = "csv"
+	AuditExportJSON = "json"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// AuditService lets admins search and export the audit log, and archives
+// old entries
+type AuditService interface {
+	Search(filter models.AuditLogFilter, cursor string, limit int) (*models.AuditLogPage, error)
+	Export(filter models.AuditLogFilter, format string, w io.Writer, adminID uuid.UUID, ip, userAgent string) error
+	Start(ctx context.Context)
+}
+
+type auditService struct {
+	auditRepo repository.AuditLogRepository
+	retention time.Duration
+}
+
+func NewAuditService(cfg *config.Config, auditRepo repository.AuditLogRepository) AuditService {
+	retention, err := time.ParseDuration(cfg.AuditLogRetention)
+	if err != nil {
+		log.Printf("⚠️ Invalid AUDIT_LOG_RETENTION %q, audit logs will not be archived", cfg.AuditLogRetention)
+		retention = 0
+	}
+
+	return &auditService{
+		auditRepo: auditRepo,
+		retention: retention,
+	}
+}
+
+// Search returns a page of entries, newest first. cursor is the NextCursor of
+// the previous page.
+func (s *auditService) Search(filter models.AuditLogFilter, cursor string, limit int) (*models.AuditLogPage, error) {
+	if cursor != "" {
+		beforeID, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil || beforeID <= 0 {
+			return nil, ErrInvalidCursor
+		}
+		filter.BeforeID = beforeID
+	}
+
+	// One extra entry tells whether there is a next page
+	events, err := s.auditRepo.Search(filter, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.AuditLogPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = strconv.FormatInt(page.Events[limit-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// Export writes every matching entry to w as CSV or a JSON array, reading
+// them in batches so large exports do not sit in memory
+func (s *auditService) Export(filter models.AuditLogFilter, format string, w io.Writer, adminID uuid.UUID, ip, userAgent string) error {
+	var exported int
+	var err error
+	switch format {
+	case AuditExportJSON:
+		exported, err = s.exportJSON(filter, w)
+	default:
+		exported, err = s.exportCSV(filter, w)
+	}
+
+	status, errMsg := "success", ""
+	if err != nil {
+		status, errMsg = "failed", err.Error()
+	}
+	writeAuditLog(s.auditRepo, &adminID, "audit_log_exported", status, ip, userAgent, errMsg, map[string]interface{}{
+		"format":   format,
+		"exported": exported,
+		"archived": filter.Archived,
+	})
+	return err
+}
+
+func (s *auditService) exportCSV(filter models.AuditLogFilter, w io.Writer) (int, error) {
+	out := csv.NewWriter(w)
+	header := []string{"id", "created_at", "user_id", "event_type", "event_status", "ip_address", "user_agent", "error_message", "metadata"}
+	if err := out.Write(header); err != nil {
+		return 0, err
+	}
+
+	exported, err := s.eachBatch(filter, func(events []models.AuditEvent) error {
+		for _, event := range events {
+			userID := ""
+			if event.UserID != nil {
+				userID = event.UserID.String()
+			}
+			metadata := ""
+			if event.Metadata.Valid {
+				metadata = event.Metadata.String()
+			}
+			record := []string{
+				strconv.FormatInt(event.ID, 10),
+				event.CreatedAt.UTC().Format(time.RFC3339),
+				userID,
+				event.EventType,
+				event.EventStatus,
+				stringOrEmpty(event.IPAddress),
+				// Client-supplied text must not run as a formula in a spreadsheet
+				csvsafe.Cell(stringOrEmpty(event.UserAgent)),
+				csvsafe.Cell(stringOrEmpty(event.ErrorMessage)),
+				csvsafe.Cell(metadata),
+			}
+			if err := out.Write(record); err != nil {
+				return err
+			}
+		}
+		out.Flush()
+		return out.Error()
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	out.Flush()
+	return exported, out.Error()
+}
+
+func (s *auditService) exportJSON(filter models.AuditLogFilter, w io.Writer) (int, error) {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return 0, err
+	}
+
+	first := true
+	exported, err := s.eachBatch(filter, func(events []models.AuditEvent) error {
+		for _, event := range events {
+			encoded, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Errorf("failed to encode audit event %d: %w", event.ID, err)
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(encoded); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return exported, err
+	}
+
+	_, err = io.WriteString(w, "]")
+	return exported, err
+}
+
+// eachBatch pages through every entry matching the filter, newest first
+func (s *auditService) eachBatch(filter models.AuditLogFilter, fn func([]models.AuditEvent) error) (int, error) {
+	total := 0
+	for {
+		events, err := s.auditRepo.Search(filter, auditExportBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(events) == 0 {
+			return total, nil
+		}
+		if err := fn(events); err != nil {
+			return total, err
+		}
+		total += len(events)
+		if len(events) < auditExportBatchSize {
+			return total, nil
+		}
+		filter.BeforeID = events[len(events)-1].ID
+	}
+}
+
+// Start archives entries older than the retention period on a ticker until
+// ctx is cancelled. A zero retention keeps every entry in audit_logs.
+func (s *auditService) Start(ctx context.Context) {
+	if s.retention <= 0 {
+		return
+	}
+
+	go func() {
+		s.archive(ctx)
+
+		ticker := time.NewTicker(auditArchiveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.archive(ctx)
+			}
+		}
+	}()
+}
+
+// archive moves old entries in batches until none are left
+func (s *auditService) archive(ctx context.Context) {
+	cutoff := time.Now().Add(-s.retention)
+	total := 0
+	for ctx.Err() == nil {
+		archived, err := s.auditRepo.ArchiveBefore(cutoff, auditArchiveBatchSize)
+		if err != nil {
+			log.Printf("❌ Failed to archive audit logs: %v", err)
+			break
+		}
+		total += archived
+		if archived < auditArchiveBatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Archived %d audit log entries older than %s", total, cutoff.Format(time.RFC3339))
+	}
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
@@ -13,6 +15,7 @@ import (
 	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
 	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
 	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
 	"github.com/go-redis/redis/v8"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -33,11 +36,59 @@ type AuthService interface {
 
 	// Email verification
 	VerifyEmail(token string) error
-	VerifyEmailByCode(code string) error
+	VerifyEmailByCode(req *models.VerifyEmailByCodeRequest, ip, userAgent string) error
 	ResendVerification(email string) error
 
 	// Reset password with code
-	ResetPasswordByCode(code, newPassword, ip string) error
+	ResetPasswordByCode(req *models.ResetPasswordByCodeRequest, ip, userAgent string) error
+
+	// Passwordless sign-in with an emailed code or magic link
+	RequestEmailLoginCode(req *models.EmailLoginCodeRequest, ip, userAgent string) error
+	LoginWithEmailCode(req *models.VerifyEmailLoginCodeRequest, ip, userAgent string) (*models.AuthResponse, error)
+
+	// Email change, confirmed with a code sent to the new address
+	RequestEmailChange(userID uuid.UUID, req *models.ChangeEmailRequest, ip, userAgent string) error
+	ConfirmEmailChange(userID uuid.UUID, code, ip, userAgent string) error
+	CancelEmailChange(token, ip, userAgent string) error
+
+	// Brute-force protection
+	UnlockAccount(userID, adminID uuid.UUID, ip, userAgent string) error
+
+	// Sessions (one per refresh token)
+	IssueTokens(userID uuid.UUID, email, role, ip, userAgent string) (accessToken, refreshToken string, expiresIn int64, err error)
+	ListSessions(userID, currentSessionID uuid.UUID) ([]models.Session, error)
+	RevokeSession(userID, sessionID uuid.UUID, ip, userAgent string) error
+	RevokeOtherSessions(userID, currentSessionID uuid.UUID, ip, userAgent string) (int, error)
+	RevokeAllSessions(userID uuid.UUID, reason string)
+
+	// Admin user management
+	SearchUsers(filter models.UserFilter, page, limit int) (*models.AdminUserList, error)
+	GetUser(userID uuid.UUID) (*models.AdminUser, error)
+	SuspendUser(userID, adminID uuid.UUID, reason, ip, userAgent string) error
+	ReactivateUser(userID, adminID uuid.UUID, ip, userAgent string) error
+	ChangeUserRoles(req *models.BulkRoleChangeRequest, adminID uuid.UUID, ip, userAgent string) (*models.BulkRoleChangeResult, error)
+	Impersonate(userID, adminID uuid.UUID, req *models.ImpersonateRequest, ip, userAgent string) (*models.ImpersonationData, error)
+	EndImpersonation(userID, adminID, sessionID uuid.UUID, ip, userAgent string) error
+
+	// Authorization (used by the API gateway)
+	GetRolePermissions(role string) ([]string, error)
+	RecordAuditEvent(req *models.AuditEventRequest) error
+
+	// Two-factor authentication
+	GetMFAStatus(userID uuid.UUID) (*models.MFAStatus, error)
+	EnrollMFA(userID uuid.UUID, ip, userAgent string) (*models.MFAEnrollmentData, error)
+	ConfirmMFA(userID uuid.UUID, code, ip, userAgent string) ([]string, error)
+	DisableMFA(userID uuid.UUID, req *models.MFADisableRequest, ip, userAgent string) error
+	RegenerateRecoveryCodes(userID uuid.UUID, code, ip, userAgent string) ([]string, error)
+	BeginMFAChallenge(user *models.User, role *models.Role, ip, userAgent string) (*models.AuthResponse, error)
+	GetChallengeEnrollment(mfaToken string) (*models.MFAEnrollmentData, error)
+	VerifyMFA(req *models.MFAVerifyRequest, ip, userAgent string) (*models.AuthResponse, error)
+	GetMFAPolicies() ([]models.MFAPolicy, error)
+	SetMFAPolicy(role string, required bool, adminID uuid.UUID, ip, userAgent string) error
+	ConfirmIdentity(userID uuid.UUID, password, code string) error
+
+	// Public keys for verifying access tokens
+	GetJWKS() jwks.Set
 }
 
 type authService struct {
@@ -45,31 +96,49 @@ type authService struct {
 	roleRepo              repository.RoleRepository
 	tokenRepo             repository.TokenRepository
 	auditRepo             repository.AuditLogRepository
+	mfaRepo               repository.MFARepository
 	passwordResetRepo     repository.PasswordResetRepository
 	emailVerificationRepo repository.EmailVerificationRepository
+	loginCodeRepo         repository.LoginCodeRepository
+	emailChangeRepo       repository.EmailChangeRepository
 	emailService          EmailService
 	redisClient           *redis.Client
+	keys                  KeyManager
+	mfaSecrets            *secretBox
 	config                *config.Config
 	userServiceClient     *client.UserServiceClient
 	notificationClient    *client.NotificationServiceClient
 }
 
 type TokenClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID      string      `json:"user_id"`
+	Email       string      `json:"email"`
+	Role        string      `json:"role"`
+	Permissions []string    `json:"permissions,omitempty"`
+	SessionID   string      `json:"sid,omitempty"` // refresh token family the access token was issued for
+	Actor       *ActorClaim `json:"act,omitempty"` // set on impersonation tokens (RFC 8693)
 	jwt.RegisteredClaims
 }
 
+// ActorClaim identifies the admin acting as the token's subject
+type ActorClaim struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+}
+
 func NewAuthService(
 	userRepo repository.UserRepository,
 	roleRepo repository.RoleRepository,
 	tokenRepo repository.TokenRepository,
 	auditRepo repository.AuditLogRepository,
+	mfaRepo repository.MFARepository,
 	passwordResetRepo repository.PasswordResetRepository,
 	emailVerificationRepo repository.EmailVerificationRepository,
+	loginCodeRepo repository.LoginCodeRepository,
+	emailChangeRepo repository.EmailChangeRepository,
 	emailService EmailService,
 	redisClient *redis.Client,
+	keys KeyManager,
 	config *config.Config,
 ) AuthService {
 	// Initialize service clients
@@ -81,10 +150,15 @@ func NewAuthService(
 		roleRepo:              roleRepo,
 		tokenRepo:             tokenRepo,
 		auditRepo:             auditRepo,
+		mfaRepo:               mfaRepo,
 		passwordResetRepo:     passwordResetRepo,
 		emailVerificationRepo: emailVerificationRepo,
+		loginCodeRepo:         loginCodeRepo,
+		emailChangeRepo:       emailChangeRepo,
 		emailService:          emailService,
 		redisClient:           redisClient,
+		keys:                  keys,
+		mfaSecrets:            newSecretBox(config.MFAEncryptionSecret),
 		config:                config,
 		userServiceClient:     userServiceClient,
 		notificationClient:    notificationClient,
@@ -254,10 +328,18 @@ func (s *authService) Login(req *models.LoginRequest, ip, userAgent string) (*mo
 		}, nil
 	}
 
+	account := normalizeAccount(req.Email)
+	var throttled *ThrottledError
+	if err := s.checkThrottle(actionLogin, ip, account); errors.As(err, &throttled) {
+		s.logAudit(nil, "login", "failed", ip, userAgent, "too many failed attempts")
+		return throttledResponse(throttled), nil
+	}
+
 	// Find user
 	user, err := s.userRepo.FindByEmail(req.Email)
 	if err != nil {
 		if err.Error() == "user not found" {
+			s.recordFailure(actionLogin, ip, account, nil, userAgent)
 			s.logAudit(nil, "login", "failed", ip, userAgent, fmt.Sprintf("user not found: %s", req.Email))
 			return &models.AuthResponse{
 				Success: false,
@@ -272,36 +354,30 @@ func (s *authService) Login(req *models.LoginRequest, ip, userAgent string) (*mo
 	}
 
 	// Check if account is locked
-	isLocked, err := s.userRepo.IsAccountLocked(user.ID)
-	if err != nil {
-		s.logAudit(&user.ID, "login", "failed", ip, userAgent, fmt.Sprintf("lock check error: %v", err))
-		return nil, fmt.Errorf("failed to check account lock: %w", err)
-	}
-
-	if isLocked {
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
 		s.logAudit(&user.ID, "login", "failed", ip, userAgent, "account locked")
-		return &models.AuthResponse{
-			Success: false,
-			Error: &models.ErrorData{
-				Code:    "ACCOUNT_LOCKED",
-				Message: "Account is locked due to too many failed login attempts. Please try again later.",
-			},
-		}, nil
+		return lockedResponse(*user.LockedUntil), nil
 	}
 
-    // Verify password (handle OAuth users where password may be nil)
-    if user.Password == nil || bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)) != nil {
-		// Increment failed attempts
-		s.userRepo.IncrementFailedAttempts(user.ID)
+	// Verify password (handle OAuth users where password may be nil)
+	if user.Password == nil || bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)) != nil {
+		s.recordFailure(actionLogin, ip, account, &user.ID, userAgent)
+		s.logAudit(&user.ID, "login", "failed", ip, userAgent, "invalid password")
 
-		// Check if should lock account
-		user, _ := s.userRepo.FindByID(user.ID)
-		if user.FailedLoginAttempts >= s.config.MaxLoginAttempts {
-			lockDuration := time.Duration(s.config.AccountLockDuration) * time.Minute
-			s.userRepo.LockAccount(user.ID, lockDuration)
+		// Lock the account after too many failures, longer each time
+		lockedUntil, err := s.userRepo.RecordFailedLogin(user.ID, s.config.MaxLoginAttempts,
+			time.Duration(s.config.AccountLockDuration)*time.Minute,
+			time.Duration(s.config.AccountLockMaxDuration)*time.Minute)
+		if err != nil {
+			log.Printf("⚠️ Failed to record failed login for user %s: %v", user.ID, err)
+		}
+		if lockedUntil != nil {
+			s.logAudit(&user.ID, "account_locked", "success", ip, userAgent, "too many failed login attempts", map[string]interface{}{
+				"locked_until": *lockedUntil,
+			})
+			return lockedResponse(*lockedUntil), nil
 		}
 
-		s.logAudit(&user.ID, "login", "failed", ip, userAgent, "invalid password")
 		return &models.AuthResponse{
 			Success: false,
 			Error: &models.ErrorData{
@@ -333,6 +409,12 @@ func (s *authService) Login(req *models.LoginRequest, ip, userAgent string) (*mo
 
 	// Reset failed attempts
 	s.userRepo.ResetFailedAttempts(user.ID)
+	s.clearFailures(account, actionLogin)
+
+	// Second factor, when enrolled or required by the role's policy
+	if challenge, err := s.BeginMFAChallenge(user, &roles[0], ip, userAgent); err != nil || challenge != nil {
+		return challenge, err
+	}
 
 	// Update login info
 	s.userRepo.UpdateLoginInfo(user.ID, ip)
@@ -358,6 +440,8 @@ func (s *authService) Login(req *models.LoginRequest, ip, userAgent string) (*mo
 	}, nil
 }
 
+// RefreshToken rotates the refresh token: each one can be used once, and the
+// response carries its replacement in the same session
 func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgent string) (*models.AuthResponse, error) {
 	// Hash the refresh token
 	tokenHash := s.hashToken(req.RefreshToken)
@@ -374,6 +458,11 @@ func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgen
 		}, nil
 	}
 
+	// A token that was already rotated is being replayed
+	if token.RotatedAt != nil {
+		return s.handleRefreshTokenReuse(token, ip, userAgent), nil
+	}
+
 	// Check if token is expired
 	if token.ExpiresAt.Before(time.Now()) {
 		return &models.AuthResponse{
@@ -399,11 +488,19 @@ func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgen
 
 	roleName := roles[0].Name
 
-	// Update last used
-	s.tokenRepo.UpdateLastUsed(token.ID)
+	// Replace the refresh token within the same session
+	refreshTokenStr, next := s.newRefreshToken(user.ID, ip, userAgent)
+	next.FamilyID = token.FamilyID
+	rotated, err := s.tokenRepo.RotateRefreshToken(token.ID, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// A concurrent request with the same token rotated it first
+		return refreshTokenUsed(), nil
+	}
 
-	// Generate new access token
-	accessToken, _, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent)
+	accessToken, expiresIn, err := s.signAccessToken(user.ID, user.Email, roleName, token.FamilyID)
 	if err != nil {
 		return nil, err
 	}
@@ -417,7 +514,7 @@ func (s *authService) RefreshToken(req *models.RefreshTokenRequest, ip, userAgen
 			Email:        user.Email,
 			Role:         roleName,
 			AccessToken:  accessToken,
-			RefreshToken: req.RefreshToken,
+			RefreshToken: refreshTokenStr,
 			ExpiresIn:    expiresIn,
 		},
 	}, nil
@@ -431,7 +528,11 @@ func (s *authService) Logout(userID uuid.UUID, refreshToken string) error {
 		return nil // Token not found, already logged out
 	}
 
-	return s.tokenRepo.RevokeToken(token.ID, userID, "logout")
+	if err := s.tokenRepo.RevokeFamily(token.FamilyID, "logout"); err != nil {
+		return err
+	}
+	s.denySessions([]uuid.UUID{token.FamilyID})
+	return nil
 }
 
 func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswordRequest) error {
@@ -458,8 +559,9 @@ func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswor
 		return fmt.Errorf("failed to update password: %w", err)
 	}
 
-	// Revoke all refresh tokens
-	s.tokenRepo.RevokeAllUserTokens(userID)
+	if req.RevokeSessions == nil || *req.RevokeSessions {
+		s.RevokeAllSessions(userID, "password_changed")
+	}
 
 	s.logAudit(&userID, "change_password", "success", "", "", "")
 
@@ -467,50 +569,45 @@ func (s *authService) ChangePassword(userID uuid.UUID, req *models.ChangePasswor
 }
 
 func (s *authService) ValidateToken(tokenString string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(s.config.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, s.keys.Keyfunc, jwks.ParserOptions()...)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
+		if s.isSessionRevoked(claims.SessionID) {
+			return nil, ErrSessionRevoked
+		}
 		return claims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
 
+func (s *authService) GetJWKS() jwks.Set {
+	return s.keys.JWKS()
+}
+
 // Helper functions
 
 func (s *authService) generateTokens(userID uuid.UUID, email, role, ip, userAgent string) (string, string, int64, error) {
-	// Parse JWT expiry
-	expiryDuration, _ := time.ParseDuration(s.config.JWTExpiry)
-	expiresAt := time.Now().Add(expiryDuration)
-
-	// Create access token
-	claims := TokenClaims{
-		UserID: userID.String(),
-		Email:  email,
-		Role:   role,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-		},
+	// Create refresh token; it starts a new session (token family)
+	refreshTokenStr, refreshToken := s.newRefreshToken(userID, ip, userAgent)
+	if err := s.tokenRepo.CreateRefreshToken(refreshToken); err != nil {
+		return "", "", 0, fmt.Errorf("failed to create refresh token: %w", err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, err := token.SignedString([]byte(s.config.JWTSecret))
+	accessToken, expiresIn, err := s.signAccessToken(userID, email, role, refreshToken.FamilyID)
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to sign token: %w", err)
+		return "", "", 0, err
 	}
 
-	// Create refresh token
+	return accessToken, refreshTokenStr, expiresIn, nil
+}
+
+// newRefreshToken returns a refresh token and the record to store for it
+func (s *authService) newRefreshToken(userID uuid.UUID, ip, userAgent string) (string, *models.RefreshToken) {
 	refreshTokenStr := uuid.New().String()
 	refreshTokenHash := s.hashToken(refreshTokenStr)
 
@@ -529,12 +626,59 @@ func (s *authService) generateTokens(userID uuid.UUID, email, role, ip, userAgen
 	if userAgent != "" {
 		refreshToken.UserAgent = &userAgent
 	}
+	deviceName, deviceType := describeDevice(userAgent)
+	refreshToken.DeviceName = &deviceName
+	refreshToken.DeviceType = &deviceType
 
-	if err := s.tokenRepo.CreateRefreshToken(refreshToken); err != nil {
-		return "", "", 0, fmt.Errorf("failed to create refresh token: %w", err)
+	return refreshTokenStr, refreshToken
+}
+
+// IssueTokens starts a session for a user who completed sign-in through
+// another flow (e.g. Google OAuth)
+func (s *authService) IssueTokens(userID uuid.UUID, email, role, ip, userAgent string) (string, string, int64, error) {
+	return s.generateTokens(userID, email, role, ip, userAgent)
+}
+
+// signAccessToken issues an access token for an existing session
+func (s *authService) signAccessToken(userID uuid.UUID, email, role string, sessionID uuid.UUID) (string, int64, error) {
+	// Parse JWT expiry
+	expiryDuration, _ := time.ParseDuration(s.config.JWTExpiry)
+	return s.signToken(userID, email, role, sessionID, expiryDuration, nil)
+}
+
+// signToken issues an access token valid for ttl; actor is set when an admin
+// acts as the user
+func (s *authService) signToken(userID uuid.UUID, email, role string, sessionID uuid.UUID, ttl time.Duration, actor *ActorClaim) (string, int64, error) {
+	expiresAt := time.Now().Add(ttl)
+
+	// Permissions are embedded so the gateway can enforce route policies
+	// without a lookup; on failure the gateway resolves them by role instead
+	permissions, err := s.roleRepo.FindPermissionNamesByRole(role)
+	if err != nil {
+		log.Printf("⚠️ Failed to load permissions for role %s: %v", role, err)
 	}
 
-	return accessToken, refreshTokenStr, int64(expiryDuration.Seconds()), nil
+	claims := TokenClaims{
+		UserID:      userID.String(),
+		Email:       email,
+		Role:        role,
+		Permissions: permissions,
+		SessionID:   sessionID.String(),
+		Actor:       actor,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	return accessToken, int64(ttl.Seconds()), nil
 }
 
 func (s *authService) hashToken(token string) string {
@@ -542,7 +686,12 @@ func (s *authService) hashToken(token string) string {
 	return hex.EncodeToString(hash[:])
 }
 
-func (s *authService) logAudit(userID *uuid.UUID, eventType, status, ip, userAgent, errorMsg string) {
+func (s *authService) logAudit(userID *uuid.UUID, eventType, status, ip, userAgent, errorMsg string, metadata ...map[string]interface{}) {
+	writeAuditLog(s.auditRepo, userID, eventType, status, ip, userAgent, errorMsg, metadata...)
+}
+
+// writeAuditLog records a security event for services outside authService
+func writeAuditLog(repo repository.AuditLogRepository, userID *uuid.UUID, eventType, status, ip, userAgent, errorMsg string, metadata ...map[string]interface{}) {
 	log := &models.AuditLog{
 		UserID:      userID,
 		EventType:   eventType,
@@ -558,8 +707,48 @@ func (s *authService) logAudit(userID *uuid.UUID, eventType, status, ip, userAge
 	if errorMsg != "" {
 		log.ErrorMessage = &errorMsg
 	}
+	if len(metadata) > 0 {
+		if encoded, err := json.Marshal(metadata[0]); err == nil {
+			metadataStr := string(encoded)
+			log.Metadata = &metadataStr
+		}
+	}
+
+	repo.Create(log)
+}
+
+// GetRolePermissions returns the permission names granted to a role; unknown roles have none
+func (s *authService) GetRolePermissions(role string) ([]string, error) {
+	return s.roleRepo.FindPermissionNamesByRole(role)
+}
+
+// RecordAuditEvent stores an audit entry reported by another service
+func (s *authService) RecordAuditEvent(req *models.AuditEventRequest) error {
+	entry := &models.AuditLog{
+		UserID:      req.UserID,
+		EventType:   req.EventType,
+		EventStatus: req.EventStatus,
+	}
+
+	if req.IPAddress != "" {
+		entry.IPAddress = &req.IPAddress
+	}
+	if req.UserAgent != "" {
+		entry.UserAgent = &req.UserAgent
+	}
+	if req.ErrorMessage != "" {
+		entry.ErrorMessage = &req.ErrorMessage
+	}
+	if len(req.Metadata) > 0 {
+		metadata, err := json.Marshal(req.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		metadataStr := string(metadata)
+		entry.Metadata = &metadataStr
+	}
 
-	s.auditRepo.Create(log)
+	return s.auditRepo.Create(entry)
 }
 
 // Helper function to validate email format
@@ -600,7 +789,7 @@ func (s *authService) ForgotPassword(req *models.ForgotPasswordRequest, ip strin
 	}
 
 	// Send email with 6-digit code
-	if err := s.emailService.SendPasswordResetEmail(user.Email, code); err != nil {
+	if err := s.emailService.SendPasswordResetEmail(user.ID, user.Email, code); err != nil {
 		fmt.Printf("Failed to send email to %s: %v\n", user.Email, err)
 		// Don't fail the request if email fails, but log it
 	} else {
@@ -649,8 +838,10 @@ func (s *authService) ResetPassword(req *models.ResetPasswordRequest, ip string)
 	// Mark token as used
 	s.passwordResetRepo.MarkAsUsed(token.ID)
 
-	// Revoke all refresh tokens for security
-	s.tokenRepo.RevokeAllUserTokens(token.UserID)
+	// Revoke all sessions for security unless the user opted out
+	if req.RevokeSessions == nil || *req.RevokeSessions {
+		s.RevokeAllSessions(token.UserID, "password_reset")
+	}
 
 	s.logAudit(&token.UserID, "reset_password", "success", ip, "", "")
 
@@ -728,7 +919,7 @@ func (s *authService) ResendVerification(email string) error {
 	}
 
 	// Send email with 6-digit code
-	if err := s.emailService.SendVerificationEmail(user.Email, code); err != nil {
+	if err := s.emailService.SendVerificationEmail(user.ID, user.Email, code); err != nil {
 		fmt.Printf("Failed to send email to %s: %v\n", user.Email, err)
 		// Don't fail the request if email fails
 	} else {
@@ -741,11 +932,15 @@ func (s *authService) ResendVerification(email string) error {
 }
 
 // VerifyEmailByCode verifies user email with 6-digit code
-func (s *authService) VerifyEmailByCode(code string) error {
-	// Find token by code
-	verificationToken, err := s.emailVerificationRepo.FindByCode(code)
+func (s *authService) VerifyEmailByCode(req *models.VerifyEmailByCodeRequest, ip, userAgent string) error {
+	account := normalizeAccount(req.Email)
+	if err := s.checkThrottle(actionVerifyEmailCode, ip, account); err != nil {
+		return err
+	}
+
+	verificationToken, err := s.findVerificationCode(req, ip, userAgent)
 	if err != nil {
-		return fmt.Errorf("invalid or expired verification code")
+		return err
 	}
 
 	// Get user
@@ -765,27 +960,62 @@ func (s *authService) VerifyEmailByCode(code string) error {
 
 	// Mark token as verified
 	s.emailVerificationRepo.MarkAsVerified(verificationToken.ID)
+	s.clearFailures(account, actionVerifyEmailCode)
 
-	s.logAudit(&user.ID, "verify_email_by_code", "success", "", "", "")
+	s.logAudit(&user.ID, "verify_email_by_code", "success", ip, userAgent, "")
 
 	return nil
 }
 
+// findVerificationCode checks a verification code against the active code of
+// the account with the email, counting wrong guesses against that code
+func (s *authService) findVerificationCode(req *models.VerifyEmailByCodeRequest, ip, userAgent string) (*models.EmailVerificationToken, error) {
+	invalidCode := fmt.Errorf("invalid or expired verification code")
+	account := normalizeAccount(req.Email)
+
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		s.recordFailure(actionVerifyEmailCode, ip, account, nil, userAgent)
+		return nil, invalidCode
+	}
+	token, err := s.emailVerificationRepo.FindActiveByUserID(user.ID)
+	if err != nil {
+		s.recordFailure(actionVerifyEmailCode, ip, account, &user.ID, userAgent)
+		return nil, invalidCode
+	}
+
+	if !codeMatches(token.Code, req.Code) {
+		s.recordFailure(actionVerifyEmailCode, ip, account, &user.ID, userAgent)
+		s.logAudit(&user.ID, "verify_email_by_code", "failed", ip, userAgent, "invalid code")
+		if invalidated, err := s.emailVerificationRepo.RecordFailedAttempt(token.ID, s.config.CodeMaxAttempts); err != nil {
+			log.Printf("⚠️ Failed to record wrong verification code for user %s: %v", user.ID, err)
+		} else if invalidated {
+			return nil, s.codeInvalidated(actionVerifyEmailCode, user.ID, ip, userAgent)
+		}
+		return nil, invalidCode
+	}
+	return token, nil
+}
+
 // ResetPasswordByCode resets user password with 6-digit code
-func (s *authService) ResetPasswordByCode(code, newPassword, ip string) error {
-	// Find token by code
-	token, err := s.passwordResetRepo.FindByCode(code)
+func (s *authService) ResetPasswordByCode(req *models.ResetPasswordByCodeRequest, ip, userAgent string) error {
+	account := normalizeAccount(req.Email)
+	if err := s.checkThrottle(actionResetPassword, ip, account); err != nil {
+		return err
+	}
+
+	token, err := s.findPasswordResetCode(req, ip, userAgent)
 	if err != nil {
-		return fmt.Errorf("invalid or expired code")
+		return err
 	}
 
 	// Validate password strength
-	if len(newPassword) < 8 {
+	if len(req.NewPassword) < 8 {
 		return fmt.Errorf("password must be at least 8 characters long")
 	}
 
 	// Hash new password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), s.config.BcryptRounds)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), s.config.BcryptRounds)
 	if err != nil {
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -805,10 +1035,45 @@ func (s *authService) ResetPasswordByCode(code, newPassword, ip string) error {
 	// Mark token as used
 	s.passwordResetRepo.MarkAsUsed(token.ID)
 
-	// Revoke all refresh tokens for security
-	s.tokenRepo.RevokeAllUserTokens(token.UserID)
+	// The new password unlocks an account locked by failed sign-ins
+	s.userRepo.ResetFailedAttempts(token.UserID)
+	s.clearFailures(normalizeAccount(user.Email), bruteForceActions...)
+
+	// Revoke all sessions for security unless the user opted out
+	if req.RevokeSessions == nil || *req.RevokeSessions {
+		s.RevokeAllSessions(token.UserID, "password_reset")
+	}
 
-	s.logAudit(&token.UserID, "reset_password_by_code", "success", ip, "", "")
+	s.logAudit(&token.UserID, "reset_password_by_code", "success", ip, userAgent, "")
 
 	return nil
 }
+
+// findPasswordResetCode checks a reset code like findVerificationCode
+func (s *authService) findPasswordResetCode(req *models.ResetPasswordByCodeRequest, ip, userAgent string) (*models.PasswordResetToken, error) {
+	invalidCode := fmt.Errorf("invalid or expired code")
+	account := normalizeAccount(req.Email)
+
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		s.recordFailure(actionResetPassword, ip, account, nil, userAgent)
+		return nil, invalidCode
+	}
+	token, err := s.passwordResetRepo.FindActiveByUserID(user.ID)
+	if err != nil {
+		s.recordFailure(actionResetPassword, ip, account, &user.ID, userAgent)
+		return nil, invalidCode
+	}
+
+	if !codeMatches(token.Code, req.Code) {
+		s.recordFailure(actionResetPassword, ip, account, &user.ID, userAgent)
+		s.logAudit(&user.ID, "reset_password_by_code", "failed", ip, userAgent, "invalid code")
+		if invalidated, err := s.passwordResetRepo.RecordFailedAttempt(token.ID, s.config.CodeMaxAttempts); err != nil {
+			log.Printf("⚠️ Failed to record wrong reset code for user %s: %v", user.ID, err)
+		} else if invalidated {
+			return nil, s.codeInvalidated(actionResetPassword, user.ID, ip, userAgent)
+		}
+		return nil, invalidCode
+	}
+	return token, nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// Actions whose failures are counted separately
+const (
+	actionLogin           = "login"
+	actionVerifyEmailCode = "verify_email_code"
+	actionResetPassword   = "reset_password_code"
+	actionLoginCode       = "login_code"
+	actionEmailChange     = "email_change_code"
+	actionMFA             = "mfa"
+)
+
+// bruteForceActions are the first-factor actions, cleared once the account
+// proves its identity. MFA failures are cleared only by a second factor or an
+// admin unlock, since whoever knows the password can prove the first factor.
+var bruteForceActions = []string{actionLogin, actionVerifyEmailCode, actionResetPassword, actionLoginCode, actionEmailChange}
+
+// After the free attempts, every failure doubles the wait before the next
+// attempt is accepted. Accounts get few free attempts; IPs (shared by NAT,
+// classrooms) get IP_FAILURE_THRESHOLD.
+const (
+	accountFreeAttempts = 3
+	failureWindow       = time.Hour
+	backoffBaseDelay    = time.Second
+	backoffMaxDelay     = 15 * time.Minute
+)
+
+var (
+	ErrCodeAttemptsExceeded = errors.New("too many incorrect codes, please request a new one")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+// ThrottledError rejects an attempt made while its IP or account waits out a backoff
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("too many failed attempts, try again in %d seconds", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds rounds the wait up for a Retry-After header
+func (e *ThrottledError) RetryAfterSeconds() int {
+	return retryAfterSeconds(e.RetryAfter)
+}
+
+// countFailure increments a failure counter within its window and, past the
+// free attempts, sets a backoff marker that expires after the delay.
+// It returns the failure count and the delay in milliseconds.
+var countFailure = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if n == 1 then redis.call('PEXPIRE', KEYS[1], ARGV[1]) end
+local over = n - tonumber(ARGV[2])
+if over <= 0 then return {n, 0} end
+local delay = math.floor(math.min(tonumber(ARGV[3]) * 2 ^ (over - 1), tonumber(ARGV[4])))
+redis.call('SET', KEYS[2], n, 'PX', delay)
+return {n, delay}
+`)
+
+// UnlockAccount lifts a lockout and clears the account's failure counters
+func (s *authService) UnlockAccount(userID, adminID uuid.UUID, ip, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if err := s.userRepo.ResetFailedAttempts(user.ID); err != nil {
+		return err
+	}
+	s.clearFailures(normalizeAccount(user.Email), bruteForceActions...)
+	s.clearFailures(normalizeAccount(user.Email), actionMFA)
+
+	metadata := map[string]interface{}{"unlocked_by": adminID.String()}
+	if user.LockedUntil != nil && user.LockedUntil.After(time.Now()) {
+		metadata["locked_until"] = *user.LockedUntil
+	}
+	s.logAudit(&user.ID, "account_unlocked", "success", ip, userAgent, "", metadata)
+	return nil
+}
+
+// checkThrottle returns a ThrottledError while the IP or the account must wait.
+// An empty ip or account is not checked.
+func (s *authService) checkThrottle(action, ip, account string) error {
+	ctx := context.Background()
+	pipe := s.redisClient.Pipeline()
+	var waits []*redis.DurationCmd
+	for _, scope := range failureScopes(ip, account) {
+		waits = append(waits, pipe.PTTL(ctx, backoffKey(action, scope)))
+	}
+	if len(waits) == 0 {
+		return nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		// Fail open: lockouts in the database still apply
+		log.Printf("⚠️ Failed to check brute-force backoff: %v", err)
+		return nil
+	}
+
+	var longest time.Duration
+	for _, wait := range waits {
+		if d := wait.Val(); d > longest {
+			longest = d
+		}
+	}
+	if longest > 0 {
+		return &ThrottledError{RetryAfter: longest}
+	}
+	return nil
+}
+
+// recordFailure counts a failed attempt against the IP and the account and
+// audits the start of each backoff
+func (s *authService) recordFailure(action, ip, account string, userID *uuid.UUID, userAgent string) {
+	ctx := context.Background()
+	for _, scope := range failureScopes(ip, account) {
+		free := accountFreeAttempts
+		if strings.HasPrefix(scope, "ip:") {
+			free = s.config.IPFailureThreshold
+		}
+
+		res, err := countFailure.Run(ctx, s.redisClient,
+			[]string{failureKey(action, scope), backoffKey(action, scope)},
+			failureWindow.Milliseconds(), free, backoffBaseDelay.Milliseconds(), backoffMaxDelay.Milliseconds(),
+		).Int64Slice()
+		if err != nil {
+			log.Printf("⚠️ Failed to count %s failure: %v", action, err)
+			continue
+		}
+
+		if failures, delay := res[0], time.Duration(res[1])*time.Millisecond; delay > 0 {
+			s.logAudit(userID, "brute_force_backoff", "failed", ip, userAgent, "too many failed attempts", map[string]interface{}{
+				"action":      action,
+				"scope":       scope,
+				"failures":    failures,
+				"retry_after": retryAfterSeconds(delay),
+			})
+		}
+	}
+}
+
+// clearFailures forgets an account's failures once it proves its identity or
+// is unlocked; IP counters are left to expire
+func (s *authService) clearFailures(account string, actions ...string) {
+	if account == "" {
+		return
+	}
+
+	keys := make([]string, 0, 2*len(actions))
+	for _, action := range actions {
+		keys = append(keys, failureKey(action, "account:"+account), backoffKey(action, "account:"+account))
+	}
+	if err := s.redisClient.Del(context.Background(), keys...).Err(); err != nil {
+		log.Printf("⚠️ Failed to clear failed attempts of %s: %v", account, err)
+	}
+}
+
+// codeInvalidated audits a code that expired after too many wrong guesses
+func (s *authService) codeInvalidated(action string, userID uuid.UUID, ip, userAgent string) error {
+	s.logAudit(&userID, "code_invalidated", "failed", ip, userAgent, "too many incorrect codes", map[string]interface{}{
+		"action": action,
+	})
+	return ErrCodeAttemptsExceeded
+}
+
+// lockedResponse tells the client how long the account stays locked
+func lockedResponse(lockedUntil time.Time) *models.AuthResponse {
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "ACCOUNT_LOCKED",
+			Message: "Account is locked due to too many failed login attempts. Please try again later.",
+			Details: map[string]interface{}{
+				"locked_until": lockedUntil,
+				"retry_after":  retryAfterSeconds(time.Until(lockedUntil)),
+			},
+		},
+	}
+}
+
+// throttledResponse is the login counterpart of ThrottledError
+func throttledResponse(err *ThrottledError) *models.AuthResponse {
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "TOO_MANY_ATTEMPTS",
+			Message: "Too many failed attempts. Please wait before trying again.",
+			Details: map[string]interface{}{
+				"retry_after": err.RetryAfterSeconds(),
+			},
+		},
+	}
+}
+
+// codeMatches compares a submitted code in constant time
+func codeMatches(expected *string, code string) bool {
+	return expected != nil && subtle.ConstantTimeCompare([]byte(*expected), []byte(code)) == 1
+}
+
+// normalizeAccount keys failure counters by email regardless of case
+func normalizeAccount(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func failureScopes(ip, account string) []string {
+	var scopes []string
+	if ip != "" {
+		scopes = append(scopes, "ip:"+ip)
+	}
+	if account != "" {
+		scopes = append(scopes, "account:"+account)
+	}
+	return scopes
+}
+
+func failureKey(action, scope string) string {
+	return "auth_failures:" + action + ":" + scope
+}
+
+func backoffKey(action, scope string) string {
+	return "auth_backoff:" + action + ":" + scope
+}
+
+func retryAfterSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,75 @@
+package service
+
+import "strings"
+
+// describeDevice derives a display name such as "Chrome on Windows" and a
+// device type (mobile, tablet, desktop) from a User-Agent header. It only
+// needs to be good enough for users to recognise their own sessions.
+func describeDevice(userAgent string) (name, deviceType string) {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return "Unknown device", "unknown"
+	}
+
+	browser := firstMatch(ua, []namedMatch{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"samsungbrowser", "Samsung Internet"},
+		{"firefox", "Firefox"},
+		{"fxios", "Firefox"},
+		{"crios", "Chrome"},
+		{"chrome", "Chrome"},
+		{"safari", "Safari"},
+		{"okhttp", "Android app"},
+		{"dart", "Mobile app"},
+		{"postman", "Postman"},
+		{"curl", "curl"},
+	})
+	os := firstMatch(ua, []namedMatch{
+		{"iphone", "iOS"},
+		{"ipad", "iPadOS"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	})
+
+	switch {
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet") ||
+		(strings.Contains(ua, "android") && !strings.Contains(ua, "mobile")):
+		deviceType = "tablet"
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "iphone") || strings.Contains(ua, "okhttp"):
+		deviceType = "mobile"
+	case os != "":
+		deviceType = "desktop"
+	default:
+		deviceType = "unknown"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		name = browser + " on " + os
+	case browser != "":
+		name = browser
+	case os != "":
+		name = os + " device"
+	default:
+		name = "Unknown device"
+	}
+	return name, deviceType
+}
+
+type namedMatch struct {
+	substr string
+	name   string
+}
+
+func firstMatch(s string, matches []namedMatch) string {
+	for _, m := range matches {
+		if strings.Contains(s, m.substr) {
+			return m.name
+		}
+	}
+	return ""
+}
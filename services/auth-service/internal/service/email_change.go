@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// emailChangeTTL bounds both the code sent to the new address and the cancel
+// link sent to the old one
+const emailChangeTTL = time.Hour
+
+var (
+	ErrEmailTaken              = errors.New("this email is already used by another account")
+	ErrSameEmail               = errors.New("this is already your email")
+	ErrInvalidEmailChangeCode  = errors.New("invalid or expired code")
+	ErrInvalidEmailChangeToken = errors.New("invalid or expired link")
+)
+
+// RequestEmailChange re-authenticates the user, emails a code to the new
+// address and tells the old address how to cancel. The email is swapped only
+// by ConfirmEmailChange.
+func (s *authService) RequestEmailChange(userID uuid.UUID, req *models.ChangeEmailRequest, ip, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	// Accounts created with a provider set a password through forgot-password first
+	if user.Password == nil {
+		s.logAudit(&userID, "email_change_requested", "failed", ip, userAgent, "invalid password")
+		return ErrInvalidPassword
+	}
+	if err := s.ConfirmIdentity(userID, req.Password, req.Code); err != nil {
+		if errors.Is(err, ErrInvalidPassword) || errors.Is(err, ErrInvalidMFACode) {
+			s.logAudit(&userID, "email_change_requested", "failed", ip, userAgent, err.Error())
+		}
+		return err
+	}
+
+	newEmail := strings.TrimSpace(req.NewEmail)
+	if strings.EqualFold(newEmail, user.Email) {
+		return ErrSameEmail
+	}
+	if existing, err := s.userRepo.FindByEmail(newEmail); err != nil && err.Error() != "user not found" {
+		return err
+	} else if existing != nil {
+		return ErrEmailTaken
+	}
+
+	// A new request replaces any earlier one
+	if err := s.emailChangeRepo.DeleteByUserID(userID); err != nil {
+		log.Printf("⚠️ Failed to delete earlier email changes of user %s: %v", userID, err)
+	}
+
+	code := Generate6DigitCode()
+	cancelToken := uuid.New().String()
+	change := &models.EmailChangeRequest{
+		UserID:          userID,
+		NewEmail:        newEmail,
+		Code:            code,
+		CancelTokenHash: s.hashToken(cancelToken),
+		ExpiresAt:       time.Now().Add(emailChangeTTL),
+	}
+	if err := s.emailChangeRepo.Create(change); err != nil {
+		return err
+	}
+
+	if err := s.emailService.SendVerificationEmail(userID, newEmail, code); err != nil {
+		// Don't fail the request if email fails, but log it
+		log.Printf("⚠️ Failed to send email change code to %s: %v", newEmail, err)
+	}
+	cancelLink := fmt.Sprintf("%s/auth/cancel-email-change?token=%s", s.config.FrontendURL, url.QueryEscape(cancelToken))
+	if err := s.emailService.SendEmailChangeNotice(userID, user.Email, newEmail, cancelLink); err != nil {
+		log.Printf("⚠️ Failed to send email change notice to %s: %v", user.Email, err)
+	}
+
+	s.logAudit(&userID, "email_change_requested", "success", ip, userAgent, "", map[string]interface{}{
+		"new_email": newEmail,
+	})
+	return nil
+}
+
+// ConfirmEmailChange checks the code sent to the new address, swaps the email
+// and signs every session out so tokens carrying the old email stop working
+func (s *authService) ConfirmEmailChange(userID uuid.UUID, code, ip, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	account := normalizeAccount(user.Email)
+	if err := s.checkThrottle(actionEmailChange, ip, account); err != nil {
+		return err
+	}
+
+	change, err := s.emailChangeRepo.FindActiveByUserID(userID)
+	if err != nil {
+		s.recordFailure(actionEmailChange, ip, account, &userID, userAgent)
+		return ErrInvalidEmailChangeCode
+	}
+
+	if !codeMatches(&change.Code, code) {
+		s.recordFailure(actionEmailChange, ip, account, &userID, userAgent)
+		s.logAudit(&userID, "email_change", "failed", ip, userAgent, "invalid code")
+		if invalidated, err := s.emailChangeRepo.RecordFailedAttempt(change.ID, s.config.CodeMaxAttempts); err != nil {
+			log.Printf("⚠️ Failed to record wrong email change code for user %s: %v", userID, err)
+		} else if invalidated {
+			return s.codeInvalidated(actionEmailChange, userID, ip, userAgent)
+		}
+		return ErrInvalidEmailChangeCode
+	}
+
+	if err := s.emailChangeRepo.Confirm(change.ID); err != nil {
+		switch err.Error() {
+		case "email already in use":
+			return ErrEmailTaken
+		case "email change not found or expired":
+			return ErrInvalidEmailChangeCode
+		}
+		return err
+	}
+
+	s.clearFailures(account, actionEmailChange)
+	s.RevokeAllSessions(userID, "email_changed")
+
+	s.logAudit(&userID, "email_change", "success", ip, userAgent, "", map[string]interface{}{
+		"old_email": user.Email,
+		"new_email": change.NewEmail,
+	})
+	return nil
+}
+
+// CancelEmailChange stops a pending change from the link sent to the old
+// address. Tokens are unguessable, so a wrong one is only counted against the IP.
+func (s *authService) CancelEmailChange(token, ip, userAgent string) error {
+	if err := s.checkThrottle(actionEmailChange, ip, ""); err != nil {
+		return err
+	}
+
+	change, err := s.emailChangeRepo.FindByCancelTokenHash(s.hashToken(token))
+	if err != nil {
+		s.recordFailure(actionEmailChange, ip, "", nil, userAgent)
+		return ErrInvalidEmailChangeToken
+	}
+	if err := s.emailChangeRepo.Cancel(change.ID); err != nil {
+		return ErrInvalidEmailChangeToken
+	}
+
+	s.logAudit(&change.UserID, "email_change_cancelled", "success", ip, userAgent, "", map[string]interface{}{
+		"new_email": change.NewEmail,
+	})
+	return nil
+}
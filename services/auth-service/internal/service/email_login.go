@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// loginCodeTTL matches the lifetime of password reset codes
+const loginCodeTTL = 15 * time.Minute
+
+var ErrInvalidLoginCode = errors.New("invalid or expired code")
+
+// RequestEmailLoginCode emails a single-use sign-in code and magic link. Like
+// ForgotPassword it does not reveal whether the email has an account.
+func (s *authService) RequestEmailLoginCode(req *models.EmailLoginCodeRequest, ip, userAgent string) error {
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	// A new code replaces any earlier one
+	if err := s.loginCodeRepo.DeleteByUserID(user.ID); err != nil {
+		log.Printf("⚠️ Failed to delete earlier login codes of user %s: %v", user.ID, err)
+	}
+
+	code := Generate6DigitCode()
+	tokenStr := uuid.New().String()
+	loginCode := &models.LoginCode{
+		UserID:    user.ID,
+		TokenHash: s.hashToken(tokenStr),
+		Code:      code,
+		ExpiresAt: time.Now().Add(loginCodeTTL),
+	}
+	if err := s.loginCodeRepo.Create(loginCode); err != nil {
+		return err
+	}
+
+	magicLink := fmt.Sprintf("%s/auth/magic-link?token=%s", s.config.FrontendURL, url.QueryEscape(tokenStr))
+	if err := s.emailService.SendLoginCodeEmail(user.ID, user.Email, code, magicLink); err != nil {
+		// Don't fail the request if email fails, but log it
+		log.Printf("⚠️ Failed to send login code to %s: %v", user.Email, err)
+	}
+
+	s.logAudit(&user.ID, "login_code_requested", "success", ip, userAgent, "")
+	return nil
+}
+
+// LoginWithEmailCode exchanges an emailed code or magic link token for the
+// same response as a password login, including the second-factor step
+func (s *authService) LoginWithEmailCode(req *models.VerifyEmailLoginCodeRequest, ip, userAgent string) (*models.AuthResponse, error) {
+	account := normalizeAccount(req.Email)
+	if err := s.checkThrottle(actionLoginCode, ip, account); err != nil {
+		return nil, err
+	}
+
+	loginCode, err := s.findLoginCode(req, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	// Consuming the code fails if a concurrent request already used it
+	if err := s.loginCodeRepo.MarkAsUsed(loginCode.ID); err != nil {
+		return nil, ErrInvalidLoginCode
+	}
+
+	user, err := s.userRepo.FindByID(loginCode.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	if !user.IsActive {
+		s.logAudit(&user.ID, "login_code", "failed", ip, userAgent, "account inactive")
+		return &models.AuthResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "ACCOUNT_INACTIVE",
+				Message: "Account is inactive",
+			},
+		}, nil
+	}
+
+	// Receiving the code proves the email address, and like a password reset
+	// it lifts a lockout caused by wrong passwords
+	if !user.IsVerified {
+		now := time.Now()
+		user.IsVerified = true
+		user.EmailVerifiedAt = &now
+		if err := s.userRepo.Update(user); err != nil {
+			log.Printf("⚠️ Failed to mark email of user %s as verified: %v", user.ID, err)
+		}
+	}
+	s.userRepo.ResetFailedAttempts(user.ID)
+	s.clearFailures(normalizeAccount(user.Email), bruteForceActions...)
+
+	roles, err := s.roleRepo.FindByUserID(user.ID)
+	if err != nil || len(roles) == 0 {
+		return nil, fmt.Errorf("failed to find user roles: %w", err)
+	}
+
+	// Second factor, when enrolled or required by the role's policy
+	if challenge, err := s.BeginMFAChallenge(user, &roles[0], ip, userAgent); err != nil || challenge != nil {
+		return challenge, err
+	}
+
+	s.userRepo.UpdateLoginInfo(user.ID, ip)
+
+	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, user.Email, roles[0].Name, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	method := "code"
+	if req.Token != "" {
+		method = "magic_link"
+	}
+	s.logAudit(&user.ID, "login_code", "success", ip, userAgent, "", map[string]interface{}{
+		"method": method,
+	})
+
+	return &models.AuthResponse{
+		Success: true,
+		Data: &models.AuthData{
+			UserID:       user.ID.String(),
+			Email:        user.Email,
+			Role:         roles[0].Name,
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    expiresIn,
+		},
+	}, nil
+}
+
+// findLoginCode checks a magic link token, or a code like findPasswordResetCode.
+// Tokens are unguessable, so a wrong one is only counted against the IP.
+func (s *authService) findLoginCode(req *models.VerifyEmailLoginCodeRequest, ip, userAgent string) (*models.LoginCode, error) {
+	if req.Token != "" {
+		loginCode, err := s.loginCodeRepo.FindByTokenHash(s.hashToken(req.Token))
+		if err != nil {
+			s.recordFailure(actionLoginCode, ip, "", nil, userAgent)
+			return nil, ErrInvalidLoginCode
+		}
+		return loginCode, nil
+	}
+
+	account := normalizeAccount(req.Email)
+	user, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		s.recordFailure(actionLoginCode, ip, account, nil, userAgent)
+		return nil, ErrInvalidLoginCode
+	}
+	loginCode, err := s.loginCodeRepo.FindActiveByUserID(user.ID)
+	if err != nil {
+		s.recordFailure(actionLoginCode, ip, account, &user.ID, userAgent)
+		return nil, ErrInvalidLoginCode
+	}
+
+	if !codeMatches(&loginCode.Code, req.Code) {
+		s.recordFailure(actionLoginCode, ip, account, &user.ID, userAgent)
+		s.logAudit(&user.ID, "login_code", "failed", ip, userAgent, "invalid code")
+		if invalidated, err := s.loginCodeRepo.RecordFailedAttempt(loginCode.ID, s.config.CodeMaxAttempts); err != nil {
+			log.Printf("⚠️ Failed to record wrong login code for user %s: %v", user.ID, err)
+		} else if invalidated {
+			return nil, s.codeInvalidated(actionLoginCode, user.ID, ip, userAgent)
+		}
+		return nil, ErrInvalidLoginCode
+	}
+	return loginCode, nil
+}
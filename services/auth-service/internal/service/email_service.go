@@ -4,135 +4,66 @@ import (
 	"crypto/rand"
 	"fmt"
 	"math/big"
-	"net/smtp"
-)
 
-// ---- Brand palette (aligned to the logo) ----
-const (
-	BrandRed      = "#E53935"
-	TextDark      = "#111827"
-	SoftBg        = "#FFF7F5"
-	BorderSoftRed = "#FAD8D6"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/google/uuid"
 )
 
+// EmailService sends the auth emails. Each one is queued in
+// notification-service's outbox, which renders it from its templates and
+// delivers it with retries, so a slow mail server does not hold up sign-up or
+// sign-in.
 type EmailService interface {
-	SendPasswordResetEmail(toEmail, resetCode string) error
-	SendVerificationEmail(toEmail, verificationCode string) error
+	SendPasswordResetEmail(userID uuid.UUID, toEmail, resetCode string) error
+	SendVerificationEmail(userID uuid.UUID, toEmail, verificationCode string) error
+	SendLoginCodeEmail(userID uuid.UUID, toEmail, loginCode, magicLink string) error
+	SendEmailChangeNotice(userID uuid.UUID, toEmail, newEmail, cancelLink string) error
 }
 
 type emailService struct {
-	smtpHost     string
-	smtpPort     string
-	smtpUsername string
-	smtpPassword string
-	fromEmail    string
-	fromName     string
+	notificationClient *client.NotificationServiceClient
 }
 
-func NewEmailService(host, port, username, password, fromEmail, fromName string) EmailService {
+func NewEmailService(notificationClient *client.NotificationServiceClient) EmailService {
 	return &emailService{
-		smtpHost:     host,
-		smtpPort:     port,
-		smtpUsername: username,
-		smtpPassword: password,
-		fromEmail:    fromEmail,
-		fromName:     fromName,
+		notificationClient: notificationClient,
 	}
 }
 
-// ---------- Shared minimal template ----------
-func minimalTemplate(title, headline, introHTML, code, noteHTML string) string {
-	return fmt.Sprintf(`<!DOCTYPE html>
-<html lang="vi">
-<head><meta charset="UTF-8"></head>
-<body style="margin:0;padding:24px;background:%s;font-family:Arial,Helvetica,sans-serif;">
-  <table role="presentation" width="100%%" cellspacing="0" cellpadding="0" style="max-width:600px;margin:0 auto;background:#FFFFFF;border-radius:10px;border:1px solid %s;">
-    <tr>
-      <td style="padding:24px;border-bottom:1px solid %s;">
-        <div style="font-size:22px;line-height:1.2;color:%s;font-weight:700;letter-spacing:-0.3px">
-          <span>IELTS</span><span style="color:%s">Go</span>
-        </div>
-        <div style="margin-top:6px;font-size:14px;color:#6B7280">%s</div>
-      </td>
-    </tr>
-    <tr>
-      <td style="padding:24px 24px 8px 24px;">
-        <h1 style="margin:0 0 12px 0;font-size:18px;color:%s">%s</h1>
-        <div style="font-size:14px;color:#374151;line-height:1.7">%s</div>
-        <div style="margin:20px 0;padding:18px;border:1px solid %s;border-radius:8px;background:%s;text-align:center">
-          <div style="font-family:Consolas,Menlo,monospace;font-size:28px;letter-spacing:6px;font-weight:700;color:%s">%s</div>
-        </div>
-        <div style="font-size:12px;color:#6B7280;line-height:1.8">%s</div>
-      </td>
-    </tr>
-    <tr>
-      <td style="padding:20px 24px 24px 24px;color:#9CA3AF;font-size:12px;border-top:1px solid %s;">
-        © 2025 IELTS<span style="color:%s">Go</span>. All rights reserved.
-      </td>
-    </tr>
-  </table>
-</body>
-</html>`, SoftBg, BorderSoftRed, BorderSoftRed, TextDark, BrandRed, title, TextDark, headline, introHTML, BorderSoftRed, SoftBg, BrandRed, code, noteHTML, BorderSoftRed, BrandRed)
+func (s *emailService) SendPasswordResetEmail(userID uuid.UUID, toEmail, resetCode string) error {
+	return s.queue(userID, toEmail, "password_reset", map[string]string{
+		"code": resetCode,
+	})
 }
 
-// ---- Password reset (vi) – simple, elegant ----
-func (s *emailService) SendPasswordResetEmail(toEmail, resetCode string) error {
-	subject := "IELTSGo – Mã đặt lại mật khẩu"
-	intro := `Chúng tôi nhận được yêu cầu đặt lại mật khẩu cho tài khoản <strong>IELTSGo</strong> của bạn. 
-Vui lòng nhập mã dưới đây để tiếp tục.`
-	note := `Mã có hiệu lực trong <strong>15 phút</strong>. 
-Không chia sẻ mã này với bất kỳ ai. Nếu bạn không yêu cầu thao tác này, hãy bỏ qua email.`
-	body := minimalTemplate(
-		"Đặt lại mật khẩu",
-		"Mã xác thực",
-		intro,
-		resetCode,
-		note,
-	)
-	return s.sendEmail(toEmail, subject, body)
+func (s *emailService) SendVerificationEmail(userID uuid.UUID, toEmail, verificationCode string) error {
+	return s.queue(userID, toEmail, "email_verification", map[string]string{
+		"code": verificationCode,
+	})
 }
 
-// ---- Email verification (en) – simple, elegant ----
-func (s *emailService) SendVerificationEmail(toEmail, verificationCode string) error {
-	subject := "IELTSGo – Verify your email"
-	intro := `Thanks for signing up for <strong>IELTSGo</strong>. 
-Please verify your email using the code below.`
-	note := `This code expires in <strong>24 hours</strong>. 
-If you didn’t create this account, you can safely ignore this email.`
-	body := minimalTemplate(
-		"Email verification",
-		"Your verification code",
-		intro,
-		verificationCode,
-		note,
-	)
-	return s.sendEmail(toEmail, subject, body)
+func (s *emailService) SendLoginCodeEmail(userID uuid.UUID, toEmail, loginCode, magicLink string) error {
+	return s.queue(userID, toEmail, "login_code", map[string]string{
+		"code": loginCode,
+		"link": magicLink,
+	})
 }
 
-// ---- Core send ----
-func (s *emailService) sendEmail(to, subject, body string) error {
-	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
-	from := fmt.Sprintf("%s <%s>", s.fromName, s.fromEmail)
-
-	headers := map[string]string{
-		"From":         from,
-		"To":           to,
-		"Subject":      subject,
-		"MIME-Version": "1.0",
-		"Content-Type": `text/html; charset="UTF-8"`,
-	}
-
-	msg := ""
-	for k, v := range headers {
-		msg += fmt.Sprintf("%s: %s\r\n", k, v)
-	}
-	msg += "\r\n" + body
+// SendEmailChangeNotice tells the old address about a pending change, with a link to cancel it
+func (s *emailService) SendEmailChangeNotice(userID uuid.UUID, toEmail, newEmail, cancelLink string) error {
+	return s.queue(userID, toEmail, "email_change_notice", map[string]string{
+		"new_email": newEmail,
+		"link":      cancelLink,
+	})
+}
 
-	addr := fmt.Sprintf("%s:%s", s.smtpHost, s.smtpPort)
-	if err := smtp.SendMail(addr, auth, s.fromEmail, []string{to}, []byte(msg)); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-	return nil
+func (s *emailService) queue(userID uuid.UUID, to, template string, data map[string]string) error {
+	return s.notificationClient.SendEmail(client.SendEmailRequest{
+		UserID:   userID.String(),
+		To:       to,
+		Template: template,
+		Data:     data,
+	})
 }
 
 // ---- Secure 6-digit code ----
@@ -0,0 +1,388 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxRotationCheckInterval is how often replicas look for due rotations and
+	// reload keys; shorter rotation intervals are checked more often
+	maxRotationCheckInterval = time.Hour
+	// minRotationInterval is the shortest JWT_KEY_ROTATION_INTERVAL accepted, so
+	// the next key is published well before verifiers' JWKS caches expire
+	minRotationInterval = time.Hour
+	// maxPrepublish is how long before activation the next key appears in the JWKS
+	maxPrepublish = 24 * time.Hour
+	// clockSkew is added to a retired key's verification window
+	clockSkew = 5 * time.Minute
+	// minReloadInterval bounds reloads triggered by tokens with an unknown kid
+	minReloadInterval = 15 * time.Second
+)
+
+// KeyManager signs access tokens with rotating asymmetric keys and publishes
+// their public halves as a JWKS
+type KeyManager interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (interface{}, error)
+	JWKS() jwks.Set
+	Start(ctx context.Context)
+}
+
+type signingKey struct {
+	kid          string
+	alg          string
+	private      crypto.Signer // nil when the key cannot be decrypted
+	public       crypto.PublicKey
+	notBefore    time.Time
+	signingUntil time.Time
+}
+
+type keyManager struct {
+	repo       repository.SigningKeyRepository
+	alg        string
+	interval   time.Duration
+	checkEvery time.Duration
+	tokenTTL   time.Duration
+	encryption *secretBox
+
+	mu         sync.RWMutex
+	keys       []*signingKey // ordered by notBefore
+	set        jwks.Set
+	lastReload time.Time
+}
+
+// NewKeyManager loads the signing keys, creating the first one if needed
+func NewKeyManager(repo repository.SigningKeyRepository, cfg *config.Config) (KeyManager, error) {
+	if cfg.JWTSigningAlgorithm != jwks.AlgRS256 && cfg.JWTSigningAlgorithm != jwks.AlgEdDSA {
+		return nil, fmt.Errorf("JWT_SIGNING_ALGORITHM must be RS256 or EdDSA, got %q", cfg.JWTSigningAlgorithm)
+	}
+
+	interval, err := time.ParseDuration(cfg.JWTKeyRotationInterval)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid JWT_KEY_ROTATION_INTERVAL %q", cfg.JWTKeyRotationInterval)
+	}
+	if interval < minRotationInterval {
+		return nil, fmt.Errorf("JWT_KEY_ROTATION_INTERVAL must be at least %s, got %s", minRotationInterval, interval)
+	}
+	tokenTTL, _ := time.ParseDuration(cfg.JWTExpiry)
+
+	m := &keyManager{
+		repo:       repo,
+		alg:        cfg.JWTSigningAlgorithm,
+		interval:   interval,
+		checkEvery: rotationCheckInterval(interval),
+		tokenTTL:   tokenTTL,
+		encryption: newSecretBox(cfg.JWTKeyEncryptionSecret),
+	}
+
+	if err := m.rotate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Start rotates keys on schedule until ctx is cancelled
+func (m *keyManager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(m.checkEvery)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.rotate(); err != nil {
+					log.Printf("❌ Signing key rotation failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Sign issues a token signed by the current key, with its kid in the header
+func (m *keyManager) Sign(claims jwt.Claims) (string, error) {
+	key := m.current(time.Now())
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	method := jwt.SigningMethod(jwt.SigningMethodRS256)
+	if key.alg == jwks.AlgEdDSA {
+		method = jwt.SigningMethodEdDSA
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the public key for a token issued by any replica
+func (m *keyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	key := m.find(kid)
+	if key == nil && m.reloadAllowed() {
+		// Another replica may have rotated before our next scheduled check
+		if err := m.reload(); err != nil {
+			log.Printf("⚠️ Failed to reload signing keys: %v", err)
+		}
+		key = m.find(kid)
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: %s", jwks.ErrUnknownKey, kid)
+	}
+
+	if alg := token.Method.Alg(); alg != key.alg {
+		return nil, fmt.Errorf("token algorithm %s does not match key %s (%s)", alg, kid, key.alg)
+	}
+	return key.public, nil
+}
+
+// JWKS returns every key that may still verify tokens, including the next key
+func (m *keyManager) JWKS() jwks.Set {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.set
+}
+
+// rotate creates the first key, or the next key once the current one is
+// within the prepublish window, then reloads the key set. The next key starts
+// signing exactly when the current one stops, and the retired key stays
+// published until every token it signed has expired.
+func (m *keyManager) rotate() error {
+	return m.repo.WithRotationLock(func() error {
+		now := time.Now()
+
+		if err := m.repo.DeleteExpired(now); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+
+		keys, err := m.repo.FindValid(now)
+		if err != nil {
+			return err
+		}
+
+		if notBefore, due := m.keyDue(keys, now); due {
+			created, err := m.create(notBefore)
+			if err != nil {
+				return err
+			}
+			log.Printf("🔑 Published signing key %s, active from %s", created.Kid, created.NotBefore.Format(time.RFC3339))
+		}
+
+		return m.reload()
+	})
+}
+
+// rotationCheckInterval is how often to check for a due rotation. Checking at
+// least four times per rotation interval means a check always falls inside
+// the prepublish window, which is half the interval.
+func rotationCheckInterval(interval time.Duration) time.Duration {
+	return min(maxRotationCheckInterval, interval/4)
+}
+
+// keyDue reports whether a key has to be created and when it starts signing:
+// now when there is neither a current nor a next key, or when the current one
+// stops once it is within the prepublish window without a successor
+func (m *keyManager) keyDue(keys []models.SigningKey, now time.Time) (time.Time, bool) {
+	var current, next *models.SigningKey
+	for i := range keys {
+		switch k := &keys[i]; {
+		case !k.NotBefore.After(now) && k.SigningUntil.After(now):
+			current = k
+		case k.NotBefore.After(now):
+			next = k
+		}
+	}
+
+	prepublish := min(maxPrepublish, m.interval/2)
+	switch {
+	case current == nil && next == nil:
+		return now, true
+	case current != nil && next == nil && current.SigningUntil.Sub(now) <= prepublish:
+		return current.SigningUntil, true
+	}
+	return time.Time{}, false
+}
+
+// create generates and stores a key that starts signing at notBefore
+func (m *keyManager) create(notBefore time.Time) (*models.SigningKey, error) {
+	var private crypto.Signer
+	switch m.alg {
+	case jwks.AlgEdDSA:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		private = priv
+	default:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		private = priv
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(private.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	sealed, err := m.encryption.seal(privateDER)
+	if err != nil {
+		return nil, err
+	}
+
+	signingUntil := notBefore.Add(m.interval)
+	key := &models.SigningKey{
+		Kid:          uuid.New().String(),
+		Algorithm:    m.alg,
+		PrivateKey:   sealed,
+		PublicKey:    string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})),
+		NotBefore:    notBefore,
+		SigningUntil: signingUntil,
+		VerifyUntil:  signingUntil.Add(m.tokenTTL + clockSkew),
+	}
+
+	if err := m.repo.Create(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// reload replaces the in-memory keys with those stored in the database
+func (m *keyManager) reload() error {
+	rows, err := m.repo.FindValid(time.Now())
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(rows))
+	set := jwks.Set{Keys: make([]jwks.Key, 0, len(rows))}
+
+	for _, row := range rows {
+		key, err := m.decode(row)
+		if err != nil {
+			log.Printf("⚠️ Skipping signing key %s: %v", row.Kid, err)
+			continue
+		}
+		jwk, err := jwks.NewKey(key.kid, key.public)
+		if err != nil {
+			log.Printf("⚠️ Skipping signing key %s: %v", row.Kid, err)
+			continue
+		}
+		keys = append(keys, key)
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.set = set
+	m.lastReload = time.Now()
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *keyManager) decode(row models.SigningKey) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(row.PublicKey))
+	if block == nil {
+		return nil, errors.New("invalid public key PEM")
+	}
+	public, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	key := &signingKey{
+		kid:          row.Kid,
+		alg:          row.Algorithm,
+		public:       public,
+		notBefore:    row.NotBefore,
+		signingUntil: row.SigningUntil,
+	}
+
+	// A key that cannot be decrypted (e.g. after changing the encryption
+	// secret) still verifies tokens; it just never signs
+	if private, err := m.decrypt(row.PrivateKey); err != nil {
+		log.Printf("⚠️ Signing key %s cannot sign: %v", row.Kid, err)
+	} else {
+		key.private = private
+	}
+
+	return key, nil
+}
+
+func (m *keyManager) decrypt(encoded string) (crypto.Signer, error) {
+	der, err := m.encryption.open(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	signer, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+	return signer, nil
+}
+
+// current returns the newest key that is allowed to sign at now
+func (m *keyManager) current(now time.Time) *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		k := m.keys[i]
+		if k.private != nil && !k.notBefore.After(now) && k.signingUntil.After(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+func (m *keyManager) find(kid string) *signingKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, k := range m.keys {
+		if k.kid == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+func (m *keyManager) reloadAllowed() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return time.Since(m.lastReload) >= minReloadInterval
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
+)
+
+// TestNewKeyManagerRotationInterval tests rejecting rotation intervals too
+// short for the next key to be published in time
+func TestNewKeyManagerRotationInterval(t *testing.T) {
+	for _, interval := range []string{"soon", "0s", "-1h", "30m", "59m"} {
+		_, err := NewKeyManager(nil, &config.Config{
+			JWTSigningAlgorithm:    jwks.AlgRS256,
+			JWTKeyRotationInterval: interval,
+		})
+		if err == nil {
+			t.Errorf("NewKeyManager() with interval %s succeeded, expected an error", interval)
+		}
+	}
+}
+
+// TestRotationCheckInterval tests that short rotation intervals are checked
+// more often
+func TestRotationCheckInterval(t *testing.T) {
+	tests := []struct {
+		interval time.Duration
+		expected time.Duration
+	}{
+		{time.Hour, 15 * time.Minute},
+		{2 * time.Hour, 30 * time.Minute},
+		{4 * time.Hour, time.Hour},
+		{720 * time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := rotationCheckInterval(tt.interval); got != tt.expected {
+			t.Errorf("rotationCheckInterval(%s) = %s, expected %s", tt.interval, got, tt.expected)
+		}
+	}
+}
+
+// TestKeyDue tests when a key is created and when it starts signing
+func TestKeyDue(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := &keyManager{interval: 720 * time.Hour}
+	key := func(from, until time.Duration) models.SigningKey {
+		return models.SigningKey{NotBefore: now.Add(from), SigningUntil: now.Add(until)}
+	}
+
+	tests := []struct {
+		name      string
+		keys      []models.SigningKey
+		due       bool
+		notBefore time.Time
+	}{
+		{"no keys", nil, true, now},
+		{"only retired keys", []models.SigningKey{key(-800*time.Hour, -80*time.Hour)}, true, now},
+		{"current key far from expiry", []models.SigningKey{key(-time.Hour, 719*time.Hour)}, false, time.Time{}},
+		{"current key just outside the prepublish window", []models.SigningKey{key(-695*time.Hour, 25*time.Hour)}, false, time.Time{}},
+		{"current key inside the prepublish window", []models.SigningKey{key(-697*time.Hour, 23*time.Hour)}, true, now.Add(23 * time.Hour)},
+		{"next key already published", []models.SigningKey{key(-697*time.Hour, 23*time.Hour), key(23*time.Hour, 743*time.Hour)}, false, time.Time{}},
+		{"only a next key", []models.SigningKey{key(time.Hour, 721*time.Hour)}, false, time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notBefore, due := m.keyDue(tt.keys, now)
+			if due != tt.due || !notBefore.Equal(tt.notBefore) {
+				t.Errorf("keyDue() = %s, %v; expected %s, %v", notBefore, due, tt.notBefore, tt.due)
+			}
+		})
+	}
+}
+
+// TestRotationSchedule simulates scheduled checks and tests that every key's
+// successor is published before verifiers' JWKS caches would miss it
+func TestRotationSchedule(t *testing.T) {
+	// jwks.KeySet trusts a fetched key set for 10 minutes
+	const jwksCacheTTL = 10 * time.Minute
+
+	for _, interval := range []time.Duration{time.Hour, 90 * time.Minute, 2 * time.Hour, 5 * time.Hour, 720 * time.Hour} {
+		t.Run(interval.String(), func(t *testing.T) {
+			m := &keyManager{interval: interval}
+			checkEvery := rotationCheckInterval(interval)
+
+			start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			var keys []models.SigningKey
+			published := map[time.Time]time.Time{} // notBefore -> when the key was created
+
+			// Checks drift from the key boundaries; the first is at startup
+			for now := start; now.Before(start.Add(5 * interval)); now = now.Add(checkEvery) {
+				if notBefore, due := m.keyDue(keys, now); due {
+					keys = append(keys, models.SigningKey{NotBefore: notBefore, SigningUntil: notBefore.Add(interval)})
+					published[notBefore] = now
+				}
+				now = now.Add(7 * time.Second)
+			}
+
+			for i, k := range keys[:len(keys)-1] {
+				next := keys[i+1]
+				if !next.NotBefore.Equal(k.SigningUntil) {
+					t.Fatalf("key %d stops at %s but the next starts at %s", i, k.SigningUntil, next.NotBefore)
+				}
+				if lead := k.SigningUntil.Sub(published[next.NotBefore]); lead < jwksCacheTTL {
+					t.Errorf("key %d was published %s before it took over, expected at least %s", i+1, lead, jwksCacheTTL)
+				}
+			}
+			if len(keys) < 5 {
+				t.Errorf("%d keys over 5 intervals, expected at least 5", len(keys))
+			}
+		})
+	}
+}
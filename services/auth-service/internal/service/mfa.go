@@ -0,0 +1,610 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	mfaChallengePrefix  = "mfa_challenge:"
+	mfaMaxAttempts      = 5  // wrong codes before a login challenge is discarded
+	recoveryCodeCount   = 10 // codes issued per enrolment or regeneration
+	recoveryCodeEntropy = 7  // random bytes per code, formatted as xxxxx-xxxxx
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotPending     = errors.New("no two-factor enrolment in progress")
+	ErrInvalidMFACode    = errors.New("invalid verification code")
+	ErrMFARequiredByRole = errors.New("two-factor authentication is required for your role")
+	ErrInvalidPassword   = errors.New("invalid password")
+	ErrMFAChallenge      = errors.New("login session expired, please sign in again")
+	ErrRoleNotFound      = errors.New("role not found")
+)
+
+// countMFAAttempt increments a challenge's failed attempts without recreating
+// a challenge that expired in the meantime; it returns -1 for a missing challenge
+var countMFAAttempt = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return -1 end
+return redis.call('HINCRBY', KEYS[1], 'attempts', 1)
+`)
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// GetMFAStatus reports whether the user has two-factor authentication enabled
+func (s *authService) GetMFAStatus(userID uuid.UUID) (*models.MFAStatus, error) {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.MFAStatus{}
+	if mfa != nil && mfa.EnabledAt != nil {
+		status.Enabled = true
+		status.EnabledAt = mfa.EnabledAt
+		if status.RecoveryCodesRemaining, err = s.mfaRepo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles {
+		status.RequiredByRole = status.RequiredByRole || role.MFARequired
+	}
+
+	return status, nil
+}
+
+// EnrollMFA starts enrolment with a new secret; it takes effect once confirmed with a code
+func (s *authService) EnrollMFA(userID uuid.UUID, ip, userAgent string) (*models.MFAEnrollmentData, error) {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa != nil && mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.startMFAEnrollment(user)
+	if err != nil {
+		s.logAudit(&userID, "mfa_enroll", "failed", ip, userAgent, err.Error())
+		return nil, err
+	}
+
+	s.logAudit(&userID, "mfa_enroll", "success", ip, userAgent, "")
+	return enrollment, nil
+}
+
+// ConfirmMFA enables two-factor authentication and returns the recovery codes, shown only once
+func (s *authService) ConfirmMFA(userID uuid.UUID, code, ip, userAgent string) ([]string, error) {
+	recoveryCodes, err := s.confirmMFAEnrollment(userID, code)
+	if err != nil {
+		s.logAudit(&userID, "mfa_enable", "failed", ip, userAgent, err.Error())
+		return nil, err
+	}
+
+	s.logAudit(&userID, "mfa_enable", "success", ip, userAgent, "")
+	return recoveryCodes, nil
+}
+
+// DisableMFA removes the enrolment after checking the password and a current code
+func (s *authService) DisableMFA(userID uuid.UUID, req *models.MFADisableRequest, ip, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	// OAuth-only accounts have no password; the code alone proves possession
+	if user.Password != nil && bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(req.Password)) != nil {
+		s.logAudit(&userID, "mfa_disable", "failed", ip, userAgent, "invalid password")
+		return ErrInvalidPassword
+	}
+
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if role.MFARequired {
+			s.logAudit(&userID, "mfa_disable", "failed", ip, userAgent, "required by role policy")
+			return ErrMFARequiredByRole
+		}
+	}
+
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		return ErrMFANotEnabled
+	}
+
+	ok, err := s.checkSecondFactor(mfa, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		s.logAudit(&userID, "mfa_disable", "failed", ip, userAgent, "invalid code")
+		return ErrInvalidMFACode
+	}
+
+	if err := s.mfaRepo.Delete(userID); err != nil {
+		return err
+	}
+
+	s.logAudit(&userID, "mfa_disable", "success", ip, userAgent, "")
+	return nil
+}
+
+// ConfirmIdentity re-authenticates a signed-in user before a sensitive action:
+// the password if the account has one, and the second factor if it is enabled
+func (s *authService) ConfirmIdentity(userID uuid.UUID, password, code string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Password != nil && bcrypt.CompareHashAndPassword([]byte(*user.Password), []byte(password)) != nil {
+		return ErrInvalidPassword
+	}
+
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		return nil
+	}
+
+	ok, err := s.checkSecondFactor(mfa, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code after checking a current TOTP code
+func (s *authService) RegenerateRecoveryCodes(userID uuid.UUID, code, ip, userAgent string) ([]string, error) {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || mfa.EnabledAt == nil {
+		return nil, ErrMFANotEnabled
+	}
+
+	ok, err := s.checkTOTP(mfa, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		s.logAudit(&userID, "mfa_recovery_codes", "failed", ip, userAgent, "invalid code")
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(&userID, "mfa_recovery_codes", "success", ip, userAgent, "")
+	return codes, nil
+}
+
+// BeginMFAChallenge is called once the first factor has succeeded. It returns
+// nil when the user needs no second factor; otherwise it returns a response
+// carrying a short-lived MFA token instead of access and refresh tokens. Users
+// whose role requires MFA but who have not enrolled get a new secret to enrol
+// with, and complete enrolment and login together at /auth/mfa/verify.
+func (s *authService) BeginMFAChallenge(user *models.User, role *models.Role, ip, userAgent string) (*models.AuthResponse, error) {
+	mfa, err := s.mfaRepo.FindByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled := mfa != nil && mfa.EnabledAt != nil
+	if !enabled && !role.MFARequired {
+		return nil, nil
+	}
+
+	data := &models.AuthData{
+		UserID: user.ID.String(),
+		Email:  user.Email,
+		Role:   role.Name,
+	}
+
+	if enabled {
+		data.MFARequired = true
+	} else {
+		enrollment, err := s.startMFAEnrollment(user)
+		if err != nil {
+			return nil, err
+		}
+		data.MFAEnrollmentRequired = true
+		data.MFAEnrollment = enrollment
+	}
+
+	if data.MFAToken, err = s.createMFAChallenge(user.ID, !enabled); err != nil {
+		return nil, err
+	}
+
+	s.logAudit(&user.ID, "mfa_challenge", "success", ip, userAgent, "", map[string]interface{}{
+		"enrollment_required": !enabled,
+	})
+
+	return &models.AuthResponse{Success: true, Data: data}, nil
+}
+
+// GetChallengeEnrollment returns the pending secret for a login that must enrol
+// first, for clients that could not receive it with the login response (e.g.
+// the Google redirect flow)
+func (s *authService) GetChallengeEnrollment(mfaToken string) (*models.MFAEnrollmentData, error) {
+	userID, enroll, err := s.findMFAChallenge(mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if !enroll {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil || mfa.EnabledAt != nil {
+		return nil, ErrMFANotPending
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.mfaSecrets.open(mfa.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MFAEnrollmentData{
+		Secret:          string(secret),
+		ProvisioningURI: totpProvisioningURI(s.config.MFAIssuer, user.Email, string(secret)),
+	}, nil
+}
+
+// VerifyMFA completes a login challenge and issues tokens
+func (s *authService) VerifyMFA(req *models.MFAVerifyRequest, ip, userAgent string) (*models.AuthResponse, error) {
+	userID, enroll, err := s.findMFAChallenge(req.MFAToken)
+	if err != nil {
+		if errors.Is(err, ErrMFAChallenge) {
+			return mfaError("MFA_CHALLENGE_EXPIRED", "Login session expired, please sign in again"), nil
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	// Wrong codes are counted per account as well as per challenge, since
+	// whoever knows the password can start a new challenge at any time
+	account := normalizeAccount(user.Email)
+	var throttled *ThrottledError
+	if err := s.checkThrottle(actionMFA, ip, account); errors.As(err, &throttled) {
+		s.logAudit(&userID, "mfa_verify", "failed", ip, userAgent, "too many failed attempts")
+		return throttledResponse(throttled), nil
+	}
+
+	var ok bool
+	var recoveryCodes []string
+	method := "totp"
+
+	switch {
+	case enroll:
+		method = "enrollment"
+		recoveryCodes, err = s.confirmMFAEnrollment(userID, req.Code)
+		switch {
+		case err == nil:
+			ok = true
+		case errors.Is(err, ErrInvalidMFACode):
+			err = nil
+		case errors.Is(err, ErrMFANotPending), errors.Is(err, ErrMFAAlreadyEnabled):
+			// Completed or cancelled through another request
+			return mfaError("MFA_CHALLENGE_EXPIRED", "Login session expired, please sign in again"), nil
+		}
+	case req.RecoveryCode != "":
+		method = "recovery_code"
+		ok, err = s.mfaRepo.UseRecoveryCode(userID, s.hashRecoveryCode(req.RecoveryCode))
+	default:
+		var mfa *models.UserMFA
+		if mfa, err = s.mfaRepo.FindByUserID(userID); err == nil {
+			if mfa == nil || mfa.EnabledAt == nil {
+				return mfaError("MFA_CHALLENGE_EXPIRED", "Login session expired, please sign in again"), nil
+			}
+			ok, err = s.checkTOTP(mfa, req.Code)
+		}
+	}
+	if err != nil {
+		s.logAudit(&userID, "mfa_verify", "failed", ip, userAgent, err.Error())
+		return nil, err
+	}
+
+	if !ok {
+		s.logAudit(&userID, "mfa_verify", "failed", ip, userAgent, "invalid code", map[string]interface{}{"method": method})
+		s.recordFailure(actionMFA, ip, account, &userID, userAgent)
+
+		attempts, err := countMFAAttempt.Run(context.Background(), s.redisClient, []string{s.mfaChallengeKey(req.MFAToken)}).Int64()
+		if err == nil && attempts >= mfaMaxAttempts {
+			s.redisClient.Del(context.Background(), s.mfaChallengeKey(req.MFAToken))
+			return mfaError("MFA_TOO_MANY_ATTEMPTS", "Too many invalid codes, please sign in again"), nil
+		}
+		return mfaError("INVALID_MFA_CODE", "Invalid verification code"), nil
+	}
+
+	// A challenge completes one login only
+	deleted, err := s.redisClient.Del(context.Background(), s.mfaChallengeKey(req.MFAToken)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume mfa challenge: %w", err)
+	}
+	if deleted == 0 {
+		return mfaError("MFA_CHALLENGE_EXPIRED", "Login session expired, please sign in again"), nil
+	}
+	s.clearFailures(account, actionMFA)
+
+	if !user.IsActive {
+		s.logAudit(&user.ID, "login", "failed", ip, userAgent, "account inactive")
+		return mfaError("ACCOUNT_INACTIVE", "Account is inactive"), nil
+	}
+
+	roles, err := s.roleRepo.FindByUserID(user.ID)
+	if err != nil || len(roles) == 0 {
+		return nil, fmt.Errorf("failed to find user roles: %w", err)
+	}
+	roleName := roles[0].Name
+
+	s.userRepo.UpdateLoginInfo(user.ID, ip)
+
+	accessToken, refreshToken, expiresIn, err := s.generateTokens(user.ID, user.Email, roleName, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logAudit(&user.ID, "mfa_verify", "success", ip, userAgent, "", map[string]interface{}{"method": method})
+	if enroll {
+		s.logAudit(&user.ID, "mfa_enable", "success", ip, userAgent, "")
+	}
+	s.logAudit(&user.ID, "login", "success", ip, userAgent, "")
+
+	return &models.AuthResponse{
+		Success: true,
+		Data: &models.AuthData{
+			UserID:        user.ID.String(),
+			Email:         user.Email,
+			Role:          roleName,
+			AccessToken:   accessToken,
+			RefreshToken:  refreshToken,
+			ExpiresIn:     expiresIn,
+			RecoveryCodes: recoveryCodes,
+		},
+	}, nil
+}
+
+// GetMFAPolicies lists whether each role requires two-factor authentication
+func (s *authService) GetMFAPolicies() ([]models.MFAPolicy, error) {
+	roles, err := s.roleRepo.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	policies := make([]models.MFAPolicy, 0, len(roles))
+	for _, role := range roles {
+		policies = append(policies, models.MFAPolicy{
+			Role:        role.Name,
+			DisplayName: role.DisplayName,
+			Required:    role.MFARequired,
+		})
+	}
+	return policies, nil
+}
+
+// SetMFAPolicy makes two-factor authentication mandatory (or optional) for a
+// role. Members without MFA are asked to enrol at their next login.
+func (s *authService) SetMFAPolicy(role string, required bool, adminID uuid.UUID, ip, userAgent string) error {
+	if err := s.roleRepo.SetMFARequired(role, required); err != nil {
+		if err.Error() == "role not found" {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+
+	s.logAudit(&adminID, "mfa_policy_update", "success", ip, userAgent, "", map[string]interface{}{
+		"role":     role,
+		"required": required,
+	})
+	return nil
+}
+
+// Helper functions
+
+// startMFAEnrollment stores a new pending secret and returns it for display
+func (s *authService) startMFAEnrollment(user *models.User) (*models.MFAEnrollmentData, error) {
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.mfaSecrets.seal([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.SavePending(user.ID, encrypted); err != nil {
+		if err.Error() == "mfa already enabled" {
+			return nil, ErrMFAAlreadyEnabled
+		}
+		return nil, err
+	}
+
+	return &models.MFAEnrollmentData{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(s.config.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// confirmMFAEnrollment enables a pending enrolment if code matches its secret
+func (s *authService) confirmMFAEnrollment(userID uuid.UUID, code string) ([]string, error) {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa == nil {
+		return nil, ErrMFANotPending
+	}
+	if mfa.EnabledAt != nil {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.mfaSecrets.open(mfa.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := validateTOTP(string(secret), code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := s.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// checkTOTP validates a code against the enrolled secret, accepting each code once
+func (s *authService) checkTOTP(mfa *models.UserMFA, code string) (bool, error) {
+	secret, err := s.mfaSecrets.open(mfa.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := validateTOTP(string(secret), code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return s.mfaRepo.MarkStepUsed(mfa.UserID, step)
+}
+
+// checkSecondFactor accepts either a TOTP code or a recovery code
+func (s *authService) checkSecondFactor(mfa *models.UserMFA, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if _, err := strconv.Atoi(code); err == nil && len(code) == totpDigits {
+		return s.checkTOTP(mfa, code)
+	}
+	return s.mfaRepo.UseRecoveryCode(mfa.UserID, s.hashRecoveryCode(code))
+}
+
+// newRecoveryCodes returns codes for the user and their hashes for storage
+func (s *authService) newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		raw := make([]byte, recoveryCodeEntropy)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		encoded := recoveryCodeEncoding.EncodeToString(raw)[:10]
+		codes[i] = encoded[:5] + "-" + encoded[5:]
+		hashes[i] = s.hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed loosely
+func (s *authService) hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return s.hashToken(normalized)
+}
+
+// createMFAChallenge stores a login awaiting its second factor and returns its token
+func (s *authService) createMFAChallenge(userID uuid.UUID, enroll bool) (string, error) {
+	token := uuid.New().String()
+	ttl, err := time.ParseDuration(s.config.MFAChallengeTTL)
+	if err != nil || ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	ctx := context.Background()
+	key := s.mfaChallengeKey(token)
+	if err := s.redisClient.HSet(ctx, key, "user_id", userID.String(), "enroll", strconv.FormatBool(enroll), "attempts", 0).Err(); err != nil {
+		return "", fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	if err := s.redisClient.Expire(ctx, key, ttl).Err(); err != nil {
+		return "", fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+
+	return token, nil
+}
+
+// findMFAChallenge returns the user and mode of a pending challenge
+func (s *authService) findMFAChallenge(token string) (uuid.UUID, bool, error) {
+	fields, err := s.redisClient.HGetAll(context.Background(), s.mfaChallengeKey(token)).Result()
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to load mfa challenge: %w", err)
+	}
+
+	userID, err := uuid.Parse(fields["user_id"])
+	if err != nil {
+		return uuid.Nil, false, ErrMFAChallenge
+	}
+
+	enroll, _ := strconv.ParseBool(fields["enroll"])
+	return userID, enroll, nil
+}
+
+// mfaChallengeKey stores challenges under a hash so Redis never holds usable tokens
+func (s *authService) mfaChallengeKey(token string) string {
+	return mfaChallengePrefix + s.hashToken(token)
+}
+
+func mfaError(code, message string) *models.AuthResponse {
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// discoveryRetryInterval spaces out discovery attempts while a provider is unreachable
+const discoveryRetryInterval = 30 * time.Second
+
+// oidcProvider is an OpenID Connect provider. Its endpoints and signing keys
+// are discovered on first use, so an unreachable provider does not stop the
+// service from starting.
+type oidcProvider struct {
+	cfg        config.OIDCProviderConfig
+	httpClient *http.Client
+
+	mu            sync.Mutex
+	oauth         *oauth2.Config
+	keys          *jwks.KeySet
+	lastAttemptAt time.Time
+}
+
+// discoveryDocument holds the fields of /.well-known/openid-configuration we use
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// idTokenClaims are the ID token claims used to find or create the user
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string    `json:"nonce"`
+	AuthorizedParty   string    `json:"azp,omitempty"`
+	Email             string    `json:"email"`
+	EmailVerified     claimBool `json:"email_verified"`
+	Name              string    `json:"name"`
+	PreferredUsername string    `json:"preferred_username"`
+}
+
+// claimBool accepts booleans sent as JSON strings, which some providers do
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = claimBool(strings.EqualFold(v, "true"))
+	}
+	return nil
+}
+
+func newOIDCProvider(cfg config.OIDCProviderConfig) *oidcProvider {
+	return &oidcProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// oauthConfig returns the provider's OAuth2 client, discovering it if needed
+func (p *oidcProvider) oauthConfig(ctx context.Context) (*oauth2.Config, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oauth != nil {
+		return p.oauth, nil
+	}
+	if time.Since(p.lastAttemptAt) < discoveryRetryInterval {
+		return nil, ErrOIDCProviderUnavailable
+	}
+	p.lastAttemptAt = time.Now()
+
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCProviderUnavailable, err)
+	}
+
+	p.oauth = &oauth2.Config{
+		ClientID:     p.cfg.ClientID,
+		ClientSecret: p.cfg.ClientSecret,
+		RedirectURL:  p.cfg.RedirectURL,
+		Scopes:       p.cfg.Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  doc.AuthorizationEndpoint,
+			TokenURL: doc.TokenEndpoint,
+		},
+	}
+	p.keys = jwks.NewKeySet(doc.JWKSURI)
+	return p.oauth, nil
+}
+
+func (p *oidcProvider) discover(ctx context.Context) (*discoveryDocument, error) {
+	url := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create discovery request: %w", err)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch discovery document: status %d", resp.StatusCode)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode discovery document: %w", err)
+	}
+
+	// The issuer must match exactly, or ID tokens from another issuer could be accepted
+	if doc.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("discovery issuer %q does not match configured issuer %q", doc.Issuer, p.cfg.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+	return &doc, nil
+}
+
+// verifyIDToken checks the ID token's signature against the provider's JWKS,
+// its issuer, audience and expiry, and that it answers our nonce
+func (p *oidcProvider) verifyIDToken(raw, nonce string) (*idTokenClaims, error) {
+	p.mu.Lock()
+	keys := p.keys
+	p.mu.Unlock()
+	if keys == nil {
+		return nil, ErrOIDCProviderUnavailable
+	}
+
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, keys.Keyfunc,
+		jwt.WithValidMethods([]string{jwks.AlgRS256}),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if !p.issuedBy(claims.Issuer) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if claims.Nonce == "" || claims.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: token was issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	return &claims, nil
+}
+
+func (p *oidcProvider) issuedBy(issuer string) bool {
+	if issuer == p.cfg.Issuer {
+		return true
+	}
+	for _, alias := range p.cfg.IssuerAliases {
+		if issuer == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// verifiedEmail returns the email the provider vouches for, or "" if it does not
+func (p *oidcProvider) verifiedEmail(claims *idTokenClaims) string {
+	email := claims.Email
+	if email == "" && p.cfg.TrustEmail && strings.Contains(claims.PreferredUsername, "@") {
+		email = claims.PreferredUsername
+	}
+	if email == "" || !(bool(claims.EmailVerified) || p.cfg.TrustEmail) {
+		return ""
+	}
+	return email
+}
@@ -0,0 +1,467 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// oidcStateTTL bounds how long a user may take at the provider's consent screen
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrUnknownOIDCProvider     = errors.New("unknown sign-in provider")
+	ErrOIDCState               = errors.New("sign-in request expired or is invalid")
+	ErrOIDCProviderUnavailable = errors.New("sign-in provider is unavailable")
+	ErrOIDCExchange            = errors.New("failed to exchange authorization code")
+	ErrInvalidIDToken          = errors.New("invalid ID token")
+	ErrEmailNotVerified        = errors.New("the provider did not confirm this email address")
+	ErrIdentityLinked          = errors.New("this account is already linked to another user")
+	ErrIdentityNotFound        = errors.New("no account from this provider is linked")
+	ErrLastLoginMethod         = errors.New("set a password or link another provider before unlinking the last sign-in method")
+)
+
+// OIDCService signs users in with OpenID Connect providers and manages the
+// external identities linked to their accounts
+type OIDCService interface {
+	Providers() []OIDCProviderInfo
+	AuthURL(provider string, linkUserID *uuid.UUID) (url, state string, err error)
+	Callback(provider, code, state string, requester OIDCRequester, ip, userAgent string) (*OIDCResult, error)
+	ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error)
+	UnlinkIdentity(userID uuid.UUID, provider, ip, userAgent string) error
+}
+
+// OIDCProviderInfo describes a configured provider to clients
+type OIDCProviderInfo struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
+
+// OIDCRequester is what a callback request proves about who sent it. Links
+// complete only for the browser or the signed-in user that started them, so a
+// link URL sent to someone else cannot attach their provider account.
+type OIDCRequester struct {
+	StateCookie bool       // the request carried the state cookie set when the flow started
+	UserID      *uuid.UUID // the user of the request's access token, if any
+}
+
+// OIDCResult is the outcome of a callback: a sign-in, or an identity linked
+// to the user who started the flow from their profile
+type OIDCResult struct {
+	Auth   *models.AuthResponse
+	Linked *models.UserIdentity
+}
+
+// oidcState is kept in Redis between the redirect and the callback
+type oidcState struct {
+	Provider   string     `json:"provider"`
+	Nonce      string     `json:"nonce"`
+	Verifier   string     `json:"verifier"`
+	LinkUserID *uuid.UUID `json:"link_user_id,omitempty"`
+}
+
+type oidcService struct {
+	providers         map[string]*oidcProvider
+	userRepo          repository.UserRepository
+	roleRepo          repository.RoleRepository
+	identityRepo      repository.IdentityRepository
+	auditRepo         repository.AuditLogRepository
+	authService       AuthService
+	redisClient       *redis.Client
+	userServiceClient *client.UserServiceClient
+}
+
+func NewOIDCService(
+	cfg *config.Config,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	identityRepo repository.IdentityRepository,
+	auditRepo repository.AuditLogRepository,
+	authService AuthService,
+	redisClient *redis.Client,
+	userServiceClient *client.UserServiceClient,
+) OIDCService {
+	providers := make(map[string]*oidcProvider, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		providers[p.Name] = newOIDCProvider(p)
+	}
+
+	return &oidcService{
+		providers:         providers,
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		identityRepo:      identityRepo,
+		auditRepo:         auditRepo,
+		authService:       authService,
+		redisClient:       redisClient,
+		userServiceClient: userServiceClient,
+	}
+}
+
+// Providers lists the configured providers by name
+func (s *oidcService) Providers() []OIDCProviderInfo {
+	infos := make([]OIDCProviderInfo, 0, len(s.providers))
+	for _, p := range s.providers {
+		infos = append(infos, OIDCProviderInfo{Name: p.cfg.Name, DisplayName: p.cfg.DisplayName})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// AuthURL starts a sign-in, or links the provider to linkUserID when set.
+// The state, nonce and PKCE verifier are stored server-side and consumed by
+// the callback, so they cannot be replayed.
+func (s *oidcService) AuthURL(provider string, linkUserID *uuid.UUID) (string, string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	ctx := context.Background()
+	oauthCfg, err := p.oauthConfig(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return "", "", err
+	}
+	pending := oidcState{
+		Provider:   provider,
+		Nonce:      nonce,
+		Verifier:   oauth2.GenerateVerifier(),
+		LinkUserID: linkUserID,
+	}
+
+	encoded, err := json.Marshal(pending)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode sign-in state: %w", err)
+	}
+	if err := s.redisClient.Set(ctx, oidcStateKey(state), encoded, oidcStateTTL).Err(); err != nil {
+		return "", "", fmt.Errorf("failed to store sign-in state: %w", err)
+	}
+
+	url := oauthCfg.AuthCodeURL(state,
+		oauth2.SetAuthURLParam("nonce", nonce),
+		oauth2.S256ChallengeOption(pending.Verifier),
+	)
+	return url, state, nil
+}
+
+// Callback completes the flow started by AuthURL
+func (s *oidcService) Callback(provider, code, state string, requester OIDCRequester, ip, userAgent string) (*OIDCResult, error) {
+	pending, err := s.consumeState(provider, state)
+	if err != nil {
+		return nil, err
+	}
+	if !pending.startedBy(requester) {
+		s.audit(pending.LinkUserID, "identity_linked", "failed", ip, userAgent, "link not completed by the browser or user that started it", provider)
+		return nil, ErrOIDCState
+	}
+	p := s.providers[provider]
+
+	ctx := context.Background()
+	oauthCfg, err := p.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauthCfg.Exchange(ctx, code, oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		s.audit(pending.LinkUserID, "oidc_login", "failed", ip, userAgent, err.Error(), provider)
+		return nil, fmt.Errorf("%w: %v", ErrOIDCExchange, err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no id_token", ErrInvalidIDToken)
+	}
+
+	claims, err := p.verifyIDToken(rawIDToken, pending.Nonce)
+	if err != nil {
+		s.audit(pending.LinkUserID, "oidc_login", "failed", ip, userAgent, err.Error(), provider)
+		return nil, err
+	}
+	email := p.verifiedEmail(claims)
+
+	if pending.LinkUserID != nil {
+		identity, err := s.link(*pending.LinkUserID, provider, claims.Subject, email, ip, userAgent)
+		if err != nil {
+			return nil, err
+		}
+		return &OIDCResult{Linked: identity}, nil
+	}
+
+	auth, err := s.login(p, claims, email, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCResult{Auth: auth}, nil
+}
+
+// login signs in the user linked to the identity. Unknown identities are
+// linked to the account with the same verified email, or get a new account.
+func (s *oidcService) login(p *oidcProvider, claims *idTokenClaims, email, ip, userAgent string) (*models.AuthResponse, error) {
+	provider := p.cfg.Name
+
+	identity, err := s.identityRepo.FindByProviderSubject(provider, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *models.User
+	isNewUser := false
+	switch {
+	case identity != nil:
+		user, err = s.userRepo.FindByID(identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+	case email == "":
+		s.audit(nil, "oidc_login", "failed", ip, userAgent, "email not verified by provider", provider)
+		return nil, ErrEmailNotVerified
+	default:
+		user, err = s.userRepo.FindByEmail(email)
+		if err != nil && err.Error() != "user not found" {
+			return nil, err
+		}
+
+		identity = &models.UserIdentity{Provider: provider, Subject: claims.Subject, Email: &email}
+		if user != nil {
+			// Only an address the account owner proved is trusted to match
+			if !user.IsVerified {
+				s.audit(&user.ID, "oidc_login", "failed", ip, userAgent, "existing account email not verified", provider)
+				return nil, ErrEmailNotVerified
+			}
+			if err := s.identityRepo.Create(identity); err != nil {
+				return nil, err
+			}
+			s.audit(&user.ID, "identity_linked", "success", ip, userAgent, "", provider)
+		} else {
+			user, err = s.identityRepo.CreateUserWithIdentity(email, identity)
+			if err != nil {
+				return nil, err
+			}
+			isNewUser = true
+		}
+	}
+
+	if !user.IsActive {
+		s.audit(&user.ID, "oidc_login", "failed", ip, userAgent, "account is not active", provider)
+		return &models.AuthResponse{
+			Success: false,
+			Error: &models.ErrorData{
+				Code:    "ACCOUNT_INACTIVE",
+				Message: "Account is not active",
+			},
+		}, nil
+	}
+
+	roles, err := s.roleRepo.FindByUserID(user.ID)
+	var role *models.Role
+	if err != nil || len(roles) == 0 {
+		// Accounts created through a provider start as students
+		studentRole, err := s.roleRepo.FindByName("student")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get default role: %w", err)
+		}
+		if err := s.roleRepo.AssignRoleToUser(user.ID, studentRole.ID, nil); err != nil {
+			return nil, fmt.Errorf("failed to assign role: %w", err)
+		}
+		role = studentRole
+	} else {
+		role = &roles[0]
+	}
+
+	if isNewUser && s.userServiceClient != nil {
+		profileReq := client.CreateProfileRequest{
+			UserID:   user.ID.String(),
+			Email:    user.Email,
+			Role:     role.Name,
+			FullName: claims.Name,
+		}
+		if err := s.userServiceClient.CreateProfile(profileReq); err != nil {
+			log.Printf("⚠️ Failed to create user profile for %s: %v", user.Email, err)
+		}
+	}
+
+	if err := s.identityRepo.TouchLogin(identity.ID); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+
+	// Second factor, when enrolled or required by the role's policy
+	if challenge, err := s.authService.BeginMFAChallenge(user, role, ip, userAgent); err != nil || challenge != nil {
+		return challenge, err
+	}
+
+	accessToken, refreshToken, expiresIn, err := s.authService.IssueTokens(user.ID, user.Email, role.Name, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateLoginInfo(user.ID, ip); err != nil {
+		log.Printf("⚠️ Failed to update login info: %v", err)
+	}
+	if err := s.userRepo.ResetFailedAttempts(user.ID); err != nil {
+		log.Printf("⚠️ Failed to reset failed attempts: %v", err)
+	}
+
+	s.audit(&user.ID, "oidc_login", "success", ip, userAgent, "", provider)
+
+	return &models.AuthResponse{
+		Success: true,
+		Data: &models.AuthData{
+			UserID:       user.ID.String(),
+			Email:        user.Email,
+			Role:         role.Name,
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+			ExpiresIn:    expiresIn,
+		},
+	}, nil
+}
+
+// link attaches the provider account to a signed-in user
+func (s *oidcService) link(userID uuid.UUID, provider, subject, email, ip, userAgent string) (*models.UserIdentity, error) {
+	existing, err := s.identityRepo.FindByProviderSubject(provider, subject)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		if existing.UserID != userID {
+			s.audit(&userID, "identity_linked", "failed", ip, userAgent, "identity belongs to another user", provider)
+			return nil, ErrIdentityLinked
+		}
+		return existing, nil
+	}
+
+	identity := &models.UserIdentity{UserID: userID, Provider: provider, Subject: subject}
+	if email != "" {
+		identity.Email = &email
+	}
+	if err := s.identityRepo.Create(identity); err != nil {
+		if err.Error() == "identity already linked" {
+			// The user already has another account at this provider linked
+			return nil, ErrIdentityLinked
+		}
+		return nil, err
+	}
+
+	s.audit(&userID, "identity_linked", "success", ip, userAgent, "", provider)
+	return identity, nil
+}
+
+// ListIdentities returns the provider accounts linked to the user
+func (s *oidcService) ListIdentities(userID uuid.UUID) ([]models.UserIdentity, error) {
+	return s.identityRepo.FindByUserID(userID)
+}
+
+// UnlinkIdentity removes a provider link, keeping at least one way to sign in
+func (s *oidcService) UnlinkIdentity(userID uuid.UUID, provider, ip, userAgent string) error {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	identities, err := s.identityRepo.FindByUserID(userID)
+	if err != nil {
+		return err
+	}
+
+	linked := false
+	for _, identity := range identities {
+		if identity.Provider == provider {
+			linked = true
+		}
+	}
+	if !linked {
+		return ErrIdentityNotFound
+	}
+	if user.Password == nil && len(identities) == 1 {
+		return ErrLastLoginMethod
+	}
+
+	deleted, err := s.identityRepo.Delete(userID, provider)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrIdentityNotFound
+	}
+
+	s.audit(&userID, "identity_unlinked", "success", ip, userAgent, "", provider)
+	return nil
+}
+
+// consumeState loads and deletes the pending sign-in, so each state works once
+// startedBy reports whether the requester may complete the flow. Sign-ins
+// may be completed by anyone holding the code; links only by the browser
+// that started them or by the user they link to.
+func (pending *oidcState) startedBy(requester OIDCRequester) bool {
+	if pending.LinkUserID == nil || requester.StateCookie {
+		return true
+	}
+	return requester.UserID != nil && *requester.UserID == *pending.LinkUserID
+}
+
+func (s *oidcService) consumeState(provider, state string) (*oidcState, error) {
+	if _, ok := s.providers[provider]; !ok {
+		return nil, ErrUnknownOIDCProvider
+	}
+	if state == "" {
+		return nil, ErrOIDCState
+	}
+
+	encoded, err := s.redisClient.GetDel(context.Background(), oidcStateKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrOIDCState
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sign-in state: %w", err)
+	}
+
+	var pending oidcState
+	if err := json.Unmarshal(encoded, &pending); err != nil {
+		return nil, fmt.Errorf("failed to decode sign-in state: %w", err)
+	}
+	if pending.Provider != provider {
+		return nil, ErrOIDCState
+	}
+	return &pending, nil
+}
+
+func (s *oidcService) audit(userID *uuid.UUID, eventType, status, ip, userAgent, errorMsg, provider string) {
+	writeAuditLog(s.auditRepo, userID, eventType, status, ip, userAgent, errorMsg, map[string]interface{}{
+		"provider": provider,
+	})
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// randomToken returns 32 random bytes, URL-safe encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestOIDCStateStartedBy tests that a link completes only for the browser or
+// user that started it, while sign-ins need neither
+func TestOIDCStateStartedBy(t *testing.T) {
+	owner := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name      string
+		link      *uuid.UUID
+		requester OIDCRequester
+		expected  bool
+	}{
+		{"sign-in without cookie or token", nil, OIDCRequester{}, true},
+		{"link from the browser that started it", &owner, OIDCRequester{StateCookie: true}, true},
+		{"link by the user it links to", &owner, OIDCRequester{UserID: &owner}, true},
+		{"link URL opened in another browser", &owner, OIDCRequester{}, false},
+		{"link completed by another user", &owner, OIDCRequester{UserID: &other}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := &oidcState{Provider: "google", LinkUserID: tt.link}
+			if got := pending.startedBy(tt.requester); got != tt.expected {
+				t.Errorf("startedBy(%+v) = %v, expected %v", tt.requester, got, tt.expected)
+			}
+		})
+	}
+}
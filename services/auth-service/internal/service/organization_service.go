@@ -0,0 +1,942 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/big"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxInviteEmails caps one invite upload
+	maxInviteEmails = 500
+	// progressBatchSize is the most learners user-service returns per call
+	progressBatchSize = 500
+
+	inviteCodeLength = 8
+	// inviteCodeAlphabet leaves out 0/O and 1/I so codes read out in class are unambiguous
+	inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	ErrOrganizationNotFound  = errors.New("organization not found")
+	ErrOrganizationSlugTaken = errors.New("this slug is already used by another organization")
+	ErrInvalidSlug           = errors.New("slug may only contain lower-case letters, digits and hyphens")
+	ErrOrganizationForbidden = errors.New("your role in this organization does not allow this")
+	ErrMemberNotFound        = errors.New("member not found")
+	ErrClassroomNotFound     = errors.New("classroom not found")
+	ErrInvalidInviteCode     = errors.New("invalid invite code")
+	ErrInviteNotFound        = errors.New("invite not found")
+	ErrTeacherRoleRequired   = errors.New("classroom teachers must be instructors or admins of the organization")
+	ErrAlreadyInClassroom    = errors.New("this account is already a member of the classroom")
+	ErrInvalidInviteFile     = errors.New("the invite file must be a CSV with one email per line or an email column")
+	ErrTooManyInvites        = fmt.Errorf("an invite file may hold at most %d emails", maxInviteEmails)
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// roleRank orders the seeded roles by privilege, so a check for a role also
+// passes for every role above it
+var roleRank = map[string]int{
+	models.RoleStudent:    1,
+	models.RoleInstructor: 2,
+	models.RoleAdmin:      3,
+}
+
+// OrganizationService manages organizations, their classrooms and rosters.
+// Roles are checked per organization and classroom: organization admins
+// manage members and every classroom, instructors run the classrooms they
+// teach, and students see the classrooms they joined. Platform admins act as
+// admins of every organization.
+type OrganizationService interface {
+	// Admin
+	CreateOrganization(req *models.CreateOrganizationRequest, adminID uuid.UUID, ip, userAgent string) (*models.Organization, error)
+	SearchOrganizations(query string, page, limit int) (*models.OrganizationList, error)
+
+	// Organizations
+	ListMyOrganizations(userID uuid.UUID) ([]models.Organization, error)
+	ListMembers(orgID, userID uuid.UUID) ([]models.OrganizationMember, error)
+	AddMember(orgID, userID uuid.UUID, req *models.AddOrganizationMemberRequest, ip, userAgent string) error
+	RemoveMember(orgID, memberID, userID uuid.UUID, ip, userAgent string) error
+
+	// Classrooms
+	CreateClassroom(orgID, userID uuid.UUID, req *models.CreateClassroomRequest) (*models.Classroom, error)
+	ListClassrooms(orgID, userID uuid.UUID) ([]models.Classroom, error)
+	ListMyClassrooms(userID uuid.UUID) ([]models.Classroom, error)
+	JoinClassroom(userID uuid.UUID, code, ip, userAgent string) (*models.Classroom, error)
+	RegenerateInviteCode(classroomID, userID uuid.UUID) (*models.Classroom, error)
+
+	// Rosters
+	GetRoster(classroomID, userID uuid.UUID) (*models.ClassroomRoster, error)
+	AddClassroomMember(classroomID, userID uuid.UUID, req *models.AddClassroomMemberRequest) error
+	RemoveClassroomMember(classroomID, memberID, userID uuid.UUID) error
+	InviteStudents(classroomID, userID uuid.UUID, file io.Reader) (*models.ClassroomInviteResult, error)
+	RevokeInvite(classroomID, inviteID, userID uuid.UUID) error
+	GetClassroomProgress(classroomID, userID uuid.UUID) (*models.ClassroomProgress, error)
+
+	// Internal
+	GetClassroomAccess(classroomID, userID uuid.UUID) (*models.ClassroomAccess, error)
+}
+
+type organizationService struct {
+	orgRepo            repository.OrganizationRepository
+	userRepo           repository.UserRepository
+	roleRepo           repository.RoleRepository
+	auditRepo          repository.AuditLogRepository
+	userServiceClient  *client.UserServiceClient
+	notificationClient *client.NotificationServiceClient
+	frontendURL        string
+}
+
+func NewOrganizationService(
+	cfg *config.Config,
+	orgRepo repository.OrganizationRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	auditRepo repository.AuditLogRepository,
+	userServiceClient *client.UserServiceClient,
+	notificationClient *client.NotificationServiceClient,
+) OrganizationService {
+	return &organizationService{
+		orgRepo:            orgRepo,
+		userRepo:           userRepo,
+		roleRepo:           roleRepo,
+		auditRepo:          auditRepo,
+		userServiceClient:  userServiceClient,
+		notificationClient: notificationClient,
+		frontendURL:        cfg.FrontendURL,
+	}
+}
+
+// CreateOrganization creates an organization. When an admin email is given,
+// that account becomes the organization's first admin.
+func (s *organizationService) CreateOrganization(req *models.CreateOrganizationRequest, adminID uuid.UUID, ip, userAgent string) (*models.Organization, error) {
+	slug := strings.TrimSpace(req.Slug)
+	if !slugPattern.MatchString(slug) {
+		return nil, ErrInvalidSlug
+	}
+
+	var orgAdminID *uuid.UUID
+	if req.AdminEmail != "" {
+		user, err := s.userRepo.FindByEmail(req.AdminEmail)
+		if err != nil {
+			if err.Error() == "user not found" {
+				return nil, ErrUserNotFound
+			}
+			return nil, err
+		}
+		orgAdminID = &user.ID
+	}
+	adminRole, err := s.role(models.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	org := &models.Organization{
+		Name:      strings.TrimSpace(req.Name),
+		Slug:      slug,
+		CreatedBy: &adminID,
+	}
+	if err := s.orgRepo.Create(org, orgAdminID, adminRole.ID); err != nil {
+		if err.Error() == "organization slug already exists" {
+			return nil, ErrOrganizationSlugTaken
+		}
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{"organization_id": org.ID, "slug": org.Slug}
+	if orgAdminID != nil {
+		metadata["organization_admin_id"] = orgAdminID.String()
+	}
+	writeAuditLog(s.auditRepo, &adminID, "organization_created", "success", ip, userAgent, "", metadata)
+	return org, nil
+}
+
+// SearchOrganizations returns a page of organizations, newest first
+func (s *organizationService) SearchOrganizations(query string, page, limit int) (*models.OrganizationList, error) {
+	orgs, total, err := s.orgRepo.Search(strings.TrimSpace(query), limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OrganizationList{
+		Organizations: orgs,
+		Total:         total,
+		Page:          page,
+		Limit:         limit,
+	}, nil
+}
+
+// ListMyOrganizations returns the organizations the user belongs to, with their role in each
+func (s *organizationService) ListMyOrganizations(userID uuid.UUID) ([]models.Organization, error) {
+	return s.orgRepo.FindByUserID(userID)
+}
+
+// ListMembers returns the organization's members, for its instructors and admins
+func (s *organizationService) ListMembers(orgID, userID uuid.UUID) ([]models.OrganizationMember, error) {
+	if _, err := s.requireOrganizationRole(orgID, userID, models.RoleInstructor); err != nil {
+		return nil, err
+	}
+	return s.orgRepo.ListMembers(orgID)
+}
+
+// AddMember adds an existing account to the organization, or changes the role
+// of a member
+func (s *organizationService) AddMember(orgID, userID uuid.UUID, req *models.AddOrganizationMemberRequest, ip, userAgent string) error {
+	if _, err := s.requireOrganizationRole(orgID, userID, models.RoleAdmin); err != nil {
+		return err
+	}
+
+	member, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	role, err := s.role(req.Role)
+	if err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.SetMember(orgID, member.ID, role.ID, userID); err != nil {
+		return err
+	}
+
+	writeAuditLog(s.auditRepo, &member.ID, "organization_member_added", "success", ip, userAgent, "", map[string]interface{}{
+		"organization_id": orgID,
+		"role":            role.Name,
+		"added_by":        userID.String(),
+	})
+	return nil
+}
+
+// RemoveMember removes a member from the organization and all its classrooms
+func (s *organizationService) RemoveMember(orgID, memberID, userID uuid.UUID, ip, userAgent string) error {
+	if _, err := s.requireOrganizationRole(orgID, userID, models.RoleAdmin); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.RemoveMember(orgID, memberID); err != nil {
+		if err.Error() == "organization member not found" {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+
+	writeAuditLog(s.auditRepo, &memberID, "organization_member_removed", "success", ip, userAgent, "", map[string]interface{}{
+		"organization_id": orgID,
+		"removed_by":      userID.String(),
+	})
+	return nil
+}
+
+// CreateClassroom creates a classroom taught by the caller, who must be an
+// instructor or admin of the organization
+func (s *organizationService) CreateClassroom(orgID, userID uuid.UUID, req *models.CreateClassroomRequest) (*models.Classroom, error) {
+	if _, err := s.requireOrganizationRole(orgID, userID, models.RoleInstructor); err != nil {
+		return nil, err
+	}
+	teacherRole, err := s.role(models.RoleInstructor)
+	if err != nil {
+		return nil, err
+	}
+
+	classroom := &models.Classroom{
+		OrganizationID: orgID,
+		Name:           strings.TrimSpace(req.Name),
+		Description:    req.Description,
+		InviteCode:     generateInviteCode(),
+		CreatedBy:      &userID,
+		Role:           models.RoleInstructor,
+	}
+	if err := s.orgRepo.CreateClassroom(classroom, teacherRole.ID); err != nil {
+		return nil, err
+	}
+
+	return classroom, nil
+}
+
+// ListClassrooms returns every classroom of the organization to its admins,
+// and the classrooms they belong to to everyone else
+func (s *organizationService) ListClassrooms(orgID, userID uuid.UUID) ([]models.Classroom, error) {
+	role, err := s.requireOrganizationRole(orgID, userID, models.RoleStudent)
+	if err != nil {
+		return nil, err
+	}
+	if role == models.RoleAdmin {
+		return s.orgRepo.ListClassrooms(orgID)
+	}
+
+	mine, err := s.orgRepo.ListClassroomsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	classrooms := []models.Classroom{}
+	for _, classroom := range mine {
+		if classroom.OrganizationID == orgID {
+			classrooms = append(classrooms, hideInviteCode(classroom))
+		}
+	}
+	return classrooms, nil
+}
+
+// ListMyClassrooms returns the classrooms the user teaches or studies in
+func (s *organizationService) ListMyClassrooms(userID uuid.UUID) ([]models.Classroom, error) {
+	classrooms, err := s.orgRepo.ListClassroomsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range classrooms {
+		classrooms[i] = hideInviteCode(classrooms[i])
+	}
+	return classrooms, nil
+}
+
+// JoinClassroom adds the user to the classroom with the invite code as a
+// student, and to its organization if they are not a member yet. Joining a
+// classroom the user is already in is a no-op.
+func (s *organizationService) JoinClassroom(userID uuid.UUID, code, ip, userAgent string) (*models.Classroom, error) {
+	classroom, err := s.orgRepo.FindClassroomByInviteCode(strings.TrimSpace(code))
+	if err != nil {
+		if err.Error() == "classroom not found" {
+			return nil, ErrInvalidInviteCode
+		}
+		return nil, err
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	joined, err := s.addStudent(classroom, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.orgRepo.AcceptInvite(classroom.ID, user.Email); err != nil {
+		return nil, err
+	}
+
+	if joined {
+		writeAuditLog(s.auditRepo, &userID, "classroom_joined", "success", ip, userAgent, "", map[string]interface{}{
+			"organization_id": classroom.OrganizationID,
+			"classroom_id":    classroom.ID,
+		})
+	}
+
+	role, err := s.orgRepo.FindClassroomRole(classroom.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	classroom.Role = role
+	if role != models.RoleInstructor {
+		*classroom = hideInviteCode(*classroom)
+	}
+	return classroom, nil
+}
+
+// RegenerateInviteCode replaces the classroom's invite code, so the old one
+// stops working. Members who already joined stay.
+func (s *organizationService) RegenerateInviteCode(classroomID, userID uuid.UUID) (*models.Classroom, error) {
+	classroom, err := s.requireClassroomTeacher(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	classroom.InviteCode = generateInviteCode()
+	if err := s.orgRepo.SetInviteCode(classroomID, classroom.InviteCode); err != nil {
+		return nil, err
+	}
+	return classroom, nil
+}
+
+// GetRoster returns the classroom's teachers, students and pending invites
+func (s *organizationService) GetRoster(classroomID, userID uuid.UUID) (*models.ClassroomRoster, error) {
+	classroom, err := s.requireClassroomTeacher(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgRepo.ListClassroomMembers(classroomID)
+	if err != nil {
+		return nil, err
+	}
+	invites, err := s.orgRepo.ListPendingInvites(classroomID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ClassroomRoster{
+		Classroom:      *classroom,
+		Members:        members,
+		PendingInvites: invites,
+	}, nil
+}
+
+// AddClassroomMember invites an existing account to the classroom as a
+// student, or makes an account a teacher of it. Students only join by
+// accepting the invite with the classroom's code, so their progress is never
+// shared with a classroom they did not choose. Teachers must already be
+// instructors or admins of the organization.
+func (s *organizationService) AddClassroomMember(classroomID, userID uuid.UUID, req *models.AddClassroomMemberRequest) error {
+	classroom, err := s.requireClassroomTeacher(classroomID, userID)
+	if err != nil {
+		return err
+	}
+
+	member, err := s.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if req.Role == models.RoleStudent {
+		role, err := s.orgRepo.FindClassroomRole(classroomID, member.ID)
+		if err != nil {
+			return err
+		}
+		if role != "" {
+			return ErrAlreadyInClassroom
+		}
+
+		invited, err := s.orgRepo.CreateInvites(classroomID, []string{strings.ToLower(member.Email)}, userID)
+		if err != nil {
+			return err
+		}
+		if len(invited) == 0 {
+			// Already invited; the pending invite stands
+			return nil
+		}
+		org, err := s.orgRepo.FindByID(classroom.OrganizationID)
+		if err != nil {
+			return err
+		}
+		s.notifyInvite(member.ID, org, classroom)
+		return nil
+	}
+
+	orgRole, err := s.orgRepo.FindMemberRole(classroom.OrganizationID, member.ID)
+	if err != nil {
+		return err
+	}
+	if roleRank[orgRole] < roleRank[models.RoleInstructor] {
+		return ErrTeacherRoleRequired
+	}
+	teacherRole, err := s.role(models.RoleInstructor)
+	if err != nil {
+		return err
+	}
+	return s.orgRepo.SetClassroomMember(classroomID, member.ID, teacherRole.ID, userID)
+}
+
+// RemoveClassroomMember removes a teacher or student from the classroom. They
+// stay in the organization.
+func (s *organizationService) RemoveClassroomMember(classroomID, memberID, userID uuid.UUID) error {
+	if _, err := s.requireClassroomTeacher(classroomID, userID); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.RemoveClassroomMember(classroomID, memberID); err != nil {
+		if err.Error() == "classroom member not found" {
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// InviteStudents reads a CSV of emails, either one per line or in a column
+// headed "email", and invites each address that is not in the classroom yet.
+// Accounts are notified in the app and other addresses by email; either way
+// the student joins by entering the classroom's code.
+func (s *organizationService) InviteStudents(classroomID, userID uuid.UUID, file io.Reader) (*models.ClassroomInviteResult, error) {
+	classroom, err := s.requireClassroomTeacher(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ClassroomInviteResult{
+		Invited:        []string{},
+		AlreadyMembers: []string{},
+		Invalid:        []models.InvalidInviteLine{},
+	}
+	emails, err := readInviteEmails(file, result)
+	if err != nil {
+		return nil, err
+	}
+	if len(emails) == 0 {
+		return result, nil
+	}
+
+	accounts, err := s.userRepo.FindIDsByEmails(emails)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.orgRepo.ListClassroomMembers(classroomID)
+	if err != nil {
+		return nil, err
+	}
+	inClassroom := make(map[uuid.UUID]bool, len(members))
+	for _, member := range members {
+		inClassroom[member.UserID] = true
+	}
+
+	var pending []string
+	for _, email := range emails {
+		if id, ok := accounts[email]; ok && inClassroom[id] {
+			result.AlreadyMembers = append(result.AlreadyMembers, email)
+			continue
+		}
+		pending = append(pending, email)
+	}
+	if len(pending) == 0 {
+		return result, nil
+	}
+
+	invited, err := s.orgRepo.CreateInvites(classroomID, pending, userID)
+	if err != nil {
+		return nil, err
+	}
+	isNew := make(map[string]bool, len(invited))
+	for _, email := range invited {
+		isNew[email] = true
+	}
+
+	org, err := s.orgRepo.FindByID(classroom.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, email := range pending {
+		if !isNew[email] {
+			result.AlreadyMembers = append(result.AlreadyMembers, email)
+			continue
+		}
+		if id, ok := accounts[email]; ok {
+			s.notifyInvite(id, org, classroom)
+		} else {
+			s.sendInvite(email, org, classroom)
+		}
+		result.Invited = append(result.Invited, email)
+	}
+
+	return result, nil
+}
+
+// RevokeInvite withdraws a pending invite. The emailed code still works until
+// it is regenerated.
+func (s *organizationService) RevokeInvite(classroomID, inviteID, userID uuid.UUID) error {
+	if _, err := s.requireClassroomTeacher(classroomID, userID); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.DeleteInvite(classroomID, inviteID); err != nil {
+		if err.Error() == "invite not found" {
+			return ErrInviteNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// GetClassroomProgress returns each student's progress from user-service with
+// class averages. Only students who joined are included, not pending invites.
+func (s *organizationService) GetClassroomProgress(classroomID, userID uuid.UUID) (*models.ClassroomProgress, error) {
+	if _, err := s.requireClassroomTeacher(classroomID, userID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgRepo.ListClassroomMembers(classroomID)
+	if err != nil {
+		return nil, err
+	}
+	var students []models.ClassroomMember
+	for _, member := range members {
+		if member.Role == models.RoleStudent {
+			students = append(students, member)
+		}
+	}
+
+	summaries := make(map[string]client.ProgressSummary, len(students))
+	for start := 0; start < len(students); start += progressBatchSize {
+		end := start + progressBatchSize
+		if end > len(students) {
+			end = len(students)
+		}
+		ids := make([]string, 0, end-start)
+		for _, student := range students[start:end] {
+			ids = append(ids, student.UserID.String())
+		}
+
+		batch, err := s.userServiceClient.GetProgressSummaries(ids)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range batch {
+			summaries[summary.UserID] = summary
+		}
+	}
+
+	progress := &models.ClassroomProgress{
+		ClassroomID: classroomID,
+		Students:    make([]models.StudentProgress, len(students)),
+	}
+	for i, student := range students {
+		progress.Students[i] = studentProgress(student, summaries[student.UserID.String()])
+	}
+	progress.Summary = summarizeProgress(progress.Students)
+
+	return progress, nil
+}
+
+// GetClassroomAccess returns the user's role in the classroom and its
+// students, for services that keep classroom data of their own. Organization
+// admins who do not teach the classroom get the admin role.
+func (s *organizationService) GetClassroomAccess(classroomID, userID uuid.UUID) (*models.ClassroomAccess, error) {
+	classroom, err := s.orgRepo.FindClassroomByID(classroomID)
+	if err != nil {
+		if err.Error() == "classroom not found" {
+			return nil, ErrClassroomNotFound
+		}
+		return nil, err
+	}
+
+	role, err := s.orgRepo.FindClassroomRole(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.RoleInstructor {
+		orgRole, err := s.organizationRole(classroom.OrganizationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if orgRole == models.RoleAdmin {
+			role = models.RoleAdmin
+		}
+	}
+
+	members, err := s.orgRepo.ListClassroomMembers(classroomID)
+	if err != nil {
+		return nil, err
+	}
+	students := make([]models.ClassroomMember, 0, len(members))
+	for _, m := range members {
+		if m.Role == models.RoleStudent {
+			students = append(students, m)
+		}
+	}
+
+	return &models.ClassroomAccess{
+		Classroom: hideInviteCode(*classroom),
+		Role:      role,
+		Students:  students,
+	}, nil
+}
+
+// requireOrganizationRole returns the caller's role in the organization and
+// fails unless it is minRole or above. Non-members get ErrOrganizationNotFound,
+// so they cannot tell which organizations exist.
+func (s *organizationService) requireOrganizationRole(orgID, userID uuid.UUID, minRole string) (string, error) {
+	if _, err := s.orgRepo.FindByID(orgID); err != nil {
+		if err.Error() == "organization not found" {
+			return "", ErrOrganizationNotFound
+		}
+		return "", err
+	}
+
+	role, err := s.organizationRole(orgID, userID)
+	if err != nil {
+		return "", err
+	}
+	if role == "" {
+		return "", ErrOrganizationNotFound
+	}
+	if roleRank[role] < roleRank[minRole] {
+		return "", ErrOrganizationForbidden
+	}
+	return role, nil
+}
+
+// organizationRole is the user's role in the organization, "" if they are not
+// a member. Platform admins are admins of every organization.
+func (s *organizationService) organizationRole(orgID, userID uuid.UUID) (string, error) {
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return "", err
+	}
+	if len(roles) > 0 && roles[0].Name == models.RoleAdmin {
+		return models.RoleAdmin, nil
+	}
+	return s.orgRepo.FindMemberRole(orgID, userID)
+}
+
+// requireClassroomTeacher loads the classroom for one of its teachers or an
+// admin of its organization. Students get ErrOrganizationForbidden and
+// everyone else ErrClassroomNotFound.
+func (s *organizationService) requireClassroomTeacher(classroomID, userID uuid.UUID) (*models.Classroom, error) {
+	classroom, err := s.orgRepo.FindClassroomByID(classroomID)
+	if err != nil {
+		if err.Error() == "classroom not found" {
+			return nil, ErrClassroomNotFound
+		}
+		return nil, err
+	}
+
+	role, err := s.orgRepo.FindClassroomRole(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role != models.RoleInstructor {
+		orgRole, err := s.organizationRole(classroom.OrganizationID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if orgRole == models.RoleAdmin {
+			role = models.RoleAdmin
+		}
+	}
+
+	switch {
+	case role == "":
+		return nil, ErrClassroomNotFound
+	case roleRank[role] < roleRank[models.RoleInstructor]:
+		return nil, ErrOrganizationForbidden
+	}
+	classroom.Role = role
+	return classroom, nil
+}
+
+// addStudent adds the user to the classroom as a student, and to its
+// organization unless they already belong to it. It reports whether the user
+// was not in the classroom before.
+func (s *organizationService) addStudent(classroom *models.Classroom, studentID, addedBy uuid.UUID) (bool, error) {
+	studentRole, err := s.role(models.RoleStudent)
+	if err != nil {
+		return false, err
+	}
+	if err := s.orgRepo.EnsureMember(classroom.OrganizationID, studentID, studentRole.ID, addedBy); err != nil {
+		return false, err
+	}
+	return s.orgRepo.AddClassroomMember(classroom.ID, studentID, studentRole.ID, addedBy)
+}
+
+func (s *organizationService) role(name string) (*models.Role, error) {
+	role, err := s.roleRepo.FindByName(name)
+	if err != nil {
+		if err.Error() == "role not found" {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// notifyInvite tells an account in the app that it was invited, with the
+// classroom's code. The invite stays listed on the roster even if the
+// notification could not be sent.
+func (s *organizationService) notifyInvite(studentID uuid.UUID, org *models.Organization, classroom *models.Classroom) {
+	err := s.notificationClient.SendClassroomInviteNotification(studentID.String(), org.Name, classroom.Name, classroom.InviteCode)
+	if err != nil {
+		log.Printf("[InviteStudents] WARNING: failed to notify %s of an invite: %v", studentID, err)
+	}
+}
+
+// sendInvite emails the classroom's code to an address without an account.
+// The invite stays listed on the roster even if the email could not be queued.
+func (s *organizationService) sendInvite(email string, org *models.Organization, classroom *models.Classroom) {
+	link := fmt.Sprintf("%s/register?email=%s&classroom_code=%s", s.frontendURL, url.QueryEscape(email), classroom.InviteCode)
+	err := s.notificationClient.SendEmail(client.SendEmailRequest{
+		To:       email,
+		Template: "classroom_invite",
+		Data: map[string]string{
+			"organization": org.Name,
+			"classroom":    classroom.Name,
+			"code":         classroom.InviteCode,
+			"link":         link,
+		},
+	})
+	if err != nil {
+		log.Printf("[InviteStudents] WARNING: failed to send invite to %s: %v", email, err)
+	}
+}
+
+// readInviteEmails returns the distinct, lower-cased emails of an invite
+// upload and records the lines it skipped in result
+func readInviteEmails(file io.Reader, result *models.ClassroomInviteResult) ([]string, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	column := 0
+	seen := make(map[string]bool)
+	var emails []string
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidInviteFile
+		}
+
+		if line == 1 {
+			if header := emailColumn(record); header >= 0 {
+				column = header
+				continue
+			}
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if column >= len(record) {
+			result.Invalid = append(result.Invalid, models.InvalidInviteLine{Line: line, Reason: "missing email"})
+			continue
+		}
+
+		value := strings.TrimSpace(record[column])
+		email := strings.ToLower(value)
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			result.Invalid = append(result.Invalid, models.InvalidInviteLine{Line: line, Value: value, Reason: "invalid email"})
+			continue
+		}
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+		emails = append(emails, email)
+		if len(emails) > maxInviteEmails {
+			return nil, ErrTooManyInvites
+		}
+	}
+
+	return emails, nil
+}
+
+// emailColumn returns the index of the "email" header, or -1 if the first
+// line holds data
+func emailColumn(record []string) int {
+	for i, field := range record {
+		if strings.EqualFold(strings.TrimSpace(field), "email") {
+			return i
+		}
+	}
+	return -1
+}
+
+func generateInviteCode() string {
+	code := make([]byte, inviteCodeLength)
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(inviteCodeAlphabet))))
+		code[i] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
+
+// hideInviteCode clears the code from a classroom shown to a student
+func hideInviteCode(classroom models.Classroom) models.Classroom {
+	if classroom.Role != models.RoleInstructor {
+		classroom.InviteCode = ""
+	}
+	return classroom
+}
+
+func studentProgress(member models.ClassroomMember, summary client.ProgressSummary) models.StudentProgress {
+	progress := models.StudentProgress{
+		UserID:                  member.UserID,
+		Email:                   member.Email,
+		FullName:                summary.FullName,
+		TotalStudyHours:         summary.TotalStudyHours,
+		TotalLessonsCompleted:   summary.TotalLessonsCompleted,
+		TotalExercisesCompleted: summary.TotalExercisesCompleted,
+		OverallScore:            summary.OverallScore,
+		CurrentStreakDays:       summary.CurrentStreakDays,
+		LastStudyDate:           summary.LastStudyDate,
+		Skills:                  make(map[string]models.SkillProgress, len(summary.Skills)),
+	}
+	for skill, s := range summary.Skills {
+		progress.Skills[skill] = models.SkillProgress{
+			Progress:         s.Progress,
+			BandScore:        s.BandScore,
+			TotalPractices:   s.TotalPractices,
+			AverageScore:     s.AverageScore,
+			BestScore:        s.BestScore,
+			TotalTimeMinutes: s.TotalTimeMinutes,
+			LastPracticeDate: s.LastPracticeDate,
+		}
+	}
+	return progress
+}
+
+// summarizeProgress averages the students' progress; band averages skip
+// students without a score
+func summarizeProgress(students []models.StudentProgress) models.ClassProgressSummary {
+	summary := models.ClassProgressSummary{
+		StudentCount:      len(students),
+		AverageBandScores: make(map[string]*float64),
+	}
+	if len(students) == 0 {
+		return summary
+	}
+
+	activeSince := time.Now().AddDate(0, 0, -7)
+	var studyHours float64
+	overall := &average{}
+	skills := make(map[string]*average)
+	for _, student := range students {
+		studyHours += student.TotalStudyHours
+		if student.LastStudyDate != nil && student.LastStudyDate.After(activeSince) {
+			summary.ActiveLast7Days++
+		}
+		overall.add(student.OverallScore)
+		for skill, progress := range student.Skills {
+			if skills[skill] == nil {
+				skills[skill] = &average{}
+			}
+			skills[skill].add(progress.BandScore)
+		}
+	}
+
+	summary.AverageStudyHours = math.Round(studyHours/float64(len(students))*100) / 100
+	summary.AverageOverallScore = overall.value()
+	for skill, avg := range skills {
+		summary.AverageBandScores[skill] = avg.value()
+	}
+	return summary
+}
+
+// average is the mean of the scores added, ignoring missing ones
+type average struct {
+	sum   float64
+	count int
+}
+
+func (a *average) add(v *float64) {
+	if v != nil {
+		a.sum += *v
+		a.count++
+	}
+}
+
+func (a *average) value() *float64 {
+	if a.count == 0 {
+		return nil
+	}
+	v := math.Round(a.sum/float64(a.count)*100) / 100
+	return &v
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/google/uuid"
+)
+
+// fakeOrgRepo holds one classroom with its members and invites
+type fakeOrgRepo struct {
+	repository.OrganizationRepository
+
+	classroom *models.Classroom
+	members   map[uuid.UUID]string // user ID -> classroom role
+	invites   map[string]bool      // email -> accepted
+}
+
+func (r *fakeOrgRepo) FindByID(uuid.UUID) (*models.Organization, error) {
+	return &models.Organization{ID: r.classroom.OrganizationID, Name: "Riverside English"}, nil
+}
+
+func (r *fakeOrgRepo) FindClassroomByID(uuid.UUID) (*models.Classroom, error) {
+	copied := *r.classroom
+	return &copied, nil
+}
+
+func (r *fakeOrgRepo) FindClassroomByInviteCode(code string) (*models.Classroom, error) {
+	if !strings.EqualFold(code, r.classroom.InviteCode) {
+		return nil, errors.New("classroom not found")
+	}
+	return r.FindClassroomByID(r.classroom.ID)
+}
+
+func (r *fakeOrgRepo) FindClassroomRole(_, userID uuid.UUID) (string, error) {
+	return r.members[userID], nil
+}
+
+func (r *fakeOrgRepo) ListClassroomMembers(uuid.UUID) ([]models.ClassroomMember, error) {
+	members := []models.ClassroomMember{}
+	for id, role := range r.members {
+		members = append(members, models.ClassroomMember{UserID: id, Role: role})
+	}
+	return members, nil
+}
+
+func (r *fakeOrgRepo) EnsureMember(uuid.UUID, uuid.UUID, int, uuid.UUID) error { return nil }
+
+func (r *fakeOrgRepo) AddClassroomMember(_, userID uuid.UUID, _ int, _ uuid.UUID) (bool, error) {
+	if _, ok := r.members[userID]; ok {
+		return false, nil
+	}
+	r.members[userID] = models.RoleStudent
+	return true, nil
+}
+
+func (r *fakeOrgRepo) SetClassroomMember(uuid.UUID, uuid.UUID, int, uuid.UUID) error {
+	return errors.New("students must not be added without accepting an invite")
+}
+
+func (r *fakeOrgRepo) CreateInvites(_ uuid.UUID, emails []string, _ uuid.UUID) ([]string, error) {
+	created := []string{}
+	for _, email := range emails {
+		if _, ok := r.invites[email]; !ok {
+			r.invites[email] = false
+			created = append(created, email)
+		}
+	}
+	return created, nil
+}
+
+func (r *fakeOrgRepo) AcceptInvite(_ uuid.UUID, email string) error {
+	if _, ok := r.invites[strings.ToLower(email)]; ok {
+		r.invites[strings.ToLower(email)] = true
+	}
+	return nil
+}
+
+type orgUserRepo struct {
+	repository.UserRepository
+	users []models.User
+}
+
+func (r *orgUserRepo) FindByID(id uuid.UUID) (*models.User, error) {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			return &r.users[i], nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *orgUserRepo) FindByEmail(email string) (*models.User, error) {
+	for i := range r.users {
+		if strings.EqualFold(r.users[i].Email, email) {
+			return &r.users[i], nil
+		}
+	}
+	return nil, errors.New("user not found")
+}
+
+func (r *orgUserRepo) FindIDsByEmails(emails []string) (map[string]uuid.UUID, error) {
+	ids := make(map[string]uuid.UUID)
+	for _, email := range emails {
+		if user, err := r.FindByEmail(email); err == nil {
+			ids[email] = user.ID
+		}
+	}
+	return ids, nil
+}
+
+type orgRoleRepo struct{ repository.RoleRepository }
+
+func (orgRoleRepo) FindByName(name string) (*models.Role, error) {
+	return &models.Role{ID: roleRank[name], Name: name}, nil
+}
+
+// classroomFixture is a classroom with one teacher, one enrolled student and
+// one account that is not in it
+type classroomFixture struct {
+	service  *organizationService
+	orgs     *fakeOrgRepo
+	teacher  models.User
+	enrolled models.User
+	outsider models.User
+
+	mu       sync.Mutex
+	notified []string // user IDs sent an in-app invite
+	emailed  []string // addresses sent an invite email
+}
+
+func newClassroomFixture(t *testing.T) *classroomFixture {
+	t.Helper()
+
+	f := &classroomFixture{
+		teacher:  models.User{ID: uuid.New(), Email: "teacher@example.com"},
+		enrolled: models.User{ID: uuid.New(), Email: "enrolled@example.com"},
+		outsider: models.User{ID: uuid.New(), Email: "Outsider@example.com"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			UserID string `json:"user_id"`
+			To     string `json:"to"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		if strings.HasSuffix(r.URL.Path, "/send") {
+			f.notified = append(f.notified, req.UserID)
+		} else {
+			f.emailed = append(f.emailed, req.To)
+		}
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	f.orgs = &fakeOrgRepo{
+		classroom: &models.Classroom{ID: uuid.New(), OrganizationID: uuid.New(), Name: "IELTS 7.0", InviteCode: "K7XQ2MPA"},
+		members: map[uuid.UUID]string{
+			f.teacher.ID:  models.RoleInstructor,
+			f.enrolled.ID: models.RoleStudent,
+		},
+		invites: map[string]bool{},
+	}
+	f.service = &organizationService{
+		orgRepo:            f.orgs,
+		userRepo:           &orgUserRepo{users: []models.User{f.teacher, f.enrolled, f.outsider}},
+		roleRepo:           orgRoleRepo{},
+		auditRepo:          &fakeAuditRepo{},
+		notificationClient: client.NewNotificationServiceClient(server.URL, "test-key"),
+		frontendURL:        "https://ielts.example.com",
+	}
+	return f
+}
+
+// TestAddClassroomMemberInvitesStudents tests that a teacher can only invite
+// an existing account, which joins once it accepts with the code
+func TestAddClassroomMemberInvitesStudents(t *testing.T) {
+	f := newClassroomFixture(t)
+	classroomID := f.orgs.classroom.ID
+
+	req := &models.AddClassroomMemberRequest{Email: f.outsider.Email, Role: models.RoleStudent}
+	if err := f.service.AddClassroomMember(classroomID, f.teacher.ID, req); err != nil {
+		t.Fatalf("AddClassroomMember() error = %v", err)
+	}
+	if _, ok := f.orgs.members[f.outsider.ID]; ok {
+		t.Fatal("the student was added without accepting the invite")
+	}
+	if accepted, ok := f.orgs.invites["outsider@example.com"]; !ok || accepted {
+		t.Fatalf("invites = %v, expected a pending invite for outsider@example.com", f.orgs.invites)
+	}
+	if len(f.notified) != 1 || f.notified[0] != f.outsider.ID.String() || len(f.emailed) != 0 {
+		t.Errorf("notified %v and emailed %v, expected an in-app invite only", f.notified, f.emailed)
+	}
+
+	// Inviting again leaves the pending invite as it is
+	if err := f.service.AddClassroomMember(classroomID, f.teacher.ID, req); err != nil {
+		t.Fatalf("AddClassroomMember() again error = %v", err)
+	}
+	if len(f.notified) != 1 {
+		t.Errorf("notified %d times, expected once", len(f.notified))
+	}
+
+	if _, err := f.service.JoinClassroom(f.outsider.ID, "k7xq2mpa", "203.0.113.7", "test"); err != nil {
+		t.Fatalf("JoinClassroom() error = %v", err)
+	}
+	if f.orgs.members[f.outsider.ID] != models.RoleStudent {
+		t.Error("the student is not in the classroom after accepting")
+	}
+	if !f.orgs.invites["outsider@example.com"] {
+		t.Error("the invite was not marked accepted")
+	}
+
+	req.Email = f.enrolled.Email
+	if err := f.service.AddClassroomMember(classroomID, f.teacher.ID, req); !errors.Is(err, ErrAlreadyInClassroom) {
+		t.Errorf("AddClassroomMember() for a member error = %v, expected ErrAlreadyInClassroom", err)
+	}
+}
+
+// TestInviteStudentsDoesNotEnrol tests that an upload invites existing
+// accounts instead of adding them
+func TestInviteStudentsDoesNotEnrol(t *testing.T) {
+	f := newClassroomFixture(t)
+
+	file := strings.NewReader("email\noutsider@example.com\nenrolled@example.com\nnew@example.com\n")
+	result, err := f.service.InviteStudents(f.orgs.classroom.ID, f.teacher.ID, file)
+	if err != nil {
+		t.Fatalf("InviteStudents() error = %v", err)
+	}
+
+	if strings.Join(result.Invited, ",") != "outsider@example.com,new@example.com" {
+		t.Errorf("invited %v", result.Invited)
+	}
+	if strings.Join(result.AlreadyMembers, ",") != "enrolled@example.com" {
+		t.Errorf("already members %v", result.AlreadyMembers)
+	}
+	if _, ok := f.orgs.members[f.outsider.ID]; ok || len(f.orgs.members) != 2 {
+		t.Errorf("members = %v, expected only the teacher and the enrolled student", f.orgs.members)
+	}
+	if len(f.notified) != 1 || f.notified[0] != f.outsider.ID.String() {
+		t.Errorf("notified %v, expected the existing account", f.notified)
+	}
+	if len(f.emailed) != 1 || f.emailed[0] != "new@example.com" {
+		t.Errorf("emailed %v, expected the address without an account", f.emailed)
+	}
+}
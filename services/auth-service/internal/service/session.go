@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/revocation"
+	"github.com/google/uuid"
+)
+
+// refreshReuseGracePeriod tolerates a client refreshing twice with the same
+// token (e.g. two tabs) without treating it as theft
+const refreshReuseGracePeriod = 10 * time.Second
+
+// refreshTokenUsed answers a replay within the grace period
+func refreshTokenUsed() *models.AuthResponse {
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "INVALID_TOKEN",
+			Message: "Refresh token has already been used",
+		},
+	}
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionRevoked  = errors.New("session has been revoked")
+)
+
+// ListSessions returns the user's active sessions, marking the one the
+// request was made from
+func (s *authService) ListSessions(userID, currentSessionID uuid.UUID) ([]models.Session, error) {
+	tokens, err := s.tokenRepo.FindActiveByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.Session, 0, len(tokens))
+	for _, token := range tokens {
+		session := models.Session{
+			ID:         token.FamilyID,
+			CreatedAt:  token.CreatedAt,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID == currentSessionID,
+		}
+		if token.SessionStartedAt != nil {
+			session.CreatedAt = *token.SessionStartedAt
+		}
+		if token.UserAgent != nil {
+			session.UserAgent = *token.UserAgent
+		}
+		// Sessions created before device detection only have a user agent
+		session.DeviceName, session.DeviceType = describeDevice(session.UserAgent)
+		if token.DeviceName != nil {
+			session.DeviceName = *token.DeviceName
+		}
+		if token.DeviceType != nil {
+			session.DeviceType = *token.DeviceType
+		}
+		if token.IPAddress != nil {
+			session.IPAddress = *token.IPAddress
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out
+func (s *authService) RevokeSession(userID, sessionID uuid.UUID, ip, userAgent string) error {
+	revoked, err := s.tokenRepo.RevokeUserSession(userID, sessionID, "session_revoked")
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	s.denySessions([]uuid.UUID{sessionID})
+	s.logAudit(&userID, "session_revoke", "success", ip, userAgent, "", map[string]interface{}{
+		"session_id": sessionID.String(),
+	})
+	return nil
+}
+
+// RevokeOtherSessions signs out every session except the current one and
+// returns how many were revoked
+func (s *authService) RevokeOtherSessions(userID, currentSessionID uuid.UUID, ip, userAgent string) (int, error) {
+	ids, err := s.tokenRepo.RevokeOtherUserTokens(userID, currentSessionID, "session_revoked")
+	if err != nil {
+		return 0, err
+	}
+
+	s.denySessions(ids)
+	s.logAudit(&userID, "session_revoke_others", "success", ip, userAgent, "", map[string]interface{}{
+		"revoked": len(ids),
+	})
+	return len(ids), nil
+}
+
+// Helper functions
+
+// RevokeAllSessions signs out every session of the user, e.g. after a password change
+func (s *authService) RevokeAllSessions(userID uuid.UUID, reason string) {
+	ids, err := s.tokenRepo.RevokeAllUserTokens(userID, reason)
+	if err != nil {
+		log.Printf("⚠️ Failed to revoke sessions of user %s: %v", userID, err)
+		return
+	}
+	s.denySessions(ids)
+}
+
+// denySessions adds revoked sessions to the denylist checked by the gateway,
+// so their access tokens stop working before they expire
+func (s *authService) denySessions(ids []uuid.UUID) {
+	if len(ids) == 0 {
+		return
+	}
+
+	ttl, err := time.ParseDuration(s.config.JWTExpiry)
+	if err != nil || ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+
+	ctx := context.Background()
+	pipe := s.redisClient.Pipeline()
+	for _, id := range ids {
+		pipe.Set(ctx, revocation.SessionKey(id.String()), "1", ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("⚠️ Failed to deny %d revoked sessions: %v", len(ids), err)
+	}
+}
+
+// isSessionRevoked checks the denylist; tokens issued before sessions were
+// tracked carry no session ID and cannot be revoked early
+func (s *authService) isSessionRevoked(sessionID string) bool {
+	if sessionID == "" {
+		return false
+	}
+
+	n, err := s.redisClient.Exists(context.Background(), revocation.SessionKey(sessionID)).Result()
+	if err != nil {
+		// Fail open: the token is still signed and unexpired
+		log.Printf("⚠️ Failed to check session denylist: %v", err)
+		return false
+	}
+	return n > 0
+}
+
+// handleRefreshTokenReuse responds to a refresh token that was already rotated.
+// Outside a short grace period for clients racing themselves, replaying a used
+// token means it was copied, so the whole session is revoked and the user warned.
+func (s *authService) handleRefreshTokenReuse(token *models.RefreshToken, ip, userAgent string) *models.AuthResponse {
+	if time.Since(*token.RotatedAt) < refreshReuseGracePeriod {
+		return refreshTokenUsed()
+	}
+
+	if err := s.tokenRepo.RevokeFamily(token.FamilyID, "refresh_token_reuse"); err != nil {
+		log.Printf("❌ Failed to revoke session %s after refresh token reuse: %v", token.FamilyID, err)
+	}
+	s.denySessions([]uuid.UUID{token.FamilyID})
+
+	s.logAudit(&token.UserID, "refresh_token_reuse", "failed", ip, userAgent, "rotated refresh token presented again", map[string]interface{}{
+		"session_id": token.FamilyID.String(),
+	})
+
+	deviceName := "Unknown device"
+	if token.DeviceName != nil {
+		deviceName = *token.DeviceName
+	}
+	go func() {
+		if err := s.notificationClient.SendSessionCompromisedNotification(token.UserID.String(), deviceName); err != nil {
+			log.Printf("⚠️ Failed to send session compromised notification to user %s: %v", token.UserID, err)
+		}
+	}()
+
+	return &models.AuthResponse{
+		Success: false,
+		Error: &models.ErrorData{
+			Code:    "TOKEN_REUSED",
+			Message: "Refresh token has already been used. The session has been signed out for your security.",
+		},
+	}
+}
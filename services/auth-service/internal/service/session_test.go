@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/config"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/bisosad1501/DATN/services/auth-service/internal/repository"
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
+	"github.com/bisosad1501/DATN/shared/pkg/revocation"
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// fakeTokenRepo holds a single stored refresh token. RotateRefreshToken
+// succeeds once per token, like the conditional UPDATE it stands in for.
+type fakeTokenRepo struct {
+	repository.TokenRepository
+
+	mu              sync.Mutex
+	token           *models.RefreshToken
+	rotations       int
+	revokedFamilies map[uuid.UUID]string
+}
+
+func (r *fakeTokenRepo) FindRefreshToken(string) (*models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *r.token
+	return &copied, nil
+}
+
+func (r *fakeTokenRepo) RotateRefreshToken(oldID uuid.UUID, next *models.RefreshToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.token.ID != oldID || r.token.RotatedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	r.token.RotatedAt = &now
+	r.rotations++
+	return true, nil
+}
+
+func (r *fakeTokenRepo) RevokeFamily(familyID uuid.UUID, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revokedFamilies[familyID] = reason
+	return nil
+}
+
+type fakeAuditRepo struct {
+	repository.AuditLogRepository
+
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *fakeAuditRepo) Create(log *models.AuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, log.EventType)
+	return nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	user *models.User
+}
+
+func (r *fakeUserRepo) FindByID(uuid.UUID) (*models.User, error) { return r.user, nil }
+
+type fakeRoleRepo struct{ repository.RoleRepository }
+
+func (fakeRoleRepo) FindByUserID(uuid.UUID) ([]models.Role, error) {
+	return []models.Role{{Name: "student"}}, nil
+}
+
+func (fakeRoleRepo) FindPermissionNamesByRole(string) ([]string, error) { return nil, nil }
+
+type fakeKeyManager struct{ KeyManager }
+
+func (fakeKeyManager) Sign(jwt.Claims) (string, error) { return "access-token", nil }
+func (fakeKeyManager) JWKS() jwks.Set                  { return jwks.Set{} }
+
+// errRedisStubbed stops recorded commands from reaching a server
+var errRedisStubbed = errors.New("redis stubbed in tests")
+
+// redisRecorder is a hook recording the keys the service sets, without a server
+type redisRecorder struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (h *redisRecorder) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return h.BeforeProcessPipeline(ctx, []redis.Cmder{cmd})
+}
+
+func (h *redisRecorder) AfterProcess(context.Context, redis.Cmder) error { return nil }
+
+func (h *redisRecorder) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, cmd := range cmds {
+		if cmd.Name() == "set" {
+			h.keys = append(h.keys, cmd.Args()[1].(string))
+		}
+	}
+	return ctx, errRedisStubbed
+}
+
+func (h *redisRecorder) AfterProcessPipeline(context.Context, []redis.Cmder) error { return nil }
+
+// refreshFixture is an auth service around one stored refresh token
+type refreshFixture struct {
+	service       *authService
+	tokens        *fakeTokenRepo
+	audit         *fakeAuditRepo
+	redis         *redisRecorder
+	notifications chan string
+}
+
+func newRefreshFixture(t *testing.T, rotatedAgo *time.Duration) *refreshFixture {
+	t.Helper()
+
+	notifications := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.SendNotificationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		notifications <- req.UserID
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	recorder := &redisRecorder{}
+	redisClient := redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})
+	redisClient.AddHook(recorder)
+	t.Cleanup(func() { redisClient.Close() })
+
+	userID := uuid.New()
+	deviceName := "Chrome on Windows"
+	token := &models.RefreshToken{
+		ID:         uuid.New(),
+		UserID:     userID,
+		FamilyID:   uuid.New(),
+		DeviceName: &deviceName,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	if rotatedAgo != nil {
+		rotatedAt := time.Now().Add(-*rotatedAgo)
+		token.RotatedAt = &rotatedAt
+	}
+
+	f := &refreshFixture{
+		tokens:        &fakeTokenRepo{token: token, revokedFamilies: map[uuid.UUID]string{}},
+		audit:         &fakeAuditRepo{},
+		redis:         recorder,
+		notifications: notifications,
+	}
+	f.service = &authService{
+		userRepo:           &fakeUserRepo{user: &models.User{ID: userID, Email: "learner@example.com"}},
+		roleRepo:           fakeRoleRepo{},
+		tokenRepo:          f.tokens,
+		auditRepo:          f.audit,
+		redisClient:        redisClient,
+		keys:               fakeKeyManager{},
+		config:             &config.Config{JWTExpiry: "15m", RefreshTokenExpiry: "168h"},
+		notificationClient: client.NewNotificationServiceClient(server.URL, "test-key"),
+	}
+	return f
+}
+
+// refresh presents the stored token; it is safe to call from several goroutines
+func (f *refreshFixture) refresh(t *testing.T) *models.AuthResponse {
+	resp, err := f.service.RefreshToken(&models.RefreshTokenRequest{RefreshToken: "presented"}, "203.0.113.7", "test")
+	if err != nil {
+		t.Errorf("RefreshToken() error = %v", err)
+		return &models.AuthResponse{Error: &models.ErrorData{Code: "ERROR"}}
+	}
+	return resp
+}
+
+func errorCode(resp *models.AuthResponse) string {
+	if resp.Error == nil {
+		return ""
+	}
+	return resp.Error.Code
+}
+
+// TestRefreshTokenReplayWithinGracePeriod tests that a client racing itself
+// is told the token was used without its session being revoked
+func TestRefreshTokenReplayWithinGracePeriod(t *testing.T) {
+	rotatedAgo := 2 * time.Second
+	f := newRefreshFixture(t, &rotatedAgo)
+
+	resp := f.refresh(t)
+	if resp.Success || errorCode(resp) != "INVALID_TOKEN" {
+		t.Fatalf("RefreshToken() = %+v, expected INVALID_TOKEN", resp.Error)
+	}
+	if len(f.tokens.revokedFamilies) != 0 {
+		t.Errorf("revoked %v, expected no revocation", f.tokens.revokedFamilies)
+	}
+	if len(f.redis.keys) != 0 {
+		t.Errorf("denied sessions %v, expected none", f.redis.keys)
+	}
+	if len(f.audit.events) != 0 {
+		t.Errorf("audited %v, expected nothing", f.audit.events)
+	}
+}
+
+// TestRefreshTokenReuseRevokesSession tests that replaying a rotated token
+// after the grace period revokes the whole session, denies its access
+// tokens, audits the reuse and warns the user
+func TestRefreshTokenReuseRevokesSession(t *testing.T) {
+	rotatedAgo := time.Minute
+	f := newRefreshFixture(t, &rotatedAgo)
+	token := f.tokens.token
+
+	resp := f.refresh(t)
+	if resp.Success || errorCode(resp) != "TOKEN_REUSED" {
+		t.Fatalf("RefreshToken() = %+v, expected TOKEN_REUSED", resp.Error)
+	}
+	if reason := f.tokens.revokedFamilies[token.FamilyID]; reason != "refresh_token_reuse" {
+		t.Errorf("session revoked with reason %q, expected refresh_token_reuse", reason)
+	}
+	if want := revocation.SessionKey(token.FamilyID.String()); len(f.redis.keys) != 1 || f.redis.keys[0] != want {
+		t.Errorf("denied sessions %v, expected [%s]", f.redis.keys, want)
+	}
+	if len(f.audit.events) != 1 || f.audit.events[0] != "refresh_token_reuse" {
+		t.Errorf("audited %v, expected [refresh_token_reuse]", f.audit.events)
+	}
+
+	select {
+	case userID := <-f.notifications:
+		if userID != token.UserID.String() {
+			t.Errorf("notified user %s, expected %s", userID, token.UserID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the user was not notified")
+	}
+}
+
+// TestRefreshTokenConcurrentUse tests that when two requests refresh with the
+// same token at once, one gets new tokens and the other INVALID_TOKEN,
+// without the session being treated as stolen
+func TestRefreshTokenConcurrentUse(t *testing.T) {
+	f := newRefreshFixture(t, nil)
+
+	const requests = 8
+	responses := make(chan *models.AuthResponse, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses <- f.refresh(t)
+		}()
+	}
+	wg.Wait()
+	close(responses)
+
+	succeeded := 0
+	for resp := range responses {
+		switch {
+		case resp.Success:
+			succeeded++
+			if resp.Data.RefreshToken == "" || resp.Data.AccessToken == "" {
+				t.Errorf("successful refresh returned %+v", resp.Data)
+			}
+		case errorCode(resp) == "INVALID_TOKEN":
+			// Lost the rotation race, or found the token already rotated
+			// within the grace period
+		default:
+			t.Errorf("unexpected error %s", errorCode(resp))
+		}
+	}
+
+	if succeeded != 1 || f.tokens.rotations != 1 {
+		t.Errorf("%d refreshes succeeded with %d rotations, expected exactly one", succeeded, f.tokens.rotations)
+	}
+	if len(f.tokens.revokedFamilies) != 0 || len(f.redis.keys) != 0 {
+		t.Errorf("revoked %v and denied %v, expected no revocation", f.tokens.revokedFamilies, f.redis.keys)
+	}
+}
+
+// TestRefreshTokenRotationLost tests the answer when the token is rotated
+// between being read and being replaced
+func TestRefreshTokenRotationLost(t *testing.T) {
+	f := newRefreshFixture(t, nil)
+	// Another replica rotates the token after this request has read it
+	f.service.tokenRepo = &staleTokenRepo{fakeTokenRepo: f.tokens, stale: uuid.New()}
+
+	resp := f.refresh(t)
+	if resp.Success || errorCode(resp) != "INVALID_TOKEN" {
+		t.Fatalf("RefreshToken() = %+v, expected INVALID_TOKEN", resp.Error)
+	}
+	if len(f.tokens.revokedFamilies) != 0 {
+		t.Errorf("revoked %v, expected no revocation", f.tokens.revokedFamilies)
+	}
+}
+
+// staleTokenRepo returns a token whose ID no longer matches the stored one,
+// so RotateRefreshToken reports it as already rotated
+type staleTokenRepo struct {
+	*fakeTokenRepo
+	stale uuid.UUID
+}
+
+func (r *staleTokenRepo) FindRefreshToken(hash string) (*models.RefreshToken, error) {
+	token, err := r.fakeTokenRepo.FindRefreshToken(hash)
+	if err != nil {
+		return nil, err
+	}
+	token.ID = r.stale
+	return token, nil
+}
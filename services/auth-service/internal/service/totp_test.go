@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of the RFC 6238 test vectors, "12345678901234567890"
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+// TestTOTPCode tests the RFC 6238 appendix B SHA-1 vectors, truncated to six digits
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix     int64
+		expected string // last six digits of the RFC's eight-digit codes
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("totpCode() error = %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("totpCode(T=%d) = %s, expected %s", tt.unix, code, tt.expected)
+		}
+	}
+
+	if lower, _ := totpCode(strings.ToLower(rfc6238Secret), 1); lower != mustTOTPCode(t, 1) {
+		t.Error("lower-case secrets should give the same codes")
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
+
+func mustTOTPCode(t *testing.T, step int64) string {
+	t.Helper()
+	code, err := totpCode(rfc6238Secret, step)
+	if err != nil {
+		t.Fatalf("totpCode() error = %v", err)
+	}
+	return code
+}
+
+// TestValidateTOTPSkew tests that codes one step either side of now are
+// accepted and report their own step, which replay protection records
+func TestValidateTOTPSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name   string
+		offset int64 // steps from the current one
+		valid  bool
+	}{
+		{"two steps early", -2, false},
+		{"previous step", -1, true},
+		{"current step", 0, true},
+		{"next step", 1, true},
+		{"two steps late", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := mustTOTPCode(t, current+tt.offset)
+			step, ok := validateTOTP(rfc6238Secret, code, now)
+			if ok != tt.valid {
+				t.Fatalf("validateTOTP() ok = %v, expected %v", ok, tt.valid)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("validateTOTP() step = %d, expected %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+// TestValidateTOTPInput tests codes that must never validate
+func TestValidateTOTPInput(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	code := mustTOTPCode(t, now.Unix()/totpPeriod)
+
+	if _, ok := validateTOTP(rfc6238Secret, " "+code+"\n", now); !ok {
+		t.Error("surrounding whitespace should be ignored")
+	}
+	for _, input := range []string{"", code[:5], code + "0", "abcdef"} {
+		if _, ok := validateTOTP(rfc6238Secret, input, now); ok {
+			t.Errorf("validateTOTP(%q) accepted", input)
+		}
+	}
+	if _, ok := validateTOTP("not base32!", code, now); ok {
+		t.Error("an invalid secret should reject every code")
+	}
+}
+
+// TestTOTPProvisioningURI tests the otpauth URI authenticator apps scan
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("IELTS Platform", "learner@example.com", rfc6238Secret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("invalid URI %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("URI %q is not an otpauth://totp URI", uri)
+	}
+	if strings.Contains(uri, "+") {
+		t.Errorf("URI %q encodes spaces as +", uri)
+	}
+	q := parsed.Query()
+	if q.Get("secret") != rfc6238Secret || q.Get("issuer") != "IELTS Platform" || q.Get("digits") != "6" || q.Get("period") != "30" {
+		t.Errorf("URI parameters = %v", q)
+	}
+}
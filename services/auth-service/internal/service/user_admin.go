@@ -0,0 +1,283 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/auth-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// defaultImpersonationDuration is used when IMPERSONATION_MAX_DURATION is unset or invalid
+const defaultImpersonationDuration = 30 * time.Minute
+
+var (
+	ErrCannotModifySelf  = errors.New("admins cannot do this to their own account")
+	ErrUserSuspended     = errors.New("account is already suspended")
+	ErrUserNotSuspended  = errors.New("account is not suspended")
+	ErrCannotImpersonate = errors.New("this account cannot be impersonated")
+)
+
+// SearchUsers returns a page of users matching the filter, with their roles
+func (s *authService) SearchUsers(filter models.UserFilter, page, limit int) (*models.AdminUserList, error) {
+	users, total, err := s.userRepo.Search(filter, limit, (page-1)*limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	roles, err := s.roleRepo.FindByUserIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &models.AdminUserList{
+		Users: make([]models.AdminUser, len(users)),
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}
+	for i, user := range users {
+		list.Users[i] = adminUser(user, roles[user.ID])
+	}
+
+	return list, nil
+}
+
+// GetUser returns one user with their roles
+func (s *authService) GetUser(userID uuid.UUID) (*models.AdminUser, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	view := adminUser(*user, roles)
+	return &view, nil
+}
+
+// SuspendUser deactivates an account and signs every session out
+func (s *authService) SuspendUser(userID, adminID uuid.UUID, reason, ip, userAgent string) error {
+	if userID == adminID {
+		return ErrCannotModifySelf
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if !user.IsActive {
+		return ErrUserSuspended
+	}
+
+	if err := s.userRepo.SetActive(userID, false); err != nil {
+		return err
+	}
+	s.RevokeAllSessions(userID, "account_suspended")
+
+	s.logAudit(&userID, "account_suspended", "success", ip, userAgent, "", map[string]interface{}{
+		"suspended_by": adminID.String(),
+		"reason":       reason,
+	})
+	return nil
+}
+
+// ReactivateUser lets a suspended account sign in again
+func (s *authService) ReactivateUser(userID, adminID uuid.UUID, ip, userAgent string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+	if user.IsActive {
+		return ErrUserNotSuspended
+	}
+
+	if err := s.userRepo.SetActive(userID, true); err != nil {
+		return err
+	}
+
+	s.logAudit(&userID, "account_reactivated", "success", ip, userAgent, "", map[string]interface{}{
+		"reactivated_by": adminID.String(),
+	})
+	return nil
+}
+
+// ChangeUserRoles assigns or revokes a role for several users. A granted role
+// shows up in the user's next access token; users losing a role are signed
+// out so their current tokens stop carrying it.
+func (s *authService) ChangeUserRoles(req *models.BulkRoleChangeRequest, adminID uuid.UUID, ip, userAgent string) (*models.BulkRoleChangeResult, error) {
+	role, err := s.roleRepo.FindByName(req.Role)
+	if err != nil {
+		if err.Error() == "role not found" {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, 0, len(req.UserIDs))
+	for _, raw := range req.UserIDs {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, ErrUserNotFound
+		}
+		// Admins keep their own admin role so the last one cannot lock everyone out
+		if req.Action == "revoke" && role.Name == "admin" && id == adminID {
+			return nil, ErrCannotModifySelf
+		}
+		userIDs = append(userIDs, id)
+	}
+
+	var changed []uuid.UUID
+	eventType := "role_assigned"
+	if req.Action == "revoke" {
+		eventType = "role_revoked"
+		changed, err = s.roleRepo.RemoveRoleFromUsers(userIDs, role.ID)
+	} else {
+		changed, err = s.roleRepo.AssignRoleToUsers(userIDs, role.ID, adminID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	isChanged := make(map[uuid.UUID]bool, len(changed))
+	for _, id := range changed {
+		isChanged[id] = true
+		if req.Action == "revoke" {
+			s.RevokeAllSessions(id, "role_revoked")
+		}
+		userID := id
+		s.logAudit(&userID, eventType, "success", ip, userAgent, "", map[string]interface{}{
+			"role":       role.Name,
+			"changed_by": adminID.String(),
+		})
+	}
+
+	result := &models.BulkRoleChangeResult{
+		Changed:   changed,
+		Unchanged: []uuid.UUID{},
+	}
+	for _, id := range userIDs {
+		if !isChanged[id] {
+			result.Unchanged = append(result.Unchanged, id)
+		}
+	}
+
+	return result, nil
+}
+
+// Impersonate issues a short-lived access token for the user with the admin
+// in its act claim. It has its own session ID, so EndImpersonation can revoke
+// it, and no refresh token.
+func (s *authService) Impersonate(userID, adminID uuid.UUID, req *models.ImpersonateRequest, ip, userAgent string) (*models.ImpersonationData, error) {
+	if userID == adminID {
+		return nil, ErrCannotModifySelf
+	}
+
+	admin, err := s.findUser(adminID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrCannotImpersonate
+	}
+
+	roles, err := s.roleRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	// Roles are ordered by privilege, so an admin is always roles[0]
+	if len(roles) == 0 || roles[0].Name == "admin" {
+		return nil, ErrCannotImpersonate
+	}
+	role := roles[0].Name
+
+	maxDuration, err := time.ParseDuration(s.config.ImpersonationMaxDuration)
+	if err != nil || maxDuration <= 0 {
+		maxDuration = defaultImpersonationDuration
+	}
+	duration := maxDuration
+	if requested := time.Duration(req.DurationMinutes) * time.Minute; requested > 0 && requested < maxDuration {
+		duration = requested
+	}
+
+	sessionID := uuid.New()
+	actor := &ActorClaim{Subject: adminID.String(), Email: admin.Email}
+	accessToken, expiresIn, err := s.signToken(user.ID, user.Email, role, sessionID, duration, actor)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(duration)
+
+	// Recorded on both accounts so either one's history shows it
+	s.logAudit(&adminID, "impersonation_started", "success", ip, userAgent, "", map[string]interface{}{
+		"target_user_id": userID.String(),
+		"session_id":     sessionID.String(),
+		"reason":         req.Reason,
+		"expires_at":     expiresAt,
+	})
+	s.logAudit(&userID, "impersonated", "success", ip, userAgent, "", map[string]interface{}{
+		"impersonator_id": adminID.String(),
+		"session_id":      sessionID.String(),
+		"reason":          req.Reason,
+		"expires_at":      expiresAt,
+	})
+
+	return &models.ImpersonationData{
+		AccessToken: accessToken,
+		ExpiresIn:   expiresIn,
+		ExpiresAt:   expiresAt,
+		SessionID:   sessionID,
+		UserID:      user.ID,
+		Email:       user.Email,
+		Role:        role,
+	}, nil
+}
+
+// EndImpersonation revokes an impersonation token before it expires
+func (s *authService) EndImpersonation(userID, adminID, sessionID uuid.UUID, ip, userAgent string) error {
+	s.denySessions([]uuid.UUID{sessionID})
+
+	s.logAudit(&adminID, "impersonation_ended", "success", ip, userAgent, "", map[string]interface{}{
+		"target_user_id": userID.String(),
+		"session_id":     sessionID.String(),
+	})
+	return nil
+}
+
+// findUser maps a missing user to ErrUserNotFound
+func (s *authService) findUser(userID uuid.UUID) (*models.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func adminUser(user models.User, roles []models.Role) models.AdminUser {
+	view := models.AdminUser{
+		User:        user,
+		Roles:       make([]string, len(roles)),
+		LockedUntil: user.LockedUntil,
+	}
+	for i, role := range roles {
+		view.Roles[i] = role.Name
+	}
+	if view.LockedUntil != nil && view.LockedUntil.Before(time.Now()) {
+		view.LockedUntil = nil
+	}
+	return view
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/bisosad1501/ielts-platform/course-service/internal/config"
 	"github.com/bisosad1501/ielts-platform/course-service/internal/database"
 	"github.com/bisosad1501/ielts-platform/course-service/internal/handlers"
@@ -20,6 +23,13 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "course-service")
+	if err != nil {
+		log.Printf("⚠️  Tracing disabled: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Connect to database
 	db, err := database.NewDatabase(cfg)
 	if err != nil {
@@ -35,6 +45,7 @@ func main() {
 	userServiceClient := client.NewUserServiceClient(cfg.UserServiceURL, cfg.InternalAPIKey)
 	notificationClient := client.NewNotificationServiceClient(cfg.NotificationServiceURL, cfg.InternalAPIKey)
 	exerciseClient := client.NewExerciseServiceClient(cfg.ExerciseServiceURL, cfg.InternalAPIKey)
+	authClient := client.NewAuthServiceClient(cfg.AuthServiceURL, cfg.InternalAPIKey)
 	log.Println("✅ Service clients initialized")
 
 	// Initialize YouTube service
@@ -47,7 +58,7 @@ func main() {
 	}
 
 	// Initialize service
-	svc := service.NewCourseService(repo, userServiceClient, notificationClient, exerciseClient, youtubeService)
+	svc := service.NewCourseService(repo, userServiceClient, notificationClient, exerciseClient, youtubeService, authClient)
 	log.Println("✅ Service initialized")
 
 	// Initialize and start video sync service
@@ -72,6 +83,8 @@ func main() {
 	// Setup Gin router
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
+	router.Use(tracing.Middleware("course-service"), tracing.RequestID(), metrics.Middleware("course-service"))
+	router.GET("/metrics", metrics.Handler())
 
 	// Setup routes
 	routes.SetupRoutes(router, handler, authMiddleware)
@@ -12,12 +12,13 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
-	JWTSecret  string
+	JWKSURL    string
 
 	// Service-to-Service Communication
 	UserServiceURL         string
 	NotificationServiceURL string
 	ExerciseServiceURL     string
+	AuthServiceURL         string
 	InternalAPIKey         string
 }
 
@@ -29,12 +30,13 @@ func LoadConfig() *Config {
 		DBUser:     getEnv("DB_USER", "ielts_admin"),
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "course_db"),
-		JWTSecret:  getEnv("JWT_SECRET", ""),
+		JWKSURL:    getEnv("JWKS_URL", "http://auth-service:8081/.well-known/jwks.json"),
 
 		// Service URLs for internal communication
 		UserServiceURL:         getEnv("USER_SERVICE_URL", "http://user-service:8082"),
 		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://notification-service:8085"),
 		ExerciseServiceURL:     getEnv("EXERCISE_SERVICE_URL", "http://exercise-service:8084"),
+		AuthServiceURL:         getEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
 		InternalAPIKey:         getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
 	}
 
@@ -42,10 +44,6 @@ func LoadConfig() *Config {
 		log.Fatal("❌ DB_PASSWORD is required")
 	}
 
-	if config.JWTSecret == "" {
-		log.Fatal("❌ JWT_SECRET is required")
-	}
-
 	log.Println("✅ Configuration loaded successfully")
 	return config
 }
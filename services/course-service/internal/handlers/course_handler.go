@@ -737,7 +737,11 @@ func (h *CourseHandler) DeleteCourse(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteCourse(courseID)
+	userIDVal, _ := c.Get("user_id")
+	userIDStr, _ := userIDVal.(string)
+	userID, _ := uuid.Parse(userIDStr)
+
+	err = h.service.DeleteCourse(courseID, userID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, Response{
 			Success: false,
@@ -1584,3 +1588,103 @@ func (h *CourseHandler) ForceResyncAllVideos(c *gin.Context) {
 		},
 	})
 }
+
+// GetLessonProgressBatch returns lessons and several users' progress in them
+// (internal, called by Exercise Service for classroom assignments)
+func (h *CourseHandler) GetLessonProgressBatch(c *gin.Context) {
+	var req models.LessonProgressBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	batch, err := h.service.GetLessonProgressBatch(&req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "GET_PROGRESS_FAILED",
+				Message: "Failed to get lesson progress",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    batch,
+	})
+}
+
+// ExportUserData returns everything stored about a user (internal, called by Auth Service for data exports)
+func (h *CourseHandler) ExportUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	data, err := h.service.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "EXPORT_FAILED",
+				Message: "Failed to export user data",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// EraseUserData deletes everything stored about a user (internal, called by Auth Service for account deletion)
+func (h *CourseHandler) EraseUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	if err := h.service.EraseUserData(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "ERASE_FAILED",
+				Message: "Failed to erase user data",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Message: "User data erased",
+	})
+}
@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // CreateCourseRequest represents course creation request
 type CreateCourseRequest struct {
@@ -218,3 +222,34 @@ type AddVideoToLessonRequest struct {
 	FileSize        *int64  `json:"file_size"`                         // File size in bytes
 	DisplayOrder    *int    `json:"display_order"`                     // Order in lesson
 }
+
+// LessonProgressBatchRequest asks for the progress of several users in several lessons
+type LessonProgressBatchRequest struct {
+	LessonIDs []uuid.UUID `json:"lesson_ids" binding:"required,min=1,max=200"`
+	UserIDs   []uuid.UUID `json:"user_ids" binding:"max=500"` // may be empty to only look the lessons up
+}
+
+// LessonProgressBatch holds the lessons that exist among those asked for and
+// the progress rows of the users in them; users who never opened a lesson
+// have no row
+type LessonProgressBatch struct {
+	Lessons  []LessonSummary        `json:"lessons"`
+	Progress []LessonProgressStatus `json:"progress"`
+}
+
+// LessonSummary identifies a lesson and its course
+type LessonSummary struct {
+	ID          uuid.UUID `json:"id"`
+	CourseID    uuid.UUID `json:"course_id"`
+	Title       string    `json:"title"`
+	IsPublished bool      `json:"is_published"`
+}
+
+// LessonProgressStatus is one user's progress in one lesson
+type LessonProgressStatus struct {
+	UserID             uuid.UUID  `json:"user_id"`
+	LessonID           uuid.UUID  `json:"lesson_id"`
+	Status             string     `json:"status"` // not_started, in_progress, completed
+	ProgressPercentage float64    `json:"progress_percentage"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/bisosad1501/ielts-platform/course-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetLessonProgressBatch returns the lessons among lessonIDs and the
+// progress of userIDs in them, used by exercise-service for classroom
+// assignments
+func (r *CourseRepository) GetLessonProgressBatch(lessonIDs, userIDs []uuid.UUID) (*models.LessonProgressBatch, error) {
+	lessons := uuidArray(lessonIDs)
+	batch := &models.LessonProgressBatch{
+		Lessons:  []models.LessonSummary{},
+		Progress: []models.LessonProgressStatus{},
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, course_id, title, is_published
+		FROM lessons
+		WHERE id = ANY($1::uuid[])
+	`, lessons)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lessons: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l models.LessonSummary
+		if err := rows.Scan(&l.ID, &l.CourseID, &l.Title, &l.IsPublished); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson: %w", err)
+		}
+		batch.Lessons = append(batch.Lessons, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get lessons: %w", err)
+	}
+
+	if len(userIDs) == 0 {
+		return batch, nil
+	}
+
+	progressRows, err := r.db.Query(`
+		SELECT user_id, lesson_id, COALESCE(status, 'not_started'),
+		       COALESCE(progress_percentage, 0), completed_at
+		FROM lesson_progress
+		WHERE lesson_id = ANY($1::uuid[]) AND user_id = ANY($2::uuid[])
+	`, lessons, uuidArray(userIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lesson progress: %w", err)
+	}
+	defer progressRows.Close()
+
+	for progressRows.Next() {
+		var p models.LessonProgressStatus
+		if err := progressRows.Scan(&p.UserID, &p.LessonID, &p.Status, &p.ProgressPercentage, &p.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson progress: %w", err)
+		}
+		batch.Progress = append(batch.Progress, p)
+	}
+	if err := progressRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get lesson progress: %w", err)
+	}
+
+	return batch, nil
+}
+
+func uuidArray(ids []uuid.UUID) pq.StringArray {
+	arr := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		arr[i] = id.String()
+	}
+	return arr
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bisosad1501/DATN/shared/pkg/personaldata"
+	"github.com/google/uuid"
+)
+
+// personalDataTables are the tables holding a learner's rows. Courses an
+// instructor authored are content, not personal data, and are kept.
+var personalDataTables = []personaldata.Table{
+	personaldata.UserTable("course_enrollments"),
+	personaldata.UserTable("lesson_progress"),
+	personaldata.UserTable("video_watch_history"),
+	personaldata.UserTable("course_reviews"),
+}
+
+// ExportUserData returns every row held about the user, keyed by table
+func (r *CourseRepository) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return personaldata.Export(ctx, r.db, userID.String(), personalDataTables)
+}
+
+// EraseUserData deletes the user's rows. Enrollment counts follow through
+// their trigger; ratings are recomputed here because the rating trigger
+// does not fire on delete.
+func (r *CourseRepository) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM course_reviews WHERE user_id = $1 RETURNING course_id`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reviews: %w", err)
+	}
+	var reviewedCourses []uuid.UUID
+	for rows.Next() {
+		var courseID uuid.UUID
+		if err := rows.Scan(&courseID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reviewed course: %w", err)
+		}
+		reviewedCourses = append(reviewedCourses, courseID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to delete reviews: %w", err)
+	}
+
+	for _, courseID := range reviewedCourses {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE courses SET
+				average_rating = (SELECT COALESCE(AVG(rating), 0) FROM course_reviews WHERE course_id = $1 AND is_approved = true),
+				total_reviews = (SELECT COUNT(*) FROM course_reviews WHERE course_id = $1 AND is_approved = true)
+			WHERE id = $1`, courseID)
+		if err != nil {
+			return fmt.Errorf("failed to update rating of course %s: %w", courseID, err)
+		}
+	}
+
+	for _, table := range []string{"video_watch_history", "lesson_progress", "course_enrollments"} {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1`, table), userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	return tx.Commit()
+}
@@ -34,6 +34,18 @@ func SetupRoutes(
 			coursesProtected.GET("/:id/progress", handler.GetCourseProgress) // Get all lesson progress for course
 		}
 
+		// Internal endpoints (service-to-service, require API key)
+		internal := v1.Group("/courses/internal")
+		internal.Use(authMiddleware.InternalAuth())
+		{
+			// Personal data export and erasure (account deletion)
+			internal.GET("/users/:user_id/data", handler.ExportUserData)
+			internal.DELETE("/users/:user_id/data", handler.EraseUserData)
+
+			// Lesson progress of classroom students (assignments)
+			internal.POST("/lessons/progress", handler.GetLessonProgressBatch)
+		}
+
 		// Public lesson endpoints
 		lessons := v1.Group("/lessons")
 		lessons.Use(authMiddleware.OptionalAuth())
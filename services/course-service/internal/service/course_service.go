@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"math"
@@ -18,15 +20,17 @@ type CourseService struct {
 	notificationClient *client.NotificationServiceClient
 	exerciseClient     *client.ExerciseServiceClient
 	youtubeService     *YouTubeService
+	authClient         *client.AuthServiceClient
 }
 
-func NewCourseService(repo *repository.CourseRepository, userServiceClient *client.UserServiceClient, notificationClient *client.NotificationServiceClient, exerciseClient *client.ExerciseServiceClient, youtubeService *YouTubeService) *CourseService {
+func NewCourseService(repo *repository.CourseRepository, userServiceClient *client.UserServiceClient, notificationClient *client.NotificationServiceClient, exerciseClient *client.ExerciseServiceClient, youtubeService *YouTubeService, authClient *client.AuthServiceClient) *CourseService {
 	return &CourseService{
 		repo:               repo,
 		userServiceClient:  userServiceClient,
 		notificationClient: notificationClient,
 		exerciseClient:     exerciseClient,
 		youtubeService:     youtubeService,
+		authClient:         authClient,
 	}
 }
 
@@ -760,8 +764,12 @@ func (s *CourseService) UpdateCourse(courseID uuid.UUID, userID uuid.UUID, userR
 }
 
 // DeleteCourse deletes a course (Admin only)
-func (s *CourseService) DeleteCourse(courseID uuid.UUID) error {
-	return s.repo.DeleteCourse(courseID)
+func (s *CourseService) DeleteCourse(courseID, userID uuid.UUID) error {
+	if err := s.repo.DeleteCourse(courseID); err != nil {
+		return err
+	}
+	s.recordAudit(userID, "course_deleted", courseID)
+	return nil
 }
 
 // CreateModule creates a new module (Admin/Instructor with ownership check)
@@ -896,7 +904,30 @@ func (s *CourseService) PublishCourse(courseID uuid.UUID, userID uuid.UUID, user
 		}
 	}
 
-	return s.repo.PublishCourse(courseID)
+	if err := s.repo.PublishCourse(courseID); err != nil {
+		return err
+	}
+	s.recordAudit(userID, "course_published", courseID)
+	return nil
+}
+
+// recordAudit reports a course change to the auth service audit log without
+// holding up the request
+func (s *CourseService) recordAudit(userID uuid.UUID, eventType string, courseID uuid.UUID) {
+	go func() {
+		err := s.authClient.RecordAuditEvent(client.AuditEventRequest{
+			UserID:      userID.String(),
+			EventType:   eventType,
+			EventStatus: "success",
+			Metadata: map[string]interface{}{
+				"service":   "course-service",
+				"course_id": courseID.String(),
+			},
+		})
+		if err != nil {
+			log.Printf("[Course-Service] WARNING: Failed to record %s audit event: %v", eventType, err)
+		}
+	}()
 }
 
 // ============================================
@@ -1310,3 +1341,22 @@ func (s *CourseService) SyncYouTubeVideoDuration(videoID string) error {
 	log.Printf("✅ Synced duration for video %s: %d seconds", videoID, details.Duration)
 	return nil
 }
+
+// GetLessonProgressBatch returns lessons and the users' progress in them
+func (s *CourseService) GetLessonProgressBatch(req *models.LessonProgressBatchRequest) (*models.LessonProgressBatch, error) {
+	return s.repo.GetLessonProgressBatch(req.LessonIDs, req.UserIDs)
+}
+
+// ExportUserData returns everything stored about the user, for the personal data export
+func (s *CourseService) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return s.repo.ExportUserData(ctx, userID)
+}
+
+// EraseUserData deletes everything stored about the user when their account is deleted
+func (s *CourseService) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.EraseUserData(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("✅ Erased course data of user %s", userID)
+	return nil
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	aiClient "github.com/bisosad1501/ielts-platform/exercise-service/internal/client"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/config"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/database"
@@ -19,6 +22,13 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "exercise-service")
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Connect to database
 	db, err := database.Connect(cfg)
 	if err != nil {
@@ -33,11 +43,13 @@ func main() {
 	notificationClient := client.NewNotificationServiceClient(cfg.NotificationServiceURL, cfg.InternalAPIKey)
 	aiServiceClient := aiClient.NewAIServiceClient(cfg.AIServiceURL, cfg.InternalAPIKey)
 	storageServiceClient := aiClient.NewStorageServiceClient(cfg.StorageServiceURL)
+	authServiceClient := client.NewAuthServiceClient(cfg.AuthServiceURL, cfg.InternalAPIKey)
+	courseServiceClient := client.NewCourseServiceClient(cfg.CourseServiceURL, cfg.InternalAPIKey)
 	log.Println("✅ Service clients initialized")
 
 	// Initialize layers
 	exerciseRepo := repository.NewExerciseRepository(db)
-	exerciseService := service.NewExerciseService(exerciseRepo, userServiceClient, notificationClient, aiServiceClient, storageServiceClient)
+	exerciseService := service.NewExerciseService(exerciseRepo, userServiceClient, notificationClient, aiServiceClient, storageServiceClient, authServiceClient, courseServiceClient)
 	exerciseHandler := handlers.NewExerciseHandler(exerciseService)
 	storageHandler := handlers.NewStorageHandler(storageServiceClient)
 	authMiddleware := middleware.NewAuthMiddleware(cfg)
@@ -45,6 +57,8 @@ func main() {
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
+	router.Use(tracing.Middleware("exercise-service"), tracing.RequestID(), metrics.Middleware("exercise-service"))
+	router.GET("/metrics", metrics.Handler())
 
 	// Note: CORS is handled by API Gateway, no need to set here
 	// to avoid duplicate headers (Access-Control-Allow-Origin: *, *)
@@ -55,6 +69,18 @@ func main() {
 	// FIX #8, #9: Start background sync retry worker
 	go exerciseService.StartSyncRetryWorker()
 
+	// Remind learners of classroom assignments due within a day
+	go exerciseService.StartAssignmentReminderWorker()
+
+	// Auto-submit timed attempts whose time ran out
+	go exerciseService.StartAttemptSweeper()
+
+	// Settle mock test sittings and record them in user-service
+	go exerciseService.StartMockTestWorker()
+
+	// Calibrate bank questions from adaptive practice responses
+	go exerciseService.StartItemCalibrationWorker()
+
 	// Start server
 	log.Printf("Exercise Service running on port %s", cfg.ServerPort)
 	if err := router.Run(":" + cfg.ServerPort); err != nil {
@@ -2,11 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 )
 
 // AIServiceClient handles communication with AI Service
@@ -22,7 +25,8 @@ func NewAIServiceClient(baseURL, apiKey string) *AIServiceClient {
 		baseURL: baseURL,
 		apiKey:  apiKey,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second, // AI calls can take longer
+			Timeout:   60 * time.Second, // AI calls can take longer
+			Transport: tracing.NewTransport(nil),
 		},
 	}
 }
@@ -132,7 +136,7 @@ type SpeakingEvaluationResponse struct {
 }
 
 // EvaluateWriting sends writing essay to AI service for evaluation
-func (c *AIServiceClient) EvaluateWriting(req WritingEvaluationRequest) (*WritingEvaluationResponse, error) {
+func (c *AIServiceClient) EvaluateWriting(ctx context.Context, req WritingEvaluationRequest) (*WritingEvaluationResponse, error) {
 	endpoint := fmt.Sprintf("%s/api/v1/ai/internal/writing/evaluate", c.baseURL)
 
 	jsonData, err := json.Marshal(req)
@@ -140,7 +144,7 @@ func (c *AIServiceClient) EvaluateWriting(req WritingEvaluationRequest) (*Writin
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -172,7 +176,7 @@ func (c *AIServiceClient) EvaluateWriting(req WritingEvaluationRequest) (*Writin
 }
 
 // TranscribeSpeaking sends audio URL to AI service for transcription
-func (c *AIServiceClient) TranscribeSpeaking(req SpeakingTranscriptionRequest) (*SpeakingTranscriptionResponse, error) {
+func (c *AIServiceClient) TranscribeSpeaking(ctx context.Context, req SpeakingTranscriptionRequest) (*SpeakingTranscriptionResponse, error) {
 	endpoint := fmt.Sprintf("%s/api/v1/ai/internal/speaking/transcribe", c.baseURL)
 
 	jsonData, err := json.Marshal(req)
@@ -180,7 +184,7 @@ func (c *AIServiceClient) TranscribeSpeaking(req SpeakingTranscriptionRequest) (
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -212,7 +216,7 @@ func (c *AIServiceClient) TranscribeSpeaking(req SpeakingTranscriptionRequest) (
 }
 
 // EvaluateSpeaking sends transcript to AI service for evaluation
-func (c *AIServiceClient) EvaluateSpeaking(req SpeakingEvaluationRequest) (*SpeakingEvaluationResponse, error) {
+func (c *AIServiceClient) EvaluateSpeaking(ctx context.Context, req SpeakingEvaluationRequest) (*SpeakingEvaluationResponse, error) {
 	endpoint := fmt.Sprintf("%s/api/v1/ai/internal/speaking/evaluate", c.baseURL)
 
 	jsonData, err := json.Marshal(req)
@@ -220,7 +224,7 @@ func (c *AIServiceClient) EvaluateSpeaking(req SpeakingEvaluationRequest) (*Spea
 		return nil, fmt.Errorf("marshal request: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", endpoint, bytes.NewBuffer(jsonData))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
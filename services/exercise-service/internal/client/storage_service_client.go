@@ -2,12 +2,15 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
 	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 )
 
 // StorageServiceClient handles communication with Storage Service
@@ -21,7 +24,8 @@ func NewStorageServiceClient(baseURL string) *StorageServiceClient {
 	return &StorageServiceClient{
 		baseURL: baseURL,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: tracing.NewTransport(nil),
 		},
 	}
 }
@@ -73,10 +77,15 @@ type UploadAudioResponse struct {
 }
 
 // GetAudioInfo gets audio file metadata
-func (c *StorageServiceClient) GetAudioInfo(objectName string) (*AudioInfoResponse, error) {
+func (c *StorageServiceClient) GetAudioInfo(ctx context.Context, objectName string) (*AudioInfoResponse, error) {
 	url := fmt.Sprintf("%s/api/v1/storage/audio/info/%s", c.baseURL, objectName)
 
-	resp, err := c.httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call storage service: %w", err)
 	}
@@ -111,10 +120,15 @@ type PresignedURLResponse struct {
 }
 
 // GetPresignedURL gets a presigned URL for an audio file
-func (c *StorageServiceClient) GetPresignedURL(objectName string, expiryDays int) (*PresignedURLResponse, error) {
+func (c *StorageServiceClient) GetPresignedURL(ctx context.Context, objectName string, expiryDays int) (*PresignedURLResponse, error) {
 	url := fmt.Sprintf("%s/api/v1/storage/audio/presigned-url/%s?expiry_days=%d", c.baseURL, objectName, expiryDays)
 
-	resp, err := c.httpClient.Get(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call storage service: %w", err)
 	}
@@ -138,7 +152,7 @@ func (c *StorageServiceClient) GetPresignedURL(objectName string, expiryDays int
 }
 
 // UploadAudio uploads audio file to storage service
-func (c *StorageServiceClient) UploadAudio(userID string, file io.Reader, header *multipart.FileHeader) (*UploadAudioResponse, error) {
+func (c *StorageServiceClient) UploadAudio(ctx context.Context, userID string, file io.Reader, header *multipart.FileHeader) (*UploadAudioResponse, error) {
 	url := fmt.Sprintf("%s/api/v1/storage/audio/upload", c.baseURL)
 
 	// Create multipart form
@@ -165,7 +179,13 @@ func (c *StorageServiceClient) UploadAudio(userID string, file io.Reader, header
 	}
 
 	// Send request
-	resp, err := c.httpClient.Post(url, writer.FormDataContentType(), body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to call storage service: %w", err)
 	}
@@ -189,10 +209,10 @@ func (c *StorageServiceClient) UploadAudio(userID string, file io.Reader, header
 }
 
 // DeleteAudio deletes audio file
-func (c *StorageServiceClient) DeleteAudio(objectName string) error {
+func (c *StorageServiceClient) DeleteAudio(ctx context.Context, objectName string) error {
 	url := fmt.Sprintf("%s/api/v1/storage/audio/%s", c.baseURL, objectName)
 
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, url, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
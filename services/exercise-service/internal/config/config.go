@@ -12,13 +12,15 @@ type Config struct {
 	DBUser     string
 	DBPassword string
 	DBName     string
-	JWTSecret  string
+	JWKSURL    string
 
 	// Service-to-Service Communication
 	UserServiceURL         string
 	NotificationServiceURL string
 	AIServiceURL           string
 	StorageServiceURL      string
+	AuthServiceURL         string
+	CourseServiceURL       string
 	InternalAPIKey         string
 }
 
@@ -30,13 +32,15 @@ func LoadConfig() *Config {
 		DBUser:     getEnv("DB_USER", "ielts_admin"),
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "exercise_db"),
-		JWTSecret:  getEnv("JWT_SECRET", ""),
+		JWKSURL:    getEnv("JWKS_URL", "http://auth-service:8081/.well-known/jwks.json"),
 
 		// Service URLs for internal communication
 		UserServiceURL:         getEnv("USER_SERVICE_URL", "http://user-service:8082"),
 		NotificationServiceURL: getEnv("NOTIFICATION_SERVICE_URL", "http://notification-service:8085"),
 		AIServiceURL:           getEnv("AI_SERVICE_URL", "http://ai-service:8086"),
 		StorageServiceURL:      getEnv("STORAGE_SERVICE_URL", "http://storage-service:8087"),
+		AuthServiceURL:         getEnv("AUTH_SERVICE_URL", "http://auth-service:8081"),
+		CourseServiceURL:       getEnv("COURSE_SERVICE_URL", "http://course-service:8083"),
 		InternalAPIKey:         getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
 	}
 
@@ -44,10 +48,6 @@ func LoadConfig() *Config {
 		log.Fatal("DB_PASSWORD is required")
 	}
 
-	if config.JWTSecret == "" {
-		log.Fatal("JWT_SECRET is required")
-	}
-
 	log.Println("✅ Configuration loaded successfully")
 	return config
 }
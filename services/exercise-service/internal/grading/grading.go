@@ -0,0 +1,367 @@
+// Package grading marks objective (listening and reading) answers. Each
+// question type has a Grader in a registry keyed by questions.question_type;
+// types without one are marked as free text. Multi-part questions earn a
+// mark per gap, item or letter.
+package grading
+
+import (
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Question is what grading needs to know about a question
+type Question struct {
+	Type   string
+	Points float64
+	// Instructions are the question text and its section's instructions,
+	// read for word limits such as "NO MORE THAN TWO WORDS"
+	Instructions string
+	Options      []Option
+	Keys         []Key
+}
+
+// Option is one of a question's choices
+type Option struct {
+	ID        uuid.UUID
+	Label     string
+	IsCorrect bool
+}
+
+// Key is an accepted answer from question_answers. Part is the item, gap or
+// map position it answers on questions with several (match_left), and is
+// empty on single-answer questions.
+type Key struct {
+	Part       string
+	Text       string
+	Variations []string
+}
+
+// Answer is a learner's answer. Which field a grader reads depends on the
+// question type:
+//   - SelectedOptionID: single choice
+//   - SelectedOptionIDs: "choose TWO letters"
+//   - Text: one gap, or a TRUE/FALSE/NOT GIVEN judgement
+//   - Matches: matching and labelling, item or position to letter or words
+//   - Blanks: completion with several gaps, gap to words
+type Answer struct {
+	SelectedOptionID  *uuid.UUID
+	SelectedOptionIDs []uuid.UUID
+	Text              *string
+	Matches           map[string]string
+	Blanks            map[string]string
+}
+
+// Result is the mark for one answer. Marks count answer-sheet question
+// numbers: one for most questions, one per gap, item or letter to choose on
+// multi-part questions, which also report each part in Parts. Points are
+// shared out across the marks.
+type Result struct {
+	IsCorrect    bool // every mark earned
+	PointsEarned float64
+	Marks        int
+	MaxMarks     int
+	Parts        []PartResult
+}
+
+// PartResult is the mark for one gap, item or letter of a multi-part question
+type PartResult struct {
+	Part      string `json:"part"`
+	Answer    string `json:"answer,omitempty"`
+	IsCorrect bool   `json:"is_correct"`
+}
+
+// Grader marks answers to one kind of question. A result without MaxMarks
+// counts as one mark.
+type Grader interface {
+	Grade(q *Question, a *Answer) Result
+}
+
+// GraderFunc adapts a function to Grader
+type GraderFunc func(q *Question, a *Answer) Result
+
+// Grade calls f(q, a)
+func (f GraderFunc) Grade(q *Question, a *Answer) Result {
+	return f(q, a)
+}
+
+var registry = map[string]Grader{}
+
+// Register sets the grader for a question type, replacing any before it
+func Register(questionType string, g Grader) {
+	registry[questionType] = g
+}
+
+// For returns the grader for a question type; free text for unknown types
+func For(questionType string) Grader {
+	if g, ok := registry[questionType]; ok {
+		return g
+	}
+	return GraderFunc(gradeCompletion)
+}
+
+// Grade marks an answer with the grader for the question's type
+func Grade(q *Question, a *Answer) Result {
+	result := For(q.Type).Grade(q, a)
+	if result.MaxMarks == 0 {
+		result.MaxMarks = 1
+		if result.IsCorrect {
+			result.Marks = 1
+		}
+	}
+	return result
+}
+
+// MaxMarks returns how many answer-sheet question numbers a question covers
+func MaxMarks(q *Question) int {
+	return Grade(q, &Answer{}).MaxMarks
+}
+
+// UsesOptions reports whether learners answer the type by picking options
+func UsesOptions(questionType string) bool {
+	switch questionType {
+	case "multiple_choice", "multiple_select", "matching", "true_false", "true_false_not_given", "yes_no_not_given":
+		return true
+	}
+	return false
+}
+
+func init() {
+	Register("multiple_choice", GraderFunc(gradeChoice))
+	Register("multiple_select", GraderFunc(gradeMultiSelect))
+
+	for _, t := range []string{"true_false", "true_false_not_given", "yes_no_not_given"} {
+		Register(t, GraderFunc(gradeJudgement))
+	}
+
+	for _, t := range []string{
+		"matching", "matching_headings", "matching_features", "matching_information",
+		"matching_sentence_endings", "map_labeling", "plan_labeling", "diagram_labeling",
+	} {
+		Register(t, GraderFunc(gradeMatching))
+	}
+
+	for _, t := range []string{
+		"fill_in_blank", "completion", "sentence_completion", "summary_completion",
+		"note_completion", "table_completion", "form_completion", "flow_chart_completion",
+		"short_answer",
+	} {
+		Register(t, GraderFunc(gradeCompletion))
+	}
+}
+
+// gradeChoice marks a single choice. Questions with more than one correct
+// option ("choose TWO letters") are marked as multiple select.
+func gradeChoice(q *Question, a *Answer) Result {
+	if countCorrect(q.Options) > 1 {
+		return gradeMultiSelect(q, a)
+	}
+	if a.SelectedOptionID != nil {
+		for _, o := range q.Options {
+			if o.ID == *a.SelectedOptionID {
+				return mark(q, o.IsCorrect)
+			}
+		}
+		return mark(q, false)
+	}
+	// A letter typed instead of picked, as on paper answer sheets
+	if a.Text != nil {
+		for _, o := range q.Options {
+			if o.IsCorrect && o.Label != "" && Normalize(o.Label) == Normalize(*a.Text) {
+				return mark(q, true)
+			}
+		}
+	}
+	return mark(q, false)
+}
+
+// gradeMultiSelect gives a mark for each correct option chosen, in any
+// order. Choosing more options than asked for scores nothing, as on the
+// answer sheet.
+func gradeMultiSelect(q *Question, a *Answer) Result {
+	selected := map[uuid.UUID]bool{}
+	for _, id := range a.SelectedOptionIDs {
+		selected[id] = true
+	}
+	if a.SelectedOptionID != nil {
+		selected[*a.SelectedOptionID] = true
+	}
+
+	correct := countCorrect(q.Options)
+	if correct == 0 {
+		return mark(q, false)
+	}
+	tooMany := len(selected) > correct
+
+	parts := make([]PartResult, 0, correct)
+	for _, o := range q.Options {
+		if !o.IsCorrect {
+			continue
+		}
+		part := PartResult{Part: o.Label, IsCorrect: selected[o.ID] && !tooMany}
+		if part.Part == "" {
+			part.Part = o.ID.String()
+		}
+		if selected[o.ID] {
+			part.Answer = part.Part
+		}
+		parts = append(parts, part)
+	}
+	return markParts(q, parts)
+}
+
+var judgements = map[string]string{
+	"t": "true", "true": "true", "f": "false", "false": "false",
+	"y": "yes", "yes": "yes", "n": "no", "no": "no",
+	"ng": "not given", "not given": "not given", "notgiven": "not given",
+}
+
+// gradeJudgement marks TRUE/FALSE/NOT GIVEN and YES/NO/NOT GIVEN answers,
+// picked as options or written, accepting T, F, Y, N and NG
+func gradeJudgement(q *Question, a *Answer) Result {
+	if a.SelectedOptionID != nil || len(q.Keys) == 0 {
+		return gradeChoice(q, a)
+	}
+	if a.Text == nil {
+		return mark(q, false)
+	}
+	given := judgement(*a.Text)
+	for _, k := range q.Keys {
+		if given != "" && given == judgement(k.Text) {
+			return mark(q, true)
+		}
+	}
+	return mark(q, false)
+}
+
+func judgement(s string) string {
+	return judgements[Normalize(s)]
+}
+
+// gradeMatching marks matching and labelling questions. With one key per
+// item each item must be matched; a single-answer question is marked as a
+// choice or as text.
+func gradeMatching(q *Question, a *Answer) Result {
+	if !hasParts(q.Keys) {
+		if a.SelectedOptionID != nil || (len(q.Keys) == 0 && len(q.Options) > 0) {
+			return gradeChoice(q, a)
+		}
+		return gradeCompletion(q, a)
+	}
+	return gradeParts(q, a.Matches)
+}
+
+// gradeCompletion marks written answers against the accepted answers,
+// rejecting answers over the instructions' word limit
+func gradeCompletion(q *Question, a *Answer) Result {
+	if hasParts(q.Keys) {
+		return gradeParts(q, a.Blanks)
+	}
+	if a.Text == nil {
+		return mark(q, false)
+	}
+	return mark(q, acceptable(q, q.Keys, *a.Text))
+}
+
+// gradeParts gives a mark for each item or gap answered correctly, in the
+// order the keys list them
+func gradeParts(q *Question, given map[string]string) Result {
+	var order []string
+	keysByPart := map[string][]Key{}
+	names := map[string]string{}
+	for _, k := range q.Keys {
+		part := normalizePart(k.Part)
+		if _, ok := keysByPart[part]; !ok {
+			order = append(order, part)
+			names[part] = strings.TrimSpace(k.Part)
+		}
+		keysByPart[part] = append(keysByPart[part], k)
+	}
+	answers := make(map[string]string, len(given))
+	for part, text := range given {
+		answers[normalizePart(part)] = text
+	}
+
+	parts := make([]PartResult, 0, len(order))
+	for _, part := range order {
+		text := answers[part]
+		parts = append(parts, PartResult{
+			Part:      names[part],
+			Answer:    text,
+			IsCorrect: acceptable(q, keysByPart[part], text),
+		})
+	}
+	return markParts(q, parts)
+}
+
+// acceptable reports whether the text is within the word limit and matches
+// one of the keys or their variations
+func acceptable(q *Question, keys []Key, text string) bool {
+	if strings.TrimSpace(text) == "" {
+		return false
+	}
+	if limit, ok := ParseWordLimit(q.Instructions); ok && !limit.Allows(text) {
+		return false
+	}
+	given := Normalize(text)
+	for _, k := range keys {
+		if Normalize(k.Text) == given {
+			return true
+		}
+		for _, v := range k.Variations {
+			if Normalize(v) == given {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// mark scores a question worth one mark
+func mark(q *Question, correct bool) Result {
+	if !correct {
+		return Result{MaxMarks: 1}
+	}
+	return Result{IsCorrect: true, PointsEarned: q.Points, Marks: 1, MaxMarks: 1}
+}
+
+// markParts scores a mark per part, sharing the points across them
+func markParts(q *Question, parts []PartResult) Result {
+	result := Result{MaxMarks: len(parts), Parts: parts}
+	for _, p := range parts {
+		if p.IsCorrect {
+			result.Marks++
+		}
+	}
+	if result.MaxMarks == 0 {
+		result.MaxMarks = 1
+		return result
+	}
+	result.IsCorrect = result.Marks == result.MaxMarks
+	result.PointsEarned = math.Round(q.Points*float64(result.Marks)/float64(result.MaxMarks)*100) / 100
+	return result
+}
+
+func countCorrect(options []Option) int {
+	n := 0
+	for _, o := range options {
+		if o.IsCorrect {
+			n++
+		}
+	}
+	return n
+}
+
+func hasParts(keys []Key) bool {
+	for _, k := range keys {
+		if k.Part != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizePart(part string) string {
+	return strings.ToLower(strings.TrimSpace(part))
+}
@@ -0,0 +1,316 @@
+package grading
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func strPtr(s string) *string { return &s }
+
+// expectedMark is the expected outcome of grading one answer
+type expectedMark struct {
+	marks, maxMarks int
+	points          float64
+}
+
+func checkMark(t *testing.T, got Result, want expectedMark) {
+	t.Helper()
+	if got.Marks != want.marks || got.MaxMarks != want.maxMarks || got.PointsEarned != want.points ||
+		got.IsCorrect != (want.marks == want.maxMarks) {
+		t.Errorf("Grade() = %d/%d marks, %.2f points, correct %v; expected %d/%d marks, %.2f points",
+			got.Marks, got.MaxMarks, got.PointsEarned, got.IsCorrect, want.marks, want.maxMarks, want.points)
+	}
+}
+
+// TestNormalize tests the forms answers are compared in
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"  Library ", "library"},
+		{"part-time", "part time"},
+		{"children's", "childrens"},
+		{"twenty-five", "25"},
+		{"Two hundred and fifty", "250"},
+		{"three thousand", "3000"},
+		{"1,000", "1000"},
+		{"7.30", "7.30"},
+		{"one two", "1 2"},
+		{"first floor", "1st floor"},
+		{"twenty-first", "21st"},
+		{"15th March", "march 15"},
+		{"the 15th of March", "the march 15"},
+		{"March 15", "march 15"},
+		{"Sept. 3rd", "september 3"},
+		{"fifteenth of march", "march 15"},
+		{"may", "may"},
+		{"and", "and"},
+		{"hundred", "hundred"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, expected %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestParseWordLimit tests reading word limits from instructions
+func TestParseWordLimit(t *testing.T) {
+	tests := []struct {
+		instructions string
+		expected     WordLimit
+		found        bool
+	}{
+		{"Write NO MORE THAN TWO WORDS AND/OR A NUMBER for each answer.", WordLimit{2, true}, true},
+		{"Complete the notes below. Write NO MORE THAN TWO WORDS for each answer.", WordLimit{2, false}, true},
+		{"Write ONE WORD ONLY for each answer.", WordLimit{1, false}, true},
+		{"Write ONE WORD AND/OR A NUMBER for each answer.", WordLimit{1, true}, true},
+		{"Write no more than three words", WordLimit{3, false}, true},
+		{"Write A NUMBER for each answer.", WordLimit{0, true}, true},
+		{"Choose the correct letter A, B, C or D.", WordLimit{}, false},
+		{"Label the map below. Choose FIVE answers from the box.", WordLimit{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.instructions, func(t *testing.T) {
+			limit, found := ParseWordLimit(tt.instructions)
+			if found != tt.found || limit != tt.expected {
+				t.Errorf("ParseWordLimit() = %+v, %v, expected %+v, %v", limit, found, tt.expected, tt.found)
+			}
+		})
+	}
+}
+
+// TestWordLimitAllows tests counting words and numbers in answers
+func TestWordLimitAllows(t *testing.T) {
+	twoWordsAndNumber := WordLimit{MaxWords: 2, AllowNumber: true}
+	twoWords := WordLimit{MaxWords: 2}
+	numberOnly := WordLimit{AllowNumber: true}
+
+	tests := []struct {
+		name     string
+		limit    WordLimit
+		answer   string
+		expected bool
+	}{
+		{"two words and a number", twoWordsAndNumber, "15 Green Street", true},
+		{"three words", twoWordsAndNumber, "the old library", false},
+		{"two numbers", twoWordsAndNumber, "15 20", false},
+		{"hyphenated word counts once", twoWords, "part-time job", true},
+		{"number counts as a word", twoWords, "15 Green Street", false},
+		{"price", numberOnly, "£250", true},
+		{"number only with a word", numberOnly, "250 pounds", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.limit.Allows(tt.answer); got != tt.expected {
+				t.Errorf("Allows(%q) = %v, expected %v", tt.answer, got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGradeChoice tests single and multiple choice questions
+func TestGradeChoice(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	single := &Question{Type: "multiple_choice", Points: 1, Options: []Option{
+		{ID: a, Label: "A"}, {ID: b, Label: "B", IsCorrect: true}, {ID: c, Label: "C"},
+	}}
+	chooseTwo := &Question{Type: "multiple_choice", Points: 2, Options: []Option{
+		{ID: a, Label: "A", IsCorrect: true}, {ID: b, Label: "B"},
+		{ID: c, Label: "C", IsCorrect: true}, {ID: d, Label: "D"},
+	}}
+
+	tests := []struct {
+		name     string
+		question *Question
+		answer   *Answer
+		expected expectedMark
+	}{
+		{"correct option", single, &Answer{SelectedOptionID: &b}, expectedMark{1, 1, 1}},
+		{"wrong option", single, &Answer{SelectedOptionID: &a}, expectedMark{0, 1, 0}},
+		{"unknown option", single, &Answer{SelectedOptionID: &d}, expectedMark{0, 1, 0}},
+		{"letter written", single, &Answer{Text: strPtr(" b ")}, expectedMark{1, 1, 1}},
+		{"no answer", single, &Answer{}, expectedMark{0, 1, 0}},
+		{"both letters in any order", chooseTwo, &Answer{SelectedOptionIDs: []uuid.UUID{c, a}}, expectedMark{2, 2, 2}},
+		{"one letter missing", chooseTwo, &Answer{SelectedOptionIDs: []uuid.UUID{a}}, expectedMark{1, 2, 1}},
+		{"extra letter", chooseTwo, &Answer{SelectedOptionIDs: []uuid.UUID{a, c, d}}, expectedMark{0, 2, 0}},
+		{"same letter twice", chooseTwo, &Answer{SelectedOptionIDs: []uuid.UUID{a, a}}, expectedMark{1, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkMark(t, Grade(tt.question, tt.answer), tt.expected)
+		})
+	}
+}
+
+// TestGradeJudgement tests TRUE/FALSE/NOT GIVEN and YES/NO/NOT GIVEN answers
+func TestGradeJudgement(t *testing.T) {
+	notGiven := &Question{Type: "true_false_not_given", Points: 1, Keys: []Key{{Text: "NOT GIVEN"}}}
+	yes := &Question{Type: "yes_no_not_given", Points: 1, Keys: []Key{{Text: "YES"}}}
+
+	tests := []struct {
+		name     string
+		question *Question
+		answer   string
+		expected bool
+	}{
+		{"full answer", notGiven, "Not Given", true},
+		{"abbreviation", notGiven, "NG", true},
+		{"wrong answer", notGiven, "false", false},
+		{"letter", yes, "y", true},
+		{"true for yes", yes, "true", false},
+		{"unrelated text", yes, "maybe", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Grade(tt.question, &Answer{Text: strPtr(tt.answer)})
+			if got.IsCorrect != tt.expected {
+				t.Errorf("Grade(%q) correct = %v, expected %v", tt.answer, got.IsCorrect, tt.expected)
+			}
+		})
+	}
+}
+
+// TestGradeMatching tests matching and labelling with several items
+func TestGradeMatching(t *testing.T) {
+	headings := &Question{Type: "matching_headings", Points: 3, Keys: []Key{
+		{Part: "A", Text: "iv"}, {Part: "B", Text: "ii"}, {Part: "C", Text: "vii"},
+	}}
+	mapLabels := &Question{
+		Type:         "map_labeling",
+		Points:       2,
+		Instructions: "Label the map. Write NO MORE THAN TWO WORDS for each answer.",
+		Keys:         []Key{{Part: "1", Text: "car park", Variations: []string{"parking lot"}}, {Part: "2", Text: "reception"}},
+	}
+	legacy := &Question{Type: "matching", Points: 1, Keys: []Key{{Text: "Answer 5"}}}
+
+	tests := []struct {
+		name     string
+		question *Question
+		answer   *Answer
+		expected expectedMark
+	}{
+		{"all headings", headings, &Answer{Matches: map[string]string{"A": "iv", "b": "II", "C": "vii"}}, expectedMark{3, 3, 3}},
+		{"one heading wrong", headings, &Answer{Matches: map[string]string{"A": "iv", "B": "iii", "C": "vii"}}, expectedMark{2, 3, 2}},
+		{"one heading missing", headings, &Answer{Matches: map[string]string{"A": "iv", "B": "ii"}}, expectedMark{2, 3, 2}},
+		{"labels with variation", mapLabels, &Answer{Matches: map[string]string{"1": "Parking lot", "2": "reception"}}, expectedMark{2, 2, 2}},
+		{"label over word limit", mapLabels, &Answer{Matches: map[string]string{"1": "the car park", "2": "reception"}}, expectedMark{1, 2, 1}},
+		{"single answer as text", legacy, &Answer{Text: strPtr("answer 5")}, expectedMark{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkMark(t, Grade(tt.question, tt.answer), tt.expected)
+		})
+	}
+}
+
+// TestGradeCompletion tests sentence, summary and multi-gap completion
+func TestGradeCompletion(t *testing.T) {
+	sentence := &Question{
+		Type:         "sentence_completion",
+		Points:       1,
+		Instructions: "Write NO MORE THAN TWO WORDS AND/OR A NUMBER for each answer.",
+		Keys:         []Key{{Text: "climate change"}},
+	}
+	date := &Question{
+		Type:         "form_completion",
+		Points:       1,
+		Instructions: "Write ONE WORD AND/OR A NUMBER for each answer.",
+		Keys:         []Key{{Text: "15 March"}},
+	}
+	amount := &Question{Type: "fill_in_blank", Points: 1, Keys: []Key{{Text: "250"}}}
+	summary := &Question{
+		Type:         "summary_completion",
+		Points:       2,
+		Instructions: "Choose NO MORE THAN TWO WORDS from the passage for each answer.",
+		Keys:         []Key{{Part: "1", Text: "economic growth"}, {Part: "2", Text: "technology"}},
+	}
+
+	tests := []struct {
+		name     string
+		question *Question
+		answer   *Answer
+		expected expectedMark
+	}{
+		{"exact", sentence, &Answer{Text: strPtr("Climate Change")}, expectedMark{1, 1, 1}},
+		{"over word limit", sentence, &Answer{Text: strPtr("the climate change")}, expectedMark{0, 1, 0}},
+		{"wrong words", sentence, &Answer{Text: strPtr("global warming")}, expectedMark{0, 1, 0}},
+		{"empty", sentence, &Answer{Text: strPtr("  ")}, expectedMark{0, 1, 0}},
+		{"date written another way", date, &Answer{Text: strPtr("March 15th")}, expectedMark{1, 1, 1}},
+		{"number in words", amount, &Answer{Text: strPtr("two hundred and fifty")}, expectedMark{1, 1, 1}},
+		{"all gaps", summary, &Answer{Blanks: map[string]string{"1": "Economic growth", "2": "technology"}}, expectedMark{2, 2, 2}},
+		{"one gap wrong", summary, &Answer{Blanks: map[string]string{"1": "economic growth", "2": "science"}}, expectedMark{1, 2, 1}},
+		{"gaps sent as text", summary, &Answer{Text: strPtr("economic growth")}, expectedMark{0, 2, 0}},
+		{"unknown type graded as text", &Question{Type: "custom", Points: 1, Keys: []Key{{Text: "library"}}}, &Answer{Text: strPtr("LIBRARY")}, expectedMark{1, 1, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkMark(t, Grade(tt.question, tt.answer), tt.expected)
+		})
+	}
+}
+
+// TestRegister tests replacing the grader for a question type
+func TestRegister(t *testing.T) {
+	previous := For("short_answer")
+	defer Register("short_answer", previous)
+
+	Register("short_answer", GraderFunc(func(q *Question, a *Answer) Result {
+		return Result{IsCorrect: true, PointsEarned: q.Points}
+	}))
+	got := Grade(&Question{Type: "short_answer", Points: 1}, &Answer{})
+	if !got.IsCorrect {
+		t.Errorf("Grade() did not use the registered grader")
+	}
+	if got.Marks != 1 || got.MaxMarks != 1 {
+		t.Errorf("Grade() = %d/%d marks, expected a result without marks to count as 1/1", got.Marks, got.MaxMarks)
+	}
+}
+
+// TestGradeParts tests the per-part breakdown of multi-part questions
+func TestGradeParts(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	chooseTwo := &Question{Type: "multiple_select", Points: 1, Options: []Option{
+		{ID: a, Label: "A", IsCorrect: true}, {ID: b, Label: "B"}, {ID: c, Label: "C", IsCorrect: true},
+	}}
+	notes := &Question{Type: "note_completion", Points: 3, Keys: []Key{
+		{Part: "3", Text: "library"}, {Part: "1", Text: "Monday"}, {Part: "2", Text: "ten"},
+	}}
+
+	got := Grade(chooseTwo, &Answer{SelectedOptionIDs: []uuid.UUID{a, b}})
+	want := []PartResult{{Part: "A", Answer: "A", IsCorrect: true}, {Part: "C"}}
+	if !reflect.DeepEqual(got.Parts, want) {
+		t.Errorf("multiple select parts = %+v, expected %+v", got.Parts, want)
+	}
+	checkMark(t, got, expectedMark{1, 2, 0.5})
+
+	got = Grade(notes, &Answer{Blanks: map[string]string{"1": "monday", "2": "10", "3": "museum"}})
+	want = []PartResult{
+		{Part: "3", Answer: "museum"},
+		{Part: "1", Answer: "monday", IsCorrect: true},
+		{Part: "2", Answer: "10", IsCorrect: true},
+	}
+	if !reflect.DeepEqual(got.Parts, want) {
+		t.Errorf("completion parts = %+v, expected %+v", got.Parts, want)
+	}
+	checkMark(t, got, expectedMark{2, 3, 2})
+
+	if n := MaxMarks(notes); n != 3 {
+		t.Errorf("MaxMarks(notes) = %d, expected 3", n)
+	}
+	if n := MaxMarks(&Question{Type: "sentence_completion", Keys: []Key{{Text: "x"}}}); n != 1 {
+		t.Errorf("MaxMarks(single gap) = %d, expected 1", n)
+	}
+}
@@ -0,0 +1,271 @@
+package grading
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Normalize reduces an answer to the form answers are compared in: lower
+// case, without punctuation, with number words written as digits and dates
+// written as "month day", so "Twenty-five" matches "25" and "15th of March"
+// matches "March 15".
+func Normalize(s string) string {
+	tokens := strings.Fields(cleanText(strings.ToLower(s)))
+	for i, t := range tokens {
+		if groupedNumber.MatchString(t) {
+			tokens[i] = strings.ReplaceAll(t, ",", "")
+		}
+	}
+	tokens = numberWordsToDigits(tokens)
+	tokens = canonicalDates(tokens)
+	return strings.Join(tokens, " ")
+}
+
+var groupedNumber = regexp.MustCompile(`^\d{1,3}(,\d{3})+$`)
+
+// cleanText keeps letters and digits, and the separators that belong inside
+// numbers such as 1,000, 7.5 and 7:30. Apostrophes are dropped and any other
+// punctuation, hyphens included, separates words.
+func cleanText(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '\'' || r == '’':
+		case strings.ContainsRune(".,:/", r) && i > 0 && i < len(runes)-1 &&
+			unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1]):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return b.String()
+}
+
+var smallNumbers = map[string]int{
+	"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+	"thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16, "seventeen": 17,
+	"eighteen": 18, "nineteen": 19, "twenty": 20, "thirty": 30, "forty": 40,
+	"fifty": 50, "sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+}
+
+var ordinalNumbers = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5, "sixth": 6,
+	"seventh": 7, "eighth": 8, "ninth": 9, "tenth": 10, "eleventh": 11,
+	"twelfth": 12, "thirteenth": 13, "fourteenth": 14, "fifteenth": 15,
+	"sixteenth": 16, "seventeenth": 17, "eighteenth": 18, "nineteenth": 19,
+	"twentieth": 20, "thirtieth": 30,
+}
+
+// numberWordsToDigits replaces runs of number words with digits: "two
+// hundred and fifty" becomes "250" and "twenty first" becomes "21st"
+func numberWordsToDigits(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); {
+		value, ordinal, next := parseNumberWords(tokens, i)
+		if next == i {
+			out = append(out, tokens[i])
+			i++
+			continue
+		}
+		if ordinal {
+			out = append(out, ordinalString(value))
+		} else {
+			out = append(out, strconv.Itoa(value))
+		}
+		i = next
+	}
+	return out
+}
+
+// parseNumberWords reads the number written in words starting at tokens[i]
+// and returns its value and the index after it; next == i when there is none
+func parseNumberWords(tokens []string, i int) (value int, ordinal bool, next int) {
+	total, current := 0, 0
+	prev := "" // kind of the previous word: unit, teen, tens, scale or and
+	j := i
+	for ; j < len(tokens); j++ {
+		w := tokens[j]
+		if v, ok := ordinalNumbers[w]; ok {
+			if prev == "tens" && v < 10 || prev == "" || prev == "scale" || prev == "and" {
+				return total + current + v, true, j + 1
+			}
+			break
+		}
+		if v, ok := smallNumbers[w]; ok {
+			kind := "unit"
+			switch {
+			case v >= 20:
+				kind = "tens"
+			case v >= 10:
+				kind = "teen"
+			}
+			if prev != "" && prev != "scale" && prev != "and" && !(prev == "tens" && kind == "unit" && v > 0) {
+				break
+			}
+			current += v
+			prev = kind
+			continue
+		}
+		if (w == "hundred" || w == "thousand") && prev != "" && prev != "scale" && prev != "and" {
+			if w == "hundred" {
+				current *= 100
+			} else {
+				total += current * 1000
+				current = 0
+			}
+			prev = "scale"
+			continue
+		}
+		if w == "and" && prev == "scale" && j+1 < len(tokens) {
+			if _, ok := smallNumbers[tokens[j+1]]; ok {
+				prev = "and"
+				continue
+			}
+		}
+		break
+	}
+	if prev == "and" {
+		j--
+	}
+	if j == i {
+		return 0, false, i
+	}
+	return total + current, false, j
+}
+
+func ordinalString(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+var months = map[string]string{
+	"january": "january", "jan": "january", "february": "february", "feb": "february",
+	"march": "march", "mar": "march", "april": "april", "apr": "april", "may": "may",
+	"june": "june", "jun": "june", "july": "july", "jul": "july", "august": "august",
+	"aug": "august", "september": "september", "sep": "september", "sept": "september",
+	"october": "october", "oct": "october", "november": "november", "nov": "november",
+	"december": "december", "dec": "december",
+}
+
+var dayOfMonth = regexp.MustCompile(`^([1-9]|[12]\d|3[01])(st|nd|rd|th)?$`)
+
+// canonicalDates rewrites "15 March", "15th of March" and "March 15th" as
+// "march 15". Month names are only read as months next to a day.
+func canonicalDates(tokens []string) []string {
+	out := make([]string, 0, len(tokens))
+	for i := 0; i < len(tokens); i++ {
+		if m := dayOfMonth.FindStringSubmatch(tokens[i]); m != nil {
+			j := i + 1
+			if j < len(tokens) && tokens[j] == "of" {
+				j++
+			}
+			if j < len(tokens) {
+				if month, ok := months[tokens[j]]; ok {
+					out = append(out, month, m[1])
+					i = j
+					continue
+				}
+			}
+		}
+		if month, ok := months[tokens[i]]; ok && i+1 < len(tokens) {
+			if m := dayOfMonth.FindStringSubmatch(tokens[i+1]); m != nil {
+				out = append(out, month, m[1])
+				i++
+				continue
+			}
+		}
+		out = append(out, tokens[i])
+	}
+	return out
+}
+
+// WordLimit is the most an answer may contain, as in "NO MORE THAN TWO WORDS
+// AND/OR A NUMBER". A zero MaxWords with AllowNumber means a number only.
+type WordLimit struct {
+	MaxWords    int
+	AllowNumber bool
+}
+
+// wordLimitPatterns find the number of words allowed, tried in order
+var wordLimitPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:NO MORE THAN|NOT MORE THAN|UP TO|A MAXIMUM OF|MAXIMUM)\s+(ONE|TWO|THREE|FOUR|FIVE|\d+)\s+WORDS?`),
+	regexp.MustCompile(`\b(ONE|TWO|THREE|FOUR|FIVE|\d+)\s+WORDS?\s+ONLY`),
+	regexp.MustCompile(`\b(?:WRITE|USE|CHOOSE)\s+(ONE|TWO|THREE|FOUR|FIVE|\d+)\s+WORDS?`),
+}
+
+var (
+	numberAllowed = regexp.MustCompile(`\b(?:AND/OR|OR|AND)\s+A\s+NUMBER`)
+	numberOnly    = regexp.MustCompile(`\bA NUMBER ONLY\b|\bONLY A NUMBER\b|\bWRITE A NUMBER\b`)
+)
+
+var limitWords = map[string]int{"ONE": 1, "TWO": 2, "THREE": 3, "FOUR": 4, "FIVE": 5}
+
+// ParseWordLimit reads the word limit from IELTS instructions such as "Write
+// NO MORE THAN TWO WORDS AND/OR A NUMBER for each answer"
+func ParseWordLimit(instructions string) (WordLimit, bool) {
+	text := strings.Join(strings.Fields(strings.ToUpper(instructions)), " ")
+
+	var limit WordLimit
+	var m []string
+	for _, pattern := range wordLimitPatterns {
+		if m = pattern.FindStringSubmatch(text); m != nil {
+			break
+		}
+	}
+	if m == nil {
+		if numberOnly.MatchString(text) {
+			return WordLimit{AllowNumber: true}, true
+		}
+		return limit, false
+	}
+	if n, ok := limitWords[m[1]]; ok {
+		limit.MaxWords = n
+	} else {
+		limit.MaxWords, _ = strconv.Atoi(m[1])
+	}
+	limit.AllowNumber = numberAllowed.MatchString(text)
+	return limit, true
+}
+
+var numberToken = regexp.MustCompile(`^[$£€]?\d+([.,:/]\d+)*(st|nd|rd|th|%)?$`)
+
+// Allows reports whether the answer keeps to the limit. Hyphenated words
+// count once; when a number is allowed on top of the words, one number does
+// not count as a word.
+func (l WordLimit) Allows(answer string) bool {
+	words, numbers := 0, 0
+	for _, token := range strings.Fields(answer) {
+		token = strings.Trim(token, `.,;:!?"'()`)
+		if token == "" {
+			continue
+		}
+		if numberToken.MatchString(token) {
+			numbers++
+		} else {
+			words++
+		}
+	}
+	if l.AllowNumber {
+		if numbers > 1 {
+			return false
+		}
+		return words <= l.MaxWords
+	}
+	return words+numbers <= l.MaxWords
+}
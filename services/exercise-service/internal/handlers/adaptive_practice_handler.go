@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// StartAdaptivePractice handles POST /api/v1/adaptive-practice/sessions
+func (h *ExerciseHandler) StartAdaptivePractice(c *gin.Context) {
+	var req models.StartAdaptivePracticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	session, err := h.service.StartAdaptivePractice(requestUserID(c), &req)
+	if err != nil {
+		respondAdaptiveFailure(c, err, "START_ADAPTIVE_PRACTICE_ERROR", "Failed to start adaptive practice")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// GetAdaptivePracticeSession handles GET /api/v1/adaptive-practice/sessions/:id
+func (h *ExerciseHandler) GetAdaptivePracticeSession(c *gin.Context) {
+	sessionID, ok := parseIDParam(c, "id", "session")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetAdaptivePracticeSession(requestUserID(c), sessionID)
+	if err != nil {
+		respondAdaptiveFailure(c, err, "GET_ADAPTIVE_PRACTICE_ERROR", "Failed to get adaptive practice session")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// AnswerAdaptiveQuestion handles POST /api/v1/adaptive-practice/sessions/:id/answers
+func (h *ExerciseHandler) AnswerAdaptiveQuestion(c *gin.Context) {
+	sessionID, ok := parseIDParam(c, "id", "session")
+	if !ok {
+		return
+	}
+
+	var req models.AdaptiveAnswerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	result, err := h.service.AnswerAdaptiveQuestion(requestUserID(c), sessionID, &req)
+	if err != nil {
+		respondAdaptiveFailure(c, err, "ANSWER_ADAPTIVE_QUESTION_ERROR", "Failed to answer question")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// GetAbilityEstimates handles GET /api/v1/adaptive-practice/ability?skill_type=
+func (h *ExerciseHandler) GetAbilityEstimates(c *gin.Context) {
+	skillType := c.Query("skill_type")
+	if skillType != "listening" && skillType != "reading" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_SKILL_TYPE",
+				Message: "skill_type must be listening or reading",
+			},
+		})
+		return
+	}
+
+	estimates, err := h.service.GetAbilityEstimates(requestUserID(c), skillType)
+	if err != nil {
+		respondAdaptiveFailure(c, err, "GET_ABILITY_ERROR", "Failed to estimate ability")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    estimates,
+	})
+}
+
+// respondAdaptiveError writes the response for adaptive practice errors and
+// reports whether err was one
+func respondAdaptiveError(c *gin.Context, err error) bool {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, service.ErrAdaptiveSessionNotFound):
+		status, code = http.StatusNotFound, "SESSION_NOT_FOUND"
+	case errors.Is(err, service.ErrNoAdaptiveQuestions):
+		status, code = http.StatusNotFound, "NO_BANK_QUESTIONS"
+	case errors.Is(err, service.ErrAdaptiveSessionFinished):
+		status, code = http.StatusConflict, "SESSION_FINISHED"
+	case errors.Is(err, service.ErrAdaptiveQuestionNotCurrent):
+		status, code = http.StatusConflict, "QUESTION_NOT_CURRENT"
+	default:
+		return false
+	}
+
+	c.JSON(status, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+	return true
+}
+
+// respondAdaptiveFailure is respondAdaptiveError with a 500 for other errors
+func respondAdaptiveFailure(c *gin.Context, err error, code, message string) {
+	if respondAdaptiveError(c, err) {
+		return
+	}
+	c.JSON(http.StatusInternalServerError, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: message,
+			Details: err.Error(),
+		},
+	})
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateAssignment handles POST /api/v1/classrooms/:id/assignments
+func (h *ExerciseHandler) CreateAssignment(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	var req models.CreateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	assignment, err := h.service.CreateAssignment(classroomID, requestUserID(c), &req)
+	if err != nil {
+		respondAssignmentFailure(c, err, "CREATE_ASSIGNMENT_ERROR", "Failed to create assignment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    assignment,
+	})
+}
+
+// ListClassroomAssignments handles GET /api/v1/classrooms/:id/assignments
+func (h *ExerciseHandler) ListClassroomAssignments(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	assignments, err := h.service.ListClassroomAssignments(classroomID, requestUserID(c))
+	if err != nil {
+		respondAssignmentFailure(c, err, "GET_ASSIGNMENTS_ERROR", "Failed to get assignments")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    assignments,
+	})
+}
+
+// GetAssignment handles GET /api/v1/assignments/:id
+func (h *ExerciseHandler) GetAssignment(c *gin.Context) {
+	assignmentID, ok := parseIDParam(c, "id", "assignment")
+	if !ok {
+		return
+	}
+
+	assignment, err := h.service.GetAssignment(assignmentID, requestUserID(c))
+	if err != nil {
+		respondAssignmentFailure(c, err, "GET_ASSIGNMENT_ERROR", "Failed to get assignment")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    assignment,
+	})
+}
+
+// UpdateAssignment handles PUT /api/v1/assignments/:id
+func (h *ExerciseHandler) UpdateAssignment(c *gin.Context) {
+	assignmentID, ok := parseIDParam(c, "id", "assignment")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateAssignmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	assignment, err := h.service.UpdateAssignment(assignmentID, requestUserID(c), &req)
+	if err != nil {
+		respondAssignmentFailure(c, err, "UPDATE_ASSIGNMENT_ERROR", "Failed to update assignment")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    assignment,
+	})
+}
+
+// DeleteAssignment handles DELETE /api/v1/assignments/:id
+func (h *ExerciseHandler) DeleteAssignment(c *gin.Context) {
+	assignmentID, ok := parseIDParam(c, "id", "assignment")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAssignment(assignmentID, requestUserID(c)); err != nil {
+		respondAssignmentFailure(c, err, "DELETE_ASSIGNMENT_ERROR", "Failed to delete assignment")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"message": "Assignment deleted successfully",
+		},
+	})
+}
+
+// GetMyAssignments handles GET /api/v1/assignments/my
+func (h *ExerciseHandler) GetMyAssignments(c *gin.Context) {
+	assignments, err := h.service.ListMyAssignments(requestUserID(c))
+	if err != nil {
+		respondAssignmentFailure(c, err, "GET_ASSIGNMENTS_ERROR", "Failed to get assignments")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    assignments,
+	})
+}
+
+// GetGradebook handles GET /api/v1/classrooms/:id/gradebook
+func (h *ExerciseHandler) GetGradebook(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	gradebook, err := h.service.GetGradebook(classroomID, requestUserID(c))
+	if err != nil {
+		respondAssignmentFailure(c, err, "GET_GRADEBOOK_ERROR", "Failed to get gradebook")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    gradebook,
+	})
+}
+
+// ExportGradebook handles GET /api/v1/classrooms/:id/gradebook/export
+func (h *ExerciseHandler) ExportGradebook(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "id", "classroom")
+	if !ok {
+		return
+	}
+
+	gradebook, err := h.service.GetGradebook(classroomID, requestUserID(c))
+	if err != nil {
+		respondAssignmentFailure(c, err, "EXPORT_GRADEBOOK_ERROR", "Failed to export gradebook")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := service.WriteGradebookCSV(&buf, gradebook); err != nil {
+		respondAssignmentFailure(c, err, "EXPORT_GRADEBOOK_ERROR", "Failed to export gradebook")
+		return
+	}
+
+	filename := fmt.Sprintf("gradebook-%s-%s.csv", classroomID, time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// respondAssignmentError writes the response for assignment and classroom
+// errors and reports whether err was one of them
+func respondAssignmentError(c *gin.Context, err error) bool {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, service.ErrAssignmentNotFound):
+		status, code = http.StatusNotFound, "ASSIGNMENT_NOT_FOUND"
+	case errors.Is(err, service.ErrClassroomNotFound):
+		status, code = http.StatusNotFound, "CLASSROOM_NOT_FOUND"
+	case errors.Is(err, service.ErrNotClassroomTeacher):
+		status, code = http.StatusForbidden, "NOT_CLASSROOM_TEACHER"
+	case errors.Is(err, service.ErrNotAssigned):
+		status, code = http.StatusForbidden, "NOT_ASSIGNED"
+	case errors.Is(err, service.ErrInvalidAssignment):
+		status, code = http.StatusBadRequest, "INVALID_ASSIGNMENT"
+	case errors.Is(err, service.ErrAssignmentNotOpen):
+		status, code = http.StatusForbidden, "ASSIGNMENT_NOT_OPEN"
+	case errors.Is(err, service.ErrAssignmentClosed):
+		status, code = http.StatusForbidden, "ASSIGNMENT_CLOSED"
+	case errors.Is(err, service.ErrAttemptLimitReached):
+		status, code = http.StatusConflict, "ATTEMPT_LIMIT_REACHED"
+	default:
+		return false
+	}
+
+	c.JSON(status, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+	return true
+}
+
+// respondAssignmentFailure is respondAssignmentError with a 500 for other errors
+func respondAssignmentFailure(c *gin.Context, err error, code, message string) {
+	if respondAssignmentError(c, err) {
+		return
+	}
+	c.JSON(http.StatusInternalServerError, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: message,
+			Details: err.Error(),
+		},
+	})
+}
+
+// parseIDParam parses a UUID path parameter, answering 400 when it is invalid
+func parseIDParam(c *gin.Context, name, label string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_ID",
+				Message: "Invalid " + label + " ID",
+			},
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// requestUserID returns the authenticated user's ID; routes using it require auth
+func requestUserID(c *gin.Context) uuid.UUID {
+	userID, _ := c.Get("user_id")
+	id, _ := uuid.Parse(userID.(string))
+	return id
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAttemptSession handles GET /api/v1/submissions/:id/session
+func (h *ExerciseHandler) GetAttemptSession(c *gin.Context) {
+	submissionID, ok := parseIDParam(c, "id", "submission")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetAttemptSession(requestUserID(c), submissionID)
+	if err != nil {
+		respondAttemptFailure(c, err, "GET_SESSION_ERROR", "Failed to get attempt session")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// SaveAttemptDraft handles PUT /api/v1/submissions/:id/draft
+func (h *ExerciseHandler) SaveAttemptDraft(c *gin.Context) {
+	submissionID, ok := parseIDParam(c, "id", "submission")
+	if !ok {
+		return
+	}
+
+	var req models.SaveDraftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	session, err := h.service.SaveAttemptDraft(requestUserID(c), submissionID, &req)
+	if err != nil {
+		respondAttemptFailure(c, err, "SAVE_DRAFT_ERROR", "Failed to save draft")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// respondAttemptError writes the response for attempt timer errors and
+// reports whether err was one
+func respondAttemptError(c *gin.Context, err error) bool {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, service.ErrSubmissionNotFound):
+		status, code = http.StatusNotFound, "SUBMISSION_NOT_FOUND"
+	case errors.Is(err, service.ErrAttemptExpired):
+		status, code = http.StatusConflict, "ATTEMPT_EXPIRED"
+	case errors.Is(err, service.ErrAttemptNotInProgress):
+		status, code = http.StatusConflict, "ATTEMPT_NOT_IN_PROGRESS"
+	default:
+		return false
+	}
+
+	c.JSON(status, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+	return true
+}
+
+// respondAttemptFailure is respondAttemptError with a 500 for other errors
+func respondAttemptFailure(c *gin.Context, err error, code, message string) {
+	if respondAttemptError(c, err) {
+		return
+	}
+	c.JSON(http.StatusInternalServerError, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: message,
+			Details: err.Error(),
+		},
+	})
+}
@@ -155,8 +155,9 @@ func (h *ExerciseHandler) StartExercise(c *gin.Context) {
 	}
 
 	var req struct {
-		ExerciseID *uuid.UUID `json:"exercise_id" binding:"-"`
-		DeviceType *string    `json:"device_type"` // web, android, ios
+		ExerciseID   *uuid.UUID `json:"exercise_id" binding:"-"`
+		DeviceType   *string    `json:"device_type"`   // web, android, ios
+		AssignmentID *uuid.UUID `json:"assignment_id"` // when working on a classroom assignment
 	}
 
 	// Try to bind JSON body if present
@@ -183,8 +184,11 @@ func (h *ExerciseHandler) StartExercise(c *gin.Context) {
 	}
 
 	userUUID, _ := uuid.Parse(userID.(string))
-	submission, err := h.service.StartExercise(userUUID, *req.ExerciseID, req.DeviceType)
+	submission, err := h.service.StartExercise(userUUID, *req.ExerciseID, req.DeviceType, req.AssignmentID)
 	if err != nil {
+		if req.AssignmentID != nil && respondAssignmentError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Error: &ErrorInfo{
@@ -230,9 +234,12 @@ func (h *ExerciseHandler) SubmitAnswers(c *gin.Context) {
 	}
 
 	// Pass time_spent_seconds from frontend to service
-	err = h.service.SubmitAnswers(submissionID, req.Answers, req.TimeSpentSeconds)
+	err = h.service.SubmitAnswers(c.Request.Context(), submissionID, req.Answers, req.TimeSpentSeconds)
 	if err != nil {
 		log.Printf("[Exercise-Handler] Error submitting answers for submission %s: %v", submissionID, err)
+		if respondAttemptError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Error: &ErrorInfo{
@@ -438,7 +445,10 @@ func (h *ExerciseHandler) DeleteExercise(c *gin.Context) {
 		return
 	}
 
-	err = h.service.DeleteExercise(id)
+	userID, _ := c.Get("user_id")
+	userUUID, _ := uuid.Parse(userID.(string))
+
+	err = h.service.DeleteExercise(id, userUUID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
@@ -1131,8 +1141,11 @@ func (h *ExerciseHandler) SubmitExercise(c *gin.Context) {
 		return
 	}
 
-	err = h.service.SubmitExercise(submissionID, &req)
+	err = h.service.SubmitExercise(c.Request.Context(), submissionID, &req)
 	if err != nil {
+		if respondAttemptError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, Response{
 			Success: false,
 			Error: &ErrorInfo{
@@ -1160,3 +1173,67 @@ func (h *ExerciseHandler) HealthCheck(c *gin.Context) {
 		"service": "exercise-service",
 	})
 }
+
+// ExportUserData handles GET /api/v1/exercises/internal/users/:user_id/data (called by Auth Service for data exports)
+func (h *ExerciseHandler) ExportUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	data, err := h.service.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Failed to export data of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "EXPORT_FAILED",
+				Message: "Failed to export user data",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// EraseUserData handles DELETE /api/v1/exercises/internal/users/:user_id/data (called by Auth Service for account deletion)
+func (h *ExerciseHandler) EraseUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	if err := h.service.EraseUserData(c.Request.Context(), userID); err != nil {
+		log.Printf("❌ Failed to erase data of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "ERASE_FAILED",
+				Message: "Failed to erase user data",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{Success: true})
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ListMockTests handles GET /api/v1/mock-tests
+func (h *ExerciseHandler) ListMockTests(c *gin.Context) {
+	h.listMockTests(c, true)
+}
+
+// ListAllMockTests handles GET /api/v1/admin/mock-tests, including unpublished ones
+func (h *ExerciseHandler) ListAllMockTests(c *gin.Context) {
+	h.listMockTests(c, false)
+}
+
+func (h *ExerciseHandler) listMockTests(c *gin.Context, publishedOnly bool) {
+	mockTests, err := h.service.ListMockTests(publishedOnly)
+	if err != nil {
+		respondMockTestFailure(c, err, "LIST_MOCK_TESTS_ERROR", "Failed to list mock tests")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    mockTests,
+	})
+}
+
+// GetMockTest handles GET /api/v1/mock-tests/:id
+func (h *ExerciseHandler) GetMockTest(c *gin.Context) {
+	mockTestID, ok := parseIDParam(c, "id", "mock test")
+	if !ok {
+		return
+	}
+
+	mockTest, err := h.service.GetMockTest(mockTestID, true)
+	if err != nil {
+		respondMockTestFailure(c, err, "GET_MOCK_TEST_ERROR", "Failed to get mock test")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    mockTest,
+	})
+}
+
+// CreateMockTest handles POST /api/v1/admin/mock-tests
+func (h *ExerciseHandler) CreateMockTest(c *gin.Context) {
+	var req models.CreateMockTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	mockTest, err := h.service.CreateMockTest(requestUserID(c), &req)
+	if err != nil {
+		respondMockTestFailure(c, err, "CREATE_MOCK_TEST_ERROR", "Failed to create mock test")
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    mockTest,
+	})
+}
+
+// UpdateMockTest handles PUT /api/v1/admin/mock-tests/:id
+func (h *ExerciseHandler) UpdateMockTest(c *gin.Context) {
+	mockTestID, ok := parseIDParam(c, "id", "mock test")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMockTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	mockTest, err := h.service.UpdateMockTest(mockTestID, &req)
+	if err != nil {
+		respondMockTestFailure(c, err, "UPDATE_MOCK_TEST_ERROR", "Failed to update mock test")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    mockTest,
+	})
+}
+
+// StartMockTest handles POST /api/v1/mock-tests/:id/sessions
+func (h *ExerciseHandler) StartMockTest(c *gin.Context) {
+	mockTestID, ok := parseIDParam(c, "id", "mock test")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.StartMockTest(requestUserID(c), mockTestID)
+	if err != nil {
+		respondMockTestFailure(c, err, "START_MOCK_TEST_ERROR", "Failed to start mock test")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// GetMyMockTestSessions handles GET /api/v1/mock-test-sessions/my
+func (h *ExerciseHandler) GetMyMockTestSessions(c *gin.Context) {
+	sessions, err := h.service.ListMyMockTestSessions(requestUserID(c))
+	if err != nil {
+		respondMockTestFailure(c, err, "LIST_MOCK_TEST_SESSIONS_ERROR", "Failed to list mock test sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    sessions,
+	})
+}
+
+// GetMockTestSession handles GET /api/v1/mock-test-sessions/:id
+func (h *ExerciseHandler) GetMockTestSession(c *gin.Context) {
+	sessionID, ok := parseIDParam(c, "id", "mock test session")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.GetMockTestSession(requestUserID(c), sessionID)
+	if err != nil {
+		respondMockTestFailure(c, err, "GET_MOCK_TEST_SESSION_ERROR", "Failed to get mock test session")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// StartNextMockTestStage handles POST /api/v1/mock-test-sessions/:id/next
+func (h *ExerciseHandler) StartNextMockTestStage(c *gin.Context) {
+	sessionID, ok := parseIDParam(c, "id", "mock test session")
+	if !ok {
+		return
+	}
+
+	session, err := h.service.StartNextMockTestStage(requestUserID(c), sessionID)
+	if err != nil {
+		respondMockTestFailure(c, err, "START_STAGE_ERROR", "Failed to start the next stage")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    session,
+	})
+}
+
+// respondMockTestError writes the response for mock test errors and reports
+// whether err was one
+func respondMockTestError(c *gin.Context, err error) bool {
+	var status int
+	var code string
+	switch {
+	case errors.Is(err, service.ErrMockTestNotFound):
+		status, code = http.StatusNotFound, "MOCK_TEST_NOT_FOUND"
+	case errors.Is(err, service.ErrMockTestSessionNotFound):
+		status, code = http.StatusNotFound, "MOCK_TEST_SESSION_NOT_FOUND"
+	case errors.Is(err, service.ErrInvalidMockTest):
+		status, code = http.StatusBadRequest, "INVALID_MOCK_TEST"
+	case errors.Is(err, service.ErrMockTestStageNotFinished):
+		status, code = http.StatusConflict, "STAGE_NOT_FINISHED"
+	case errors.Is(err, service.ErrMockTestOnBreak):
+		status, code = http.StatusConflict, "ON_BREAK"
+	case errors.Is(err, service.ErrMockTestNoStagesLeft):
+		status, code = http.StatusConflict, "NO_STAGES_LEFT"
+	default:
+		return false
+	}
+
+	c.JSON(status, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: err.Error(),
+		},
+	})
+	return true
+}
+
+// respondMockTestFailure is respondMockTestError with a 500 for other errors
+func respondMockTestFailure(c *gin.Context, err error, code, message string) {
+	if respondMockTestError(c, err) {
+		return
+	}
+	c.JSON(http.StatusInternalServerError, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: message,
+			Details: err.Error(),
+		},
+	})
+}
@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// GetReviewQueue handles GET /api/v1/admin/reviews
+func (h *ExerciseHandler) GetReviewQueue(c *gin.Context) {
+	var query models.ReviewQueueQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid query parameters",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	reviews, total, err := h.service.ListReviewQueue(&query, requestUserID(c))
+	if err != nil {
+		respondReviewFailure(c, err, "GET_REVIEWS_ERROR", "Failed to get review queue")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"reviews": reviews,
+			"pagination": gin.H{
+				"page":        query.Page,
+				"limit":       query.Limit,
+				"total":       total,
+				"total_pages": (total + query.Limit - 1) / query.Limit,
+			},
+		},
+	})
+}
+
+// GetReview handles GET /api/v1/admin/reviews/:id
+func (h *ExerciseHandler) GetReview(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	detail, err := h.service.GetReviewDetail(reviewID)
+	if err != nil {
+		respondReviewFailure(c, err, "GET_REVIEW_ERROR", "Failed to get review")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    detail,
+	})
+}
+
+// ClaimReview handles POST /api/v1/admin/reviews/:id/claim
+func (h *ExerciseHandler) ClaimReview(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	review, err := h.service.ClaimReview(reviewID, requestUserID(c))
+	if err != nil {
+		respondReviewFailure(c, err, "CLAIM_REVIEW_ERROR", "Failed to claim review")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    review,
+	})
+}
+
+// ReleaseReview handles POST /api/v1/admin/reviews/:id/release
+func (h *ExerciseHandler) ReleaseReview(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	if err := h.service.ReleaseReview(reviewID, requestUserID(c)); err != nil {
+		respondReviewFailure(c, err, "RELEASE_REVIEW_ERROR", "Failed to release review")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"message": "Review returned to the queue",
+		},
+	})
+}
+
+// UpdateReviewScores handles PUT /api/v1/admin/reviews/:id/scores
+func (h *ExerciseHandler) UpdateReviewScores(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateReviewScoresRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	review, err := h.service.UpdateReviewScores(reviewID, requestUserID(c), &req)
+	if err != nil {
+		respondReviewFailure(c, err, "UPDATE_REVIEW_ERROR", "Failed to update review scores")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"review":          review,
+			"criteria_scores": review.CriteriaScores(),
+		},
+	})
+}
+
+// AddReviewComment handles POST /api/v1/admin/reviews/:id/comments
+func (h *ExerciseHandler) AddReviewComment(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	var req models.CreateReviewCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	comment, err := h.service.AddReviewComment(reviewID, requestUserID(c), &req)
+	if err != nil {
+		respondReviewFailure(c, err, "ADD_COMMENT_ERROR", "Failed to add comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    comment,
+	})
+}
+
+// DeleteReviewComment handles DELETE /api/v1/admin/reviews/:id/comments/:comment_id
+func (h *ExerciseHandler) DeleteReviewComment(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+	commentID, ok := parseIDParam(c, "comment_id", "comment")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReviewComment(reviewID, commentID, requestUserID(c)); err != nil {
+		respondReviewFailure(c, err, "DELETE_COMMENT_ERROR", "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: gin.H{
+			"message": "Comment deleted successfully",
+		},
+	})
+}
+
+// PublishReview handles POST /api/v1/admin/reviews/:id/publish
+func (h *ExerciseHandler) PublishReview(c *gin.Context) {
+	reviewID, ok := parseIDParam(c, "id", "review")
+	if !ok {
+		return
+	}
+
+	review, err := h.service.PublishReview(reviewID, requestUserID(c))
+	if err != nil {
+		respondReviewFailure(c, err, "PUBLISH_REVIEW_ERROR", "Failed to publish review")
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    review,
+	})
+}
+
+// respondReviewFailure writes the response for review errors, with a 500
+// for unexpected ones
+func respondReviewFailure(c *gin.Context, err error, code, message string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrReviewNotFound):
+		status, code = http.StatusNotFound, "REVIEW_NOT_FOUND"
+	case errors.Is(err, service.ErrReviewCommentMissing):
+		status, code = http.StatusNotFound, "COMMENT_NOT_FOUND"
+	case errors.Is(err, service.ErrReviewClaimed):
+		status, code = http.StatusConflict, "REVIEW_CLAIMED"
+	case errors.Is(err, service.ErrReviewNotClaimed):
+		status, code = http.StatusConflict, "REVIEW_NOT_CLAIMED"
+	case errors.Is(err, service.ErrReviewPublished):
+		status, code = http.StatusConflict, "REVIEW_PUBLISHED"
+	case errors.Is(err, service.ErrInvalidReview):
+		status, code = http.StatusBadRequest, "INVALID_REVIEW"
+	}
+
+	if status != http.StatusInternalServerError {
+		c.JSON(status, Response{
+			Success: false,
+			Error: &ErrorInfo{
+				Code:    code,
+				Message: err.Error(),
+			},
+		})
+		return
+	}
+	c.JSON(status, Response{
+		Success: false,
+		Error: &ErrorInfo{
+			Code:    code,
+			Message: message,
+			Details: err.Error(),
+		},
+	})
+}
@@ -52,7 +52,7 @@ func (h *StorageHandler) UploadAudio(c *gin.Context) {
 	defer file.Close()
 
 	// Forward to Storage Service
-	result, err := h.storageClient.UploadAudio(userID.(string), file, header)
+	result, err := h.storageClient.UploadAudio(c.Request.Context(), userID.(string), file, header)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -105,7 +105,7 @@ func (h *StorageHandler) GetAudioInfo(c *gin.Context) {
 	}
 
 	// Call Storage Service
-	result, err := h.storageClient.GetAudioInfo(objectName)
+	result, err := h.storageClient.GetAudioInfo(c.Request.Context(), objectName)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"success": false,
@@ -0,0 +1,165 @@
+// Package irt estimates learner ability and item parameters with the
+// two-parameter logistic (2PL) item response model. Ability (theta) and item
+// difficulty share a logit scale where 0 is an average learner and an
+// average item. Scores are between 0 and 1 so multi-part questions count
+// the share of their marks earned.
+package irt
+
+import "math"
+
+// Item holds an item's parameters. The chance of answering it correctly is
+// 1 / (1 + exp(-Discrimination * (theta - Difficulty))).
+type Item struct {
+	Discrimination float64
+	Difficulty     float64
+}
+
+// Probability returns the chance a learner of ability theta answers the item correctly
+func (it Item) Probability(theta float64) float64 {
+	return 1 / (1 + math.Exp(-it.Discrimination*(theta-it.Difficulty)))
+}
+
+// Information returns the Fisher information the item gives about theta;
+// items are most informative for learners near their difficulty
+func (it Item) Information(theta float64) float64 {
+	p := it.Probability(theta)
+	return it.Discrimination * it.Discrimination * p * (1 - p)
+}
+
+// Response is a learner's score on an item
+type Response struct {
+	Item  Item
+	Score float64
+}
+
+// Normal is a normal distribution over theta
+type Normal struct {
+	Mean float64
+	SD   float64
+}
+
+// StandardNormal is the ability distribution assumed without other evidence
+var StandardNormal = Normal{Mean: 0, SD: 1}
+
+// Estimate is an ability estimate with its standard error
+type Estimate struct {
+	Theta         float64
+	StandardError float64
+}
+
+// Interval returns the range z standard errors either side of the estimate
+func (e Estimate) Interval(z float64) (lower, upper float64) {
+	return e.Theta - z*e.StandardError, e.Theta + z*e.StandardError
+}
+
+const (
+	gridMin  = -4.0
+	gridMax  = 4.0
+	gridStep = 0.05
+)
+
+// EstimateAbility returns the expected a posteriori (EAP) estimate of theta
+// given the responses and a prior, integrated over a grid from -4 to 4. It
+// stays finite when every answer is right or wrong, unlike maximum likelihood.
+func EstimateAbility(responses []Response, prior Normal) Estimate {
+	var weights, sum, sumSquares float64
+	for theta := gridMin; theta <= gridMax+gridStep/2; theta += gridStep {
+		z := (theta - prior.Mean) / prior.SD
+		logPosterior := -z * z / 2
+		for _, r := range responses {
+			p := clamp(r.Item.Probability(theta), 1e-9, 1-1e-9)
+			logPosterior += r.Score*math.Log(p) + (1-r.Score)*math.Log(1-p)
+		}
+		w := math.Exp(logPosterior)
+		weights += w
+		sum += w * theta
+		sumSquares += w * theta * theta
+	}
+	if weights == 0 {
+		return Estimate{Theta: prior.Mean, StandardError: prior.SD}
+	}
+	mean := sum / weights
+	variance := math.Max(sumSquares/weights-mean*mean, 0)
+	return Estimate{Theta: mean, StandardError: math.Sqrt(variance)}
+}
+
+// DifficultyFromResponses estimates an item's difficulty from how many
+// learners answered it and their total score, taking theta 0 as the average
+// learner. The difficulty prior counts as two responses, so items few
+// learners have answered stay close to it.
+func DifficultyFromResponses(n, score, prior float64) float64 {
+	p0 := Item{Discrimination: 1, Difficulty: prior}.Probability(0)
+	const pseudo = 2.0
+	return math.Log((n - score + pseudo*(1-p0)) / (score + pseudo*p0))
+}
+
+// Observation is a score on an item from a learner of estimated ability Theta
+type Observation struct {
+	Theta float64
+	Score float64
+}
+
+const (
+	// calibrationIterations caps the Newton-Raphson steps of Calibrate
+	calibrationIterations = 50
+	// discriminationPriorSD is the SD of log(discrimination) around 0
+	discriminationPriorSD = 0.5
+	// difficultyPriorSD is the SD of difficulty around the starting difficulty
+	difficultyPriorSD = 2.0
+	// maxCalibrationStep caps how far one step moves either parameter
+	maxCalibrationStep = 1.0
+	minDiscrimination  = 0.2
+	maxDiscrimination  = 4.0
+)
+
+// Calibrate fits an item's parameters to observations by maximum a
+// posteriori, starting from and shrinking towards start: log(discrimination)
+// has a normal prior around 0 and difficulty one around start's. It uses
+// Fisher scoring, which unlike Newton-Raphson always steps uphill. ok is
+// false when the fit does not converge, in which case start should be kept.
+func Calibrate(observations []Observation, start Item) (fitted Item, ok bool) {
+	if len(observations) == 0 {
+		return start, false
+	}
+	logA, b := math.Log(clamp(start.Discrimination, minDiscrimination, maxDiscrimination)), start.Difficulty
+	priorB := start.Difficulty
+
+	for i := 0; i < calibrationIterations; i++ {
+		a := math.Exp(logA)
+		// Gradient of the log posterior in (log a, b) and its expected information
+		gLogA := -logA / (discriminationPriorSD * discriminationPriorSD)
+		gB := -(b - priorB) / (difficultyPriorSD * difficultyPriorSD)
+		iLogALogA := 1 / (discriminationPriorSD * discriminationPriorSD)
+		iBB := 1 / (difficultyPriorSD * difficultyPriorSD)
+		iLogAB := 0.0
+		for _, o := range observations {
+			d := o.Theta - b
+			p := Item{Discrimination: a, Difficulty: b}.Probability(o.Theta)
+			residual := o.Score - p
+			w := p * (1 - p)
+			gLogA += residual * a * d
+			gB -= residual * a
+			iLogALogA += w * a * a * d * d
+			iBB += w * a * a
+			iLogAB -= w * a * a * d
+		}
+
+		det := iLogALogA*iBB - iLogAB*iLogAB
+		if det <= 0 || math.IsNaN(det) {
+			return start, false
+		}
+		stepLogA := clamp((iBB*gLogA-iLogAB*gB)/det, -maxCalibrationStep, maxCalibrationStep)
+		stepB := clamp((iLogALogA*gB-iLogAB*gLogA)/det, -maxCalibrationStep, maxCalibrationStep)
+		logA = clamp(logA+stepLogA, math.Log(minDiscrimination), math.Log(maxDiscrimination))
+		b = clamp(b+stepB, gridMin, gridMax)
+
+		if math.Abs(stepLogA) < 1e-5 && math.Abs(stepB) < 1e-5 {
+			return Item{Discrimination: math.Exp(logA), Difficulty: b}, true
+		}
+	}
+	return start, false
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
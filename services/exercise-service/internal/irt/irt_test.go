@@ -0,0 +1,105 @@
+package irt
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestProbability tests the 2PL response curve
+func TestProbability(t *testing.T) {
+	item := Item{Discrimination: 1.5, Difficulty: 0.5}
+	if got := item.Probability(0.5); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("Probability(difficulty) = %.4f, expected 0.5", got)
+	}
+	if item.Probability(2) <= item.Probability(1) {
+		t.Error("Probability should rise with ability")
+	}
+	if item.Information(0.5) <= item.Information(2.5) {
+		t.Error("Information should peak at the item's difficulty")
+	}
+}
+
+// TestEstimateAbility tests EAP estimates against answer patterns
+func TestEstimateAbility(t *testing.T) {
+	items := []Item{{1, -1}, {1, -0.5}, {1, 0}, {1, 0.5}, {1, 1}}
+	responses := func(scores ...float64) []Response {
+		r := make([]Response, len(scores))
+		for i, s := range scores {
+			r[i] = Response{Item: items[i], Score: s}
+		}
+		return r
+	}
+
+	none := EstimateAbility(nil, Normal{Mean: 0.5, SD: 0.8})
+	if math.Abs(none.Theta-0.5) > 0.01 || math.Abs(none.StandardError-0.8) > 0.01 {
+		t.Errorf("without responses = %.3f ± %.3f, expected the prior 0.5 ± 0.8", none.Theta, none.StandardError)
+	}
+
+	allRight := EstimateAbility(responses(1, 1, 1, 1, 1), StandardNormal)
+	allWrong := EstimateAbility(responses(0, 0, 0, 0, 0), StandardNormal)
+	mixed := EstimateAbility(responses(1, 1, 1, 0, 0), StandardNormal)
+	if !(allRight.Theta > mixed.Theta && mixed.Theta > allWrong.Theta) {
+		t.Errorf("estimates %.3f, %.3f, %.3f should fall with fewer right answers", allRight.Theta, mixed.Theta, allWrong.Theta)
+	}
+	if allRight.Theta > gridMax || math.IsNaN(allRight.Theta) {
+		t.Errorf("all right = %.3f, expected a finite estimate", allRight.Theta)
+	}
+	if mixed.StandardError >= 1 {
+		t.Errorf("standard error %.3f should fall below the prior's", mixed.StandardError)
+	}
+
+	half := EstimateAbility(responses(0.5), StandardNormal)
+	right := EstimateAbility(responses(1), StandardNormal)
+	wrong := EstimateAbility(responses(0), StandardNormal)
+	if !(wrong.Theta < half.Theta && half.Theta < right.Theta) {
+		t.Errorf("half marks = %.3f, expected between %.3f and %.3f", half.Theta, wrong.Theta, right.Theta)
+	}
+
+	lower, upper := mixed.Interval(1.96)
+	if !(lower < mixed.Theta && mixed.Theta < upper) {
+		t.Errorf("Interval() = [%.3f, %.3f] does not contain %.3f", lower, upper, mixed.Theta)
+	}
+}
+
+// TestDifficultyFromResponses tests difficulty from observed scores
+func TestDifficultyFromResponses(t *testing.T) {
+	if got := DifficultyFromResponses(0, 0, 1); math.Abs(got-1) > 1e-9 {
+		t.Errorf("without responses = %.3f, expected the prior 1", got)
+	}
+	easy := DifficultyFromResponses(100, 90, 0)
+	hard := DifficultyFromResponses(100, 10, 0)
+	if !(easy < -1.5 && hard > 1.5) {
+		t.Errorf("90%% right = %.3f, 10%% right = %.3f; expected clearly easy and hard", easy, hard)
+	}
+}
+
+// TestCalibrate tests that calibration recovers simulated item parameters
+func TestCalibrate(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	truth := Item{Discrimination: 1.6, Difficulty: 0.8}
+
+	var observations []Observation
+	for i := 0; i < 2000; i++ {
+		theta := rng.NormFloat64()
+		score := 0.0
+		if rng.Float64() < truth.Probability(theta) {
+			score = 1
+		}
+		observations = append(observations, Observation{Theta: theta, Score: score})
+	}
+
+	fitted, ok := Calibrate(observations, Item{Discrimination: 1, Difficulty: 0})
+	if !ok {
+		t.Fatal("Calibrate() did not converge")
+	}
+	if math.Abs(fitted.Difficulty-truth.Difficulty) > 0.2 || math.Abs(fitted.Discrimination-truth.Discrimination) > 0.3 {
+		t.Errorf("Calibrate() = a %.2f, b %.2f; expected near a %.2f, b %.2f",
+			fitted.Discrimination, fitted.Difficulty, truth.Discrimination, truth.Difficulty)
+	}
+
+	start := Item{Discrimination: 1, Difficulty: 0.3}
+	if got, ok := Calibrate(nil, start); ok || got != start {
+		t.Errorf("Calibrate(nil) = %+v, %v; expected the start item, false", got, ok)
+	}
+}
@@ -1,17 +1,18 @@
 package middleware
 
 import (
-	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/config"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthMiddleware struct {
-	jwtSecret string
+	keySet         *jwks.KeySet
+	internalAPIKey string
 }
 
 type ErrorInfo struct {
@@ -27,7 +28,8 @@ type Response struct {
 
 func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret: cfg.JWTSecret,
+		keySet:         jwks.NewKeySet(cfg.JWKSURL),
+		internalAPIKey: cfg.InternalAPIKey,
 	}
 }
 
@@ -60,12 +62,7 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.Parse(tokenString, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, Response{
@@ -110,12 +107,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.Parse(tokenString, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err == nil && token.Valid {
 			if claims, ok := token.Claims.(jwt.MapClaims); ok {
@@ -162,3 +154,37 @@ func (m *AuthMiddleware) RequireRole(allowedRoles ...string) gin.HandlerFunc {
 		c.Abort()
 	}
 }
+
+// InternalAuth validates internal API key for service-to-service communication
+func (m *AuthMiddleware) InternalAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-Internal-API-Key")
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "MISSING_API_KEY",
+					Message: "Internal API key required",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if apiKey != m.internalAPIKey {
+			c.JSON(http.StatusForbidden, Response{
+				Success: false,
+				Error: &ErrorInfo{
+					Code:    "INVALID_API_KEY",
+					Message: "Invalid internal API key",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		// Mark request as internal
+		c.Set("is_internal", true)
+		c.Next()
+	}
+}
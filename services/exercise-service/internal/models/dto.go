@@ -1,6 +1,10 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 // ExerciseListQuery for filtering exercises
 type ExerciseListQuery struct {
@@ -34,6 +38,7 @@ type SectionWithQuestions struct {
 type QuestionWithOptions struct {
 	Question *Question        `json:"question"`
 	Options  []QuestionOption `json:"options,omitempty"`
+	Parts    []string         `json:"parts,omitempty"` // items, gaps or map positions answered in matches or blanks
 }
 
 // SubmitAnswersRequest for submitting exercise answers
@@ -42,12 +47,19 @@ type SubmitAnswersRequest struct {
 	TimeSpentSeconds *int               `json:"time_spent_seconds,omitempty"` // Total time spent on entire exercise (frontend tracked)
 }
 
-// SubmitAnswerItem represents a single answer
+// SubmitAnswerItem represents a single answer. Which fields are read depends
+// on the question type: selected_option_id for single choice,
+// selected_option_ids for "choose TWO letters", matches for matching and
+// labelling with several items, blanks for completion with several gaps,
+// and text_answer otherwise.
 type SubmitAnswerItem struct {
-	QuestionID       uuid.UUID  `json:"question_id" binding:"required"`
-	SelectedOptionID *uuid.UUID `json:"selected_option_id,omitempty"`
-	TextAnswer       *string    `json:"text_answer,omitempty"`
-	TimeSpentSeconds *int       `json:"time_spent_seconds,omitempty"` // Time spent on this specific question (optional)
+	QuestionID        uuid.UUID         `json:"question_id" binding:"required"`
+	SelectedOptionID  *uuid.UUID        `json:"selected_option_id,omitempty"`
+	SelectedOptionIDs []uuid.UUID       `json:"selected_option_ids,omitempty"`
+	TextAnswer        *string           `json:"text_answer,omitempty"`
+	Matches           map[string]string `json:"matches,omitempty"`            // item or position -> letter or words
+	Blanks            map[string]string `json:"blanks,omitempty"`             // gap -> words
+	TimeSpentSeconds  *int              `json:"time_spent_seconds,omitempty"` // Time spent on this specific question (optional)
 }
 
 // SubmissionResultResponse includes detailed results for a user's exercise attempt
@@ -56,6 +68,7 @@ type SubmissionResultResponse struct {
 	Exercise    *Exercise                      `json:"exercise"`
 	Answers     []SubmissionAnswerWithQuestion `json:"answers"`
 	Performance *PerformanceStats              `json:"performance"`
+	Review      *PublishedReview               `json:"review,omitempty"` // examiner review, once published
 }
 
 // SubmissionAnswerWithQuestion includes answer with question details
@@ -161,6 +174,7 @@ type CreateQuestionAnswerRequest struct {
 	AlternativeAnswers []string `json:"alternative_answers"`
 	IsCaseSensitive    bool     `json:"is_case_sensitive"`
 	MatchingOrder      *int     `json:"matching_order"`
+	MatchLeft          *string  `json:"match_left"` // item, gap or map position on questions with several
 }
 
 // MySubmissionsQuery for filtering user submissions
@@ -186,3 +200,210 @@ type UserExerciseAttemptWithExercise struct {
 	Submission *UserExerciseAttempt `json:"submission"`
 	Exercise   *Exercise            `json:"exercise"`
 }
+
+// CreateAssignmentRequest sets an exercise or a lesson for a classroom.
+// Exactly one of exercise_id and lesson_id is required.
+type CreateAssignmentRequest struct {
+	Title              string      `json:"title" binding:"required,max=200"`
+	Instructions       *string     `json:"instructions"`
+	ExerciseID         *uuid.UUID  `json:"exercise_id"`
+	LessonID           *uuid.UUID  `json:"lesson_id"`
+	StudentIDs         []uuid.UUID `json:"student_ids"`                                               // empty assigns the whole classroom
+	OpenAt             *time.Time  `json:"open_at"`                                                   // defaults to now
+	DueAt              time.Time   `json:"due_at" binding:"required"`                                 // RFC 3339
+	MaxAttempts        *int        `json:"max_attempts" binding:"omitempty,min=1"`                    // exercises only; empty = unlimited
+	LatePolicy         string      `json:"late_policy" binding:"omitempty,oneof=allow penalty block"` // defaults to allow
+	LatePenaltyPercent int         `json:"late_penalty_percent" binding:"min=0,max=100"`              // with the penalty policy
+}
+
+// UpdateAssignmentRequest changes an assignment. The exercise or lesson
+// cannot change; create a new assignment instead.
+type UpdateAssignmentRequest struct {
+	Title              *string      `json:"title" binding:"omitempty,max=200"`
+	Instructions       *string      `json:"instructions"`
+	StudentIDs         *[]uuid.UUID `json:"student_ids"` // an empty list assigns the whole classroom
+	OpenAt             *time.Time   `json:"open_at"`
+	DueAt              *time.Time   `json:"due_at"`
+	MaxAttempts        *int         `json:"max_attempts" binding:"omitempty,min=0"` // 0 removes the limit
+	LatePolicy         *string      `json:"late_policy" binding:"omitempty,oneof=allow penalty block"`
+	LatePenaltyPercent *int         `json:"late_penalty_percent" binding:"omitempty,min=0,max=100"`
+}
+
+// AssignmentResult is how one student is doing on one assignment
+type AssignmentResult struct {
+	AssignmentID   uuid.UUID  `json:"assignment_id"`
+	Status         string     `json:"status"` // not_assigned, not_started, in_progress, completed, missing
+	Late           bool       `json:"late"`   // completed after the due date
+	AttemptsUsed   int        `json:"attempts_used"`
+	BandScore      *float64   `json:"band_score,omitempty"`       // best attempt
+	Score          *float64   `json:"score,omitempty"`            // best attempt
+	FinalBandScore *float64   `json:"final_band_score,omitempty"` // after the late penalty
+	FinalScore     *float64   `json:"final_score,omitempty"`      // after the late penalty
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// StudentAssignment is an assignment with the learner's own result
+type StudentAssignment struct {
+	Assignment
+	ClassroomName string           `json:"classroom_name,omitempty"`
+	Result        AssignmentResult `json:"result"`
+}
+
+// Gradebook holds every student's result on every assignment of a classroom
+type Gradebook struct {
+	ClassroomID uuid.UUID          `json:"classroom_id"`
+	Assignments []Assignment       `json:"assignments"`
+	Students    []GradebookStudent `json:"students"`
+}
+
+// GradebookStudent is one gradebook row; Results follow the order of the
+// gradebook's assignments
+type GradebookStudent struct {
+	UserID           uuid.UUID          `json:"user_id"`
+	Email            string             `json:"email"`
+	Results          []AssignmentResult `json:"results"`
+	CompletedCount   int                `json:"completed_count"`
+	MissingCount     int                `json:"missing_count"`
+	AverageBandScore *float64           `json:"average_band_score,omitempty"` // of final band scores
+}
+
+// ReviewQueueQuery filters the examiner review queue
+type ReviewQueueQuery struct {
+	Status    string `form:"status" binding:"omitempty,oneof=queued claimed published"` // defaults to queued
+	SkillType string `form:"skill_type" binding:"omitempty,oneof=writing speaking"`
+	Mine      bool   `form:"mine"` // only reviews I claimed
+	Page      int    `form:"page"`
+	Limit     int    `form:"limit"`
+}
+
+// ReviewQueueItem is a review with what the queue shows of its submission
+type ReviewQueueItem struct {
+	SubmissionReview
+	UserID        uuid.UUID  `json:"user_id"`
+	ExerciseID    uuid.UUID  `json:"exercise_id"`
+	ExerciseTitle string     `json:"exercise_title"`
+	SubmittedAt   *time.Time `json:"submitted_at,omitempty"`
+}
+
+// ReviewDetail is everything a reviewer needs to grade a submission
+type ReviewDetail struct {
+	Review         *SubmissionReview    `json:"review"`
+	CriteriaScores map[string]float64   `json:"criteria_scores"` // AI scores with the overrides applied
+	Submission     *UserExerciseAttempt `json:"submission"`
+	Exercise       *Exercise            `json:"exercise"`
+	Comments       []ReviewComment      `json:"comments"`
+}
+
+// UpdateReviewScoresRequest overrides criteria scores, e.g.
+// {"task_achievement": 6.5}. A score equal to the AI's removes the override.
+type UpdateReviewScoresRequest struct {
+	CriteriaScores map[string]float64 `json:"criteria_scores"`
+	Feedback       *string            `json:"feedback" binding:"omitempty,max=5000"`
+}
+
+// CreateReviewCommentRequest anchors a comment to essay offsets or recording seconds
+type CreateReviewCommentRequest struct {
+	AnchorType   string   `json:"anchor_type" binding:"required,oneof=text_span audio_timestamp general"`
+	StartOffset  *int     `json:"start_offset" binding:"omitempty,min=0"`  // text_span, in characters
+	EndOffset    *int     `json:"end_offset" binding:"omitempty,min=1"`    // text_span, exclusive
+	StartSeconds *float64 `json:"start_seconds" binding:"omitempty,min=0"` // audio_timestamp
+	EndSeconds   *float64 `json:"end_seconds" binding:"omitempty,min=0"`   // audio_timestamp, optional
+	Comment      string   `json:"comment" binding:"required,max=2000"`
+}
+
+// PublishedReview is the examiner review a learner sees on their result
+type PublishedReview struct {
+	BandScore      *float64           `json:"band_score,omitempty"`
+	AIBandScore    *float64           `json:"ai_band_score,omitempty"`
+	CriteriaScores map[string]float64 `json:"criteria_scores"`
+	Feedback       *string            `json:"feedback,omitempty"`
+	PublishedAt    *time.Time         `json:"published_at,omitempty"`
+	Comments       []ReviewComment    `json:"comments"`
+}
+
+// SaveDraftRequest autosaves answers of an in-progress attempt without
+// grading them. Answers are merged by question; the essay replaces the last.
+type SaveDraftRequest struct {
+	Answers     []SubmitAnswerItem     `json:"answers"`
+	WritingData *WritingSubmissionData `json:"writing_data,omitempty"`
+}
+
+// AttemptDraft is the autosaved state of an in-progress attempt
+type AttemptDraft struct {
+	Answers     []SubmitAnswerItem     `json:"answers"`
+	WritingData *WritingSubmissionData `json:"writing_data,omitempty"`
+	SavedAt     *time.Time             `json:"saved_at,omitempty"`
+}
+
+// SectionDeadline is when answers to a timed section stop being accepted
+type SectionDeadline struct {
+	SectionID  uuid.UUID `json:"section_id"`
+	DeadlineAt time.Time `json:"deadline_at"`
+	Closed     bool      `json:"closed"`
+}
+
+// AttemptSession is what a device needs to resume an attempt: the server's
+// clock, the time left and the autosaved draft
+type AttemptSession struct {
+	Submission       *UserExerciseAttempt `json:"submission"`
+	ServerTime       time.Time            `json:"server_time"`
+	RemainingSeconds *int                 `json:"remaining_seconds,omitempty"` // nil when untimed
+	SectionDeadlines []SectionDeadline    `json:"section_deadlines,omitempty"`
+	Draft            *AttemptDraft        `json:"draft"`
+}
+
+// CreateMockTestRequest puts an exercise of each skill together as a mock
+// test. The reading exercise must be of the mock test's IELTS test type.
+type CreateMockTestRequest struct {
+	Title                string    `json:"title" binding:"required,max=255"`
+	Description          *string   `json:"description"`
+	IELTSTestType        string    `json:"ielts_test_type" binding:"required,oneof=academic general_training"`
+	ListeningExerciseID  uuid.UUID `json:"listening_exercise_id" binding:"required"`
+	ReadingExerciseID    uuid.UUID `json:"reading_exercise_id" binding:"required"`
+	WritingExerciseID    uuid.UUID `json:"writing_exercise_id" binding:"required"`
+	SpeakingExerciseID   uuid.UUID `json:"speaking_exercise_id" binding:"required"`
+	SpeakingBreakMinutes *int      `json:"speaking_break_minutes" binding:"omitempty,min=0,max=1440"` // defaults to 10
+	IsPublished          bool      `json:"is_published"`
+}
+
+// UpdateMockTestRequest changes a mock test. Sittings already started keep
+// the exercises they started with.
+type UpdateMockTestRequest struct {
+	Title                *string    `json:"title" binding:"omitempty,max=255"`
+	Description          *string    `json:"description"`
+	IELTSTestType        *string    `json:"ielts_test_type" binding:"omitempty,oneof=academic general_training"`
+	ListeningExerciseID  *uuid.UUID `json:"listening_exercise_id"`
+	ReadingExerciseID    *uuid.UUID `json:"reading_exercise_id"`
+	WritingExerciseID    *uuid.UUID `json:"writing_exercise_id"`
+	SpeakingExerciseID   *uuid.UUID `json:"speaking_exercise_id"`
+	SpeakingBreakMinutes *int       `json:"speaking_break_minutes" binding:"omitempty,min=0,max=1440"`
+	IsPublished          *bool      `json:"is_published"`
+}
+
+// StartAdaptivePracticeRequest starts adaptive practice at a skill and question type
+type StartAdaptivePracticeRequest struct {
+	SkillType    string `json:"skill_type" binding:"required,oneof=listening reading"`
+	QuestionType string `json:"question_type" binding:"required,max=50"`
+}
+
+// AdaptiveAnswerRequest answers the session's current question. Which field
+// is read depends on the question type, as for exercise answers; options are
+// picked by their letters.
+type AdaptiveAnswerRequest struct {
+	QuestionID     uuid.UUID         `json:"question_id" binding:"required"`
+	TextAnswer     *string           `json:"text_answer,omitempty"`
+	SelectedLabels []string          `json:"selected_labels,omitempty"`
+	Matches        map[string]string `json:"matches,omitempty"`
+	Blanks         map[string]string `json:"blanks,omitempty"`
+}
+
+// AdaptiveAnswerResult is the mark for an answer and the session after it
+type AdaptiveAnswerResult struct {
+	QuestionID  uuid.UUID                `json:"question_id"`
+	IsCorrect   bool                     `json:"is_correct"`
+	Marks       int                      `json:"marks"`
+	MaxMarks    int                      `json:"max_marks"`
+	Parts       []AnswerPart             `json:"parts,omitempty"`
+	Explanation *string                  `json:"explanation,omitempty"`
+	Session     *AdaptivePracticeSession `json:"session"`
+}
@@ -129,6 +129,7 @@ type QuestionAnswer struct {
 	AlternativeAnswers *string   `json:"alternative_answers,omitempty"` // JSON array
 	IsCaseSensitive    bool      `json:"is_case_sensitive"`
 	MatchingOrder      *int      `json:"matching_order,omitempty"`
+	MatchLeft          *string   `json:"match_left,omitempty"`
 	CreatedAt          time.Time `json:"created_at"`
 }
 
@@ -151,7 +152,9 @@ type UserExerciseAttempt struct {
 	TimeSpentSeconds  int        `json:"time_spent_seconds"`
 	StartedAt         time.Time  `json:"started_at"`
 	CompletedAt       *time.Time `json:"completed_at,omitempty"`
-	DeviceType        *string    `json:"device_type,omitempty"` // web, android, ios
+	DeadlineAt        *time.Time `json:"deadline_at,omitempty"`       // Server-owned deadline of timed attempts
+	AutoSubmittedAt   *time.Time `json:"auto_submitted_at,omitempty"` // Submitted by the server when time ran out
+	DeviceType        *string    `json:"device_type,omitempty"`       // web, android, ios
 
 	// Writing-specific fields (Phase 4)
 	EssayText  *string `json:"essay_text,omitempty"`
@@ -181,22 +184,40 @@ type UserExerciseAttempt struct {
 	UserServiceLastSyncAttempt *time.Time `json:"user_service_last_sync_attempt"` // Last sync attempt timestamp
 	UserServiceSyncError       *string    `json:"user_service_sync_error"`        // Last sync error message
 
+	AssignmentID      *uuid.UUID `json:"assignment_id,omitempty"`        // Classroom assignment the attempt was started for
+	MockTestSessionID *uuid.UUID `json:"mock_test_session_id,omitempty"` // Mock test sitting the attempt is a stage of
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // SubmissionAnswer represents an answer in a submission (maps to user_answers table)
 type SubmissionAnswer struct {
-	ID               uuid.UUID  `json:"id"`
-	AttemptID        uuid.UUID  `json:"attempt_id"` // FK to user_exercise_attempts
-	QuestionID       uuid.UUID  `json:"question_id"`
-	UserID           uuid.UUID  `json:"user_id"`
-	AnswerText       *string    `json:"answer_text,omitempty"`
-	SelectedOptionID *uuid.UUID `json:"selected_option_id,omitempty"`
-	IsCorrect        *bool      `json:"is_correct,omitempty"`
-	PointsEarned     *float64   `json:"points_earned,omitempty"`
-	TimeSpentSeconds *int       `json:"time_spent_seconds,omitempty"`
-	AnsweredAt       time.Time  `json:"answered_at"`
+	ID                uuid.UUID         `json:"id"`
+	AttemptID         uuid.UUID         `json:"attempt_id"` // FK to user_exercise_attempts
+	QuestionID        uuid.UUID         `json:"question_id"`
+	UserID            uuid.UUID         `json:"user_id"`
+	AnswerText        *string           `json:"answer_text,omitempty"`
+	SelectedOptionID  *uuid.UUID        `json:"selected_option_id,omitempty"`
+	SelectedOptionIDs []uuid.UUID       `json:"selected_option_ids,omitempty"`
+	Matches           map[string]string `json:"matches,omitempty"`
+	Blanks            map[string]string `json:"blanks,omitempty"`
+	IsCorrect         *bool             `json:"is_correct,omitempty"`
+	PointsEarned      *float64          `json:"points_earned,omitempty"`
+	// Marks and MaxMarks count answer-sheet question numbers; Parts marks each
+	// gap, item or letter of a multi-part question
+	Marks            *int         `json:"marks,omitempty"`
+	MaxMarks         *int         `json:"max_marks,omitempty"`
+	Parts            []AnswerPart `json:"parts,omitempty"`
+	TimeSpentSeconds *int         `json:"time_spent_seconds,omitempty"`
+	AnsweredAt       time.Time    `json:"answered_at"`
+}
+
+// AnswerPart is the mark for one gap, item or letter of an answer
+type AnswerPart struct {
+	Part      string `json:"part"`
+	Answer    string `json:"answer,omitempty"`
+	IsCorrect bool   `json:"is_correct"`
 }
 
 // ExerciseTag represents a tag for exercises
@@ -225,8 +246,13 @@ type QuestionBank struct {
 	CreatedBy    uuid.UUID `json:"created_by"`
 	IsVerified   bool      `json:"is_verified"`
 	IsPublished  bool      `json:"is_published"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	// Item response theory parameters, nil until calibrated
+	IRTDiscrimination *float64   `json:"irt_discrimination,omitempty"`
+	IRTDifficulty     *float64   `json:"irt_difficulty,omitempty"`
+	IRTResponses      int        `json:"irt_responses"`
+	IRTCalibratedAt   *time.Time `json:"irt_calibrated_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
 // ExerciseAnalytics represents analytics for an exercise
@@ -379,3 +405,258 @@ type AIEvaluationResult struct {
 	Feedback         string                 `json:"feedback"`
 	CriteriaScores   map[string]float64     `json:"criteria_scores"` // TA, CC, LR, GRA for writing; Fluency, Lexical, Grammar, Pronunciation for speaking
 }
+
+// Assignment is an exercise or lesson a teacher sets for a classroom
+type Assignment struct {
+	ID                 uuid.UUID   `json:"id"`
+	ClassroomID        uuid.UUID   `json:"classroom_id"`
+	Title              string      `json:"title"`
+	Instructions       *string     `json:"instructions,omitempty"`
+	ItemType           string      `json:"item_type"` // exercise, lesson
+	ExerciseID         *uuid.UUID  `json:"exercise_id,omitempty"`
+	LessonID           *uuid.UUID  `json:"lesson_id,omitempty"`
+	CourseID           *uuid.UUID  `json:"course_id,omitempty"`
+	ItemTitle          string      `json:"item_title"`
+	StudentIDs         []uuid.UUID `json:"student_ids,omitempty"` // empty = every student of the classroom
+	OpenAt             time.Time   `json:"open_at"`
+	DueAt              time.Time   `json:"due_at"`
+	MaxAttempts        *int        `json:"max_attempts,omitempty"` // nil = unlimited
+	LatePolicy         string      `json:"late_policy"`            // allow, penalty, block
+	LatePenaltyPercent int         `json:"late_penalty_percent"`
+	DueReminderSentAt  *time.Time  `json:"-"`
+	CreatedBy          uuid.UUID   `json:"created_by"`
+	CreatedAt          time.Time   `json:"created_at"`
+	UpdatedAt          time.Time   `json:"updated_at"`
+}
+
+// AssignedTo reports whether the assignment is set for the student
+func (a *Assignment) AssignedTo(studentID uuid.UUID) bool {
+	if len(a.StudentIDs) == 0 {
+		return true
+	}
+	for _, id := range a.StudentIDs {
+		if id == studentID {
+			return true
+		}
+	}
+	return false
+}
+
+// AssignmentAttempt is the part of an attempt the gradebook needs
+type AssignmentAttempt struct {
+	AssignmentID uuid.UUID
+	UserID       uuid.UUID
+	Score        *float64
+	BandScore    *float64
+	CompletedAt  *time.Time // set once submitted, even while a writing or speaking attempt awaits its band
+}
+
+// SubmissionReview is an instructor's review of an AI-evaluated writing or
+// speaking attempt
+type SubmissionReview struct {
+	ID                  uuid.UUID          `json:"id"`
+	AttemptID           uuid.UUID          `json:"attempt_id"`
+	SkillType           string             `json:"skill_type"` // writing, speaking
+	Status              string             `json:"status"`     // queued, claimed, published
+	ReviewerID          *uuid.UUID         `json:"reviewer_id,omitempty"`
+	ClaimedAt           *time.Time         `json:"claimed_at,omitempty"`
+	AIBandScore         *float64           `json:"ai_band_score,omitempty"`
+	AICriteriaScores    map[string]float64 `json:"ai_criteria_scores"`
+	CriteriaOverrides   map[string]float64 `json:"criteria_overrides"`   // only the criteria the reviewer changed
+	BandScore           *float64           `json:"band_score,omitempty"` // recalculated with the overrides
+	ReviewerFeedback    *string            `json:"reviewer_feedback,omitempty"`
+	PublishedAt         *time.Time         `json:"published_at,omitempty"`
+	UserServiceSyncedAt *time.Time         `json:"-"`
+	CreatedAt           time.Time          `json:"created_at"`
+	UpdatedAt           time.Time          `json:"updated_at"`
+}
+
+// CriteriaScores returns the AI criteria scores with the reviewer's overrides applied
+func (r *SubmissionReview) CriteriaScores() map[string]float64 {
+	scores := make(map[string]float64, len(r.AICriteriaScores))
+	for k, v := range r.AICriteriaScores {
+		scores[k] = v
+	}
+	for k, v := range r.CriteriaOverrides {
+		scores[k] = v
+	}
+	return scores
+}
+
+// ReviewComment is a reviewer's comment on a span of the essay, a stretch of
+// the recording, or the whole submission
+type ReviewComment struct {
+	ID           uuid.UUID `json:"id"`
+	ReviewID     uuid.UUID `json:"review_id"`
+	AuthorID     uuid.UUID `json:"author_id"`
+	AnchorType   string    `json:"anchor_type"` // text_span, audio_timestamp, general
+	StartOffset  *int      `json:"start_offset,omitempty"`
+	EndOffset    *int      `json:"end_offset,omitempty"`
+	QuotedText   *string   `json:"quoted_text,omitempty"`
+	StartSeconds *float64  `json:"start_seconds,omitempty"`
+	EndSeconds   *float64  `json:"end_seconds,omitempty"`
+	Comment      string    `json:"comment"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MockTest is a full IELTS sitting: an exercise for each skill, taken in
+// the order Listening, Reading, Writing, Speaking
+type MockTest struct {
+	ID                   uuid.UUID `json:"id"`
+	Title                string    `json:"title"`
+	Description          *string   `json:"description,omitempty"`
+	IELTSTestType        string    `json:"ielts_test_type"` // academic, general_training
+	ListeningExerciseID  uuid.UUID `json:"listening_exercise_id"`
+	ReadingExerciseID    uuid.UUID `json:"reading_exercise_id"`
+	WritingExerciseID    uuid.UUID `json:"writing_exercise_id"`
+	SpeakingExerciseID   uuid.UUID `json:"speaking_exercise_id"`
+	SpeakingBreakMinutes int       `json:"speaking_break_minutes"` // break between Writing and Speaking
+	IsPublished          bool      `json:"is_published"`
+	CreatedBy            uuid.UUID `json:"created_by"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// ExerciseIDs returns the exercises of the mock test in stage order
+func (m *MockTest) ExerciseIDs() []uuid.UUID {
+	return []uuid.UUID{m.ListeningExerciseID, m.ReadingExerciseID, m.WritingExerciseID, m.SpeakingExerciseID}
+}
+
+// MockTestSession is a learner's sitting of a mock test. Each stage is an
+// exercise attempt linked to the session.
+type MockTestSession struct {
+	ID            uuid.UUID            `json:"id"`
+	MockTestID    uuid.UUID            `json:"mock_test_id"`
+	UserID        uuid.UUID            `json:"user_id"`
+	Status        string               `json:"status"`        // in_progress, awaiting_results, completed, incomplete
+	CurrentStage  int                  `json:"current_stage"` // 0 listening .. 3 speaking
+	ListeningBand *float64             `json:"listening_band,omitempty"`
+	ReadingBand   *float64             `json:"reading_band,omitempty"`
+	WritingBand   *float64             `json:"writing_band,omitempty"`
+	SpeakingBand  *float64             `json:"speaking_band,omitempty"`
+	OverallBand   *float64             `json:"overall_band,omitempty"`
+	ScoreReport   *MockTestScoreReport `json:"score_report,omitempty"`
+	StartedAt     time.Time            `json:"started_at"`
+	CompletedAt   *time.Time           `json:"completed_at,omitempty"`
+
+	UserServiceSyncedAt     *time.Time `json:"user_service_synced_at,omitempty"`
+	UserServiceSyncAttempts int        `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Filled in for responses
+	Title                string          `json:"title,omitempty"`
+	Stages               []MockTestStage `json:"stages,omitempty"`
+	NextStageAvailableAt *time.Time      `json:"next_stage_available_at,omitempty"`
+}
+
+// MockTestStage is one skill of a mock test sitting and its attempt
+type MockTestStage struct {
+	Stage              int        `json:"stage"`
+	Skill              string     `json:"skill"`
+	ExerciseID         uuid.UUID  `json:"exercise_id"`
+	DurationMinutes    int        `json:"duration_minutes"`
+	BreakBeforeMinutes int        `json:"break_before_minutes"`
+	AttemptID          *uuid.UUID `json:"attempt_id,omitempty"`
+	Status             string     `json:"status"` // not_started, in_progress, completed, abandoned
+	EvaluationStatus   *string    `json:"evaluation_status,omitempty"`
+	StartedAt          *time.Time `json:"started_at,omitempty"`
+	DeadlineAt         *time.Time `json:"deadline_at,omitempty"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	BandScore          *float64   `json:"band_score,omitempty"`
+}
+
+// MockTestScoreReport is the combined result of a completed sitting, also
+// recorded in user-service
+type MockTestScoreReport struct {
+	MockTestID    uuid.UUID             `json:"mock_test_id"`
+	Title         string                `json:"title"`
+	IELTSTestType string                `json:"ielts_test_type"`
+	TestDate      time.Time             `json:"test_date"`
+	OverallBand   float64               `json:"overall_band"`
+	Skills        []MockTestSkillResult `json:"skills"`
+}
+
+// MockTestSkillResult is one skill's line of a score report
+type MockTestSkillResult struct {
+	Skill            string             `json:"skill"`
+	ExerciseID       uuid.UUID          `json:"exercise_id"`
+	ExerciseTitle    string             `json:"exercise_title"`
+	AttemptID        uuid.UUID          `json:"attempt_id"`
+	BandScore        float64            `json:"band_score"`
+	RawScore         *int               `json:"raw_score,omitempty"`       // listening and reading
+	TotalQuestions   *int               `json:"total_questions,omitempty"` // listening and reading
+	CriteriaScores   map[string]float64 `json:"criteria_scores,omitempty"` // writing and speaking
+	TimeSpentSeconds int                `json:"time_spent_seconds"`
+	AutoSubmitted    bool               `json:"auto_submitted"`
+}
+
+// AdaptivePracticeSession is a run of bank questions picked one at a time to
+// match the learner's estimated ability at a skill and question type. Theta is
+// ability on the items' logit scale; the band and its 95% confidence interval
+// are worked out from it.
+type AdaptivePracticeSession struct {
+	ID                 uuid.UUID  `json:"id"`
+	UserID             uuid.UUID  `json:"user_id"`
+	SkillType          string     `json:"skill_type"`
+	QuestionType       string     `json:"question_type"`
+	Status             string     `json:"status"`                // in_progress, completed
+	StopReason         *string    `json:"stop_reason,omitempty"` // converged, max_items, bank_exhausted
+	PriorTheta         float64    `json:"prior_theta"`
+	PriorStandardError float64    `json:"prior_standard_error"`
+	PriorResponses     int        `json:"prior_responses"` // earlier answers the prior was estimated from
+	Theta              float64    `json:"theta"`
+	StandardError      float64    `json:"standard_error"`
+	ItemsAnswered      int        `json:"items_answered"`
+	CurrentQuestionID  *uuid.UUID `json:"-"`
+	EstimatedBand      float64    `json:"estimated_band"`
+	BandLower          float64    `json:"band_lower"`
+	BandUpper          float64    `json:"band_upper"`
+	StartedAt          time.Time  `json:"started_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// The question to answer next while in progress
+	NextQuestion *AdaptiveQuestion `json:"next_question,omitempty"`
+}
+
+// AdaptiveQuestion is a bank question as shown to the learner, without its answer
+type AdaptiveQuestion struct {
+	ID           uuid.UUID   `json:"id"`
+	Title        *string     `json:"title,omitempty"`
+	QuestionType string      `json:"question_type"`
+	QuestionText string      `json:"question_text"`
+	ContextText  *string     `json:"context_text,omitempty"`
+	AudioURL     *string     `json:"audio_url,omitempty"`
+	ImageURL     *string     `json:"image_url,omitempty"`
+	Options      interface{} `json:"options,omitempty"` // answer_data.options, when the question has them
+}
+
+// AdaptivePracticeResponse is a learner's answer to a bank question in a session
+type AdaptivePracticeResponse struct {
+	ID                 uuid.UUID `json:"id"`
+	SessionID          uuid.UUID `json:"session_id"`
+	UserID             uuid.UUID `json:"user_id"`
+	BankQuestionID     uuid.UUID `json:"bank_question_id"`
+	Score              float64   `json:"score"` // share of the question's marks earned
+	IsCorrect          bool      `json:"is_correct"`
+	ThetaBefore        float64   `json:"theta_before"`
+	ThetaAfter         float64   `json:"theta_after"`
+	StandardErrorAfter float64   `json:"standard_error_after"`
+	AnsweredAt         time.Time `json:"answered_at"`
+}
+
+// AbilityEstimate is a learner's estimated ability at a skill and question
+// type from their answers to exercises and adaptive practice
+type AbilityEstimate struct {
+	SkillType     string  `json:"skill_type"`
+	QuestionType  string  `json:"question_type"`
+	Responses     int     `json:"responses"`
+	Theta         float64 `json:"theta"`
+	StandardError float64 `json:"standard_error"`
+	EstimatedBand float64 `json:"estimated_band"`
+	BandLower     float64 `json:"band_lower"`
+	BandUpper     float64 `json:"band_upper"`
+}
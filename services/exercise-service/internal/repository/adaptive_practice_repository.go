@@ -0,0 +1,376 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/irt"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const adaptiveSessionColumns = `
+	id, user_id, skill_type, question_type, status, stop_reason, prior_theta,
+	prior_standard_error, prior_responses, theta, standard_error, items_answered,
+	current_question_id, estimated_band, band_lower, band_upper, started_at,
+	completed_at, created_at, updated_at`
+
+// ItemEvidence is a learner's score on a question with what is known of the
+// question's difficulty: its calibrated parameters for bank questions, or how
+// other learners scored on it for exercise questions
+type ItemEvidence struct {
+	QuestionType    string
+	Score           float64
+	DifficultyLabel *string
+	Discrimination  *float64
+	Difficulty      *float64
+	ItemResponses   int
+	ItemScore       float64
+}
+
+// answerScore is the share of a graded answer's marks earned, for the
+// user_answers row with the given alias
+func answerScore(alias string) string {
+	return fmt.Sprintf(`CASE WHEN %[1]s.max_marks > 0 THEN %[1]s.marks_earned::float / %[1]s.max_marks
+		WHEN %[1]s.is_correct THEN 1 ELSE 0 END`, alias)
+}
+
+// GetBankQuestionByID returns a bank question, or sql.ErrNoRows
+func (r *ExerciseRepository) GetBankQuestionByID(id uuid.UUID) (*models.QuestionBank, error) {
+	q, err := scanBankQuestion(r.db.QueryRow(`SELECT `+bankQuestionColumns+` FROM question_bank WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank question: %w", err)
+	}
+	return q, nil
+}
+
+// GetAbilityEvidence returns the user's latest graded answers at a skill,
+// from exercises and from adaptive practice outside excludeSession, newest
+// first and at most limit of each. An empty questionType returns every type.
+func (r *ExerciseRepository) GetAbilityEvidence(userID uuid.UUID, skillType, questionType string, excludeSession *uuid.UUID, limit int) ([]ItemEvidence, error) {
+	rows, err := r.db.Query(`
+		WITH mine AS (
+			SELECT ua.question_id, q.question_type, COALESCE(q.difficulty, e.difficulty) AS difficulty,
+				`+answerScore("ua")+` AS score
+			FROM user_answers ua
+			JOIN questions q ON q.id = ua.question_id
+			JOIN exercises e ON e.id = q.exercise_id
+			WHERE ua.user_id = $1 AND e.skill_type = $2 AND ($3 = '' OR q.question_type = $3)
+				AND ua.is_correct IS NOT NULL
+			ORDER BY ua.answered_at DESC
+			LIMIT $4
+		)
+		SELECT m.question_type, m.score, m.difficulty, others.n, others.total
+		FROM mine m
+		CROSS JOIN LATERAL (
+			SELECT COUNT(*) AS n, COALESCE(SUM(`+answerScore("o")+`), 0) AS total
+			FROM user_answers o
+			WHERE o.question_id = m.question_id AND o.user_id <> $1 AND o.is_correct IS NOT NULL
+		) others
+	`, userID, skillType, questionType, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exercise answers: %w", err)
+	}
+	evidence, err := scanItemEvidence(rows, func(e *ItemEvidence) []interface{} {
+		return []interface{}{&e.QuestionType, &e.Score, &e.DifficultyLabel, &e.ItemResponses, &e.ItemScore}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = r.db.Query(`
+		SELECT s.question_type, r.score, qb.difficulty, qb.irt_discrimination, qb.irt_difficulty
+		FROM adaptive_practice_responses r
+		JOIN adaptive_practice_sessions s ON s.id = r.session_id
+		JOIN question_bank qb ON qb.id = r.bank_question_id
+		WHERE r.user_id = $1 AND s.skill_type = $2 AND ($3 = '' OR s.question_type = $3)
+			AND r.session_id IS DISTINCT FROM $4
+		ORDER BY r.answered_at DESC
+		LIMIT $5
+	`, userID, skillType, questionType, excludeSession, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adaptive practice answers: %w", err)
+	}
+	practice, err := scanItemEvidence(rows, func(e *ItemEvidence) []interface{} {
+		return []interface{}{&e.QuestionType, &e.Score, &e.DifficultyLabel, &e.Discrimination, &e.Difficulty}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(evidence, practice...), nil
+}
+
+func scanItemEvidence(rows *sql.Rows, fields func(*ItemEvidence) []interface{}) ([]ItemEvidence, error) {
+	defer rows.Close()
+	evidence := []ItemEvidence{}
+	for rows.Next() {
+		var e ItemEvidence
+		if err := rows.Scan(fields(&e)...); err != nil {
+			return nil, fmt.Errorf("failed to scan answer: %w", err)
+		}
+		evidence = append(evidence, e)
+	}
+	return evidence, rows.Err()
+}
+
+// GetAdaptiveCandidates returns the published bank questions of a skill and
+// type that the user has not answered in adaptive practice since
+// answeredSince, at most limit of them
+func (r *ExerciseRepository) GetAdaptiveCandidates(userID uuid.UUID, skillType, questionType string, answeredSince time.Time, limit int) ([]models.QuestionBank, error) {
+	rows, err := r.db.Query(`
+		SELECT `+bankQuestionColumns+`
+		FROM question_bank qb
+		WHERE skill_type = $1 AND question_type = $2 AND is_published
+			AND NOT EXISTS (
+				SELECT 1 FROM adaptive_practice_responses r
+				WHERE r.bank_question_id = qb.id AND r.user_id = $3 AND r.answered_at >= $4
+			)
+		ORDER BY times_used, id
+		LIMIT $5
+	`, skillType, questionType, userID, answeredSince, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank questions: %w", err)
+	}
+	defer rows.Close()
+
+	questions := []models.QuestionBank{}
+	for rows.Next() {
+		q, err := scanBankQuestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bank question: %w", err)
+		}
+		questions = append(questions, *q)
+	}
+	return questions, rows.Err()
+}
+
+// CreateAdaptiveSession stores a new session and fills in its ID and timestamps
+func (r *ExerciseRepository) CreateAdaptiveSession(s *models.AdaptivePracticeSession) error {
+	err := r.db.QueryRow(`
+		INSERT INTO adaptive_practice_sessions (
+			user_id, skill_type, question_type, prior_theta, prior_standard_error,
+			prior_responses, theta, standard_error, current_question_id,
+			estimated_band, band_lower, band_upper
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id, status, started_at, created_at, updated_at
+	`, s.UserID, s.SkillType, s.QuestionType, s.PriorTheta, s.PriorStandardError,
+		s.PriorResponses, s.Theta, s.StandardError, s.CurrentQuestionID,
+		s.EstimatedBand, s.BandLower, s.BandUpper,
+	).Scan(&s.ID, &s.Status, &s.StartedAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create adaptive practice session: %w", err)
+	}
+	return nil
+}
+
+// GetAdaptiveSession returns a session, or sql.ErrNoRows
+func (r *ExerciseRepository) GetAdaptiveSession(id uuid.UUID) (*models.AdaptivePracticeSession, error) {
+	s, err := scanAdaptiveSession(r.db.QueryRow(`SELECT `+adaptiveSessionColumns+` FROM adaptive_practice_sessions WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get adaptive practice session: %w", err)
+	}
+	return s, nil
+}
+
+// GetActiveAdaptiveSession returns the user's session in progress at a skill
+// and question type, or nil
+func (r *ExerciseRepository) GetActiveAdaptiveSession(userID uuid.UUID, skillType, questionType string) (*models.AdaptivePracticeSession, error) {
+	s, err := scanAdaptiveSession(r.db.QueryRow(`
+		SELECT `+adaptiveSessionColumns+`
+		FROM adaptive_practice_sessions
+		WHERE user_id = $1 AND skill_type = $2 AND question_type = $3 AND status = 'in_progress'
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, userID, skillType, questionType))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active adaptive practice session: %w", err)
+	}
+	return s, nil
+}
+
+// GetAdaptiveResponses returns a session's responses in the order they were
+// given, with the evidence each gives about the learner's ability
+func (r *ExerciseRepository) GetAdaptiveResponses(sessionID uuid.UUID) ([]models.AdaptivePracticeResponse, []ItemEvidence, error) {
+	rows, err := r.db.Query(`
+		SELECT r.id, r.session_id, r.user_id, r.bank_question_id, r.score, r.is_correct,
+			r.theta_before, r.theta_after, r.standard_error_after, r.answered_at,
+			qb.question_type, qb.difficulty, qb.irt_discrimination, qb.irt_difficulty
+		FROM adaptive_practice_responses r
+		JOIN question_bank qb ON qb.id = r.bank_question_id
+		WHERE r.session_id = $1
+		ORDER BY r.answered_at
+	`, sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get adaptive practice responses: %w", err)
+	}
+	defer rows.Close()
+
+	responses := []models.AdaptivePracticeResponse{}
+	evidence := []ItemEvidence{}
+	for rows.Next() {
+		var resp models.AdaptivePracticeResponse
+		var e ItemEvidence
+		err := rows.Scan(
+			&resp.ID, &resp.SessionID, &resp.UserID, &resp.BankQuestionID, &resp.Score, &resp.IsCorrect,
+			&resp.ThetaBefore, &resp.ThetaAfter, &resp.StandardErrorAfter, &resp.AnsweredAt,
+			&e.QuestionType, &e.DifficultyLabel, &e.Discrimination, &e.Difficulty,
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan adaptive practice response: %w", err)
+		}
+		e.Score = resp.Score
+		responses = append(responses, resp)
+		evidence = append(evidence, e)
+	}
+	return responses, evidence, rows.Err()
+}
+
+// RecordAdaptiveAnswer stores a response and the session's new estimate, next
+// question and status in one transaction. It returns false, storing nothing,
+// when the session is no longer waiting for an answer to questionID.
+func (r *ExerciseRepository) RecordAdaptiveAnswer(s *models.AdaptivePracticeSession, resp *models.AdaptivePracticeResponse, answerData []byte, questionID uuid.UUID) (bool, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE adaptive_practice_sessions
+		SET status = $3, stop_reason = $4, theta = $5, standard_error = $6,
+		    items_answered = items_answered + 1, current_question_id = $7,
+		    estimated_band = $8, band_lower = $9, band_upper = $10, completed_at = $11
+		WHERE id = $1 AND status = 'in_progress' AND current_question_id = $2
+	`, s.ID, questionID, s.Status, s.StopReason, s.Theta, s.StandardError, s.CurrentQuestionID,
+		s.EstimatedBand, s.BandLower, s.BandUpper, s.CompletedAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to update adaptive practice session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO adaptive_practice_responses (
+			session_id, user_id, bank_question_id, answer_data, score, is_correct,
+			theta_before, theta_after, standard_error_after
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, answered_at
+	`, resp.SessionID, resp.UserID, resp.BankQuestionID, answerData, resp.Score, resp.IsCorrect,
+		resp.ThetaBefore, resp.ThetaAfter, resp.StandardErrorAfter,
+	).Scan(&resp.ID, &resp.AnsweredAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to store adaptive practice response: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE question_bank SET times_used = times_used + 1 WHERE id = $1`, questionID); err != nil {
+		return false, fmt.Errorf("failed to count bank question use: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit adaptive practice answer: %w", err)
+	}
+	s.ItemsAnswered++
+	return true, nil
+}
+
+// GetItemsToCalibrate returns bank questions answered in at least
+// minResponses completed sessions and in minNew more than when they were
+// last calibrated, most new responses first
+func (r *ExerciseRepository) GetItemsToCalibrate(minResponses, minNew, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT qb.id
+		FROM question_bank qb
+		JOIN (
+			SELECT r.bank_question_id, COUNT(*) AS n
+			FROM adaptive_practice_responses r
+			JOIN adaptive_practice_sessions s ON s.id = r.session_id AND s.status = 'completed'
+			GROUP BY r.bank_question_id
+		) c ON c.bank_question_id = qb.id
+		WHERE c.n >= $1 AND c.n >= qb.irt_responses + $2
+		ORDER BY c.n - qb.irt_responses DESC
+		LIMIT $3
+	`, minResponses, minNew, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bank questions to calibrate: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan bank question to calibrate: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetCalibrationObservations returns the scores on a bank question in
+// completed sessions, each with the learner's final ability estimate
+func (r *ExerciseRepository) GetCalibrationObservations(bankQuestionID uuid.UUID) ([]irt.Observation, error) {
+	rows, err := r.db.Query(`
+		SELECT s.theta, r.score
+		FROM adaptive_practice_responses r
+		JOIN adaptive_practice_sessions s ON s.id = r.session_id AND s.status = 'completed'
+		WHERE r.bank_question_id = $1
+	`, bankQuestionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get calibration responses: %w", err)
+	}
+	defer rows.Close()
+
+	observations := []irt.Observation{}
+	for rows.Next() {
+		var o irt.Observation
+		if err := rows.Scan(&o.Theta, &o.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan calibration response: %w", err)
+		}
+		observations = append(observations, o)
+	}
+	return observations, rows.Err()
+}
+
+// UpdateItemParameters saves a bank question's calibrated parameters
+func (r *ExerciseRepository) UpdateItemParameters(bankQuestionID uuid.UUID, item irt.Item, responses int) error {
+	_, err := r.db.Exec(`
+		UPDATE question_bank
+		SET irt_discrimination = $2, irt_difficulty = $3, irt_responses = $4, irt_calibrated_at = NOW()
+		WHERE id = $1
+	`, bankQuestionID, item.Discrimination, item.Difficulty, responses)
+	if err != nil {
+		return fmt.Errorf("failed to update item parameters: %w", err)
+	}
+	return nil
+}
+
+func scanAdaptiveSession(row rowScanner) (*models.AdaptivePracticeSession, error) {
+	var s models.AdaptivePracticeSession
+	err := row.Scan(
+		&s.ID, &s.UserID, &s.SkillType, &s.QuestionType, &s.Status, &s.StopReason, &s.PriorTheta,
+		&s.PriorStandardError, &s.PriorResponses, &s.Theta, &s.StandardError, &s.ItemsAnswered,
+		&s.CurrentQuestionID, &s.EstimatedBand, &s.BandLower, &s.BandUpper, &s.StartedAt,
+		&s.CompletedAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// SetAdaptiveCurrentQuestion replaces the question an in-progress session is
+// waiting on, when the one it was waiting on was deleted from the bank
+func (r *ExerciseRepository) SetAdaptiveCurrentQuestion(sessionID, questionID uuid.UUID) error {
+	_, err := r.db.Exec(`
+		UPDATE adaptive_practice_sessions SET current_question_id = $2
+		WHERE id = $1 AND status = 'in_progress' AND current_question_id IS NULL
+	`, sessionID, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to set current question: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const assignmentColumns = `
+	id, classroom_id, title, instructions, item_type, exercise_id, lesson_id, course_id,
+	item_title, student_ids, open_at, due_at, max_attempts, late_policy, late_penalty_percent,
+	due_reminder_sent_at, created_by, created_at, updated_at`
+
+// CreateAssignment stores a new assignment and fills in its ID and timestamps
+func (r *ExerciseRepository) CreateAssignment(a *models.Assignment) error {
+	err := r.db.QueryRow(`
+		INSERT INTO assignments (
+			classroom_id, title, instructions, item_type, exercise_id, lesson_id, course_id,
+			item_title, student_ids, open_at, due_at, max_attempts, late_policy,
+			late_penalty_percent, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		RETURNING id, created_at, updated_at
+	`, a.ClassroomID, a.Title, a.Instructions, a.ItemType, a.ExerciseID, a.LessonID, a.CourseID,
+		a.ItemTitle, studentIDArray(a.StudentIDs), a.OpenAt, a.DueAt, a.MaxAttempts, a.LatePolicy,
+		a.LatePenaltyPercent, a.CreatedBy,
+	).Scan(&a.ID, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create assignment: %w", err)
+	}
+	return nil
+}
+
+// GetAssignmentByID returns an assignment
+func (r *ExerciseRepository) GetAssignmentByID(id uuid.UUID) (*models.Assignment, error) {
+	row := r.db.QueryRow(`SELECT `+assignmentColumns+` FROM assignments WHERE id = $1`, id)
+	a, err := scanAssignment(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("assignment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment: %w", err)
+	}
+	return a, nil
+}
+
+// ListAssignmentsByClassrooms returns the assignments of the classrooms, by due date
+func (r *ExerciseRepository) ListAssignmentsByClassrooms(classroomIDs []uuid.UUID) ([]models.Assignment, error) {
+	rows, err := r.db.Query(`
+		SELECT `+assignmentColumns+`
+		FROM assignments
+		WHERE classroom_id = ANY($1::uuid[])
+		ORDER BY due_at, created_at
+	`, studentIDArray(classroomIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list assignments: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAssignments(rows)
+}
+
+// UpdateAssignment saves the editable fields of an assignment. Moving the
+// due date clears the reminder so learners are reminded of the new one.
+func (r *ExerciseRepository) UpdateAssignment(a *models.Assignment) error {
+	err := r.db.QueryRow(`
+		UPDATE assignments
+		SET title = $2, instructions = $3, student_ids = $4, open_at = $5, due_at = $6,
+		    max_attempts = $7, late_policy = $8, late_penalty_percent = $9,
+		    due_reminder_sent_at = CASE WHEN due_at = $6 THEN due_reminder_sent_at END
+		WHERE id = $1
+		RETURNING updated_at
+	`, a.ID, a.Title, a.Instructions, studentIDArray(a.StudentIDs), a.OpenAt, a.DueAt,
+		a.MaxAttempts, a.LatePolicy, a.LatePenaltyPercent,
+	).Scan(&a.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("assignment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update assignment: %w", err)
+	}
+	return nil
+}
+
+// DeleteAssignment deletes an assignment. Attempts made for it are kept as
+// ordinary practice.
+func (r *ExerciseRepository) DeleteAssignment(id uuid.UUID) error {
+	result, err := r.db.Exec(`DELETE FROM assignments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete assignment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("assignment not found")
+	}
+	return nil
+}
+
+// CreateAssignmentSubmission starts an attempt for an exercise assignment.
+// The assignment row is locked while attempts are counted so parallel starts
+// cannot exceed the attempt limit; it returns nil when the limit is reached.
+func (r *ExerciseRepository) CreateAssignmentSubmission(userID uuid.UUID, a *models.Assignment, deviceType *string) (*models.UserExerciseAttempt, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM assignments WHERE id = $1 FOR UPDATE`, a.ID); err != nil {
+		return nil, fmt.Errorf("failed to lock assignment: %w", err)
+	}
+
+	if a.MaxAttempts != nil {
+		var used int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM user_exercise_attempts
+			WHERE assignment_id = $1 AND user_id = $2
+		`, a.ID, userID).Scan(&used)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count attempts: %w", err)
+		}
+		if used >= *a.MaxAttempts {
+			return nil, nil
+		}
+	}
+
+	submission, err := createSubmission(tx, userID, *a.ExerciseID, deviceType, &a.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit attempt: %w", err)
+	}
+	return submission, nil
+}
+
+// GetAssignmentAttempts returns the attempts made for the assignments,
+// optionally only those of one user
+func (r *ExerciseRepository) GetAssignmentAttempts(assignmentIDs []uuid.UUID, userID *uuid.UUID) ([]models.AssignmentAttempt, error) {
+	rows, err := r.db.Query(`
+		SELECT assignment_id, user_id, score, band_score, completed_at
+		FROM user_exercise_attempts
+		WHERE assignment_id = ANY($1::uuid[]) AND ($2::uuid IS NULL OR user_id = $2)
+		ORDER BY started_at
+	`, studentIDArray(assignmentIDs), userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []models.AssignmentAttempt{}
+	for rows.Next() {
+		var a models.AssignmentAttempt
+		if err := rows.Scan(&a.AssignmentID, &a.UserID, &a.Score, &a.BandScore, &a.CompletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan assignment attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get assignment attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+// GetAssignmentsDueForReminder returns open assignments due before the given
+// time whose learners have not been reminded yet
+func (r *ExerciseRepository) GetAssignmentsDueForReminder(before time.Time, limit int) ([]models.Assignment, error) {
+	rows, err := r.db.Query(`
+		SELECT `+assignmentColumns+`
+		FROM assignments
+		WHERE due_reminder_sent_at IS NULL
+		  AND open_at <= CURRENT_TIMESTAMP
+		  AND due_at > CURRENT_TIMESTAMP
+		  AND due_at <= $1
+		ORDER BY due_at
+		LIMIT $2
+	`, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignments due: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAssignments(rows)
+}
+
+// MarkDueReminderSent records that the assignment's learners were reminded
+func (r *ExerciseRepository) MarkDueReminderSent(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE assignments SET due_reminder_sent_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark reminder sent: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAssignment(row rowScanner) (*models.Assignment, error) {
+	var (
+		a          models.Assignment
+		studentIDs pq.StringArray
+	)
+	err := row.Scan(
+		&a.ID, &a.ClassroomID, &a.Title, &a.Instructions, &a.ItemType, &a.ExerciseID, &a.LessonID,
+		&a.CourseID, &a.ItemTitle, &studentIDs, &a.OpenAt, &a.DueAt, &a.MaxAttempts, &a.LatePolicy,
+		&a.LatePenaltyPercent, &a.DueReminderSentAt, &a.CreatedBy, &a.CreatedAt, &a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range studentIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid student ID %q: %w", id, err)
+		}
+		a.StudentIDs = append(a.StudentIDs, parsed)
+	}
+	return &a, nil
+}
+
+func scanAssignments(rows *sql.Rows) ([]models.Assignment, error) {
+	assignments := []models.Assignment{}
+	for rows.Next() {
+		a, err := scanAssignment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan assignment: %w", err)
+		}
+		assignments = append(assignments, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+// studentIDArray converts IDs to a Postgres uuid[] parameter; an empty list
+// is stored as NULL
+func studentIDArray(ids []uuid.UUID) interface{} {
+	if len(ids) == 0 {
+		return nil
+	}
+	arr := make(pq.StringArray, len(ids))
+	for i, id := range ids {
+		arr[i] = id.String()
+	}
+	return arr
+}
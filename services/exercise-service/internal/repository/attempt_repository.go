@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// attemptDeadlines works out when an attempt started at startedAt must end.
+// Sections with a time limit run one after another in section order, so each
+// closes once the limits up to and including it have passed. Without an
+// exercise time limit the sections' limits add up to the attempt's. Untimed
+// attempts have no deadline.
+func attemptDeadlines(q querier, exerciseID uuid.UUID, exerciseLimit *int, startedAt time.Time) (*int, *time.Time, map[uuid.UUID]time.Time, error) {
+	rows, err := q.Query(`
+		SELECT id, time_limit_minutes
+		FROM exercise_sections
+		WHERE exercise_id = $1
+		ORDER BY section_number, display_order
+	`, exerciseID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get section time limits: %w", err)
+	}
+	defer rows.Close()
+
+	sections := map[uuid.UUID]time.Time{}
+	elapsed := 0
+	for rows.Next() {
+		var id uuid.UUID
+		var limit *int
+		if err := rows.Scan(&id, &limit); err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to scan section time limit: %w", err)
+		}
+		if limit != nil && *limit > 0 {
+			elapsed += *limit
+			sections[id] = startedAt.Add(time.Duration(elapsed) * time.Minute)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get section time limits: %w", err)
+	}
+
+	limit := exerciseLimit
+	if limit == nil || *limit <= 0 {
+		if elapsed == 0 {
+			return exerciseLimit, nil, nil, nil
+		}
+		limit = &elapsed
+	}
+	deadline := startedAt.Add(time.Duration(*limit) * time.Minute)
+	for id, at := range sections {
+		if at.After(deadline) {
+			sections[id] = deadline
+		}
+	}
+	return limit, &deadline, sections, nil
+}
+
+// GetAttemptDraft returns an attempt's autosaved draft and the deadlines of
+// its timed sections
+func (r *ExerciseRepository) GetAttemptDraft(submissionID uuid.UUID) (*models.AttemptDraft, map[uuid.UUID]time.Time, error) {
+	var answersJSON, writingJSON, sectionsJSON []byte
+	draft := &models.AttemptDraft{Answers: []models.SubmitAnswerItem{}}
+	err := r.db.QueryRow(`
+		SELECT draft_answers, draft_writing, draft_saved_at, section_deadlines
+		FROM user_exercise_attempts
+		WHERE id = $1
+	`, submissionID).Scan(&answersJSON, &writingJSON, &draft.SavedAt, &sectionsJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(answersJSON) > 0 {
+		var byQuestion map[string]models.SubmitAnswerItem
+		if err := json.Unmarshal(answersJSON, &byQuestion); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode draft answers: %w", err)
+		}
+		keys := make([]string, 0, len(byQuestion))
+		for k := range byQuestion {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			draft.Answers = append(draft.Answers, byQuestion[k])
+		}
+	}
+	if len(writingJSON) > 0 {
+		draft.WritingData = &models.WritingSubmissionData{}
+		if err := json.Unmarshal(writingJSON, draft.WritingData); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode draft essay: %w", err)
+		}
+	}
+	var sections map[uuid.UUID]time.Time
+	if len(sectionsJSON) > 0 {
+		if err := json.Unmarshal(sectionsJSON, &sections); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode section deadlines: %w", err)
+		}
+	}
+	return draft, sections, nil
+}
+
+// SaveAttemptDraft merges answers into an in-progress attempt's draft by
+// question and replaces its essay draft when one is given. It returns
+// sql.ErrNoRows when the attempt is no longer in progress.
+func (r *ExerciseRepository) SaveAttemptDraft(submissionID uuid.UUID, answers []models.SubmitAnswerItem, writing *models.WritingSubmissionData, savedAt time.Time) error {
+	byQuestion := make(map[string]models.SubmitAnswerItem, len(answers))
+	for _, a := range answers {
+		byQuestion[a.QuestionID.String()] = a
+	}
+	answersJSON, err := json.Marshal(byQuestion)
+	if err != nil {
+		return fmt.Errorf("failed to encode draft answers: %w", err)
+	}
+	var writingJSON interface{}
+	if writing != nil {
+		if writingJSON, err = json.Marshal(writing); err != nil {
+			return fmt.Errorf("failed to encode draft essay: %w", err)
+		}
+	}
+
+	result, err := r.db.Exec(`
+		UPDATE user_exercise_attempts SET
+			draft_answers = COALESCE(draft_answers, '{}'::jsonb) || $2::jsonb,
+			draft_writing = COALESCE($3::jsonb, draft_writing),
+			draft_saved_at = $4,
+			updated_at = $4
+		WHERE id = $1 AND status = 'in_progress'
+	`, submissionID, answersJSON, writingJSON, savedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save draft: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetQuestionSectionIDs maps an exercise's questions to their sections
+func (r *ExerciseRepository) GetQuestionSectionIDs(exerciseID uuid.UUID) (map[uuid.UUID]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT id, section_id FROM questions
+		WHERE exercise_id = $1 AND section_id IS NOT NULL
+	`, exerciseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get question sections: %w", err)
+	}
+	defer rows.Close()
+
+	sections := map[uuid.UUID]uuid.UUID{}
+	for rows.Next() {
+		var questionID, sectionID uuid.UUID
+		if err := rows.Scan(&questionID, &sectionID); err != nil {
+			return nil, fmt.Errorf("failed to scan question section: %w", err)
+		}
+		sections[questionID] = sectionID
+	}
+	return sections, rows.Err()
+}
+
+// GetResumableAttempt returns the user's latest timed attempt at the exercise,
+// outside mock tests, that is still in progress with its deadline after
+// openAfter, or nil
+func (r *ExerciseRepository) GetResumableAttempt(userID, exerciseID uuid.UUID, assignmentID *uuid.UUID, openAfter time.Time) (*models.UserExerciseAttempt, error) {
+	var id uuid.UUID
+	err := r.db.QueryRow(`
+		SELECT id FROM user_exercise_attempts
+		WHERE user_id = $1 AND exercise_id = $2 AND assignment_id IS NOT DISTINCT FROM $3
+			AND mock_test_session_id IS NULL AND status = 'in_progress' AND deadline_at > $4
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, userID, exerciseID, assignmentID, openAfter).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find attempt to resume: %w", err)
+	}
+	return r.GetSubmissionByID(id)
+}
+
+// GetExpiredAttempts returns in-progress attempts whose deadline passed before
+// cutoff and that no sweeper has claimed since claimedBefore, oldest first
+func (r *ExerciseRepository) GetExpiredAttempts(cutoff, claimedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM user_exercise_attempts
+		WHERE status = 'in_progress' AND deadline_at < $1
+			AND (auto_submitted_at IS NULL OR auto_submitted_at < $2)
+		ORDER BY deadline_at
+		LIMIT $3
+	`, cutoff, claimedBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired attempts: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expired attempt: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// ClaimExpiredAttempt marks an in-progress attempt as being auto-submitted,
+// so parallel sweepers submit it once. A claim older than claimedBefore is
+// taken to have failed and can be claimed again. A claim racing the
+// learner's SubmitAttempt waits for its row lock and then finds the attempt
+// completed.
+func (r *ExerciseRepository) ClaimExpiredAttempt(submissionID uuid.UUID, now, claimedBefore time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE user_exercise_attempts SET auto_submitted_at = $2, updated_at = $2
+		WHERE id = $1 AND status = 'in_progress'
+			AND (auto_submitted_at IS NULL OR auto_submitted_at < $3)
+	`, submissionID, now, claimedBefore)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim expired attempt: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// AbandonAttempt closes an in-progress attempt that has nothing to submit
+func (r *ExerciseRepository) AbandonAttempt(submissionID uuid.UUID) error {
+	_, err := r.db.Exec(`
+		UPDATE user_exercise_attempts SET status = 'abandoned', updated_at = NOW()
+		WHERE id = $1 AND status = 'in_progress'
+	`, submissionID)
+	if err != nil {
+		return fmt.Errorf("failed to abandon attempt: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/utils"
 	"github.com/google/uuid"
@@ -348,7 +349,7 @@ func (r *ExerciseRepository) GetQuestionsWithOptions(sectionID uuid.UUID) ([]mod
 		// Get options based on question type
 		var options []models.QuestionOption
 
-		if question.QuestionType == "multiple_choice" || question.QuestionType == "matching" {
+		if grading.UsesOptions(question.QuestionType) {
 			optionRows, err := r.db.Query(`
 				SELECT id, question_id, option_label, option_text, option_image_url,
 					is_correct, display_order, created_at
@@ -374,11 +375,32 @@ func (r *ExerciseRepository) GetQuestionsWithOptions(sectionID uuid.UUID) ([]mod
 				options = append(options, option)
 			}
 		}
-		// Note: For text-based questions, answers are not included in public view
+		// Note: For text-based questions, answers are not included in public view,
+		// only the items or gaps of multi-part questions
+		partRows, err := r.db.Query(`
+			SELECT match_left FROM question_answers
+			WHERE question_id = $1 AND match_left IS NOT NULL
+			GROUP BY match_left
+			ORDER BY MIN(created_at)
+		`, question.ID)
+		if err != nil {
+			return nil, err
+		}
+		var parts []string
+		for partRows.Next() {
+			var part string
+			if err := partRows.Scan(&part); err != nil {
+				partRows.Close()
+				return nil, err
+			}
+			parts = append(parts, part)
+		}
+		partRows.Close()
 
 		questions = append(questions, models.QuestionWithOptions{
 			Question: &question,
 			Options:  options,
+			Parts:    parts,
 		})
 	}
 
@@ -387,10 +409,22 @@ func (r *ExerciseRepository) GetQuestionsWithOptions(sectionID uuid.UUID) ([]mod
 
 // CreateSubmission starts a new submission (uses user_exercise_attempts table)
 func (r *ExerciseRepository) CreateSubmission(userID, exerciseID uuid.UUID, deviceType *string) (*models.UserExerciseAttempt, error) {
+	return createSubmission(r.db, userID, exerciseID, deviceType, nil)
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// createSubmission inserts an in-progress attempt, optionally for a classroom
+// assignment, with its deadlines when the exercise or its sections are timed
+func createSubmission(q querier, userID, exerciseID uuid.UUID, deviceType *string, assignmentID *uuid.UUID) (*models.UserExerciseAttempt, error) {
 	// Get exercise details
 	var totalQuestions int
 	var timeLimitMinutes *int
-	err := r.db.QueryRow(`
+	err := q.QueryRow(`
 		SELECT total_questions, time_limit_minutes 
 		FROM exercises 
 		WHERE id = $1
@@ -399,32 +433,44 @@ func (r *ExerciseRepository) CreateSubmission(userID, exerciseID uuid.UUID, devi
 		return nil, err
 	}
 
+	now := time.Now()
+	timeLimitMinutes, deadlineAt, sectionDeadlines, err := attemptDeadlines(q, exerciseID, timeLimitMinutes, now)
+	if err != nil {
+		return nil, err
+	}
+	var sectionDeadlinesJSON interface{}
+	if len(sectionDeadlines) > 0 {
+		if sectionDeadlinesJSON, err = json.Marshal(sectionDeadlines); err != nil {
+			return nil, fmt.Errorf("failed to encode section deadlines: %w", err)
+		}
+	}
+
 	// FIX #13: Use database calculation in INSERT to avoid race condition
 	// Instead of SELECT + INSERT, do INSERT with subquery for attempt_number
 	submissionID := uuid.New()
-	now := time.Now()
 	status := "in_progress"
 	questionsAnswered := 0
 	correctAnswers := 0
 	timeSpent := 0
 
 	var attemptNumber int
-	err = r.db.QueryRow(`
+	err = q.QueryRow(`
 		INSERT INTO user_exercise_attempts (
 			id, user_id, exercise_id, attempt_number, status, 
 			total_questions, questions_answered, correct_answers, 
 			time_limit_minutes, time_spent_seconds, started_at, device_type,
-			created_at, updated_at
+			created_at, updated_at, assignment_id, deadline_at, section_deadlines
 		) VALUES (
 			$1, $2, $3, 
 			(SELECT COALESCE(MAX(attempt_number), 0) + 1 
 			 FROM user_exercise_attempts 
 			 WHERE user_id = $2 AND exercise_id = $3),
-			$4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+			$4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		)
 		RETURNING attempt_number
 	`, submissionID, userID, exerciseID, status, totalQuestions, questionsAnswered,
-		correctAnswers, timeLimitMinutes, timeSpent, now, deviceType, now, now).Scan(&attemptNumber)
+		correctAnswers, timeLimitMinutes, timeSpent, now, deviceType, now, now, assignmentID,
+		deadlineAt, sectionDeadlinesJSON).Scan(&attemptNumber)
 
 	if err != nil {
 		return nil, err
@@ -442,7 +488,9 @@ func (r *ExerciseRepository) CreateSubmission(userID, exerciseID uuid.UUID, devi
 		TimeSpentSeconds:  timeSpent,
 		TimeLimitMinutes:  timeLimitMinutes,
 		StartedAt:         now,
+		DeadlineAt:        deadlineAt,
 		DeviceType:        deviceType,
+		AssignmentID:      assignmentID,
 		CreatedAt:         now,
 		UpdatedAt:         now,
 	}
@@ -450,112 +498,106 @@ func (r *ExerciseRepository) CreateSubmission(userID, exerciseID uuid.UUID, devi
 	return submission, nil
 }
 
-// SaveSubmissionAnswers saves all answers and grades submission
-func (r *ExerciseRepository) SaveSubmissionAnswers(submissionID uuid.UUID, answers []models.SubmitAnswerItem) error {
+// SubmitAttempt saves an attempt's answers, grades them and completes the
+// attempt in one transaction that holds the attempt's row lock, so the
+// sweeper cannot claim it between the save and the completion. The learner's
+// answers replace saved ones; the sweeper's draft (autoSubmitted) only fills
+// questions without a saved answer. It returns sql.ErrNoRows unless the
+// attempt is in progress and claimed by the sweeper only when autoSubmitted.
+func (r *ExerciseRepository) SubmitAttempt(submissionID uuid.UUID, answers []models.SubmitAnswerItem, frontendTimeSpent *int, autoSubmitted bool) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	// Get user_id once before loop
-	var userID uuid.UUID
+	// Lock the attempt until it is completed; ClaimExpiredAttempt waits and
+	// then finds it no longer in progress
+	var userID, exerciseID uuid.UUID
 	err = tx.QueryRow(`
-		SELECT user_id FROM user_exercise_attempts WHERE id = $1
-	`, submissionID).Scan(&userID)
+		SELECT user_id, exercise_id FROM user_exercise_attempts
+		WHERE id = $1 AND status = 'in_progress' AND (auto_submitted_at IS NOT NULL) = $2
+		FOR UPDATE
+	`, submissionID, autoSubmitted).Scan(&userID, &exerciseID)
 	if err != nil {
-		return fmt.Errorf("submission not found: %s: %w", submissionID, err)
+		return fmt.Errorf("submission not found or no longer in progress: %s: %w", submissionID, err)
+	}
+
+	if err := saveSubmissionAnswers(tx, submissionID, userID, exerciseID, answers, autoSubmitted); err != nil {
+		return err
+	}
+	if err := completeSubmission(tx, submissionID, frontendTimeSpent, autoSubmitted); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
+// saveSubmissionAnswers grades and saves answers to the attempt. With
+// keepSaved, questions that already have a saved answer keep it.
+func saveSubmissionAnswers(tx *sql.Tx, submissionID, userID, exerciseID uuid.UUID, answers []models.SubmitAnswerItem, keepSaved bool) error {
 	log.Printf("[Exercise-Repo] Saving %d answers for submission %s (user: %s)", len(answers), submissionID, userID)
 
 	// If no answers provided, just return (will be graded as 0 points)
 	if len(answers) == 0 {
 		log.Printf("[Exercise-Repo] No answers provided for submission %s, will be graded as 0 points", submissionID)
-		return tx.Commit()
+		return nil
 	}
 
-	// Get exercise_id from submission to validate questions belong to this exercise
-	var exerciseID uuid.UUID
-	err = tx.QueryRow(`
-		SELECT exercise_id FROM user_exercise_attempts WHERE id = $1
-	`, submissionID).Scan(&exerciseID)
-	if err != nil {
-		return fmt.Errorf("failed to get exercise_id from submission: %w", err)
+	onConflict := `DO UPDATE SET
+				answer_text = EXCLUDED.answer_text,
+				selected_option_id = EXCLUDED.selected_option_id,
+				selected_options = EXCLUDED.selected_options,
+				answer_data = EXCLUDED.answer_data,
+				is_correct = EXCLUDED.is_correct,
+				points_earned = EXCLUDED.points_earned,
+				marks_earned = EXCLUDED.marks_earned,
+				max_marks = EXCLUDED.max_marks,
+				part_results = EXCLUDED.part_results,
+				time_spent_seconds = COALESCE(EXCLUDED.time_spent_seconds, user_answers.time_spent_seconds),
+				answered_at = EXCLUDED.answered_at,
+				updated_at = CURRENT_TIMESTAMP`
+	if keepSaved {
+		onConflict = `DO NOTHING`
 	}
 
 	for _, answer := range answers {
 		// Get question details for grading and validate it belongs to the exercise
-		var questionType string
-		var points float64
-		var questionExerciseID uuid.UUID
-		err := tx.QueryRow(`
-			SELECT question_type, points, exercise_id FROM questions WHERE id = $1
-		`, answer.QuestionID).Scan(&questionType, &points, &questionExerciseID)
+		question, err := loadGradingQuestion(tx, answer.QuestionID)
 		if err != nil {
 			log.Printf("[Exercise-Repo] Error fetching question %s: %v", answer.QuestionID, err)
 			return fmt.Errorf("question not found: %s: %w", answer.QuestionID, err)
 		}
 
 		// Validate question belongs to the exercise
-		if questionExerciseID != exerciseID {
+		if question.ExerciseID != exerciseID {
 			log.Printf("[Exercise-Repo] Question %s does not belong to exercise %s (belongs to %s)", 
-				answer.QuestionID, exerciseID, questionExerciseID)
+				answer.QuestionID, exerciseID, question.ExerciseID)
 			return fmt.Errorf("question %s does not belong to exercise %s", answer.QuestionID, exerciseID)
 		}
 
-		isCorrect := false
-		pointsEarned := 0.0
-
-		// Grade based on question type
-		if questionType == "multiple_choice" || questionType == "matching" {
-			if answer.SelectedOptionID != nil {
-				var optionIsCorrect bool
-				err = tx.QueryRow(`
-					SELECT is_correct FROM question_options 
-					WHERE id = $1
-				`, *answer.SelectedOptionID).Scan(&optionIsCorrect)
-				if err == nil && optionIsCorrect {
-					isCorrect = true
-					pointsEarned = points
-				}
-			}
-		} else {
-			// Text-based questions (fill-in-blank, short_answer)
-			if answer.TextAnswer != nil && *answer.TextAnswer != "" {
-				var answerText string
-				var answerVariations pq.StringArray
-				err = tx.QueryRow(`
-					SELECT answer_text, COALESCE(answer_variations, '{}')
-					FROM question_answers 
-					WHERE question_id = $1
-				`, answer.QuestionID).Scan(&answerText, &answerVariations)
-
-				if err == nil {
-					// Case-insensitive comparison by default
-					userAnswer := strings.ToLower(strings.TrimSpace(*answer.TextAnswer))
-					answerText = strings.ToLower(strings.TrimSpace(answerText))
-
-					// Check main answer
-					if userAnswer == answerText {
-						isCorrect = true
-						pointsEarned = points
-					} else {
-						// Check alternative answer variations
-						for _, alt := range answerVariations {
-							if strings.ToLower(strings.TrimSpace(alt)) == userAnswer {
-								isCorrect = true
-								pointsEarned = points
-								break
-							}
-						}
-					}
-				} else {
-					// Log warning when correct answer not found in database
-					log.Printf("[Exercise-Repo] ⚠️  WARNING: No correct answer found for question %s (question_id: %s). User answer: '%s'. Error: %v",
-						answer.QuestionID, answer.QuestionID, *answer.TextAnswer, err)
-					// Answer will be marked as incorrect (isCorrect = false, pointsEarned = 0)
-				}
+		if len(question.Options) == 0 && len(question.Keys) == 0 {
+			// Log warning when correct answer not found in database
+			log.Printf("[Exercise-Repo] ⚠️  WARNING: No correct answer found for question %s (type %s)",
+				answer.QuestionID, question.Type)
+			// Answer will be marked as incorrect (isCorrect = false, pointsEarned = 0)
+		}
+
+		result := grading.Grade(&question.Question, &grading.Answer{
+			SelectedOptionID:  answer.SelectedOptionID,
+			SelectedOptionIDs: answer.SelectedOptionIDs,
+			Text:              answer.TextAnswer,
+			Matches:           answer.Matches,
+			Blanks:            answer.Blanks,
+		})
+
+		answerData, err := marshalAnswerData(answer)
+		if err != nil {
+			return err
+		}
+		var partResults interface{}
+		if len(result.Parts) > 0 {
+			if partResults, err = json.Marshal(result.Parts); err != nil {
+				return fmt.Errorf("failed to encode part results: %w", err)
 			}
 		}
 
@@ -563,63 +605,277 @@ func (r *ExerciseRepository) SaveSubmissionAnswers(submissionID uuid.UUID, answe
 		_, err = tx.Exec(`
 			INSERT INTO user_answers (
 				id, attempt_id, question_id, user_id, answer_text, selected_option_id,
-				is_correct, points_earned, time_spent_seconds, answered_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-			ON CONFLICT (attempt_id, question_id) DO UPDATE SET
-				answer_text = EXCLUDED.answer_text,
-				selected_option_id = EXCLUDED.selected_option_id,
-				is_correct = EXCLUDED.is_correct,
-				points_earned = EXCLUDED.points_earned,
-				time_spent_seconds = COALESCE(EXCLUDED.time_spent_seconds, user_answers.time_spent_seconds),
-				answered_at = EXCLUDED.answered_at,
-				updated_at = CURRENT_TIMESTAMP
-		`, uuid.New(), submissionID, answer.QuestionID, userID, answer.TextAnswer,
-			answer.SelectedOptionID, isCorrect, pointsEarned, answer.TimeSpentSeconds,
-			time.Now())
+				selected_options, answer_data, is_correct, points_earned, marks_earned, max_marks,
+				part_results, time_spent_seconds, answered_at
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+			ON CONFLICT (attempt_id, question_id) `+onConflict, uuid.New(), submissionID, answer.QuestionID, userID, answer.TextAnswer,
+			answer.SelectedOptionID, studentIDArray(answer.SelectedOptionIDs), answerData,
+			result.IsCorrect, result.PointsEarned, result.Marks, result.MaxMarks, partResults,
+			answer.TimeSpentSeconds, time.Now())
 		if err != nil {
 			log.Printf("[Exercise-Repo] Error upserting answer for question %s: %v", answer.QuestionID, err)
 			return fmt.Errorf("failed to upsert answer: %w", err)
 		}
 	}
 
-	return tx.Commit()
+	return nil
 }
 
-// CompleteSubmission finalizes submission (backward compatibility)
-func (r *ExerciseRepository) CompleteSubmission(submissionID uuid.UUID) error {
-	return r.CompleteSubmissionWithTime(submissionID, nil)
+// gradingQuestion is a question loaded for grading with the exercise it belongs to
+type gradingQuestion struct {
+	grading.Question
+	ExerciseID uuid.UUID
 }
 
-// CompleteSubmissionWithTime finalizes submission and calculates final score with optional frontend time
-func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID, frontendTimeSpent *int) error {
-	tx, err := r.db.Begin()
+// loadGradingQuestion reads a question with its options and accepted
+// answers for grading
+func loadGradingQuestion(tx *sql.Tx, questionID uuid.UUID) (*gradingQuestion, error) {
+	questions, err := loadGradingQuestions(tx, "q.id = $1", questionID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer tx.Rollback()
+	if len(questions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return questions[0], nil
+}
+
+// loadGradingQuestions reads the questions matching where (on questions q,
+// with one argument) with their options and accepted answers
+func loadGradingQuestions(tx *sql.Tx, where string, arg interface{}) ([]*gradingQuestion, error) {
+	rows, err := tx.Query(`
+		SELECT q.id, q.question_type, q.points, q.exercise_id, q.question_text, COALESCE(s.instructions, '')
+		FROM questions q
+		LEFT JOIN exercise_sections s ON s.id = q.section_id
+		WHERE `+where+`
+		ORDER BY q.display_order, q.question_number
+	`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get questions: %w", err)
+	}
+	defer rows.Close()
+
+	var questions []*gradingQuestion
+	byID := map[uuid.UUID]*gradingQuestion{}
+	for rows.Next() {
+		var (
+			q                          gradingQuestion
+			id                         uuid.UUID
+			questionText, instructions string
+		)
+		if err := rows.Scan(&id, &q.Type, &q.Points, &q.ExerciseID, &questionText, &instructions); err != nil {
+			return nil, fmt.Errorf("failed to scan question: %w", err)
+		}
+		q.Instructions = strings.TrimSpace(questionText + "\n" + instructions)
+		questions = append(questions, &q)
+		byID[id] = &q
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get questions: %w", err)
+	}
+	if len(questions) == 0 {
+		return nil, nil
+	}
+
+	optionRows, err := tx.Query(`
+		SELECT o.question_id, o.id, COALESCE(o.option_label, ''), COALESCE(o.is_correct, false)
+		FROM question_options o
+		JOIN questions q ON q.id = o.question_id
+		WHERE `+where+`
+		ORDER BY o.display_order
+	`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get options: %w", err)
+	}
+	defer optionRows.Close()
+	for optionRows.Next() {
+		var (
+			questionID uuid.UUID
+			o          grading.Option
+		)
+		if err := optionRows.Scan(&questionID, &o.ID, &o.Label, &o.IsCorrect); err != nil {
+			return nil, fmt.Errorf("failed to scan option: %w", err)
+		}
+		if q, ok := byID[questionID]; ok {
+			q.Options = append(q.Options, o)
+		}
+	}
+	if err := optionRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get options: %w", err)
+	}
+
+	keyRows, err := tx.Query(`
+		SELECT a.question_id, COALESCE(a.match_left, ''), a.answer_text, COALESCE(a.answer_variations, '{}')
+		FROM question_answers a
+		JOIN questions q ON q.id = a.question_id
+		WHERE `+where+`
+		ORDER BY a.created_at
+	`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answers: %w", err)
+	}
+	defer keyRows.Close()
+	for keyRows.Next() {
+		var (
+			questionID uuid.UUID
+			k          grading.Key
+			variations pq.StringArray
+		)
+		if err := keyRows.Scan(&questionID, &k.Part, &k.Text, &variations); err != nil {
+			return nil, fmt.Errorf("failed to scan answer: %w", err)
+		}
+		k.Variations = variations
+		if q, ok := byID[questionID]; ok {
+			q.Keys = append(q.Keys, k)
+		}
+	}
+	if err := keyRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get answers: %w", err)
+	}
+
+	return questions, nil
+}
+
+// exerciseMaxMarks returns the answer-sheet question numbers an exercise's
+// questions cover, counting each gap, item or letter of multi-part questions
+func exerciseMaxMarks(tx *sql.Tx, exerciseID uuid.UUID) (int, error) {
+	questions, err := loadGradingQuestions(tx, "q.exercise_id = $1", exerciseID)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, q := range questions {
+		total += grading.MaxMarks(&q.Question)
+	}
+	return total, nil
+}
+
+// answerData is the multi-part part of an answer, stored in user_answers.answer_data
+type answerData struct {
+	Matches map[string]string `json:"matches,omitempty"`
+	Blanks  map[string]string `json:"blanks,omitempty"`
+}
+
+// marshalAnswerData returns the answer's matches and blanks as JSON, or nil
+// when it has none
+func marshalAnswerData(answer models.SubmitAnswerItem) (interface{}, error) {
+	if len(answer.Matches) == 0 && len(answer.Blanks) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(answerData{Matches: answer.Matches, Blanks: answer.Blanks})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode answer: %w", err)
+	}
+	return data, nil
+}
+
+// getCorrectAnswer returns the correct answer to show with a result: the
+// option, the options of a "choose TWO" question, the accepted text, or the
+// answer for each item or gap of a multi-part question
+func (r *ExerciseRepository) getCorrectAnswer(question *models.Question) (interface{}, error) {
+	if grading.UsesOptions(question.QuestionType) {
+		rows, err := r.db.Query(`
+			SELECT option_label, option_text FROM question_options
+			WHERE question_id = $1 AND is_correct = true
+			ORDER BY display_order
+		`, question.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		correct := []string{}
+		for rows.Next() {
+			var label, text string
+			if err := rows.Scan(&label, &text); err != nil {
+				return nil, err
+			}
+			// Format as "Option A: text" for better display
+			correct = append(correct, fmt.Sprintf("Option %s: %s", label, text))
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		switch len(correct) {
+		case 0:
+			// Written TRUE/FALSE/NOT GIVEN answers are in question_answers
+		case 1:
+			return correct[0], nil
+		default:
+			return correct, nil
+		}
+	}
+
+	rows, err := r.db.Query(`
+		SELECT COALESCE(match_left, ''), answer_text FROM question_answers
+		WHERE question_id = $1
+		ORDER BY created_at
+	`, question.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var first *string
+	parts := map[string]string{}
+	for rows.Next() {
+		var part, text string
+		if err := rows.Scan(&part, &text); err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = &text
+		}
+		if _, ok := parts[part]; part != "" && !ok {
+			parts[part] = text
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(parts) > 0 {
+		return parts, nil
+	}
+	if first != nil {
+		return *first, nil
+	}
+	return nil, nil
+}
+
+// completeSubmission finalizes submission and calculates final score with
+// optional frontend time. Only an in-progress attempt is completed, by the
+// learner before the sweeper claims it or by the sweeper after
+// (autoSubmitted); otherwise it returns sql.ErrNoRows, so a submit racing an
+// auto-submit is graded once.
+func completeSubmission(tx *sql.Tx, submissionID uuid.UUID, frontendTimeSpent *int, autoSubmitted bool) error {
+	var err error
 
 	// FIX #18: Check if already completed to prevent duplicate completion
 	var currentStatus string
+	var claimed bool
 	var startedAt time.Time
 	var totalQuestions int
 	var exerciseID uuid.UUID
 	var timeLimitMinutes *int
 	err = tx.QueryRow(`
-		SELECT status, started_at, total_questions, exercise_id, time_limit_minutes
+		SELECT status, auto_submitted_at IS NOT NULL, started_at, total_questions, exercise_id, time_limit_minutes
 		FROM user_exercise_attempts 
 		WHERE id = $1
-	`, submissionID).Scan(&currentStatus, &startedAt, &totalQuestions, &exerciseID, &timeLimitMinutes)
+	`, submissionID).Scan(&currentStatus, &claimed, &startedAt, &totalQuestions, &exerciseID, &timeLimitMinutes)
 	if err != nil {
 		return err
 	}
 
-	// If already completed, skip to avoid duplicate statistics
-	if currentStatus == "completed" {
-		log.Printf("[Exercise-Repo] Submission %s already completed, skipping duplicate completion", submissionID)
-		return nil
+	// If already completed or claimed by the other side, skip to avoid
+	// grading and counting it twice
+	if currentStatus != "in_progress" || claimed != autoSubmitted {
+		log.Printf("[Exercise-Repo] Submission %s is %s (auto-submit claimed: %v), skipping duplicate completion", submissionID, currentStatus, claimed)
+		return sql.ErrNoRows
 	}
 
-	// Calculate statistics from user_answers
+	// Calculate statistics from user_answers. Counts are in answer-sheet
+	// question numbers: each gap, item or letter of a multi-part question
+	// counts once, so the raw score is what the IELTS conversion tables expect.
 	var correctCount int
 	var totalPointsEarned float64
 	var totalTimeSpent int
@@ -627,8 +883,8 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 
 	err = tx.QueryRow(`
 		SELECT 
-			COUNT(*) as answered,
-			COUNT(CASE WHEN is_correct = true THEN 1 END) as correct,
+			COALESCE(SUM(COALESCE(max_marks, 1)), 0) as answered,
+			COALESCE(SUM(COALESCE(marks_earned, CASE WHEN is_correct = true THEN 1 ELSE 0 END)), 0) as correct,
 			COALESCE(SUM(points_earned), 0) as points,
 			COALESCE(SUM(time_spent_seconds), 0) as time_spent
 		FROM user_answers
@@ -638,6 +894,14 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 		return err
 	}
 
+	maxMarks, err := exerciseMaxMarks(tx, exerciseID)
+	if err != nil {
+		return err
+	}
+	if maxMarks > 0 {
+		totalQuestions = maxMarks
+	}
+
 	// Get total points and passing score from exercise
 	var totalPoints float64
 	var passingScore float64
@@ -784,23 +1048,27 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 	
 	log.Printf("[Exercise-Repo] 📊 Final time_spent_seconds: %d (~%.1f minutes)", timeSpent, float64(timeSpent)/60)
 
-	// Update attempt
-	_, err = tx.Exec(`
+	// Update attempt, unless the other side completed it since it was read
+	result, err := tx.Exec(`
 		UPDATE user_exercise_attempts SET
 			completed_at = $1,
 			time_spent_seconds = $2,
-			questions_answered = $3,
-			correct_answers = $4,
-			score = $5,
-			band_score = $6,
+			total_questions = $3,
+			questions_answered = $4,
+			correct_answers = $5,
+			score = $6,
+			band_score = $7,
 			status = 'completed',
-			updated_at = $7
-		WHERE id = $8
-	`, completedAt, timeSpent, questionsAnswered, correctCount,
-		score, bandScore, completedAt, submissionID)
+			updated_at = $8
+		WHERE id = $9 AND status = 'in_progress' AND (auto_submitted_at IS NOT NULL) = $10
+	`, completedAt, timeSpent, totalQuestions, questionsAnswered, correctCount,
+		score, bandScore, completedAt, submissionID, autoSubmitted)
 	if err != nil {
 		return err
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
 
 	// Update exercise statistics
 	_, err = tx.Exec(`
@@ -823,7 +1091,7 @@ func (r *ExerciseRepository) CompleteSubmissionWithTime(submissionID uuid.UUID,
 		return err
 	}
 
-	return tx.Commit()
+	return nil
 }
 
 // GetSubmissionResult returns detailed submission result (uses user_exercise_attempts)
@@ -895,8 +1163,9 @@ func (r *ExerciseRepository) GetSubmissionResult(submissionID uuid.UUID) (*model
 	// Get answers with questions (from user_answers table)
 	rows, err := r.db.Query(`
 		SELECT ua.id, ua.attempt_id, ua.question_id, ua.user_id, ua.answer_text,
-			ua.selected_option_id, ua.is_correct, ua.points_earned, ua.time_spent_seconds,
-			ua.answered_at,
+			ua.selected_option_id, ua.selected_options, ua.answer_data, ua.is_correct,
+			ua.points_earned, ua.marks_earned, ua.max_marks, ua.part_results,
+			ua.time_spent_seconds, ua.answered_at,
 			q.id, q.exercise_id, q.section_id, q.question_number, q.question_text,
 			q.question_type, q.audio_url, q.image_url, q.context_text, q.points,
 			q.difficulty, q.explanation, q.tips, q.display_order, q.created_at, q.updated_at
@@ -914,11 +1183,14 @@ func (r *ExerciseRepository) GetSubmissionResult(submissionID uuid.UUID) (*model
 	for rows.Next() {
 		var submissionAnswer models.SubmissionAnswer
 		var question models.Question
+		var selectedOptions pq.StringArray
+		var data, partResults []byte
 		err := rows.Scan(
 			&submissionAnswer.ID, &submissionAnswer.AttemptID, &submissionAnswer.QuestionID,
 			&submissionAnswer.UserID, &submissionAnswer.AnswerText, &submissionAnswer.SelectedOptionID,
-			&submissionAnswer.IsCorrect, &submissionAnswer.PointsEarned, &submissionAnswer.TimeSpentSeconds,
-			&submissionAnswer.AnsweredAt,
+			&selectedOptions, &data, &submissionAnswer.IsCorrect, &submissionAnswer.PointsEarned,
+			&submissionAnswer.Marks, &submissionAnswer.MaxMarks, &partResults,
+			&submissionAnswer.TimeSpentSeconds, &submissionAnswer.AnsweredAt,
 			&question.ID, &question.ExerciseID, &question.SectionID, &question.QuestionNumber,
 			&question.QuestionText, &question.QuestionType, &question.AudioURL, &question.ImageURL,
 			&question.ContextText, &question.Points, &question.Difficulty, &question.Explanation,
@@ -927,31 +1199,30 @@ func (r *ExerciseRepository) GetSubmissionResult(submissionID uuid.UUID) (*model
 		if err != nil {
 			return nil, err
 		}
-
-		// Get correct answer
-		var correctAnswer interface{}
-		if question.QuestionType == "multiple_choice" {
-			var correctLabel string
-			var correctText string
-			err := r.db.QueryRow(`
-				SELECT option_label, option_text FROM question_options 
-				WHERE question_id = $1 AND is_correct = true
-			`, question.ID).Scan(&correctLabel, &correctText)
-			if err == nil {
-				// Format as "Option A: text" for better display
-				correctAnswer = fmt.Sprintf("Option %s: %s", correctLabel, correctText)
+		for _, id := range selectedOptions {
+			if parsed, err := uuid.Parse(id); err == nil {
+				submissionAnswer.SelectedOptionIDs = append(submissionAnswer.SelectedOptionIDs, parsed)
 			}
-		} else {
-			var correctText string
-			err := r.db.QueryRow(`
-				SELECT answer_text FROM question_answers 
-				WHERE question_id = $1
-			`, question.ID).Scan(&correctText)
-			if err == nil {
-				correctAnswer = correctText
+		}
+		if len(data) > 0 {
+			var multiPart answerData
+			if err := json.Unmarshal(data, &multiPart); err == nil {
+				submissionAnswer.Matches = multiPart.Matches
+				submissionAnswer.Blanks = multiPart.Blanks
+			}
+		}
+		if len(partResults) > 0 {
+			if err := json.Unmarshal(partResults, &submissionAnswer.Parts); err != nil {
+				log.Printf("[Exercise-Repo] Warning: invalid part results on answer %s: %v", submissionAnswer.ID, err)
 			}
 		}
 
+		// Get correct answer
+		correctAnswer, err := r.getCorrectAnswer(&question)
+		if err != nil {
+			return nil, err
+		}
+
 		// Get selected option text if user selected an option
 		if submissionAnswer.SelectedOptionID != nil && question.QuestionType == "multiple_choice" {
 			var selectedLabel string
@@ -1488,6 +1759,7 @@ func (r *ExerciseRepository) CreateQuestionAnswer(questionID uuid.UUID, req *mod
 		AlternativeAnswers: alternativeAnswersJSON,
 		IsCaseSensitive:    req.IsCaseSensitive,
 		MatchingOrder:      req.MatchingOrder,
+		MatchLeft:          req.MatchLeft,
 		CreatedAt:          time.Now(),
 	}
 
@@ -1499,10 +1771,10 @@ func (r *ExerciseRepository) CreateQuestionAnswer(questionID uuid.UUID, req *mod
 
 	_, err := r.db.Exec(`
 		INSERT INTO question_answers (
-			id, question_id, answer_text, answer_variations,
+			id, question_id, answer_text, answer_variations, match_left,
 			is_primary_answer, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6)
-	`, answer.ID, answer.QuestionID, answer.AnswerText, answerVariations,
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, answer.ID, answer.QuestionID, answer.AnswerText, answerVariations, answer.MatchLeft,
 		true, answer.CreatedAt)
 
 	return answer, err
@@ -1647,10 +1919,7 @@ func (r *ExerciseRepository) GetBankQuestions(skillType, questionType string, li
 
 	// Get questions
 	selectQuery := fmt.Sprintf(`
-		SELECT id, title, skill_type, question_type, difficulty, topic,
-			question_text, context_text, audio_url, image_url, answer_data,
-			tags, times_used, created_by, is_verified, is_published,
-			created_at, updated_at
+		SELECT `+bankQuestionColumns+`
 		FROM question_bank %s
 		ORDER BY created_at DESC
 		LIMIT $%d OFFSET $%d
@@ -1665,26 +1934,42 @@ func (r *ExerciseRepository) GetBankQuestions(skillType, questionType string, li
 
 	var questions []models.QuestionBank
 	for rows.Next() {
-		var q models.QuestionBank
-		var answerDataJSON []byte
-		var tagsArray pq.StringArray
-		err := rows.Scan(
-			&q.ID, &q.Title, &q.SkillType, &q.QuestionType, &q.Difficulty,
-			&q.Topic, &q.QuestionText, &q.ContextText, &q.AudioURL, &q.ImageURL,
-			&answerDataJSON, &tagsArray, &q.TimesUsed, &q.CreatedBy,
-			&q.IsVerified, &q.IsPublished, &q.CreatedAt, &q.UpdatedAt,
-		)
+		q, err := scanBankQuestion(rows)
 		if err != nil {
 			return nil, 0, err
 		}
-		q.AnswerData = string(answerDataJSON)
-		q.Tags = []string(tagsArray)
-		questions = append(questions, q)
+		questions = append(questions, *q)
 	}
 
 	return questions, total, nil
 }
 
+const bankQuestionColumns = `
+	id, title, skill_type, question_type, difficulty, topic,
+	question_text, context_text, audio_url, image_url, answer_data,
+	tags, times_used, created_by, is_verified, is_published,
+	irt_discrimination, irt_difficulty, irt_responses, irt_calibrated_at,
+	created_at, updated_at`
+
+func scanBankQuestion(row rowScanner) (*models.QuestionBank, error) {
+	var q models.QuestionBank
+	var answerDataJSON []byte
+	var tagsArray pq.StringArray
+	err := row.Scan(
+		&q.ID, &q.Title, &q.SkillType, &q.QuestionType, &q.Difficulty,
+		&q.Topic, &q.QuestionText, &q.ContextText, &q.AudioURL, &q.ImageURL,
+		&answerDataJSON, &tagsArray, &q.TimesUsed, &q.CreatedBy,
+		&q.IsVerified, &q.IsPublished, &q.IRTDiscrimination, &q.IRTDifficulty,
+		&q.IRTResponses, &q.IRTCalibratedAt, &q.CreatedAt, &q.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	q.AnswerData = string(answerDataJSON)
+	q.Tags = []string(tagsArray)
+	return &q, nil
+}
+
 // CreateBankQuestion creates a new question in the question bank
 func (r *ExerciseRepository) CreateBankQuestion(req *models.CreateBankQuestionRequest, userID uuid.UUID) (*models.QuestionBank, error) {
 	answerDataJSON, err := json.Marshal(req.AnswerData)
@@ -1802,11 +2087,11 @@ func (r *ExerciseRepository) GetSubmissionByID(submissionID uuid.UUID) (*models.
 	query := `
 		SELECT id, user_id, exercise_id, attempt_number, status, total_questions, questions_answered,
 			correct_answers, score, band_score, time_limit_minutes, time_spent_seconds,
-			started_at, completed_at, device_type,
+			started_at, completed_at, deadline_at, auto_submitted_at, device_type,
 			essay_text, word_count, task_type, prompt_text,
 			audio_url, audio_duration_seconds, transcript_text, speaking_part_number,
 			evaluation_status, ai_evaluation_id, detailed_scores, ai_feedback,
-			official_test_result_id, practice_activity_id,
+			official_test_result_id, practice_activity_id, mock_test_session_id,
 			created_at, updated_at
 		FROM user_exercise_attempts
 		WHERE id = $1
@@ -1816,11 +2101,11 @@ func (r *ExerciseRepository) GetSubmissionByID(submissionID uuid.UUID) (*models.
 	err := r.db.QueryRow(query, submissionID).Scan(
 		&s.ID, &s.UserID, &s.ExerciseID, &s.AttemptNumber, &s.Status, &s.TotalQuestions, &s.QuestionsAnswered,
 		&s.CorrectAnswers, &s.Score, &s.BandScore, &s.TimeLimitMinutes, &s.TimeSpentSeconds,
-		&s.StartedAt, &s.CompletedAt, &s.DeviceType,
+		&s.StartedAt, &s.CompletedAt, &s.DeadlineAt, &s.AutoSubmittedAt, &s.DeviceType,
 		&s.EssayText, &s.WordCount, &s.TaskType, &s.PromptText,
 		&s.AudioURL, &s.AudioDurationSeconds, &s.TranscriptText, &s.SpeakingPartNumber,
 		&s.EvaluationStatus, &s.AIEvaluationID, &s.DetailedScores, &s.AIFeedback,
-		&s.OfficialTestResultID, &s.PracticeActivityID,
+		&s.OfficialTestResultID, &s.PracticeActivityID, &s.MockTestSessionID,
 		&s.CreatedAt, &s.UpdatedAt,
 	)
 	if err != nil {
@@ -1957,6 +2242,21 @@ func (r *ExerciseRepository) GetPendingSyncs(limit int) ([]*models.UserExerciseA
 	return submissions, nil
 }
 
+// CountPendingSyncs counts submissions GetPendingSyncs would still retry
+func (r *ExerciseRepository) CountPendingSyncs() (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM user_exercise_attempts
+		WHERE user_service_sync_status IN ('pending', 'failed')
+		  AND status = 'completed'
+		  AND user_service_sync_attempts < 5
+	`
+
+	var count int
+	err := r.db.QueryRow(query).Scan(&count)
+	return count, err
+}
+
 // UpdateSubmissionWritingData updates writing-specific fields
 func (r *ExerciseRepository) UpdateSubmissionWritingData(submissionID uuid.UUID, essayText string, wordCount int, taskType, promptText string) error {
 	query := `
@@ -1991,13 +2291,15 @@ func (r *ExerciseRepository) UpdateSubmissionEvaluationStatus(submissionID uuid.
 }
 
 // MarkSubmissionAsSubmitted marks submission as submitted with completed_at timestamp (backward compatibility)
-func (r *ExerciseRepository) MarkSubmissionAsSubmitted(submissionID uuid.UUID) error {
-	return r.MarkSubmissionAsSubmittedWithTime(submissionID, nil)
+func (r *ExerciseRepository) MarkSubmissionAsSubmitted(submissionID uuid.UUID, autoSubmitted bool) error {
+	return r.MarkSubmissionAsSubmittedWithTime(submissionID, nil, autoSubmitted)
 }
 
 // MarkSubmissionAsSubmittedWithTime marks submission as submitted with time tracking
 // This ensures completed_at and time_spent_seconds are set when user submits (for Writing/Speaking)
-func (r *ExerciseRepository) MarkSubmissionAsSubmittedWithTime(submissionID uuid.UUID, frontendTimeSpent *int) error {
+// Like SubmitAttempt, it returns sql.ErrNoRows unless the
+// attempt is in progress and claimed by the sweeper only when autoSubmitted.
+func (r *ExerciseRepository) MarkSubmissionAsSubmittedWithTime(submissionID uuid.UUID, frontendTimeSpent *int, autoSubmitted bool) error {
 	// Get started_at for time calculation
 	var startedAt time.Time
 	var timeLimitMinutes *int
@@ -2010,7 +2312,7 @@ func (r *ExerciseRepository) MarkSubmissionAsSubmittedWithTime(submissionID uuid
 		return fmt.Errorf("failed to get submission info: %w", err)
 	}
 
-	// Calculate time spent using same logic as CompleteSubmissionWithTime
+	// Calculate time spent using same logic as completeSubmission
 	completedAt := time.Now()
 	var timeSpent int
 	
@@ -2037,14 +2339,17 @@ func (r *ExerciseRepository) MarkSubmissionAsSubmittedWithTime(submissionID uuid
 		UPDATE user_exercise_attempts
 		SET completed_at = COALESCE(completed_at, $1),
 		    time_spent_seconds = $2,
-		    status = CASE WHEN status = 'in_progress' THEN 'submitted' ELSE status END,
+		    status = 'submitted',
 		    updated_at = $3
-		WHERE id = $4
+		WHERE id = $4 AND status = 'in_progress' AND (auto_submitted_at IS NOT NULL) = $5
 	`
-	_, err = r.db.Exec(query, completedAt, timeSpent, completedAt, submissionID)
+	result, err := r.db.Exec(query, completedAt, timeSpent, completedAt, submissionID, autoSubmitted)
 	if err != nil {
 		return fmt.Errorf("failed to mark submission as submitted: %w", err)
 	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
 	
 	log.Printf("[Exercise-Repo] 📊 W/S submission marked as submitted - time_spent: %d seconds", timeSpent)
 	return nil
@@ -0,0 +1,375 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const mockTestColumns = `
+	id, title, description, ielts_test_type, listening_exercise_id, reading_exercise_id,
+	writing_exercise_id, speaking_exercise_id, speaking_break_minutes, is_published,
+	created_by, created_at, updated_at`
+
+const mockTestSessionColumns = `
+	id, mock_test_id, user_id, status, current_stage, listening_band, reading_band,
+	writing_band, speaking_band, overall_band, score_report, started_at, completed_at,
+	user_service_synced_at, user_service_sync_attempts, created_at, updated_at`
+
+// CreateMockTest stores a new mock test and fills in its ID and timestamps
+func (r *ExerciseRepository) CreateMockTest(m *models.MockTest) error {
+	err := r.db.QueryRow(`
+		INSERT INTO mock_tests (
+			title, description, ielts_test_type, listening_exercise_id, reading_exercise_id,
+			writing_exercise_id, speaking_exercise_id, speaking_break_minutes, is_published, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, created_at, updated_at
+	`, m.Title, m.Description, m.IELTSTestType, m.ListeningExerciseID, m.ReadingExerciseID,
+		m.WritingExerciseID, m.SpeakingExerciseID, m.SpeakingBreakMinutes, m.IsPublished, m.CreatedBy,
+	).Scan(&m.ID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create mock test: %w", err)
+	}
+	return nil
+}
+
+// UpdateMockTest saves the editable fields of a mock test
+func (r *ExerciseRepository) UpdateMockTest(m *models.MockTest) error {
+	err := r.db.QueryRow(`
+		UPDATE mock_tests
+		SET title = $2, description = $3, ielts_test_type = $4, listening_exercise_id = $5,
+		    reading_exercise_id = $6, writing_exercise_id = $7, speaking_exercise_id = $8,
+		    speaking_break_minutes = $9, is_published = $10
+		WHERE id = $1
+		RETURNING updated_at
+	`, m.ID, m.Title, m.Description, m.IELTSTestType, m.ListeningExerciseID, m.ReadingExerciseID,
+		m.WritingExerciseID, m.SpeakingExerciseID, m.SpeakingBreakMinutes, m.IsPublished,
+	).Scan(&m.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update mock test: %w", err)
+	}
+	return nil
+}
+
+// GetMockTestByID returns a mock test, or sql.ErrNoRows
+func (r *ExerciseRepository) GetMockTestByID(id uuid.UUID) (*models.MockTest, error) {
+	m, err := scanMockTest(r.db.QueryRow(`SELECT `+mockTestColumns+` FROM mock_tests WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock test: %w", err)
+	}
+	return m, nil
+}
+
+// ListMockTests returns the mock tests, newest first, optionally only the
+// published ones
+func (r *ExerciseRepository) ListMockTests(publishedOnly bool) ([]models.MockTest, error) {
+	rows, err := r.db.Query(`
+		SELECT `+mockTestColumns+`
+		FROM mock_tests
+		WHERE is_published OR NOT $1
+		ORDER BY created_at DESC
+	`, publishedOnly)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list mock tests: %w", err)
+	}
+	defer rows.Close()
+
+	mockTests := []models.MockTest{}
+	for rows.Next() {
+		m, err := scanMockTest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mock test: %w", err)
+		}
+		mockTests = append(mockTests, *m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list mock tests: %w", err)
+	}
+	return mockTests, nil
+}
+
+// CreateMockTestSession starts a sitting of a mock test with its first
+// stage's attempt
+func (r *ExerciseRepository) CreateMockTestSession(userID uuid.UUID, m *models.MockTest, limitMinutes int, deadline time.Time) (*models.MockTestSession, *models.UserExerciseAttempt, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	session, err := scanMockTestSession(tx.QueryRow(`
+		INSERT INTO mock_test_sessions (mock_test_id, user_id)
+		VALUES ($1, $2)
+		RETURNING `+mockTestSessionColumns,
+		m.ID, userID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create mock test session: %w", err)
+	}
+
+	submission, err := createStageAttempt(tx, session, m.ListeningExerciseID, limitMinutes, deadline)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit mock test session: %w", err)
+	}
+	return session, submission, nil
+}
+
+// StartMockTestStage moves a sitting on to a stage and starts its attempt.
+// It returns nil when the sitting is no longer on the stage before, e.g.
+// because another request started the stage first.
+func (r *ExerciseRepository) StartMockTestStage(session *models.MockTestSession, stage int, exerciseID uuid.UUID, limitMinutes int, deadline time.Time) (*models.UserExerciseAttempt, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+		UPDATE mock_test_sessions SET current_stage = $2
+		WHERE id = $1 AND current_stage = $2 - 1 AND status = 'in_progress'
+	`, session.ID, stage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move mock test session on: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, nil
+	}
+
+	submission, err := createStageAttempt(tx, session, exerciseID, limitMinutes, deadline)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit mock test stage: %w", err)
+	}
+	session.CurrentStage = stage
+	return submission, nil
+}
+
+// createStageAttempt starts the attempt for a stage of a sitting. The stage's
+// official time replaces the exercise's limits, and the attempt is recorded
+// in user-service with the sitting rather than on its own.
+func createStageAttempt(tx *sql.Tx, session *models.MockTestSession, exerciseID uuid.UUID, limitMinutes int, deadline time.Time) (*models.UserExerciseAttempt, error) {
+	submission, err := createSubmission(tx, session.UserID, exerciseID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(`
+		UPDATE user_exercise_attempts
+		SET mock_test_session_id = $2, time_limit_minutes = $3, deadline_at = $4,
+		    section_deadlines = NULL, user_service_sync_status = 'not_required'
+		WHERE id = $1
+	`, submission.ID, session.ID, limitMinutes, deadline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to link attempt to mock test session: %w", err)
+	}
+	submission.MockTestSessionID = &session.ID
+	submission.TimeLimitMinutes = &limitMinutes
+	submission.DeadlineAt = &deadline
+	return submission, nil
+}
+
+// GetMockTestSession returns a sitting, or sql.ErrNoRows
+func (r *ExerciseRepository) GetMockTestSession(id uuid.UUID) (*models.MockTestSession, error) {
+	session, err := scanMockTestSession(r.db.QueryRow(`
+		SELECT `+mockTestSessionColumns+` FROM mock_test_sessions WHERE id = $1
+	`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock test session: %w", err)
+	}
+	return session, nil
+}
+
+// GetActiveMockTestSession returns the user's sitting of the mock test that
+// is still in progress, or nil
+func (r *ExerciseRepository) GetActiveMockTestSession(userID, mockTestID uuid.UUID) (*models.MockTestSession, error) {
+	session, err := scanMockTestSession(r.db.QueryRow(`
+		SELECT `+mockTestSessionColumns+`
+		FROM mock_test_sessions
+		WHERE user_id = $1 AND mock_test_id = $2 AND status = 'in_progress'
+		ORDER BY started_at DESC
+		LIMIT 1
+	`, userID, mockTestID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock test session: %w", err)
+	}
+	return session, nil
+}
+
+// GetUserMockTestSessions returns the user's sittings, latest first
+func (r *ExerciseRepository) GetUserMockTestSessions(userID uuid.UUID) ([]models.MockTestSession, error) {
+	rows, err := r.db.Query(`
+		SELECT `+mockTestSessionColumns+`
+		FROM mock_test_sessions
+		WHERE user_id = $1
+		ORDER BY started_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock test sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.MockTestSession{}
+	for rows.Next() {
+		session, err := scanMockTestSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan mock test session: %w", err)
+		}
+		sessions = append(sessions, *session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get mock test sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetMockTestStageAttempts returns a sitting's attempts in stage order
+func (r *ExerciseRepository) GetMockTestStageAttempts(sessionID uuid.UUID) ([]*models.UserExerciseAttempt, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM user_exercise_attempts
+		WHERE mock_test_session_id = $1
+		ORDER BY started_at
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock test attempts: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan mock test attempt: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get mock test attempts: %w", err)
+	}
+
+	attempts := make([]*models.UserExerciseAttempt, 0, len(ids))
+	for _, id := range ids {
+		submission, err := r.GetSubmissionByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get mock test attempt %s: %w", id, err)
+		}
+		attempts = append(attempts, submission)
+	}
+	return attempts, nil
+}
+
+// UpdateMockTestSessionResult saves a sitting's status, bands and score report
+func (r *ExerciseRepository) UpdateMockTestSessionResult(session *models.MockTestSession) error {
+	var report interface{}
+	if session.ScoreReport != nil {
+		data, err := json.Marshal(session.ScoreReport)
+		if err != nil {
+			return fmt.Errorf("failed to encode score report: %w", err)
+		}
+		report = data
+	}
+	_, err := r.db.Exec(`
+		UPDATE mock_test_sessions
+		SET status = $2, listening_band = $3, reading_band = $4, writing_band = $5,
+		    speaking_band = $6, overall_band = $7, score_report = $8, completed_at = $9
+		WHERE id = $1
+	`, session.ID, session.Status, session.ListeningBand, session.ReadingBand, session.WritingBand,
+		session.SpeakingBand, session.OverallBand, report, session.CompletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update mock test session: %w", err)
+	}
+	return nil
+}
+
+// GetMockTestSessionsToSettle returns sittings that may be waiting on their
+// last attempt or its evaluation, or whose result user-service has not
+// recorded and that have been tried fewer than maxSyncAttempts times
+func (r *ExerciseRepository) GetMockTestSessionsToSettle(maxSyncAttempts, limit int) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(`
+		SELECT id FROM mock_test_sessions
+		WHERE (status = 'in_progress' AND current_stage = 3)
+		   OR status = 'awaiting_results'
+		   OR (status = 'completed' AND user_service_synced_at IS NULL AND user_service_sync_attempts < $1)
+		ORDER BY updated_at
+		LIMIT $2
+	`, maxSyncAttempts, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mock test sessions to settle: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan mock test session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkMockTestSessionSynced records that user-service has the sitting's result
+func (r *ExerciseRepository) MarkMockTestSessionSynced(sessionID uuid.UUID) error {
+	_, err := r.db.Exec(`
+		UPDATE mock_test_sessions
+		SET user_service_synced_at = NOW(), user_service_sync_error = NULL
+		WHERE id = $1
+	`, sessionID)
+	return err
+}
+
+// MarkMockTestSessionSyncFailed counts a failed attempt to record the
+// sitting's result in user-service
+func (r *ExerciseRepository) MarkMockTestSessionSyncFailed(sessionID uuid.UUID, errorMsg string) error {
+	_, err := r.db.Exec(`
+		UPDATE mock_test_sessions
+		SET user_service_sync_attempts = user_service_sync_attempts + 1, user_service_sync_error = $2
+		WHERE id = $1
+	`, sessionID, errorMsg)
+	return err
+}
+
+func scanMockTest(row rowScanner) (*models.MockTest, error) {
+	var m models.MockTest
+	err := row.Scan(
+		&m.ID, &m.Title, &m.Description, &m.IELTSTestType, &m.ListeningExerciseID, &m.ReadingExerciseID,
+		&m.WritingExerciseID, &m.SpeakingExerciseID, &m.SpeakingBreakMinutes, &m.IsPublished,
+		&m.CreatedBy, &m.CreatedAt, &m.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func scanMockTestSession(row rowScanner) (*models.MockTestSession, error) {
+	var (
+		s      models.MockTestSession
+		report []byte
+	)
+	err := row.Scan(
+		&s.ID, &s.MockTestID, &s.UserID, &s.Status, &s.CurrentStage, &s.ListeningBand, &s.ReadingBand,
+		&s.WritingBand, &s.SpeakingBand, &s.OverallBand, &report, &s.StartedAt, &s.CompletedAt,
+		&s.UserServiceSyncedAt, &s.UserServiceSyncAttempts, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(report) > 0 {
+		s.ScoreReport = &models.MockTestScoreReport{}
+		if err := json.Unmarshal(report, s.ScoreReport); err != nil {
+			return nil, fmt.Errorf("failed to decode score report: %w", err)
+		}
+	}
+	return &s, nil
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bisosad1501/DATN/shared/pkg/personaldata"
+	"github.com/google/uuid"
+)
+
+// personalDataTables are the tables holding a learner's rows. Exercises and
+// bank questions an instructor wrote are content, not personal data, and are kept.
+var personalDataTables = []personaldata.Table{
+	personaldata.UserTable("mock_test_sessions"),
+	personaldata.UserTable("adaptive_practice_sessions"),
+	personaldata.UserTable("adaptive_practice_responses"),
+	personaldata.UserTable("user_exercise_attempts"),
+	personaldata.UserTable("user_answers"),
+	{Name: "submission_reviews", Where: "attempt_id IN (SELECT id FROM user_exercise_attempts WHERE user_id = $1)"},
+	{Name: "review_comments", Where: `review_id IN (
+		SELECT r.id FROM submission_reviews r
+		JOIN user_exercise_attempts a ON a.id = r.attempt_id
+		WHERE a.user_id = $1)`},
+}
+
+// ExportUserData returns every row held about the user, keyed by table
+func (r *ExerciseRepository) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return personaldata.Export(ctx, r.db, userID.String(), personalDataTables)
+}
+
+// EraseUserData deletes the user's attempts, answers, mock test sittings and
+// adaptive practice, and takes them off assignments set for specific
+// learners; assignments set only for them are deleted. Examiner reviews go
+// with the attempts. Recordings referenced by speaking attempts are deleted
+// by storage-service.
+func (r *ExerciseRepository) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_answers WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete answers: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_exercise_attempts WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete attempts: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM mock_test_sessions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete mock test sessions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM adaptive_practice_sessions WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete adaptive practice sessions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM assignments WHERE student_ids = ARRAY[$1::uuid]`, userID); err != nil {
+		return fmt.Errorf("failed to delete assignments: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE assignments SET student_ids = array_remove(student_ids, $1::uuid)
+		WHERE $1::uuid = ANY(student_ids)
+	`, userID); err != nil {
+		return fmt.Errorf("failed to remove user from assignments: %w", err)
+	}
+
+	return tx.Commit()
+}
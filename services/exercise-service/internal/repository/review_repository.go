@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+const reviewColumns = `
+	r.id, r.attempt_id, r.skill_type, r.status, r.reviewer_id, r.claimed_at, r.ai_band_score,
+	r.ai_criteria_scores, r.criteria_overrides, r.band_score, r.reviewer_feedback, r.published_at,
+	r.user_service_synced_at, r.created_at, r.updated_at`
+
+// CreateReview queues an AI-evaluated attempt for examiner review. An
+// attempt evaluated again keeps its review unless it is still queued.
+func (r *ExerciseRepository) CreateReview(attemptID uuid.UUID, skillType string, aiBandScore float64, aiCriteria map[string]float64) error {
+	criteriaJSON, err := json.Marshal(aiCriteria)
+	if err != nil {
+		return fmt.Errorf("failed to marshal criteria scores: %w", err)
+	}
+
+	_, err = r.db.Exec(`
+		INSERT INTO submission_reviews (attempt_id, skill_type, ai_band_score, ai_criteria_scores, band_score)
+		VALUES ($1, $2, $3, $4, $3)
+		ON CONFLICT (attempt_id) DO UPDATE
+		SET ai_band_score = EXCLUDED.ai_band_score,
+		    ai_criteria_scores = EXCLUDED.ai_criteria_scores,
+		    band_score = EXCLUDED.band_score
+		WHERE submission_reviews.status = 'queued'
+	`, attemptID, skillType, aiBandScore, string(criteriaJSON))
+	if err != nil {
+		return fmt.Errorf("failed to queue review: %w", err)
+	}
+	return nil
+}
+
+// ListReviews returns a page of the review queue, oldest submissions first
+func (r *ExerciseRepository) ListReviews(query *models.ReviewQueueQuery, reviewerID uuid.UUID) ([]models.ReviewQueueItem, int, error) {
+	where := `WHERE r.status = $1
+		AND ($2 = '' OR r.skill_type = $2)
+		AND (NOT $3 OR r.reviewer_id = $4)`
+	args := []interface{}{query.Status, query.SkillType, query.Mine, reviewerID}
+
+	var total int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM submission_reviews r `+where, args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	offset := (query.Page - 1) * query.Limit
+	rows, err := r.db.Query(`
+		SELECT `+reviewColumns+`, a.user_id, a.exercise_id, e.title, a.completed_at
+		FROM submission_reviews r
+		JOIN user_exercise_attempts a ON a.id = r.attempt_id
+		JOIN exercises e ON e.id = a.exercise_id
+		`+where+`
+		ORDER BY r.created_at
+		LIMIT $5 OFFSET $6
+	`, append(args, query.Limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.ReviewQueueItem{}
+	for rows.Next() {
+		var item models.ReviewQueueItem
+		err := scanReview(rows, &item.SubmissionReview, &item.UserID, &item.ExerciseID, &item.ExerciseTitle, &item.SubmittedAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan review: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list reviews: %w", err)
+	}
+	return items, total, nil
+}
+
+// GetReviewByID returns a review
+func (r *ExerciseRepository) GetReviewByID(id uuid.UUID) (*models.SubmissionReview, error) {
+	return r.getReview(`r.id = $1`, id)
+}
+
+// GetReviewByAttemptID returns the review of an attempt
+func (r *ExerciseRepository) GetReviewByAttemptID(attemptID uuid.UUID) (*models.SubmissionReview, error) {
+	return r.getReview(`r.attempt_id = $1`, attemptID)
+}
+
+func (r *ExerciseRepository) getReview(where string, arg interface{}) (*models.SubmissionReview, error) {
+	var review models.SubmissionReview
+	row := r.db.QueryRow(`SELECT `+reviewColumns+` FROM submission_reviews r WHERE `+where, arg)
+	err := scanReview(row, &review)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("review not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review: %w", err)
+	}
+	return &review, nil
+}
+
+// ClaimReview assigns a review to a reviewer. Reviews claimed before
+// staleBefore can be taken over. It reports whether the claim succeeded.
+func (r *ExerciseRepository) ClaimReview(id, reviewerID uuid.UUID, staleBefore time.Time) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE submission_reviews
+		SET status = 'claimed', reviewer_id = $2, claimed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND (status = 'queued'
+		       OR (status = 'claimed' AND (reviewer_id = $2 OR claimed_at < $3)))
+	`, id, reviewerID, staleBefore)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim review: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// ReleaseReview puts a claimed review back in the queue, keeping its scores
+// and comments. It reports whether the reviewer held the claim.
+func (r *ExerciseRepository) ReleaseReview(id, reviewerID uuid.UUID) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE submission_reviews
+		SET status = 'queued', reviewer_id = NULL, claimed_at = NULL
+		WHERE id = $1 AND status = 'claimed' AND reviewer_id = $2
+	`, id, reviewerID)
+	if err != nil {
+		return false, fmt.Errorf("failed to release review: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// UpdateReviewScores saves the reviewer's overrides, recalculated band and feedback
+func (r *ExerciseRepository) UpdateReviewScores(review *models.SubmissionReview) error {
+	overridesJSON, err := json.Marshal(review.CriteriaOverrides)
+	if err != nil {
+		return fmt.Errorf("failed to marshal criteria overrides: %w", err)
+	}
+
+	err = r.db.QueryRow(`
+		UPDATE submission_reviews
+		SET criteria_overrides = $2, band_score = $3, reviewer_feedback = $4
+		WHERE id = $1 AND status = 'claimed'
+		RETURNING updated_at
+	`, review.ID, string(overridesJSON), review.BandScore, review.ReviewerFeedback).Scan(&review.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("review not claimed")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update review: %w", err)
+	}
+	return nil
+}
+
+// PublishReview makes the review's band and criteria the attempt's result.
+// The AI result is kept in detailed_scores under ai_band_score.
+func (r *ExerciseRepository) PublishReview(review *models.SubmissionReview) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		UPDATE submission_reviews
+		SET status = 'published', published_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'claimed' AND reviewer_id = $2
+		RETURNING published_at, updated_at
+	`, review.ID, review.ReviewerID).Scan(&review.PublishedAt, &review.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("review not claimed")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to publish review: %w", err)
+	}
+
+	reviewed := map[string]interface{}{
+		"overall_band":  review.BandScore,
+		"ai_band_score": review.AIBandScore,
+		"reviewed":      true,
+	}
+	for k, v := range review.CriteriaScores() {
+		reviewed[k] = v
+	}
+	reviewedJSON, err := json.Marshal(reviewed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal detailed scores: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE user_exercise_attempts
+		SET band_score = $2,
+		    detailed_scores = COALESCE(detailed_scores, '{}'::jsonb) || $3::jsonb,
+		    updated_at = NOW()
+		WHERE id = $1
+	`, review.AttemptID, review.BandScore, string(reviewedJSON))
+	if err != nil {
+		return fmt.Errorf("failed to update attempt: %w", err)
+	}
+
+	review.Status = "published"
+	return tx.Commit()
+}
+
+// GetUnsyncedPublishedReviews returns published reviews whose corrected band
+// has not reached user-service yet
+func (r *ExerciseRepository) GetUnsyncedPublishedReviews(limit int) ([]models.SubmissionReview, error) {
+	rows, err := r.db.Query(`
+		SELECT `+reviewColumns+`
+		FROM submission_reviews r
+		WHERE r.status = 'published' AND r.user_service_synced_at IS NULL
+		ORDER BY r.published_at
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unsynced reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.SubmissionReview{}
+	for rows.Next() {
+		var review models.SubmissionReview
+		if err := scanReview(rows, &review); err != nil {
+			return nil, fmt.Errorf("failed to scan review: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get unsynced reviews: %w", err)
+	}
+	return reviews, nil
+}
+
+// MarkReviewSynced records that user-service has the corrected band
+func (r *ExerciseRepository) MarkReviewSynced(id uuid.UUID) error {
+	_, err := r.db.Exec(`UPDATE submission_reviews SET user_service_synced_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark review synced: %w", err)
+	}
+	return nil
+}
+
+// CreateReviewComment adds a comment to a review
+func (r *ExerciseRepository) CreateReviewComment(comment *models.ReviewComment) error {
+	err := r.db.QueryRow(`
+		INSERT INTO review_comments (
+			review_id, author_id, anchor_type, start_offset, end_offset, quoted_text,
+			start_seconds, end_seconds, comment
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`, comment.ReviewID, comment.AuthorID, comment.AnchorType, comment.StartOffset, comment.EndOffset,
+		comment.QuotedText, comment.StartSeconds, comment.EndSeconds, comment.Comment,
+	).Scan(&comment.ID, &comment.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create review comment: %w", err)
+	}
+	return nil
+}
+
+// ListReviewComments returns a review's comments in the order they were written
+func (r *ExerciseRepository) ListReviewComments(reviewID uuid.UUID) ([]models.ReviewComment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, review_id, author_id, anchor_type, start_offset, end_offset, quoted_text,
+		       start_seconds, end_seconds, comment, created_at
+		FROM review_comments
+		WHERE review_id = $1
+		ORDER BY created_at
+	`, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list review comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []models.ReviewComment{}
+	for rows.Next() {
+		var c models.ReviewComment
+		err := rows.Scan(&c.ID, &c.ReviewID, &c.AuthorID, &c.AnchorType, &c.StartOffset, &c.EndOffset,
+			&c.QuotedText, &c.StartSeconds, &c.EndSeconds, &c.Comment, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan review comment: %w", err)
+		}
+		comments = append(comments, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list review comments: %w", err)
+	}
+	return comments, nil
+}
+
+// DeleteReviewComment deletes a comment its author wrote on a review
+func (r *ExerciseRepository) DeleteReviewComment(reviewID, commentID, authorID uuid.UUID) error {
+	result, err := r.db.Exec(`
+		DELETE FROM review_comments WHERE id = $1 AND review_id = $2 AND author_id = $3
+	`, commentID, reviewID, authorID)
+	if err != nil {
+		return fmt.Errorf("failed to delete review comment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("review comment not found")
+	}
+	return nil
+}
+
+// scanReview scans reviewColumns, followed by any extra columns, into review
+func scanReview(row rowScanner, review *models.SubmissionReview, extra ...interface{}) error {
+	var aiCriteria, overrides []byte
+	dest := []interface{}{
+		&review.ID, &review.AttemptID, &review.SkillType, &review.Status, &review.ReviewerID,
+		&review.ClaimedAt, &review.AIBandScore, &aiCriteria, &overrides, &review.BandScore,
+		&review.ReviewerFeedback, &review.PublishedAt, &review.UserServiceSyncedAt,
+		&review.CreatedAt, &review.UpdatedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	review.AICriteriaScores = map[string]float64{}
+	review.CriteriaOverrides = map[string]float64{}
+	if err := json.Unmarshal(aiCriteria, &review.AICriteriaScores); err != nil {
+		return fmt.Errorf("failed to decode AI criteria scores: %w", err)
+	}
+	if err := json.Unmarshal(overrides, &review.CriteriaOverrides); err != nil {
+		return fmt.Errorf("failed to decode criteria overrides: %w", err)
+	}
+	return nil
+}
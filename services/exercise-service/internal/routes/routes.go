@@ -24,6 +24,15 @@ func SetupRoutes(router *gin.Engine, handler *handlers.ExerciseHandler, storageH
 			}
 		}
 
+		// Internal routes (service-to-service, require API key)
+		internal := api.Group("/exercises/internal")
+		internal.Use(authMiddleware.InternalAuth())
+		{
+			// Personal data export and erasure (account deletion)
+			internal.GET("/users/:user_id/data", handler.ExportUserData)
+			internal.DELETE("/users/:user_id/data", handler.EraseUserData)
+		}
+
 		// Public routes (optional auth)
 		exercises := api.Group("/exercises")
 		exercises.Use(authMiddleware.OptionalAuth())
@@ -42,9 +51,56 @@ func SetupRoutes(router *gin.Engine, handler *handlers.ExerciseHandler, storageH
 			submissions.POST("/:id/submit", handler.SubmitExercise)     // Unified submission (Phase 4)
 			submissions.PUT("/:id/answers", handler.SubmitAnswers)      // Submit answers (deprecated, use /submit)
 			submissions.GET("/:id/result", handler.GetSubmissionResult) // Get result
+			submissions.GET("/:id/session", handler.GetAttemptSession)  // Resume: deadline, time left and draft
+			submissions.PUT("/:id/draft", handler.SaveAttemptDraft)     // Autosave answers without grading
 			submissions.GET("/my", handler.GetMySubmissions)            // Get my submissions
 		}
 
+		// Classroom assignments and gradebook (access is checked per classroom)
+		classrooms := api.Group("/classrooms/:id")
+		classrooms.Use(authMiddleware.AuthRequired())
+		{
+			classrooms.GET("/assignments", handler.ListClassroomAssignments) // List assignments
+			classrooms.POST("/assignments", handler.CreateAssignment)        // Create assignment (teachers)
+			classrooms.GET("/gradebook", handler.GetGradebook)               // Gradebook (teachers)
+			classrooms.GET("/gradebook/export", handler.ExportGradebook)     // Gradebook as CSV (teachers)
+		}
+
+		assignments := api.Group("/assignments")
+		assignments.Use(authMiddleware.AuthRequired())
+		{
+			assignments.GET("/my", handler.GetMyAssignments)     // My assignments with results
+			assignments.GET("/:id", handler.GetAssignment)       // Get assignment
+			assignments.PUT("/:id", handler.UpdateAssignment)    // Update assignment (teachers)
+			assignments.DELETE("/:id", handler.DeleteAssignment) // Delete assignment (teachers)
+		}
+
+		// Mock tests: the four skills in one sitting
+		mockTests := api.Group("/mock-tests")
+		{
+			mockTests.GET("", handler.ListMockTests)                                              // List published mock tests
+			mockTests.GET("/:id", handler.GetMockTest)                                            // Get mock test
+			mockTests.POST("/:id/sessions", authMiddleware.AuthRequired(), handler.StartMockTest) // Start or resume a sitting
+		}
+
+		mockTestSessions := api.Group("/mock-test-sessions")
+		mockTestSessions.Use(authMiddleware.AuthRequired())
+		{
+			mockTestSessions.GET("/my", handler.GetMyMockTestSessions)         // My sittings
+			mockTestSessions.GET("/:id", handler.GetMockTestSession)           // Sitting with stages and result
+			mockTestSessions.POST("/:id/next", handler.StartNextMockTestStage) // Start the next stage
+		}
+
+		// Adaptive practice from the question bank
+		adaptive := api.Group("/adaptive-practice")
+		adaptive.Use(authMiddleware.AuthRequired())
+		{
+			adaptive.GET("/ability", handler.GetAbilityEstimates)                  // Ability per question type of a skill
+			adaptive.POST("/sessions", handler.StartAdaptivePractice)              // Start or resume a session
+			adaptive.GET("/sessions/:id", handler.GetAdaptivePracticeSession)      // Session with its next question
+			adaptive.POST("/sessions/:id/answers", handler.AnswerAdaptiveQuestion) // Answer the current question
+		}
+
 		// Tags routes (public)
 		tags := api.Group("/tags")
 		{
@@ -85,6 +141,21 @@ func SetupRoutes(router *gin.Engine, handler *handlers.ExerciseHandler, storageH
 			admin.POST("/question-bank", handler.CreateBankQuestion)       // Create bank question
 			admin.PUT("/question-bank/:id", handler.UpdateBankQuestion)    // Update bank question
 			admin.DELETE("/question-bank/:id", handler.DeleteBankQuestion) // Delete bank question
+
+			// Mock test management
+			admin.GET("/mock-tests", handler.ListAllMockTests)   // List mock tests
+			admin.POST("/mock-tests", handler.CreateMockTest)    // Create mock test
+			admin.PUT("/mock-tests/:id", handler.UpdateMockTest) // Update mock test
+
+			// Examiner review of AI-evaluated writing and speaking
+			admin.GET("/reviews", handler.GetReviewQueue)                                  // Review queue
+			admin.GET("/reviews/:id", handler.GetReview)                                   // Review with submission and comments
+			admin.POST("/reviews/:id/claim", handler.ClaimReview)                          // Claim review
+			admin.POST("/reviews/:id/release", handler.ReleaseReview)                      // Return review to queue
+			admin.PUT("/reviews/:id/scores", handler.UpdateReviewScores)                   // Override criteria scores
+			admin.POST("/reviews/:id/comments", handler.AddReviewComment)                  // Add inline comment
+			admin.DELETE("/reviews/:id/comments/:comment_id", handler.DeleteReviewComment) // Delete comment
+			admin.POST("/reviews/:id/publish", handler.PublishReview)                      // Publish final result
 		}
 	}
 }
@@ -0,0 +1,578 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/grading"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/irt"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAdaptiveSessionNotFound    = errors.New("adaptive practice session not found")
+	ErrAdaptiveSessionFinished    = errors.New("adaptive practice session has finished")
+	ErrAdaptiveQuestionNotCurrent = errors.New("the question is not the one the session is waiting on")
+	ErrNoAdaptiveQuestions        = errors.New("no bank questions to practise at this skill and question type")
+)
+
+const (
+	// adaptiveMinItems are answered before a session can stop as converged
+	adaptiveMinItems = 5
+	// adaptiveMaxItems ends a session whether or not it has converged
+	adaptiveMaxItems = 30
+	// adaptiveTargetStandardError is the standard error a converged estimate
+	// reaches; about half a band either side
+	adaptiveTargetStandardError = 0.3
+	// adaptiveStableItems answers in a row moving the estimate by less than
+	// adaptiveStableChange also count as converged
+	adaptiveStableItems  = 3
+	adaptiveStableChange = 0.05
+	// adaptivePriorMinSD keeps the prior from earlier answers loose enough for
+	// the session to move it
+	adaptivePriorMinSD = 0.6
+	// adaptiveEvidenceLimit caps the earlier answers of each kind the prior is
+	// estimated from
+	adaptiveEvidenceLimit = 200
+	// adaptiveRepeatAfter is how long before a bank question is asked again
+	adaptiveRepeatAfter = 30 * 24 * time.Hour
+	// adaptiveCandidateLimit caps the bank questions considered for each pick
+	adaptiveCandidateLimit = 500
+	// adaptiveTopItems is how many of the most informative questions the
+	// next one is drawn from, so learners at the same level do not all see
+	// the same questions
+	adaptiveTopItems = 3
+	// confidenceZ gives 95% confidence intervals
+	confidenceZ = 1.96
+
+	// itemCalibrationInterval is how often bank questions are calibrated
+	itemCalibrationInterval = 6 * time.Hour
+	// calibrationMinResponses are needed before a question is calibrated, and
+	// calibrationMinNewResponses more before it is calibrated again
+	calibrationMinResponses    = 30
+	calibrationMinNewResponses = 10
+	// calibrationBatchSize caps the questions calibrated per run
+	calibrationBatchSize = 100
+)
+
+// Ability and item difficulty share a logit scale. An average learner on an
+// average ("medium") question is put at band 6, and each logit is worth 1.25
+// bands, so the scale's -4 to 4 covers bands 1 to 9.
+const (
+	bandAtAverageAbility = 6.0
+	bandsPerLogit        = 1.25
+)
+
+// labelDifficulties are the difficulties assumed for questions without
+// calibrated parameters or answers from other learners
+var labelDifficulties = map[string]float64{
+	"easy":   -1,
+	"medium": 0,
+	"hard":   1,
+}
+
+// StartAdaptivePractice starts a session at a skill and question type, with a
+// prior estimated from the learner's earlier answers, or returns the session
+// in progress there
+func (s *ExerciseService) StartAdaptivePractice(userID uuid.UUID, req *models.StartAdaptivePracticeRequest) (*models.AdaptivePracticeSession, error) {
+	session, err := s.repo.GetActiveAdaptiveSession(userID, req.SkillType, req.QuestionType)
+	if err != nil {
+		return nil, err
+	}
+	if session != nil {
+		if err := s.loadNextQuestion(session); err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+
+	evidence, err := s.repo.GetAbilityEvidence(userID, req.SkillType, req.QuestionType, nil, adaptiveEvidenceLimit)
+	if err != nil {
+		return nil, err
+	}
+	estimate := irt.EstimateAbility(evidenceResponses(evidence), irt.StandardNormal)
+
+	session = &models.AdaptivePracticeSession{
+		UserID:             userID,
+		SkillType:          req.SkillType,
+		QuestionType:       req.QuestionType,
+		PriorTheta:         estimate.Theta,
+		PriorStandardError: math.Max(estimate.StandardError, adaptivePriorMinSD),
+		PriorResponses:     len(evidence),
+	}
+	setSessionEstimate(session, irt.Estimate{Theta: session.PriorTheta, StandardError: session.PriorStandardError})
+
+	next, err := s.pickAdaptiveQuestion(session, nil)
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		return nil, ErrNoAdaptiveQuestions
+	}
+	session.CurrentQuestionID = &next.ID
+	if err := s.repo.CreateAdaptiveSession(session); err != nil {
+		return nil, err
+	}
+	session.NextQuestion = adaptiveQuestionView(next)
+	log.Printf("🎯 User %s started adaptive %s %s practice at band %.1f (%d earlier answers)",
+		userID, req.SkillType, req.QuestionType, session.EstimatedBand, session.PriorResponses)
+	return session, nil
+}
+
+// GetAdaptivePracticeSession returns one of the learner's sessions with the
+// question it is waiting on
+func (s *ExerciseService) GetAdaptivePracticeSession(userID, sessionID uuid.UUID) (*models.AdaptivePracticeSession, error) {
+	session, err := s.ownAdaptiveSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadNextQuestion(session); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// AnswerAdaptiveQuestion marks the answer to the session's current question,
+// updates the ability estimate and picks the next question, or ends the
+// session once the estimate has converged, the item limit is reached or no
+// questions are left
+func (s *ExerciseService) AnswerAdaptiveQuestion(userID, sessionID uuid.UUID, req *models.AdaptiveAnswerRequest) (*models.AdaptiveAnswerResult, error) {
+	session, err := s.ownAdaptiveSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "in_progress" {
+		return nil, ErrAdaptiveSessionFinished
+	}
+	if session.CurrentQuestionID == nil || *session.CurrentQuestionID != req.QuestionID {
+		return nil, ErrAdaptiveQuestionNotCurrent
+	}
+
+	question, err := s.repo.GetBankQuestionByID(req.QuestionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAdaptiveQuestionNotCurrent
+	}
+	if err != nil {
+		return nil, err
+	}
+	key := parseBankAnswerKey(question)
+	mark := gradeBankAnswer(question, key, req)
+	score := float64(mark.Marks) / float64(mark.MaxMarks)
+
+	responses, evidence, err := s.repo.GetAdaptiveResponses(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	evidence = append(evidence, repository.ItemEvidence{
+		QuestionType:    question.QuestionType,
+		Score:           score,
+		DifficultyLabel: question.Difficulty,
+		Discrimination:  question.IRTDiscrimination,
+		Difficulty:      question.IRTDifficulty,
+	})
+	estimate := irt.EstimateAbility(evidenceResponses(evidence),
+		irt.Normal{Mean: session.PriorTheta, SD: session.PriorStandardError})
+
+	response := &models.AdaptivePracticeResponse{
+		SessionID:          session.ID,
+		UserID:             userID,
+		BankQuestionID:     question.ID,
+		Score:              score,
+		IsCorrect:          mark.IsCorrect,
+		ThetaBefore:        session.Theta,
+		ThetaAfter:         estimate.Theta,
+		StandardErrorAfter: estimate.StandardError,
+	}
+	responses = append(responses, *response)
+	setSessionEstimate(session, estimate)
+
+	var next *models.QuestionBank
+	stopReason := adaptiveStopReason(responses, estimate)
+	if stopReason == "" {
+		if next, err = s.pickAdaptiveQuestion(session, &question.ID); err != nil {
+			return nil, err
+		}
+		if next == nil {
+			stopReason = "bank_exhausted"
+		}
+	}
+	session.CurrentQuestionID = nil
+	if stopReason != "" {
+		now := time.Now()
+		session.Status = "completed"
+		session.StopReason = &stopReason
+		session.CompletedAt = &now
+	} else {
+		session.CurrentQuestionID = &next.ID
+	}
+
+	answerData, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode answer: %w", err)
+	}
+	recorded, err := s.repo.RecordAdaptiveAnswer(session, response, answerData, question.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !recorded {
+		return nil, ErrAdaptiveQuestionNotCurrent
+	}
+
+	if stopReason != "" {
+		adaptiveSessionsCompletedTotal.WithLabelValues(stopReason).Inc()
+		log.Printf("🎯 Adaptive practice session %s finished (%s) after %d questions: band %.1f (%.1f-%.1f)",
+			session.ID, stopReason, session.ItemsAnswered, session.EstimatedBand, session.BandLower, session.BandUpper)
+	} else {
+		session.NextQuestion = adaptiveQuestionView(next)
+	}
+
+	result := &models.AdaptiveAnswerResult{
+		QuestionID:  question.ID,
+		IsCorrect:   mark.IsCorrect,
+		Marks:       mark.Marks,
+		MaxMarks:    mark.MaxMarks,
+		Explanation: key.Explanation,
+		Session:     session,
+	}
+	for _, p := range mark.Parts {
+		result.Parts = append(result.Parts, models.AnswerPart{Part: p.Part, Answer: p.Answer, IsCorrect: p.IsCorrect})
+	}
+	return result, nil
+}
+
+// GetAbilityEstimates estimates the learner's ability at each question type
+// of a skill they have answered, from exercises and adaptive practice
+func (s *ExerciseService) GetAbilityEstimates(userID uuid.UUID, skillType string) ([]models.AbilityEstimate, error) {
+	evidence, err := s.repo.GetAbilityEvidence(userID, skillType, "", nil, adaptiveEvidenceLimit)
+	if err != nil {
+		return nil, err
+	}
+	byType := map[string][]repository.ItemEvidence{}
+	for _, e := range evidence {
+		byType[e.QuestionType] = append(byType[e.QuestionType], e)
+	}
+
+	estimates := []models.AbilityEstimate{}
+	for questionType, typeEvidence := range byType {
+		estimate := irt.EstimateAbility(evidenceResponses(typeEvidence), irt.StandardNormal)
+		band, lower, upper := abilityBands(estimate)
+		estimates = append(estimates, models.AbilityEstimate{
+			SkillType:     skillType,
+			QuestionType:  questionType,
+			Responses:     len(typeEvidence),
+			Theta:         estimate.Theta,
+			StandardError: estimate.StandardError,
+			EstimatedBand: band,
+			BandLower:     lower,
+			BandUpper:     upper,
+		})
+	}
+	sort.Slice(estimates, func(i, j int) bool { return estimates[i].QuestionType < estimates[j].QuestionType })
+	return estimates, nil
+}
+
+// StartItemCalibrationWorker refits the parameters of bank questions from
+// adaptive practice responses
+func (s *ExerciseService) StartItemCalibrationWorker() {
+	ticker := time.NewTicker(itemCalibrationInterval)
+	defer ticker.Stop()
+
+	log.Println("🎯 Started bank question calibration worker (running every 6 hours)")
+
+	for range ticker.C {
+		s.calibrateBankQuestions()
+	}
+}
+
+// calibrateBankQuestions calibrates a batch of bank questions with enough new
+// responses, against the final ability estimates of the learners who gave them
+func (s *ExerciseService) calibrateBankQuestions() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in calibrateBankQuestions: %v", r)
+		}
+	}()
+
+	ids, err := s.repo.GetItemsToCalibrate(calibrationMinResponses, calibrationMinNewResponses, calibrationBatchSize)
+	if err != nil {
+		log.Printf("⚠️ Failed to get bank questions to calibrate: %v", err)
+		return
+	}
+	for _, id := range ids {
+		question, err := s.repo.GetBankQuestionByID(id)
+		if err != nil {
+			log.Printf("⚠️ Failed to get bank question %s: %v", id, err)
+			continue
+		}
+		observations, err := s.repo.GetCalibrationObservations(id)
+		if err != nil {
+			log.Printf("⚠️ Failed to get responses to bank question %s: %v", id, err)
+			continue
+		}
+
+		// Shrink towards the label, not the last fit, so fits do not drift
+		start := irt.Item{Discrimination: 1, Difficulty: labelDifficulty(question.Difficulty)}
+		fitted, ok := irt.Calibrate(observations, start)
+		if !ok {
+			itemCalibrationsTotal.WithLabelValues("not_converged").Inc()
+			log.Printf("⚠️ Calibration of bank question %s did not converge (%d responses)", id, len(observations))
+			continue
+		}
+		if err := s.repo.UpdateItemParameters(id, fitted, len(observations)); err != nil {
+			itemCalibrationsTotal.WithLabelValues("error").Inc()
+			log.Printf("⚠️ Failed to save parameters of bank question %s: %v", id, err)
+			continue
+		}
+		itemCalibrationsTotal.WithLabelValues("calibrated").Inc()
+	}
+	if len(ids) > 0 {
+		log.Printf("🎯 Calibrated %d bank questions", len(ids))
+	}
+}
+
+// adaptiveStopReason returns why a session should stop after its latest
+// response, or "" to keep going
+func adaptiveStopReason(responses []models.AdaptivePracticeResponse, estimate irt.Estimate) string {
+	n := len(responses)
+	if n >= adaptiveMaxItems {
+		return "max_items"
+	}
+	if n < adaptiveMinItems {
+		return ""
+	}
+	if estimate.StandardError <= adaptiveTargetStandardError {
+		return "converged"
+	}
+	for _, r := range responses[n-adaptiveStableItems:] {
+		if math.Abs(r.ThetaAfter-r.ThetaBefore) >= adaptiveStableChange {
+			return ""
+		}
+	}
+	return "converged"
+}
+
+// pickAdaptiveQuestion picks one of the most informative bank questions at
+// the session's ability estimate, or nil when none are left
+func (s *ExerciseService) pickAdaptiveQuestion(session *models.AdaptivePracticeSession, exclude *uuid.UUID) (*models.QuestionBank, error) {
+	candidates, err := s.repo.GetAdaptiveCandidates(session.UserID, session.SkillType, session.QuestionType,
+		time.Now().Add(-adaptiveRepeatAfter), adaptiveCandidateLimit)
+	if err != nil {
+		return nil, err
+	}
+	if exclude != nil {
+		kept := candidates[:0]
+		for _, q := range candidates {
+			if q.ID != *exclude {
+				kept = append(kept, q)
+			}
+		}
+		candidates = kept
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return bankItem(&candidates[i]).Information(session.Theta) > bankItem(&candidates[j]).Information(session.Theta)
+	})
+	top := candidates
+	if len(top) > adaptiveTopItems {
+		top = top[:adaptiveTopItems]
+	}
+	return &top[rand.Intn(len(top))], nil
+}
+
+// loadNextQuestion fills in the question an in-progress session is waiting
+// on, picking another if that one was deleted from the bank
+func (s *ExerciseService) loadNextQuestion(session *models.AdaptivePracticeSession) error {
+	if session.Status != "in_progress" {
+		return nil
+	}
+	if session.CurrentQuestionID != nil {
+		question, err := s.repo.GetBankQuestionByID(*session.CurrentQuestionID)
+		if err == nil {
+			session.NextQuestion = adaptiveQuestionView(question)
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	next, err := s.pickAdaptiveQuestion(session, nil)
+	if err != nil {
+		return err
+	}
+	if next == nil {
+		return ErrNoAdaptiveQuestions
+	}
+	if err := s.repo.SetAdaptiveCurrentQuestion(session.ID, next.ID); err != nil {
+		return err
+	}
+	session.CurrentQuestionID = &next.ID
+	session.NextQuestion = adaptiveQuestionView(next)
+	return nil
+}
+
+// ownAdaptiveSession loads a session and checks it belongs to the user
+func (s *ExerciseService) ownAdaptiveSession(userID, sessionID uuid.UUID) (*models.AdaptivePracticeSession, error) {
+	session, err := s.repo.GetAdaptiveSession(sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAdaptiveSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrAdaptiveSessionNotFound
+	}
+	return session, nil
+}
+
+// setSessionEstimate sets a session's ability estimate and the band it means
+func setSessionEstimate(session *models.AdaptivePracticeSession, estimate irt.Estimate) {
+	session.Theta = estimate.Theta
+	session.StandardError = estimate.StandardError
+	session.EstimatedBand, session.BandLower, session.BandUpper = abilityBands(estimate)
+}
+
+// abilityBands converts an ability estimate to a band with a 95% confidence interval
+func abilityBands(estimate irt.Estimate) (band, lower, upper float64) {
+	lo, hi := estimate.Interval(confidenceZ)
+	return thetaBand(estimate.Theta), thetaBand(lo), thetaBand(hi)
+}
+
+func thetaBand(theta float64) float64 {
+	return math.Max(1, ielts.RoundToHalfBand(bandAtAverageAbility+bandsPerLogit*theta))
+}
+
+// evidenceResponses turns answers into IRT responses. Bank questions use their
+// calibrated parameters; exercise questions get a difficulty from how other
+// learners scored on them; otherwise the difficulty label is used.
+func evidenceResponses(evidence []repository.ItemEvidence) []irt.Response {
+	responses := make([]irt.Response, len(evidence))
+	for i, e := range evidence {
+		item := irt.Item{Discrimination: 1, Difficulty: labelDifficulty(e.DifficultyLabel)}
+		switch {
+		case e.Difficulty != nil:
+			item.Difficulty = *e.Difficulty
+			if e.Discrimination != nil {
+				item.Discrimination = *e.Discrimination
+			}
+		case e.ItemResponses > 0:
+			item.Difficulty = irt.DifficultyFromResponses(float64(e.ItemResponses), e.ItemScore, item.Difficulty)
+		}
+		responses[i] = irt.Response{Item: item, Score: e.Score}
+	}
+	return responses
+}
+
+// bankItem returns a bank question's parameters, from its label until calibrated
+func bankItem(q *models.QuestionBank) irt.Item {
+	item := irt.Item{Discrimination: 1, Difficulty: labelDifficulty(q.Difficulty)}
+	if q.IRTDifficulty != nil {
+		item.Difficulty = *q.IRTDifficulty
+	}
+	if q.IRTDiscrimination != nil {
+		item.Discrimination = *q.IRTDiscrimination
+	}
+	return item
+}
+
+func labelDifficulty(label *string) float64 {
+	if label == nil {
+		return 0
+	}
+	return labelDifficulties[strings.ToLower(strings.TrimSpace(*label))]
+}
+
+// bankAnswerKey is what adaptive practice reads from a bank question's
+// answer_data: correct_answer holds the accepted answer or answers (letters
+// for choice questions, "A-1" item-answer pairs for matching and labelling),
+// alternative_answers more accepted wordings, options what is shown to
+// learners and explanation what they see after answering
+type bankAnswerKey struct {
+	CorrectAnswer      []string    `json:"-"`
+	AlternativeAnswers []string    `json:"alternative_answers"`
+	Options            interface{} `json:"options"`
+	Explanation        *string     `json:"explanation"`
+}
+
+func parseBankAnswerKey(q *models.QuestionBank) *bankAnswerKey {
+	var raw struct {
+		CorrectAnswer json.RawMessage `json:"correct_answer"`
+		bankAnswerKey
+	}
+	key := &bankAnswerKey{}
+	if err := json.Unmarshal([]byte(q.AnswerData), &raw); err != nil {
+		return key
+	}
+	*key = raw.bankAnswerKey
+	var one string
+	if err := json.Unmarshal(raw.CorrectAnswer, &key.CorrectAnswer); err != nil && json.Unmarshal(raw.CorrectAnswer, &one) == nil {
+		key.CorrectAnswer = []string{one}
+	}
+	return key
+}
+
+// gradeBankAnswer marks an answer to a bank question with the grader for its type
+func gradeBankAnswer(q *models.QuestionBank, key *bankAnswerKey, req *models.AdaptiveAnswerRequest) grading.Result {
+	question := &grading.Question{Type: q.QuestionType, Points: 1, Instructions: q.QuestionText}
+	answer := &grading.Answer{Text: req.TextAnswer, Matches: req.Matches, Blanks: req.Blanks}
+
+	switch q.QuestionType {
+	case "multiple_choice", "multiple_select":
+		for _, label := range key.CorrectAnswer {
+			question.Options = append(question.Options, grading.Option{ID: optionLabelID(q.ID, label), Label: label, IsCorrect: true})
+		}
+		for _, label := range req.SelectedLabels {
+			answer.SelectedOptionIDs = append(answer.SelectedOptionIDs, optionLabelID(q.ID, label))
+		}
+		if len(answer.SelectedOptionIDs) == 1 {
+			answer.SelectedOptionID = &answer.SelectedOptionIDs[0]
+		}
+	default:
+		matching := strings.HasPrefix(q.QuestionType, "matching") || strings.HasSuffix(q.QuestionType, "_labeling")
+		for _, entry := range key.CorrectAnswer {
+			if part, text, ok := strings.Cut(entry, "-"); matching && ok {
+				question.Keys = append(question.Keys, grading.Key{Part: strings.TrimSpace(part), Text: strings.TrimSpace(text)})
+				continue
+			}
+			question.Keys = append(question.Keys, grading.Key{Text: entry, Variations: key.AlternativeAnswers})
+		}
+		if answer.Text == nil && len(req.SelectedLabels) == 1 {
+			answer.Text = &req.SelectedLabels[0]
+		}
+	}
+	return grading.Grade(question, answer)
+}
+
+// optionLabelID gives a bank question's option letters stable IDs to grade with
+func optionLabelID(questionID uuid.UUID, label string) uuid.UUID {
+	return uuid.NewSHA1(questionID, []byte(strings.ToUpper(strings.TrimSpace(label))))
+}
+
+// adaptiveQuestionView is a bank question without its answer
+func adaptiveQuestionView(q *models.QuestionBank) *models.AdaptiveQuestion {
+	return &models.AdaptiveQuestion{
+		ID:           q.ID,
+		Title:        q.Title,
+		QuestionType: q.QuestionType,
+		QuestionText: q.QuestionText,
+		ContextText:  q.ContextText,
+		AudioURL:     q.AudioURL,
+		ImageURL:     q.ImageURL,
+		Options:      parseBankAnswerKey(q).Options,
+	}
+}
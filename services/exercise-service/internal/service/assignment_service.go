@@ -0,0 +1,787 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/csvsafe"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAssignmentNotFound  = errors.New("assignment not found")
+	ErrClassroomNotFound   = errors.New("classroom not found")
+	ErrNotClassroomTeacher = errors.New("only the classroom's teachers can manage its assignments")
+	ErrNotAssigned         = errors.New("this assignment is not set for you")
+	ErrInvalidAssignment   = errors.New("invalid assignment")
+	ErrAssignmentNotOpen   = errors.New("assignment is not open yet")
+	ErrAssignmentClosed    = errors.New("assignment is past its due date and does not accept late work")
+	ErrAttemptLimitReached = errors.New("no attempts left for this assignment")
+)
+
+const (
+	// dueReminderWindow is how long before the due date learners are reminded
+	dueReminderWindow = 24 * time.Hour
+	// reminderBatchSize caps the assignments reminded about per run
+	reminderBatchSize = 50
+)
+
+// CreateAssignment sets an exercise or a lesson for a classroom and notifies
+// the learners it is set for
+func (s *ExerciseService) CreateAssignment(classroomID, teacherID uuid.UUID, req *models.CreateAssignmentRequest) (*models.Assignment, error) {
+	access, err := s.teacherAccess(classroomID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &models.Assignment{
+		ClassroomID:        classroomID,
+		Title:              req.Title,
+		Instructions:       req.Instructions,
+		StudentIDs:         req.StudentIDs,
+		DueAt:              req.DueAt,
+		MaxAttempts:        req.MaxAttempts,
+		LatePolicy:         req.LatePolicy,
+		LatePenaltyPercent: req.LatePenaltyPercent,
+		CreatedBy:          teacherID,
+	}
+	a.OpenAt = time.Now()
+	if req.OpenAt != nil {
+		a.OpenAt = *req.OpenAt
+	}
+	if a.LatePolicy == "" {
+		a.LatePolicy = "allow"
+	}
+
+	switch {
+	case req.ExerciseID != nil && req.LessonID == nil:
+		exercise, err := s.repo.GetExerciseByIDSimple(*req.ExerciseID)
+		if err != nil || !exercise.IsPublished {
+			return nil, fmt.Errorf("%w: exercise not found or not published", ErrInvalidAssignment)
+		}
+		a.ItemType = "exercise"
+		a.ExerciseID = req.ExerciseID
+		a.ItemTitle = exercise.Title
+	case req.LessonID != nil && req.ExerciseID == nil:
+		batch, err := s.courseClient.GetLessonProgress([]string{req.LessonID.String()}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch.Lessons) == 0 || !batch.Lessons[0].IsPublished {
+			return nil, fmt.Errorf("%w: lesson not found or not published", ErrInvalidAssignment)
+		}
+		courseID, err := uuid.Parse(batch.Lessons[0].CourseID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid course ID of lesson %s: %w", req.LessonID, err)
+		}
+		a.ItemType = "lesson"
+		a.LessonID = req.LessonID
+		a.CourseID = &courseID
+		a.ItemTitle = batch.Lessons[0].Title
+	default:
+		return nil, fmt.Errorf("%w: set exactly one of exercise_id and lesson_id", ErrInvalidAssignment)
+	}
+
+	if err := validateAssignment(a, access); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateAssignment(a); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📋 Assignment %s created in classroom %s by %s", a.ID, classroomID, teacherID)
+	go s.notifyAssignmentCreated(a, access)
+	return a, nil
+}
+
+// ListClassroomAssignments returns the classroom's assignments. Teachers see
+// all of them, students only those set for them that are open.
+func (s *ExerciseService) ListClassroomAssignments(classroomID, userID uuid.UUID) ([]models.Assignment, error) {
+	access, err := s.classroomAccess(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.CanTeach() && access.Role != "student" {
+		return nil, ErrClassroomNotFound
+	}
+
+	assignments, err := s.repo.ListAssignmentsByClassrooms([]uuid.UUID{classroomID})
+	if err != nil {
+		return nil, err
+	}
+	if access.CanTeach() {
+		return assignments, nil
+	}
+
+	now := time.Now()
+	visible := []models.Assignment{}
+	for _, a := range assignments {
+		if a.AssignedTo(userID) && !now.Before(a.OpenAt) {
+			visible = append(visible, a)
+		}
+	}
+	return visible, nil
+}
+
+// GetAssignment returns an assignment to its classroom's teachers and to the
+// students it is set for once it is open
+func (s *ExerciseService) GetAssignment(assignmentID, userID uuid.UUID) (*models.Assignment, error) {
+	a, err := s.getAssignment(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.classroomAccess(a.ClassroomID, userID)
+	if err != nil {
+		if errors.Is(err, ErrClassroomNotFound) {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+	if access.CanTeach() {
+		return a, nil
+	}
+	if access.Role != "student" || !a.AssignedTo(userID) || time.Now().Before(a.OpenAt) {
+		return nil, ErrAssignmentNotFound
+	}
+	return a, nil
+}
+
+// UpdateAssignment changes an assignment's title, learners, dates, attempt
+// limit or late policy
+func (s *ExerciseService) UpdateAssignment(assignmentID, teacherID uuid.UUID, req *models.UpdateAssignmentRequest) (*models.Assignment, error) {
+	a, err := s.getAssignment(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.teacherAccess(a.ClassroomID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		a.Title = *req.Title
+	}
+	if req.Instructions != nil {
+		a.Instructions = req.Instructions
+	}
+	if req.StudentIDs != nil {
+		a.StudentIDs = *req.StudentIDs
+	}
+	if req.OpenAt != nil {
+		a.OpenAt = *req.OpenAt
+	}
+	if req.DueAt != nil {
+		a.DueAt = *req.DueAt
+	}
+	if req.MaxAttempts != nil {
+		a.MaxAttempts = req.MaxAttempts
+		if *req.MaxAttempts == 0 {
+			a.MaxAttempts = nil
+		}
+	}
+	if req.LatePolicy != nil {
+		a.LatePolicy = *req.LatePolicy
+	}
+	if req.LatePenaltyPercent != nil {
+		a.LatePenaltyPercent = *req.LatePenaltyPercent
+	}
+
+	if err := validateAssignment(a, access); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateAssignment(a); err != nil {
+		if err.Error() == "assignment not found" {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// DeleteAssignment removes an assignment. Attempts already made stay in the
+// learners' history.
+func (s *ExerciseService) DeleteAssignment(assignmentID, teacherID uuid.UUID) error {
+	a, err := s.getAssignment(assignmentID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.teacherAccess(a.ClassroomID, teacherID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteAssignment(assignmentID); err != nil {
+		if err.Error() == "assignment not found" {
+			return ErrAssignmentNotFound
+		}
+		return err
+	}
+	log.Printf("📋 Assignment %s deleted by %s", assignmentID, teacherID)
+	return nil
+}
+
+// ListMyAssignments returns the open assignments set for a learner across
+// their classrooms, with how they are doing on each
+func (s *ExerciseService) ListMyAssignments(userID uuid.UUID) ([]models.StudentAssignment, error) {
+	classrooms, err := s.authClient.ListUserClassrooms(userID.String())
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string)
+	classroomIDs := []uuid.UUID{}
+	for _, c := range classrooms {
+		id, err := uuid.Parse(c.ID)
+		if err != nil || c.Role != "student" {
+			continue
+		}
+		names[id] = c.Name
+		classroomIDs = append(classroomIDs, id)
+	}
+	result := []models.StudentAssignment{}
+	if len(classroomIDs) == 0 {
+		return result, nil
+	}
+
+	all, err := s.repo.ListAssignmentsByClassrooms(classroomIDs)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	assignments := []models.Assignment{}
+	for _, a := range all {
+		if a.AssignedTo(userID) && !now.Before(a.OpenAt) {
+			assignments = append(assignments, a)
+		}
+	}
+
+	results, err := s.assignmentResults(assignments, []uuid.UUID{userID}, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assignments {
+		result = append(result, models.StudentAssignment{
+			Assignment:    a,
+			ClassroomName: names[a.ClassroomID],
+			Result:        results[resultKey{a.ID, userID}],
+		})
+	}
+	return result, nil
+}
+
+// GetGradebook returns every student's result on every assignment of the
+// classroom, for its teachers
+func (s *ExerciseService) GetGradebook(classroomID, teacherID uuid.UUID) (*models.Gradebook, error) {
+	access, err := s.teacherAccess(classroomID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	assignments, err := s.repo.ListAssignmentsByClassrooms([]uuid.UUID{classroomID})
+	if err != nil {
+		return nil, err
+	}
+
+	studentIDs := make([]uuid.UUID, 0, len(access.Students))
+	for _, st := range access.Students {
+		if id, err := uuid.Parse(st.UserID); err == nil {
+			studentIDs = append(studentIDs, id)
+		}
+	}
+
+	now := time.Now()
+	results, err := s.assignmentResults(assignments, studentIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	gradebook := &models.Gradebook{
+		ClassroomID: classroomID,
+		Assignments: assignments,
+		Students:    make([]models.GradebookStudent, 0, len(access.Students)),
+	}
+	for _, st := range access.Students {
+		studentID, err := uuid.Parse(st.UserID)
+		if err != nil {
+			continue
+		}
+		row := models.GradebookStudent{
+			UserID:  studentID,
+			Email:   st.Email,
+			Results: make([]models.AssignmentResult, 0, len(assignments)),
+		}
+		var bandSum float64
+		var bandCount int
+		for _, a := range assignments {
+			r := results[resultKey{a.ID, studentID}]
+			switch r.Status {
+			case "completed":
+				row.CompletedCount++
+			case "missing":
+				row.MissingCount++
+			}
+			if r.FinalBandScore != nil {
+				bandSum += *r.FinalBandScore
+				bandCount++
+			}
+			row.Results = append(row.Results, r)
+		}
+		if bandCount > 0 {
+			avg := math.Round(bandSum/float64(bandCount)*100) / 100
+			row.AverageBandScore = &avg
+		}
+		gradebook.Students = append(gradebook.Students, row)
+	}
+	return gradebook, nil
+}
+
+// WriteGradebookCSV writes the gradebook with one row per student and assignment
+func WriteGradebookCSV(w io.Writer, gradebook *models.Gradebook) error {
+	cw := csv.NewWriter(w)
+	header := []string{
+		"student_email", "student_id", "assignment", "item_type", "item_title", "due_at",
+		"status", "late", "attempts_used", "band_score", "final_band_score", "score",
+		"final_score", "completed_at",
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for _, st := range gradebook.Students {
+		for i, r := range st.Results {
+			a := gradebook.Assignments[i]
+			completedAt := ""
+			if r.CompletedAt != nil {
+				completedAt = r.CompletedAt.UTC().Format(time.RFC3339)
+			}
+			record := []string{
+				// Emails and titles are user-supplied; keep them from running as formulas
+				csvsafe.Cell(st.Email), st.UserID.String(), csvsafe.Cell(a.Title), a.ItemType, csvsafe.Cell(a.ItemTitle),
+				a.DueAt.UTC().Format(time.RFC3339), r.Status, strconv.FormatBool(r.Late),
+				strconv.Itoa(r.AttemptsUsed), formatScore(r.BandScore), formatScore(r.FinalBandScore),
+				formatScore(r.Score), formatScore(r.FinalScore), completedAt,
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// startAssignmentAttempt starts an attempt at an exercise for an assignment,
+// enforcing its dates, learners and attempt limit
+func (s *ExerciseService) startAssignmentAttempt(userID, exerciseID, assignmentID uuid.UUID, deviceType *string) (*models.UserExerciseAttempt, error) {
+	a, err := s.getAssignment(assignmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.ExerciseID == nil || *a.ExerciseID != exerciseID {
+		return nil, fmt.Errorf("%w: the assignment is not for this exercise", ErrInvalidAssignment)
+	}
+
+	access, err := s.classroomAccess(a.ClassroomID, userID)
+	if err != nil {
+		if errors.Is(err, ErrClassroomNotFound) {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+	if access.Role != "student" || !a.AssignedTo(userID) {
+		return nil, ErrNotAssigned
+	}
+
+	now := time.Now()
+	if now.Before(a.OpenAt) {
+		return nil, ErrAssignmentNotOpen
+	}
+	if now.After(a.DueAt) && a.LatePolicy == "block" {
+		return nil, ErrAssignmentClosed
+	}
+
+	submission, err := s.repo.CreateAssignmentSubmission(userID, a, deviceType)
+	if err != nil {
+		return nil, err
+	}
+	if submission == nil {
+		return nil, ErrAttemptLimitReached
+	}
+	return submission, nil
+}
+
+// StartAssignmentReminderWorker reminds learners of assignments due within a
+// day that they have not completed
+func (s *ExerciseService) StartAssignmentReminderWorker() {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	log.Println("⏰ Started assignment due reminder worker (checking every 15 minutes)")
+
+	for range ticker.C {
+		s.sendDueReminders()
+	}
+}
+
+// sendDueReminders sends one reminder per assignment nearing its due date
+func (s *ExerciseService) sendDueReminders() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in sendDueReminders: %v", r)
+		}
+	}()
+
+	now := time.Now()
+	assignments, err := s.repo.GetAssignmentsDueForReminder(now.Add(dueReminderWindow), reminderBatchSize)
+	if err != nil {
+		log.Printf("⚠️ Failed to get assignments due: %v", err)
+		return
+	}
+
+	for i := range assignments {
+		a := &assignments[i]
+		if err := s.remindAssignmentDue(a, now); err != nil {
+			log.Printf("⚠️ Failed to remind learners of assignment %s: %v", a.ID, err)
+			continue
+		}
+		if err := s.repo.MarkDueReminderSent(a.ID); err != nil {
+			log.Printf("⚠️ Failed to mark reminder of assignment %s: %v", a.ID, err)
+		}
+	}
+}
+
+func (s *ExerciseService) remindAssignmentDue(a *models.Assignment, now time.Time) error {
+	access, err := s.classroomAccess(a.ClassroomID, a.CreatedBy)
+	if errors.Is(err, ErrClassroomNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	students := assignedStudents(a, access)
+	if len(students) == 0 {
+		return nil
+	}
+	results, err := s.assignmentResults([]models.Assignment{*a}, students, now)
+	if err != nil {
+		return err
+	}
+
+	recipients := []string{}
+	for _, id := range students {
+		if results[resultKey{a.ID, id}].Status != "completed" {
+			recipients = append(recipients, id.String())
+		}
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	actionType, actionData := assignmentAction(a)
+	return s.notificationClient.SendBulk(client.BulkNotificationRequest{
+		UserIDs:    recipients,
+		Title:      "Bài tập sắp đến hạn",
+		Message:    fmt.Sprintf("Bài tập \"%s\" sẽ đến hạn lúc %s. Hãy hoàn thành trước hạn nhé!", a.Title, a.DueAt.Format("15:04 02/01/2006")),
+		Type:       "reminder",
+		Category:   "warning",
+		ActionType: &actionType,
+		ActionData: actionData,
+	})
+}
+
+// notifyAssignmentCreated tells the learners an assignment was set for them
+func (s *ExerciseService) notifyAssignmentCreated(a *models.Assignment, access *client.ClassroomAccess) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in notifyAssignmentCreated: %v", r)
+		}
+	}()
+
+	students := assignedStudents(a, access)
+	if len(students) == 0 {
+		return
+	}
+	recipients := make([]string, len(students))
+	for i, id := range students {
+		recipients[i] = id.String()
+	}
+
+	actionType, actionData := assignmentAction(a)
+	err := s.notificationClient.SendBulk(client.BulkNotificationRequest{
+		UserIDs:    recipients,
+		Title:      "Bài tập mới",
+		Message:    fmt.Sprintf("Lớp %s có bài tập mới \"%s\", hạn nộp %s.", access.Classroom.Name, a.Title, a.DueAt.Format("15:04 02/01/2006")),
+		Type:       "course_update",
+		Category:   "info",
+		ActionType: &actionType,
+		ActionData: actionData,
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to notify learners of assignment %s: %v", a.ID, err)
+	}
+}
+
+// resultKey identifies a student's result on an assignment
+type resultKey struct {
+	assignmentID uuid.UUID
+	userID       uuid.UUID
+}
+
+// assignmentResults works out each student's result on each assignment.
+// Students the assignment is not set for get not_assigned.
+func (s *ExerciseService) assignmentResults(assignments []models.Assignment, studentIDs []uuid.UUID, now time.Time) (map[resultKey]models.AssignmentResult, error) {
+	exerciseIDs := []uuid.UUID{}
+	lessonIDs := []string{}
+	for _, a := range assignments {
+		if a.ItemType == "lesson" {
+			lessonIDs = append(lessonIDs, a.LessonID.String())
+		} else {
+			exerciseIDs = append(exerciseIDs, a.ID)
+		}
+	}
+
+	attempts := make(map[resultKey][]models.AssignmentAttempt)
+	if len(exerciseIDs) > 0 && len(studentIDs) > 0 {
+		var only *uuid.UUID
+		if len(studentIDs) == 1 {
+			only = &studentIDs[0]
+		}
+		rows, err := s.repo.GetAssignmentAttempts(exerciseIDs, only)
+		if err != nil {
+			return nil, err
+		}
+		for _, at := range rows {
+			key := resultKey{at.AssignmentID, at.UserID}
+			attempts[key] = append(attempts[key], at)
+		}
+	}
+
+	progress := make(map[[2]string]client.LessonProgressStatus)
+	if len(lessonIDs) > 0 && len(studentIDs) > 0 {
+		users := make([]string, len(studentIDs))
+		for i, id := range studentIDs {
+			users[i] = id.String()
+		}
+		batch, err := s.courseClient.GetLessonProgress(lessonIDs, users)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range batch.Progress {
+			progress[[2]string{p.LessonID, p.UserID}] = p
+		}
+	}
+
+	results := make(map[resultKey]models.AssignmentResult)
+	for i := range assignments {
+		a := &assignments[i]
+		for _, studentID := range studentIDs {
+			key := resultKey{a.ID, studentID}
+			switch {
+			case !a.AssignedTo(studentID):
+				results[key] = models.AssignmentResult{AssignmentID: a.ID, Status: "not_assigned"}
+			case a.ItemType == "lesson":
+				p, ok := progress[[2]string{a.LessonID.String(), studentID.String()}]
+				var pp *client.LessonProgressStatus
+				if ok {
+					pp = &p
+				}
+				results[key] = lessonResult(a, pp, now)
+			default:
+				results[key] = exerciseResult(a, attempts[key], now)
+			}
+		}
+	}
+	return results, nil
+}
+
+// exerciseResult grades a student on an exercise assignment by their best
+// completed attempt, after any late penalty
+func exerciseResult(a *models.Assignment, attempts []models.AssignmentAttempt, now time.Time) models.AssignmentResult {
+	r := models.AssignmentResult{AssignmentID: a.ID, AttemptsUsed: len(attempts)}
+
+	var best *models.AssignmentAttempt
+	var bestBand, bestScore float64
+	for i := range attempts {
+		at := &attempts[i]
+		if at.CompletedAt == nil {
+			continue
+		}
+		late := at.CompletedAt.After(a.DueAt)
+		band := penalized(at.BandScore, a, late, roundDownToHalfBand)
+		score := penalized(at.Score, a, late, roundToHundredths)
+		b, sc := valueOr(band), valueOr(score)
+		if best == nil || b > bestBand || (b == bestBand && sc > bestScore) {
+			best, bestBand, bestScore = at, b, sc
+			r.Late = late
+			r.BandScore = at.BandScore
+			r.Score = at.Score
+			r.FinalBandScore = band
+			r.FinalScore = score
+			r.CompletedAt = at.CompletedAt
+		}
+	}
+
+	switch {
+	case best != nil:
+		r.Status = "completed"
+	case now.After(a.DueAt):
+		r.Status = "missing"
+	case len(attempts) > 0:
+		r.Status = "in_progress"
+	default:
+		r.Status = "not_started"
+	}
+	return r
+}
+
+// lessonResult reports a student's state on a lesson assignment from their
+// lesson progress
+func lessonResult(a *models.Assignment, p *client.LessonProgressStatus, now time.Time) models.AssignmentResult {
+	r := models.AssignmentResult{AssignmentID: a.ID}
+	switch {
+	case p != nil && p.Status == "completed" && p.CompletedAt != nil:
+		r.Status = "completed"
+		r.Late = p.CompletedAt.After(a.DueAt)
+		r.CompletedAt = p.CompletedAt
+	case now.After(a.DueAt):
+		r.Status = "missing"
+	case p != nil && p.Status != "not_started":
+		r.Status = "in_progress"
+	default:
+		r.Status = "not_started"
+	}
+	return r
+}
+
+// penalized applies the assignment's late penalty to a late value
+func penalized(v *float64, a *models.Assignment, late bool, round func(float64) float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	out := *v
+	if late && a.LatePolicy == "penalty" {
+		out = round(out * float64(100-a.LatePenaltyPercent) / 100)
+	}
+	return &out
+}
+
+// roundDownToHalfBand rounds a band score down to the nearest half band
+func roundDownToHalfBand(v float64) float64 {
+	return math.Floor(v*2) / 2
+}
+
+func roundToHundredths(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func valueOr(v *float64) float64 {
+	if v == nil {
+		return -1
+	}
+	return *v
+}
+
+func formatScore(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// validateAssignment checks an assignment's dates, policy and learners
+// against the classroom roster
+func validateAssignment(a *models.Assignment, access *client.ClassroomAccess) error {
+	if !a.DueAt.After(a.OpenAt) {
+		return fmt.Errorf("%w: due_at must be after open_at", ErrInvalidAssignment)
+	}
+	if a.ItemType == "lesson" && a.MaxAttempts != nil {
+		return fmt.Errorf("%w: max_attempts only applies to exercises", ErrInvalidAssignment)
+	}
+	if a.LatePolicy != "penalty" {
+		a.LatePenaltyPercent = 0
+	}
+
+	roster := make(map[string]bool, len(access.Students))
+	for _, st := range access.Students {
+		roster[st.UserID] = true
+	}
+	for _, id := range a.StudentIDs {
+		if !roster[id.String()] {
+			return fmt.Errorf("%w: %s is not a student of this classroom", ErrInvalidAssignment, id)
+		}
+	}
+	return nil
+}
+
+// assignedStudents returns the students of the classroom the assignment is set for
+func assignedStudents(a *models.Assignment, access *client.ClassroomAccess) []uuid.UUID {
+	students := []uuid.UUID{}
+	for _, st := range access.Students {
+		id, err := uuid.Parse(st.UserID)
+		if err == nil && a.AssignedTo(id) {
+			students = append(students, id)
+		}
+	}
+	return students
+}
+
+// assignmentAction is where the app takes a learner who opens an assignment notification
+func assignmentAction(a *models.Assignment) (string, map[string]interface{}) {
+	if a.ItemType == "lesson" {
+		return "navigate_to_lesson", map[string]interface{}{
+			"course_id":     a.CourseID.String(),
+			"lesson_id":     a.LessonID.String(),
+			"assignment_id": a.ID.String(),
+		}
+	}
+	return "navigate_to_exercise", map[string]interface{}{
+		"exercise_id":   a.ExerciseID.String(),
+		"assignment_id": a.ID.String(),
+	}
+}
+
+func (s *ExerciseService) getAssignment(id uuid.UUID) (*models.Assignment, error) {
+	a, err := s.repo.GetAssignmentByID(id)
+	if err != nil {
+		if err.Error() == "assignment not found" {
+			return nil, ErrAssignmentNotFound
+		}
+		return nil, err
+	}
+	return a, nil
+}
+
+// classroomAccess looks up the user's role in the classroom in auth-service
+func (s *ExerciseService) classroomAccess(classroomID, userID uuid.UUID) (*client.ClassroomAccess, error) {
+	access, err := s.authClient.GetClassroomAccess(classroomID.String(), userID.String())
+	if err != nil {
+		if errors.Is(err, client.ErrClassroomNotFound) {
+			return nil, ErrClassroomNotFound
+		}
+		return nil, err
+	}
+	return access, nil
+}
+
+// teacherAccess is classroomAccess for actions only the classroom's
+// instructors and organization admins may take
+func (s *ExerciseService) teacherAccess(classroomID, userID uuid.UUID) (*client.ClassroomAccess, error) {
+	access, err := s.classroomAccess(classroomID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.CanTeach() {
+		if access.Role == "" {
+			return nil, ErrClassroomNotFound
+		}
+		return nil, ErrNotClassroomTeacher
+	}
+	return access, nil
+}
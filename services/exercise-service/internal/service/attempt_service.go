@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSubmissionNotFound   = errors.New("submission not found")
+	ErrAttemptExpired       = errors.New("time is up for this attempt")
+	ErrAttemptNotInProgress = errors.New("attempt is no longer in progress")
+)
+
+const (
+	// submissionGracePeriod is how long after a deadline answers are still
+	// accepted, for requests sent just before it
+	submissionGracePeriod = 30 * time.Second
+	// sweepInterval is how often expired attempts are auto-submitted
+	sweepInterval = time.Minute
+	// sweepBatchSize caps the attempts auto-submitted per run
+	sweepBatchSize = 50
+	// sweepClaimTimeout is after how long an unfinished auto-submit is retried
+	sweepClaimTimeout = 10 * time.Minute
+)
+
+// attemptExpired reports whether an attempt's deadline and grace period
+// have passed, or the sweeper has already submitted it
+func attemptExpired(submission *models.UserExerciseAttempt, now time.Time) bool {
+	if submission.AutoSubmittedAt != nil {
+		return true
+	}
+	return submission.DeadlineAt != nil && now.After(submission.DeadlineAt.Add(submissionGracePeriod))
+}
+
+// remainingSeconds returns the time left on a timed attempt, or nil
+func remainingSeconds(submission *models.UserExerciseAttempt, now time.Time) *int {
+	if submission.DeadlineAt == nil {
+		return nil
+	}
+	left := int(submission.DeadlineAt.Sub(now).Seconds())
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
+
+// elapsedTimeSpent caps a client-reported time spent at the time the server
+// has seen pass since the attempt started
+func elapsedTimeSpent(submission *models.UserExerciseAttempt, reported *int, now time.Time) *int {
+	if reported == nil {
+		return nil
+	}
+	end := now
+	if submission.DeadlineAt != nil && end.After(*submission.DeadlineAt) {
+		end = *submission.DeadlineAt
+	}
+	elapsed := int(end.Sub(submission.StartedAt).Seconds())
+	if *reported > elapsed {
+		log.Printf("[Exercise-Service] Submission %s reported %d seconds spent, only %d elapsed; using elapsed",
+			submission.ID, *reported, elapsed)
+		return &elapsed
+	}
+	return reported
+}
+
+// ownSubmission loads a submission and checks it belongs to the user
+func (s *ExerciseService) ownSubmission(userID, submissionID uuid.UUID) (*models.UserExerciseAttempt, error) {
+	submission, err := s.repo.GetSubmissionByID(submissionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSubmissionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if submission.UserID != userID {
+		return nil, ErrSubmissionNotFound
+	}
+	return submission, nil
+}
+
+// GetAttemptSession returns what a device needs to resume an attempt
+func (s *ExerciseService) GetAttemptSession(userID, submissionID uuid.UUID) (*models.AttemptSession, error) {
+	submission, err := s.ownSubmission(userID, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	draft, sectionDeadlines, err := s.repo.GetAttemptDraft(submissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.AttemptSession{
+		Submission:       submission,
+		ServerTime:       now,
+		RemainingSeconds: remainingSeconds(submission, now),
+		Draft:            draft,
+	}
+	for id, at := range sectionDeadlines {
+		session.SectionDeadlines = append(session.SectionDeadlines, models.SectionDeadline{
+			SectionID:  id,
+			DeadlineAt: at,
+			Closed:     now.After(at.Add(submissionGracePeriod)),
+		})
+	}
+	sort.Slice(session.SectionDeadlines, func(i, j int) bool {
+		return session.SectionDeadlines[i].DeadlineAt.Before(session.SectionDeadlines[j].DeadlineAt)
+	})
+	return session, nil
+}
+
+// SaveAttemptDraft autosaves answers without grading them. Answers to
+// sections whose time is up are left out.
+func (s *ExerciseService) SaveAttemptDraft(userID, submissionID uuid.UUID, req *models.SaveDraftRequest) (*models.AttemptSession, error) {
+	submission, err := s.ownSubmission(userID, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if submission.Status != "in_progress" {
+		return nil, ErrAttemptNotInProgress
+	}
+	if attemptExpired(submission, now) {
+		return nil, ErrAttemptExpired
+	}
+
+	_, sectionDeadlines, err := s.repo.GetAttemptDraft(submissionID)
+	if err != nil {
+		return nil, err
+	}
+	answers, err := s.openSectionAnswers(submission, sectionDeadlines, req.Answers, now)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.SaveAttemptDraft(submissionID, answers, req.WritingData, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAttemptNotInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &models.AttemptSession{
+		Submission:       submission,
+		ServerTime:       now,
+		RemainingSeconds: remainingSeconds(submission, now),
+		Draft:            &models.AttemptDraft{Answers: answers, WritingData: req.WritingData, SavedAt: &now},
+	}, nil
+}
+
+// openSectionAnswers drops answers to questions in sections whose deadline
+// and grace period have passed
+func (s *ExerciseService) openSectionAnswers(submission *models.UserExerciseAttempt, sectionDeadlines map[uuid.UUID]time.Time, answers []models.SubmitAnswerItem, now time.Time) ([]models.SubmitAnswerItem, error) {
+	closed := map[uuid.UUID]bool{}
+	for id, at := range sectionDeadlines {
+		if now.After(at.Add(submissionGracePeriod)) {
+			closed[id] = true
+		}
+	}
+	if len(closed) == 0 {
+		return answers, nil
+	}
+
+	questionSections, err := s.repo.GetQuestionSectionIDs(submission.ExerciseID)
+	if err != nil {
+		return nil, err
+	}
+	open := make([]models.SubmitAnswerItem, 0, len(answers))
+	for _, a := range answers {
+		if closed[questionSections[a.QuestionID]] {
+			log.Printf("[Exercise-Service] Ignoring late answer to question %s of submission %s: its section's time is up",
+				a.QuestionID, submission.ID)
+			continue
+		}
+		open = append(open, a)
+	}
+	return open, nil
+}
+
+// answersToGrade checks an attempt still accepts answers and returns the
+// submitted answers on top of its draft, leaving out late section answers
+func (s *ExerciseService) answersToGrade(submission *models.UserExerciseAttempt, submitted []models.SubmitAnswerItem, now time.Time) ([]models.SubmitAnswerItem, *models.AttemptDraft, error) {
+	if submission.Status != "in_progress" {
+		return nil, nil, ErrAttemptNotInProgress
+	}
+	if attemptExpired(submission, now) {
+		return nil, nil, ErrAttemptExpired
+	}
+	draft, sectionDeadlines, err := s.repo.GetAttemptDraft(submission.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	submitted, err = s.openSectionAnswers(submission, sectionDeadlines, submitted, now)
+	if err != nil {
+		return nil, nil, err
+	}
+	return mergeDraftAnswers(draft.Answers, submitted), draft, nil
+}
+
+// attemptClosed turns the repository's answer for an attempt that was
+// submitted or claimed by the sweeper in the meantime into
+// ErrAttemptNotInProgress
+func attemptClosed(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrAttemptNotInProgress
+	}
+	return err
+}
+
+// mergeDraftAnswers returns the submitted answers followed by the draft
+// answers to questions they leave out
+func mergeDraftAnswers(draft, submitted []models.SubmitAnswerItem) []models.SubmitAnswerItem {
+	answered := make(map[uuid.UUID]bool, len(submitted))
+	for _, a := range submitted {
+		answered[a.QuestionID] = true
+	}
+	merged := append([]models.SubmitAnswerItem{}, submitted...)
+	for _, a := range draft {
+		if !answered[a.QuestionID] {
+			merged = append(merged, a)
+		}
+	}
+	return merged
+}
+
+// StartAttemptSweeper auto-submits attempts whose time ran out
+func (s *ExerciseService) StartAttemptSweeper() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	log.Printf("⏱️ Started expired attempt sweeper (checking every %v)", sweepInterval)
+
+	for range ticker.C {
+		s.sweepExpiredAttempts()
+	}
+}
+
+// sweepExpiredAttempts submits the drafts of attempts past their deadline
+// and grace period
+func (s *ExerciseService) sweepExpiredAttempts() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in sweepExpiredAttempts: %v", r)
+		}
+	}()
+
+	now := time.Now()
+	ids, err := s.repo.GetExpiredAttempts(now.Add(-submissionGracePeriod), now.Add(-sweepClaimTimeout), sweepBatchSize)
+	if err != nil {
+		log.Printf("⚠️ Failed to get expired attempts: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		claimed, err := s.repo.ClaimExpiredAttempt(id, now, now.Add(-sweepClaimTimeout))
+		if err != nil {
+			log.Printf("⚠️ Failed to claim expired attempt %s: %v", id, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.autoSubmit(id); err != nil {
+			if errors.Is(err, ErrAttemptNotInProgress) {
+				// The learner submitted before the claim landed
+				log.Printf("⏱️ Expired attempt %s was submitted by the learner first", id)
+				continue
+			}
+			attemptsAutoSubmittedTotal.WithLabelValues("failure").Inc()
+			log.Printf("❌ Failed to auto-submit attempt %s: %v", id, err)
+			continue
+		}
+	}
+}
+
+// autoSubmit submits an expired attempt's draft as if the learner had,
+// abandoning attempts with nothing to grade
+func (s *ExerciseService) autoSubmit(submissionID uuid.UUID) error {
+	submission, err := s.repo.GetSubmissionByID(submissionID)
+	if err != nil {
+		return fmt.Errorf("get submission: %w", err)
+	}
+	exercise, err := s.repo.GetExerciseByIDSimple(submission.ExerciseID)
+	if err != nil {
+		return fmt.Errorf("get exercise: %w", err)
+	}
+	draft, _, err := s.repo.GetAttemptDraft(submissionID)
+	if err != nil {
+		return fmt.Errorf("get draft: %w", err)
+	}
+
+	ctx := context.Background()
+	switch {
+	case exercise.SkillType == "listening" || exercise.SkillType == "reading":
+		// The draft only fills questions the learner has no saved answer to
+		err = s.finishListeningReadingSubmission(ctx, submission, exercise, draft.Answers)
+	case exercise.SkillType == "writing" && draft.WritingData != nil && draft.WritingData.EssayText != "":
+		err = s.handleWritingSubmission(ctx, submission, exercise, &models.SubmitExerciseRequest{WritingData: draft.WritingData})
+	default:
+		if err := s.repo.AbandonAttempt(submissionID); err != nil {
+			return err
+		}
+		attemptsAutoSubmittedTotal.WithLabelValues("abandoned").Inc()
+		log.Printf("⏱️ Abandoned expired attempt %s: nothing to submit", submissionID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	attemptsAutoSubmittedTotal.WithLabelValues("submitted").Inc()
+	log.Printf("⏱️ Auto-submitted expired attempt %s", submissionID)
+	return nil
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -19,15 +21,19 @@ type ExerciseService struct {
 	notificationClient  *client.NotificationServiceClient
 	aiServiceClient     *aiClient.AIServiceClient // Phase 4: AI service client
 	storageServiceClient *aiClient.StorageServiceClient // For generating presigned URLs
+	authClient          *client.AuthServiceClient      // For the audit log and classroom access
+	courseClient        *client.CourseServiceClient    // For lessons set as assignments
 }
 
-func NewExerciseService(repo *repository.ExerciseRepository, userServiceClient *client.UserServiceClient, notificationClient *client.NotificationServiceClient, aiServiceClient *aiClient.AIServiceClient, storageServiceClient *aiClient.StorageServiceClient) *ExerciseService {
+func NewExerciseService(repo *repository.ExerciseRepository, userServiceClient *client.UserServiceClient, notificationClient *client.NotificationServiceClient, aiServiceClient *aiClient.AIServiceClient, storageServiceClient *aiClient.StorageServiceClient, authClient *client.AuthServiceClient, courseClient *client.CourseServiceClient) *ExerciseService {
 	return &ExerciseService{
 		repo:                repo,
 		userServiceClient:   userServiceClient,
 		notificationClient:  notificationClient,
 		aiServiceClient:     aiServiceClient,
 		storageServiceClient: storageServiceClient,
+		authClient:          authClient,
+		courseClient:        courseClient,
 	}
 }
 
@@ -49,33 +55,54 @@ func (s *ExerciseService) GetExerciseByID(id uuid.UUID) (*models.ExerciseDetailR
 	return s.repo.GetExerciseByID(id)
 }
 
-// StartExercise creates a new submission for user
-func (s *ExerciseService) StartExercise(userID, exerciseID uuid.UUID, deviceType *string) (*models.UserExerciseAttempt, error) {
+// StartExercise creates a new submission for user, optionally for a classroom assignment
+// A timed attempt still running is resumed instead, so restarting neither
+// resets the clock nor loses the work on another device.
+func (s *ExerciseService) StartExercise(userID, exerciseID uuid.UUID, deviceType *string, assignmentID *uuid.UUID) (*models.UserExerciseAttempt, error) {
+	running, err := s.repo.GetResumableAttempt(userID, exerciseID, assignmentID, time.Now().Add(-submissionGracePeriod))
+	if err != nil {
+		return nil, err
+	}
+	if running != nil {
+		return running, nil
+	}
+
+	if assignmentID != nil {
+		return s.startAssignmentAttempt(userID, exerciseID, *assignmentID, deviceType)
+	}
 	return s.repo.CreateSubmission(userID, exerciseID, deviceType)
 }
 
 // SubmitAnswers saves answers and grades the submission
-func (s *ExerciseService) SubmitAnswers(submissionID uuid.UUID, answers []models.SubmitAnswerItem, frontendTimeSpent *int) error {
-	// Save and grade answers
-	err := s.repo.SaveSubmissionAnswers(submissionID, answers)
+func (s *ExerciseService) SubmitAnswers(ctx context.Context, submissionID uuid.UUID, answers []models.SubmitAnswerItem, frontendTimeSpent *int) error {
+	submission, err := s.repo.GetSubmissionByID(submissionID)
 	if err != nil {
 		return err
 	}
-
-	// Complete submission and calculate final score (pass frontend time_spent)
-	err = s.repo.CompleteSubmissionWithTime(submissionID, frontendTimeSpent)
+	now := time.Now()
+	answers, _, err = s.answersToGrade(submission, answers, now)
 	if err != nil {
 		return err
 	}
+	frontendTimeSpent = elapsedTimeSpent(submission, frontendTimeSpent, now)
+
+	// Save and grade answers, then complete submission and calculate final
+	// score (pass frontend time_spent) in one go, so the sweeper cannot claim
+	// the attempt in between
+	err = s.repo.SubmitAttempt(submissionID, answers, frontendTimeSpent, false)
+	if err != nil {
+		return attemptClosed(err)
+	}
 
 	// Service-to-service integration: Update user stats and send notification
+	ctx = context.WithoutCancel(ctx)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
 				log.Printf("[Exercise-Service] PANIC in handleExerciseCompletion: %v", r)
 			}
 		}()
-		s.handleExerciseCompletion(submissionID)
+		s.handleExerciseCompletion(ctx, submissionID)
 	}()
 
 	return nil
@@ -89,28 +116,51 @@ func (s *ExerciseService) GetSubmissionResult(submissionID uuid.UUID) (*models.S
 	}
 	
 	// If submission has audio_url, convert it to API Gateway URL for frontend access
-	if result.Submission != nil && result.Submission.AudioURL != nil && *result.Submission.AudioURL != "" {
-		audioURL := *result.Submission.AudioURL
-		log.Printf("📎 [GetSubmissionResult] Audio URL from DB: %s", audioURL)
-		
-		// Extract object name from URL
-		// Format: http://minio:9000/ielts-audio/audio/user-id/file-id.ext
-		// Or: http://localhost:9000/ielts-audio/audio/user-id/file-id.ext
-		objectName := s.extractObjectNameFromURL(audioURL)
-		if objectName != "" {
-			// Convert to API Gateway URL: http://localhost:8080/api/v1/storage/audio/file/{object_name}
-			// This allows frontend to access audio through API Gateway proxy
-			apiGatewayURL := fmt.Sprintf("http://localhost:8080/api/v1/storage/audio/file/%s", objectName)
-			result.Submission.AudioURL = &apiGatewayURL
-			log.Printf("✅ [GetSubmissionResult] Converted to API Gateway URL: %s", apiGatewayURL)
-		} else {
-			log.Printf("⚠️ [GetSubmissionResult] Could not extract object name from URL: %s, keeping original", audioURL)
+	s.rewriteAudioURL(result.Submission)
+
+	// Examiner corrections, once published
+	if review, err := s.repo.GetReviewByAttemptID(submissionID); err == nil && review.Status == "published" {
+		comments, err := s.repo.ListReviewComments(review.ID)
+		if err != nil {
+			return nil, err
+		}
+		result.Review = &models.PublishedReview{
+			BandScore:      review.BandScore,
+			AIBandScore:    review.AIBandScore,
+			CriteriaScores: review.CriteriaScores(),
+			Feedback:       review.ReviewerFeedback,
+			PublishedAt:    review.PublishedAt,
+			Comments:       comments,
 		}
 	}
-	
+
 	return result, nil
 }
 
+// rewriteAudioURL points a submission's audio_url at the API Gateway for frontend access
+func (s *ExerciseService) rewriteAudioURL(submission *models.UserExerciseAttempt) {
+	if submission == nil || submission.AudioURL == nil || *submission.AudioURL == "" {
+		return
+	}
+
+	audioURL := *submission.AudioURL
+	log.Printf("📎 [rewriteAudioURL] Audio URL from DB: %s", audioURL)
+	
+	// Extract object name from URL
+	// Format: http://minio:9000/ielts-audio/audio/user-id/file-id.ext
+	// Or: http://localhost:9000/ielts-audio/audio/user-id/file-id.ext
+	objectName := s.extractObjectNameFromURL(audioURL)
+	if objectName != "" {
+		// Convert to API Gateway URL: http://localhost:8080/api/v1/storage/audio/file/{object_name}
+		// This allows frontend to access audio through API Gateway proxy
+		apiGatewayURL := fmt.Sprintf("http://localhost:8080/api/v1/storage/audio/file/%s", objectName)
+		submission.AudioURL = &apiGatewayURL
+		log.Printf("✅ [rewriteAudioURL] Converted to API Gateway URL: %s", apiGatewayURL)
+	} else {
+		log.Printf("⚠️ [rewriteAudioURL] Could not extract object name from URL: %s, keeping original", audioURL)
+	}
+}
+
 // extractObjectNameFromURL extracts object name from audio URL
 // Format: http://minio:9000/ielts-audio/audio/user-id/file-id.ext
 // Returns: audio/user-id/file-id.ext
@@ -169,8 +219,12 @@ func (s *ExerciseService) UpdateExercise(id uuid.UUID, req *models.UpdateExercis
 }
 
 // DeleteExercise soft deletes exercise (admin only)
-func (s *ExerciseService) DeleteExercise(id uuid.UUID) error {
-	return s.repo.DeleteExercise(id)
+func (s *ExerciseService) DeleteExercise(id, userID uuid.UUID) error {
+	if err := s.repo.DeleteExercise(id); err != nil {
+		return err
+	}
+	s.recordAudit(userID, "exercise_deleted", id)
+	return nil
 }
 
 // CheckOwnership verifies if user owns the exercise
@@ -216,7 +270,11 @@ func (s *ExerciseService) PublishExercise(exerciseID, userID uuid.UUID) error {
 	if err := s.repo.CheckExerciseOwnership(exerciseID, userID); err != nil {
 		return err
 	}
-	return s.repo.PublishExercise(exerciseID)
+	if err := s.repo.PublishExercise(exerciseID); err != nil {
+		return err
+	}
+	s.recordAudit(userID, "exercise_published", exerciseID)
+	return nil
 }
 
 // UnpublishExercise unpublishes an exercise
@@ -225,7 +283,30 @@ func (s *ExerciseService) UnpublishExercise(exerciseID, userID uuid.UUID) error
 	if err := s.repo.CheckExerciseOwnership(exerciseID, userID); err != nil {
 		return err
 	}
-	return s.repo.UnpublishExercise(exerciseID)
+	if err := s.repo.UnpublishExercise(exerciseID); err != nil {
+		return err
+	}
+	s.recordAudit(userID, "exercise_unpublished", exerciseID)
+	return nil
+}
+
+// recordAudit reports an admin action to the auth service audit log without
+// holding up the request
+func (s *ExerciseService) recordAudit(userID uuid.UUID, eventType string, exerciseID uuid.UUID) {
+	go func() {
+		err := s.authClient.RecordAuditEvent(client.AuditEventRequest{
+			UserID:      userID.String(),
+			EventType:   eventType,
+			EventStatus: "success",
+			Metadata: map[string]interface{}{
+				"service":     "exercise-service",
+				"exercise_id": exerciseID.String(),
+			},
+		})
+		if err != nil {
+			log.Printf("[Exercise-Service] WARNING: Failed to record %s audit event: %v", eventType, err)
+		}
+	}()
 }
 
 // GetAllTags returns all available tags
@@ -294,7 +375,7 @@ func (s *ExerciseService) GetExerciseAnalytics(exerciseID uuid.UUID) (*models.Ex
 }
 
 // handleExerciseCompletion handles service-to-service integration when exercise is completed
-func (s *ExerciseService) handleExerciseCompletion(submissionID uuid.UUID) {
+func (s *ExerciseService) handleExerciseCompletion(ctx context.Context, submissionID uuid.UUID) {
 	log.Printf("[Exercise-Service] Handling exercise completion for submission %s", submissionID)
 
 	// Get submission result (includes both submission and exercise)
@@ -317,7 +398,7 @@ func (s *ExerciseService) handleExerciseCompletion(submissionID uuid.UUID) {
 
 	// Use time_spent_seconds from submission (already calculated and stored)
 	// This represents ACTIVE study time, not just elapsed time
-	// TimeSpentSeconds is set by CompleteSubmissionWithTime or MarkSubmissionAsSubmittedWithTime
+	// TimeSpentSeconds is set by SubmitAttempt or MarkSubmissionAsSubmittedWithTime
 	timeMinutes := 0
 	if submission.TimeSpentSeconds > 0 {
 		timeMinutes = submission.TimeSpentSeconds / 60
@@ -330,6 +411,9 @@ func (s *ExerciseService) handleExerciseCompletion(submissionID uuid.UUID) {
 		log.Printf("[Exercise-Service] ⚠️  No time_spent, using elapsed: %d minutes", timeMinutes)
 	}
 
+	userServiceClient := s.userServiceClient.WithContext(ctx)
+	notificationClient := s.notificationClient.WithContext(ctx)
+
 	// FIX #15: Add retry mechanism for service integration
 	maxRetries := 3
 	retryDelay := time.Second
@@ -342,7 +426,7 @@ func (s *ExerciseService) handleExerciseCompletion(submissionID uuid.UUID) {
 		log.Printf("[Exercise-Service] Updating skill statistics in User Service (bandScore: %.1f, skill: %s)...", bandScore, skillType)
 		var lastErr error
 		for attempt := 1; attempt <= maxRetries; attempt++ {
-			err = s.userServiceClient.UpdateSkillStatistics(client.UpdateSkillStatsRequest{
+			err = userServiceClient.UpdateSkillStatistics(client.UpdateSkillStatsRequest{
 				UserID:         submission.UserID.String(),
 				SkillType:      skillType,
 				Score:          bandScore, // Send band score (0-9) instead of percentage
@@ -373,7 +457,7 @@ func (s *ExerciseService) handleExerciseCompletion(submissionID uuid.UUID) {
 	retryDelay = time.Second // Reset delay
 	var progressErr error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		err = s.userServiceClient.UpdateProgress(client.UpdateProgressRequest{
+		err = userServiceClient.UpdateProgress(client.UpdateProgressRequest{
 			UserID:            submission.UserID.String(),
 			ExercisesComplete: 1,
 			StudyMinutes:      timeMinutes,
@@ -402,7 +486,7 @@ func (s *ExerciseService) handleExerciseCompletion(submissionID uuid.UUID) {
 	retryDelay = time.Second // Reset delay
 	var notificationErr error
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		err = s.notificationClient.SendExerciseResultNotification(
+		err = notificationClient.SendExerciseResultNotification(
 			submission.UserID.String(),
 			exercise.Title,
 			bandScore, // Send band score (0-9) instead of percentage
@@ -433,6 +517,7 @@ func (s *ExerciseService) StartSyncRetryWorker() {
 
 	for range ticker.C {
 		s.retryFailedSyncs()
+		s.retryReviewCorrections()
 	}
 }
 
@@ -444,6 +529,10 @@ func (s *ExerciseService) retryFailedSyncs() {
 		}
 	}()
 
+	if count, err := s.repo.CountPendingSyncs(); err == nil {
+		pendingSyncs.Set(float64(count))
+	}
+
 	// Get pending syncs (limit 50 per batch)
 	submissions, err := s.repo.GetPendingSyncs(50)
 	if err != nil {
@@ -478,9 +567,11 @@ func (s *ExerciseService) retryFailedSyncs() {
 		err = s.retrySingleSync(submission, exercise, bandScore)
 		if err != nil {
 			failCount++
+			syncRetriesTotal.WithLabelValues("failure").Inc()
 			log.Printf("❌ Retry failed for submission %s: %v", submission.ID, err)
 		} else {
 			successCount++
+			syncRetriesTotal.WithLabelValues("success").Inc()
 			log.Printf("✅ Retry succeeded for submission %s", submission.ID)
 		}
 	}
@@ -536,3 +627,17 @@ func (s *ExerciseService) retrySingleSync(
 		return nil
 	}
 }
+
+// ExportUserData returns everything stored about the user, for the personal data export
+func (s *ExerciseService) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return s.repo.ExportUserData(ctx, userID)
+}
+
+// EraseUserData deletes everything stored about the user when their account is deleted
+func (s *ExerciseService) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.EraseUserData(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("✅ Erased exercise data of user %s", userID)
+	return nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	pendingSyncs = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "exercise_user_sync_pending",
+		Help: "Completed attempts still waiting to be synced to the User Service, as of the last retry run.",
+	})
+
+	syncRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "exercise_user_sync_retries_total",
+		Help: "Background User Service sync retries, by result (success, failure).",
+	}, []string{"result"})
+
+	attemptsAutoSubmittedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "exercise_attempts_auto_submitted_total",
+		Help: "Expired attempts closed by the sweeper, by result (submitted, abandoned, failure).",
+	}, []string{"result"})
+
+	mockTestSessionsSettledTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "exercise_mock_test_sessions_settled_total",
+		Help: "Mock test sittings given a final status, by status (completed, incomplete).",
+	}, []string{"status"})
+
+	adaptiveSessionsCompletedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "exercise_adaptive_sessions_completed_total",
+		Help: "Adaptive practice sessions finished, by why they stopped (converged, max_items, bank_exhausted).",
+	}, []string{"stop_reason"})
+
+	itemCalibrationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "exercise_item_calibrations_total",
+		Help: "Bank question calibrations, by result (calibrated, not_converged, error).",
+	}, []string{"result"})
+)
@@ -0,0 +1,595 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMockTestNotFound         = errors.New("mock test not found")
+	ErrInvalidMockTest          = errors.New("invalid mock test")
+	ErrMockTestSessionNotFound  = errors.New("mock test session not found")
+	ErrMockTestStageNotFinished = errors.New("the current stage is still in progress")
+	ErrMockTestOnBreak          = errors.New("the break before the next stage is not over")
+	ErrMockTestNoStagesLeft     = errors.New("every stage of this mock test has been started")
+)
+
+const (
+	// defaultSpeakingBreakMinutes is the break before Speaking when a mock
+	// test does not set one
+	defaultSpeakingBreakMinutes = 10
+	// mockTestSettleInterval is how often sittings waiting on results are checked
+	mockTestSettleInterval = time.Minute
+	// mockTestSettleBatchSize caps the sittings checked per run
+	mockTestSettleBatchSize = 50
+	// mockTestMaxSyncAttempts is how often recording a result in user-service
+	// is tried before it is left for an operator
+	mockTestMaxSyncAttempts = 10
+)
+
+// mockTestStage is a skill of a mock test with its official time
+type mockTestStage struct {
+	skill    string
+	duration time.Duration
+}
+
+// mockTestStages are the stages of a sitting in order. Listening is 30
+// minutes of recording and 2 to check answers, as on computer-delivered
+// IELTS. Listening, Reading and Writing run back to back; Speaking follows
+// after the mock test's break.
+var mockTestStages = []mockTestStage{
+	{skill: "listening", duration: 32 * time.Minute},
+	{skill: "reading", duration: 60 * time.Minute},
+	{skill: "writing", duration: 60 * time.Minute},
+	{skill: "speaking", duration: 14 * time.Minute},
+}
+
+// breakBefore returns the break learners take before a stage
+func breakBefore(m *models.MockTest, stage int) time.Duration {
+	if mockTestStages[stage].skill == "speaking" {
+		return time.Duration(m.SpeakingBreakMinutes) * time.Minute
+	}
+	return 0
+}
+
+// stageEnd returns when a stage's attempt ended: when it was submitted, or
+// its deadline once that has passed. ok is false while it is still running.
+func stageEnd(a *models.UserExerciseAttempt, now time.Time) (end time.Time, ok bool) {
+	switch {
+	case a.Status == "in_progress":
+		if a.DeadlineAt != nil && attemptExpired(a, now) {
+			return *a.DeadlineAt, true
+		}
+		return time.Time{}, false
+	case a.CompletedAt != nil:
+		return *a.CompletedAt, true
+	default:
+		return a.UpdatedAt, true
+	}
+}
+
+// CreateMockTest puts an exercise of each skill together as a mock test
+func (s *ExerciseService) CreateMockTest(userID uuid.UUID, req *models.CreateMockTestRequest) (*models.MockTest, error) {
+	m := &models.MockTest{
+		Title:                req.Title,
+		Description:          req.Description,
+		IELTSTestType:        req.IELTSTestType,
+		ListeningExerciseID:  req.ListeningExerciseID,
+		ReadingExerciseID:    req.ReadingExerciseID,
+		WritingExerciseID:    req.WritingExerciseID,
+		SpeakingExerciseID:   req.SpeakingExerciseID,
+		SpeakingBreakMinutes: defaultSpeakingBreakMinutes,
+		IsPublished:          req.IsPublished,
+		CreatedBy:            userID,
+	}
+	if req.SpeakingBreakMinutes != nil {
+		m.SpeakingBreakMinutes = *req.SpeakingBreakMinutes
+	}
+	if err := s.validateMockTest(m); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateMockTest(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// UpdateMockTest changes a mock test
+func (s *ExerciseService) UpdateMockTest(mockTestID uuid.UUID, req *models.UpdateMockTestRequest) (*models.MockTest, error) {
+	m, err := s.getMockTest(mockTestID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Title != nil {
+		m.Title = *req.Title
+	}
+	if req.Description != nil {
+		m.Description = req.Description
+	}
+	if req.IELTSTestType != nil {
+		m.IELTSTestType = *req.IELTSTestType
+	}
+	if req.ListeningExerciseID != nil {
+		m.ListeningExerciseID = *req.ListeningExerciseID
+	}
+	if req.ReadingExerciseID != nil {
+		m.ReadingExerciseID = *req.ReadingExerciseID
+	}
+	if req.WritingExerciseID != nil {
+		m.WritingExerciseID = *req.WritingExerciseID
+	}
+	if req.SpeakingExerciseID != nil {
+		m.SpeakingExerciseID = *req.SpeakingExerciseID
+	}
+	if req.SpeakingBreakMinutes != nil {
+		m.SpeakingBreakMinutes = *req.SpeakingBreakMinutes
+	}
+	if req.IsPublished != nil {
+		m.IsPublished = *req.IsPublished
+	}
+	if err := s.validateMockTest(m); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateMockTest(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ListMockTests returns the mock tests, only the published ones for learners
+func (s *ExerciseService) ListMockTests(publishedOnly bool) ([]models.MockTest, error) {
+	return s.repo.ListMockTests(publishedOnly)
+}
+
+// GetMockTest returns a mock test; unpublished ones are only found for staff
+func (s *ExerciseService) GetMockTest(mockTestID uuid.UUID, publishedOnly bool) (*models.MockTest, error) {
+	m, err := s.getMockTest(mockTestID)
+	if err != nil {
+		return nil, err
+	}
+	if publishedOnly && !m.IsPublished {
+		return nil, ErrMockTestNotFound
+	}
+	return m, nil
+}
+
+// StartMockTest starts a sitting of a published mock test at its Listening
+// stage, or returns the learner's sitting of it that is still in progress
+func (s *ExerciseService) StartMockTest(userID, mockTestID uuid.UUID) (*models.MockTestSession, error) {
+	m, err := s.GetMockTest(mockTestID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.repo.GetActiveMockTestSession(userID, mockTestID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		stage := mockTestStages[0]
+		session, _, err = s.repo.CreateMockTestSession(userID, m, int(stage.duration.Minutes()), time.Now().Add(stage.duration))
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("📝 User %s started mock test %s (session %s)", userID, mockTestID, session.ID)
+	}
+
+	attempts, err := s.repo.GetMockTestStageAttempts(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return mockTestSessionView(session, m, attempts, time.Now()), nil
+}
+
+// GetMockTestSession returns one of the learner's sittings with its stages,
+// settling its result first if the last results have come in
+func (s *ExerciseService) GetMockTestSession(userID, sessionID uuid.UUID) (*models.MockTestSession, error) {
+	session, err := s.ownMockTestSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	m, attempts, err := s.loadMockTestSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.settleMockTestSession(session, m, attempts, now); err != nil {
+		log.Printf("⚠️ Failed to settle mock test session %s: %v", session.ID, err)
+	}
+	return mockTestSessionView(session, m, attempts, now), nil
+}
+
+// ListMyMockTestSessions returns the learner's sittings, latest first
+func (s *ExerciseService) ListMyMockTestSessions(userID uuid.UUID) ([]models.MockTestSession, error) {
+	sessions, err := s.repo.GetUserMockTestSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	titles := map[uuid.UUID]string{}
+	for i := range sessions {
+		id := sessions[i].MockTestID
+		if _, ok := titles[id]; !ok {
+			if m, err := s.repo.GetMockTestByID(id); err == nil {
+				titles[id] = m.Title
+			}
+		}
+		sessions[i].Title = titles[id]
+	}
+	return sessions, nil
+}
+
+// StartNextMockTestStage starts the next stage of a sitting once the current
+// one has been submitted or timed out and any break before it is over. The
+// stage gets its full official time from when it starts.
+func (s *ExerciseService) StartNextMockTestStage(userID, sessionID uuid.UUID) (*models.MockTestSession, error) {
+	session, err := s.ownMockTestSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	m, attempts, err := s.loadMockTestSession(session)
+	if err != nil {
+		return nil, err
+	}
+
+	next := len(attempts)
+	if session.Status != "in_progress" || next >= len(mockTestStages) {
+		return nil, ErrMockTestNoStagesLeft
+	}
+	now := time.Now()
+	end, ok := stageEnd(attempts[next-1], now)
+	if !ok {
+		return nil, ErrMockTestStageNotFinished
+	}
+	if now.Before(end.Add(breakBefore(m, next))) {
+		return nil, ErrMockTestOnBreak
+	}
+
+	stage := mockTestStages[next]
+	submission, err := s.repo.StartMockTestStage(session, next, m.ExerciseIDs()[next], int(stage.duration.Minutes()), now.Add(stage.duration))
+	if err != nil {
+		return nil, err
+	}
+	if submission != nil {
+		log.Printf("📝 Mock test session %s moved on to %s", session.ID, stage.skill)
+	}
+
+	// Reload: another request may have started the stage first
+	if session, err = s.repo.GetMockTestSession(sessionID); err != nil {
+		return nil, err
+	}
+	if attempts, err = s.repo.GetMockTestStageAttempts(sessionID); err != nil {
+		return nil, err
+	}
+	return mockTestSessionView(session, m, attempts, now), nil
+}
+
+// StartMockTestWorker settles sittings whose last results have come in and
+// records them in user-service, for results that arrived while no request
+// looked at the sitting and for recordings that failed
+func (s *ExerciseService) StartMockTestWorker() {
+	ticker := time.NewTicker(mockTestSettleInterval)
+	defer ticker.Stop()
+
+	log.Println("📝 Started mock test result worker (checking every minute)")
+
+	for range ticker.C {
+		s.settleMockTestSessions()
+	}
+}
+
+// settleMockTestSessions settles a batch of sittings waiting on results
+func (s *ExerciseService) settleMockTestSessions() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in settleMockTestSessions: %v", r)
+		}
+	}()
+
+	ids, err := s.repo.GetMockTestSessionsToSettle(mockTestMaxSyncAttempts, mockTestSettleBatchSize)
+	if err != nil {
+		log.Printf("⚠️ Failed to get mock test sessions to settle: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if err := s.settleMockTestSessionByID(id); err != nil {
+			log.Printf("⚠️ Failed to settle mock test session %s: %v", id, err)
+		}
+	}
+}
+
+// settleMockTestSessionByID settles a sitting and records a completed one in
+// user-service if it is not there yet
+func (s *ExerciseService) settleMockTestSessionByID(sessionID uuid.UUID) error {
+	session, err := s.repo.GetMockTestSession(sessionID)
+	if err != nil {
+		return err
+	}
+	m, attempts, err := s.loadMockTestSession(session)
+	if err != nil {
+		return err
+	}
+	if err := s.settleMockTestSession(session, m, attempts, time.Now()); err != nil {
+		return err
+	}
+	if session.Status == "completed" && session.UserServiceSyncedAt == nil {
+		return s.recordMockTestResult(session, m, attempts)
+	}
+	return nil
+}
+
+// settleMockTestSession works out a sitting's status from its attempts once
+// the last stage has been taken. It waits while an attempt is running or
+// its writing or speaking evaluation is, and is incomplete when an attempt
+// was abandoned or could not be evaluated. A completed sitting gets its
+// bands, overall band and score report, kept up to date with examiner
+// reviews.
+func (s *ExerciseService) settleMockTestSession(session *models.MockTestSession, m *models.MockTest, attempts []*models.UserExerciseAttempt, now time.Time) error {
+	if session.Status == "incomplete" || len(attempts) < len(mockTestStages) {
+		return nil
+	}
+
+	waiting := false
+	for _, a := range attempts {
+		if a.Status == "in_progress" {
+			return nil
+		}
+		evaluationFailed := a.EvaluationStatus != nil && *a.EvaluationStatus == "failed"
+		if a.Status == "abandoned" || (a.BandScore == nil && evaluationFailed) {
+			session.Status = "incomplete"
+			mockTestSessionsSettledTotal.WithLabelValues("incomplete").Inc()
+			log.Printf("⚠️ Mock test session %s is incomplete: %s attempt %s has no result", session.ID, a.Status, a.ID)
+			return s.repo.UpdateMockTestSessionResult(session)
+		}
+		if a.BandScore == nil {
+			waiting = true
+		}
+	}
+	if waiting {
+		if session.Status == "awaiting_results" {
+			return nil
+		}
+		session.Status = "awaiting_results"
+		return s.repo.UpdateMockTestSessionResult(session)
+	}
+
+	report, err := s.mockTestScoreReport(session, m, attempts)
+	if err != nil {
+		return err
+	}
+	bands := make([]*float64, len(attempts))
+	for i, a := range attempts {
+		band := *a.BandScore
+		bands[i] = &band
+	}
+	if session.Status == "completed" && session.OverallBand != nil && *session.OverallBand == report.OverallBand &&
+		sameBand(session.ListeningBand, bands[0]) && sameBand(session.ReadingBand, bands[1]) &&
+		sameBand(session.WritingBand, bands[2]) && sameBand(session.SpeakingBand, bands[3]) {
+		return nil
+	}
+
+	if session.Status != "completed" {
+		mockTestSessionsSettledTotal.WithLabelValues("completed").Inc()
+	}
+	session.Status = "completed"
+	session.ListeningBand, session.ReadingBand, session.WritingBand, session.SpeakingBand = bands[0], bands[1], bands[2], bands[3]
+	session.OverallBand = &report.OverallBand
+	session.ScoreReport = report
+	if session.CompletedAt == nil {
+		session.CompletedAt = &now
+	}
+	log.Printf("✅ Mock test session %s completed: overall band %.1f", session.ID, report.OverallBand)
+	return s.repo.UpdateMockTestSessionResult(session)
+}
+
+func sameBand(a, b *float64) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+// mockTestScoreReport builds the combined report of a sitting whose attempts
+// all have bands
+func (s *ExerciseService) mockTestScoreReport(session *models.MockTestSession, m *models.MockTest, attempts []*models.UserExerciseAttempt) (*models.MockTestScoreReport, error) {
+	report := &models.MockTestScoreReport{
+		MockTestID:    m.ID,
+		Title:         m.Title,
+		IELTSTestType: m.IELTSTestType,
+		TestDate:      session.StartedAt,
+	}
+	for i, a := range attempts {
+		skill := mockTestStages[i].skill
+		line := models.MockTestSkillResult{
+			Skill:            skill,
+			ExerciseID:       a.ExerciseID,
+			AttemptID:        a.ID,
+			BandScore:        *a.BandScore,
+			TimeSpentSeconds: a.TimeSpentSeconds,
+			AutoSubmitted:    a.AutoSubmittedAt != nil,
+		}
+		exercise, err := s.repo.GetExerciseByIDSimple(a.ExerciseID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get %s exercise: %w", skill, err)
+		}
+		line.ExerciseTitle = exercise.Title
+
+		switch skill {
+		case "listening", "reading":
+			correct, total := a.CorrectAnswers, a.TotalQuestions
+			line.RawScore, line.TotalQuestions = &correct, &total
+		default:
+			line.CriteriaScores = criteriaScores(skill, a.DetailedScores)
+		}
+		report.Skills = append(report.Skills, line)
+	}
+	report.OverallBand = ielts.CalculateOverallBand(
+		report.Skills[0].BandScore, report.Skills[1].BandScore, report.Skills[2].BandScore, report.Skills[3].BandScore)
+	return report, nil
+}
+
+// criteriaScores picks the marking criteria out of an attempt's detailed scores
+func criteriaScores(skill string, detailed *string) map[string]float64 {
+	if detailed == nil {
+		return nil
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal([]byte(*detailed), &all); err != nil {
+		return nil
+	}
+	scores := map[string]float64{}
+	for _, criterion := range reviewCriteria[skill] {
+		if v, ok := all[criterion].(float64); ok {
+			scores[criterion] = v
+		}
+	}
+	return scores
+}
+
+// recordMockTestResult records a completed sitting in user-service as one
+// result set, with a test result per skill from its attempts. User-service
+// ignores a set it already has, so failures are simply retried.
+func (s *ExerciseService) recordMockTestResult(session *models.MockTestSession, m *models.MockTest, attempts []*models.UserExerciseAttempt) error {
+	req := client.RecordTestResultSetRequest{
+		TestType:      "mock_test",
+		IELTSVariant:  m.IELTSTestType,
+		SourceService: "exercise_service",
+		SourceID:      session.ID.String(),
+		TestDate:      &session.StartedAt,
+		ScoreReport:   session.ScoreReport,
+	}
+	for i, a := range attempts {
+		attemptID := a.ID.String()
+		result := client.RecordTestResultRequest{
+			SkillType:   mockTestStages[i].skill,
+			BandScore:   *a.BandScore,
+			SourceTable: "user_exercise_attempts",
+			SourceID:    &attemptID,
+			TestSource:  "platform",
+		}
+		if result.SkillType == "listening" || result.SkillType == "reading" {
+			correct, total := a.CorrectAnswers, a.TotalQuestions
+			result.RawScore, result.TotalQuestions = &correct, &total
+		}
+		req.Results = append(req.Results, result)
+	}
+
+	if err := s.userServiceClient.RecordTestResultSet(session.UserID.String(), req); err != nil {
+		if markErr := s.repo.MarkMockTestSessionSyncFailed(session.ID, err.Error()); markErr != nil {
+			log.Printf("⚠️ Failed to mark sync failure of mock test session %s: %v", session.ID, markErr)
+		}
+		return fmt.Errorf("record mock test result: %w", err)
+	}
+	if err := s.repo.MarkMockTestSessionSynced(session.ID); err != nil {
+		return err
+	}
+	now := time.Now()
+	session.UserServiceSyncedAt = &now
+	log.Printf("✅ Recorded mock test session %s in user-service", session.ID)
+	return nil
+}
+
+// mockTestSessionView fills in a sitting's title, stages and when the next
+// stage can start
+func mockTestSessionView(session *models.MockTestSession, m *models.MockTest, attempts []*models.UserExerciseAttempt, now time.Time) *models.MockTestSession {
+	session.Title = m.Title
+	session.Stages = make([]models.MockTestStage, len(mockTestStages))
+	exerciseIDs := m.ExerciseIDs()
+	for i, st := range mockTestStages {
+		stage := models.MockTestStage{
+			Stage:              i,
+			Skill:              st.skill,
+			ExerciseID:         exerciseIDs[i],
+			DurationMinutes:    int(st.duration.Minutes()),
+			BreakBeforeMinutes: int(breakBefore(m, i).Minutes()),
+			Status:             "not_started",
+		}
+		if i < len(attempts) {
+			a := attempts[i]
+			stage.ExerciseID = a.ExerciseID
+			stage.AttemptID = &a.ID
+			stage.Status = a.Status
+			stage.StartedAt = &a.StartedAt
+			stage.DeadlineAt = a.DeadlineAt
+			stage.CompletedAt = a.CompletedAt
+			stage.BandScore = a.BandScore
+			if st.skill == "writing" || st.skill == "speaking" {
+				stage.EvaluationStatus = a.EvaluationStatus
+			}
+		}
+		session.Stages[i] = stage
+	}
+
+	next := len(attempts)
+	if session.Status == "in_progress" && next > 0 && next < len(mockTestStages) {
+		if end, ok := stageEnd(attempts[next-1], now); ok {
+			at := end.Add(breakBefore(m, next))
+			session.NextStageAvailableAt = &at
+		}
+	}
+	return session
+}
+
+// loadMockTestSession loads a sitting's mock test and stage attempts
+func (s *ExerciseService) loadMockTestSession(session *models.MockTestSession) (*models.MockTest, []*models.UserExerciseAttempt, error) {
+	m, err := s.repo.GetMockTestByID(session.MockTestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	attempts, err := s.repo.GetMockTestStageAttempts(session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, attempts, nil
+}
+
+// ownMockTestSession loads a sitting and checks it belongs to the user
+func (s *ExerciseService) ownMockTestSession(userID, sessionID uuid.UUID) (*models.MockTestSession, error) {
+	session, err := s.repo.GetMockTestSession(sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMockTestSessionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrMockTestSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *ExerciseService) getMockTest(id uuid.UUID) (*models.MockTest, error) {
+	m, err := s.repo.GetMockTestByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrMockTestNotFound
+	}
+	return m, err
+}
+
+// validateMockTest checks each stage's exercise exists and is of its skill,
+// and the reading exercise is of the mock test's IELTS test type
+func (s *ExerciseService) validateMockTest(m *models.MockTest) error {
+	for i, exerciseID := range m.ExerciseIDs() {
+		skill := mockTestStages[i].skill
+		exercise, err := s.repo.GetExerciseByIDSimple(exerciseID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s exercise not found", ErrInvalidMockTest, skill)
+		}
+		if err != nil {
+			return err
+		}
+		if exercise.SkillType != skill {
+			return fmt.Errorf("%w: the %s exercise is a %s exercise", ErrInvalidMockTest, skill, exercise.SkillType)
+		}
+		if skill == "reading" && (exercise.IELTSTestType == nil || *exercise.IELTSTestType != m.IELTSTestType) {
+			return fmt.Errorf("%w: the reading exercise is not a %s test", ErrInvalidMockTest, m.IELTSTestType)
+		}
+	}
+	return nil
+}
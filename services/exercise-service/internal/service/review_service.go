@@ -0,0 +1,383 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrReviewNotFound       = errors.New("review not found")
+	ErrReviewClaimed        = errors.New("review is claimed by another reviewer")
+	ErrReviewNotClaimed     = errors.New("claim the review before changing it")
+	ErrReviewPublished      = errors.New("review is already published")
+	ErrInvalidReview        = errors.New("invalid review")
+	ErrReviewCommentMissing = errors.New("review comment not found")
+)
+
+// reviewClaimTimeout is how long a claim holds before another reviewer can take the review over
+const reviewClaimTimeout = 24 * time.Hour
+
+// reviewCriteria are the criteria a reviewer can override, by skill, in the
+// order the band calculation takes them
+var reviewCriteria = map[string][]string{
+	"writing":  {"task_achievement", "coherence_cohesion", "lexical_resource", "grammar_accuracy"},
+	"speaking": {"fluency", "lexical_resource", "grammar", "pronunciation"},
+}
+
+// queueReview puts an AI-evaluated writing or speaking attempt in the examiner review queue
+func (s *ExerciseService) queueReview(submissionID uuid.UUID, skillType string, bandScore float64, criteria map[string]float64) {
+	if err := s.repo.CreateReview(submissionID, skillType, bandScore, criteria); err != nil {
+		log.Printf("⚠️ Failed to queue submission %s for review: %v", submissionID, err)
+	}
+}
+
+// ListReviewQueue returns a page of reviews, queued ones by default
+func (s *ExerciseService) ListReviewQueue(query *models.ReviewQueueQuery, reviewerID uuid.UUID) ([]models.ReviewQueueItem, int, error) {
+	if query.Status == "" {
+		query.Status = "queued"
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 20
+	}
+	return s.repo.ListReviews(query, reviewerID)
+}
+
+// GetReviewDetail returns a review with the submission, exercise and comments
+func (s *ExerciseService) GetReviewDetail(reviewID uuid.UUID) (*models.ReviewDetail, error) {
+	review, err := s.getReview(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	submission, err := s.repo.GetSubmissionByID(review.AttemptID)
+	if err != nil {
+		return nil, err
+	}
+	exercise, err := s.repo.GetExerciseByIDSimple(submission.ExerciseID)
+	if err != nil {
+		return nil, err
+	}
+	comments, err := s.repo.ListReviewComments(review.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.rewriteAudioURL(submission)
+
+	return &models.ReviewDetail{
+		Review:         review,
+		CriteriaScores: review.CriteriaScores(),
+		Submission:     submission,
+		Exercise:       exercise,
+		Comments:       comments,
+	}, nil
+}
+
+// ClaimReview assigns a queued review to the reviewer. A claim left idle for
+// a day can be taken over.
+func (s *ExerciseService) ClaimReview(reviewID, reviewerID uuid.UUID) (*models.SubmissionReview, error) {
+	review, err := s.getReview(reviewID)
+	if err != nil {
+		return nil, err
+	}
+	if review.Status == "published" {
+		return nil, ErrReviewPublished
+	}
+
+	claimed, err := s.repo.ClaimReview(reviewID, reviewerID, time.Now().Add(-reviewClaimTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, ErrReviewClaimed
+	}
+	return s.getReview(reviewID)
+}
+
+// ReleaseReview returns a claimed review to the queue
+func (s *ExerciseService) ReleaseReview(reviewID, reviewerID uuid.UUID) error {
+	if _, err := s.claimedReview(reviewID, reviewerID); err != nil {
+		return err
+	}
+	released, err := s.repo.ReleaseReview(reviewID, reviewerID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return ErrReviewNotClaimed
+	}
+	return nil
+}
+
+// UpdateReviewScores overrides criteria scores and recalculates the band
+// with the IELTS formula for the skill
+func (s *ExerciseService) UpdateReviewScores(reviewID, reviewerID uuid.UUID, req *models.UpdateReviewScoresRequest) (*models.SubmissionReview, error) {
+	review, err := s.claimedReview(reviewID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := reviewCriteria[review.SkillType]
+	for criterion, score := range req.CriteriaScores {
+		if !containsString(allowed, criterion) {
+			return nil, fmt.Errorf("%w: %s is not a %s criterion", ErrInvalidReview, criterion, review.SkillType)
+		}
+		if score < 0 || score > 9 || math.Mod(score*2, 1) != 0 {
+			return nil, fmt.Errorf("%w: %s must be a band from 0 to 9 in steps of 0.5", ErrInvalidReview, criterion)
+		}
+		if ai, ok := review.AICriteriaScores[criterion]; ok && ai == score {
+			delete(review.CriteriaOverrides, criterion)
+		} else {
+			review.CriteriaOverrides[criterion] = score
+		}
+	}
+	if req.Feedback != nil {
+		review.ReviewerFeedback = req.Feedback
+	}
+
+	band := recalculateBand(review.SkillType, review.CriteriaScores())
+	review.BandScore = &band
+
+	if err := s.repo.UpdateReviewScores(review); err != nil {
+		if err.Error() == "review not claimed" {
+			return nil, ErrReviewNotClaimed
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+// AddReviewComment anchors a comment to a span of the essay, a stretch of
+// the recording or the whole submission
+func (s *ExerciseService) AddReviewComment(reviewID, reviewerID uuid.UUID, req *models.CreateReviewCommentRequest) (*models.ReviewComment, error) {
+	review, err := s.claimedReview(reviewID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	submission, err := s.repo.GetSubmissionByID(review.AttemptID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.ReviewComment{
+		ReviewID:   reviewID,
+		AuthorID:   reviewerID,
+		AnchorType: req.AnchorType,
+		Comment:    req.Comment,
+	}
+
+	switch req.AnchorType {
+	case "text_span":
+		if review.SkillType != "writing" || submission.EssayText == nil {
+			return nil, fmt.Errorf("%w: text spans can only be commented on essays", ErrInvalidReview)
+		}
+		essay := []rune(*submission.EssayText)
+		if req.StartOffset == nil || req.EndOffset == nil || *req.EndOffset <= *req.StartOffset || *req.EndOffset > len(essay) {
+			return nil, fmt.Errorf("%w: start_offset and end_offset must select a span of the essay", ErrInvalidReview)
+		}
+		quoted := string(essay[*req.StartOffset:*req.EndOffset])
+		comment.StartOffset = req.StartOffset
+		comment.EndOffset = req.EndOffset
+		comment.QuotedText = &quoted
+	case "audio_timestamp":
+		if review.SkillType != "speaking" {
+			return nil, fmt.Errorf("%w: timestamps can only be commented on recordings", ErrInvalidReview)
+		}
+		if req.StartSeconds == nil || (req.EndSeconds != nil && *req.EndSeconds < *req.StartSeconds) {
+			return nil, fmt.Errorf("%w: start_seconds is required and must not be after end_seconds", ErrInvalidReview)
+		}
+		if d := submission.AudioDurationSeconds; d != nil && *d > 0 && *req.StartSeconds > float64(*d) {
+			return nil, fmt.Errorf("%w: start_seconds is past the end of the recording", ErrInvalidReview)
+		}
+		comment.StartSeconds = req.StartSeconds
+		comment.EndSeconds = req.EndSeconds
+	}
+
+	if err := s.repo.CreateReviewComment(comment); err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// DeleteReviewComment deletes one of the reviewer's comments before the review is published
+func (s *ExerciseService) DeleteReviewComment(reviewID, commentID, reviewerID uuid.UUID) error {
+	if _, err := s.claimedReview(reviewID, reviewerID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteReviewComment(reviewID, commentID, reviewerID); err != nil {
+		if err.Error() == "review comment not found" {
+			return ErrReviewCommentMissing
+		}
+		return err
+	}
+	return nil
+}
+
+// PublishReview makes the reviewed band the submission's result, sends it
+// to user-service and tells the learner
+func (s *ExerciseService) PublishReview(reviewID, reviewerID uuid.UUID) (*models.SubmissionReview, error) {
+	review, err := s.claimedReview(reviewID, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+	if review.BandScore == nil {
+		band := recalculateBand(review.SkillType, review.CriteriaScores())
+		review.BandScore = &band
+	}
+
+	if err := s.repo.PublishReview(review); err != nil {
+		if err.Error() == "review not claimed" {
+			return nil, ErrReviewNotClaimed
+		}
+		return nil, err
+	}
+	log.Printf("📝 Review %s of submission %s published by %s: %.1f band", review.ID, review.AttemptID, reviewerID, *review.BandScore)
+
+	go s.afterReviewPublished(*review)
+	return review, nil
+}
+
+// afterReviewPublished syncs the corrected band and notifies the learner
+func (s *ExerciseService) afterReviewPublished(review models.SubmissionReview) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in afterReviewPublished: %v", r)
+		}
+	}()
+
+	submission, err := s.repo.GetSubmissionByID(review.AttemptID)
+	if err != nil {
+		log.Printf("⚠️ Failed to get submission %s: %v", review.AttemptID, err)
+		return
+	}
+
+	if err := s.syncReviewCorrection(&review, submission); err != nil {
+		log.Printf("⚠️ Corrected band of submission %s not synced, will retry: %v", review.AttemptID, err)
+	}
+	if submission.MockTestSessionID != nil {
+		if err := s.settleMockTestSessionByID(*submission.MockTestSessionID); err != nil {
+			log.Printf("⚠️ Mock test session %s not settled, will retry: %v", *submission.MockTestSessionID, err)
+		}
+	}
+
+	title := "bài tập"
+	if exercise, err := s.repo.GetExerciseByIDSimple(submission.ExerciseID); err == nil {
+		title = exercise.Title
+	}
+	message := fmt.Sprintf("Giáo viên đã chấm lại bài '%s': band %.1f.", title, *review.BandScore)
+	if review.AIBandScore != nil && *review.AIBandScore != *review.BandScore {
+		message = fmt.Sprintf("Giáo viên đã chấm lại bài '%s': band %.1f (AI chấm %.1f).", title, *review.BandScore, *review.AIBandScore)
+	}
+	actionType := "navigate_to_exercise"
+	err = s.notificationClient.SendNotification(client.SendNotificationRequest{
+		UserID:     submission.UserID.String(),
+		Title:      "Bài làm đã được giáo viên chấm",
+		Message:    message,
+		Type:       "exercise_graded",
+		Category:   "success",
+		ActionType: &actionType,
+		ActionData: map[string]interface{}{
+			"exercise_id":   submission.ExerciseID.String(),
+			"submission_id": submission.ID.String(),
+		},
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to notify learner of review %s: %v", review.ID, err)
+	}
+}
+
+// syncReviewCorrection replaces the AI band user-service recorded for the
+// submission. If nothing was recorded yet, the pending sync sends the
+// corrected band, so there is nothing to correct.
+func (s *ExerciseService) syncReviewCorrection(review *models.SubmissionReview, submission *models.UserExerciseAttempt) error {
+	err := s.userServiceClient.CorrectBandScore(submission.UserID.String(), client.CorrectBandScoreRequest{
+		SourceID:  submission.ID.String(),
+		SkillType: review.SkillType,
+		BandScore: *review.BandScore,
+	})
+	if err != nil && !errors.Is(err, client.ErrResultNotFound) {
+		return err
+	}
+	return s.repo.MarkReviewSynced(review.ID)
+}
+
+// retryReviewCorrections resends corrected bands user-service did not get
+func (s *ExerciseService) retryReviewCorrections() {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ PANIC in retryReviewCorrections: %v", r)
+		}
+	}()
+
+	reviews, err := s.repo.GetUnsyncedPublishedReviews(50)
+	if err != nil {
+		log.Printf("⚠️ Failed to get unsynced reviews: %v", err)
+		return
+	}
+	for i := range reviews {
+		review := &reviews[i]
+		submission, err := s.repo.GetSubmissionByID(review.AttemptID)
+		if err != nil {
+			log.Printf("⚠️ Failed to get submission %s: %v", review.AttemptID, err)
+			continue
+		}
+		if err := s.syncReviewCorrection(review, submission); err != nil {
+			log.Printf("❌ Retry of corrected band for submission %s failed: %v", review.AttemptID, err)
+		}
+	}
+}
+
+// recalculateBand applies the IELTS band formula for the skill to criteria scores
+func recalculateBand(skillType string, scores map[string]float64) float64 {
+	c := reviewCriteria[skillType]
+	if skillType == "speaking" {
+		return ielts.CalculateSpeakingBand(scores[c[0]], scores[c[1]], scores[c[2]], scores[c[3]])
+	}
+	return ielts.CalculateWritingBand(scores[c[0]], scores[c[1]], scores[c[2]], scores[c[3]])
+}
+
+func (s *ExerciseService) getReview(id uuid.UUID) (*models.SubmissionReview, error) {
+	review, err := s.repo.GetReviewByID(id)
+	if err != nil {
+		if err.Error() == "review not found" {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+	return review, nil
+}
+
+// claimedReview returns a review the reviewer holds the claim on
+func (s *ExerciseService) claimedReview(id, reviewerID uuid.UUID) (*models.SubmissionReview, error) {
+	review, err := s.getReview(id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case review.Status == "published":
+		return nil, ErrReviewPublished
+	case review.Status != "claimed":
+		return nil, ErrReviewNotClaimed
+	case review.ReviewerID == nil || *review.ReviewerID != reviewerID:
+		return nil, ErrReviewClaimed
+	}
+	return review, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
@@ -1,19 +1,24 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	sharedClient "github.com/bisosad1501/DATN/shared/pkg/client"
 	"github.com/bisosad1501/DATN/shared/pkg/ielts"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	aiClient "github.com/bisosad1501/ielts-platform/exercise-service/internal/client"
 	"github.com/bisosad1501/ielts-platform/exercise-service/internal/models"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// SubmitExercise is the unified submission handler for all 4 skills
-func (s *ExerciseService) SubmitExercise(submissionID uuid.UUID, req *models.SubmitExerciseRequest) error {
+// SubmitExercise is the unified submission handler for all 4 skills.
+// Background grading continues the request's trace after the response is sent.
+func (s *ExerciseService) SubmitExercise(ctx context.Context, submissionID uuid.UUID, req *models.SubmitExerciseRequest) error {
 	// Get submission and exercise info
 	submission, err := s.repo.GetSubmissionByID(submissionID)
 	if err != nil {
@@ -25,14 +30,33 @@ func (s *ExerciseService) SubmitExercise(submissionID uuid.UUID, req *models.Sub
 		return fmt.Errorf("get exercise: %w", err)
 	}
 
+	// The server owns the clock: late answers are rejected, autosaved ones
+	// fill in what the request leaves out, and time spent cannot exceed
+	// the time that has passed
+	now := time.Now()
+	answers, draft, err := s.answersToGrade(submission, req.Answers, now)
+	if err != nil {
+		return err
+	}
+	req.Answers = answers
+	if req.WritingData == nil {
+		req.WritingData = draft.WritingData
+	}
+	if t := elapsedTimeSpent(submission, &req.TimeSpentSeconds, now); t != nil {
+		req.TimeSpentSeconds = *t
+	}
+
+	// Async work must outlive the HTTP request but stay in its trace
+	ctx = context.WithoutCancel(ctx)
+
 	// Route to appropriate handler based on skill type
 	switch exercise.SkillType {
 	case "listening", "reading":
-		return s.handleListeningReadingSubmission(submission, exercise, req)
+		return s.handleListeningReadingSubmission(ctx, submission, exercise, req)
 	case "writing":
-		return s.handleWritingSubmission(submission, exercise, req)
+		return s.handleWritingSubmission(ctx, submission, exercise, req)
 	case "speaking":
-		return s.handleSpeakingSubmission(submission, exercise, req)
+		return s.handleSpeakingSubmission(ctx, submission, exercise, req)
 	default:
 		return fmt.Errorf("unsupported skill type: %s", exercise.SkillType)
 	}
@@ -40,24 +64,33 @@ func (s *ExerciseService) SubmitExercise(submissionID uuid.UUID, req *models.Sub
 
 // handleListeningReadingSubmission handles L/R with immediate grading
 func (s *ExerciseService) handleListeningReadingSubmission(
+	ctx context.Context,
 	submission *models.UserExerciseAttempt,
 	exercise *models.Exercise,
 	req *models.SubmitExerciseRequest,
 ) error {
-	// 1. Save answers
+	// 1. Check answers
 	if len(req.Answers) == 0 {
 		return fmt.Errorf("no answers provided")
 	}
 
-	err := s.repo.SaveSubmissionAnswers(submission.ID, req.Answers)
-	if err != nil {
-		return fmt.Errorf("save answers: %w", err)
-	}
+	return s.finishListeningReadingSubmission(ctx, submission, exercise, req.Answers)
+}
 
-	// 2. Grade immediately
-	err = s.repo.CompleteSubmission(submission.ID)
+// finishListeningReadingSubmission saves and grades the answers of an L/R
+// submission and records the result. When the learner's submit races the
+// sweeper, only the side that completes the attempt records it, with the
+// answers it saved.
+func (s *ExerciseService) finishListeningReadingSubmission(
+	ctx context.Context,
+	submission *models.UserExerciseAttempt,
+	exercise *models.Exercise,
+	answers []models.SubmitAnswerItem,
+) error {
+	// 2. Save answers and grade immediately
+	err := s.repo.SubmitAttempt(submission.ID, answers, nil, submission.AutoSubmittedAt != nil)
 	if err != nil {
-		return fmt.Errorf("complete submission: %w", err)
+		return fmt.Errorf("complete submission: %w", attemptClosed(err))
 	}
 
 	// 3. Get updated submission with score
@@ -88,16 +121,17 @@ func (s *ExerciseService) handleListeningReadingSubmission(
 	}
 
 	// 6. Record to user service (async) - for practice activities and test results
-	go s.recordToUserService(submission.ID, exercise, bandScore)
+	go s.recordToUserService(ctx, submission.ID, exercise, bandScore)
 
 	// 7. Handle exercise completion (update user stats and send notification)
-	go s.handleExerciseCompletion(submission.ID)
+	go s.handleExerciseCompletion(ctx, submission.ID)
 
 	return nil
 }
 
 // handleWritingSubmission handles Writing with async AI evaluation
 func (s *ExerciseService) handleWritingSubmission(
+	ctx context.Context,
 	submission *models.UserExerciseAttempt,
 	exercise *models.Exercise,
 	req *models.SubmitExerciseRequest,
@@ -107,6 +141,18 @@ func (s *ExerciseService) handleWritingSubmission(
 		return fmt.Errorf("essay text is required for writing submission")
 	}
 
+	// FIX: Set completed_at and time_spent_seconds when user submits
+	// This ensures timestamp and time tracking are set immediately, not when AI evaluation completes.
+	// Marking comes first so that, when the learner's submit races the
+	// sweeper, the side that loses leaves the attempt alone.
+	var timePtr *int
+	if req.TimeSpentSeconds > 0 {
+		timePtr = &req.TimeSpentSeconds
+	}
+	if err := s.repo.MarkSubmissionAsSubmittedWithTime(submission.ID, timePtr, submission.AutoSubmittedAt != nil); err != nil {
+		return fmt.Errorf("mark submission as submitted: %w", attemptClosed(err))
+	}
+
 	// 2. Save essay data
 	wordCount := req.WritingData.WordCount
 	if wordCount == 0 {
@@ -124,16 +170,6 @@ func (s *ExerciseService) handleWritingSubmission(
 		return fmt.Errorf("save writing data: %w", err)
 	}
 
-	// FIX: Set completed_at and time_spent_seconds when user submits
-	// This ensures timestamp and time tracking are set immediately, not when AI evaluation completes
-	var timePtr *int
-	if req.TimeSpentSeconds > 0 {
-		timePtr = &req.TimeSpentSeconds
-	}
-	if err := s.repo.MarkSubmissionAsSubmittedWithTime(submission.ID, timePtr); err != nil {
-		return fmt.Errorf("mark submission as submitted: %w", err)
-	}
-
 	// Set pending status
 	if err := s.repo.UpdateSubmissionEvaluationStatus(submission.ID, "pending"); err != nil {
 		return fmt.Errorf("update evaluation status: %w", err)
@@ -142,13 +178,14 @@ func (s *ExerciseService) handleWritingSubmission(
 	// 3. Start async evaluation
 	taskType := req.WritingData.TaskType
 	promptText := req.WritingData.PromptText
-	go s.evaluateWritingAsync(submission.ID, exercise, req.WritingData.EssayText, &taskType, &promptText)
+	go s.evaluateWritingAsync(ctx, submission.ID, exercise, req.WritingData.EssayText, &taskType, &promptText)
 
 	return nil
 }
 
 // handleSpeakingSubmission handles Speaking with async transcription + evaluation
 func (s *ExerciseService) handleSpeakingSubmission(
+	ctx context.Context,
 	submission *models.UserExerciseAttempt,
 	exercise *models.Exercise,
 	req *models.SubmitExerciseRequest,
@@ -158,6 +195,18 @@ func (s *ExerciseService) handleSpeakingSubmission(
 		return fmt.Errorf("audio URL is required for speaking submission")
 	}
 
+	// FIX: Set completed_at and time_spent_seconds when user submits
+	// This ensures timestamp and time tracking are set immediately, not when AI evaluation completes.
+	// Marking comes first so that a second submit, or one after the sweeper
+	// abandoned the attempt, leaves it alone.
+	var timePtr *int
+	if req.TimeSpentSeconds > 0 {
+		timePtr = &req.TimeSpentSeconds
+	}
+	if err := s.repo.MarkSubmissionAsSubmittedWithTime(submission.ID, timePtr, false); err != nil {
+		return fmt.Errorf("mark submission as submitted: %w", attemptClosed(err))
+	}
+
 	// 2. Save audio data
 	// Frontend sends presigned URL (if available) or public URL
 	// We save this URL as-is for frontend access
@@ -177,16 +226,6 @@ func (s *ExerciseService) handleSpeakingSubmission(
 		return fmt.Errorf("save speaking data: %w", err)
 	}
 
-	// FIX: Set completed_at and time_spent_seconds when user submits
-	// This ensures timestamp and time tracking are set immediately, not when AI evaluation completes
-	var timePtr *int
-	if req.TimeSpentSeconds > 0 {
-		timePtr = &req.TimeSpentSeconds
-	}
-	if err := s.repo.MarkSubmissionAsSubmittedWithTime(submission.ID, timePtr); err != nil {
-		return fmt.Errorf("mark submission as submitted: %w", err)
-	}
-
 	// Set processing status
 	if err := s.repo.UpdateSubmissionEvaluationStatus(submission.ID, "processing"); err != nil {
 		return fmt.Errorf("update evaluation status: %w", err)
@@ -218,13 +257,14 @@ func (s *ExerciseService) handleSpeakingSubmission(
 	
 	log.Printf("📎 Using audio URL for AI service: %s (original: %s)", audioURLForAI, audioURL)
 	speakingPart := req.SpeakingData.SpeakingPartNumber
-	go s.evaluateSpeakingAsync(submission.ID, exercise, audioURLForAI, &speakingPart)
+	go s.evaluateSpeakingAsync(ctx, submission.ID, exercise, audioURLForAI, &speakingPart)
 
 	return nil
 }
 
 // evaluateWritingAsync performs async writing evaluation
 func (s *ExerciseService) evaluateWritingAsync(
+	ctx context.Context,
 	submissionID uuid.UUID,
 	exercise *models.Exercise,
 	essayText string,
@@ -238,6 +278,9 @@ func (s *ExerciseService) evaluateWritingAsync(
 		}
 	}()
 
+	ctx, span := tracing.Start(ctx, "exercise.evaluate_writing", attribute.String("submission.id", submissionID.String()))
+	defer span.End()
+
 	log.Printf("🔄 Starting writing evaluation for submission %s", submissionID)
 
 	// Check if AI client exists
@@ -261,7 +304,7 @@ func (s *ExerciseService) evaluateWritingAsync(
 	var result *aiClient.WritingEvaluationResponse
 	err := RetryWithBackoff(AIServiceRetryConfig(), func() error {
 		var evalErr error
-		result, evalErr = s.aiServiceClient.EvaluateWriting(aiClient.WritingEvaluationRequest{
+		result, evalErr = s.aiServiceClient.EvaluateWriting(ctx, aiClient.WritingEvaluationRequest{
 			EssayText:  essayText,
 			TaskType:   taskTypeStr,
 			PromptText: promptStr,
@@ -296,17 +339,19 @@ func (s *ExerciseService) evaluateWritingAsync(
 		"suggestions":        nil,
 	}
 
+	criteriaScores := map[string]float64{
+		"task_achievement":   result.Data.CriteriaScores.TaskAchievement,
+		"coherence_cohesion": result.Data.CriteriaScores.CoherenceCohesion,
+		"lexical_resource":   result.Data.CriteriaScores.LexicalResource,
+		"grammar_accuracy":   result.Data.CriteriaScores.GrammaticalRange,
+	}
+
 	// Update submission with results
 	err = s.repo.UpdateSubmissionWithAIResult(submissionID, &models.AIEvaluationResult{
 		OverallBandScore: overallBand,
 		DetailedScores:   detailedScores,
 		Feedback:         result.Data.ExaminerFeedback,
-		CriteriaScores: map[string]float64{
-			"task_achievement":   result.Data.CriteriaScores.TaskAchievement,
-			"coherence_cohesion": result.Data.CriteriaScores.CoherenceCohesion,
-			"lexical_resource":   result.Data.CriteriaScores.LexicalResource,
-			"grammar_accuracy":   result.Data.CriteriaScores.GrammaticalRange,
-		},
+		CriteriaScores:   criteriaScores,
 	})
 	if err != nil {
 		log.Printf("❌ Failed to update submission: %v", err)
@@ -315,15 +360,19 @@ func (s *ExerciseService) evaluateWritingAsync(
 
 	log.Printf("✅ Writing evaluation completed: %.1f band", overallBand)
 
+	// Queue for examiner review; the AI band stands until a review is published
+	s.queueReview(submissionID, "writing", overallBand, criteriaScores)
+
 	// Record to user service
-	go s.recordToUserService(submissionID, exercise, overallBand)
+	go s.recordToUserService(ctx, submissionID, exercise, overallBand)
 
 	// Handle exercise completion (update user stats and send notification)
-	go s.handleExerciseCompletion(submissionID)
+	go s.handleExerciseCompletion(ctx, submissionID)
 }
 
 // evaluateSpeakingAsync performs async speaking transcription + evaluation
 func (s *ExerciseService) evaluateSpeakingAsync(
+	ctx context.Context,
 	submissionID uuid.UUID,
 	exercise *models.Exercise,
 	audioURL string,
@@ -336,6 +385,9 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 		}
 	}()
 
+	ctx, span := tracing.Start(ctx, "exercise.evaluate_speaking", attribute.String("submission.id", submissionID.String()))
+	defer span.End()
+
 	log.Printf("🔄 Starting speaking evaluation for submission %s", submissionID)
 	log.Printf("📎 Audio URL: %s", audioURL)
 	log.Printf("📎 Part Number: %v", partNumber)
@@ -359,7 +411,7 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 	err := RetryWithBackoff(AIServiceRetryConfig(), func() error {
 		var transcribeErr error
 		log.Printf("🎤 Transcribing audio from URL: %s", audioURL)
-		transcriptResult, transcribeErr = s.aiServiceClient.TranscribeSpeaking(aiClient.SpeakingTranscriptionRequest{
+		transcriptResult, transcribeErr = s.aiServiceClient.TranscribeSpeaking(ctx, aiClient.SpeakingTranscriptionRequest{
 			AudioURL: audioURL,
 		})
 
@@ -446,7 +498,7 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 	err = RetryWithBackoff(AIServiceRetryConfig(), func() error {
 		var evalErr error
 		log.Printf("📝 Evaluating speaking with transcript length: %d, part: %d, word count: %d, duration: %.1fs", len(transcriptResult.Data.TranscriptText), partNum, wordCount, duration)
-		evalResult, evalErr = s.aiServiceClient.EvaluateSpeaking(aiClient.SpeakingEvaluationRequest{
+		evalResult, evalErr = s.aiServiceClient.EvaluateSpeaking(ctx, aiClient.SpeakingEvaluationRequest{
 			AudioURL:       audioURL,
 			TranscriptText: transcriptResult.Data.TranscriptText,
 			PromptText:     promptText,
@@ -490,17 +542,19 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 		"suggestions":      nil,
 	}
 
+	criteriaScores := map[string]float64{
+		"fluency":          evalResult.Data.CriteriaScores.FluencyCoherence,
+		"lexical_resource": evalResult.Data.CriteriaScores.LexicalResource,
+		"grammar":          evalResult.Data.CriteriaScores.GrammaticalRange,
+		"pronunciation":    evalResult.Data.CriteriaScores.Pronunciation,
+	}
+
 	// Update submission with results
 	err = s.repo.UpdateSubmissionWithAIResult(submissionID, &models.AIEvaluationResult{
 		OverallBandScore: overallBand,
 		DetailedScores:   detailedScores,
 		Feedback:         evalResult.Data.ExaminerFeedback,
-		CriteriaScores: map[string]float64{
-			"fluency":          evalResult.Data.CriteriaScores.FluencyCoherence,
-			"lexical_resource": evalResult.Data.CriteriaScores.LexicalResource,
-			"grammar":          evalResult.Data.CriteriaScores.GrammaticalRange,
-			"pronunciation":    evalResult.Data.CriteriaScores.Pronunciation,
-		},
+		CriteriaScores:   criteriaScores,
 	})
 	if err != nil {
 		log.Printf("❌ Failed to update submission: %v", err)
@@ -509,15 +563,19 @@ func (s *ExerciseService) evaluateSpeakingAsync(
 
 	log.Printf("✅ Speaking evaluation completed: %.1f band", overallBand)
 
+	// Queue for examiner review; the AI band stands until a review is published
+	s.queueReview(submissionID, "speaking", overallBand, criteriaScores)
+
 	// Record to user service
-	go s.recordToUserService(submissionID, exercise, overallBand)
+	go s.recordToUserService(ctx, submissionID, exercise, overallBand)
 
 	// Handle exercise completion (update user stats and send notification)
-	go s.handleExerciseCompletion(submissionID)
+	go s.handleExerciseCompletion(ctx, submissionID)
 }
 
 // recordToUserService records results to user service (for all 4 skills)
 func (s *ExerciseService) recordToUserService(
+	ctx context.Context,
 	submissionID uuid.UUID,
 	exercise *models.Exercise,
 	bandScore float64,
@@ -535,6 +593,14 @@ func (s *ExerciseService) recordToUserService(
 		return
 	}
 
+	// Mock test stages are recorded together once the sitting is settled
+	if submission.MockTestSessionID != nil {
+		if err := s.settleMockTestSessionByID(*submission.MockTestSessionID); err != nil {
+			log.Printf("⚠️ Mock test session %s not settled, will retry: %v", *submission.MockTestSessionID, err)
+		}
+		return
+	}
+
 	// Determine if this is official test or practice
 	isOfficialTest := exercise.IsOfficialTest()
 
@@ -577,7 +643,7 @@ func (s *ExerciseService) recordToUserService(
 
 		// FIX #9: Track sync attempts and failures
 		err := RetryWithBackoff(DefaultRetryConfig(), func() error {
-			return s.userServiceClient.RecordTestResult(submission.UserID.String(), req)
+			return s.userServiceClient.WithContext(ctx).RecordTestResult(submission.UserID.String(), req)
 		})
 
 		if err != nil {
@@ -599,11 +665,13 @@ func (s *ExerciseService) recordToUserService(
 		}
 
 		exerciseIDStr := exercise.ID.String()
+		submissionIDStr := submission.ID.String()
 		req := sharedClient.RecordPracticeActivityRequest{
 			Skill:            exercise.SkillType,
 			ActivityType:     "drill",
 			ExerciseID:       &exerciseIDStr,
 			ExerciseTitle:    &exercise.Title,
+			SourceID:         &submissionIDStr,
 			BandScore:        &bandScore,
 			TimeSpentSeconds: &timeSpent,
 			CompletionStatus: "completed",
@@ -611,7 +679,7 @@ func (s *ExerciseService) recordToUserService(
 
 		// FIX #9: Track sync attempts for practice activities too
 		err := RetryWithBackoff(DefaultRetryConfig(), func() error {
-			return s.userServiceClient.RecordPracticeActivity(submission.UserID.String(), req)
+			return s.userServiceClient.WithContext(ctx).RecordPracticeActivity(submission.UserID.String(), req)
 		})
 
 		if err != nil {
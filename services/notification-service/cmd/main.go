@@ -1,13 +1,20 @@
 package main
 
 import (
+	"context"
 	"log"
+	"net/mail"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/config"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/database"
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/email"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/handlers"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/middleware"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/repository"
@@ -27,6 +34,12 @@ func main() {
 	log.Printf("📝 Server Port: %s", cfg.ServerPort)
 	log.Printf("📝 Database: %s", cfg.Database.DBName)
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "notification-service")
+	if err != nil {
+		log.Printf("⚠️ Tracing disabled: %v", err)
+	}
+
 	// Connect to database
 	db, err := database.NewDatabase(cfg.GetDBConnectionString())
 	if err != nil {
@@ -38,13 +51,36 @@ func main() {
 	notificationRepo := repository.NewNotificationRepository(db.DB)
 	broadcaster := service.NewNotificationBroadcaster()
 	notificationService := service.NewNotificationService(notificationRepo, broadcaster)
+
+	// Transactional email outbox
+	renderer, err := email.NewRenderer(cfg.Email.DefaultLocale)
+	if err != nil {
+		log.Fatalf("❌ Failed to load email templates: %v", err)
+	}
+	transport, err := email.NewTransport(cfg.Email.Transport, email.SMTPConfig{
+		Host:     cfg.Email.SMTPHost,
+		Port:     cfg.Email.SMTPPort,
+		Username: cfg.Email.SMTPUsername,
+		Password: cfg.Email.SMTPPassword,
+	}, cfg.Email.OutboxDir)
+	if err != nil {
+		log.Fatalf("❌ Failed to set up email transport: %v", err)
+	}
+	from := mail.Address{Name: cfg.Email.FromName, Address: cfg.Email.FromAddress}
+	userServiceClient := client.NewUserServiceClient(cfg.UserServiceURL, cfg.InternalAPIKey)
+	emailOutbox := service.NewEmailOutbox(notificationRepo, renderer, transport, userServiceClient, from, cfg.Email.MaxAttempts)
+	emailOutbox.Start(context.Background())
+	log.Printf("📧 Email transport: %s", cfg.Email.Transport)
+
 	notificationHandler := handlers.NewNotificationHandler(notificationService, broadcaster)
-	internalHandler := handlers.NewInternalHandler(notificationService)
-	authMiddleware := middleware.NewAuthMiddleware(cfg.JWTSecret, cfg.InternalAPIKey)
+	internalHandler := handlers.NewInternalHandler(notificationService, emailOutbox)
+	authMiddleware := middleware.NewAuthMiddleware(cfg.JWKSURL, cfg.InternalAPIKey)
 
 	// Setup Gin
 	gin.SetMode(gin.ReleaseMode)
 	r := gin.Default()
+	r.Use(tracing.Middleware("notification-service"), tracing.RequestID(), metrics.Middleware("notification-service"))
+	r.GET("/metrics", metrics.Handler())
 
 	// CORS is handled by API Gateway - no need to set headers here
 
@@ -65,4 +101,10 @@ func main() {
 
 	<-quit
 	log.Println("🛑 Shutting down Notification Service...")
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		log.Printf("⚠️ Failed to flush traces: %v", err)
+	}
 }
@@ -8,9 +8,11 @@ import (
 
 type Config struct {
 	ServerPort     string
-	JWTSecret      string
+	JWKSURL        string
 	InternalAPIKey string
+	UserServiceURL string
 	Database       DatabaseConfig
+	Email          EmailConfig
 }
 
 type DatabaseConfig struct {
@@ -21,11 +23,26 @@ type DatabaseConfig struct {
 	DBName   string
 }
 
+// EmailConfig configures the transactional email outbox
+type EmailConfig struct {
+	Transport     string // smtp, file or console
+	OutboxDir     string // Where the file transport writes .eml files
+	DefaultLocale string // vi or en, used when a request names none
+	FromAddress   string
+	FromName      string
+	MaxAttempts   int
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUsername  string
+	SMTPPassword  string
+}
+
 func LoadConfig() (*Config, error) {
 	config := &Config{
 		ServerPort:     getEnv("SERVER_PORT", "8085"),
-		JWTSecret:      getEnv("JWT_SECRET", "your-secret-key"),
+		JWKSURL:        getEnv("JWKS_URL", "http://auth-service:8081/.well-known/jwks.json"),
 		InternalAPIKey: getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
+		UserServiceURL: getEnv("USER_SERVICE_URL", "http://user-service:8082"),
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
 			Port:     getEnv("DB_PORT", "5432"),
@@ -33,10 +50,18 @@ func LoadConfig() (*Config, error) {
 			Password: getEnv("DB_PASSWORD", "postgres"),
 			DBName:   getEnv("DB_NAME", "notification_db"),
 		},
-	}
-
-	if config.JWTSecret == "" {
-		return nil, fmt.Errorf("JWT_SECRET is required")
+		Email: EmailConfig{
+			Transport:     getEnv("EMAIL_TRANSPORT", "smtp"),
+			OutboxDir:     getEnv("EMAIL_OUTBOX_DIR", "./tmp/emails"),
+			DefaultLocale: getEnv("EMAIL_DEFAULT_LOCALE", "vi"),
+			FromAddress:   getEnv("SMTP_FROM_EMAIL", "noreply@ieltsplatform.com"),
+			FromName:      getEnv("SMTP_FROM_NAME", "IELTS Learning Platform"),
+			MaxAttempts:   getEnvAsInt("EMAIL_MAX_ATTEMPTS", 8),
+			SMTPHost:      getEnv("SMTP_HOST", "smtp.gmail.com"),
+			SMTPPort:      getEnv("SMTP_PORT", "587"),
+			SMTPUsername:  getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+		},
 	}
 
 	return config, nil
@@ -0,0 +1,80 @@
+package email
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is one outbox email, sent as multipart/alternative with a text and
+// an HTML part
+type Message struct {
+	ID      uuid.UUID
+	From    mail.Address
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// MessageID is the Message-ID header, derived from the outbox ID so retries
+// of the same email carry the same one
+func (m *Message) MessageID() string {
+	domain := "localhost"
+	if at := strings.LastIndex(m.From.Address, "@"); at >= 0 {
+		domain = m.From.Address[at+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", m.ID, domain)
+}
+
+// Bytes encodes the message as RFC 5322 text
+func (m *Message) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	headers := []string{
+		"From: " + m.From.String(),
+		"To: " + m.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + m.MessageID(),
+		"MIME-Version: 1.0",
+		fmt.Sprintf(`Content-Type: multipart/alternative; boundary="%s"`, body.Boundary()),
+	}
+	var msg bytes.Buffer
+	msg.WriteString(strings.Join(headers, "\r\n") + "\r\n\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + `; charset="UTF-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	msg.Write(buf.Bytes())
+	return msg.Bytes(), nil
+}
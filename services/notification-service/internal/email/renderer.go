@@ -0,0 +1,138 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	texttemplate "text/template"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// Locales every template is written in
+var Locales = []string{"vi", "en"}
+
+// templateVersions is the version of each template used for new messages.
+// Add a new version directory rather than editing a released one, so queued
+// and already-sent messages can still be traced to the text they were sent with.
+var templateVersions = map[string]int{
+	"password_reset":      1,
+	"email_verification":  1,
+	"login_code":          1,
+	"email_change_notice": 1,
+	"classroom_invite":    1,
+}
+
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// Rendered is a message ready to be stored in the outbox
+type Rendered struct {
+	Template string
+	Version  int
+	Locale   string
+	Subject  string
+	HTML     string
+	Text     string
+}
+
+type compiled struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Renderer renders the embedded email templates. Each template version has an
+// HTML and a text file per locale; the text file also defines the subject.
+type Renderer struct {
+	defaultLocale string
+	templates     map[string]compiled // keyed by name/vN/locale
+}
+
+// NewRenderer parses every template up front so a missing or broken file
+// stops the service at startup instead of failing a send
+func NewRenderer(defaultLocale string) (*Renderer, error) {
+	if !IsLocale(defaultLocale) {
+		return nil, fmt.Errorf("unsupported default email locale %q", defaultLocale)
+	}
+
+	r := &Renderer{
+		defaultLocale: defaultLocale,
+		templates:     make(map[string]compiled),
+	}
+	for name, version := range templateVersions {
+		for _, locale := range Locales {
+			dir := fmt.Sprintf("templates/%s/v%d", name, version)
+
+			html, err := htmltemplate.New("layout.html").Option("missingkey=error").
+				ParseFS(templateFS, "templates/layout.html", path.Join(dir, locale+".html"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.html: %w", dir, locale, err)
+			}
+			text, err := texttemplate.New(locale+".txt").Option("missingkey=error").
+				ParseFS(templateFS, path.Join(dir, locale+".txt"))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s/%s.txt: %w", dir, locale, err)
+			}
+			if text.Lookup("subject") == nil {
+				return nil, fmt.Errorf("%s/%s.txt does not define a subject", dir, locale)
+			}
+
+			r.templates[templateKey(name, version, locale)] = compiled{html: html, text: text}
+		}
+	}
+
+	return r, nil
+}
+
+// Render renders the current version of a template. An empty locale uses the
+// default one.
+func (r *Renderer) Render(name, locale string, data map[string]string) (*Rendered, error) {
+	version, ok := templateVersions[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+	if locale == "" {
+		locale = r.defaultLocale
+	}
+	t, ok := r.templates[templateKey(name, version, locale)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported email locale %q", locale)
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", name, err)
+	}
+	if err := t.text.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text of %s: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML of %s: %w", name, err)
+	}
+
+	return &Rendered{
+		Template: name,
+		Version:  version,
+		Locale:   locale,
+		Subject:  subject.String(),
+		HTML:     html.String(),
+		Text:     text.String(),
+	}, nil
+}
+
+func templateKey(name string, version int, locale string) string {
+	return fmt.Sprintf("%s/v%d/%s", name, version, locale)
+}
+
+// IsLocale reports whether the templates are written in locale
+func IsLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+)
+
+// Transport names accepted by EMAIL_TRANSPORT
+const (
+	TransportSMTP    = "smtp"
+	TransportFile    = "file"
+	TransportConsole = "console"
+)
+
+// Transport hands a message to whatever delivers it
+type Transport interface {
+	Send(msg *Message) error
+}
+
+// SMTPConfig is the mail server used by the smtp transport
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+}
+
+// NewTransport builds the transport named by EMAIL_TRANSPORT. The file and
+// console transports stand in for a mail server in local development and tests.
+func NewTransport(name string, smtpConfig SMTPConfig, outboxDir string) (Transport, error) {
+	switch name {
+	case TransportSMTP:
+		return &smtpTransport{config: smtpConfig}, nil
+	case TransportFile:
+		if err := os.MkdirAll(outboxDir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create email outbox directory: %w", err)
+		}
+		return &fileTransport{dir: outboxDir}, nil
+	case TransportConsole:
+		return consoleTransport{}, nil
+	default:
+		return nil, fmt.Errorf("unknown email transport %q", name)
+	}
+}
+
+// IsPermanent reports whether the mail server rejected the message outright
+// (a 5xx reply), so retrying it would not help
+func IsPermanent(err error) bool {
+	var smtpErr *textproto.Error
+	return errors.As(err, &smtpErr) && smtpErr.Code >= 500
+}
+
+type smtpTransport struct {
+	config SMTPConfig
+}
+
+func (t *smtpTransport) Send(msg *Message) error {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	// Local stand-ins such as Mailpit take mail without credentials
+	var auth smtp.Auth
+	if t.config.Username != "" {
+		auth = smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%s", t.config.Host, t.config.Port)
+	if err := smtp.SendMail(addr, auth, msg.From.Address, []string{msg.To}, raw); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// fileTransport writes each message to <dir>/<id>.eml, which mail clients open
+type fileTransport struct {
+	dir string
+}
+
+func (t *fileTransport) Send(msg *Message) error {
+	raw, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(t.dir, msg.ID.String()+".eml")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	log.Printf("[Email] Wrote %q for %s to %s", msg.Subject, msg.To, path)
+	return nil
+}
+
+// consoleTransport logs the text part instead of sending anything
+type consoleTransport struct{}
+
+func (consoleTransport) Send(msg *Message) error {
+	log.Printf("[Email] To: %s\nSubject: %s\n\n%s", msg.To, msg.Subject, msg.Text)
+	return nil
+}
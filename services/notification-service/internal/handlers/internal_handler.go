@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -13,12 +14,14 @@ import (
 // InternalHandler handles internal service-to-service API calls
 type InternalHandler struct {
 	notificationService *service.NotificationService
+	emailOutbox         *service.EmailOutbox
 }
 
 // NewInternalHandler creates a new internal handler
-func NewInternalHandler(notificationService *service.NotificationService) *InternalHandler {
+func NewInternalHandler(notificationService *service.NotificationService, emailOutbox *service.EmailOutbox) *InternalHandler {
 	return &InternalHandler{
 		notificationService: notificationService,
+		emailOutbox:         emailOutbox,
 	}
 }
 
@@ -48,6 +51,15 @@ type SendBulkNotificationInternalRequest struct {
 	ImageURL   *string                `json:"image_url,omitempty"`
 }
 
+// SendEmailInternalRequest represents request to send a transactional email
+type SendEmailInternalRequest struct {
+	UserID   *uuid.UUID        `json:"user_id"` // Omitted for addresses without an account
+	To       string            `json:"to" binding:"required,email"`
+	Template string            `json:"template" binding:"required"` // password_reset, email_verification, login_code, email_change_notice, classroom_invite
+	Locale   string            `json:"locale,omitempty" binding:"omitempty,oneof=vi en"`
+	Data     map[string]string `json:"data,omitempty"` // Template variables, e.g. code, link
+}
+
 // SendNotificationInternal sends a single notification (internal API)
 // POST /internal/send
 func (h *InternalHandler) SendNotificationInternal(c *gin.Context) {
@@ -233,3 +245,100 @@ func (h *InternalHandler) UpdatePreferencesInternal(c *gin.Context) {
 		},
 	})
 }
+
+// ExportUserDataInternal returns everything stored about a user (called by Auth Service for data exports)
+// GET /api/v1/notifications/internal/users/:user_id/data
+func (h *InternalHandler) ExportUserDataInternal(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("[Internal] Invalid user ID: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_user_id",
+			Message: "Invalid user ID format",
+		})
+		return
+	}
+
+	data, err := h.notificationService.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("[Internal] Failed to export data of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "export_failed",
+			Message: "Failed to export user data: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// EraseUserDataInternal deletes everything stored about a user (called by Auth Service for account deletion)
+// DELETE /api/v1/notifications/internal/users/:user_id/data
+func (h *InternalHandler) EraseUserDataInternal(c *gin.Context) {
+	userIDStr := c.Param("user_id")
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		log.Printf("[Internal] Invalid user ID: %s", userIDStr)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "invalid_user_id",
+			Message: "Invalid user ID format",
+		})
+		return
+	}
+
+	if err := h.notificationService.EraseUserData(c.Request.Context(), userID); err != nil {
+		log.Printf("[Internal] Failed to erase data of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "erase_failed",
+			Message: "Failed to erase user data: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "User data erased",
+	})
+}
+
+// SendEmailInternal queues a transactional email in the outbox (internal API).
+// The outbox worker sends it with retries; 202 means it is stored, not sent.
+// POST /internal/emails
+func (h *InternalHandler) SendEmailInternal(c *gin.Context) {
+	var req SendEmailInternalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("[Internal] Send email validation error: %v", err)
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{
+			Error:   "validation_error",
+			Message: "Invalid request payload: " + err.Error(),
+		})
+		return
+	}
+
+	email, err := h.emailOutbox.Enqueue(req.UserID, req.To, req.Template, req.Locale, req.Data)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidEmail) {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error:   "invalid_email",
+				Message: err.Error(),
+			})
+			return
+		}
+		log.Printf("[Internal] Failed to queue %s email to %s: %v", req.Template, req.To, err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{
+			Error:   "queue_failed",
+			Message: "Failed to queue email: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success":  true,
+		"email_id": email.ID,
+		"message":  "Email queued",
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
 	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -12,13 +13,13 @@ import (
 )
 
 type AuthMiddleware struct {
-	jwtSecret      string
+	keySet         *jwks.KeySet
 	internalAPIKey string
 }
 
-func NewAuthMiddleware(jwtSecret, internalAPIKey string) *AuthMiddleware {
+func NewAuthMiddleware(jwksURL, internalAPIKey string) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret:      jwtSecret,
+		keySet:         jwks.NewKeySet(jwksURL),
 		internalAPIKey: internalAPIKey,
 	}
 }
@@ -79,13 +80,7 @@ func (m *AuthMiddleware) Authenticate() gin.HandlerFunc {
 		tokenString := parts[1]
 
 		// Parse and validate token
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			// Verify signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, models.ErrorResponse{
@@ -88,26 +88,39 @@ type PushNotification struct {
 
 // EmailNotification represents an email notification
 type EmailNotification struct {
-	ID             uuid.UUID  `json:"id"`
-	NotificationID *uuid.UUID `json:"notification_id,omitempty"`
-	UserID         uuid.UUID  `json:"user_id"`
-	ToEmail        string     `json:"to_email"`
-	Subject        string     `json:"subject"`
-	BodyHTML       string     `json:"body_html"`
-	BodyText       *string    `json:"body_text,omitempty"`
-	TemplateName   *string    `json:"template_name,omitempty"`
-	TemplateData   *string    `json:"template_data,omitempty"` // JSON string
-	Status         string     `json:"status"`                  // pending, sent, delivered, bounced, failed
-	SentAt         *time.Time `json:"sent_at,omitempty"`
-	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
-	OpenedAt       *time.Time `json:"opened_at,omitempty"`
-	ClickedAt      *time.Time `json:"clicked_at,omitempty"`
-	ExternalID     *string    `json:"external_id,omitempty"`
-	ErrorMessage   *string    `json:"error_message,omitempty"`
-	RetryCount     int        `json:"retry_count"`
-	CreatedAt      time.Time  `json:"created_at"`
+	ID              uuid.UUID  `json:"id"`
+	NotificationID  *uuid.UUID `json:"notification_id,omitempty"`
+	UserID          *uuid.UUID `json:"user_id,omitempty"`
+	ToEmail         string     `json:"to_email"`
+	Subject         string     `json:"subject"`
+	BodyHTML        string     `json:"body_html"`
+	BodyText        *string    `json:"body_text,omitempty"`
+	TemplateName    *string    `json:"template_name,omitempty"`
+	TemplateVersion *int       `json:"template_version,omitempty"`
+	Locale          *string    `json:"locale,omitempty"`
+	TemplateData    *string    `json:"template_data,omitempty"` // JSON string
+	Status          string     `json:"status"`                  // pending, sent, delivered, bounced, failed
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+	DeliveredAt     *time.Time `json:"delivered_at,omitempty"`
+	OpenedAt        *time.Time `json:"opened_at,omitempty"`
+	ClickedAt       *time.Time `json:"clicked_at,omitempty"`
+	BouncedAt       *time.Time `json:"bounced_at,omitempty"`
+	FailedAt        *time.Time `json:"failed_at,omitempty"`
+	ExternalID      *string    `json:"external_id,omitempty"`
+	ErrorMessage    *string    `json:"error_message,omitempty"`
+	RetryCount      int        `json:"retry_count"`
+	NextAttemptAt   *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
 }
 
+// Email outbox statuses
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+	EmailStatusBounced = "bounced"
+)
+
 // NotificationTemplate represents a reusable notification template
 type NotificationTemplate struct {
 	ID                int            `json:"id"`
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/google/uuid"
+)
+
+// CreateEmail stores a rendered email in the outbox, due straight away
+func (r *NotificationRepository) CreateEmail(email *models.EmailNotification) error {
+	query := `
+		INSERT INTO email_notifications (
+			id, user_id, to_email, subject, body_html, body_text,
+			template_name, template_version, locale, status,
+			next_attempt_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW(), NOW())
+		RETURNING next_attempt_at, created_at
+	`
+
+	err := r.db.QueryRow(query,
+		email.ID,
+		email.UserID,
+		email.ToEmail,
+		email.Subject,
+		email.BodyHTML,
+		email.BodyText,
+		email.TemplateName,
+		email.TemplateVersion,
+		email.Locale,
+		email.Status,
+	).Scan(&email.NextAttemptAt, &email.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create email: %w", err)
+	}
+
+	return nil
+}
+
+// ClaimDueEmails returns up to limit pending emails whose next attempt is due
+// and moves that attempt lease into the future, so another worker skips them
+// and a worker that dies mid-send leaves them to be picked up again
+func (r *NotificationRepository) ClaimDueEmails(limit int, lease time.Duration) ([]models.EmailNotification, error) {
+	query := `
+		UPDATE email_notifications
+		SET next_attempt_at = NOW() + make_interval(secs => $2), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM email_notifications
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, to_email, subject, body_html, body_text, retry_count
+	`
+
+	rows, err := r.db.Query(query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim due emails: %w", err)
+	}
+	defer rows.Close()
+
+	var emails []models.EmailNotification
+	for rows.Next() {
+		var email models.EmailNotification
+		if err := rows.Scan(
+			&email.ID, &email.UserID, &email.ToEmail, &email.Subject,
+			&email.BodyHTML, &email.BodyText, &email.RetryCount,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan email: %w", err)
+		}
+		emails = append(emails, email)
+	}
+
+	return emails, rows.Err()
+}
+
+// MarkEmailSent records that the mail server accepted the email
+func (r *NotificationRepository) MarkEmailSent(id uuid.UUID, externalID string) error {
+	query := `
+		UPDATE email_notifications
+		SET status = 'sent', sent_at = NOW(), external_id = $2,
+			error_message = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, externalID); err != nil {
+		return fmt.Errorf("failed to mark email sent: %w", err)
+	}
+	return nil
+}
+
+// RescheduleEmail records a failed attempt and when to try again
+func (r *NotificationRepository) RescheduleEmail(id uuid.UUID, errorMessage string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE email_notifications
+		SET retry_count = retry_count + 1, error_message = $2,
+			next_attempt_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, errorMessage, nextAttemptAt); err != nil {
+		return fmt.Errorf("failed to reschedule email: %w", err)
+	}
+	return nil
+}
+
+// MarkEmailUndeliverable gives up on an email, as bounced when the mail
+// server rejected it or failed when it ran out of retries
+func (r *NotificationRepository) MarkEmailUndeliverable(id uuid.UUID, status, errorMessage string) error {
+	query := `
+		UPDATE email_notifications
+		SET status = $2, error_message = $3, retry_count = retry_count + 1,
+			bounced_at = CASE WHEN $2 = 'bounced' THEN NOW() END,
+			failed_at = CASE WHEN $2 = 'failed' THEN NOW() END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Exec(query, id, status, errorMessage); err != nil {
+		return fmt.Errorf("failed to mark email %s: %w", status, err)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bisosad1501/DATN/shared/pkg/personaldata"
+	"github.com/google/uuid"
+)
+
+// personalDataTables are the tables holding a user's rows, in the order they
+// are deleted (logs and deliveries before the notifications they point to)
+var personalDataTables = []personaldata.Table{
+	personaldata.UserTable("notification_logs"),
+	personaldata.UserTable("scheduled_notifications"),
+	personaldata.UserTable("push_notifications"),
+	personaldata.UserTable("email_notifications"),
+	personaldata.UserTable("device_tokens"),
+	personaldata.UserTable("notification_preferences"),
+	personaldata.UserTable("notifications"),
+}
+
+// ExportUserData returns every row held about the user, keyed by table
+func (r *NotificationRepository) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return personaldata.Export(ctx, r.db, userID.String(), personalDataTables)
+}
+
+// EraseUserData deletes every row held about the user
+func (r *NotificationRepository) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range personalDataTables {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s`, t.Name, t.Where), userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", t.Name, err)
+		}
+	}
+
+	return tx.Commit()
+}
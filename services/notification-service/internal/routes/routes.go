@@ -65,6 +65,9 @@ func SetupRoutes(r *gin.Engine, handler *handlers.NotificationHandler, internalH
 	{
 		internal.POST("/send", internalHandler.SendNotificationInternal)     // Send notification from another service
 		internal.POST("/bulk", internalHandler.SendBulkNotificationInternal) // Send bulk notifications from another service
+		internal.POST("/emails", internalHandler.SendEmailInternal)          // Queue a transactional email in the outbox
 		internal.PUT("/preferences/:user_id", internalHandler.UpdatePreferencesInternal) // Update preferences for a user (internal)
+		internal.GET("/users/:user_id/data", internalHandler.ExportUserDataInternal)      // Personal data export
+		internal.DELETE("/users/:user_id/data", internalHandler.EraseUserDataInternal)    // Erase a deleted account's data
 	}
 }
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/email"
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/models"
+	"github.com/bisosad1501/ielts-platform/notification-service/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	emailPollInterval = 5 * time.Second
+	emailBatchSize    = 20
+	// emailSendLease is how long a claimed email is hidden from other workers
+	emailSendLease = 5 * time.Minute
+	emailRetryBase = 30 * time.Second
+	emailRetryMax  = time.Hour
+)
+
+// ErrInvalidEmail means the template, locale or data could not be rendered
+var ErrInvalidEmail = errors.New("invalid email")
+
+// EmailOutbox stores transactional emails and delivers them in the background,
+// so a slow or unavailable mail server never holds up the request that sent
+// one and a failed send is retried instead of lost
+type EmailOutbox struct {
+	repo        *repository.NotificationRepository
+	renderer    *email.Renderer
+	transport   email.Transport
+	users       *client.UserServiceClient
+	from        mail.Address
+	maxAttempts int
+	wake        chan struct{}
+}
+
+func NewEmailOutbox(repo *repository.NotificationRepository, renderer *email.Renderer, transport email.Transport, users *client.UserServiceClient, from mail.Address, maxAttempts int) *EmailOutbox {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &EmailOutbox{
+		repo:        repo,
+		renderer:    renderer,
+		transport:   transport,
+		users:       users,
+		from:        from,
+		maxAttempts: maxAttempts,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// Enqueue renders the template and stores the email for the worker to send.
+// Without a locale, an account gets the email in the language it chose for
+// the site.
+func (o *EmailOutbox) Enqueue(userID *uuid.UUID, to, template, locale string, data map[string]string) (*models.EmailNotification, error) {
+	if locale == "" && userID != nil {
+		locale = o.recipientLocale(*userID)
+	}
+
+	rendered, err := o.renderer.Render(template, locale, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+
+	msg := &models.EmailNotification{
+		ID:              uuid.New(),
+		UserID:          userID,
+		ToEmail:         to,
+		Subject:         rendered.Subject,
+		BodyHTML:        rendered.HTML,
+		BodyText:        &rendered.Text,
+		TemplateName:    &rendered.Template,
+		TemplateVersion: &rendered.Version,
+		Locale:          &rendered.Locale,
+		Status:          models.EmailStatusPending,
+	}
+	if err := o.repo.CreateEmail(msg); err != nil {
+		return nil, err
+	}
+
+	// Send now rather than at the next poll
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return msg, nil
+}
+
+// recipientLocale returns the user's language when the templates are written
+// in it, or "" for the default locale
+func (o *EmailOutbox) recipientLocale(userID uuid.UUID) string {
+	if o.users == nil {
+		return ""
+	}
+	language, err := o.users.GetLanguagePreference(userID.String())
+	if err != nil {
+		log.Printf("[Email-Outbox] WARNING: using the default locale for user %s: %v", userID, err)
+		return ""
+	}
+	if !email.IsLocale(language) {
+		return ""
+	}
+	return language
+}
+
+// Start delivers due emails until ctx is cancelled
+func (o *EmailOutbox) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(emailPollInterval)
+		defer ticker.Stop()
+
+		for {
+			o.deliverDue(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-o.wake:
+			}
+		}
+	}()
+}
+
+// deliverDue sends claimed batches until nothing is due
+func (o *EmailOutbox) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		emails, err := o.repo.ClaimDueEmails(emailBatchSize, emailSendLease)
+		if err != nil {
+			log.Printf("[Email-Outbox] ERROR: %v", err)
+			return
+		}
+		for _, e := range emails {
+			o.deliver(e)
+		}
+		if len(emails) < emailBatchSize {
+			return
+		}
+	}
+}
+
+func (o *EmailOutbox) deliver(e models.EmailNotification) {
+	msg := &email.Message{
+		ID:      e.ID,
+		From:    o.from,
+		To:      e.ToEmail,
+		Subject: e.Subject,
+		HTML:    e.BodyHTML,
+	}
+	if e.BodyText != nil {
+		msg.Text = *e.BodyText
+	}
+
+	sendErr := o.transport.Send(msg)
+	if sendErr == nil {
+		if err := o.repo.MarkEmailSent(e.ID, msg.MessageID()); err != nil {
+			log.Printf("[Email-Outbox] ERROR: %v", err)
+		}
+		emailsTotal.WithLabelValues("sent").Inc()
+		return
+	}
+
+	attempt := e.RetryCount + 1
+	var err error
+	switch {
+	case email.IsPermanent(sendErr):
+		log.Printf("[Email-Outbox] Email %s to %s bounced: %v", e.ID, e.ToEmail, sendErr)
+		err = o.repo.MarkEmailUndeliverable(e.ID, models.EmailStatusBounced, sendErr.Error())
+		emailsTotal.WithLabelValues("bounced").Inc()
+	case attempt >= o.maxAttempts:
+		log.Printf("[Email-Outbox] Giving up on email %s to %s after %d attempts: %v", e.ID, e.ToEmail, attempt, sendErr)
+		err = o.repo.MarkEmailUndeliverable(e.ID, models.EmailStatusFailed, sendErr.Error())
+		emailsTotal.WithLabelValues("failed").Inc()
+	default:
+		delay := emailRetryDelay(attempt)
+		log.Printf("[Email-Outbox] WARNING: Email %s attempt %d failed, retrying in %s: %v", e.ID, attempt, delay, sendErr)
+		err = o.repo.RescheduleEmail(e.ID, sendErr.Error(), time.Now().Add(delay))
+		emailsTotal.WithLabelValues("retried").Inc()
+	}
+	if err != nil {
+		log.Printf("[Email-Outbox] ERROR: %v", err)
+	}
+}
+
+// emailRetryDelay doubles from emailRetryBase after each failed attempt, up
+// to emailRetryMax
+func emailRetryDelay(attempt int) time.Duration {
+	delay := emailRetryBase
+	for i := 1; i < attempt && delay < emailRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > emailRetryMax {
+		delay = emailRetryMax
+	}
+	return delay
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	sseConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "notification_sse_connections",
+		Help: "Open SSE notification streams.",
+	})
+
+	sseSubscribedUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "notification_sse_subscribed_users",
+		Help: "Distinct users with at least one open SSE notification stream.",
+	})
+
+	broadcastsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_sse_messages_total",
+		Help: "Notifications pushed to SSE streams, by result (sent, dropped).",
+	}, []string{"result"})
+
+	emailsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "notification_email_attempts_total",
+		Help: "Outbox email send attempts, by result (sent, retried, bounced, failed).",
+	}, []string{"result"})
+)
@@ -29,10 +29,12 @@ func (nb *NotificationBroadcaster) Subscribe(userID uuid.UUID) chan []byte {
 
 	if nb.clients[userID] == nil {
 		nb.clients[userID] = make(map[chan []byte]bool)
+		sseSubscribedUsers.Inc()
 	}
 
 	ch := make(chan []byte, 10) // Buffer to prevent blocking
 	nb.clients[userID][ch] = true
+	sseConnections.Inc()
 
 	log.Printf("[Notification-Broadcaster] ✅ Subscribed user %s (total clients: %d)", userID, len(nb.clients[userID]))
 	return ch
@@ -44,11 +46,15 @@ func (nb *NotificationBroadcaster) Unsubscribe(userID uuid.UUID, ch chan []byte)
 	defer nb.mu.Unlock()
 
 	if nb.clients[userID] != nil {
+		if nb.clients[userID][ch] {
+			sseConnections.Dec()
+		}
 		delete(nb.clients[userID], ch)
 		close(ch)
 
 		if len(nb.clients[userID]) == 0 {
 			delete(nb.clients, userID)
+			sseSubscribedUsers.Dec()
 		}
 
 		log.Printf("[Notification-Broadcaster] ❌ Unsubscribed user %s (remaining clients: %d)", userID, len(nb.clients[userID]))
@@ -78,8 +84,10 @@ func (nb *NotificationBroadcaster) Broadcast(userID uuid.UUID, notification *mod
 		select {
 		case ch <- data:
 			sentCount++
+			broadcastsTotal.WithLabelValues("sent").Inc()
 		default:
 			// Channel is full, skip
+			broadcastsTotal.WithLabelValues("dropped").Inc()
 			log.Printf("[Notification-Broadcaster] ⚠️ Channel full for user %s, skipping", userID)
 		}
 	}
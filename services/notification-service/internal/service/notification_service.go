@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -691,3 +692,17 @@ func calculateNextSendTime(scheduleType, scheduledTime string, daysOfWeek []int)
 		return nil
 	}
 }
+
+// ExportUserData returns everything stored about the user, for the personal data export
+func (s *NotificationService) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return s.repo.ExportUserData(ctx, userID)
+}
+
+// EraseUserData deletes everything stored about the user when their account is deleted
+func (s *NotificationService) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.EraseUserData(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("✅ Erased notification data of user %s", userID)
+	return nil
+}
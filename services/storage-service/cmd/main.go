@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -8,6 +9,8 @@ import (
 	"github.com/bisosad1501/DATN/services/storage-service/internal/handlers"
 	"github.com/bisosad1501/DATN/services/storage-service/internal/minio"
 	"github.com/bisosad1501/DATN/services/storage-service/internal/routes"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
@@ -19,6 +22,13 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "storage-service")
+	if err != nil {
+		log.Printf("Tracing disabled: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize MinIO client
 	minioClient, err := minio.NewMinIOClient(cfg)
 	if err != nil {
@@ -41,7 +51,9 @@ func main() {
 	}
 
 	router := gin.Default()
-	routes.SetupRoutes(router, storageHandler)
+	router.Use(tracing.Middleware("storage-service"), tracing.RequestID(), metrics.Middleware("storage-service"))
+	router.GET("/metrics", metrics.Handler())
+	routes.SetupRoutes(router, storageHandler, cfg.InternalAPIKey)
 
 	// Start server
 	port := cfg.Server.Port
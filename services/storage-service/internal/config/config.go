@@ -5,8 +5,9 @@ import (
 )
 
 type Config struct {
-	Server ServerConfig
-	MinIO  MinIOConfig
+	Server         ServerConfig
+	MinIO          MinIOConfig
+	InternalAPIKey string
 }
 
 type ServerConfig struct {
@@ -35,6 +36,7 @@ func LoadConfig() *Config {
 			BucketName:     getEnv("MINIO_BUCKET_NAME", "ielts-audio"),
 			UseSSL:         getEnv("MINIO_USE_SSL", "false") == "true",
 		},
+		InternalAPIKey: getEnv("INTERNAL_API_KEY", "internal_secret_key_ielts_2025_change_in_production"),
 	}
 }
 
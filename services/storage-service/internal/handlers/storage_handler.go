@@ -340,3 +340,79 @@ func ValidateAudioExtension(filename string) bool {
 	}
 	return false
 }
+
+// ExportUserData lists a user's recordings (called by Auth Service for data exports)
+// GET /api/v1/storage/internal/users/:user_id/data
+func (h *StorageHandler) ExportUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid user_id",
+		})
+		return
+	}
+
+	objects, err := h.minioClient.ListObjects(c.Request.Context(), userAudioPrefix(userID))
+	if err != nil {
+		log.Printf("❌ Failed to list audio of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to list audio files",
+		})
+		return
+	}
+
+	recordings := make([]gin.H, 0, len(objects))
+	for _, obj := range objects {
+		recordings = append(recordings, gin.H{
+			"object_name":   obj.Key,
+			"size":          obj.Size,
+			"content_type":  obj.ContentType,
+			"last_modified": obj.LastModified.Unix(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"recordings": recordings,
+		},
+	})
+}
+
+// EraseUserData deletes every recording a user uploaded (called by Auth Service for account deletion)
+// DELETE /api/v1/storage/internal/users/:user_id/data
+func (h *StorageHandler) EraseUserData(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"error":   "invalid user_id",
+		})
+		return
+	}
+
+	deleted, err := h.minioClient.DeleteObjects(c.Request.Context(), userAudioPrefix(userID))
+	if err != nil {
+		log.Printf("❌ Failed to delete audio of user %s (%d deleted): %v", userID, deleted, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"error":   "failed to delete audio files",
+		})
+		return
+	}
+
+	log.Printf("✅ Deleted %d audio files of user %s", deleted, userID)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"deleted": deleted,
+		},
+	})
+}
+
+// userAudioPrefix is where UploadAudio stores a user's files
+func userAudioPrefix(userID uuid.UUID) string {
+	return fmt.Sprintf("audio/%s/", userID)
+}
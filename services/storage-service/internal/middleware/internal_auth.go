@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalAuth validates internal API key for service-to-service communication
+func InternalAuth(internalAPIKey string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKey := c.GetHeader("X-Internal-API-Key")
+		if apiKey == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "internal API key required",
+			})
+			c.Abort()
+			return
+		}
+
+		if apiKey != internalAPIKey {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "invalid internal API key",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
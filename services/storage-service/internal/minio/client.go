@@ -76,13 +76,53 @@ func (m *MinIOClient) DeleteObject(objectName string) error {
 	return m.client.RemoveObject(ctx, m.bucketName, objectName, minio.RemoveObjectOptions{})
 }
 
+// ListObjects returns the objects whose names start with prefix
+func (m *MinIOClient) ListObjects(ctx context.Context, prefix string) ([]minio.ObjectInfo, error) {
+	var objects []minio.ObjectInfo
+	for obj := range m.client.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// DeleteObjects deletes every object whose name starts with prefix and
+// returns how many were deleted
+func (m *MinIOClient) DeleteObjects(ctx context.Context, prefix string) (int, error) {
+	objects, err := m.ListObjects(ctx, prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	objectsCh := make(chan minio.ObjectInfo, len(objects))
+	for _, obj := range objects {
+		objectsCh <- obj
+	}
+	close(objectsCh)
+
+	// Drain every error so the removal goroutine can finish
+	var firstErr error
+	failed := 0
+	for removeErr := range m.client.RemoveObjects(ctx, m.bucketName, objectsCh, minio.RemoveObjectsOptions{}) {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("failed to delete %s: %w", removeErr.ObjectName, removeErr.Err)
+		}
+		failed++
+	}
+	return len(objects) - failed, firstErr
+}
+
 // UploadObject uploads an object to MinIO
 func (m *MinIOClient) UploadObject(objectName string, reader io.Reader, objectSize int64, contentType string) error {
 	ctx := context.Background()
+	start := time.Now()
 
-	_, err := m.client.PutObject(ctx, m.bucketName, objectName, reader, objectSize, minio.PutObjectOptions{
+	info, err := m.client.PutObject(ctx, m.bucketName, objectName, reader, objectSize, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
+	observeUpload(start, info.Size, err)
 
 	return err
 }
@@ -0,0 +1,37 @@
+package minio
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	uploadsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "storage_uploads_total",
+		Help: "Object uploads to MinIO, by status (success, error).",
+	}, []string{"status"})
+
+	uploadBytesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "storage_upload_bytes_total",
+		Help: "Bytes successfully uploaded to MinIO.",
+	})
+
+	uploadDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "storage_upload_duration_seconds",
+		Help:    "Latency of MinIO PutObject calls.",
+		Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	})
+)
+
+// observeUpload records one PutObject call started at start
+func observeUpload(start time.Time, size int64, err error) {
+	uploadDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		uploadsTotal.WithLabelValues("error").Inc()
+		return
+	}
+	uploadsTotal.WithLabelValues("success").Inc()
+	uploadBytesTotal.Add(float64(size))
+}
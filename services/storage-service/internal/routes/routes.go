@@ -2,10 +2,11 @@ package routes
 
 import (
 	"github.com/bisosad1501/DATN/services/storage-service/internal/handlers"
+	"github.com/bisosad1501/DATN/services/storage-service/internal/middleware"
 	"github.com/gin-gonic/gin"
 )
 
-func SetupRoutes(router *gin.Engine, handler *handlers.StorageHandler) {
+func SetupRoutes(router *gin.Engine, handler *handlers.StorageHandler, internalAPIKey string) {
 	// Health check
 	router.GET("/health", handler.HealthCheck)
 
@@ -31,6 +32,15 @@ func SetupRoutes(router *gin.Engine, handler *handlers.StorageHandler) {
 				// Delete audio (use *object_name to match full path with slashes)
 				audio.DELETE("/*object_name", handler.DeleteAudio)
 			}
+
+			// Internal routes (service-to-service, require API key)
+			internal := storage.Group("/internal")
+			internal.Use(middleware.InternalAuth(internalAPIKey))
+			{
+				// Personal data export and erasure (account deletion)
+				internal.GET("/users/:user_id/data", handler.ExportUserData)
+				internal.DELETE("/users/:user_id/data", handler.EraseUserData)
+			}
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/bisosad1501/DATN/services/user-service/internal/repository"
 	"github.com/bisosad1501/DATN/services/user-service/internal/routes"
 	"github.com/bisosad1501/DATN/services/user-service/internal/service"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 )
 
 func main() {
@@ -22,6 +24,13 @@ func main() {
 	// Load configuration
 	cfg := config.LoadConfig()
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "user-service")
+	if err != nil {
+		log.Printf("⚠️ Tracing disabled: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
 	// Initialize database
 	db, err := database.NewDatabase(cfg)
 	if err != nil {
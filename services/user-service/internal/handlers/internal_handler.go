@@ -136,7 +136,7 @@ func (h *InternalHandler) UpdateProgressInternal(c *gin.Context) {
 		updates["study_minutes"] = req.StudyMinutes
 	}
 
-	if err := h.userService.UpdateProgress(userID, updates); err != nil {
+	if err := h.userService.UpdateProgress(c.Request.Context(), userID, updates); err != nil {
 		log.Printf("❌ Failed to update progress for user %s: %v", req.UserID, err)
 		c.JSON(http.StatusInternalServerError, models.Response{
 			Success: false,
@@ -265,7 +265,7 @@ func (h *InternalHandler) UpdateSkillStatisticsInternal(c *gin.Context) {
 		updates["total_practices"] = req.TotalPractices
 	}
 
-	if err := h.userService.UpdateSkillStatistics(userID, skillType, updates); err != nil {
+	if err := h.userService.UpdateSkillStatistics(c.Request.Context(), userID, skillType, updates); err != nil {
 		log.Printf("❌ Failed to update %s statistics for user %s: %v", skillType, req.UserID, err)
 		c.JSON(http.StatusInternalServerError, models.Response{
 			Success: false,
@@ -521,3 +521,140 @@ func (h *InternalHandler) RecordCompletedSessionInternal(c *gin.Context) {
 		Message: "Completed session recorded successfully",
 	})
 }
+
+// ExportUserDataInternal returns everything stored about a user (called by Auth Service for data exports)
+func (h *InternalHandler) ExportUserDataInternal(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	data, err := h.userService.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("❌ Failed to export data of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "EXPORT_FAILED",
+				Message: "Failed to export user data",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    data,
+	})
+}
+
+// EraseUserDataInternal deletes everything stored about a user (called by Auth Service for account deletion)
+func (h *InternalHandler) EraseUserDataInternal(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	if err := h.userService.EraseUserData(c.Request.Context(), userID); err != nil {
+		log.Printf("❌ Failed to erase data of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "ERASE_FAILED",
+				Message: "Failed to erase user data",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Message: "User data erased",
+	})
+}
+
+// GetProgressSummariesInternal returns the progress of several learners (called by Auth Service for class progress)
+func (h *InternalHandler) GetProgressSummariesInternal(c *gin.Context) {
+	var req models.ProgressSummariesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "INVALID_REQUEST",
+				Message: "Invalid request body",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	summaries, err := h.userService.GetProgressSummaries(req.UserIDs)
+	if err != nil {
+		log.Printf("❌ Failed to get progress summaries: %v", err)
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to get progress summaries",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    summaries,
+	})
+}
+
+// GetLanguagePreferenceInternal returns the language a user chose for the site
+// (called by Notification Service to pick the locale of an email)
+func (h *InternalHandler) GetLanguagePreferenceInternal(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "INVALID_USER_ID",
+				Message: "Invalid user ID format",
+			},
+		})
+		return
+	}
+
+	language, err := h.userService.GetLanguagePreference(userID)
+	if err != nil {
+		log.Printf("❌ Failed to get language preference of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Success: false,
+			Error: &models.ErrorInfo{
+				Code:    "INTERNAL_ERROR",
+				Message: "Failed to get language preference",
+				Details: err.Error(),
+			},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Success: true,
+		Data:    gin.H{"language_preference": language},
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
 	"strconv"
@@ -42,11 +43,24 @@ type RecordTestResultRequest struct {
 	Notes          *string    `json:"notes,omitempty"`
 }
 
+// RecordTestResultSetRequest records the four skills of one sitting, such as
+// a mock test, together. Results take the set's test type and date.
+type RecordTestResultSetRequest struct {
+	TestType      string                    `json:"test_type" binding:"required,oneof=full_test mock_test"`
+	IELTSVariant  string                    `json:"ielts_variant" binding:"required,oneof=academic general_training"`
+	SourceService string                    `json:"source_service,omitempty"`
+	SourceID      uuid.UUID                 `json:"source_id" binding:"required"`
+	TestDate      time.Time                 `json:"test_date"`
+	ScoreReport   json.RawMessage           `json:"score_report,omitempty"`
+	Results       []RecordTestResultRequest `json:"results" binding:"required,len=4"`
+}
+
 type RecordPracticeActivityRequest struct {
 	Skill              string     `json:"skill" validate:"required,oneof=listening reading writing speaking"`
 	ActivityType       string     `json:"activity_type" validate:"required,oneof=drill part_test section_practice question_set"`
 	ExerciseID         *uuid.UUID `json:"exercise_id,omitempty"`
 	ExerciseTitle      *string    `json:"exercise_title,omitempty"`
+	SourceID           *uuid.UUID `json:"source_id,omitempty"` // e.g. the exercise attempt, for later corrections
 	Score              *float64   `json:"score,omitempty"`
 	MaxScore           *float64   `json:"max_score,omitempty"`
 	BandScore          *float64   `json:"band_score,omitempty"`
@@ -82,95 +96,105 @@ func (h *ScoringHandler) RecordTestResultInternal(c *gin.Context) {
 		return
 	}
 
-	// Create OfficialTestResult model (per-skill)
-	sourceService := req.SourceService
-	if sourceService == "" {
-		sourceService = "exercise_service" // default
-	}
-	sourceTable := req.SourceTable
-	if sourceTable == "" {
-		sourceTable = "user_exercise_attempts"
-	}
-
-	// Validate business rules
-	if req.SkillType == "reading" && req.IELTSVariant == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ielts_variant is required for reading tests"})
+	result, errMsg := testResultFromRequest(userID, &req)
+	if errMsg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errMsg})
 		return
 	}
-	if req.SkillType != "reading" && req.IELTSVariant != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "ielts_variant should only be set for reading tests"})
+
+	// Record test result
+	if err := h.service.RecordOfficialTestResult(result); err != nil {
+		log.Printf("❌ Error recording test result: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record test result"})
 		return
 	}
 
-	// FIX #10: Validate that EITHER band_score OR raw_score is provided
-	// Valid IELTS bands: 1.0, 1.5, 2.0, ..., 8.5, 9.0 (0 is NOT valid)
-	bandScore := req.BandScore
-	hasValidBandScore := bandScore >= 1.0 && bandScore <= 9.0
-	hasRawScore := req.RawScore != nil && req.TotalQuestions != nil
+	// Return success response
+	c.JSON(http.StatusCreated, gin.H{
+		"success":        true,
+		"test_result_id": result.ID,
+		"message":        "Test result recorded successfully",
+	})
+}
 
-	if !hasValidBandScore && !hasRawScore {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "must provide EITHER band_score (1.0-9.0) OR (raw_score + total_questions)",
-		})
+// RecordTestResultSetInternal records a four-skill sitting with its overall
+// band (internal service-to-service). Sending the same source again returns
+// the set already recorded.
+func (h *ScoringHandler) RecordTestResultSetInternal(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
 		return
 	}
 
-	// Calculate band_score if not provided (but raw_score is)
-	if bandScore == 0 && req.RawScore != nil && req.TotalQuestions != nil {
-		// Calculate from raw score using IELTS conversion tables
-		switch req.SkillType {
-		case "listening":
-			bandScore = ielts.ConvertListeningScore(*req.RawScore, *req.TotalQuestions)
-		case "reading":
-			// Use ielts_variant to determine conversion table
-			testType := "academic" // default
-			if req.IELTSVariant != nil && *req.IELTSVariant == "general_training" {
-				testType = "general"
-			}
-			bandScore = ielts.ConvertReadingScore(*req.RawScore, *req.TotalQuestions, testType)
-		default:
-			// Writing/Speaking must provide band_score (calculated by AI)
-			if bandScore == 0 {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "band_score is required for writing/speaking tests"})
-				return
-			}
-		}
-		log.Printf("✅ Calculated band score from raw score: %d/%d = %.1f", *req.RawScore, *req.TotalQuestions, bandScore)
+	var req RecordTestResultSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+	if req.SourceService == "" {
+		req.SourceService = "exercise_service"
+	}
+	if req.TestDate.IsZero() {
+		req.TestDate = time.Now()
 	}
 
-	result := &models.OfficialTestResult{
-		UserID:           userID,
-		TestType:         req.TestType,
-		SkillType:        req.SkillType,
-		IELTSVariant:     req.IELTSVariant,
-		BandScore:        bandScore,
-		RawScore:         req.RawScore,
-		TotalQuestions:   req.TotalQuestions,
-		SourceService:    &sourceService,
-		SourceTable:      &sourceTable,
-		SourceID:         req.SourceID,
-		TestDate:         req.TestDate,
-		CompletionStatus: "completed",
-		TestSource:       &req.TestSource,
-		Notes:            req.Notes,
+	set := &models.TestResultSet{
+		UserID:        userID,
+		TestType:      req.TestType,
+		IELTSVariant:  req.IELTSVariant,
+		TestDate:      req.TestDate,
+		ScoreReport:   req.ScoreReport,
+		SourceService: req.SourceService,
+		SourceID:      req.SourceID,
 	}
+	skills := map[string]bool{}
+	for i := range req.Results {
+		resultReq := &req.Results[i]
+		resultReq.TestType = req.TestType
+		resultReq.TestDate = req.TestDate
+		if resultReq.SourceService == "" {
+			resultReq.SourceService = req.SourceService
+		}
+		if resultReq.SkillType == "reading" && resultReq.IELTSVariant == nil {
+			resultReq.IELTSVariant = &req.IELTSVariant
+		}
+		if skills[resultReq.SkillType] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "results must cover listening, reading, writing and speaking once each"})
+			return
+		}
+		skills[resultReq.SkillType] = true
 
-	if req.TestDate.IsZero() {
-		result.TestDate = time.Now()
+		result, errMsg := testResultFromRequest(userID, resultReq)
+		if errMsg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": resultReq.SkillType + ": " + errMsg})
+			return
+		}
+		set.Results = append(set.Results, *result)
+	}
+	for _, skill := range []string{"listening", "reading", "writing", "speaking"} {
+		if !skills[skill] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "results must cover listening, reading, writing and speaking once each"})
+			return
+		}
 	}
 
-	// Record test result
-	if err := h.service.RecordOfficialTestResult(result); err != nil {
-		log.Printf("❌ Error recording test result: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record test result"})
+	created, err := h.service.RecordTestResultSet(set)
+	if err != nil {
+		log.Printf("❌ Error recording test result set: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record test result set"})
 		return
 	}
 
-	// Return success response
-	c.JSON(http.StatusCreated, gin.H{
-		"success":        true,
-		"test_result_id": result.ID,
-		"message":        "Test result recorded successfully",
+	status := http.StatusCreated
+	if !created {
+		status = http.StatusOK
+	}
+	c.JSON(status, gin.H{
+		"success":      true,
+		"test_set_id":  set.ID,
+		"overall_band": set.OverallBand,
+		"created":      created,
 	})
 }
 
@@ -198,6 +222,7 @@ func (h *ScoringHandler) RecordPracticeActivityInternal(c *gin.Context) {
 		ActivityType:       req.ActivityType,
 		ExerciseID:         req.ExerciseID,
 		ExerciseTitle:      req.ExerciseTitle,
+		SourceID:           req.SourceID,
 		Score:              req.Score,
 		MaxScore:           req.MaxScore,
 		BandScore:          req.BandScore,
@@ -215,7 +240,7 @@ func (h *ScoringHandler) RecordPracticeActivityInternal(c *gin.Context) {
 	}
 
 	// Record practice activity
-	if err := h.service.RecordPracticeActivity(activity); err != nil {
+	if err := h.service.RecordPracticeActivity(c.Request.Context(), activity); err != nil {
 		log.Printf("❌ Error recording practice activity: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record practice activity"})
 		return
@@ -229,6 +254,45 @@ func (h *ScoringHandler) RecordPracticeActivityInternal(c *gin.Context) {
 	})
 }
 
+// CorrectBandScoreRequest replaces the band recorded from a source record
+type CorrectBandScoreRequest struct {
+	SourceID  uuid.UUID `json:"source_id" binding:"required"`
+	SkillType string    `json:"skill_type" binding:"required,oneof=listening reading writing speaking"`
+	BandScore float64   `json:"band_score" binding:"min=0,max=9"`
+}
+
+// CorrectBandScoreInternal corrects the band of a recorded test result or
+// practice activity (internal service-to-service)
+func (h *ScoringHandler) CorrectBandScoreInternal(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req CorrectBandScoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body", "details": err.Error()})
+		return
+	}
+
+	corrected, err := h.service.CorrectBandScore(userID, req.SourceID, req.SkillType, req.BandScore)
+	if err != nil {
+		log.Printf("❌ Error correcting band score: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to correct band score"})
+		return
+	}
+	if !corrected {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No result was recorded from this source"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Band score corrected successfully",
+	})
+}
+
 // GetUserTestHistory retrieves user's test history with pagination
 func (h *ScoringHandler) GetUserTestHistory(c *gin.Context) {
 	// Extract user_id from path
@@ -304,3 +368,80 @@ func (h *ScoringHandler) GetUserPracticeStatistics(c *gin.Context) {
 		"message": "Practice statistics retrieved (placeholder)",
 	})
 }
+
+// testResultFromRequest builds the per-skill result to record, working out
+// the band from the raw score when none was given. It returns the reason a
+// request is invalid instead of a result.
+func testResultFromRequest(userID uuid.UUID, req *RecordTestResultRequest) (*models.OfficialTestResult, string) {
+	sourceService := req.SourceService
+	if sourceService == "" {
+		sourceService = "exercise_service" // default
+	}
+	sourceTable := req.SourceTable
+	if sourceTable == "" {
+		sourceTable = "user_exercise_attempts"
+	}
+
+	// Validate business rules
+	if req.SkillType == "reading" && req.IELTSVariant == nil {
+		return nil, "ielts_variant is required for reading tests"
+	}
+	if req.SkillType != "reading" && req.IELTSVariant != nil {
+		return nil, "ielts_variant should only be set for reading tests"
+	}
+
+	// FIX #10: Validate that EITHER band_score OR raw_score is provided
+	// Valid IELTS bands: 1.0, 1.5, 2.0, ..., 8.5, 9.0 (0 is NOT valid)
+	bandScore := req.BandScore
+	hasValidBandScore := bandScore >= 1.0 && bandScore <= 9.0
+	hasRawScore := req.RawScore != nil && req.TotalQuestions != nil
+
+	if !hasValidBandScore && !hasRawScore {
+		return nil, "must provide EITHER band_score (1.0-9.0) OR (raw_score + total_questions)"
+	}
+
+	// Calculate band_score if not provided (but raw_score is)
+	if bandScore == 0 && req.RawScore != nil && req.TotalQuestions != nil {
+		// Calculate from raw score using IELTS conversion tables
+		switch req.SkillType {
+		case "listening":
+			bandScore = ielts.ConvertListeningScore(*req.RawScore, *req.TotalQuestions)
+		case "reading":
+			// Use ielts_variant to determine conversion table
+			testType := "academic" // default
+			if req.IELTSVariant != nil && *req.IELTSVariant == "general_training" {
+				testType = "general"
+			}
+			bandScore = ielts.ConvertReadingScore(*req.RawScore, *req.TotalQuestions, testType)
+		default:
+			// Writing/Speaking must provide band_score (calculated by AI)
+			if bandScore == 0 {
+				return nil, "band_score is required for writing/speaking tests"
+			}
+		}
+		log.Printf("✅ Calculated band score from raw score: %d/%d = %.1f", *req.RawScore, *req.TotalQuestions, bandScore)
+	}
+
+	result := &models.OfficialTestResult{
+		UserID:           userID,
+		TestType:         req.TestType,
+		SkillType:        req.SkillType,
+		IELTSVariant:     req.IELTSVariant,
+		BandScore:        bandScore,
+		RawScore:         req.RawScore,
+		TotalQuestions:   req.TotalQuestions,
+		SourceService:    &sourceService,
+		SourceTable:      &sourceTable,
+		SourceID:         req.SourceID,
+		TestDate:         req.TestDate,
+		CompletionStatus: "completed",
+		TestSource:       &req.TestSource,
+		Notes:            req.Notes,
+	}
+
+	if req.TestDate.IsZero() {
+		result.TestDate = time.Now()
+	}
+
+	return result, ""
+}
@@ -7,18 +7,19 @@ import (
 
 	"github.com/bisosad1501/DATN/services/user-service/internal/config"
 	"github.com/bisosad1501/DATN/services/user-service/internal/models"
+	"github.com/bisosad1501/DATN/shared/pkg/jwks"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthMiddleware struct {
-	jwtSecret      string
+	keySet         *jwks.KeySet
 	internalAPIKey string
 }
 
 func NewAuthMiddleware(cfg *config.Config) *AuthMiddleware {
 	return &AuthMiddleware{
-		jwtSecret:      cfg.JWTSecret,
+		keySet:         jwks.NewKeySet(cfg.JWKSURL),
 		internalAPIKey: cfg.InternalAPIKey,
 	}
 }
@@ -54,12 +55,7 @@ func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 		}
 
 		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.Parse(tokenString, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, models.Response{
@@ -152,12 +148,7 @@ func (m *AuthMiddleware) OptionalAuth() gin.HandlerFunc {
 		}
 
 		// Try to parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(m.jwtSecret), nil
-		})
+		token, err := jwt.Parse(tokenString, m.keySet.Keyfunc, jwks.ParserOptions()...)
 
 		// If token is valid, set user context
 		if err == nil && token.Valid {
@@ -159,3 +159,34 @@ type StatisticsResponse struct {
 	WeakSkills         []string                    `json:"weak_skills"`
 	StrongSkills       []string                    `json:"strong_skills"`
 }
+
+// ============= Class Progress DTOs =============
+
+// ProgressSummariesRequest asks for the progress of several learners at once
+type ProgressSummariesRequest struct {
+	UserIDs []uuid.UUID `json:"user_ids" binding:"required,min=1,max=500"`
+}
+
+// ProgressSummary is one learner's progress as a teacher sees it on a class roster
+type ProgressSummary struct {
+	UserID                  uuid.UUID               `json:"user_id"`
+	FullName                *string                 `json:"full_name,omitempty"`
+	TotalStudyHours         float64                 `json:"total_study_hours"`
+	TotalLessonsCompleted   int                     `json:"total_lessons_completed"`
+	TotalExercisesCompleted int                     `json:"total_exercises_completed"`
+	OverallScore            *float64                `json:"overall_score,omitempty"`
+	CurrentStreakDays       int                     `json:"current_streak_days"`
+	LastStudyDate           *time.Time              `json:"last_study_date,omitempty"`
+	Skills                  map[string]SkillSummary `json:"skills"` // keyed by skill type
+}
+
+// SkillSummary combines a skill's learning_progress columns with its skill_statistics row
+type SkillSummary struct {
+	Progress         float64    `json:"progress"`
+	BandScore        *float64   `json:"band_score,omitempty"`
+	TotalPractices   int        `json:"total_practices"`
+	AverageScore     float64    `json:"average_score"`
+	BestScore        float64    `json:"best_score"`
+	TotalTimeMinutes int        `json:"total_time_minutes"`
+	LastPracticeDate *time.Time `json:"last_practice_date,omitempty"`
+}
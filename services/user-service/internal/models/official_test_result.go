@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -37,6 +38,9 @@ type OfficialTestResult struct {
 	SourceTable   *string    `json:"source_table,omitempty" db:"source_table"`     // user_exercise_attempts
 	SourceID      *uuid.UUID `json:"source_id,omitempty" db:"source_id"`           // submission_id
 
+	// Set of results this belongs to when the four skills were sat together
+	TestSetID *uuid.UUID `json:"test_set_id,omitempty" db:"test_set_id"`
+
 	// Test metadata
 	TestDate            time.Time `json:"test_date" db:"test_date"`
 	TestDurationMinutes *int      `json:"test_duration_minutes,omitempty" db:"test_duration_minutes" validate:"omitempty,min=0"`
@@ -60,3 +64,33 @@ func (o *OfficialTestResult) Validate() error {
 	// This could include checking if overall score matches average of skill scores
 	return nil
 }
+
+// TestResultSet is one sitting of all four skills, such as a mock test. Its
+// skill results are OfficialTestResults with the set's TestSetID.
+type TestResultSet struct {
+	ID            uuid.UUID `json:"id" db:"id"`
+	UserID        uuid.UUID `json:"user_id" db:"user_id"`
+	TestType      string    `json:"test_type" db:"test_type"`
+	IELTSVariant  string    `json:"ielts_variant" db:"ielts_variant"`
+	TestDate      time.Time `json:"test_date" db:"test_date"`
+	ListeningBand float64   `json:"listening_band" db:"listening_band"`
+	ReadingBand   float64   `json:"reading_band" db:"reading_band"`
+	WritingBand   float64   `json:"writing_band" db:"writing_band"`
+	SpeakingBand  float64   `json:"speaking_band" db:"speaking_band"`
+	OverallBand   float64   `json:"overall_band" db:"overall_band"`
+
+	// Combined score report from the source service
+	ScoreReport json.RawMessage `json:"score_report,omitempty" db:"score_report"`
+
+	SourceService string    `json:"source_service" db:"source_service"`
+	SourceID      uuid.UUID `json:"source_id" db:"source_id"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+
+	Results []OfficialTestResult `json:"results,omitempty"`
+}
+
+// TableName returns the table name for TestResultSet
+func (TestResultSet) TableName() string {
+	return "test_result_sets"
+}
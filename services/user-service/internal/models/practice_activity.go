@@ -22,6 +22,7 @@ type PracticeActivity struct {
 	// Exercise reference (from exercise_db)
 	ExerciseID    *uuid.UUID `json:"exercise_id,omitempty" db:"exercise_id"`
 	ExerciseTitle *string    `json:"exercise_title,omitempty" db:"exercise_title"`
+	SourceID      *uuid.UUID `json:"source_id,omitempty" db:"source_id"` // Originating record, e.g. an exercise attempt
 
 	// Performance metrics
 	Score     *float64 `json:"score,omitempty" db:"score"`         // Raw score (can be percentage, count, etc.)
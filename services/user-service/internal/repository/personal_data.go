@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/bisosad1501/DATN/shared/pkg/personaldata"
+	"github.com/google/uuid"
+)
+
+// personalDataTables are the tables exported when a user asks for their data.
+// All of them reference user_profiles, so deleting the profile erases them.
+var personalDataTables = []personaldata.Table{
+	personaldata.UserTable("user_profiles"),
+	personaldata.UserTable("learning_progress"),
+	personaldata.UserTable("skill_statistics"),
+	personaldata.UserTable("practice_activities"),
+	personaldata.UserTable("official_test_results"),
+	personaldata.UserTable("test_result_sets"),
+	personaldata.UserTable("study_sessions"),
+	personaldata.UserTable("study_goals"),
+	personaldata.UserTable("study_reminders"),
+	personaldata.UserTable("user_achievements"),
+	personaldata.UserTable("user_preferences"),
+	{Name: "user_follows", Where: "follower_id = $1 OR following_id = $1"},
+}
+
+// ExportUserData returns every row held about the user, keyed by table
+func (r *UserRepository) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return personaldata.Export(ctx, r.db.DB, userID.String(), personalDataTables)
+}
+
+// EraseUserData deletes the user's profile; the rest cascades
+func (r *UserRepository) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	if _, err := r.db.DB.ExecContext(ctx, `DELETE FROM user_profiles WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to erase user data: %w", err)
+	}
+	return nil
+}
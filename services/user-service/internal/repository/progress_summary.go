@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bisosad1501/DATN/services/user-service/internal/models"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// GetProgressSummaries returns the progress of each given user in request
+// order. Users who have not studied yet get an empty summary rather than
+// being left out, so a class roster always lines up with the result.
+func (r *UserRepository) GetProgressSummaries(userIDs []uuid.UUID) ([]models.ProgressSummary, error) {
+	ids := make(pq.StringArray, len(userIDs))
+	for i, id := range userIDs {
+		ids[i] = id.String()
+	}
+
+	query := `
+		SELECT
+			u.user_id, up.full_name,
+			COALESCE(lp.total_lessons_completed, 0), COALESCE(lp.total_exercises_completed, 0),
+			COALESCE(lp.listening_progress, 0), COALESCE(lp.reading_progress, 0),
+			COALESCE(lp.writing_progress, 0), COALESCE(lp.speaking_progress, 0),
+			lp.listening_score, lp.reading_score, lp.writing_score, lp.speaking_score,
+			lp.overall_score, COALESCE(lp.current_streak_days, 0), lp.last_study_date,
+			COALESCE((
+				SELECT ROUND((SUM(ss.duration_minutes) / 60.0)::numeric, 2)
+				FROM study_sessions ss WHERE ss.user_id = u.user_id
+			), 0)
+		FROM unnest($1::uuid[]) WITH ORDINALITY AS u(user_id, position)
+		LEFT JOIN user_profiles up ON up.user_id = u.user_id
+		LEFT JOIN learning_progress lp ON lp.user_id = u.user_id
+		ORDER BY u.position
+	`
+
+	rows, err := r.db.DB.Query(query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get progress summaries: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := make([]models.ProgressSummary, 0, len(userIDs))
+	index := make(map[uuid.UUID]int, len(userIDs))
+	for rows.Next() {
+		var (
+			s                                                     models.ProgressSummary
+			listening, reading, writing, speaking                 float64
+			listeningBand, readingBand, writingBand, speakingBand *float64
+		)
+		if err := rows.Scan(
+			&s.UserID, &s.FullName,
+			&s.TotalLessonsCompleted, &s.TotalExercisesCompleted,
+			&listening, &reading, &writing, &speaking,
+			&listeningBand, &readingBand, &writingBand, &speakingBand,
+			&s.OverallScore, &s.CurrentStreakDays, &s.LastStudyDate,
+			&s.TotalStudyHours,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan progress summary: %w", err)
+		}
+		s.Skills = map[string]models.SkillSummary{
+			"listening": {Progress: listening, BandScore: listeningBand},
+			"reading":   {Progress: reading, BandScore: readingBand},
+			"writing":   {Progress: writing, BandScore: writingBand},
+			"speaking":  {Progress: speaking, BandScore: speakingBand},
+		}
+		index[s.UserID] = len(summaries)
+		summaries = append(summaries, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get progress summaries: %w", err)
+	}
+
+	statsQuery := `
+		SELECT user_id, skill_type, total_practices, average_score, best_score,
+		       total_time_minutes, last_practice_date
+		FROM skill_statistics
+		WHERE user_id = ANY($1::uuid[])
+	`
+
+	statsRows, err := r.db.DB.Query(statsQuery, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get skill statistics: %w", err)
+	}
+	defer statsRows.Close()
+
+	for statsRows.Next() {
+		var (
+			userID           uuid.UUID
+			skillType        string
+			totalPractices   int
+			averageScore     float64
+			bestScore        float64
+			totalTimeMinutes int
+			lastPracticeDate *time.Time
+		)
+		if err := statsRows.Scan(
+			&userID, &skillType, &totalPractices, &averageScore, &bestScore,
+			&totalTimeMinutes, &lastPracticeDate,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan skill statistics: %w", err)
+		}
+
+		i, ok := index[userID]
+		if !ok {
+			continue
+		}
+		skill := summaries[i].Skills[skillType]
+		skill.TotalPractices = totalPractices
+		skill.AverageScore = averageScore
+		skill.BestScore = bestScore
+		skill.TotalTimeMinutes = totalTimeMinutes
+		skill.LastPracticeDate = lastPracticeDate
+		summaries[i].Skills[skillType] = skill
+	}
+	if err := statsRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get skill statistics: %w", err)
+	}
+
+	return summaries, nil
+}
@@ -62,9 +62,9 @@ func (r *UserRepository) CreateOfficialTestResultTx(tx *sql.Tx, result *models.O
 			raw_score, total_questions,
 			source_service, source_table, source_id,
 			test_date, test_duration_minutes, completion_status,
-			test_source, notes
+			test_source, notes, test_set_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		) RETURNING id, created_at, updated_at`
 
 	err := tx.QueryRow(
@@ -84,6 +84,7 @@ func (r *UserRepository) CreateOfficialTestResultTx(tx *sql.Tx, result *models.O
 		result.CompletionStatus,
 		result.TestSource,
 		result.Notes,
+		result.TestSetID,
 	).Scan(&result.ID, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
@@ -1943,9 +1944,9 @@ func (r *UserRepository) CreateOfficialTestResult(result *models.OfficialTestRes
 			raw_score, total_questions,
 			source_service, source_table, source_id,
 			test_date, test_duration_minutes, completion_status,
-			test_source, notes
+			test_source, notes, test_set_id
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
 		) RETURNING id, created_at, updated_at`
 
 	err := r.db.DB.QueryRow(
@@ -1965,6 +1966,7 @@ func (r *UserRepository) CreateOfficialTestResult(result *models.OfficialTestRes
 		result.CompletionStatus,
 		result.TestSource,
 		result.Notes,
+		result.TestSetID,
 	).Scan(&result.ID, &result.CreatedAt, &result.UpdatedAt)
 
 	if err != nil {
@@ -2007,7 +2009,7 @@ func (r *UserRepository) GetUserTestHistory(userID uuid.UUID, skillType *string,
 			   raw_score, total_questions,
 			   source_service, source_table, source_id,
 			   test_date, test_duration_minutes, completion_status,
-			   test_source, notes, created_at, updated_at
+			   test_source, notes, test_set_id, created_at, updated_at
 		FROM official_test_results
 		%s
 		ORDER BY test_date DESC
@@ -2040,6 +2042,7 @@ func (r *UserRepository) GetUserTestHistory(userID uuid.UUID, skillType *string,
 			&result.CompletionStatus,
 			&result.TestSource,
 			&result.Notes,
+			&result.TestSetID,
 			&result.CreatedAt,
 			&result.UpdatedAt,
 		)
@@ -2124,10 +2127,10 @@ func (r *UserRepository) CreatePracticeActivity(activity *models.PracticeActivit
 			correct_answers, total_questions, accuracy_percentage,
 			time_spent_seconds, started_at, completed_at,
 			completion_status, ai_evaluated, ai_feedback_summary,
-			difficulty_level, tags, notes
+			difficulty_level, tags, notes, source_id
 		) VALUES (
 			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
-			$11, $12, $13, $14, $15, $16, $17, $18, $19, $20
+			$11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21
 		) RETURNING id, created_at, updated_at`
 
 	err := r.db.DB.QueryRow(
@@ -2152,6 +2155,7 @@ func (r *UserRepository) CreatePracticeActivity(activity *models.PracticeActivit
 		activity.DifficultyLevel,
 		activity.Tags,
 		activity.Notes,
+		activity.SourceID,
 	).Scan(&activity.ID, &activity.CreatedAt, &activity.UpdatedAt)
 
 	if err != nil {
@@ -2398,3 +2402,138 @@ func (r *UserRepository) UpdateLearningProgressWithTestScore(
 	log.Printf("✅ Updated learning progress for user %s: %s = %.1f", userID, skillType, bandScore)
 	return nil
 }
+
+// ============= Score Corrections =============
+
+// CorrectOfficialTestResultTx changes the band of the test result recorded
+// from a source record and reports whether there was one
+func (r *UserRepository) CorrectOfficialTestResultTx(tx *sql.Tx, userID, sourceID uuid.UUID, skillType string, bandScore float64) (bool, error) {
+	result, err := tx.Exec(`
+		UPDATE official_test_results
+		SET band_score = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2 AND source_id = $3 AND skill_type = $4
+	`, bandScore, userID, sourceID, skillType)
+	if err != nil {
+		return false, fmt.Errorf("failed to correct test result: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// GetLatestTestBandTx returns the band of the user's latest test result for a skill
+func (r *UserRepository) GetLatestTestBandTx(tx *sql.Tx, userID uuid.UUID, skillType string) (float64, error) {
+	var band float64
+	err := tx.QueryRow(`
+		SELECT band_score FROM official_test_results
+		WHERE user_id = $1 AND skill_type = $2
+		ORDER BY test_date DESC, created_at DESC
+		LIMIT 1
+	`, userID, skillType).Scan(&band)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest test result: %w", err)
+	}
+	return band, nil
+}
+
+// CreateTestResultSetTx records a four-skill sitting. A set already recorded
+// from the same source is loaded into set instead, and false is returned.
+func (r *UserRepository) CreateTestResultSetTx(tx *sql.Tx, set *models.TestResultSet) (bool, error) {
+	var scoreReport interface{}
+	if len(set.ScoreReport) > 0 {
+		scoreReport = []byte(set.ScoreReport)
+	}
+	err := tx.QueryRow(`
+		INSERT INTO test_result_sets (
+			user_id, test_type, ielts_variant, test_date,
+			listening_band, reading_band, writing_band, speaking_band, overall_band,
+			score_report, source_service, source_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (source_service, source_id) DO NOTHING
+		RETURNING id, created_at, updated_at
+	`, set.UserID, set.TestType, set.IELTSVariant, set.TestDate,
+		set.ListeningBand, set.ReadingBand, set.WritingBand, set.SpeakingBand, set.OverallBand,
+		scoreReport, set.SourceService, set.SourceID,
+	).Scan(&set.ID, &set.CreatedAt, &set.UpdatedAt)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to create test result set: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		SELECT id, listening_band, reading_band, writing_band, speaking_band, overall_band, created_at, updated_at
+		FROM test_result_sets
+		WHERE source_service = $1 AND source_id = $2
+	`, set.SourceService, set.SourceID).Scan(
+		&set.ID, &set.ListeningBand, &set.ReadingBand, &set.WritingBand, &set.SpeakingBand,
+		&set.OverallBand, &set.CreatedAt, &set.UpdatedAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to get test result set: %w", err)
+	}
+	return false, nil
+}
+
+// CorrectTestResultSetBandTx changes a skill's band on the set holding the
+// user's test result from sourceID and returns the set, or nil when the
+// result is not part of one
+func (r *UserRepository) CorrectTestResultSetBandTx(tx *sql.Tx, userID, sourceID uuid.UUID, skillType string, bandScore float64) (*models.TestResultSet, error) {
+	var bandColumn string
+	switch skillType {
+	case "listening":
+		bandColumn = "listening_band"
+	case "reading":
+		bandColumn = "reading_band"
+	case "writing":
+		bandColumn = "writing_band"
+	case "speaking":
+		bandColumn = "speaking_band"
+	default:
+		return nil, fmt.Errorf("invalid skill type: %s", skillType)
+	}
+
+	set := &models.TestResultSet{}
+	err := tx.QueryRow(fmt.Sprintf(`
+		UPDATE test_result_sets s
+		SET %s = $1, updated_at = CURRENT_TIMESTAMP
+		FROM official_test_results t
+		WHERE t.test_set_id = s.id AND t.user_id = $2 AND t.source_id = $3 AND t.skill_type = $4
+		RETURNING s.id, s.listening_band, s.reading_band, s.writing_band, s.speaking_band, s.overall_band
+	`, bandColumn), bandScore, userID, sourceID, skillType).Scan(
+		&set.ID, &set.ListeningBand, &set.ReadingBand, &set.WritingBand, &set.SpeakingBand, &set.OverallBand,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to correct test result set: %w", err)
+	}
+	return set, nil
+}
+
+// UpdateTestResultSetOverallTx sets a test result set's overall band
+func (r *UserRepository) UpdateTestResultSetOverallTx(tx *sql.Tx, setID uuid.UUID, overallBand float64) error {
+	_, err := tx.Exec(`
+		UPDATE test_result_sets SET overall_band = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2
+	`, overallBand, setID)
+	if err != nil {
+		return fmt.Errorf("failed to update test result set: %w", err)
+	}
+	return nil
+}
+
+// CorrectPracticeActivityBand changes the band of the practice activity
+// recorded from a source record and reports whether there was one
+func (r *UserRepository) CorrectPracticeActivityBand(userID, sourceID uuid.UUID, bandScore float64) (bool, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE practice_activities
+		SET band_score = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2 AND source_id = $3
+	`, bandScore, userID, sourceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to correct practice activity: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
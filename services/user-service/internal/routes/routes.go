@@ -3,11 +3,15 @@ package routes
 import (
 	"github.com/bisosad1501/DATN/services/user-service/internal/handlers"
 	"github.com/bisosad1501/DATN/services/user-service/internal/middleware"
+	"github.com/bisosad1501/DATN/shared/pkg/metrics"
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(handler *handlers.UserHandler, internalHandler *handlers.InternalHandler, scoringHandler *handlers.ScoringHandler, authMiddleware *middleware.AuthMiddleware) *gin.Engine {
 	router := gin.Default()
+	router.Use(tracing.Middleware("user-service"), tracing.RequestID(), metrics.Middleware("user-service"))
+	router.GET("/metrics", metrics.Handler())
 
 	// Health check
 	router.GET("/health", handler.HealthCheck)
@@ -90,9 +94,11 @@ func SetupRoutes(handler *handlers.UserHandler, internalHandler *handlers.Intern
 		{
 			// Profile management
 			internal.POST("/profile/create", internalHandler.CreateProfileInternal)
+			internal.GET("/users/:user_id/language", internalHandler.GetLanguagePreferenceInternal)
 
 			// Progress updates
 			internal.PUT("/progress/update", internalHandler.UpdateProgressInternal)
+			internal.POST("/progress/batch", internalHandler.GetProgressSummariesInternal)
 
 			// Skill statistics updates
 			internal.PUT("/statistics/:skill/update", internalHandler.UpdateSkillStatisticsInternal)
@@ -104,9 +110,15 @@ func SetupRoutes(handler *handlers.UserHandler, internalHandler *handlers.Intern
 
 			// Scoring endpoints (Phase 3 - Official vs Practice separation)
 			internal.POST("/users/:user_id/test-results", scoringHandler.RecordTestResultInternal)
+			internal.POST("/users/:user_id/test-result-sets", scoringHandler.RecordTestResultSetInternal)
 			internal.POST("/users/:user_id/practice-activities", scoringHandler.RecordPracticeActivityInternal)
+			internal.POST("/users/:user_id/band-score-corrections", scoringHandler.CorrectBandScoreInternal)
 			internal.GET("/users/:user_id/test-history", scoringHandler.GetUserTestHistory)
 			internal.GET("/users/:user_id/practice-statistics", scoringHandler.GetUserPracticeStatistics)
+
+			// Personal data export and erasure (account deletion)
+			internal.GET("/users/:user_id/data", internalHandler.ExportUserDataInternal)
+			internal.DELETE("/users/:user_id/data", internalHandler.EraseUserDataInternal)
 		}
 	}
 
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -10,6 +13,7 @@ import (
 	"github.com/bisosad1501/DATN/services/user-service/internal/models"
 	"github.com/bisosad1501/DATN/services/user-service/internal/repository"
 	"github.com/bisosad1501/DATN/shared/pkg/client"
+	"github.com/bisosad1501/DATN/shared/pkg/ielts"
 	"github.com/google/uuid"
 )
 
@@ -58,6 +62,16 @@ func (s *UserService) GetOrCreateProfile(userID uuid.UUID) (*models.UserProfile,
 	return profile, nil
 }
 
+// GetLanguagePreference returns the language the user chose for the site, or
+// "" when they have no profile
+func (s *UserService) GetLanguagePreference(userID uuid.UUID) (string, error) {
+	profile, err := s.repo.GetProfileByUserID(userID)
+	if err != nil || profile == nil {
+		return "", err
+	}
+	return profile.LanguagePreference, nil
+}
+
 // GetPublicProfile gets another user's profile with visibility check
 // Returns profile with profile_visibility included in response
 func (s *UserService) GetPublicProfile(targetUserID uuid.UUID, requestingUserID *uuid.UUID) (map[string]interface{}, error) {
@@ -550,7 +564,7 @@ func (s *UserService) UnlockAchievement(userID uuid.UUID, achievementID uuid.UUI
 }
 
 // CheckAndUnlockAchievements checks user progress and auto-unlocks eligible achievements
-func (s *UserService) CheckAndUnlockAchievements(userID uuid.UUID) error {
+func (s *UserService) CheckAndUnlockAchievements(ctx context.Context, userID uuid.UUID) error {
 	log.Printf("[User-Service] 🏆 Checking achievements for user %s", userID)
 
 	// 1. Get all achievements
@@ -626,7 +640,7 @@ func (s *UserService) CheckAndUnlockAchievements(userID uuid.UUID) error {
 			unlockedCount++
 
 			// Send achievement notification (async)
-			go s.sendAchievementNotification(userID, &achievement)
+			go s.sendAchievementNotification(ctx, userID, &achievement)
 		}
 	}
 
@@ -694,7 +708,7 @@ func (s *UserService) checkScoreCriteria(achievement models.Achievement, progres
 }
 
 // sendAchievementNotification sends a notification when achievement is unlocked
-func (s *UserService) sendAchievementNotification(userID uuid.UUID, achievement *models.Achievement) {
+func (s *UserService) sendAchievementNotification(ctx context.Context, userID uuid.UUID, achievement *models.Achievement) {
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("❌ PANIC in sendAchievementNotification: %v", r)
@@ -707,7 +721,7 @@ func (s *UserService) sendAchievementNotification(userID uuid.UUID, achievement
 	}
 
 	// Send notification via Notification Service
-	err := s.notificationClient.SendAchievementNotification(
+	err := s.notificationClient.WithContext(ctx).SendAchievementNotification(
 		userID.String(),
 		achievement.Name,
 	)
@@ -1156,7 +1170,7 @@ func (s *UserService) CreateProfileWithData(userID uuid.UUID, fullName string, t
 }
 
 // UpdateProgress updates user learning progress using atomic operations
-func (s *UserService) UpdateProgress(userID uuid.UUID, updates map[string]interface{}) error {
+func (s *UserService) UpdateProgress(ctx context.Context, userID uuid.UUID, updates map[string]interface{}) error {
 	// Ensure progress record exists
 	progress, err := s.repo.GetLearningProgress(userID)
 	if err != nil || progress == nil {
@@ -1212,13 +1226,14 @@ func (s *UserService) UpdateProgress(userID uuid.UUID, updates map[string]interf
 
 	// Check achievements after progress update (async)
 	// This handles achievements for streaks, exercises, and scores
+	ctx = context.WithoutCancel(ctx)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
 				log.Printf("❌ PANIC in CheckAndUnlockAchievements from UpdateProgress: %v", r)
 			}
 		}()
-		if err := s.CheckAndUnlockAchievements(userID); err != nil {
+		if err := s.CheckAndUnlockAchievements(ctx, userID); err != nil {
 			log.Printf("⚠️  Failed to check achievements after progress update: %v", err)
 		}
 	}()
@@ -1227,7 +1242,7 @@ func (s *UserService) UpdateProgress(userID uuid.UUID, updates map[string]interf
 }
 
 // UpdateSkillStatistics updates skill-specific statistics
-func (s *UserService) UpdateSkillStatistics(userID uuid.UUID, skillType string, updates map[string]interface{}) error {
+func (s *UserService) UpdateSkillStatistics(ctx context.Context, userID uuid.UUID, skillType string, updates map[string]interface{}) error {
 	stats, err := s.repo.GetSkillStatistics(userID, skillType)
 	if err != nil || stats == nil {
 		// Create stats if doesn't exist
@@ -1309,7 +1324,7 @@ func (s *UserService) UpdateSkillStatistics(userID uuid.UUID, skillType string,
 		// Update learning_progress with skill score
 		// This will also trigger overall_score recalculation
 		if len(progressUpdates) > 0 {
-			return s.UpdateProgress(userID, progressUpdates)
+			return s.UpdateProgress(ctx, userID, progressUpdates)
 		}
 	}
 
@@ -1385,9 +1400,138 @@ func (s *UserService) RecordOfficialTestResult(result *models.OfficialTestResult
 	}
 
 	// 3. Always recalculate overall score from all available skills after any skill update
-	progress, err := s.repo.GetLearningProgressTx(tx, result.UserID)
-	if err != nil {
+	if err := s.recalculateOverallScoreTx(tx, result.UserID); err != nil {
 		tx.Rollback()
+		return err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("✅ Recorded official test result for user %s (skill: %s, score: %.1f)",
+		result.UserID, result.SkillType, result.BandScore)
+	return nil
+}
+
+// RecordTestResultSet records a four-skill sitting: the set with its overall
+// band and one test result per skill, updating the skill scores as
+// RecordOfficialTestResult does. A set already recorded from the same source
+// is left as it is and false is returned, so the source can retry safely.
+func (s *UserService) RecordTestResultSet(set *models.TestResultSet) (bool, error) {
+	for _, result := range set.Results {
+		switch result.SkillType {
+		case "listening":
+			set.ListeningBand = result.BandScore
+		case "reading":
+			set.ReadingBand = result.BandScore
+		case "writing":
+			set.WritingBand = result.BandScore
+		case "speaking":
+			set.SpeakingBand = result.BandScore
+		}
+	}
+	set.OverallBand = ielts.CalculateOverallBand(set.ListeningBand, set.ReadingBand, set.WritingBand, set.SpeakingBand)
+
+	tx, err := s.repo.BeginTx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	created, err := s.repo.CreateTestResultSetTx(tx, set)
+	if err != nil {
+		return false, err
+	}
+	if !created {
+		return false, nil
+	}
+
+	for i := range set.Results {
+		result := &set.Results[i]
+		result.TestSetID = &set.ID
+		if err := s.repo.CreateOfficialTestResultTx(tx, result); err != nil {
+			return false, fmt.Errorf("failed to create %s test result: %w", result.SkillType, err)
+		}
+		if result.BandScore > 0 {
+			if err := s.repo.UpdateLearningProgressWithTestScoreTx(tx, result.UserID, result.SkillType, result.BandScore, true); err != nil {
+				return false, fmt.Errorf("failed to update learning progress for %s: %w", result.SkillType, err)
+			}
+		}
+	}
+	if err := s.recalculateOverallScoreTx(tx, set.UserID); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Printf("✅ Recorded test result set %s for user %s (overall: %.1f)", set.ID, set.UserID, set.OverallBand)
+	return true, nil
+}
+
+// CorrectBandScore replaces the band recorded from a source record, e.g.
+// after an examiner reviewed an AI-graded attempt. A corrected test result
+// updates the skill's score if it is the latest, and the overall band of the
+// test result set it belongs to. It reports whether a result or practice
+// activity was recorded from the source.
+func (s *UserService) CorrectBandScore(userID, sourceID uuid.UUID, skillType string, bandScore float64) (bool, error) {
+	tx, err := s.repo.BeginTx()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	corrected, err := s.repo.CorrectOfficialTestResultTx(tx, userID, sourceID, skillType, bandScore)
+	if err != nil {
+		return false, err
+	}
+	if corrected {
+		set, err := s.repo.CorrectTestResultSetBandTx(tx, userID, sourceID, skillType, bandScore)
+		if err != nil {
+			return false, err
+		}
+		if set != nil {
+			overall := ielts.CalculateOverallBand(set.ListeningBand, set.ReadingBand, set.WritingBand, set.SpeakingBand)
+			if err := s.repo.UpdateTestResultSetOverallTx(tx, set.ID, overall); err != nil {
+				return false, err
+			}
+		}
+
+		latest, err := s.repo.GetLatestTestBandTx(tx, userID, skillType)
+		if err != nil {
+			return false, err
+		}
+		if err := s.repo.UpdateLearningProgressWithTestScoreTx(tx, userID, skillType, latest, false); err != nil {
+			return false, err
+		}
+		if err := s.recalculateOverallScoreTx(tx, userID); err != nil {
+			return false, err
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		log.Printf("✅ Corrected %s test result %s of user %s to %.1f", skillType, sourceID, userID, bandScore)
+		return true, nil
+	}
+
+	corrected, err = s.repo.CorrectPracticeActivityBand(userID, sourceID, bandScore)
+	if err != nil {
+		return false, err
+	}
+	if corrected {
+		log.Printf("✅ Corrected %s practice activity %s of user %s to %.1f", skillType, sourceID, userID, bandScore)
+	}
+	return corrected, nil
+}
+
+// recalculateOverallScoreTx sets the overall score to the average of the
+// user's skill scores
+func (s *UserService) recalculateOverallScoreTx(tx *sql.Tx, userID uuid.UUID) error {
+	progress, err := s.repo.GetLearningProgressTx(tx, userID)
+	if err != nil {
 		return fmt.Errorf("failed to get learning progress: %w", err)
 	}
 
@@ -1425,32 +1569,23 @@ func (s *UserService) RecordOfficialTestResult(result *models.OfficialTestResult
 			newOverall := totalScore / float64(skillCount)
 			if err := s.repo.UpdateLearningProgressWithTestScoreTx(
 				tx,
-				result.UserID,
+				userID,
 				"overall",
 				newOverall,
 				false,
 			); err != nil {
-				tx.Rollback()
 				return fmt.Errorf("failed to update overall score: %w", err)
 			}
 			log.Printf("✅ Recalculated overall score from %d skills: %.1f", skillCount, newOverall)
 		}
 	}
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	log.Printf("✅ Recorded official test result for user %s (skill: %s, score: %.1f)",
-		result.UserID, result.SkillType, result.BandScore)
 	return nil
 }
 
 // ============= Practice Activities =============
 
 // RecordPracticeActivity records a practice activity and updates statistics
-func (s *UserService) RecordPracticeActivity(activity *models.PracticeActivity) error {
+func (s *UserService) RecordPracticeActivity(ctx context.Context, activity *models.PracticeActivity) error {
 	// 1. Save practice activity to database
 	if err := s.repo.CreatePracticeActivity(activity); err != nil {
 		return fmt.Errorf("failed to create practice activity: %w", err)
@@ -1515,13 +1650,14 @@ func (s *UserService) RecordPracticeActivity(activity *models.PracticeActivity)
 		}
 
 		// 4. Check and unlock achievements (async to avoid blocking)
+		ctx := context.WithoutCancel(ctx)
 		go func() {
 			defer func() {
 				if r := recover(); r != nil {
 					log.Printf("❌ PANIC in CheckAndUnlockAchievements: %v", r)
 				}
 			}()
-			if err := s.CheckAndUnlockAchievements(activity.UserID); err != nil {
+			if err := s.CheckAndUnlockAchievements(ctx, activity.UserID); err != nil {
 				log.Printf("⚠️  Failed to check achievements: %v", err)
 			}
 		}()
@@ -1531,3 +1667,31 @@ func (s *UserService) RecordPracticeActivity(activity *models.PracticeActivity)
 		activity.UserID, activity.Skill, activity.ActivityType)
 	return nil
 }
+
+// ExportUserData returns everything stored about the user, for the personal data export
+func (s *UserService) ExportUserData(ctx context.Context, userID uuid.UUID) (map[string]json.RawMessage, error) {
+	return s.repo.ExportUserData(ctx, userID)
+}
+
+// EraseUserData deletes everything stored about the user when their account is deleted
+func (s *UserService) EraseUserData(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.EraseUserData(ctx, userID); err != nil {
+		return err
+	}
+	log.Printf("✅ Erased personal data of user %s", userID)
+	return nil
+}
+
+// GetProgressSummaries returns each learner's progress for a class roster.
+// Duplicate IDs are dropped so every learner appears once.
+func (s *UserService) GetProgressSummaries(userIDs []uuid.UUID) ([]models.ProgressSummary, error) {
+	seen := make(map[uuid.UUID]bool, len(userIDs))
+	unique := make([]uuid.UUID, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return s.repo.GetProgressSummaries(unique)
+}
@@ -76,7 +76,10 @@ if [ ! -f .env ]; then
         echo -e "${YELLOW}⚠️  .env.example not found. Creating default .env...${NC}"
         cat > .env << 'ENVEOF'
 # JWT Configuration
-JWT_SECRET=your-super-secret-jwt-key-change-this-in-production-2025
+JWT_SIGNING_ALGORITHM=RS256
+JWT_KEY_ROTATION_INTERVAL=720h
+JWT_KEY_ENCRYPTION_SECRET=your-super-secret-key-encryption-secret-change-this-in-production-2025
+MFA_ENCRYPTION_SECRET=your-super-secret-mfa-encryption-secret-change-this-in-production-2025
 JWT_EXPIRATION=86400
 
 # Database Configuration
@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AuthServiceClient handles communication with Auth Service
+type AuthServiceClient struct {
+	*ServiceClient
+}
+
+// NewAuthServiceClient creates a new auth service client
+func NewAuthServiceClient(baseURL, apiKey string) *AuthServiceClient {
+	return &AuthServiceClient{
+		ServiceClient: NewServiceClient(baseURL, apiKey),
+	}
+}
+
+// WithContext returns a copy of the client bound to ctx (see ServiceClient.WithContext)
+func (c *AuthServiceClient) WithContext(ctx context.Context) *AuthServiceClient {
+	return &AuthServiceClient{ServiceClient: c.ServiceClient.WithContext(ctx)}
+}
+
+// AuditEventRequest represents an entry for the auth service audit log
+type AuditEventRequest struct {
+	UserID       string                 `json:"user_id,omitempty"`
+	EventType    string                 `json:"event_type"`   // e.g. access_denied
+	EventStatus  string                 `json:"event_status"` // success, failed
+	IPAddress    string                 `json:"ip_address,omitempty"`
+	UserAgent    string                 `json:"user_agent,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	ErrorMessage string                 `json:"error_message,omitempty"`
+}
+
+// RecordAuditEvent writes an entry to the auth service audit log
+func (c *AuthServiceClient) RecordAuditEvent(req AuditEventRequest) error {
+	endpoint := "/api/v1/auth/internal/audit-events"
+
+	if err := c.PostWithRetry(endpoint, req, 3); err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+
+	return nil
+}
+
+// GetRolePermissions returns the permission names granted to a role
+func (c *AuthServiceClient) GetRolePermissions(role string) ([]string, error) {
+	endpoint := fmt.Sprintf("/api/v1/auth/internal/roles/%s/permissions", url.PathEscape(role))
+
+	resp, err := c.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("get role permissions: %w", err)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+		Data    struct {
+			Permissions []string `json:"permissions"`
+		} `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("get role permissions: %w", err)
+	}
+
+	return result.Data.Permissions, nil
+}
+
+// Classroom is a classroom of an organization
+type Classroom struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organization_id"`
+	Name           string `json:"name"`
+	Role           string `json:"role,omitempty"` // the user's role, when listing a user's classrooms
+}
+
+// ClassroomStudent is a student on a classroom roster
+type ClassroomStudent struct {
+	UserID   string    `json:"user_id"`
+	Email    string    `json:"email"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ClassroomAccess is a user's role in a classroom, with the classroom's students
+type ClassroomAccess struct {
+	Classroom Classroom          `json:"classroom"`
+	Role      string             `json:"role"` // instructor, admin (of the organization), student or empty
+	Students  []ClassroomStudent `json:"students"`
+}
+
+// CanTeach reports whether the user may manage the classroom's work
+func (a *ClassroomAccess) CanTeach() bool {
+	return a.Role == "instructor" || a.Role == "admin"
+}
+
+// ErrClassroomNotFound is returned when the classroom does not exist
+var ErrClassroomNotFound = errors.New("classroom not found")
+
+// GetClassroomAccess returns the user's role in a classroom and its students
+func (c *AuthServiceClient) GetClassroomAccess(classroomID, userID string) (*ClassroomAccess, error) {
+	endpoint := fmt.Sprintf("/api/v1/auth/internal/classrooms/%s/access?user_id=%s", url.PathEscape(classroomID), url.QueryEscape(userID))
+
+	resp, err := c.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("get classroom access: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrClassroomNotFound
+	}
+
+	var result struct {
+		Success bool            `json:"success"`
+		Data    ClassroomAccess `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("get classroom access: %w", err)
+	}
+
+	return &result.Data, nil
+}
+
+// ListUserClassrooms returns the classrooms a user teaches or studies in
+func (c *AuthServiceClient) ListUserClassrooms(userID string) ([]Classroom, error) {
+	endpoint := fmt.Sprintf("/api/v1/auth/internal/users/%s/classrooms", url.PathEscape(userID))
+
+	resp, err := c.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("list user classrooms: %w", err)
+	}
+
+	var result struct {
+		Success bool        `json:"success"`
+		Data    []Classroom `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("list user classrooms: %w", err)
+	}
+
+	return result.Data, nil
+}
@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// CourseServiceClient handles communication with Course Service
+type CourseServiceClient struct {
+	*ServiceClient
+}
+
+// NewCourseServiceClient creates a new course service client
+func NewCourseServiceClient(baseURL, apiKey string) *CourseServiceClient {
+	return &CourseServiceClient{
+		ServiceClient: NewServiceClient(baseURL, apiKey),
+	}
+}
+
+// WithContext returns a copy of the client bound to ctx (see ServiceClient.WithContext)
+func (c *CourseServiceClient) WithContext(ctx context.Context) *CourseServiceClient {
+	return &CourseServiceClient{ServiceClient: c.ServiceClient.WithContext(ctx)}
+}
+
+// ExportUserData returns the user's enrollments, lesson progress, watch history and reviews
+func (c *CourseServiceClient) ExportUserData(userID string) (json.RawMessage, error) {
+	data, err := c.exportUserData("/api/v1/courses/internal", userID)
+	if err != nil {
+		return nil, fmt.Errorf("export course data: %w", err)
+	}
+	return data, nil
+}
+
+// EraseUserData deletes the user's enrollments, lesson progress, watch history and reviews
+func (c *CourseServiceClient) EraseUserData(userID string) error {
+	if err := c.eraseUserData("/api/v1/courses/internal", userID); err != nil {
+		return fmt.Errorf("erase course data: %w", err)
+	}
+	return nil
+}
+
+// LessonSummary identifies a lesson and its course
+type LessonSummary struct {
+	ID          string `json:"id"`
+	CourseID    string `json:"course_id"`
+	Title       string `json:"title"`
+	IsPublished bool   `json:"is_published"`
+}
+
+// LessonProgressStatus is one user's progress in one lesson
+type LessonProgressStatus struct {
+	UserID             string     `json:"user_id"`
+	LessonID           string     `json:"lesson_id"`
+	Status             string     `json:"status"` // not_started, in_progress, completed
+	ProgressPercentage float64    `json:"progress_percentage"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+}
+
+// LessonProgressBatch holds the lessons found and the users' progress in
+// them. Users who never opened a lesson have no progress entry.
+type LessonProgressBatch struct {
+	Lessons  []LessonSummary        `json:"lessons"`
+	Progress []LessonProgressStatus `json:"progress"`
+}
+
+// GetLessonProgress returns the lessons among lessonIDs and the progress of
+// userIDs in them. userIDs may be empty to only look the lessons up.
+func (c *CourseServiceClient) GetLessonProgress(lessonIDs, userIDs []string) (*LessonProgressBatch, error) {
+	resp, err := c.Post("/api/v1/courses/internal/lessons/progress", map[string]interface{}{
+		"lesson_ids": lessonIDs,
+		"user_ids":   userIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get lesson progress: %w", err)
+	}
+
+	var result struct {
+		Success bool                `json:"success"`
+		Data    LessonProgressBatch `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("get lesson progress: %w", err)
+	}
+
+	return &result.Data, nil
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,6 +19,11 @@ func NewExerciseServiceClient(baseURL, apiKey string) *ExerciseServiceClient {
 	}
 }
 
+// WithContext returns a copy of the client bound to ctx (see ServiceClient.WithContext)
+func (c *ExerciseServiceClient) WithContext(ctx context.Context) *ExerciseServiceClient {
+	return &ExerciseServiceClient{ServiceClient: c.ServiceClient.WithContext(ctx)}
+}
+
 // ExerciseSummary represents a summary of an exercise
 type ExerciseSummary struct {
 	ID             string   `json:"id"`
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 )
 
@@ -16,6 +17,11 @@ func NewNotificationServiceClient(baseURL, apiKey string) *NotificationServiceCl
 	}
 }
 
+// WithContext returns a copy of the client bound to ctx (see ServiceClient.WithContext)
+func (c *NotificationServiceClient) WithContext(ctx context.Context) *NotificationServiceClient {
+	return &NotificationServiceClient{ServiceClient: c.ServiceClient.WithContext(ctx)}
+}
+
 // SendNotificationRequest represents notification send request
 type SendNotificationRequest struct {
 	UserID     string                 `json:"user_id"`
@@ -51,17 +57,31 @@ func (c *NotificationServiceClient) SendNotification(req SendNotificationRequest
 
 // SendBulkNotification sends notifications to multiple users
 func (c *NotificationServiceClient) SendBulkNotification(userIDs []string, title, message, notifType, category string) error {
-	endpoint := "/api/v1/notifications/internal/bulk"
+	return c.SendBulk(BulkNotificationRequest{
+		UserIDs:  userIDs,
+		Title:    title,
+		Message:  message,
+		Type:     notifType,
+		Category: category,
+	})
+}
 
-	payload := map[string]interface{}{
-		"user_ids": userIDs,
-		"title":    title,
-		"message":  message,
-		"type":     notifType,
-		"category": category,
-	}
+// BulkNotificationRequest is one notification sent to several users
+type BulkNotificationRequest struct {
+	UserIDs    []string               `json:"user_ids"`
+	Title      string                 `json:"title"`
+	Message    string                 `json:"message"`
+	Type       string                 `json:"type"`
+	Category   string                 `json:"category"`
+	ActionType *string                `json:"action_type,omitempty"`
+	ActionData map[string]interface{} `json:"action_data,omitempty"`
+}
 
-	err := c.PostWithRetry(endpoint, payload, 3)
+// SendBulk sends the same notification, with an optional action, to several users
+func (c *NotificationServiceClient) SendBulk(req BulkNotificationRequest) error {
+	endpoint := "/api/v1/notifications/internal/bulk"
+
+	err := c.PostWithRetry(endpoint, req, 3)
 	if err != nil {
 		return fmt.Errorf("send bulk notification: %w", err)
 	}
@@ -69,6 +89,25 @@ func (c *NotificationServiceClient) SendBulkNotification(userIDs []string, title
 	return nil
 }
 
+// SendEmailRequest queues a transactional email rendered from one of
+// notification-service's email templates
+type SendEmailRequest struct {
+	UserID   string            `json:"user_id,omitempty"` // Empty for addresses without an account
+	To       string            `json:"to"`
+	Template string            `json:"template"`         // password_reset, email_verification, login_code, email_change_notice, classroom_invite
+	Locale   string            `json:"locale,omitempty"` // vi or en; empty uses the recipient's language, else EMAIL_DEFAULT_LOCALE
+	Data     map[string]string `json:"data,omitempty"`   // Template variables, e.g. code, link
+}
+
+// SendEmail stores the email in notification-service's outbox, which sends it
+// with retries. A nil error means it was queued, not delivered.
+func (c *NotificationServiceClient) SendEmail(req SendEmailRequest) error {
+	if err := c.PostWithRetry("/api/v1/notifications/internal/emails", req, 3); err != nil {
+		return fmt.Errorf("queue %s email: %w", req.Template, err)
+	}
+	return nil
+}
+
 // Helper functions for common notification types
 
 // SendWelcomeNotification sends welcome notification to new user
@@ -83,6 +122,32 @@ func (c *NotificationServiceClient) SendWelcomeNotification(userID, email string
 	})
 }
 
+// SendSessionCompromisedNotification warns a user that a session was signed
+// out because its refresh token was used twice
+func (c *NotificationServiceClient) SendSessionCompromisedNotification(userID, deviceName string) error {
+	return c.SendNotification(SendNotificationRequest{
+		UserID:   userID,
+		Title:    "Cảnh báo bảo mật: phiên đăng nhập đã bị đăng xuất",
+		Message:  fmt.Sprintf("Phiên đăng nhập trên '%s' đã bị đăng xuất vì phát hiện mã đăng nhập bị sử dụng lại. Nếu không phải bạn, hãy đổi mật khẩu ngay.", deviceName),
+		Type:     "system",
+		Category: "alert",
+		Priority: "high",
+	})
+}
+
+// SendClassroomInviteNotification tells a user with an account that they were
+// invited to a classroom, with the code that joins it
+func (c *NotificationServiceClient) SendClassroomInviteNotification(userID, organization, classroom, code string) error {
+	return c.SendNotification(SendNotificationRequest{
+		UserID:   userID,
+		Title:    "Lời mời vào lớp học",
+		Message:  fmt.Sprintf("%s mời bạn vào lớp '%s'. Nhập mã %s để tham gia lớp.", organization, classroom, code),
+		Type:     "system",
+		Category: "info",
+		Priority: "normal",
+	})
+}
+
 // SendLessonCompletionNotification sends lesson completion notification
 func (c *NotificationServiceClient) SendLessonCompletionNotification(userID, lessonTitle string, progress int) error {
 	return c.SendNotification(SendNotificationRequest{
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// PersonalDataClient exports and erases what a service holds about a user.
+// auth-service calls every service's client when a user asks for their data
+// or deletes their account.
+type PersonalDataClient interface {
+	ExportUserData(userID string) (json.RawMessage, error)
+	EraseUserData(userID string) error
+}
+
+// exportUserData fetches GET <internalPath>/users/:user_id/data
+func (c *ServiceClient) exportUserData(internalPath, userID string) (json.RawMessage, error) {
+	resp, err := c.Get(fmt.Sprintf("%s/users/%s/data", internalPath, userID))
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// eraseUserData calls DELETE <internalPath>/users/:user_id/data. Erasing a
+// user the service knows nothing about succeeds, so failed steps can be retried.
+func (c *ServiceClient) eraseUserData(internalPath, userID string) error {
+	resp, err := c.Delete(fmt.Sprintf("%s/users/%s/data", internalPath, userID))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// ExportUserData returns the user's profile, progress, statistics and history
+func (c *UserServiceClient) ExportUserData(userID string) (json.RawMessage, error) {
+	data, err := c.exportUserData("/api/v1/user/internal", userID)
+	if err != nil {
+		return nil, fmt.Errorf("export user data: %w", err)
+	}
+	return data, nil
+}
+
+// EraseUserData deletes the user's profile and everything attached to it
+func (c *UserServiceClient) EraseUserData(userID string) error {
+	if err := c.eraseUserData("/api/v1/user/internal", userID); err != nil {
+		return fmt.Errorf("erase user data: %w", err)
+	}
+	return nil
+}
+
+// ExportUserData returns the user's notifications, devices and preferences
+func (c *NotificationServiceClient) ExportUserData(userID string) (json.RawMessage, error) {
+	data, err := c.exportUserData("/api/v1/notifications/internal", userID)
+	if err != nil {
+		return nil, fmt.Errorf("export notification data: %w", err)
+	}
+	return data, nil
+}
+
+// EraseUserData deletes the user's notifications, devices and preferences
+func (c *NotificationServiceClient) EraseUserData(userID string) error {
+	if err := c.eraseUserData("/api/v1/notifications/internal", userID); err != nil {
+		return fmt.Errorf("erase notification data: %w", err)
+	}
+	return nil
+}
+
+// ExportUserData returns the user's exercise attempts and answers
+func (c *ExerciseServiceClient) ExportUserData(userID string) (json.RawMessage, error) {
+	data, err := c.exportUserData("/api/v1/exercises/internal", userID)
+	if err != nil {
+		return nil, fmt.Errorf("export exercise data: %w", err)
+	}
+	return data, nil
+}
+
+// EraseUserData deletes the user's exercise attempts and answers
+func (c *ExerciseServiceClient) EraseUserData(userID string) error {
+	if err := c.eraseUserData("/api/v1/exercises/internal", userID); err != nil {
+		return fmt.Errorf("erase exercise data: %w", err)
+	}
+	return nil
+}
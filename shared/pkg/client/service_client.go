@@ -2,11 +2,14 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
+
+	"github.com/bisosad1501/DATN/shared/pkg/tracing"
 )
 
 // ServiceClient is a reusable HTTP client for service-to-service communication
@@ -14,6 +17,7 @@ type ServiceClient struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
+	ctx        context.Context
 }
 
 // NewServiceClient creates a new service client
@@ -23,15 +27,24 @@ func NewServiceClient(baseURL, apiKey string) *ServiceClient {
 		apiKey:  apiKey,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
-			Transport: &http.Transport{
+			Transport: tracing.NewTransport(&http.Transport{
 				MaxIdleConns:        100,
 				MaxIdleConnsPerHost: 10,
 				IdleConnTimeout:     90 * time.Second,
-			},
+			}),
 		},
+		ctx: context.Background(),
 	}
 }
 
+// WithContext returns a copy of the client whose requests carry ctx, so they
+// join the caller's trace and forward its X-Request-ID
+func (c *ServiceClient) WithContext(ctx context.Context) *ServiceClient {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
 // Post sends a POST request
 func (c *ServiceClient) Post(endpoint string, payload interface{}) (*http.Response, error) {
 	return c.doRequest("POST", endpoint, payload)
@@ -63,7 +76,7 @@ func (c *ServiceClient) doRequest(method, endpoint string, payload interface{})
 		body = bytes.NewBuffer(jsonData)
 	}
 
-	req, err := http.NewRequest(method, c.baseURL+endpoint, body)
+	req, err := http.NewRequestWithContext(c.ctx, method, c.baseURL+endpoint, body)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StorageServiceClient handles communication with Storage Service
+type StorageServiceClient struct {
+	*ServiceClient
+}
+
+// NewStorageServiceClient creates a new storage service client
+func NewStorageServiceClient(baseURL, apiKey string) *StorageServiceClient {
+	return &StorageServiceClient{
+		ServiceClient: NewServiceClient(baseURL, apiKey),
+	}
+}
+
+// WithContext returns a copy of the client bound to ctx (see ServiceClient.WithContext)
+func (c *StorageServiceClient) WithContext(ctx context.Context) *StorageServiceClient {
+	return &StorageServiceClient{ServiceClient: c.ServiceClient.WithContext(ctx)}
+}
+
+// StoredObject describes a file the user uploaded, such as a speaking recording
+type StoredObject struct {
+	ObjectName   string `json:"object_name"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type"`
+	LastModified int64  `json:"last_modified"`
+}
+
+// ExportUserData lists the user's recordings as {"recordings": [StoredObject]}
+func (c *StorageServiceClient) ExportUserData(userID string) (json.RawMessage, error) {
+	data, err := c.exportUserData("/api/v1/storage/internal", userID)
+	if err != nil {
+		return nil, fmt.Errorf("export storage data: %w", err)
+	}
+	return data, nil
+}
+
+// EraseUserData deletes every recording the user uploaded
+func (c *StorageServiceClient) EraseUserData(userID string) error {
+	if err := c.eraseUserData("/api/v1/storage/internal", userID); err != nil {
+		return fmt.Errorf("erase storage data: %w", err)
+	}
+	return nil
+}
+
+// DownloadObject streams a stored file. The caller closes the reader.
+func (c *StorageServiceClient) DownloadObject(objectName string) (io.ReadCloser, error) {
+	resp, err := c.Get("/api/v1/storage/audio/file/" + objectName)
+	if err != nil {
+		return nil, fmt.Errorf("download object: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("download object: request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	return resp.Body, nil
+}
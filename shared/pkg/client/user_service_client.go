@@ -1,8 +1,11 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 )
 
@@ -18,6 +21,11 @@ func NewUserServiceClient(baseURL, apiKey string) *UserServiceClient {
 	}
 }
 
+// WithContext returns a copy of the client bound to ctx (see ServiceClient.WithContext)
+func (c *UserServiceClient) WithContext(ctx context.Context) *UserServiceClient {
+	return &UserServiceClient{ServiceClient: c.ServiceClient.WithContext(ctx)}
+}
+
 // CreateProfileRequest represents profile creation request
 type CreateProfileRequest struct {
 	UserID          string  `json:"user_id"`
@@ -189,12 +197,26 @@ type RecordTestResultRequest struct {
 	Notes          *string    `json:"notes,omitempty"`
 }
 
+// RecordTestResultSetRequest records the four skills of one sitting, such as
+// a mock test, as one set with an overall band. Results take the set's test
+// type and date.
+type RecordTestResultSetRequest struct {
+	TestType      string                    `json:"test_type"`     // full_test, mock_test
+	IELTSVariant  string                    `json:"ielts_variant"` // academic, general_training
+	SourceService string                    `json:"source_service,omitempty"`
+	SourceID      string                    `json:"source_id"` // e.g. mock test session ID
+	TestDate      *time.Time                `json:"test_date,omitempty"`
+	ScoreReport   interface{}               `json:"score_report,omitempty"`
+	Results       []RecordTestResultRequest `json:"results"` // one per skill
+}
+
 // RecordPracticeActivityRequest represents practice activity recording request
 type RecordPracticeActivityRequest struct {
 	Skill              string     `json:"skill"`
 	ActivityType       string     `json:"activity_type"`
 	ExerciseID         *string    `json:"exercise_id,omitempty"`
 	ExerciseTitle      *string    `json:"exercise_title,omitempty"`
+	SourceID           *string    `json:"source_id,omitempty"` // e.g. the exercise attempt, for later corrections
 	Score              *float64   `json:"score,omitempty"`
 	MaxScore           *float64   `json:"max_score,omitempty"`
 	BandScore          *float64   `json:"band_score,omitempty"`
@@ -223,6 +245,19 @@ func (c *UserServiceClient) RecordTestResult(userID string, req RecordTestResult
 	return nil
 }
 
+// RecordTestResultSet records a four-skill sitting. Recording the same
+// source again is a no-op, so it is safe to retry.
+func (c *UserServiceClient) RecordTestResultSet(userID string, req RecordTestResultSetRequest) error {
+	endpoint := fmt.Sprintf("/api/v1/user/internal/users/%s/test-result-sets", userID)
+
+	err := c.PostWithRetry(endpoint, req, 3)
+	if err != nil {
+		return fmt.Errorf("record test result set: %w", err)
+	}
+
+	return nil
+}
+
 // RecordPracticeActivity records a practice activity (separate from official test scores)
 func (c *UserServiceClient) RecordPracticeActivity(userID string, req RecordPracticeActivityRequest) error {
 	endpoint := fmt.Sprintf("/api/v1/user/internal/users/%s/practice-activities", userID)
@@ -235,6 +270,39 @@ func (c *UserServiceClient) RecordPracticeActivity(userID string, req RecordPrac
 	return nil
 }
 
+// CorrectBandScoreRequest replaces the band recorded from a source record
+type CorrectBandScoreRequest struct {
+	SourceID  string  `json:"source_id"`
+	SkillType string  `json:"skill_type"`
+	BandScore float64 `json:"band_score"`
+}
+
+// ErrResultNotFound is returned when no result was recorded from the source
+var ErrResultNotFound = errors.New("no result recorded from this source")
+
+// CorrectBandScore replaces the band of the test result or practice activity
+// recorded from a source record, e.g. after an examiner review
+func (c *UserServiceClient) CorrectBandScore(userID string, req CorrectBandScoreRequest) error {
+	endpoint := fmt.Sprintf("/api/v1/user/internal/users/%s/band-score-corrections", userID)
+
+	resp, err := c.Post(endpoint, req)
+	if err != nil {
+		return fmt.Errorf("correct band score: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return ErrResultNotFound
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return fmt.Errorf("correct band score: %w", err)
+	}
+	return nil
+}
+
 // GetTestHistory retrieves user's test history with pagination
 func (c *UserServiceClient) GetTestHistory(userID string, page, limit int, skillType *string) (interface{}, error) {
 	endpoint := fmt.Sprintf("/api/v1/user/internal/scoring/%s/test-history?page=%d&limit=%d", userID, page, limit)
@@ -276,3 +344,65 @@ func (c *UserServiceClient) GetPracticeStatistics(userID string, skillType *stri
 
 	return result.Data, nil
 }
+
+// ProgressSummary is one learner's progress as returned for a class roster
+type ProgressSummary struct {
+	UserID                  string                  `json:"user_id"`
+	FullName                *string                 `json:"full_name,omitempty"`
+	TotalStudyHours         float64                 `json:"total_study_hours"`
+	TotalLessonsCompleted   int                     `json:"total_lessons_completed"`
+	TotalExercisesCompleted int                     `json:"total_exercises_completed"`
+	OverallScore            *float64                `json:"overall_score,omitempty"`
+	CurrentStreakDays       int                     `json:"current_streak_days"`
+	LastStudyDate           *time.Time              `json:"last_study_date,omitempty"`
+	Skills                  map[string]SkillSummary `json:"skills"`
+}
+
+// SkillSummary is a learner's progress and practice statistics in one skill
+type SkillSummary struct {
+	Progress         float64    `json:"progress"`
+	BandScore        *float64   `json:"band_score,omitempty"`
+	TotalPractices   int        `json:"total_practices"`
+	AverageScore     float64    `json:"average_score"`
+	BestScore        float64    `json:"best_score"`
+	TotalTimeMinutes int        `json:"total_time_minutes"`
+	LastPracticeDate *time.Time `json:"last_practice_date,omitempty"`
+}
+
+// GetProgressSummaries returns the progress of each learner, in the order given.
+// User Service accepts at most 500 IDs per call.
+func (c *UserServiceClient) GetProgressSummaries(userIDs []string) ([]ProgressSummary, error) {
+	resp, err := c.Post("/api/v1/user/internal/progress/batch", map[string][]string{"user_ids": userIDs})
+	if err != nil {
+		return nil, fmt.Errorf("get progress summaries: %w", err)
+	}
+
+	var result struct {
+		Success bool              `json:"success"`
+		Data    []ProgressSummary `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("get progress summaries: %w", err)
+	}
+	return result.Data, nil
+}
+
+// GetLanguagePreference returns the language a user chose for the site, or ""
+// when they have no profile
+func (c *UserServiceClient) GetLanguagePreference(userID string) (string, error) {
+	resp, err := c.Get("/api/v1/user/internal/users/" + userID + "/language")
+	if err != nil {
+		return "", fmt.Errorf("get language preference: %w", err)
+	}
+
+	var result struct {
+		Success bool `json:"success"`
+		Data    struct {
+			LanguagePreference string `json:"language_preference"`
+		} `json:"data"`
+	}
+	if err := DecodeResponse(resp, &result); err != nil {
+		return "", fmt.Errorf("get language preference: %w", err)
+	}
+	return result.Data.LanguagePreference, nil
+}
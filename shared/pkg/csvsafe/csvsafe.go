@@ -0,0 +1,19 @@
+// Package csvsafe neutralises CSV cells that spreadsheet applications would
+// otherwise run as formulas when an export is opened (CSV injection).
+package csvsafe
+
+import "strings"
+
+// formulaPrefixes start a formula, or a DDE payload, in Excel, LibreOffice
+// and Google Sheets
+const formulaPrefixes = "=+-@\t\r"
+
+// Cell returns value prefixed with a single quote when it starts like a
+// formula, so spreadsheets show it as text. Use it for every user-supplied
+// column of an export.
+func Cell(value string) string {
+	if value != "" && strings.IndexByte(formulaPrefixes, value[0]) >= 0 {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,27 @@
+package csvsafe
+
+import "testing"
+
+// TestCell tests which values are escaped
+func TestCell(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"", ""},
+		{"Mozilla/5.0", "Mozilla/5.0"},
+		{"Nguyễn Văn A", "Nguyễn Văn A"},
+		{"a=b", "a=b"},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1+2", "'+1+2"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+	}
+	for _, tt := range tests {
+		if got := Cell(tt.value); got != tt.expected {
+			t.Errorf("Cell(%q) = %q, expected %q", tt.value, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	// cacheTTL is how long a fetched key set is trusted before it is refreshed
+	cacheTTL = 10 * time.Minute
+	// minRefreshInterval bounds refetches triggered by unknown kids, so forged
+	// tokens cannot turn into a flood of requests against auth-service
+	minRefreshInterval = 15 * time.Second
+)
+
+// ErrUnknownKey is returned when a token's kid is not in the key set
+var ErrUnknownKey = errors.New("unknown signing key")
+
+type publicKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// KeySet verifies tokens against a remote JWKS. Keys are cached; the set is
+// refetched when the cache is stale or a token names a kid it has not seen,
+// which is how verifiers pick up a rotated key. A stale set keeps being used
+// while auth-service is unreachable.
+type KeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]publicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+
+	refreshMu sync.Mutex
+}
+
+// NewKeySet creates a key set backed by the JWKS at url
+func NewKeySet(url string) *KeySet {
+	return &KeySet{
+		url:        url,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       make(map[string]publicKey),
+	}
+}
+
+// Keyfunc resolves the verification key for a token; pass it to jwt.Parse
+// together with ParserOptions()
+func (s *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no kid header")
+	}
+
+	key, ok, fresh := s.lookup(kid)
+	if !ok || !fresh {
+		if err := s.refresh(context.Background(), !ok); err != nil {
+			log.Printf("[JWKS] Failed to refresh keys from %s: %v", s.url, err)
+		}
+		key, ok, _ = s.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+
+	if alg := token.Method.Alg(); alg != key.alg {
+		return nil, fmt.Errorf("token algorithm %s does not match key %s (%s)", alg, kid, key.alg)
+	}
+	return key.key, nil
+}
+
+// ParserOptions restricts parsing to the asymmetric algorithms auth-service signs with
+func ParserOptions() []jwt.ParserOption {
+	return []jwt.ParserOption{jwt.WithValidMethods(Algorithms)}
+}
+
+func (s *KeySet) lookup(kid string) (publicKey, bool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[kid]
+	return key, ok, time.Since(s.fetchedAt) < cacheTTL
+}
+
+// refresh refetches the key set. Refetches caused by an unknown kid are rate
+// limited; concurrent callers share a single request.
+func (s *KeySet) refresh(ctx context.Context, unknownKid bool) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.mu.RLock()
+	fetchedAt, lastAttempt := s.fetchedAt, s.lastAttempt
+	s.mu.RUnlock()
+
+	// Another caller refreshed while we waited for the lock
+	if !unknownKid && time.Since(fetchedAt) < cacheTTL {
+		return nil
+	}
+	if time.Since(lastAttempt) < minRefreshInterval {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.lastAttempt = time.Now()
+	s.mu.Unlock()
+
+	keys, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.fetchedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *KeySet) fetch(ctx context.Context) (map[string]publicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch key set: status %d", resp.StatusCode)
+	}
+
+	var set Set
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode key set: %w", err)
+	}
+
+	keys := make(map[string]publicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if err != nil {
+			log.Printf("[JWKS] Skipping key %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = publicKey{alg: k.algorithm(), key: pub}
+	}
+	return keys, nil
+}
@@ -0,0 +1,93 @@
+// Package metrics exposes Prometheus RED (rate, errors, duration) metrics for
+// every IELTS platform service. Services mount Middleware on their gin router
+// and serve Handler on GET /metrics; domain metrics are registered by each
+// service with promauto on the default registry so they appear on the same
+// endpoint.
+//
+// Useful queries:
+//
+//	rate:     sum by (service, route) (rate(http_requests_total[5m]))
+//	errors:   sum by (service) (rate(http_requests_total{status=~"5.."}[5m]))
+//	duration: histogram_quantile(0.95, sum by (le, service, route) (rate(http_request_duration_seconds_bucket[5m])))
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests that hit no route, keeping label cardinality bounded
+const unmatchedRoute = "unmatched"
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by route template and status code.",
+	}, []string{"service", "method", "route", "status"})
+
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency, by route template.",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"service", "method", "route"})
+
+	requestsInFlight = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "HTTP requests currently being served.",
+	}, []string{"service"})
+)
+
+// Middleware records request count, status and latency labelled by the gin
+// route template (e.g. /api/v1/submissions/:id), never the raw path
+func Middleware(service string) gin.HandlerFunc {
+	inFlight := requestsInFlight.WithLabelValues(service)
+
+	return func(c *gin.Context) {
+		if c.Request.URL.Path == "/metrics" {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		inFlight.Inc()
+
+		defer func() {
+			inFlight.Dec()
+
+			status := c.Writer.Status()
+			r := recover()
+			if r != nil {
+				// gin.Recovery runs outside this middleware and will answer 500
+				status = 500
+			}
+
+			route := c.FullPath()
+			if route == "" {
+				route = unmatchedRoute
+			}
+			method := c.Request.Method
+
+			requestsTotal.WithLabelValues(service, method, route, strconv.Itoa(status)).Inc()
+			requestDuration.WithLabelValues(service, method, route).Observe(time.Since(start).Seconds())
+
+			if r != nil {
+				panic(r)
+			}
+		}()
+
+		c.Next()
+	}
+}
+
+// Handler serves every registered metric in the Prometheus exposition format
+func Handler() gin.HandlerFunc {
+	h := promhttp.Handler()
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,44 @@
+// Package personaldata lets each service export the rows it holds about a user.
+// auth-service collects the exports into one archive when a user asks for their
+// data, and asks every service to erase them when the account is deleted.
+package personaldata
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// Table is a table holding personal data. Where selects one user's rows, with
+// the user ID as $1. Columns limits the export, e.g. to leave out secrets;
+// empty means every column.
+type Table struct {
+	Name    string
+	Where   string
+	Columns string
+}
+
+// UserTable is a table whose user_id column names the owner of each row
+func UserTable(name string) Table {
+	return Table{Name: name, Where: "user_id = $1"}
+}
+
+// Export returns every table's rows for the user as JSON arrays, keyed by table name
+func Export(ctx context.Context, db *sql.DB, userID string, tables []Table) (map[string]json.RawMessage, error) {
+	data := make(map[string]json.RawMessage, len(tables))
+	for _, t := range tables {
+		columns := t.Columns
+		if columns == "" {
+			columns = "*"
+		}
+		query := fmt.Sprintf(`SELECT COALESCE(json_agg(t), '[]'::json) FROM (SELECT %s FROM %s WHERE %s) t`, columns, t.Name, t.Where)
+
+		var rows []byte
+		if err := db.QueryRowContext(ctx, query, userID).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("export %s: %w", t.Name, err)
+		}
+		data[t.Name] = rows
+	}
+	return data, nil
+}
@@ -0,0 +1,12 @@
+// Package revocation names the Redis denylist entries auth-service writes when
+// a session is revoked. Access tokens carry their session's ID in the "sid"
+// claim, so the gateway can reject them before they expire.
+package revocation
+
+const sessionKeyPrefix = "revoked_session:"
+
+// SessionKey is the denylist key for a session, i.e. a refresh token's ID. The
+// entry only needs to outlive the access tokens issued for the session.
+func SessionKey(sessionID string) string {
+	return sessionKeyPrefix + sessionID
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the correlation ID between the client, the gateway and every service
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied IDs so they cannot bloat logs
+const maxRequestIDLength = 128
+
+// Middleware starts a server span for every request, continuing the trace from
+// an incoming traceparent header when present. Health checks are not traced.
+func Middleware(serviceName string) gin.HandlerFunc {
+	return otelgin.Middleware(serviceName, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/health"
+	}))
+}
+
+// RequestID accepts the caller's X-Request-ID or mints one, stores it in the
+// request context for outbound clients and echoes it with the trace ID.
+// It must run after Middleware so the ID is recorded on the server span.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.NewString()
+		}
+
+		c.Request.Header.Set(RequestIDHeader, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Set("request_id", requestID)
+
+		ctx := WithRequestID(c.Request.Context(), requestID)
+		span := trace.SpanFromContext(ctx)
+		span.SetAttributes(attribute.String("request.id", requestID))
+		if sc := span.SpanContext(); sc.HasTraceID() {
+			c.Header("X-Trace-ID", sc.TraceID().String())
+		}
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
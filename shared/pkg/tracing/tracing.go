@@ -0,0 +1,108 @@
+// Package tracing wires OpenTelemetry tracing and X-Request-ID correlation
+// into every IELTS platform service.
+//
+// Exporters are selected with OTEL_TRACES_EXPORTER:
+//
+//	otlp    - OTLP/HTTP to OTEL_EXPORTER_OTLP_ENDPOINT (default when the endpoint is set)
+//	console - pretty-printed spans on stdout
+//	file    - one JSON span per line appended to OTEL_TRACES_FILE
+//	none    - no export; trace context is still minted and propagated
+//
+// Sampling follows the standard OTEL_TRACES_SAMPLER / OTEL_TRACES_SAMPLER_ARG variables.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Init installs the global tracer provider and W3C trace-context propagator.
+// The returned shutdown function flushes pending spans and is never nil.
+func Init(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME / OTEL_RESOURCE_ATTRIBUTES win
+	)
+	if err != nil {
+		return noopShutdown, fmt.Errorf("create resource: %w", err)
+	}
+
+	opts := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	var cleanup func() error
+
+	switch exporter := exporterName(); exporter {
+	case "none":
+	case "otlp":
+		exp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return noopShutdown, fmt.Errorf("create OTLP exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "console", "stdout":
+		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return noopShutdown, fmt.Errorf("create stdout exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+	case "file":
+		path := os.Getenv("OTEL_TRACES_FILE")
+		if path == "" {
+			path = serviceName + "-traces.jsonl"
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return noopShutdown, fmt.Errorf("open trace file: %w", err)
+		}
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(f))
+		if err != nil {
+			f.Close()
+			return noopShutdown, fmt.Errorf("create file exporter: %w", err)
+		}
+		opts = append(opts, sdktrace.WithBatcher(exp))
+		cleanup = f.Close
+	default:
+		return noopShutdown, fmt.Errorf("unknown OTEL_TRACES_EXPORTER %q", exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(opts...)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if cleanup != nil {
+			err = errors.Join(err, cleanup())
+		}
+		return err
+	}, nil
+}
+
+// exporterName resolves OTEL_TRACES_EXPORTER, defaulting to OTLP when an endpoint is configured
+func exporterName() string {
+	if name := strings.ToLower(strings.TrimSpace(os.Getenv("OTEL_TRACES_EXPORTER"))); name != "" {
+		return name
+	}
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "" {
+		return "otlp"
+	}
+	return "none"
+}
+
+func noopShutdown(context.Context) error { return nil }
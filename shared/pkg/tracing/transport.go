@@ -0,0 +1,30 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// NewTransport wraps base so every outbound request gets a client span and
+// carries the caller's traceparent and X-Request-ID. Requests must be built
+// with the caller's context (http.NewRequestWithContext) to join its trace.
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(&requestIDTransport{base: base})
+}
+
+// requestIDTransport copies the correlation ID from the request context to its headers
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t *requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if requestID := RequestIDFromContext(req.Context()); requestID != "" && req.Header.Get(RequestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+	return t.base.RoundTrip(req)
+}